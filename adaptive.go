@@ -0,0 +1,143 @@
+package sqln
+
+import (
+	"time"
+)
+
+// AdaptiveCache sizes the statement cache from observed traffic instead of a
+// fixed capacity. The cache starts at Min and doubles (up to Max) when it is
+// thrashing, ie. a significant share of lookups evict another statement and
+// prepares are expensive enough to be worth avoiding. It halves (down to Min)
+// whenever MemoryPressure reports true.
+type AdaptiveCache struct {
+	// Min and Max bound the capacity. Default to 16 and 1024.
+	Min, Max int
+	// Window is the observation period between sizing decisions. Defaults
+	// to one minute.
+	Window time.Duration
+	// CheapPrepare is the average prepare latency below which the cache is
+	// not grown, since re-preparing costs little.
+	CheapPrepare time.Duration
+	// MemoryPressure is checked at the end of each window.
+	MemoryPressure func() bool
+	// OnResize is called after each sizing decision.
+	OnResize func(CacheResize)
+}
+
+// CacheResize describes a sizing decision made by an adaptive cache.
+type CacheResize struct {
+	From, To int
+	Reason   string
+
+	// Observations over the window that led to the decision. Misses
+	// approximate the rate of unique queries.
+	Window     time.Duration
+	Lookups    int
+	Misses     int
+	Evictions  int
+	AvgPrepare time.Duration
+}
+
+// WithAdaptiveCache sizes the statement cache adaptively.
+func WithAdaptiveCache(a AdaptiveCache) Option {
+	if a.Min <= 0 {
+		a.Min = 16
+	}
+	if a.Max < a.Min {
+		a.Max = 1024
+		if a.Max < a.Min {
+			a.Max = a.Min
+		}
+	}
+	if a.Window <= 0 {
+		a.Window = time.Minute
+	}
+	return func(d *Database) {
+		d.cache.capacity = a.Min
+		d.cache.adaptive = &adaptiveCache{cfg: a, start: time.Now()}
+	}
+}
+
+// adaptiveCache accumulates observations for the current window. It is
+// guarded by the stmtCache mutex.
+type adaptiveCache struct {
+	cfg   AdaptiveCache
+	start time.Time
+
+	lookups, misses, evictions int
+	prepareTime                time.Duration
+
+	// pending is a decision to be reported once the cache lock is released.
+	pending *CacheResize
+}
+
+func (a *adaptiveCache) hit(c *stmtCache) {
+	a.lookups++
+	a.maybeResize(c)
+}
+
+func (a *adaptiveCache) miss(c *stmtCache, prepare time.Duration, evicted int) {
+	a.lookups++
+	a.misses++
+	a.evictions += evicted
+	a.prepareTime += prepare
+	a.maybeResize(c)
+}
+
+func (a *adaptiveCache) maybeResize(c *stmtCache) {
+	elapsed := time.Since(a.start)
+	if elapsed < a.cfg.Window {
+		return
+	}
+
+	r := CacheResize{
+		From:      c.capacity,
+		To:        c.capacity,
+		Window:    elapsed,
+		Lookups:   a.lookups,
+		Misses:    a.misses,
+		Evictions: a.evictions,
+	}
+	if a.misses > 0 {
+		r.AvgPrepare = a.prepareTime / time.Duration(a.misses)
+	}
+
+	switch {
+	case a.cfg.MemoryPressure != nil && a.cfg.MemoryPressure():
+		r.To, r.Reason = c.capacity/2, "memory pressure"
+		if r.To < a.cfg.Min {
+			r.To = a.cfg.Min
+		}
+	case a.evictions > 0 && a.evictions*10 >= a.lookups && r.AvgPrepare >= a.cfg.CheapPrepare:
+		r.To, r.Reason = c.capacity*2, "thrashing"
+		if r.To > a.cfg.Max {
+			r.To = a.cfg.Max
+		}
+	}
+
+	a.start = time.Now()
+	a.lookups, a.misses, a.evictions, a.prepareTime = 0, 0, 0, 0
+
+	if r.To != r.From {
+		c.resizeLocked(r.To)
+		if a.cfg.OnResize != nil {
+			a.pending = &r
+		}
+	}
+}
+
+// report calls OnResize with a pending decision. Must be called without the
+// cache lock held.
+func (c *stmtCache) report() {
+	if c.adaptive == nil {
+		return
+	}
+	c.mtx.Lock()
+	r := c.adaptive.pending
+	c.adaptive.pending = nil
+	c.mtx.Unlock()
+
+	if r != nil {
+		c.adaptive.cfg.OnResize(*r)
+	}
+}
@@ -0,0 +1,34 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+
+	"github.com/pkg/errors"
+)
+
+// TryLocked runs f in a transaction holding the Postgres advisory lock named
+// name, ie. so only one instance of a service runs a periodic job. If another
+// session holds the lock f is not run and false is returned. The lock is
+// released when the transaction ends.
+func TryLocked(ctx context.Context, db DB, name string, f func(DB) error) (bool, error) {
+	locked := false
+	err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		if err := tx.Get(withInternal(ctx), "SELECT pg_try_advisory_xact_lock(:id);", &locked, map[string]interface{}{"id": lockID(name)}); err != nil {
+			return errors.Wrap(err, "advisory lock")
+		}
+		if !locked {
+			return nil
+		}
+		return f(tx)
+	})
+	return locked, err
+}
+
+// lockID hashes name to an advisory lock id.
+func lockID(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,36 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nstogner/psqlxtest"
+)
+
+func TestTryLocked(t *testing.T) {
+	dbx, dropx := psqlxtest.TmpDB(t)
+	defer dropx()
+	db := New(dbx)
+	defer db.Close()
+	ctx := context.Background()
+
+	ran := 0
+	locked, err := TryLocked(ctx, db, "job", func(tx DB) error {
+		ran++
+		// A second session cannot take the lock while it is held.
+		inner, err := TryLocked(ctx, db, "job", func(DB) error {
+			ran++
+			return nil
+		})
+		if err != nil || inner {
+			t.Errorf("expected the lock to be held, got %v %v", inner, err)
+		}
+		return nil
+	})
+	if err != nil || !locked || ran != 1 {
+		t.Fatalf("unexpected result: %v %v %v", locked, err, ran)
+	}
+	if locked, err := TryLocked(ctx, db, "job", func(DB) error { return nil }); err != nil || !locked {
+		t.Fatalf("expected the lock to be released, got %v %v", locked, err)
+	}
+}
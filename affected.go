@@ -0,0 +1,83 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Errors returned by ExecOne.
+var (
+	ErrNoRowsAffected      = errors.New("sqln: no rows affected")
+	ErrTooManyRowsAffected = errors.New("sqln: more than one row affected")
+)
+
+// ExecOne executes a statement that must change exactly one row, ie. an
+// UPDATE ... WHERE id = :id. ErrNoRowsAffected or ErrTooManyRowsAffected is
+// returned otherwise.
+// NOTE: A statement affecting too many rows is not rolled back; run ExecOne in
+// a transaction where that matters.
+func ExecOne(ctx context.Context, db DB, query string, params interface{}) (sql.Result, error) {
+	res, err := db.Exec(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, errors.Wrap(err, "rows affected")
+	}
+	switch {
+	case n == 0:
+		return res, ErrNoRowsAffected
+	case n > 1:
+		return res, ErrTooManyRowsAffected
+	}
+	return res, nil
+}
+
+// ExecResult is the result of ExecX.
+type ExecResult struct {
+	RowsAffected int64
+	// LastInsertID is only set where the driver supports it (not lib/pq or
+	// pgx, which need a RETURNING clause), as reported by HasLastInsertID.
+	LastInsertID    int64
+	HasLastInsertID bool
+	Duration        time.Duration
+	// Name is the query name, if any (see QueryName).
+	Name string
+	// Token is the consistency token of the write when run through a
+	// Replicated DB, see WaitForToken. It is empty otherwise, or if it
+	// could not be read.
+	Token ConsistencyToken
+}
+
+// ExecX executes a statement like Exec, returning the rows affected and
+// last insert id rather than a sql.Result. Through a Replicated DB it also
+// returns a consistency token of the write, which costs a query on the
+// primary.
+func ExecX(ctx context.Context, db DB, query string, params interface{}) (ExecResult, error) {
+	r := ExecResult{Name: QueryName(ctx, query)}
+	start := time.Now()
+	res, err := db.Exec(ctx, query, params)
+	r.Duration = time.Since(start)
+	if err != nil {
+		return r, err
+	}
+	if r.RowsAffected, err = res.RowsAffected(); err != nil {
+		return r, errors.Wrap(err, "rows affected")
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		r.LastInsertID, r.HasLastInsertID = id, true
+	}
+	if rep := replicatedOf(db); rep != nil && !InTx(db) {
+		r.Token, _ = rep.Token(ctx)
+	}
+	return r, nil
+}
+
+// ExecX executes a statement, see the ExecX function.
+func (d *Database) ExecX(ctx context.Context, query string, params interface{}) (ExecResult, error) {
+	return ExecX(ctx, d, query, params)
+}
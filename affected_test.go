@@ -0,0 +1,52 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+)
+
+func TestExecOne(t *testing.T) {
+	db := sqliteDB(t)
+
+	ctx := context.Background()
+	for _, q := range []string{
+		"CREATE TABLE affected_abc (id INTEGER PRIMARY KEY, status TEXT);",
+		"INSERT INTO affected_abc VALUES (1, 'a'), (2, 'a');",
+	} {
+		if _, err := db.Exec(ctx, q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const update = "UPDATE affected_abc SET status = 'b' WHERE id = :id OR :all;"
+	cases := []struct {
+		id  int
+		all bool
+		err error
+	}{
+		{id: 1, err: nil},
+		{id: 3, err: ErrNoRowsAffected},
+		{id: 1, all: true, err: ErrTooManyRowsAffected},
+	}
+	for _, c := range cases {
+		if _, err := ExecOne(ctx, db, update, map[string]interface{}{"id": c.id, "all": c.all}); err != c.err {
+			t.Errorf("id %v, all %v: expected %v, got %v", c.id, c.all, c.err, err)
+		}
+	}
+}
+
+func TestExecX(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE exec_x (id INTEGER PRIMARY KEY, name TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := db.ExecX(WithQueryName(ctx, "insert_x"), "INSERT INTO exec_x (name) VALUES ('a'), ('b');", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RowsAffected != 2 || !r.HasLastInsertID || r.LastInsertID != 2 || r.Name != "insert_x" || r.Duration <= 0 {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+}
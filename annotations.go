@@ -0,0 +1,113 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// WithAnnotation returns a context annotating the operations run with it with
+// key and value, ie. a request ID or feature flag. Annotations are attached to
+// audit entries, in-flight operations, slow queries and plans, and, with the
+// SQLComments middleware, to the statements sent to the server.
+func WithAnnotation(ctx context.Context, key, value string) context.Context {
+	prev := Annotations(ctx)
+	next := make(map[string]string, len(prev)+1)
+	for k, v := range prev {
+		next[k] = v
+	}
+	next[key] = value
+	return context.WithValue(ctx, annotationsKey, next)
+}
+
+// Annotations returns the annotations of ctx (see WithAnnotation). The map
+// must not be modified.
+func Annotations(ctx context.Context) map[string]string {
+	a, _ := ctx.Value(annotationsKey).(map[string]string)
+	return a
+}
+
+// SQLComments returns a middleware appending the annotations of each
+// operation's context to its query as a sqlcommenter comment, ie.
+//
+//	SELECT * FROM users /*request_id='abc',route='%2Fusers'*/;
+//
+// so they show up in server logs and pg_stat_activity.
+// NOTE: Each distinct comment is a distinct statement, so annotated queries
+// should not be prepared (see WithUnprepared and ConnStmts) unless the
+// annotations take few values.
+func SQLComments() Middleware {
+	return func(db DB) DB {
+		return &commentDB{DB: db}
+	}
+}
+
+type commentDB struct {
+	DB
+}
+
+func (d *commentDB) Unwrap() DB { return d.DB }
+
+func (d *commentDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	return d.DB.Exec(ctx, comment(ctx, query), params)
+}
+
+func (d *commentDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	return d.DB.ExecReturning(ctx, comment(ctx, query), dest, params)
+}
+
+func (d *commentDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	return d.DB.Get(ctx, comment(ctx, query), dest, params)
+}
+
+func (d *commentDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	return d.DB.Select(ctx, comment(ctx, query), dest, params)
+}
+
+func (d *commentDB) Transact(ctx context.Context, opts sql.TxOptions, f func(DB) error) error {
+	return d.DB.Transact(ctx, opts, func(tx DB) error {
+		return f(&commentDB{DB: tx})
+	})
+}
+
+// comment appends the annotations of ctx to query, before any trailing
+// semicolon. Keys and values are URL encoded, so they cannot end the comment
+// or contain quotes.
+func comment(ctx context.Context, query string) string {
+	a := Annotations(ctx)
+	if len(a) == 0 {
+		return query
+	}
+	keys := make([]string, 0, len(a))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	q := strings.TrimRight(query, " \t\r\n")
+	semi := strings.HasSuffix(q, ";")
+	b.WriteString(strings.TrimSuffix(q, ";"))
+	b.WriteString(" /*")
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(commentEscape(k))
+		b.WriteString("='")
+		b.WriteString(commentEscape(a[k]))
+		b.WriteByte('\'')
+	}
+	b.WriteString("*/")
+	if semi {
+		b.WriteByte(';')
+	}
+	return b.String()
+}
+
+// commentEscape URL encodes s as sqlcommenter does, with spaces as %20.
+func commentEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -0,0 +1,53 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestAnnotations(t *testing.T) {
+	ctx := WithAnnotation(context.Background(), "request_id", "abc")
+	child := WithAnnotation(ctx, "route", "/users?all=1 x")
+	if a := Annotations(ctx); len(a) != 1 || a["request_id"] != "abc" {
+		t.Fatalf("expected the parent to be unchanged, got %v", a)
+	}
+
+	cases := map[string]string{
+		"SELECT 1;":       "SELECT 1 /*request_id='abc',route='%2Fusers%3Fall%3D1%20x'*/;",
+		"SELECT 1\n;\n  ": "SELECT 1\n /*request_id='abc',route='%2Fusers%3Fall%3D1%20x'*/;",
+		"SELECT 1":        "SELECT 1 /*request_id='abc',route='%2Fusers%3Fall%3D1%20x'*/",
+	}
+	for q, exp := range cases {
+		if got := comment(child, q); got != exp {
+			t.Errorf("comment(%q): expected %q, got %q", q, exp, got)
+		}
+	}
+	if got := comment(context.Background(), "SELECT 1;"); got != "SELECT 1;" {
+		t.Errorf("expected no comment without annotations, got %q", got)
+	}
+
+	base := sqliteDB(t)
+	db := New(base.X, WithSlowQueryLog(0, 10))
+	defer db.Close()
+	var logged []AuditEntry
+	adb := Wrap(db, Auditor{Log: func(ctx context.Context, e AuditEntry) { logged = append(logged, e) }}.Middleware(), SQLComments())
+
+	if _, err := adb.Exec(child, "CREATE TABLE annotated (id INTEGER);", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := adb.Transact(child, sql.TxOptions{}, func(tx DB) error {
+		var n int
+		return tx.Get(child, "SELECT COUNT(*) FROM annotated;", &n, nil)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(logged) != 1 || logged[0].Annotations["route"] != "/users?all=1 x" {
+		t.Fatalf("unexpected audit entries: %+v", logged)
+	}
+	slow := db.SlowQueries()
+	if len(slow) != 2 || slow[0].Query != "SELECT COUNT(*) FROM annotated /*request_id='abc',route='%2Fusers%3Fall%3D1%20x'*/;" || slow[0].Annotations["request_id"] != "abc" {
+		t.Fatalf("unexpected slow queries: %+v", slow)
+	}
+}
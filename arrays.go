@@ -0,0 +1,79 @@
+package sqln
+
+import (
+	"database/sql/driver"
+	"reflect"
+
+	"github.com/lib/pq"
+)
+
+// Array is a slice that is passed to and scanned from Postgres arrays, ie. in
+// params for "WHERE id = ANY(:ids)" or in dest structs for array columns.
+// Slices in map params are wrapped for Postgres automatically.
+type Array[T any] []T
+
+// Value implements driver.Valuer.
+func (a Array[T]) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	return pq.Array([]T(a)).Value()
+}
+
+// Scan implements sql.Scanner.
+func (a *Array[T]) Scan(src interface{}) error {
+	if src == nil {
+		*a = nil
+		return nil
+	}
+	var s []T
+	if err := pq.Array(&s).Scan(src); err != nil {
+		return err
+	}
+	*a = s
+	return nil
+}
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// arrayParams wraps the slices in map params as Postgres arrays, copying the
+// map if any are found.
+func (d *Database) arrayParams(params interface{}) interface{} {
+	if d.dialect != Postgres {
+		return params
+	}
+	v := reflect.Indirect(reflect.ValueOf(params))
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return params
+	}
+
+	var m map[string]interface{}
+	iter := v.MapRange()
+	for iter.Next() {
+		if !isArrayParam(iter.Value()) {
+			continue
+		}
+		if m == nil {
+			m = make(map[string]interface{}, v.Len())
+			inner := v.MapRange()
+			for inner.Next() {
+				m[inner.Key().String()] = inner.Value().Interface()
+			}
+		}
+		m[iter.Key().String()] = pq.Array(iter.Value().Interface())
+	}
+	if m == nil {
+		return params
+	}
+	return m
+}
+
+func isArrayParam(v reflect.Value) bool {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return false
+	}
+	return !v.Type().Implements(valuerType)
+}
@@ -0,0 +1,69 @@
+package sqln
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestArray(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	// SQLite stores the Postgres array literal as text, which is enough to
+	// round trip it.
+	if _, err := db.Exec(ctx, "CREATE TABLE tagged (id INTEGER, tags TEXT, scores TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+	type row struct {
+		ID     int            `db:"id"`
+		Tags   Array[string]  `db:"tags"`
+		Scores Array[float64] `db:"scores"`
+	}
+	in := row{ID: 1, Tags: Array[string]{"a", "b,c"}, Scores: Array[float64]{1.5, 2}}
+	if _, err := db.Exec(ctx, "INSERT INTO tagged (id, tags, scores) VALUES (:id, :tags, :scores);", in); err != nil {
+		t.Fatal(err)
+	}
+	var out row
+	if err := db.Get(ctx, "SELECT id, tags, scores FROM tagged;", &out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("expected %+v, got %+v", in, out)
+	}
+
+	var null Array[int64]
+	if err := null.Scan(nil); err != nil || null != nil {
+		t.Fatalf("unexpected null scan: %v %v", null, err)
+	}
+	if v, err := null.Value(); err != nil || v != nil {
+		t.Fatalf("unexpected null value: %v %v", v, err)
+	}
+}
+
+func TestArrayParams(t *testing.T) {
+	d := &Database{dialect: Postgres}
+	params := map[string]interface{}{"ids": []int64{1, 2}, "name": "a", "data": []byte("x"), "tags": Array[string]{"a"}}
+	got := d.arrayParams(params).(map[string]interface{})
+	if !reflect.DeepEqual(got["ids"], pq.Array([]int64{1, 2})) {
+		t.Fatalf("expected ids to be wrapped, got %#v", got["ids"])
+	}
+	for _, k := range []string{"name", "data", "tags"} {
+		if !reflect.DeepEqual(got[k], params[k]) {
+			t.Fatalf("expected %v to be unchanged, got %#v", k, got[k])
+		}
+	}
+	if _, ok := params["ids"].([]int64); !ok {
+		t.Fatal("expected params not to be modified")
+	}
+
+	unchanged := map[string]interface{}{"name": "a"}
+	if got := d.arrayParams(unchanged); reflect.ValueOf(got).Pointer() != reflect.ValueOf(unchanged).Pointer() {
+		t.Fatal("expected params without slices to be returned as is")
+	}
+	if got := (&Database{dialect: SQLite}).arrayParams(params); !reflect.DeepEqual(got, params) {
+		t.Fatal("expected params to be unchanged for sqlite")
+	}
+}
@@ -0,0 +1,160 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WithActor returns a context carrying the user or service responsible for
+// its operations, recorded by the audit middleware.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// ActorFromContext returns the actor set by WithActor.
+func ActorFromContext(ctx context.Context) (string, bool) {
+	a, ok := ctx.Value(actorKey).(string)
+	return a, ok
+}
+
+// AuditEntry records a write.
+type AuditEntry struct {
+	Time  time.Time `db:"at"`
+	Actor string    `db:"actor"`
+	Name  string    `db:"name"`
+	Query string    `db:"query"`
+	// Tables is a comma separated list of the tables written (see
+	// MutatedTables).
+	Tables string `db:"tables"`
+	// RowsAffected is -1 when unknown, ie. for ExecReturning.
+	RowsAffected int64 `db:"rows_affected"`
+	// Annotations are those of the write's context (see WithAnnotation).
+	// They are not inserted into Table.
+	Annotations map[string]string `db:"-"`
+}
+
+// Auditor records every Exec and ExecReturning. At least one of Log and
+// Table should be set.
+type Auditor struct {
+	// Log is called for each write, once its transaction (if any) commits.
+	Log func(ctx context.Context, e AuditEntry)
+	// Table is inserted into in the same transaction as each write, so a
+	// write is never committed without its audit row. Writes outside a
+	// transaction are run in one. The table needs the columns at, actor,
+	// name, query, tables and rows_affected.
+	Table string
+	// Name resolves query names, ie. (*Queries).NameOf. Optional.
+	Name func(query string) (string, bool)
+	// Clock defaults to SystemClock.
+	Clock Clock
+}
+
+// Middleware returns the audit middleware.
+func (a Auditor) Middleware() Middleware {
+	if a.Clock == nil {
+		a.Clock = SystemClock
+	}
+	var insert string
+	if a.Table != "" {
+		insert = fmt.Sprintf("INSERT INTO %v (at, actor, name, query, tables, rows_affected) VALUES (:at, :actor, :name, :query, :tables, :rows_affected);", a.Table)
+	}
+	return func(db DB) DB {
+		return &auditDB{DB: db, a: a, insert: insert}
+	}
+}
+
+func (a Auditor) entry(ctx context.Context, query string, rows int64) AuditEntry {
+	e := AuditEntry{
+		Time:         a.Clock.Now(),
+		Query:        query,
+		Tables:       strings.Join(MutatedTables(query), ","),
+		RowsAffected: rows,
+	}
+	e.Actor, _ = ActorFromContext(ctx)
+	e.Annotations = Annotations(ctx)
+	e.Name = QueryName(ctx, query)
+	if a.Name != nil && e.Name == "" {
+		e.Name, _ = a.Name(query)
+	}
+	return e
+}
+
+type auditDB struct {
+	DB
+	a      Auditor
+	insert string
+
+	// pending is set inside transactions to the entries to log on commit.
+	pending *[]AuditEntry
+}
+
+func (d *auditDB) Unwrap() DB { return d.DB }
+
+// audited runs write, in a transaction when entries are inserted into the
+// audit table, and records its entry.
+func (d *auditDB) audited(ctx context.Context, query string, write func(DB) (int64, error)) error {
+	if d.insert != "" && d.pending == nil {
+		return d.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+			return tx.(*auditDB).audited(ctx, query, write)
+		})
+	}
+
+	rows, err := write(d.DB)
+	if err != nil {
+		return err
+	}
+	e := d.a.entry(ctx, query, rows)
+	if d.insert != "" {
+		if _, err := d.DB.Exec(ctx, d.insert, e); err != nil {
+			return err
+		}
+	}
+	if d.a.Log != nil {
+		if d.pending != nil {
+			*d.pending = append(*d.pending, e)
+		} else {
+			d.a.Log(ctx, e)
+		}
+	}
+	return nil
+}
+
+func (d *auditDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	var res sql.Result
+	err := d.audited(ctx, query, func(db DB) (int64, error) {
+		var err error
+		res, err = db.Exec(ctx, query, params)
+		if err != nil {
+			return 0, err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return -1, nil
+		}
+		return rows, nil
+	})
+	return res, err
+}
+
+func (d *auditDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	return d.audited(ctx, query, func(db DB) (int64, error) {
+		return -1, db.ExecReturning(ctx, query, dest, params)
+	})
+}
+
+func (d *auditDB) Transact(ctx context.Context, opts sql.TxOptions, f func(DB) error) error {
+	var pending []AuditEntry
+	if err := d.DB.Transact(ctx, opts, func(tx DB) error {
+		pending = pending[:0]
+		return f(&auditDB{DB: tx, a: d.a, insert: d.insert, pending: &pending})
+	}); err != nil {
+		return err
+	}
+	for _, e := range pending {
+		d.a.Log(ctx, e)
+	}
+	return nil
+}
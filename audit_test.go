@@ -0,0 +1,59 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestAuditor(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := WithActor(context.Background(), "alice")
+	for _, q := range []string{
+		"CREATE TABLE audit_abc (id INTEGER PRIMARY KEY);",
+		"CREATE TABLE audit_log (at TIMESTAMP, actor TEXT, name TEXT, query TEXT, tables TEXT, rows_affected INTEGER);",
+	} {
+		if _, err := db.Exec(ctx, q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const insert = "INSERT INTO audit_abc (id) VALUES (:id);"
+	var logged []AuditEntry
+	adb := Wrap(db, Auditor{
+		Log:   func(ctx context.Context, e AuditEntry) { logged = append(logged, e) },
+		Table: "audit_log",
+		Clock: NewTestClock(time.Unix(0, 0)),
+	}.Middleware())
+
+	if _, err := adb.Exec(ctx, insert, map[string]interface{}{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(logged) != 1 || logged[0].Actor != "alice" || logged[0].Tables != "audit_abc" || logged[0].RowsAffected != 1 {
+		t.Fatalf("unexpected entries: %+v", logged)
+	}
+
+	err := adb.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		if _, err := tx.Exec(ctx, insert, map[string]interface{}{"id": 2}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	if err == nil {
+		t.Fatal("expected rollback")
+	}
+	if len(logged) != 1 {
+		t.Fatalf("expected rolled back writes not to be logged, got %+v", logged)
+	}
+
+	var rows []AuditEntry
+	if err := db.Select(ctx, "SELECT * FROM audit_log;", &rows, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Actor != "alice" || rows[0].Query != insert {
+		t.Fatalf("unexpected audit rows: %+v", rows)
+	}
+}
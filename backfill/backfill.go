@@ -0,0 +1,207 @@
+/*
+Package backfill runs large data migrations over a table in primary key order.
+Each batch of keys is passed to a callback in its own small transaction, which
+also records the progress made, so a backfill that is interrupted (ie. by a
+deploy) resumes after the last committed batch. Progress is recorded in a
+table, created if it does not exist:
+
+	CREATE TABLE sqln_backfills (
+		name       VARCHAR(255) PRIMARY KEY,
+		last_key   BIGINT NOT NULL,
+		rows_done  BIGINT NOT NULL,
+		done       BOOLEAN NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+*/
+package backfill
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+)
+
+// DefaultTable is the table progress is recorded in unless Options.Table is
+// given.
+const DefaultTable = "sqln_backfills"
+
+// Job declares a backfill over Table, which must have an integer Key.
+type Job struct {
+	// Name identifies the progress of the job. Running a job with the same
+	// name resumes it.
+	Name  string
+	Table string
+	// Key is the integer primary key of Table. Defaults to id.
+	Key string
+	// BatchSize is the maximum number of rows per batch. Defaults to 1000.
+	BatchSize int
+}
+
+// Batch is a range of keys: those greater than From and at most To.
+type Batch struct {
+	From, To int64
+	// Rows is the number of rows in the range when it was selected.
+	Rows int64
+}
+
+// Progress of a job, as recorded after every batch.
+type Progress struct {
+	Name    string `db:"name"`
+	LastKey int64  `db:"last_key"`
+	Rows    int64  `db:"rows_done"`
+	Done    bool   `db:"done"`
+	// Batches is the number of batches run by this call to Run.
+	Batches int `db:"-"`
+}
+
+// Options configures Run.
+type Options struct {
+	// Table records progress. Defaults to DefaultTable.
+	Table string
+	// Pause between batches to limit load.
+	Pause time.Duration
+	// TxOptions are the options of each batch's transaction.
+	TxOptions sql.TxOptions
+	// OnBatch is called after each batch is committed, ie. to log progress.
+	OnBatch func(Progress)
+	// Clock sets the time recorded with progress. Defaults to
+	// sqln.SystemClock.
+	Clock sqln.Clock
+}
+
+// Run calls f with every batch of the job's table after the recorded
+// progress, until the table is exhausted, ctx is done or f returns an error.
+// f is called in a transaction which also records the progress, so each batch
+// is applied at most once. Rows inserted behind the progress while the job
+// runs are not visited, and a job that is done is not run again.
+func Run(ctx context.Context, db sqln.DB, job Job, f func(ctx context.Context, tx sqln.DB, b Batch) error, opts Options) (Progress, error) {
+	if job.Name == "" || job.Table == "" {
+		return Progress{}, errors.New("backfill: name and table are required")
+	}
+	if job.Key == "" {
+		job.Key = "id"
+	}
+	if job.BatchSize <= 0 {
+		job.BatchSize = 1000
+	}
+	if opts.Table == "" {
+		opts.Table = DefaultTable
+	}
+	if opts.Clock == nil {
+		opts.Clock = sqln.SystemClock
+	}
+
+	p, err := load(ctx, db, job.Name, opts.Table)
+	if err != nil {
+		return p, err
+	}
+
+	for !p.Done {
+		if p.Batches > 0 && opts.Pause > 0 {
+			t := time.NewTimer(opts.Pause)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return p, ctx.Err()
+			case <-t.C:
+			}
+		}
+
+		next := p
+		err := db.Transact(ctx, opts.TxOptions, func(tx sqln.DB) error {
+			var r struct {
+				Rows int64         `db:"n"`
+				Last sql.NullInt64 `db:"last_key"`
+			}
+			query := fmt.Sprintf("SELECT COUNT(*) AS n, MAX(k) AS last_key FROM (SELECT %[2]s AS k FROM %[1]s WHERE %[2]s > :from ORDER BY %[2]s LIMIT :limit) batch;",
+				job.Table, job.Key)
+			if err := tx.Get(ctx, query, &r, map[string]interface{}{"from": p.LastKey, "limit": job.BatchSize}); err != nil {
+				return errors.Wrap(err, "selecting batch")
+			}
+
+			if r.Rows > 0 {
+				b := Batch{From: p.LastKey, To: r.Last.Int64, Rows: r.Rows}
+				if err := f(ctx, tx, b); err != nil {
+					return errors.Wrapf(err, "batch (%v, %v]", b.From, b.To)
+				}
+				next.LastKey = b.To
+				next.Rows += b.Rows
+			}
+			next.Done = r.Rows < int64(job.BatchSize)
+			return save(ctx, tx, next, opts)
+		})
+		if err != nil {
+			return p, errors.Wrapf(err, "backfill: %v", job.Name)
+		}
+
+		p = next
+		p.Batches++
+		if opts.OnBatch != nil {
+			opts.OnBatch(p)
+		}
+	}
+	return p, nil
+}
+
+// Reset deletes the recorded progress of the named job, so that it starts
+// over when run again.
+func Reset(ctx context.Context, db sqln.DB, name string, opts Options) error {
+	if opts.Table == "" {
+		opts.Table = DefaultTable
+	}
+	if _, err := db.Exec(ctx, fmt.Sprintf("DELETE FROM %v WHERE name = :name;", opts.Table), map[string]interface{}{"name": name}); err != nil {
+		return errors.Wrapf(err, "backfill: reset %v", name)
+	}
+	return nil
+}
+
+// load creates the progress table if needed and reads the progress of the
+// named job.
+func load(ctx context.Context, db sqln.DB, name, table string) (Progress, error) {
+	p := Progress{Name: name}
+	if _, err := db.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %v (
+	name VARCHAR(255) PRIMARY KEY,
+	last_key BIGINT NOT NULL,
+	rows_done BIGINT NOT NULL,
+	done BOOLEAN NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);`, table), nil); err != nil {
+		return p, errors.Wrap(err, "backfill: creating progress table")
+	}
+
+	err := db.Get(ctx, fmt.Sprintf("SELECT name, last_key, rows_done, done FROM %v WHERE name = :name;", table), &p, map[string]interface{}{"name": name})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return p, errors.Wrapf(err, "backfill: reading progress of %v", name)
+	}
+	return p, nil
+}
+
+func save(ctx context.Context, tx sqln.DB, p Progress, opts Options) error {
+	_, err := tx.Exec(ctx, upsertSQL(dialectOf(tx), opts.Table), map[string]interface{}{
+		"name":      p.Name,
+		"last_key":  p.LastKey,
+		"rows_done": p.Rows,
+		"done":      p.Done,
+		"now":       opts.Clock.Now(),
+	})
+	return errors.Wrap(err, "recording progress")
+}
+
+func dialectOf(db sqln.DB) sqln.Dialect {
+	if d, ok := db.(interface{ Dialect() sqln.Dialect }); ok {
+		return d.Dialect()
+	}
+	return sqln.Postgres
+}
+
+func upsertSQL(dialect sqln.Dialect, table string) string {
+	const insert = "INSERT INTO %v (name, last_key, rows_done, done, updated_at) VALUES (:name, :last_key, :rows_done, :done, :now)"
+	if dialect == sqln.MySQL {
+		return fmt.Sprintf(insert+" ON DUPLICATE KEY UPDATE last_key = VALUES(last_key), rows_done = VALUES(rows_done), done = VALUES(done), updated_at = VALUES(updated_at);", table)
+	}
+	return fmt.Sprintf(insert+" ON CONFLICT (name) DO UPDATE SET last_key = excluded.last_key, rows_done = excluded.rows_done, done = excluded.done, updated_at = excluded.updated_at;", table)
+}
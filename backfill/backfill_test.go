@@ -0,0 +1,77 @@
+package backfill
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+)
+
+func TestRun(t *testing.T) {
+	dbx, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbx.Close()
+	db := sqln.New(dbx)
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, lower_email TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := db.Exec(ctx, "INSERT INTO users (email) VALUES (:email);", map[string]interface{}{"email": "User@Example.com"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	job := Job{Name: "lower_email", Table: "users", BatchSize: 4}
+	var batches []Batch
+	lower := func(ctx context.Context, tx sqln.DB, b Batch) error {
+		if len(batches) == 1 {
+			return errors.New("interrupted")
+		}
+		batches = append(batches, b)
+		_, err := tx.Exec(ctx, "UPDATE users SET lower_email = LOWER(email) WHERE id > :from AND id <= :to;", b)
+		return err
+	}
+
+	// The second batch fails, leaving the first recorded.
+	if p, err := Run(ctx, db, job, lower, Options{}); err == nil || p.LastKey != 4 || p.Rows != 4 || p.Done {
+		t.Fatalf("expected the second batch to fail, got %+v %v", p, err)
+	}
+
+	batches = append(batches, Batch{})
+	var progress []Progress
+	p, err := Run(ctx, db, job, lower, Options{OnBatch: func(p Progress) { progress = append(progress, p) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != (Progress{Name: "lower_email", LastKey: 10, Rows: 10, Done: true, Batches: 2}) || len(progress) != 2 {
+		t.Fatalf("unexpected progress: %+v %+v", p, progress)
+	}
+	if batches[2] != (Batch{From: 4, To: 8, Rows: 4}) || batches[3] != (Batch{From: 8, To: 10, Rows: 2}) {
+		t.Fatalf("unexpected batches: %+v", batches)
+	}
+
+	var left int
+	if err := db.Get(ctx, "SELECT COUNT(*) FROM users WHERE lower_email IS NULL;", &left, nil); err != nil || left != 0 {
+		t.Fatalf("expected every row backfilled, got %v %v", left, err)
+	}
+
+	// A done job is not run again until reset.
+	if p, err := Run(ctx, db, job, lower, Options{}); err != nil || !p.Done || p.Batches != 0 {
+		t.Fatalf("expected the job to be done, got %+v %v", p, err)
+	}
+	if err := Reset(ctx, db, job.Name, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := Run(ctx, db, job, lower, Options{}); err != nil || p.Rows != 10 || p.Batches != 3 {
+		t.Fatalf("expected the job to start over, got %+v %v", p, err)
+	}
+}
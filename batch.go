@@ -0,0 +1,165 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// BatchEntry is a single queued statement. Method is one of Exec, Get or
+// Select; Dest is nil for Exec.
+type BatchEntry struct {
+	Method string
+	Query  string
+	Dest   interface{}
+	Params interface{}
+}
+
+// Batch queues statements to be sent together with SendBatch.
+type Batch struct {
+	Entries []BatchEntry
+}
+
+// Exec queues a statement.
+func (b *Batch) Exec(query string, params interface{}) {
+	b.Entries = append(b.Entries, BatchEntry{Method: "Exec", Query: query, Params: params})
+}
+
+// Get queues a single record query.
+func (b *Batch) Get(query string, dest, params interface{}) {
+	b.Entries = append(b.Entries, BatchEntry{Method: "Get", Query: query, Dest: dest, Params: params})
+}
+
+// Select queues a multiple record query.
+func (b *Batch) Select(query string, dest, params interface{}) {
+	b.Entries = append(b.Entries, BatchEntry{Method: "Select", Query: query, Dest: dest, Params: params})
+}
+
+// BatchResult is the outcome of a single batch entry. Result is only set for
+// Exec entries.
+type BatchResult struct {
+	Result sql.Result
+	Err    error
+}
+
+// Batcher is implemented by DBs that can send a batch in a single round trip.
+type Batcher interface {
+	SendBatch(ctx context.Context, b *Batch) ([]BatchResult, error)
+}
+
+// SendBatch sends b through db, using a single round trip if db implements
+// Batcher. Results are returned per entry along with the first error.
+func SendBatch(ctx context.Context, db DB, b *Batch) ([]BatchResult, error) {
+	if bt, ok := db.(Batcher); ok {
+		return bt.SendBatch(ctx, b)
+	}
+	return sendSequential(ctx, db, b)
+}
+
+// SendBatch runs the queued statements in order. database/sql has no
+// pipelining, so this costs one round trip per entry (using cached
+// statements); it stops at the first error.
+func (d *Database) SendBatch(ctx context.Context, b *Batch) ([]BatchResult, error) {
+	return sendSequential(ctx, d, b)
+}
+
+func sendSequential(ctx context.Context, db DB, b *Batch) ([]BatchResult, error) {
+	results := make([]BatchResult, len(b.Entries))
+	for i, e := range b.Entries {
+		var r BatchResult
+		switch e.Method {
+		case "Exec":
+			r.Result, r.Err = db.Exec(ctx, e.Query, e.Params)
+		case "Get":
+			r.Err = db.Get(ctx, e.Query, e.Dest, e.Params)
+		case "Select":
+			r.Err = db.Select(ctx, e.Query, e.Dest, e.Params)
+		default:
+			r.Err = errors.Errorf("unknown batch method %q", e.Method)
+		}
+		results[i] = r
+
+		if r.Err != nil {
+			err := errors.Wrapf(r.Err, "batch entry %v", i)
+			for j := i + 1; j < len(results); j++ {
+				results[j].Err = errors.New("batch aborted")
+			}
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// Op is a statement run by TransactBatch. If Dest is set it is scanned like
+// Get, or like Select if it is a pointer to a slice.
+type Op struct {
+	Query  string
+	Params interface{}
+	Dest   interface{}
+}
+
+// BatchError is returned by TransactBatch when an op fails.
+type BatchError struct {
+	// Index is the index of the first op that failed.
+	Index int
+	Op    Op
+	Err   error
+	// Results are the outcomes of every op, as returned by SendBatch.
+	Results []BatchResult
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch op %v: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the error of the failed op.
+func (e *BatchError) Unwrap() error { return e.Err }
+
+// TransactBatch runs ops in order within one transaction of db, sent as a
+// single batch (see SendBatch) followed by the commit, for flows of simple
+// statements that do not need the closure of Transact. It returns the result
+// of each op, nil for ops with a Dest. If an op fails the transaction is
+// rolled back and a *BatchError is returned.
+func TransactBatch(ctx context.Context, db DB, opts sql.TxOptions, ops []Op) ([]sql.Result, error) {
+	var b Batch
+	for _, op := range ops {
+		switch {
+		case op.Dest == nil:
+			b.Exec(op.Query, op.Params)
+		case isSlicePtr(op.Dest):
+			b.Select(op.Query, op.Dest, op.Params)
+		default:
+			b.Get(op.Query, op.Dest, op.Params)
+		}
+	}
+
+	var results []BatchResult
+	err := db.Transact(ctx, opts, func(tx DB) error {
+		var err error
+		results, err = SendBatch(ctx, tx, &b)
+		if err == nil {
+			return nil
+		}
+		for i, r := range results {
+			if r.Err != nil {
+				return &BatchError{Index: i, Op: ops[i], Err: r.Err, Results: results}
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]sql.Result, len(results))
+	for i, r := range results {
+		res[i] = r.Result
+	}
+	return res, nil
+}
+
+// TransactBatch runs ops in one transaction, see the TransactBatch function.
+func (d *Database) TransactBatch(ctx context.Context, opts sql.TxOptions, ops []Op) ([]sql.Result, error) {
+	return TransactBatch(ctx, d, opts, ops)
+}
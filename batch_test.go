@@ -0,0 +1,99 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestSendBatch(t *testing.T) {
+	d := sqliteDB(t)
+	ctx := context.Background()
+
+	if _, err := d.X.Exec("CREATE TABLE batch_abc (id INT PRIMARY KEY, x INT);"); err != nil {
+		t.Fatal("unable to create table:", err)
+	}
+
+	const insert = "INSERT INTO batch_abc (id, x) VALUES (:id, :x);"
+	var (
+		b     Batch
+		x     int
+		count int
+	)
+	b.Exec(insert, map[string]interface{}{"id": 1, "x": 10})
+	b.Exec(insert, map[string]interface{}{"id": 2, "x": 20})
+	b.Get("SELECT x FROM batch_abc WHERE id = :id;", &x, map[string]interface{}{"id": 2})
+	b.Get("SELECT COUNT(*) FROM batch_abc;", &count, nil)
+
+	results, err := SendBatch(ctx, d, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %v", len(results))
+	}
+	if n, _ := results[0].Result.RowsAffected(); n != 1 {
+		t.Fatalf("expected 1 row affected, got %v", n)
+	}
+	if x != 20 || count != 2 {
+		t.Fatalf("unexpected scanned values: x=%v count=%v", x, count)
+	}
+
+	b = Batch{}
+	b.Exec(insert, map[string]interface{}{"id": 1, "x": 10})
+	b.Exec(insert, map[string]interface{}{"id": 3, "x": 30})
+	results, err = SendBatch(ctx, d, &b)
+	if err == nil {
+		t.Fatal("expected duplicate key error")
+	}
+	if results[0].Err == nil || results[1].Err == nil {
+		t.Fatal("expected both entries to report errors")
+	}
+}
+
+func TestTransactBatch(t *testing.T) {
+	d := sqliteDB(t)
+	ctx := context.Background()
+
+	if _, err := d.X.Exec("CREATE TABLE tx_batch_abc (id INT PRIMARY KEY, x INT);"); err != nil {
+		t.Fatal("unable to create table:", err)
+	}
+
+	const insert = "INSERT INTO tx_batch_abc (id, x) VALUES (:id, :x);"
+	var xs []int
+	results, err := d.TransactBatch(ctx, sql.TxOptions{}, []Op{
+		{Query: insert, Params: map[string]interface{}{"id": 1, "x": 10}},
+		{Query: insert, Params: map[string]interface{}{"id": 2, "x": 20}},
+		{Query: "SELECT x FROM tx_batch_abc ORDER BY id;", Dest: &xs},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || results[2] != nil {
+		t.Fatalf("unexpected results: %v", results)
+	}
+	if n, _ := results[1].RowsAffected(); n != 1 {
+		t.Fatalf("expected 1 row affected, got %v", n)
+	}
+	if len(xs) != 2 || xs[1] != 20 {
+		t.Fatalf("unexpected rows: %v", xs)
+	}
+
+	_, err = d.TransactBatch(ctx, sql.TxOptions{}, []Op{
+		{Query: insert, Params: map[string]interface{}{"id": 3, "x": 30}},
+		{Query: insert, Params: map[string]interface{}{"id": 1, "x": 10}},
+	})
+	var berr *BatchError
+	if !errors.As(err, &berr) || berr.Index != 1 || Classify(err) != ClassUniqueViolation {
+		t.Fatalf("expected a unique violation of op 1, got %v", err)
+	}
+	var count int
+	if err := d.Get(ctx, "SELECT COUNT(*) FROM tx_batch_abc;", &count, nil); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expected the batch to be rolled back, got %v rows", count)
+	}
+}
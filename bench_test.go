@@ -0,0 +1,99 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func benchDB(b *testing.B) *Database {
+	db := sqliteDB(b)
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE bench_rows (id INT PRIMARY KEY, x INT);", nil); err != nil {
+		b.Fatal(err)
+	}
+	for _, id := range []int{1, 2, 3} {
+		if _, err := db.Exec(ctx, "INSERT INTO bench_rows VALUES (:id, 10);", map[string]interface{}{"id": id}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return db
+}
+
+type benchRow struct {
+	ID int `db:"id"`
+	X  int `db:"x"`
+}
+
+func BenchmarkExec(b *testing.B) {
+	db := benchDB(b)
+	ctx := context.Background()
+	params := benchRow{ID: 1, X: 10}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := db.Exec(ctx, "UPDATE bench_rows SET x = :x WHERE id = :id;", params); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	db := benchDB(b)
+	ctx := context.Background()
+	params := map[string]interface{}{"id": 1}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var r benchRow
+		if err := db.Get(ctx, "SELECT id, x FROM bench_rows WHERE id = :id;", &r, params); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetNoParams(b *testing.B) {
+	db := benchDB(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var n int
+		if err := db.Get(ctx, "SELECT COUNT(*) FROM bench_rows;", &n, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSelect(b *testing.B) {
+	db := benchDB(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var rows []benchRow
+		if err := db.Select(ctx, "SELECT id, x FROM bench_rows ORDER BY id;", &rows, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetInTx(b *testing.B) {
+	db := benchDB(b)
+	ctx := context.Background()
+	params := map[string]interface{}{"id": 1}
+	b.ReportAllocs()
+	b.ResetTimer()
+	err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		for i := 0; i < b.N; i++ {
+			var r benchRow
+			if err := tx.Get(ctx, "SELECT id, x FROM bench_rows WHERE id = :id;", &r, params); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+}
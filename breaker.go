@@ -0,0 +1,258 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCircuitOpen is returned while a Breaker is open.
+var ErrCircuitOpen = errors.New("sqln: circuit open")
+
+// BreakerState is the state of a Breaker.
+type BreakerState int
+
+// Breaker states.
+const (
+	// BreakerClosed lets every call through.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails every call with ErrCircuitOpen.
+	BreakerOpen
+	// BreakerHalfOpen lets a single trial call through.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// BreakerOptions configures a Breaker.
+type BreakerOptions struct {
+	// ErrorRate is the fraction of failed calls within Window at which the
+	// breaker opens. Defaults to 0.5.
+	ErrorRate float64
+	// MinCalls is the number of calls within Window before the breaker may
+	// open. Defaults to 20.
+	MinCalls int
+	// Window is the period over which calls are counted. Defaults to 10
+	// seconds.
+	Window time.Duration
+	// Slow counts successful calls slower than it as failures, when set.
+	Slow time.Duration
+	// Cooldown is how long the breaker stays open before a trial call.
+	// Defaults to 5 seconds.
+	Cooldown time.Duration
+	// Failure reports whether an error indicates a database incident.
+	// Defaults to connection errors, too many connections and timeouts.
+	Failure func(error) bool
+	// OnStateChange is called on every transition, ie. to log it.
+	OnStateChange func(from, to BreakerState)
+	// Clock defaults to SystemClock.
+	Clock Clock
+}
+
+// Breaker is a circuit breaker which fails calls fast with ErrCircuitOpen
+// once too many of them fail, protecting the pool (and callers' latency)
+// during database incidents.
+type Breaker struct {
+	opts BreakerOptions
+
+	mtx         sync.Mutex
+	state       BreakerState
+	windowStart time.Time
+	calls       int
+	failures    int
+	openedAt    time.Time
+	trial       bool
+}
+
+// NewBreaker returns a closed Breaker.
+func NewBreaker(opts BreakerOptions) *Breaker {
+	if opts.ErrorRate <= 0 {
+		opts.ErrorRate = 0.5
+	}
+	if opts.MinCalls <= 0 {
+		opts.MinCalls = 20
+	}
+	if opts.Window <= 0 {
+		opts.Window = 10 * time.Second
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = 5 * time.Second
+	}
+	if opts.Failure == nil {
+		opts.Failure = incident
+	}
+	if opts.Clock == nil {
+		opts.Clock = SystemClock
+	}
+	return &Breaker{opts: opts, windowStart: opts.Clock.Now()}
+}
+
+// incident is the default BreakerOptions.Failure.
+func incident(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		// Canceled by the caller rather than timed out.
+		return false
+	}
+	switch Classify(err) {
+	case ClassConnection, ClassTooManyConnections, ClassQueryCanceled:
+		return true
+	}
+	return false
+}
+
+// State returns the current state.
+func (b *Breaker) State() BreakerState {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.state == BreakerOpen && b.opts.Clock.Now().Sub(b.openedAt) >= b.opts.Cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// Middleware returns a middleware that guards calls with the breaker. A
+// transaction counts as a single call.
+func (b *Breaker) Middleware() Middleware {
+	return func(db DB) DB {
+		return &breakerDB{DB: db, b: b}
+	}
+}
+
+// allow reports whether a call may proceed, returning a func to report its
+// outcome.
+func (b *Breaker) allow() (func(err error, elapsed time.Duration), error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	now := b.opts.Clock.Now()
+	trial := false
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.opts.Cooldown {
+			return nil, ErrCircuitOpen
+		}
+		b.setLocked(BreakerHalfOpen)
+		fallthrough
+	case BreakerHalfOpen:
+		if b.trial {
+			return nil, ErrCircuitOpen
+		}
+		b.trial, trial = true, true
+	}
+	return func(err error, elapsed time.Duration) {
+		failed := (err != nil && b.opts.Failure(err)) || (err == nil && b.opts.Slow > 0 && elapsed > b.opts.Slow)
+		b.done(trial, failed)
+	}, nil
+}
+
+func (b *Breaker) done(trial, failed bool) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	now := b.opts.Clock.Now()
+	if trial {
+		b.trial = false
+		if failed {
+			b.openedAt = now
+			b.setLocked(BreakerOpen)
+		} else {
+			b.windowStart, b.calls, b.failures = now, 0, 0
+			b.setLocked(BreakerClosed)
+		}
+		return
+	}
+	if b.state != BreakerClosed {
+		return
+	}
+
+	if now.Sub(b.windowStart) >= b.opts.Window {
+		b.windowStart, b.calls, b.failures = now, 0, 0
+	}
+	b.calls++
+	if failed {
+		b.failures++
+	}
+	if b.calls >= b.opts.MinCalls && float64(b.failures) >= b.opts.ErrorRate*float64(b.calls) {
+		b.openedAt = now
+		b.setLocked(BreakerOpen)
+	}
+}
+
+func (b *Breaker) setLocked(s BreakerState) {
+	if s == b.state {
+		return
+	}
+	from := b.state
+	b.state = s
+	if b.opts.OnStateChange != nil {
+		b.opts.OnStateChange(from, s)
+	}
+}
+
+type breakerDB struct {
+	DB
+	b *Breaker
+}
+
+func (d *breakerDB) Unwrap() DB { return d.DB }
+
+func (d *breakerDB) guard(f func() error) error {
+	done, err := d.b.allow()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	err = f()
+	// sql.ErrNoRows is a result rather than a failure.
+	if errors.Is(err, sql.ErrNoRows) {
+		done(nil, time.Since(start))
+	} else {
+		done(err, time.Since(start))
+	}
+	return err
+}
+
+func (d *breakerDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	var res sql.Result
+	err := d.guard(func() error {
+		var err error
+		res, err = d.DB.Exec(ctx, query, params)
+		return err
+	})
+	return res, err
+}
+
+func (d *breakerDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	return d.guard(func() error {
+		return d.DB.Get(ctx, query, dest, params)
+	})
+}
+
+func (d *breakerDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	return d.guard(func() error {
+		return d.DB.Select(ctx, query, dest, params)
+	})
+}
+
+func (d *breakerDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	return d.guard(func() error {
+		return d.DB.ExecReturning(ctx, query, dest, params)
+	})
+}
+
+func (d *breakerDB) Transact(ctx context.Context, opts sql.TxOptions, f func(DB) error) error {
+	return d.guard(func() error {
+		return d.DB.Transact(ctx, opts, f)
+	})
+}
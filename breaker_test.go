@@ -0,0 +1,63 @@
+package sqln
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestBreaker(t *testing.T) {
+	ctx := context.Background()
+	clock := NewTestClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	var transitions []BreakerState
+	b := NewBreaker(BreakerOptions{
+		MinCalls:      4,
+		Cooldown:      time.Second,
+		Clock:         clock,
+		OnStateChange: func(from, to BreakerState) { transitions = append(transitions, to) },
+	})
+	flaky := &flakyDB{DB: sqliteDB(t), failures: 2, err: driver.ErrBadConn}
+	db := Wrap(flaky, b.Middleware())
+
+	var n int
+	for i := 0; i < 4; i++ {
+		db.Get(ctx, "SELECT 1;", &n, nil)
+	}
+	if b.State() != BreakerOpen {
+		t.Fatalf("expected open breaker, got %v", b.State())
+	}
+	if err := db.Get(ctx, "SELECT 1;", &n, nil); !errors.Is(err, ErrCircuitOpen) || flaky.calls != 4 {
+		t.Fatalf("expected fast failure, got %v after %v calls", err, flaky.calls)
+	}
+
+	clock.Advance(time.Second)
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("expected half-open breaker, got %v", b.State())
+	}
+	if err := db.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+		t.Fatalf("expected trial to succeed, got %v", err)
+	}
+	if b.State() != BreakerClosed {
+		t.Fatalf("expected closed breaker, got %v", b.State())
+	}
+	expected := []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerClosed}
+	if len(transitions) != len(expected) {
+		t.Fatalf("unexpected transitions: %v", transitions)
+	}
+	for i, s := range expected {
+		if transitions[i] != s {
+			t.Fatalf("unexpected transitions: %v", transitions)
+		}
+	}
+
+	// Application errors do not count.
+	for i := 0; i < 10; i++ {
+		db.Get(ctx, "SELECT * FROM breaker_missing;", &n, nil)
+	}
+	if b.State() != BreakerClosed {
+		t.Fatalf("expected closed breaker, got %v", b.State())
+	}
+}
@@ -0,0 +1,182 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Sqlizer is implemented by query builders such as squirrel. Builders with a
+// differently named method (ie. goqu's ToSQL) can be adapted with SqlizerFunc.
+type Sqlizer interface {
+	ToSql() (string, []interface{}, error)
+}
+
+// SqlizerFunc adapts a function to a Sqlizer.
+type SqlizerFunc func() (string, []interface{}, error)
+
+// ToSql calls f.
+func (f SqlizerFunc) ToSql() (string, []interface{}, error) {
+	return f()
+}
+
+// Builder is implemented by DBs that run built queries. Built queries are not
+// prepared and cached since dynamic SQL would fill the statement cache.
+type Builder interface {
+	ExecBuilder(ctx context.Context, b Sqlizer) (sql.Result, error)
+	GetBuilder(ctx context.Context, dest interface{}, b Sqlizer) error
+	SelectBuilder(ctx context.Context, dest interface{}, b Sqlizer) error
+}
+
+// ExecBuilder executes a built statement on db. A db that is not a Builder,
+// ie. one decorated by middleware, runs the query through ExecUnprepared as a
+// named query, so the middleware sees it. Placeholders (? or $1) become
+// :arg1, :arg2... and colons are escaped.
+func ExecBuilder(ctx context.Context, db DB, b Sqlizer) (sql.Result, error) {
+	if bd, ok := db.(Builder); ok {
+		return bd.ExecBuilder(ctx, b)
+	}
+	q, params, err := builtNamed(b)
+	if err != nil {
+		return nil, err
+	}
+	return ExecUnprepared(ctx, db, q, params)
+}
+
+// GetBuilder gets a single record with a built query on db, see ExecBuilder.
+func GetBuilder(ctx context.Context, db DB, dest interface{}, b Sqlizer) error {
+	if bd, ok := db.(Builder); ok {
+		return bd.GetBuilder(ctx, dest, b)
+	}
+	q, params, err := builtNamed(b)
+	if err != nil {
+		return err
+	}
+	return GetUnprepared(ctx, db, q, dest, params)
+}
+
+// SelectBuilder selects multiple records with a built query on db, see
+// ExecBuilder.
+func SelectBuilder(ctx context.Context, db DB, dest interface{}, b Sqlizer) error {
+	if bd, ok := db.(Builder); ok {
+		return bd.SelectBuilder(ctx, dest, b)
+	}
+	q, params, err := builtNamed(b)
+	if err != nil {
+		return err
+	}
+	return SelectUnprepared(ctx, db, q, dest, params)
+}
+
+// builtNamed renders b as a query with named params, as taken by the DB
+// methods. Placeholders (? or $1) outside of quotes become :arg1, :arg2... and
+// colons are escaped, so casts and literals are left alone.
+func builtNamed(b Sqlizer) (string, map[string]interface{}, error) {
+	q, args, err := b.ToSql()
+	if err != nil {
+		return "", nil, errors.Wrap(err, "building query")
+	}
+	params := make(map[string]interface{}, len(args))
+	param := func(n int) (string, error) {
+		if n < 1 || n > len(args) {
+			return "", errors.Errorf("building query: placeholder %v of %v args", n, len(args))
+		}
+		name := "arg" + strconv.Itoa(n)
+		params[name] = args[n-1]
+		return ":" + name, nil
+	}
+
+	var (
+		sb   strings.Builder
+		next int
+	)
+	for i := 0; i < len(q); i++ {
+		switch c := q[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			j := skipQuoted(q, i, c)
+			if j >= len(q) {
+				j = len(q) - 1
+			}
+			sb.WriteString(strings.ReplaceAll(q[i:j+1], ":", "::"))
+			i = j
+		case c == ':':
+			sb.WriteString("::")
+		case c == '?':
+			next++
+			name, err := param(next)
+			if err != nil {
+				return "", nil, err
+			}
+			sb.WriteString(name)
+		case c == '$' && i+1 < len(q) && q[i+1] >= '0' && q[i+1] <= '9' && (i == 0 || !isIdentByte(q[i-1])):
+			j := i + 1
+			for j < len(q) && q[j] >= '0' && q[j] <= '9' {
+				j++
+			}
+			n, _ := strconv.Atoi(q[i+1 : j])
+			name, err := param(n)
+			if err != nil {
+				return "", nil, err
+			}
+			sb.WriteString(name)
+			i = j - 1
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), params, nil
+}
+
+// ExecBuilder executes a built statement without preparing it. It is
+// tracked, timed and checked like ExecPositional.
+// NOTE: Queries using ? placeholders are rebound for the dialect.
+func (d *Database) ExecBuilder(ctx context.Context, b Sqlizer) (sql.Result, error) {
+	var res sql.Result
+	err := d.built(ctx, "Exec", b, func(ctx context.Context, q positionalQuerier, query string, args []interface{}) error {
+		var err error
+		res, err = q.ExecContext(ctx, query, args...)
+		return err
+	})
+	return res, err
+}
+
+// GetBuilder gets a single record with a built query, without preparing it.
+func (d *Database) GetBuilder(ctx context.Context, dest interface{}, b Sqlizer) error {
+	return d.built(ctx, "Get", b, func(ctx context.Context, q positionalQuerier, query string, args []interface{}) error {
+		return sqlx.GetContext(ctx, q, dest, query, args...)
+	})
+}
+
+// SelectBuilder selects multiple records with a built query, without
+// preparing it.
+func (d *Database) SelectBuilder(ctx context.Context, dest interface{}, b Sqlizer) error {
+	return d.built(ctx, "Select", b, func(ctx context.Context, q positionalQuerier, query string, args []interface{}) error {
+		return sqlx.SelectContext(ctx, q, dest, query, args...)
+	})
+}
+
+// built builds b and runs it unprepared with f, see runPositional.
+func (d *Database) built(ctx context.Context, method string, b Sqlizer, f func(context.Context, positionalQuerier, string, []interface{}) error) error {
+	q, args, err := b.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "building query")
+	}
+	return d.runPositional(ctx, method, q, false, func(ctx context.Context, pq positionalQuerier, query string) error {
+		return f(ctx, pq, query, args)
+	})
+}
+
+// ext returns the transaction, if any, or the pool for unprepared queries.
+func (d *Database) ext() sqlx.ExtContext {
+	if d.tx != nil {
+		return d.tx
+	}
+	if d.conn != nil {
+		return d.conn
+	}
+	return d.drv
+}
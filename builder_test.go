@@ -0,0 +1,77 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	db := sqliteDB(t)
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE builder_abc (id INTEGER PRIMARY KEY, name TEXT NOT NULL);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	built := func(q string, args ...interface{}) Sqlizer {
+		return SqlizerFunc(func() (string, []interface{}, error) { return q, args, nil })
+	}
+
+	if err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		_, err := ExecBuilder(ctx, tx, built("INSERT INTO builder_abc (id, name) VALUES (?, ?), (?, ?);", 1, "a", 2, "b"))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	if err := SelectBuilder(ctx, db, &names, built("SELECT name FROM builder_abc WHERE id >= ? ORDER BY id;", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Fatalf("unexpected names: %v", names)
+	}
+	var name string
+	if err := GetBuilder(ctx, db, &name, built("SELECT name FROM builder_abc WHERE id = ?;", 2)); err != nil || name != "b" {
+		t.Fatalf("expected b, got %q (%v)", name, err)
+	}
+
+	if n := db.cache.len(); n != 1 {
+		t.Fatalf("expected only the CREATE statement to be cached, got %v", n)
+	}
+
+	// Middleware sees built queries, as named queries.
+	var seen []string
+	var spy func(DB) DB
+	spy = func(db DB) DB { return &builderSpyDB{BaseDB: BaseDB{DB: db, Wrap: spy}, seen: &seen} }
+	if err := GetBuilder(ctx, spy(db), &name, built("SELECT name || ':' || ? FROM builder_abc WHERE id = $2 AND name <> '?';", "x", 1)); err != nil || name != "a:x" {
+		t.Fatalf("expected a:x, got %q (%v)", name, err)
+	}
+	if len(seen) != 1 || seen[0] != "SELECT name || '::' || :arg1 FROM builder_abc WHERE id = :arg2 AND name <> '?';" {
+		t.Fatalf("expected the middleware to see the built query, got %q", seen)
+	}
+
+	strict := New(db.X, WithStrict(StrictOptions{Allowlist: true}), WithAllowlist("SELECT name FROM builder_abc WHERE id = ?;"))
+	if err := GetBuilder(ctx, strict, &name, built("SELECT name FROM builder_abc WHERE id = ?;", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExecBuilder(ctx, strict, built("DELETE FROM builder_abc;")); err != ErrQueryNotAllowed {
+		t.Fatalf("expected ErrQueryNotAllowed, got %v", err)
+	}
+
+	strict.Close()
+	if err := GetBuilder(ctx, strict, &name, built("SELECT name FROM builder_abc WHERE id = ?;", 1)); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+type builderSpyDB struct {
+	BaseDB
+	seen *[]string
+}
+
+func (d *builderSpyDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	*d.seen = append(*d.seen, query)
+	return d.DB.Get(ctx, query, dest, params)
+}
@@ -0,0 +1,197 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// maxBulkParams bounds the number of bind parameters in a single multi-row
+// INSERT (SQLite's default limit is the lowest of the supported drivers).
+const maxBulkParams = 999
+
+// BulkInsert loads rows into table. Each row must have one value per column.
+// For Postgres it uses COPY FROM (within the current transaction, or a new
+// one), otherwise it falls back to batched multi-row INSERT statements. On
+// pgx's database/sql driver it uses pgx's CopyFrom, except within a
+// transaction, whose pgx connection cannot be reached, where it falls back to
+// INSERT statements.
+// NOTE: table and columns are not escaped for the INSERT fallback and must not
+// come from untrusted input.
+func (d *Database) BulkInsert(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error) {
+	if len(columns) == 0 {
+		return 0, errors.New("bulk insert: no columns")
+	}
+	for i, r := range rows {
+		if len(r) != len(columns) {
+			return 0, errors.Errorf("bulk insert: row %v has %v values, expected %v", i, len(r), len(columns))
+		}
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	if d.dialect == Postgres && d.tx == nil && !d.isPgx() {
+		// lib/pq's COPY FROM runs within a transaction.
+		var n int64
+		err := d.Transact(ctx, sql.TxOptions{}, func(db DB) error {
+			txd, ok := databaseOf(db)
+			if !ok {
+				return errors.Errorf("bulk insert: %T is not a Database", db)
+			}
+			var err error
+			n, err = txd.BulkInsert(ctx, table, columns, rows)
+			return err
+		})
+		return n, err
+	}
+
+	query := d.bulkQuery(table, columns)
+	ctx, done, err := d.begin(ctx, "BulkInsert", query)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+	if err := d.checkQuery(ctx, query); err != nil {
+		return 0, err
+	}
+	if !d.copies() {
+		return insertMulti(ctx, d.ext().ExecContext, d.drv.Rebind, table, columns, rows)
+	}
+	if d.isPgx() {
+		return d.copyFrom(ctx, table, columns, pgx.CopyFromRows(rows))
+	}
+	return copyIn(ctx, d.tx, table, columns, rows)
+}
+
+// copies reports whether BulkInsert uses COPY FROM rather than INSERT
+// statements: on Postgres, except within transactions on pgx's driver.
+func (d *Database) copies() bool {
+	return d.dialect == Postgres && !(d.tx != nil && d.isPgx())
+}
+
+// bulkQuery returns the statement BulkInsert is tracked and checked as (see
+// WithStrict): the COPY FROM statement of table, or a single-row INSERT.
+func (d *Database) bulkQuery(table string, columns []string) string {
+	if d.copies() {
+		return copyInQuery(table, columns)
+	}
+	return d.drv.Rebind("INSERT INTO " + table + " (" + strings.Join(columns, ",") + ") VALUES (" + strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",") + ")")
+}
+
+// isPgx reports whether d runs on pgx's database/sql driver, which does not
+// support lib/pq's COPY FROM statements.
+func (d *Database) isPgx() bool {
+	db, ok := d.drv.(interface{ Driver() driver.Driver })
+	if !ok {
+		return false
+	}
+	_, ok = db.Driver().(*stdlib.Driver)
+	return ok
+}
+
+// copyFrom loads rows with pgx's CopyFrom on a connection of the pool, or the
+// connection d is pinned to. A single COPY is atomic on its own.
+func (d *Database) copyFrom(ctx context.Context, table string, columns []string, rows pgx.CopyFromSource) (int64, error) {
+	var c *sqlx.Conn
+	if d.conn != nil {
+		c = d.conn.Conn
+	} else {
+		var err error
+		c, err = d.drv.Connx(ctx)
+		if err != nil {
+			return 0, errors.Wrap(err, "copy: conn")
+		}
+		defer c.Close()
+	}
+
+	var n int64
+	err := c.Raw(func(dc interface{}) error {
+		pc, ok := dc.(*stdlib.Conn)
+		if !ok {
+			return errors.Errorf("%T is not a pgx connection", dc)
+		}
+		var err error
+		n, err = pc.Conn().CopyFrom(ctx, pgx.Identifier(strings.SplitN(table, ".", 2)), columns, rows)
+		return err
+	})
+	return n, errors.Wrap(err, "copy")
+}
+
+func copyIn(ctx context.Context, tx *sqlx.Tx, table string, columns []string, rows [][]interface{}) (int64, error) {
+	stmt, err := tx.PrepareContext(ctx, copyInQuery(table, columns))
+	if err != nil {
+		return 0, errors.Wrap(err, "copy: prepare")
+	}
+	defer stmt.Close()
+
+	for i, r := range rows {
+		if _, err := stmt.ExecContext(ctx, r...); err != nil {
+			return 0, errors.Wrapf(err, "copy: row %v", i)
+		}
+	}
+
+	res, err := stmt.ExecContext(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "copy: flush")
+	}
+	return res.RowsAffected()
+}
+
+// copyInQuery returns the COPY FROM statement of table, which may be
+// qualified by a schema.
+func copyInQuery(table string, columns []string) string {
+	if i := strings.Index(table, "."); i >= 0 {
+		return pq.CopyInSchema(table[:i], table[i+1:], columns...)
+	}
+	return pq.CopyIn(table, columns...)
+}
+
+func insertMulti(ctx context.Context, exec func(context.Context, string, ...interface{}) (sql.Result, error), rebind func(string) string, table string, columns []string, rows [][]interface{}) (int64, error) {
+	perBatch := maxBulkParams / len(columns)
+	if perBatch == 0 {
+		perBatch = 1
+	}
+
+	tuple := "(" + strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",") + ")"
+	prefix := "INSERT INTO " + table + " (" + strings.Join(columns, ",") + ") VALUES "
+
+	var total int64
+	for start := 0; start < len(rows); start += perBatch {
+		end := start + perBatch
+		if end > len(rows) {
+			end = len(rows)
+		}
+		batch := rows[start:end]
+
+		var sb strings.Builder
+		sb.WriteString(prefix)
+		args := make([]interface{}, 0, len(batch)*len(columns))
+		for i, r := range batch {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(tuple)
+			args = append(args, r...)
+		}
+
+		res, err := exec(ctx, rebind(sb.String()), args...)
+		if err != nil {
+			return total, errors.Wrapf(err, "insert rows %v-%v", start, end-1)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+
+	return total, nil
+}
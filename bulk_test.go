@@ -0,0 +1,156 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nstogner/psqlxtest"
+)
+
+func TestBulkInsert(t *testing.T) {
+	dbx, dropx := psqlxtest.TmpDB(t)
+	defer dropx()
+	d := New(dbx)
+	defer d.Close()
+
+	ctx := context.Background()
+	if _, err := d.X.Exec("DROP TABLE IF EXISTS bulk_abc; CREATE TABLE bulk_abc (id INT PRIMARY KEY, x TEXT);"); err != nil {
+		t.Fatal("unable to create table:", err)
+	}
+
+	var rows [][]interface{}
+	for i := 0; i < 1000; i++ {
+		rows = append(rows, []interface{}{i, "x"})
+	}
+
+	n, err := d.BulkInsert(ctx, "bulk_abc", []string{"id", "x"}, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(rows)) {
+		t.Fatalf("expected %v rows inserted, got %v", len(rows), n)
+	}
+
+	// Conflicting rows inside a transaction roll back with it.
+	err = d.Transact(ctx, sql.TxOptions{}, func(db DB) error {
+		_, err := db.(*Database).BulkInsert(ctx, "bulk_abc", []string{"id", "x"}, rows[:1])
+		return err
+	})
+	if err == nil {
+		t.Fatal("expected duplicate key error")
+	}
+
+	var count int
+	if err := d.Get(ctx, "SELECT COUNT(*) FROM bulk_abc;", &count, nil); err != nil {
+		t.Fatal(err)
+	}
+	if count != len(rows) {
+		t.Fatalf("expected %v rows, got %v", len(rows), count)
+	}
+}
+
+func TestBulkInsertPgx(t *testing.T) {
+	dbx, err := sqlx.Connect("pgx", testDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := New(dbx)
+	defer d.Close()
+	if !d.isPgx() {
+		t.Fatal("expected the pgx driver to be detected")
+	}
+
+	ctx := context.Background()
+	if _, err := d.X.Exec("DROP TABLE IF EXISTS bulk_pgx; CREATE TABLE bulk_pgx (id INT PRIMARY KEY, x TEXT);"); err != nil {
+		t.Fatal("unable to create table:", err)
+	}
+	defer d.X.Exec("DROP TABLE bulk_pgx;")
+
+	var rows [][]interface{}
+	for i := 0; i < 1000; i++ {
+		rows = append(rows, []interface{}{i, "x"})
+	}
+	// CopyFrom outside of a transaction, INSERT statements within one.
+	n, err := d.BulkInsert(ctx, "public.bulk_pgx", []string{"id", "x"}, rows[:500])
+	if err != nil || n != 500 {
+		t.Fatalf("expected 500 rows copied, got %v (%v)", n, err)
+	}
+	if err := d.Transact(ctx, sql.TxOptions{}, func(db DB) error {
+		n, err = db.(*Database).BulkInsert(ctx, "bulk_pgx", []string{"id", "x"}, rows[500:])
+		return err
+	}); err != nil || n != 500 {
+		t.Fatalf("expected 500 rows inserted, got %v (%v)", n, err)
+	}
+
+	// Import streams into CopyFrom too.
+	type row struct {
+		ID int    `db:"id"`
+		X  string `db:"x"`
+	}
+	if n, err := d.Import(ctx, "bulk_pgx", strings.NewReader("id,x\n1000,y\n1001,\n"), ImportOptions{Struct: row{}}); err != nil || n != 2 {
+		t.Fatalf("expected 2 rows imported, got %v (%v)", n, err)
+	}
+
+	var count int
+	if err := d.Get(ctx, "SELECT COUNT(*) FROM bulk_pgx;", &count, nil); err != nil {
+		t.Fatal(err)
+	}
+	if count != len(rows)+2 {
+		t.Fatalf("expected %v rows, got %v", len(rows)+2, count)
+	}
+}
+
+func TestBulkTxMiddleware(t *testing.T) {
+	dbx, dropx := psqlxtest.TmpDB(t)
+	defer dropx()
+	d := New(dbx)
+	defer d.Close()
+
+	// Transactions begun by BulkInsert, Import and BulkJoinSelect pass their
+	// callback a DB decorated by the middleware.
+	ctx := WithTxMiddleware(context.Background(), Guard(GuardOptions{}))
+	if _, err := d.X.Exec("DROP TABLE IF EXISTS bulk_mw; CREATE TABLE bulk_mw (id INT PRIMARY KEY, x TEXT);"); err != nil {
+		t.Fatal("unable to create table:", err)
+	}
+	if _, err := d.BulkInsert(ctx, "bulk_mw", []string{"id", "x"}, [][]interface{}{{1, "a"}, {2, "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Import(ctx, "bulk_mw", strings.NewReader("id,x\n3,c\n"), ImportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	err := d.BulkJoinSelect(ctx, BulkJoin{Type: "INT", Values: []interface{}{1, 3, 5}},
+		"SELECT b.id FROM bulk_mw b JOIN sqln_bulk_join j ON j.v = b.id ORDER BY b.id;", &ids, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[1] != 3 {
+		t.Fatalf("unexpected ids %v", ids)
+	}
+}
+
+func TestBulkInsertChecked(t *testing.T) {
+	base := sqliteDB(t)
+	const insert = "INSERT INTO bulk_checked (id,x) VALUES (?,?)"
+	db := New(base.X, WithStrict(StrictOptions{Allowlist: true}), WithAllowlist(insert))
+	ctx := context.Background()
+	if _, err := db.X.Exec("CREATE TABLE bulk_checked (id INTEGER PRIMARY KEY, x TEXT);"); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := [][]interface{}{{1, "a"}, {2, "b"}}
+	if n, err := db.BulkInsert(ctx, "bulk_checked", []string{"id", "x"}, rows); err != nil || n != 2 {
+		t.Fatalf("expected 2 rows inserted, got %v (%v)", n, err)
+	}
+	if _, err := db.BulkInsert(ctx, "bulk_checked", []string{"x"}, [][]interface{}{{"c"}}); err != ErrQueryNotAllowed {
+		t.Fatalf("expected ErrQueryNotAllowed, got %v", err)
+	}
+
+	db.Close()
+	if _, err := db.BulkInsert(ctx, "bulk_checked", []string{"id", "x"}, rows); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
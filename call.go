@@ -0,0 +1,106 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	callNameRe = regexp.MustCompile(`^\s*[A-Za-z_][A-Za-z0-9_.]*\s*\(`)
+	callOutRe  = regexp.MustCompile(`@([A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+// isCall reports whether call is a single procedure call: a name and a
+// balanced argument list, followed by at most a semicolon. Semicolons,
+// comments and dollar signs are rejected outside of quotes, and backslashes
+// anywhere, since the database could end a quote elsewhere around them.
+func isCall(call string) bool {
+	loc := callNameRe.FindStringIndex(call)
+	if loc == nil {
+		return false
+	}
+	i, depth := loc[1], 1
+	for ; i < len(call) && depth > 0; i++ {
+		switch c := call[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			j := skipQuoted(call, i, c)
+			if j >= len(call) || strings.ContainsRune(call[i:j], '\\') {
+				return false
+			}
+			i = j
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ';' || c == '#' || c == '\\' || c == '$':
+			return false
+		case strings.HasPrefix(call[i:], "--") || strings.HasPrefix(call[i:], "/*"):
+			return false
+		}
+	}
+	rest := strings.TrimSpace(call[i:])
+	return depth == 0 && (rest == "" || rest == ";")
+}
+
+// Call calls a stored procedure, ie. Call(ctx, db, "transfer(:from, :to,
+// :amount, @balance)", &out, params). Arguments are named params as for Exec
+// and OUT parameters are written @name; if dest is not nil it is scanned from
+// the OUT parameters as Get would scan a row with a column per name (a struct
+// for several). The statements go through db, so they are cached and
+// instrumented as any other.
+//
+// On Postgres the OUT parameters are passed as NULL and returned by the CALL.
+// On MySQL they are session variables, which are selected after the CALL on
+// the same connection, in a transaction if db is not already in one. Other
+// dialects are not supported.
+func Call(ctx context.Context, db DB, call string, dest, params interface{}) error {
+	if !isCall(call) {
+		return errors.Errorf("call: invalid procedure call %q", call)
+	}
+	call = strings.TrimSuffix(strings.TrimSpace(call), ";")
+
+	var outs []string
+	for _, m := range callOutRe.FindAllStringSubmatch(call, -1) {
+		outs = append(outs, m[1])
+	}
+
+	switch dialect := DialectOf(db); dialect {
+	case Postgres, UnknownDialect:
+		query := "CALL " + callOutRe.ReplaceAllString(call, "NULL") + ";"
+		if dest == nil || len(outs) == 0 {
+			_, err := db.Exec(ctx, query, params)
+			return errors.Wrap(err, "call")
+		}
+		return errors.Wrap(db.ExecReturning(ctx, query, dest, params), "call")
+
+	case MySQL:
+		query := "CALL " + callOutRe.ReplaceAllString(call, "@sqln_$1") + ";"
+		if dest == nil || len(outs) == 0 {
+			_, err := db.Exec(ctx, query, params)
+			return errors.Wrap(err, "call")
+		}
+		cols := make([]string, len(outs))
+		for i, name := range outs {
+			cols[i] = fmt.Sprintf("@sqln_%v AS %v", name, name)
+		}
+		sel := "SELECT " + strings.Join(cols, ", ") + ";"
+		run := func(tx DB) error {
+			if _, err := tx.Exec(ctx, query, params); err != nil {
+				return err
+			}
+			return tx.Get(ctx, sel, dest, nil)
+		}
+		if InTx(db) {
+			return errors.Wrap(run(db), "call")
+		}
+		return errors.Wrap(db.Transact(ctx, sql.TxOptions{}, run), "call")
+
+	default:
+		return errors.Errorf("call: stored procedures not supported by dialect %q", dialect)
+	}
+}
@@ -0,0 +1,93 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+// callDB records the statements of a dialect that SQLite cannot stand in
+// for, returning 42 for every row scanned.
+type callDB struct {
+	DB
+	dialect Dialect
+	queries []string
+}
+
+func (d *callDB) Dialect() Dialect { return d.dialect }
+func (d *callDB) TxLevel() int     { return 1 }
+func (d *callDB) InTx() bool       { return true }
+
+func (d *callDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	d.queries = append(d.queries, query)
+	return nil, nil
+}
+
+func (d *callDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	d.queries = append(d.queries, query)
+	*dest.(*int) = 42
+	return nil
+}
+
+func (d *callDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	return d.Get(ctx, query, dest, params)
+}
+
+func TestCall(t *testing.T) {
+	ctx := context.Background()
+	params := map[string]interface{}{"from": 1, "to": 2}
+	cases := []struct {
+		dialect Dialect
+		queries []string
+	}{
+		{Postgres, []string{"CALL transfer(:from, :to, NULL);"}},
+		{MySQL, []string{"CALL transfer(:from, :to, @sqln_balance);", "SELECT @sqln_balance AS balance;"}},
+	}
+	for _, c := range cases {
+		db := &callDB{dialect: c.dialect}
+		var balance int
+		if err := Call(ctx, db, "transfer(:from, :to, @balance);", &balance, params); err != nil {
+			t.Fatal(err)
+		}
+		if balance != 42 {
+			t.Errorf("%v: expected balance 42, got %v", c.dialect, balance)
+		}
+		if !reflect.DeepEqual(db.queries, c.queries) {
+			t.Errorf("%v: unexpected queries: %q", c.dialect, db.queries)
+		}
+	}
+
+	db := &callDB{dialect: MySQL}
+	if err := Call(ctx, db, "audit(:from)", nil, params); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(db.queries, []string{"CALL audit(:from);"}) {
+		t.Errorf("unexpected queries: %q", db.queries)
+	}
+
+	if err := Call(ctx, sqliteDB(t), "audit(:from)", nil, params); err == nil {
+		t.Error("expected an error for SQLite")
+	}
+	for _, call := range []string{
+		"audit; DROP TABLE users",
+		"audit(); DROP TABLE x; --()",
+		"audit(:from)); DROP TABLE x; --(",
+		"audit(:from",
+		"audit(')",
+		"audit(:from) -- x",
+		"audit(:from -- '\n); DROP TABLE x; --')",
+		"audit('\\', '); DROP TABLE x; -- ')",
+		"audit($$'$$); DROP TABLE x; --')",
+		"audit(:from);;",
+	} {
+		if err := Call(ctx, db, call, nil, nil); err == nil {
+			t.Errorf("expected an error for %q", call)
+		}
+	}
+	for _, call := range []string{"audit(:from, 'a;b', (1 + 2)) ;", "audit()"} {
+		if !isCall(call) {
+			t.Errorf("expected %q to be a valid call", call)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+/*
+Package cdc captures row changes of selected Postgres tables with triggers
+which record each change, as JSON, in a changes table and notify a channel. A
+Tailer delivers the changes to a callback in commit order, woken by a
+sqln.Listener.
+*/
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+)
+
+// Options names the changes table and notification channel. The zero value
+// uses sqln_changes for both.
+type Options struct {
+	Table   string
+	Channel string
+}
+
+func (o Options) withDefaults() Options {
+	if o.Table == "" {
+		o.Table = "sqln_changes"
+	}
+	if o.Channel == "" {
+		o.Channel = o.Table
+	}
+	return o
+}
+
+var identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+func (o Options) validate(tables []string) error {
+	for _, name := range append([]string{o.Table, o.Channel}, tables...) {
+		if !identRe.MatchString(name) {
+			return errors.Errorf("cdc: invalid name %q", name)
+		}
+	}
+	return nil
+}
+
+// function is the name of the trigger function recording into the changes
+// table.
+func (o Options) function() string {
+	return o.Table + "_capture"
+}
+
+// trigger is the name of the trigger on each table.
+func (o Options) trigger() string {
+	return strings.NewReplacer(".", "_").Replace(o.Table) + "_trigger"
+}
+
+// Install creates the changes table and trigger function if they do not
+// exist, and (re)creates the trigger on each of tables.
+func Install(ctx context.Context, db sqln.DB, opts Options, tables ...string) error {
+	opts = opts.withDefaults()
+	if err := opts.validate(tables); err != nil {
+		return err
+	}
+	if err := sqln.ExecScript(ctx, db, installSQL(opts, tables)); err != nil {
+		return errors.Wrap(err, "cdc: install")
+	}
+	return nil
+}
+
+// Uninstall drops the triggers of tables. The changes table is kept.
+func Uninstall(ctx context.Context, db sqln.DB, opts Options, tables ...string) error {
+	opts = opts.withDefaults()
+	if err := opts.validate(tables); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, t := range tables {
+		fmt.Fprintf(&b, "DROP TRIGGER IF EXISTS %v ON %v;\n", opts.trigger(), t)
+	}
+	if err := sqln.ExecScript(ctx, db, b.String()); err != nil {
+		return errors.Wrap(err, "cdc: uninstall")
+	}
+	return nil
+}
+
+// installSQL returns the script run by Install. Each change records the id of
+// its transaction so a Tailer can wait for it to commit.
+func installSQL(opts Options, tables []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `CREATE TABLE IF NOT EXISTS %[1]v (
+	id BIGSERIAL PRIMARY KEY,
+	tx BIGINT NOT NULL DEFAULT txid_current(),
+	schema_name TEXT NOT NULL,
+	table_name TEXT NOT NULL,
+	op TEXT NOT NULL,
+	new_row JSONB,
+	old_row JSONB,
+	changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS %[3]v_tx_id ON %[1]v (tx, id);
+CREATE OR REPLACE FUNCTION %[4]v() RETURNS trigger AS $cdc$
+BEGIN
+	INSERT INTO %[1]v (schema_name, table_name, op, new_row, old_row) VALUES (
+		TG_TABLE_SCHEMA, TG_TABLE_NAME, TG_OP,
+		CASE WHEN TG_OP <> 'DELETE' THEN to_jsonb(NEW) END,
+		CASE WHEN TG_OP <> 'INSERT' THEN to_jsonb(OLD) END);
+	PERFORM pg_notify('%[2]v', TG_TABLE_NAME);
+	RETURN NULL;
+END;
+$cdc$ LANGUAGE plpgsql;
+`, opts.Table, opts.Channel, strings.NewReplacer(".", "_").Replace(opts.Table), opts.function())
+	for _, t := range tables {
+		fmt.Fprintf(&b, "DROP TRIGGER IF EXISTS %[1]v ON %[2]v;\nCREATE TRIGGER %[1]v AFTER INSERT OR UPDATE OR DELETE ON %[2]v FOR EACH ROW EXECUTE FUNCTION %[3]v();\n",
+			opts.trigger(), t, opts.function())
+	}
+	return b.String()
+}
+
+// Op is the kind of a change.
+type Op string
+
+// Change operations.
+const (
+	Insert Op = "INSERT"
+	Update Op = "UPDATE"
+	Delete Op = "DELETE"
+)
+
+// Position orders changes: by transaction, then by change within it. A Tailer
+// resumes after a saved Position.
+type Position struct {
+	Tx int64 `db:"tx"`
+	ID int64 `db:"id"`
+}
+
+// Change is a captured row change. New is JSON null for deletes and Old for
+// inserts.
+type Change struct {
+	Position
+	Schema string          `db:"schema_name"`
+	Table  string          `db:"table_name"`
+	Op     Op              `db:"op"`
+	New    json.RawMessage `db:"new_row"`
+	Old    json.RawMessage `db:"old_row"`
+	Time   time.Time       `db:"changed_at"`
+}
+
+// TailerOptions configures a Tailer.
+type TailerOptions struct {
+	Options
+	// Listener, if set, wakes the Tailer when changes are made. Without it
+	// (or when notifications are lost) the changes table is polled.
+	Listener *sqln.Listener
+	// Interval between polls. Defaults to one second, or a minute with a
+	// Listener.
+	Interval time.Duration
+	// BatchSize is the maximum number of changes read per query. Defaults to
+	// 100.
+	BatchSize int
+}
+
+// Tailer delivers changes from the changes table.
+type Tailer struct {
+	db   sqln.DB
+	opts TailerOptions
+}
+
+// NewTailer returns a Tailer reading through db.
+func NewTailer(db sqln.DB, opts TailerOptions) *Tailer {
+	opts.Options = opts.Options.withDefaults()
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+		if opts.Listener != nil {
+			opts.Interval = time.Minute
+		}
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	return &Tailer{db: db, opts: opts}
+}
+
+// Run calls f with every change after from, in order, until ctx is done or f
+// returns an error. Changes are only delivered once every transaction that
+// started before them has ended, so none is skipped by a transaction that
+// commits late. The Position of each change delivered should be saved to
+// resume from.
+func (t *Tailer) Run(ctx context.Context, from Position, f func(context.Context, Change) error) error {
+	if err := t.opts.validate(nil); err != nil {
+		return err
+	}
+	var notify <-chan sqln.Notification
+	if t.opts.Listener != nil {
+		c, err := t.opts.Listener.Subscribe(t.opts.Channel)
+		if err != nil {
+			return errors.Wrap(err, "cdc: subscribe")
+		}
+		defer t.opts.Listener.Unsubscribe(t.opts.Channel, c)
+		notify = c
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case _, ok := <-notify:
+			if !ok {
+				notify = nil
+			}
+		case <-timer.C:
+		}
+
+		for {
+			changes, err := t.next(ctx, from)
+			if err != nil {
+				return err
+			}
+			for _, c := range changes {
+				if err := f(ctx, c); err != nil {
+					return err
+				}
+				from = c.Position
+			}
+			if len(changes) < t.opts.BatchSize {
+				break
+			}
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(t.opts.Interval)
+	}
+}
+
+// next reads the changes after from of transactions older than any still in
+// progress.
+func (t *Tailer) next(ctx context.Context, from Position) ([]Change, error) {
+	query := fmt.Sprintf(`SELECT id, tx, schema_name, table_name, op,
+	COALESCE(new_row, 'null') AS new_row, COALESCE(old_row, 'null') AS old_row, changed_at FROM %v
+	WHERE (tx, id) > (:tx, :id) AND tx < txid_snapshot_xmin(txid_current_snapshot())
+	ORDER BY tx, id LIMIT :limit;`, t.opts.Table)
+	var changes []Change
+	err := t.db.Select(ctx, query, &changes, map[string]interface{}{"tx": from.Tx, "id": from.ID, "limit": t.opts.BatchSize})
+	if err != nil {
+		return nil, errors.Wrap(err, "cdc: select changes")
+	}
+	return changes, nil
+}
+
+// Purge deletes changes recorded before cutoff, returning the number deleted.
+func Purge(ctx context.Context, db sqln.DB, opts Options, cutoff time.Time) (int64, error) {
+	opts = opts.withDefaults()
+	if err := opts.validate(nil); err != nil {
+		return 0, err
+	}
+	res, err := db.Exec(ctx, fmt.Sprintf("DELETE FROM %v WHERE changed_at < :cutoff;", opts.Table), map[string]interface{}{"cutoff": cutoff})
+	if err != nil {
+		return 0, errors.Wrap(err, "cdc: purge")
+	}
+	return res.RowsAffected()
+}
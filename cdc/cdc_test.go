@@ -0,0 +1,87 @@
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nstogner/sqln/sqlntest"
+)
+
+func TestInstallSQL(t *testing.T) {
+	opts := Options{Table: "audit.changes"}.withDefaults()
+	if err := opts.validate([]string{"users", "public.orders"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, names := range [][]string{{"users; DROP TABLE x"}, {"a.b.c"}, {""}} {
+		if err := opts.validate(names); err == nil {
+			t.Errorf("expected %q to be rejected", names)
+		}
+	}
+
+	sql := installSQL(opts, []string{"users", "public.orders"})
+	for _, want := range []string{
+		"CREATE TABLE IF NOT EXISTS audit.changes (",
+		"CREATE OR REPLACE FUNCTION audit.changes_capture() RETURNS trigger",
+		"PERFORM pg_notify('audit.changes', TG_TABLE_NAME);",
+		"CREATE TRIGGER audit_changes_trigger AFTER INSERT OR UPDATE OR DELETE ON users FOR EACH ROW EXECUTE FUNCTION audit.changes_capture();",
+		"ON public.orders FOR EACH ROW",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("expected the install script to contain %q:\n%v", want, sql)
+		}
+	}
+}
+
+func TestTailer(t *testing.T) {
+	db := sqlntest.NewPostgres(t, sqlntest.PostgresOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := db.Exec(ctx, "CREATE TABLE cdc_users (id INT PRIMARY KEY, name TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := Install(ctx, db, Options{}, "cdc_users"); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		"INSERT INTO cdc_users VALUES (1, 'a');",
+		"UPDATE cdc_users SET name = 'b' WHERE id = 1;",
+		"DELETE FROM cdc_users WHERE id = 1;",
+	} {
+		if _, err := db.Exec(ctx, q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var changes []Change
+	tailer := NewTailer(db, TailerOptions{Interval: 10 * time.Millisecond})
+	err := tailer.Run(ctx, Position{}, func(ctx context.Context, c Change) error {
+		changes = append(changes, c)
+		if len(changes) == 3 {
+			return context.Canceled
+		}
+		return nil
+	})
+	if err != context.Canceled {
+		t.Fatal(err)
+	}
+	var name struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(changes[1].New, &name); err != nil || name.Name != "b" {
+		t.Fatalf("unexpected update: %s %v", changes[1].New, err)
+	}
+	if changes[0].Op != Insert || changes[2].Op != Delete || string(changes[2].New) != "null" || changes[0].Table != "cdc_users" {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+
+	if err := Uninstall(ctx, db, Options{}, "cdc_users"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := Purge(ctx, db, Options{}, time.Now().Add(time.Hour)); err != nil || n != 3 {
+		t.Fatalf("unexpected purge: %v %v", n, err)
+	}
+}
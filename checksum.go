@@ -0,0 +1,225 @@
+package sqln
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NOTE: Fields tagged with the signed option (ie. `db:"balance,signed"`) are
+// covered by an HMAC stored in the field tagged with the checksum option (ie.
+// `db:"checksum,checksum"`), a []byte or hex encoded string field. Signed
+// values must scan back as they were written, ie. times are compared in UTC
+// and must be truncated to the precision of their column.
+
+// ErrChecksumMismatch is returned for a row whose checksum does not match its
+// signed columns, ie. one changed outside the application.
+var ErrChecksumMismatch = errors.New("sqln: checksum mismatch")
+
+// Checksums returns a middleware that sets the checksum field of struct params
+// for any Exec or ExecReturning, and verifies the checksum of each struct
+// scanned by Get and Select, failing with ErrChecksumMismatch. Place it
+// within (after) Timestamps when timestamps are signed.
+func Checksums(key []byte) Middleware {
+	var wrap func(DB) DB
+	wrap = func(db DB) DB {
+		return &checksumDB{BaseDB: BaseDB{DB: db, Wrap: wrap}, key: key}
+	}
+	return wrap
+}
+
+// SetChecksum sets the checksum field of v, a pointer to a struct.
+func SetChecksum(key []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("set checksum: expected a pointer to a struct")
+	}
+	_, err := signStruct(key, v)
+	return err
+}
+
+// VerifyChecksum returns ErrChecksumMismatch if the checksum field of v (a
+// struct or pointer to one) does not match its signed fields.
+func VerifyChecksum(key []byte, v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return errors.Errorf("verify checksum: expected a struct, got %T", v)
+	}
+	fields, sum := checksumFields(rv.Type())
+	if sum == nil {
+		return errors.Errorf("verify checksum: %v has no checksum field", rv.Type())
+	}
+	want, err := rowChecksum(key, rv, fields)
+	if err != nil {
+		return err
+	}
+	var got []byte
+	switch f := rv.FieldByIndex(sum.index); f.Kind() {
+	case reflect.String:
+		if got, err = hex.DecodeString(f.String()); err != nil {
+			return ErrChecksumMismatch
+		}
+	case reflect.Slice:
+		got = f.Bytes()
+	default:
+		return errors.Errorf("verify checksum: %v: unsupported type %v", sum.path, f.Type())
+	}
+	if !hmac.Equal(got, want) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// checksumFields returns the signed fields of t and its checksum field, nil
+// if it has none.
+func checksumFields(t reflect.Type) ([]reflectField, *reflectField) {
+	var signed []reflectField
+	var sum *reflectField
+	for _, f := range columnFields(defaultMapper, t) {
+		if _, ok := f.Options["signed"]; ok {
+			signed = append(signed, reflectField{index: f.Index, path: f.Path})
+		}
+		if _, ok := f.Options["checksum"]; ok {
+			sum = &reflectField{index: f.Index, path: f.Path}
+		}
+	}
+	return signed, sum
+}
+
+// rowChecksum computes the HMAC of the signed fields of sv.
+func rowChecksum(key []byte, sv reflect.Value, fields []reflectField) ([]byte, error) {
+	mac := hmac.New(sha256.New, key)
+	for _, f := range fields {
+		v := sv.FieldByIndex(f.index).Interface()
+		if valuer, ok := v.(driver.Valuer); ok {
+			var err error
+			if v, err = valuer.Value(); err != nil {
+				return nil, errors.Wrapf(err, "checksum %v", f.path)
+			}
+		}
+		if t, ok := v.(time.Time); ok {
+			v = t.UTC()
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "checksum %v", f.path)
+		}
+		mac.Write([]byte(f.path))
+		mac.Write([]byte{0})
+		mac.Write(b)
+		mac.Write([]byte{0})
+	}
+	return mac.Sum(nil), nil
+}
+
+// signStruct sets the checksum field of v, like stampStruct: a struct that is
+// not a pointer is copied and other params are returned as is.
+func signStruct(key []byte, v interface{}) (interface{}, error) {
+	if v == nil {
+		return v, nil
+	}
+	rv := reflect.ValueOf(v)
+	t := rv.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || reflect.PtrTo(t).Implements(scannerType) {
+		return v, nil
+	}
+	fields, sum := checksumFields(t)
+	if sum == nil {
+		return v, nil
+	}
+	if rv.Kind() != reflect.Ptr {
+		cp := reflect.New(t)
+		cp.Elem().Set(rv)
+		rv, v = cp, cp.Interface()
+	} else if rv.IsNil() {
+		return v, nil
+	}
+	sv := reflect.Indirect(rv)
+	b, err := rowChecksum(key, sv, fields)
+	if err != nil {
+		return nil, err
+	}
+	switch f := sv.FieldByIndex(sum.index); {
+	case f.Kind() == reflect.String:
+		f.SetString(hex.EncodeToString(b))
+	case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Uint8:
+		f.SetBytes(b)
+	default:
+		return nil, errors.Errorf("checksum %v: unsupported type %v", sum.path, f.Type())
+	}
+	return v, nil
+}
+
+// verifyDest verifies the checksums of the structs in dest, a pointer to a
+// struct or to a slice of structs or struct pointers.
+func verifyDest(key []byte, dest interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(dest))
+	t := rv.Type()
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	if _, sum := checksumFields(t); sum == nil {
+		return nil
+	}
+	if rv.Kind() != reflect.Slice {
+		return VerifyChecksum(key, rv.Interface())
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := VerifyChecksum(key, rv.Index(i).Interface()); err != nil {
+			return errors.Wrapf(err, "row %v", i)
+		}
+	}
+	return nil
+}
+
+type checksumDB struct {
+	BaseDB
+	key []byte
+}
+
+func (d *checksumDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	params, err := signStruct(d.key, params)
+	if err != nil {
+		return nil, err
+	}
+	return d.DB.Exec(ctx, query, params)
+}
+
+func (d *checksumDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	params, err := signStruct(d.key, params)
+	if err != nil {
+		return err
+	}
+	return d.DB.ExecReturning(ctx, query, dest, params)
+}
+
+func (d *checksumDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.DB.Get(ctx, query, dest, params); err != nil {
+		return err
+	}
+	return verifyDest(d.key, dest)
+}
+
+func (d *checksumDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.DB.Select(ctx, query, dest, params); err != nil {
+		return err
+	}
+	return verifyDest(d.key, dest)
+}
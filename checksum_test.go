@@ -0,0 +1,66 @@
+package sqln
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestChecksums(t *testing.T) {
+	base := sqliteDB(t)
+	ctx := context.Background()
+	if _, err := base.Exec(ctx, "CREATE TABLE checksum_accounts (id INTEGER PRIMARY KEY, owner TEXT, balance INTEGER, note TEXT, checksum TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+	type account struct {
+		ID       int         `db:"id,signed"`
+		Owner    string      `db:"owner,signed"`
+		Balance  Null[int64] `db:"balance,signed"`
+		Note     string      `db:"note"`
+		Checksum string      `db:"checksum,checksum"`
+	}
+	key := []byte("secret")
+	db := Wrap(base, Checksums(key))
+
+	if _, err := InsertStruct(ctx, db, "checksum_accounts", account{ID: 1, Owner: "a", Balance: NullOf[int64](10)}); err != nil {
+		t.Fatal(err)
+	}
+	a := account{ID: 2, Owner: "b"}
+	if err := SetChecksum(key, &a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := InsertStruct(ctx, base, "checksum_accounts", a); err != nil {
+		t.Fatal(err)
+	}
+
+	const list = "SELECT * FROM checksum_accounts ORDER BY id;"
+	var accounts []account
+	if err := db.Select(ctx, list, &accounts, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 || accounts[0].Checksum == "" || accounts[0].Checksum == accounts[1].Checksum {
+		t.Fatalf("unexpected accounts: %+v", accounts)
+	}
+
+	// Unsigned columns may change.
+	if _, err := base.Exec(ctx, "UPDATE checksum_accounts SET note = 'x' WHERE id = 1;", nil); err != nil {
+		t.Fatal(err)
+	}
+	var got account
+	if err := db.Get(ctx, "SELECT * FROM checksum_accounts WHERE id = 1;", &got, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := base.Exec(ctx, "UPDATE checksum_accounts SET balance = 1000 WHERE id = 2;", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Select(ctx, list, &accounts, nil); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+	if err := db.Get(ctx, "SELECT * FROM checksum_accounts WHERE id = 2;", &got, nil); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+	if err := VerifyChecksum([]byte("other"), accounts[0]); err != ErrChecksumMismatch {
+		t.Fatalf("expected ErrChecksumMismatch with another key, got %v", err)
+	}
+}
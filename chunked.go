@@ -0,0 +1,84 @@
+package sqln
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ChunkOptions configures ExecChunked.
+type ChunkOptions struct {
+	// Sleep pauses between chunks, ie. to let replicas catch up.
+	Sleep time.Duration
+	// MaxChunks stops after the given number of chunks. Zero is unbounded.
+	MaxChunks int
+	// OnChunk is called after every chunk.
+	OnChunk func(ChunkProgress)
+}
+
+// ChunkProgress reports the progress of ExecChunked.
+type ChunkProgress struct {
+	Chunks int
+	// RowsAffected is the total so far.
+	RowsAffected int64
+	// Last is the number of rows affected by the latest chunk.
+	Last int64
+}
+
+// ExecChunked runs a mass DELETE or UPDATE in bounded chunks so that locks are
+// held briefly and writes are spread out. The statement must limit itself to
+// :chunk_size rows, ie.
+//
+//	DELETE FROM events WHERE id IN (
+//		SELECT id FROM events WHERE created_at < :before LIMIT :chunk_size
+//	);
+//
+// and is run until a chunk affects fewer than chunkSize rows. The total
+// number of rows affected is returned, also on error. Each chunk is committed
+// separately unless db is a transaction.
+func ExecChunked(ctx context.Context, db DB, query string, params interface{}, chunkSize int, opts ChunkOptions) (int64, error) {
+	if chunkSize <= 0 {
+		return 0, errors.New("exec chunked: chunk size must be positive")
+	}
+	m, _ := mapperOf(db)
+	params = withParams(m, params, map[string]interface{}{"chunk_size": chunkSize})
+
+	var p ChunkProgress
+	for opts.MaxChunks == 0 || p.Chunks < opts.MaxChunks {
+		if p.Chunks > 0 && opts.Sleep > 0 {
+			t := time.NewTimer(opts.Sleep)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return p.RowsAffected, ctx.Err()
+			case <-t.C:
+			}
+		}
+
+		res, err := db.Exec(ctx, query, params)
+		if err != nil {
+			return p.RowsAffected, errors.Wrapf(err, "chunk %v", p.Chunks+1)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return p.RowsAffected, errors.Wrap(err, "rows affected")
+		}
+		p.Chunks++
+		p.RowsAffected += n
+		p.Last = n
+		if opts.OnChunk != nil {
+			opts.OnChunk(p)
+		}
+		if n < int64(chunkSize) {
+			break
+		}
+	}
+	return p.RowsAffected, nil
+}
+
+// ExecChunked runs a statement in bounded chunks, see the ExecChunked
+// function.
+func (d *Database) ExecChunked(ctx context.Context, query string, params interface{}, chunkSize int, opts ChunkOptions) (int64, error) {
+	return ExecChunked(ctx, d, query, params, chunkSize, opts)
+}
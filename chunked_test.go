@@ -0,0 +1,45 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+)
+
+func TestExecChunked(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, "CREATE TABLE events (id INTEGER PRIMARY KEY, old BOOLEAN);", nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 12; i++ {
+		if _, err := db.Exec(ctx, "INSERT INTO events (old) VALUES (:old);", map[string]interface{}{"old": i < 10}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const query = "DELETE FROM events WHERE id IN (SELECT id FROM events WHERE old = :old LIMIT :chunk_size);"
+	var progress []ChunkProgress
+	n, err := db.ExecChunked(ctx, query, map[string]interface{}{"old": true}, 4, ChunkOptions{
+		OnChunk: func(p ChunkProgress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 10 {
+		t.Fatalf("expected 10 rows deleted, got %v", n)
+	}
+	if len(progress) != 3 || progress[2] != (ChunkProgress{Chunks: 3, RowsAffected: 10, Last: 2}) {
+		t.Fatalf("unexpected progress: %+v", progress)
+	}
+
+	var left int
+	if err := db.Get(ctx, "SELECT COUNT(*) FROM events;", &left, nil); err != nil || left != 2 {
+		t.Fatalf("expected 2 rows left, got %v %v", left, err)
+	}
+
+	n, err = db.ExecChunked(ctx, query, map[string]interface{}{"old": false}, 1, ChunkOptions{MaxChunks: 1})
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 row deleted, got %v %v", n, err)
+	}
+}
@@ -0,0 +1,136 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Claimer drains a table used as a work queue. Claimed rows are hidden from
+// other claimers until their visibility timeout passes, after which they can
+// be claimed again (ie. when a worker dies before finishing). Postgres only.
+type Claimer struct {
+	// Table holding the work items.
+	Table string
+	// Key is the primary key column. Defaults to "id".
+	Key string
+	// Column is a nullable timestamp column recording when the current claim
+	// expires. Defaults to "claimed_until".
+	Column string
+	// Filter is an optional condition on claimable rows. It may reference
+	// named params.
+	Filter string
+	// OrderBy orders claimable rows. Defaults to Key.
+	OrderBy string
+
+	calls, claimed, empty, failed int64
+}
+
+// ClaimStats reports a Claimer's activity.
+type ClaimStats struct {
+	// Calls to Claim, and those that found nothing to claim.
+	Calls, Empty int64
+	// Claimed is the total number of rows claimed, including re-claims.
+	Claimed int64
+	Errors  int64
+}
+
+func (c *Claimer) key() string {
+	if c.Key == "" {
+		return "id"
+	}
+	return c.Key
+}
+
+func (c *Claimer) column() string {
+	if c.Column == "" {
+		return "claimed_until"
+	}
+	return c.Column
+}
+
+func (c *Claimer) claimSQL() string {
+	filter := "TRUE"
+	if c.Filter != "" {
+		filter = "(" + c.Filter + ")"
+	}
+	order := c.OrderBy
+	if order == "" {
+		order = c.key()
+	}
+	return fmt.Sprintf(`UPDATE %[1]v SET %[3]v = now() + :sqln_visibility * interval '1 millisecond'
+WHERE %[2]v IN (
+	SELECT %[2]v FROM %[1]v
+	WHERE %[4]v AND (%[3]v IS NULL OR %[3]v <= now())
+	ORDER BY %[5]v LIMIT :sqln_limit
+	FOR UPDATE SKIP LOCKED
+) RETURNING *;`, c.Table, c.key(), c.column(), filter, order)
+}
+
+// Claim claims up to limit rows for the visibility timeout, selecting them
+// into dest (a pointer to a slice). Rows locked by a concurrent Claim are
+// skipped rather than waited on.
+func (c *Claimer) Claim(ctx context.Context, db DB, dest interface{}, params interface{}, limit int, visibility time.Duration) (int, error) {
+	atomic.AddInt64(&c.calls, 1)
+
+	extra := map[string]interface{}{
+		"sqln_limit":      limit,
+		"sqln_visibility": visibility.Milliseconds(),
+	}
+	m, _ := mapperOf(db)
+	if err := db.Select(ctx, c.claimSQL(), dest, withParams(m, params, extra)); err != nil {
+		atomic.AddInt64(&c.failed, 1)
+		return 0, errors.Wrapf(err, "claiming from %v", c.Table)
+	}
+
+	n := reflect.Indirect(reflect.ValueOf(dest)).Len()
+	if n == 0 {
+		atomic.AddInt64(&c.empty, 1)
+	}
+	atomic.AddInt64(&c.claimed, int64(n))
+	return n, nil
+}
+
+// Extend pushes the visibility timeout of a claimed row out by visibility
+// from now, ie. for long running work.
+func (c *Claimer) Extend(ctx context.Context, db DB, key interface{}, visibility time.Duration) error {
+	q := fmt.Sprintf("UPDATE %v SET %v = now() + :visibility * interval '1 millisecond' WHERE %v = :key;", c.Table, c.column(), c.key())
+	res, err := db.Exec(ctx, q, map[string]interface{}{"key": key, "visibility": visibility.Milliseconds()})
+	return c.checkOne(res, err)
+}
+
+// Release makes a claimed row immediately claimable again.
+func (c *Claimer) Release(ctx context.Context, db DB, key interface{}) error {
+	q := fmt.Sprintf("UPDATE %v SET %v = NULL WHERE %v = :key;", c.Table, c.column(), c.key())
+	res, err := db.Exec(ctx, q, map[string]interface{}{"key": key})
+	return c.checkOne(res, err)
+}
+
+func (c *Claimer) checkOne(res sql.Result, err error) error {
+	if err != nil {
+		return errors.Wrapf(err, "updating claim in %v", c.Table)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "rows affected")
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Stats returns counters accumulated since the Claimer was created.
+func (c *Claimer) Stats() ClaimStats {
+	return ClaimStats{
+		Calls:   atomic.LoadInt64(&c.calls),
+		Empty:   atomic.LoadInt64(&c.empty),
+		Claimed: atomic.LoadInt64(&c.claimed),
+		Errors:  atomic.LoadInt64(&c.failed),
+	}
+}
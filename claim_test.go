@@ -0,0 +1,67 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nstogner/psqlxtest"
+)
+
+func TestClaimer(t *testing.T) {
+	dbx, dropx := psqlxtest.TmpDB(t)
+	defer dropx()
+	db := New(dbx)
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, `CREATE TABLE jobs (
+	id SERIAL PRIMARY KEY,
+	queue TEXT NOT NULL,
+	claimed_until TIMESTAMPTZ
+);`, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, "INSERT INTO jobs (queue) SELECT 'email' FROM generate_series(1, 5);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Claimer{Table: "jobs", Filter: "queue = :queue"}
+	params := map[string]interface{}{"queue": "email"}
+
+	type job struct {
+		ID           int        `db:"id"`
+		Queue        string     `db:"queue"`
+		ClaimedUntil *time.Time `db:"claimed_until"`
+	}
+
+	var first []job
+	if n, err := c.Claim(ctx, db, &first, params, 3, time.Minute); err != nil || n != 3 {
+		t.Fatalf("expected 3 claimed, got %v (%v)", n, err)
+	}
+	var second []job
+	if n, err := c.Claim(ctx, db, &second, params, 3, time.Minute); err != nil || n != 2 {
+		t.Fatalf("expected remaining 2 claimed, got %v (%v)", n, err)
+	}
+	var none []job
+	if n, err := c.Claim(ctx, db, &none, params, 3, time.Minute); err != nil || n != 0 {
+		t.Fatalf("expected nothing claimable, got %v (%v)", n, err)
+	}
+
+	// Released and expired claims are claimable again.
+	if err := c.Release(ctx, db, first[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Extend(ctx, db, first[1].ID, -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	var again []job
+	if n, err := c.Claim(ctx, db, &again, params, 3, time.Minute); err != nil || n != 2 {
+		t.Fatalf("expected 2 re-claimed, got %v (%v)", n, err)
+	}
+
+	s := c.Stats()
+	if s.Calls != 4 || s.Claimed != 7 || s.Empty != 1 || s.Errors != 0 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+}
@@ -0,0 +1,175 @@
+package sqln
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// ErrorClass is a driver-independent classification of database errors.
+type ErrorClass int
+
+// Error classes returned by Classify.
+const (
+	ClassUnknown ErrorClass = iota
+	ClassUniqueViolation
+	ClassForeignKeyViolation
+	ClassNotNullViolation
+	ClassCheckViolation
+	ClassSerializationFailure
+	ClassDeadlock
+	ClassLockTimeout
+	ClassQueryCanceled
+	ClassReadOnly
+	ClassTooManyConnections
+	ClassConnection
+)
+
+var classNames = map[ErrorClass]string{
+	ClassUnknown:              "unknown",
+	ClassUniqueViolation:      "unique violation",
+	ClassForeignKeyViolation:  "foreign key violation",
+	ClassNotNullViolation:     "not null violation",
+	ClassCheckViolation:       "check violation",
+	ClassSerializationFailure: "serialization failure",
+	ClassDeadlock:             "deadlock",
+	ClassLockTimeout:          "lock timeout",
+	ClassQueryCanceled:        "query canceled",
+	ClassReadOnly:             "read only",
+	ClassTooManyConnections:   "too many connections",
+	ClassConnection:           "connection",
+}
+
+func (c ErrorClass) String() string {
+	return classNames[c]
+}
+
+// Classify inspects err (and the errors it wraps) for a known driver error.
+// Postgres (lib/pq and pgx), MySQL and SQLite (mattn and modernc) errors are
+// recognized.
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return ClassUnknown
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return classifyPostgres(string(pqErr.Code))
+	}
+
+	// Implemented by pgx (*pgconn.PgError).
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		return classifyPostgres(stateErr.SQLState())
+	}
+
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return classifyMySQL(myErr.Number)
+	}
+
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		t := reflect.TypeOf(e)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if strings.Contains(t.PkgPath(), "sqlite") {
+			return classifySQLite(e.Error())
+		}
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ClassQueryCanceled
+	}
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr) {
+		return ClassConnection
+	}
+
+	return ClassUnknown
+}
+
+func classifyPostgres(code string) ErrorClass {
+	switch code {
+	case "23505":
+		return ClassUniqueViolation
+	case "23503":
+		return ClassForeignKeyViolation
+	case "23502":
+		return ClassNotNullViolation
+	case "23514":
+		return ClassCheckViolation
+	case "40001":
+		return ClassSerializationFailure
+	case "40P01":
+		return ClassDeadlock
+	case "55P03":
+		return ClassLockTimeout
+	case "57014":
+		return ClassQueryCanceled
+	case "25006":
+		return ClassReadOnly
+	case "53300":
+		return ClassTooManyConnections
+	case "57P01", "57P02", "57P03":
+		return ClassConnection
+	}
+	if strings.HasPrefix(code, "08") {
+		return ClassConnection
+	}
+	return ClassUnknown
+}
+
+func classifyMySQL(number uint16) ErrorClass {
+	switch number {
+	case 1062, 1586:
+		return ClassUniqueViolation
+	case 1216, 1217, 1451, 1452:
+		return ClassForeignKeyViolation
+	case 1048, 1364:
+		return ClassNotNullViolation
+	case 3819:
+		return ClassCheckViolation
+	case 1213:
+		return ClassDeadlock
+	case 1205:
+		return ClassLockTimeout
+	case 1317, 3024:
+		return ClassQueryCanceled
+	case 1290, 1792:
+		return ClassReadOnly
+	case 1040:
+		return ClassTooManyConnections
+	}
+	return ClassUnknown
+}
+
+// classifySQLite matches on messages since SQLite drivers require cgo (or a
+// large dependency) to inspect their error codes.
+func classifySQLite(msg string) ErrorClass {
+	switch {
+	case strings.Contains(msg, "UNIQUE constraint failed"):
+		return ClassUniqueViolation
+	case strings.Contains(msg, "FOREIGN KEY constraint failed"):
+		return ClassForeignKeyViolation
+	case strings.Contains(msg, "NOT NULL constraint failed"):
+		return ClassNotNullViolation
+	case strings.Contains(msg, "CHECK constraint failed"):
+		return ClassCheckViolation
+	case strings.Contains(msg, "database is locked"), strings.Contains(msg, "database table is locked"):
+		return ClassLockTimeout
+	case strings.Contains(msg, "readonly database"):
+		return ClassReadOnly
+	case strings.Contains(msg, "interrupted"):
+		return ClassQueryCanceled
+	}
+	return ClassUnknown
+}
@@ -0,0 +1,34 @@
+package sqln
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		err error
+		exp ErrorClass
+	}{
+		{nil, ClassUnknown},
+		{errors.New("boom"), ClassUnknown},
+		{&pq.Error{Code: "23505"}, ClassUniqueViolation},
+		{errors.Wrap(&pq.Error{Code: "40001"}, "tx level 1"), ClassSerializationFailure},
+		{fmt.Errorf("wrapped: %w", &pq.Error{Code: "08006"}), ClassConnection},
+		{&mysql.MySQLError{Number: 1062}, ClassUniqueViolation},
+		{errors.Wrap(&mysql.MySQLError{Number: 1213}, "wrapped"), ClassDeadlock},
+		{errors.Wrap(context.DeadlineExceeded, "wrapped"), ClassQueryCanceled},
+		{driver.ErrBadConn, ClassConnection},
+	}
+	for _, c := range cases {
+		if got := Classify(c.err); got != c.exp {
+			t.Errorf("%v: expected %v, got %v", c.err, c.exp, got)
+		}
+	}
+}
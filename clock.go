@@ -0,0 +1,215 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Clock provides the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Timer is a function scheduled by a TimerClock.
+type Timer interface {
+	// Stop prevents the function from running, reporting whether it did.
+	Stop() bool
+}
+
+// TimerClock is a Clock that also schedules functions, so that the timers of
+// helpers using it (ie. retry backoff, TxWatchdog and WithStmtTTL) follow the
+// clock. Clocks that do not implement it are read with system timers.
+type TimerClock interface {
+	Clock
+	// AfterFunc calls f, in its own goroutine, once d has elapsed.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer { return time.AfterFunc(d, f) }
+
+// SystemClock reads the system time.
+var SystemClock Clock = systemClock{}
+
+// WithClock times the statement cache with c: statement use for WithStmtTTL
+// and failed prepares for WithPrepareBackoff. Defaults to SystemClock.
+func WithClock(c Clock) Option {
+	return func(d *Database) {
+		d.cache.clock = c
+	}
+}
+
+// afterFunc schedules f on c, or a system timer if c is not a TimerClock.
+func afterFunc(c Clock, d time.Duration, f func()) Timer {
+	if tc, ok := c.(TimerClock); ok {
+		return tc.AfterFunc(d, f)
+	}
+	return time.AfterFunc(d, f)
+}
+
+// wait waits for d to elapse on c, reporting false if done is closed first.
+func wait(c Clock, d time.Duration, done <-chan struct{}) bool {
+	elapsed := make(chan struct{})
+	t := afterFunc(c, d, func() { close(elapsed) })
+	select {
+	case <-done:
+		t.Stop()
+		return false
+	case <-elapsed:
+		return true
+	}
+}
+
+// TestClock is a Clock that only moves when told to. Functions scheduled with
+// AfterFunc run when Set or Advance moves the clock past their time.
+type TestClock struct {
+	mtx    sync.Mutex
+	t      time.Time
+	timers []*testTimer
+}
+
+type testTimer struct {
+	c  *TestClock
+	at time.Time
+	f  func()
+}
+
+// Stop removes the timer from its clock.
+func (t *testTimer) Stop() bool {
+	t.c.mtx.Lock()
+	defer t.c.mtx.Unlock()
+	for i, o := range t.c.timers {
+		if o == t {
+			t.c.timers = append(t.c.timers[:i], t.c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// NewTestClock returns a TestClock set to t.
+func NewTestClock(t time.Time) *TestClock {
+	return &TestClock{t: t}
+}
+
+// Now returns the clock's current time.
+func (c *TestClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.t
+}
+
+// Set the clock to t.
+func (c *TestClock) Set(t time.Time) {
+	c.mtx.Lock()
+	c.t = t
+	c.fireLocked()
+}
+
+// Advance the clock by d.
+func (c *TestClock) Advance(d time.Duration) {
+	c.mtx.Lock()
+	c.t = c.t.Add(d)
+	c.fireLocked()
+}
+
+// AfterFunc calls f, in its own goroutine, once the clock has moved d past
+// its current time.
+func (c *TestClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mtx.Lock()
+	t := &testTimer{c: c, at: c.t.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	c.fireLocked()
+	return t
+}
+
+// Timers returns the number of functions waiting on the clock, ie. to wait
+// for a goroutine to schedule a timer before advancing the clock.
+func (c *TestClock) Timers() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return len(c.timers)
+}
+
+// fireLocked starts the due timers and unlocks the mutex.
+func (c *TestClock) fireLocked() {
+	var due []*testTimer
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.t) {
+			pending = append(pending, t)
+		} else {
+			due = append(due, t)
+		}
+	}
+	c.timers = pending
+	c.mtx.Unlock()
+	for _, t := range due {
+		go t.f()
+	}
+}
+
+// BindNow returns a middleware that binds a "now" parameter from clock into
+// the given queries, which use :now in place of now(). Like now() in
+// Postgres, the value is fixed for the duration of a transaction. A "now"
+// value supplied by the caller is left untouched.
+func BindNow(clock Clock, queries ...string) Middleware {
+	set := make(map[string]bool, len(queries))
+	for _, q := range queries {
+		set[q] = true
+	}
+	return func(db DB) DB {
+		return &nowDB{DB: db, clock: clock, queries: set}
+	}
+}
+
+type nowDB struct {
+	DB
+	clock   Clock
+	queries map[string]bool
+
+	// txNow is set inside transactions.
+	txNow time.Time
+}
+
+func (d *nowDB) Unwrap() DB { return d.DB }
+
+func (d *nowDB) params(query string, params interface{}) interface{} {
+	if !d.queries[query] {
+		return params
+	}
+	now := d.txNow
+	if now.IsZero() {
+		now = d.clock.Now()
+	}
+	m, _ := mapperOf(d.DB)
+	return withParams(m, params, map[string]interface{}{"now": now})
+}
+
+func (d *nowDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	return d.DB.Exec(ctx, query, d.params(query, params))
+}
+
+func (d *nowDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	return d.DB.ExecReturning(ctx, query, dest, d.params(query, params))
+}
+
+func (d *nowDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	return d.DB.Get(ctx, query, dest, d.params(query, params))
+}
+
+func (d *nowDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	return d.DB.Select(ctx, query, dest, d.params(query, params))
+}
+
+func (d *nowDB) Transact(ctx context.Context, opts sql.TxOptions, f func(DB) error) error {
+	now := d.clock.Now()
+	return d.DB.Transact(ctx, opts, func(tx DB) error {
+		return f(&nowDB{DB: tx, clock: d.clock, queries: d.queries, txNow: now})
+	})
+}
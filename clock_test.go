@@ -0,0 +1,89 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestBindNow(t *testing.T) {
+	d := sqliteDB(t)
+	if _, err := d.X.Exec("CREATE TABLE clock_sessions (id INT PRIMARY KEY, expires_at TIMESTAMP);"); err != nil {
+		t.Fatal("unable to create table:", err)
+	}
+
+	start := time.Date(2019, 9, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewTestClock(start)
+
+	const (
+		insert = "INSERT INTO clock_sessions (id, expires_at) VALUES (:id, :expires_at);"
+		active = "SELECT COUNT(*) FROM clock_sessions WHERE expires_at > :now;"
+	)
+	db := Wrap(d, BindNow(clock, active))
+
+	ctx := context.Background()
+	for i, exp := range []time.Duration{time.Minute, time.Hour} {
+		if _, err := db.Exec(ctx, insert, map[string]interface{}{"id": i, "expires_at": start.Add(exp)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count := func(db DB) int {
+		var n int
+		if err := db.Get(ctx, active, &n, nil); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	if n := count(db); n != 2 {
+		t.Fatalf("expected 2 active sessions, got %v", n)
+	}
+	clock.Advance(30 * time.Minute)
+	if n := count(db); n != 1 {
+		t.Fatalf("expected 1 active session, got %v", n)
+	}
+
+	err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		// Time is fixed at the start of the transaction.
+		clock.Advance(time.Hour)
+		if n := count(tx); n != 1 {
+			t.Errorf("expected 1 active session inside transaction, got %v", n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := count(db); n != 0 {
+		t.Fatalf("expected no active sessions, got %v", n)
+	}
+}
+
+func TestTestClockTimers(t *testing.T) {
+	c := NewTestClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	fired := make(chan int, 2)
+	c.AfterFunc(time.Minute, func() { fired <- 1 })
+	stopped := c.AfterFunc(time.Minute, func() { fired <- 2 })
+	c.AfterFunc(time.Hour, func() { fired <- 3 })
+
+	if !stopped.Stop() || stopped.Stop() {
+		t.Fatal("expected the timer to stop once")
+	}
+	c.Advance(59 * time.Second)
+	if n := c.Timers(); n != 2 {
+		t.Fatalf("expected 2 timers waiting, got %v", n)
+	}
+	c.Advance(time.Second)
+	if n := <-fired; n != 1 {
+		t.Fatalf("expected timer 1 to fire, got %v", n)
+	}
+	c.Set(c.Now().Add(time.Hour))
+	if n := <-fired; n != 3 {
+		t.Fatalf("expected timer 3 to fire, got %v", n)
+	}
+	if n := c.Timers(); n != 0 {
+		t.Fatalf("expected no timers waiting, got %v", n)
+	}
+}
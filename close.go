@@ -0,0 +1,44 @@
+package sqln
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrClosed is returned by operations on a closed Database.
+var ErrClosed = errors.New("sqln: database closed")
+
+// WithCloseTimeout bounds how long Close waits for in-flight operations
+// before cancelling them. Defaults to 10 seconds.
+func WithCloseTimeout(t time.Duration) Option {
+	return func(d *Database) {
+		d.closeTimeout = t
+	}
+}
+
+// Shutdown stops accepting operations, waits for those in flight until ctx
+// is done (cancelling any that remain) and closes every statement. Errors
+// are joined. Operations started afterwards return ErrClosed. Closing a
+// closed Database is a no-op.
+func (d *Database) Shutdown(ctx context.Context) error {
+	d.stats.mtx.Lock()
+	if d.stats.closed {
+		d.stats.mtx.Unlock()
+		return nil
+	}
+	d.stats.closed = true
+	d.stats.mtx.Unlock()
+
+	var errs []error
+	if err := d.Drain(ctx); err != nil {
+		d.CancelAll()
+		errs = append(errs, errors.Wrap(err, "draining"))
+	}
+	if err := d.cache.close(); err != nil {
+		errs = append(errs, err)
+	}
+	return stderrors.Join(errs...)
+}
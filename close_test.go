@@ -0,0 +1,48 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	const (
+		forever = "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT COUNT(*) FROM c;"
+		one     = "SELECT 1;"
+	)
+	if _, err := db.Stmt(one); err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error)
+	go func() {
+		var n int
+		errs <- db.Get(ctx, forever, &n, nil)
+	}()
+	for len(db.InFlight()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := db.Shutdown(short); err == nil {
+		t.Fatal("expected shutdown to report the drain timeout")
+	}
+	if err := <-errs; err == nil {
+		t.Fatal("expected the in-flight query to be cancelled")
+	}
+
+	var n int
+	if err := db.Get(ctx, one, &n, nil); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+	if _, err := db.Stmt(one); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("expected closing twice to succeed, got %v", err)
+	}
+}
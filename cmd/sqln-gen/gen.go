@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+type field struct {
+	Column string
+	Type   string
+}
+
+// Name is the exported Go name of the column.
+func (f field) Name() string {
+	return goName(f.Column)
+}
+
+type query struct {
+	Name    string
+	Kind    string
+	SQL     string
+	Params  []field
+	Returns []field
+}
+
+// parse reads the annotated queries in a .sql file.
+func parse(file, src string) ([]query, error) {
+	var (
+		qs    []query
+		cur   *query
+		types map[string]string
+		body  strings.Builder
+	)
+	flush := func() error {
+		if cur == nil {
+			return nil
+		}
+		cur.SQL = strings.TrimSpace(body.String())
+		body.Reset()
+		for _, p := range namedParams(cur.SQL) {
+			t, ok := types[p]
+			if !ok {
+				t = "interface{}"
+			}
+			cur.Params = append(cur.Params, field{Column: p, Type: t})
+		}
+		for p := range types {
+			if !hasParam(cur.Params, p) {
+				return errors.Errorf("%v: %v: param %q is not used by the query", file, cur.Name, p)
+			}
+		}
+		if cur.Kind != "exec" && len(cur.Returns) == 0 {
+			return errors.Errorf("%v: %v: :%v queries require a returns annotation", file, cur.Name, cur.Kind)
+		}
+		qs = append(qs, *cur)
+		cur = nil
+		return nil
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(src))
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if rest, ok := strings.CutPrefix(text, "-- name:"); ok {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			parts := strings.Fields(rest)
+			if len(parts) != 2 || !strings.HasPrefix(parts[1], ":") {
+				return nil, errors.Errorf("%v:%v: expected -- name: <Name> :one|:many|:exec", file, line)
+			}
+			kind := strings.TrimPrefix(parts[1], ":")
+			if kind != "one" && kind != "many" && kind != "exec" {
+				return nil, errors.Errorf("%v:%v: unknown kind %q", file, line, parts[1])
+			}
+			cur, types = &query{Name: parts[0], Kind: kind}, make(map[string]string)
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(text, "-- params:"); ok {
+			fs, err := parseFields(rest)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%v:%v", file, line)
+			}
+			for _, f := range fs {
+				types[f.Column] = f.Type
+			}
+			continue
+		}
+		if rest, ok := strings.CutPrefix(text, "-- returns:"); ok {
+			fs, err := parseFields(rest)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%v:%v", file, line)
+			}
+			cur.Returns = append(cur.Returns, fs...)
+			continue
+		}
+		body.WriteString(sc.Text())
+		body.WriteByte('\n')
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return qs, nil
+}
+
+// parseFields parses "col type, col type".
+func parseFields(s string) ([]field, error) {
+	var fs []field
+	for _, part := range strings.Split(s, ",") {
+		parts := strings.Fields(part)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("expected <column> <type>, got %q", strings.TrimSpace(part))
+		}
+		fs = append(fs, field{Column: parts[0], Type: parts[1]})
+	}
+	return fs, nil
+}
+
+func hasParam(fs []field, name string) bool {
+	for _, f := range fs {
+		if f.Column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// namedParams returns the distinct :name params of query in order, skipping
+// quoted strings and :: casts.
+func namedParams(query string) []string {
+	var (
+		params []string
+		seen   = make(map[string]bool)
+		quote  rune
+	)
+	rs := []rune(query)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ':' && i+1 < len(rs) && rs[i+1] == ':':
+			i++
+		case r == ':' && i+1 < len(rs) && isIdent(rs[i+1]):
+			j := i + 1
+			for j < len(rs) && (isIdent(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			name := string(rs[i+1 : j])
+			if !seen[name] {
+				seen[name] = true
+				params = append(params, name)
+			}
+			i = j - 1
+		}
+	}
+	return params
+}
+
+func isIdent(r rune) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+var initialisms = map[string]string{"id": "ID", "url": "URL", "uri": "URI", "api": "API", "ip": "IP", "json": "JSON", "sql": "SQL", "uuid": "UUID", "http": "HTTP"}
+
+// goName converts a snake_case column to an exported Go name.
+func goName(column string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(column, func(r rune) bool { return r == '_' || r == '.' }) {
+		if s, ok := initialisms[strings.ToLower(part)]; ok {
+			b.WriteString(s)
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// lowerFirst returns name with its first letter (or leading initialism)
+// lower cased, for unexported identifiers.
+func lowerFirst(name string) string {
+	for _, s := range initialisms {
+		if strings.HasPrefix(name, s) {
+			return strings.ToLower(s) + name[len(s):]
+		}
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+var tmpl = template.Must(template.New("gen").Funcs(template.FuncMap{
+	"lower": lowerFirst,
+	"quote": func(s string) string {
+		if strings.Contains(s, "`") {
+			return fmt.Sprintf("%q", s)
+		}
+		return "`" + s + "`"
+	},
+}).Parse(`// Code generated by sqln-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .Imports}}
+	"{{.}}"
+{{- end}}
+)
+{{range .Queries}}
+const {{lower .Name}}Query = {{quote .SQL}}
+{{if .Params}}
+// {{.Name}}Params are the params of {{.Name}}.
+type {{.Name}}Params struct {
+{{- range .Params}}
+	{{.Name}} {{.Type}} ` + "`" + `db:"{{.Column}}"` + "`" + `
+{{- end}}
+}
+{{end}}
+{{- if .Returns}}
+// {{.Name}}Row is a row returned by {{.Name}}.
+type {{.Name}}Row struct {
+{{- range .Returns}}
+	{{.Name}} {{.Type}} ` + "`" + `db:"{{.Column}}"` + "`" + `
+{{- end}}
+}
+{{end}}
+{{- if eq .Kind "one"}}
+// {{.Name}} returns a single row, or sql.ErrNoRows.
+func {{.Name}}(ctx context.Context, db sqln.DB{{if .Params}}, p {{.Name}}Params{{end}}) ({{.Name}}Row, error) {
+	var r {{.Name}}Row
+	err := db.Get(ctx, {{lower .Name}}Query, &r, {{if .Params}}p{{else}}nil{{end}})
+	return r, err
+}
+{{- else if eq .Kind "many"}}
+// {{.Name}} returns all matching rows.
+func {{.Name}}(ctx context.Context, db sqln.DB{{if .Params}}, p {{.Name}}Params{{end}}) ([]{{.Name}}Row, error) {
+	var rs []{{.Name}}Row
+	err := db.Select(ctx, {{lower .Name}}Query, &rs, {{if .Params}}p{{else}}nil{{end}})
+	return rs, err
+}
+{{- else}}
+// {{.Name}} executes the statement.
+func {{.Name}}(ctx context.Context, db sqln.DB{{if .Params}}, p {{.Name}}Params{{end}}) (sql.Result, error) {
+	return db.Exec(ctx, {{lower .Name}}Query, {{if .Params}}p{{else}}nil{{end}})
+}
+{{- end}}
+{{end}}`))
+
+// generate renders the Go source for queries.
+func generate(pkg string, queries []query) ([]byte, error) {
+	names := make(map[string]bool, len(queries))
+	imports := map[string]bool{"context": true, "github.com/nstogner/sqln": true}
+	for _, q := range queries {
+		if names[q.Name] {
+			return nil, errors.Errorf("duplicate query %q", q.Name)
+		}
+		names[q.Name] = true
+		if q.Kind == "exec" {
+			imports["database/sql"] = true
+		}
+		for _, f := range append(append([]field(nil), q.Params...), q.Returns...) {
+			if strings.Contains(f.Type, "time.") {
+				imports["time"] = true
+			}
+			if strings.Contains(f.Type, "sql.") {
+				imports["database/sql"] = true
+			}
+			if strings.Contains(f.Type, "json.") {
+				imports["encoding/json"] = true
+			}
+		}
+	}
+	sorted := make([]string, 0, len(imports))
+	for imp := range imports {
+		sorted = append(sorted, imp)
+	}
+	sort.Strings(sorted)
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, map[string]interface{}{
+		"Package": pkg,
+		"Imports": sorted,
+		"Queries": queries,
+	}); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrapf(err, "formatting generated code:\n%s", buf.Bytes())
+	}
+	return src, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const users = `
+-- name: GetUser :one
+-- params: id int64
+-- returns: id int64, name string, created_at time.Time
+SELECT id, name, created_at FROM users WHERE id = :id AND name <> ':nope' AND created_at::date > '2020-01-01';
+
+-- name: ListUsers :many
+-- returns: id int64, avatar_url sql.NullString
+SELECT id, avatar_url FROM users;
+
+-- name: RenameUser :exec
+-- params: id int64
+UPDATE users SET name = :name WHERE id = :id;
+`
+
+func TestGenerate(t *testing.T) {
+	qs, err := parse("users.sql", users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(qs) != 3 {
+		t.Fatalf("expected 3 queries, got %v", len(qs))
+	}
+	if ps := qs[0].Params; len(ps) != 1 || ps[0].Column != "id" || ps[0].Type != "int64" {
+		t.Fatalf("unexpected params: %+v", ps)
+	}
+	if ps := qs[2].Params; len(ps) != 2 || ps[0] != (field{"name", "interface{}"}) {
+		t.Fatalf("unexpected params: %+v", ps)
+	}
+
+	src, err := generate("db", qs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"func GetUser(ctx context.Context, db sqln.DB, p GetUserParams) (GetUserRow, error) {",
+		"func ListUsers(ctx context.Context, db sqln.DB) ([]ListUsersRow, error) {",
+		"func RenameUser(ctx context.Context, db sqln.DB, p RenameUserParams) (sql.Result, error) {",
+		"AvatarURL sql.NullString `db:\"avatar_url\"`",
+		"\"time\"",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("expected generated code to contain %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, src := range map[string]string{
+		"kind":    "-- name: X :some\nSELECT 1;",
+		"returns": "-- name: X :one\nSELECT 1;",
+		"params":  "-- name: X :exec\n-- params: id int64\nDELETE FROM t;",
+	} {
+		if _, err := parse("x.sql", src); err == nil {
+			t.Errorf("%v: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Command sqln-gen generates typed Go functions for annotated queries in .sql
+// files, calling sqln.DB so they share its statement cache.
+//
+// Queries are annotated as follows:
+//
+//	-- name: GetUser :one
+//	-- params: id int64
+//	-- returns: id int64, name string, created_at time.Time
+//	SELECT id, name, created_at FROM users WHERE id = :id;
+//
+// The kind is :one (Get), :many (Select) or :exec (Exec). Params default to
+// interface{} when their types are not annotated. The same files can be
+// loaded at runtime with sqln.LoadQueries.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+func main() {
+	var (
+		out = flag.String("out", "queries.gen.go", "output file")
+		pkg = flag.String("package", "", "package name (defaults to the output directory name)")
+	)
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatal("usage: sqln-gen [-out file] [-package name] <file.sql or glob>...")
+	}
+
+	var files []string
+	for _, pattern := range flag.Args() {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			log.Fatal(err)
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	var queries []query
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		qs, err := parse(file, string(b))
+		if err != nil {
+			log.Fatal(err)
+		}
+		queries = append(queries, qs...)
+	}
+
+	if *pkg == "" {
+		abs, err := filepath.Abs(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		*pkg = filepath.Base(filepath.Dir(abs))
+	}
+	src, err := generate(*pkg, queries)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
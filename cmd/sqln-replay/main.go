@@ -0,0 +1,64 @@
+// Command sqln-replay replays a captured query log against a Postgres
+// database.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/nstogner/sqln"
+	"github.com/nstogner/sqln/replay"
+)
+
+func main() {
+	var (
+		dsn         = flag.String("dsn", "", "target database connection string")
+		file        = flag.String("file", "", "query log to replay")
+		concurrency = flag.Int("concurrency", 1, "number of queries run in parallel")
+		speed       = flag.Float64("speed", 0, "replay speed relative to the original traffic (0 is unthrottled)")
+		writes      = flag.Bool("writes", false, "replay Exec records")
+		verbose     = flag.Bool("v", false, "log every failed query")
+	)
+	flag.Parse()
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	recs, err := sqln.ReadRecords(f)
+	f.Close()
+	if err != nil {
+		log.Fatal("reading log: ", err)
+	}
+
+	dbx, err := sqlx.Connect("postgres", *dsn)
+	if err != nil {
+		log.Fatal("connecting: ", err)
+	}
+	defer dbx.Close()
+	db := sqln.New(dbx)
+	defer db.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	sum, err := replay.Run(ctx, db, recs, replay.Options{
+		Concurrency: *concurrency,
+		Speed:       *speed,
+		Writes:      *writes,
+		OnResult: func(r replay.Result) {
+			if *verbose && r.Err != nil {
+				log.Printf("%q: %v", r.Record.Query, r.Err)
+			}
+		},
+	})
+	log.Printf("executed=%v failed=%v skipped=%v elapsed=%v", sum.Executed, sum.Failed, sum.Skipped, sum.Elapsed)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
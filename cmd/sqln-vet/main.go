@@ -0,0 +1,14 @@
+// Command sqln-vet checks that sqln queries are constant and that their named
+// params are bound (see package sqlnvet).
+//
+//	go vet -vettool=$(which sqln-vet) ./...
+package main
+
+import (
+	"github.com/nstogner/sqln/sqlnvet"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(sqlnvet.Analyzer)
+}
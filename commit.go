@@ -0,0 +1,74 @@
+package sqln
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCommitAmbiguous is matched (with errors.Is) by commit errors after which
+// the transaction may or may not have committed, ie. because the connection
+// dropped before the server's reply arrived. Other commit errors mean the
+// transaction was rolled back and can be retried. Retrying after an
+// ambiguous commit is only safe when the transaction is idempotent, ie.
+// because it records an idempotency key.
+var ErrCommitAmbiguous = errors.New("sqln: commit outcome unknown")
+
+type commitError struct {
+	err error
+}
+
+func (e *commitError) Error() string {
+	return ErrCommitAmbiguous.Error() + ": " + e.err.Error()
+}
+
+func (e *commitError) Is(target error) bool {
+	return target == ErrCommitAmbiguous
+}
+
+func (e *commitError) Unwrap() error {
+	return e.err
+}
+
+// CommitError marks an error returned by a commit as ambiguous (see
+// ErrCommitAmbiguous) when it is a connection error, and returns constraint
+// violations as a *ConstraintError. It returns other errors unchanged and is
+// only needed by other DB implementations.
+func CommitError(err error) error {
+	if err == nil || errors.Is(err, sql.ErrTxDone) {
+		return err
+	}
+	if ce := constraintError(err); ce != nil {
+		return ce
+	}
+	if Classify(err) != ClassConnection {
+		return err
+	}
+	return &commitError{err: err}
+}
+
+// canceledTxError is returned for a transaction rolled back because its
+// context was done. It matches ctxErr and unwraps to the error returned by
+// the transaction's function, if any.
+type canceledTxError struct {
+	ctxErr error
+	err    error
+}
+
+func (e *canceledTxError) Error() string {
+	if e.err == nil {
+		return e.ctxErr.Error()
+	}
+	if errors.Is(e.err, e.ctxErr) {
+		return e.err.Error()
+	}
+	return e.ctxErr.Error() + ": " + e.err.Error()
+}
+
+func (e *canceledTxError) Is(target error) bool {
+	return target == e.ctxErr
+}
+
+func (e *canceledTxError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,25 @@
+package sqln
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+func TestCommitError(t *testing.T) {
+	for _, err := range []error{driver.ErrBadConn, errors.Wrap(io.ErrUnexpectedEOF, "reading")} {
+		cerr := CommitError(err)
+		if !errors.Is(cerr, ErrCommitAmbiguous) || !errors.Is(cerr, err) {
+			t.Errorf("expected %v to be ambiguous, got %v", err, cerr)
+		}
+	}
+	for _, err := range []error{nil, sql.ErrTxDone, &pq.Error{Code: "40001"}} {
+		if cerr := CommitError(err); cerr != err {
+			t.Errorf("expected %v to be returned unchanged, got %v", err, cerr)
+		}
+	}
+}
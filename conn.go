@@ -0,0 +1,54 @@
+package sqln
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// WithConn runs f with a DB pinned to a single pooled connection, so session
+// state such as temporary tables, session advisory locks and SET variables
+// is seen by every operation. Transactions begun in f use the connection.
+// Statements are not prepared (see ExecUnprepared) since cached statements are
+// prepared on the pool, where ie. temporary tables do not exist.
+// NOTE: Session state remains on the connection when it is returned to the
+// pool; f should reset it (ie. DISCARD ALL on Postgres) where that matters.
+func (d *Database) WithConn(ctx context.Context, f func(DB) error) error {
+	if d.tx != nil || d.conn != nil {
+		// Already pinned.
+		return f(d)
+	}
+	if d.stats.isClosed() {
+		return ErrClosed
+	}
+	release, err := d.acquireClass(ctx)
+	if err != nil {
+		return err
+	}
+	if release != nil {
+		defer release()
+	}
+
+	conn, err := d.drv.Connx(ctx)
+	if err != nil {
+		return errors.Wrap(err, "conn")
+	}
+	defer conn.Close()
+
+	cd := *d
+	cd.conn = &connExt{Conn: conn, db: d.drv}
+	return f(&cd)
+}
+
+// connExt adds the binding methods of the pool to a connection.
+type connExt struct {
+	*sqlx.Conn
+	db backend
+}
+
+func (c *connExt) DriverName() string { return c.db.DriverName() }
+
+func (c *connExt) BindNamed(query string, arg interface{}) (string, []interface{}, error) {
+	return c.db.BindNamed(query, arg)
+}
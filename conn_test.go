@@ -0,0 +1,52 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestWithConn(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	err := db.WithConn(ctx, func(conn DB) error {
+		if _, err := conn.Exec(ctx, "CREATE TEMP TABLE scratch (id INTEGER);", nil); err != nil {
+			return err
+		}
+		// Hold another connection so the pool cannot hand the same one
+		// back by chance.
+		other, err := db.X.Connx(ctx)
+		if err != nil {
+			return err
+		}
+		defer other.Close()
+
+		for i := 0; i < 3; i++ {
+			if _, err := conn.Exec(ctx, "INSERT INTO scratch (id) VALUES (:id);", map[string]interface{}{"id": i}); err != nil {
+				return err
+			}
+		}
+		err = conn.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+			_, err := tx.Exec(ctx, "DELETE FROM scratch WHERE id = 0;", nil)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		var n int
+		if err := conn.Get(ctx, "SELECT COUNT(*) FROM scratch;", &n, nil); err != nil {
+			return err
+		}
+		if n != 2 {
+			t.Errorf("expected 2 rows, got %v", n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := db.Stats(); s.InUse != 0 {
+		t.Fatalf("expected connection to be returned, got %v in use", s.InUse)
+	}
+}
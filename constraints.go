@@ -0,0 +1,79 @@
+package sqln
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// ConstraintError is returned by a commit that failed on a constraint
+// violation, ie. of a constraint deferred with DeferConstraints.
+type ConstraintError struct {
+	// Class is the kind of violation, ie. ClassForeignKeyViolation.
+	Class ErrorClass
+	// Constraint is the name of the violated constraint, when the driver
+	// reports it.
+	Constraint string
+	Err        error
+}
+
+func (e *ConstraintError) Error() string {
+	if e.Constraint != "" {
+		return fmt.Sprintf("sqln: %v of %v at commit: %v", e.Class, e.Constraint, e.Err)
+	}
+	return fmt.Sprintf("sqln: %v at commit: %v", e.Class, e.Err)
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+// DeferConstraints defers the checking of deferrable constraints until the
+// transaction tx commits, where a violation is returned as a
+// *ConstraintError. Postgres defers all deferrable constraints; SQLite only
+// defers foreign keys. MySQL does not support deferring constraints.
+func DeferConstraints(ctx context.Context, tx DB) error {
+	if !InTx(tx) {
+		return errors.New("defer constraints: not in a transaction")
+	}
+	var q string
+	switch DialectOf(tx) {
+	case MySQL:
+		return errors.New("defer constraints: not supported by mysql")
+	case SQLite:
+		q = "PRAGMA defer_foreign_keys = ON;"
+	default:
+		q = "SET CONSTRAINTS ALL DEFERRED;"
+	}
+	_, err := tx.Exec(withInternal(ctx), q, nil)
+	return errors.Wrap(err, "defer constraints")
+}
+
+// constraintError returns err as a *ConstraintError if it is a constraint
+// violation, or nil.
+func constraintError(err error) error {
+	class := Classify(err)
+	switch class {
+	case ClassUniqueViolation, ClassForeignKeyViolation, ClassNotNullViolation, ClassCheckViolation:
+	default:
+		return nil
+	}
+	ce := &ConstraintError{Class: class, Err: err}
+	var pqErr *pq.Error
+	var stateErr interface{ SQLState() string }
+	switch {
+	case errors.As(err, &pqErr):
+		ce.Constraint = pqErr.Constraint
+	case errors.As(err, &stateErr):
+		// The ConstraintName field of pgx's *pgconn.PgError.
+		if v := reflect.Indirect(reflect.ValueOf(stateErr)); v.Kind() == reflect.Struct {
+			if f := v.FieldByName("ConstraintName"); f.Kind() == reflect.String {
+				ce.Constraint = f.String()
+			}
+		}
+	}
+	return ce
+}
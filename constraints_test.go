@@ -0,0 +1,52 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestDeferConstraints(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+	for _, q := range []string{
+		"CREATE TABLE deferred_parents (id INTEGER PRIMARY KEY);",
+		"CREATE TABLE deferred_children (parent_id INTEGER REFERENCES deferred_parents (id));",
+	} {
+		if _, err := db.Exec(ctx, q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The child can be inserted before its parent.
+	if err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		if err := DeferConstraints(ctx, tx); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO deferred_children VALUES (1);", nil); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "INSERT INTO deferred_parents VALUES (1);", nil)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		if err := DeferConstraints(ctx, tx); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "INSERT INTO deferred_children VALUES (2);", nil)
+		return err
+	})
+	var ce *ConstraintError
+	if !errors.As(err, &ce) || ce.Class != ClassForeignKeyViolation {
+		t.Fatalf("expected a foreign key violation at commit, got %v", err)
+	}
+
+	if err := DeferConstraints(ctx, db); err == nil {
+		t.Fatal("expected an error outside of a transaction")
+	}
+}
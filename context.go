@@ -0,0 +1,26 @@
+package sqln
+
+type ctxKey int
+
+const (
+	tenantKey ctxKey = iota
+	roleKey
+	stickyKey
+	shardKey
+	txSettingsKey
+	actorKey
+	queryNameKey
+	internalKey
+	poolClassKey
+	attemptKey
+	txMiddlewareKey
+	notFoundKey
+	replicaKey
+	dedupKey
+	annotationsKey
+	migrationKey
+	resultInfoKey
+	rowBatchesKey
+	queryScopeKey
+	tokenKey
+)
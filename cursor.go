@@ -0,0 +1,71 @@
+package sqln
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+var cursorID uint64
+
+// SelectCursor runs query in the transaction tx through a server-side cursor
+// (DECLARE CURSOR), fetching batchSize rows at a time into dest (a pointer to
+// a slice) and calling f after each batch, so large result sets are read with
+// flat memory. Iteration stops at the first error from f, which is returned.
+// Only Postgres supports cursors outside of stored procedures.
+func SelectCursor(ctx context.Context, tx DB, query string, dest, params interface{}, batchSize int, f func() error) error {
+	if !InTx(tx) {
+		return errors.New("cursor outside of a transaction")
+	}
+	if dialect := DialectOf(tx); dialect != Postgres && dialect != UnknownDialect {
+		return errors.Errorf("cursor: %v does not support cursors", dialect)
+	}
+	if batchSize <= 0 {
+		return errors.New("cursor: batch size must be positive")
+	}
+	if !isSlicePtr(dest) {
+		return errors.New("cursor: dest must be a pointer to a slice")
+	}
+
+	name := fmt.Sprintf("sqln_cursor_%v", atomic.AddUint64(&cursorID, 1))
+	// Cursor names are unique, so their statements are not prepared.
+	ctx = withInternal(ctx)
+	declare := "DECLARE " + name + " NO SCROLL CURSOR FOR " + strings.TrimSuffix(strings.TrimSpace(query), ";") + ";"
+	if _, err := ExecUnprepared(ctx, tx, declare, params); err != nil {
+		return errors.Wrap(err, "declare cursor")
+	}
+
+	fetch := fmt.Sprintf("FETCH FORWARD %v FROM %v;", batchSize, name)
+	slice := reflect.ValueOf(dest).Elem()
+	for {
+		slice.Set(reflect.Zero(slice.Type()))
+		if err := SelectUnprepared(ctx, tx, fetch, dest, nil); err != nil {
+			return errors.Wrap(err, "fetch cursor")
+		}
+		n := slice.Len()
+		if n == 0 {
+			break
+		}
+		if err := f(); err != nil {
+			return err
+		}
+		if n < batchSize {
+			break
+		}
+	}
+
+	if _, err := ExecUnprepared(ctx, tx, "CLOSE "+name+";", nil); err != nil {
+		return errors.Wrap(err, "close cursor")
+	}
+	return nil
+}
+
+// SelectCursor runs query through a server-side cursor, see the SelectCursor
+// function.
+func (d *Database) SelectCursor(ctx context.Context, query string, dest, params interface{}, batchSize int, f func() error) error {
+	return SelectCursor(ctx, d, query, dest, params, batchSize, f)
+}
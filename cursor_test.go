@@ -0,0 +1,84 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// cursorDB serves FETCH statements from rows, recording every query.
+type cursorDB struct {
+	DB
+	rows    []int
+	queries []string
+}
+
+func (d *cursorDB) TxLevel() int { return 1 }
+func (d *cursorDB) InTx() bool   { return true }
+
+func (d *cursorDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	d.queries = append(d.queries, query)
+	return nil, nil
+}
+
+func (d *cursorDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	d.queries = append(d.queries, query)
+	n := 2
+	if n > len(d.rows) {
+		n = len(d.rows)
+	}
+	*dest.(*[]int) = append(*dest.(*[]int), d.rows[:n]...)
+	d.rows = d.rows[n:]
+	return nil
+}
+
+func TestSelectCursor(t *testing.T) {
+	ctx := context.Background()
+	db := &cursorDB{rows: []int{1, 2, 3, 4, 5}}
+
+	var (
+		ids     []int
+		batches [][]int
+	)
+	err := SelectCursor(ctx, db, "SELECT id FROM users WHERE org = :org;", &ids, map[string]interface{}{"org": 1}, 2, func() error {
+		batches = append(batches, ids)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(batches, [][]int{{1, 2}, {3, 4}, {5}}) {
+		t.Fatalf("unexpected batches: %v", batches)
+	}
+	if len(db.queries) != 5 {
+		t.Fatalf("unexpected queries: %v", db.queries)
+	}
+	if q := db.queries[0]; !strings.HasPrefix(q, "DECLARE sqln_cursor_") || !strings.HasSuffix(q, "CURSOR FOR SELECT id FROM users WHERE org = :org;") {
+		t.Fatalf("unexpected declare: %v", q)
+	}
+	if q := db.queries[1]; !strings.HasPrefix(q, "FETCH FORWARD 2 FROM sqln_cursor_") {
+		t.Fatalf("unexpected fetch: %v", q)
+	}
+	if q := db.queries[4]; !strings.HasPrefix(q, "CLOSE sqln_cursor_") {
+		t.Fatalf("unexpected close: %v", q)
+	}
+}
+
+func TestSelectCursorUnsupported(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+	var ids []int
+	noop := func() error { return nil }
+
+	if err := db.SelectCursor(ctx, "SELECT 1;", &ids, nil, 10, noop); err == nil {
+		t.Fatal("expected error outside of a transaction")
+	}
+	err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		return SelectCursor(ctx, tx, "SELECT 1;", &ids, nil, 10, noop)
+	})
+	if err == nil || !strings.Contains(err.Error(), "does not support cursors") {
+		t.Fatalf("expected unsupported error, got %v", err)
+	}
+}
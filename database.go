@@ -7,18 +7,28 @@ package sqln
 import (
 	"context"
 	"database/sql"
-	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/semaphore"
 )
 
 // New wraps a sqlx database.
-func New(dbx *sqlx.DB) *Database {
-	return &Database{
-		X:        dbx,
-		stmtsMtx: &sync.Mutex{},
-		stmts:    make(map[string]*sqlx.NamedStmt),
+func New(dbx *sqlx.DB, opts ...Option) *Database {
+	return newBackend(dbx, opts...)
+}
+
+// Option configures a Database.
+type Option func(*Database)
+
+// WithCacheSize bounds the statement cache to n statements, closing the least
+// recently used statement when full. Zero (the default) is unbounded.
+func WithCacheSize(n int) Option {
+	return func(d *Database) {
+		d.cache.capacity = n
 	}
 }
 
@@ -28,6 +38,10 @@ type DB interface {
 	Exec(ctx context.Context, query string, params interface{}) (sql.Result, error)
 	Get(ctx context.Context, query string, dest, params interface{}) error
 	Select(ctx context.Context, query string, dest, params interface{}) error
+	// ExecReturning executes a statement with a RETURNING clause, scanning
+	// the returned columns into dest (a pointer to a slice for multiple
+	// rows). Unlike Get it is routed as a write.
+	ExecReturning(ctx context.Context, query string, dest, params interface{}) error
 
 	// Stmt creates a named statement if one does not exist. It is not safe
 	// to Close the returned statement.
@@ -39,48 +53,144 @@ type DB interface {
 
 // Database wraps a sqlx.DB and manages NamedStmt's.
 type Database struct {
+	// X is the underlying sqlx database.
 	X *sqlx.DB
 
+	drv backend
+
+	dialect Dialect
+	// mapper, if set, maps columns to fields by mapperTag (see WithMapper).
+	mapper    *reflectx.Mapper
+	mapperTag string
+
 	tx      *sqlx.Tx
+	conn    *connExt
 	txLevel int
+	txState *txState
+
+	// cache is shared with transactions.
+	cache *stmtCache
+
+	strict    StrictOptions
+	allowlist map[string]bool
+	policies  map[string]QueryPolicy
+
+	// registry is shared with transactions.
+	registry *registry
+
+	slow *slowPlans
+	// slowLog is shared with transactions.
+	slowLog *slowLog
+	locks   *LockDiagnostics
+	// plans is shared with transactions.
+	plans        *planCheck
+	planBaseline *PlanBaseline
+
+	// queryStats is shared with transactions.
+	queryStats *queryStats
+	// usage is shared with transactions.
+	usage *usageTracker
+
+	// stats is shared with transactions.
+	stats *dbStats
+
+	watchdog  *TxWatchdog
+	txBudget  *TxBudget
+	txSummary func(context.Context, TxSummary)
+	limits    *limiter
+	rowLimit  *RowLimit
+	classes   map[string]*semaphore.Weighted
+	dynamic   func(query string) bool
 
-	// stmtsMtx serializes access to the stmts map.
-	stmtsMtx *sync.Mutex
-	stmts    map[string]*sqlx.NamedStmt
+	closeTimeout     time.Duration
+	queryTimeout     time.Duration
+	statementTimeout time.Duration
+	deadlineTimeouts bool
+	connStmts        bool
+	notFound         bool
 }
 
 // Exec a SQL statement.
-func (d *Database) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
-	s, err := d.Stmt(query)
+func (d *Database) Exec(ctx context.Context, query string, params interface{}) (res sql.Result, err error) {
+	if d.isDynamic(query) {
+		return d.ExecUnprepared(ctx, query, params)
+	}
+	defer func() { err = nameErr(ctx, query, d.diagnoseLocks(ctx, query, err)) }()
+	ctx, done, err := d.begin(ctx, "Exec", query)
 	if err != nil {
 		return nil, err
 	}
+	defer done()
+	defer d.observe(ctx, query, params, time.Now())
+	defer d.queryStats.measure(query, time.Now(), &err)
 
+	if err := d.checkQuery(ctx, query); err != nil {
+		return nil, err
+	}
+	if err := d.checkPlan(ctx, query, params); err != nil {
+		return nil, err
+	}
+	s, release, err := d.acquire(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if err := d.checkParams(s, params); err != nil {
+		return nil, err
+	}
 	if params == nil {
 		params = struct{}{}
 	}
+	params = d.arrayParams(params)
 
 	exec := s.ExecContext
 	if d.tx != nil {
-		exec = d.tx.NamedStmt(s).ExecContext
+		exec = d.txStmt(s).ExecContext
 	}
 	return exec(ctx, params)
 }
 
 // Get a single record.
-func (d *Database) Get(ctx context.Context, query string, dest, params interface{}) error {
-	s, err := d.Stmt(query)
+func (d *Database) Get(ctx context.Context, query string, dest, params interface{}) (err error) {
+	if d.isDynamic(query) {
+		return d.GetUnprepared(ctx, query, dest, params)
+	}
+	defer func() { err = d.notFoundErr(ctx, nameErr(ctx, query, d.diagnoseLocks(ctx, query, err))) }()
+	ctx, done, err := d.begin(ctx, "Get", query)
+	if err != nil {
+		return err
+	}
+	defer done()
+	defer d.observe(ctx, query, params, time.Now())
+	defer d.queryStats.measure(query, time.Now(), &err)
+
+	if err := d.checkQuery(ctx, query); err != nil {
+		return err
+	}
+	if err := d.checkPlan(ctx, query, params); err != nil {
+		return err
+	}
+	s, release, err := d.acquire(ctx, query)
 	if err != nil {
 		return err
 	}
+	defer release()
 
+	if err := d.checkParams(s, params); err != nil {
+		return err
+	}
 	if params == nil {
 		params = struct{}{}
 	}
+	params = d.arrayParams(params)
+	if d.strict.GetOne || d.strict.AllFields || isMapDest(dest) {
+		return d.strictQuery(ctx, s, dest, params, true)
+	}
 
 	get := s.GetContext
 	if d.tx != nil {
-		get = d.tx.NamedStmt(s).GetContext
+		get = d.txStmt(s).GetContext
 	}
 	if err := get(ctx, dest, params); err != nil {
 		return err
@@ -90,19 +200,56 @@ func (d *Database) Get(ctx context.Context, query string, dest, params interface
 }
 
 // Select multiple records.
-func (d *Database) Select(ctx context.Context, query string, dest, params interface{}) error {
-	s, err := d.Stmt(query)
+func (d *Database) Select(ctx context.Context, query string, dest, params interface{}) (err error) {
+	if d.isDynamic(query) {
+		return d.SelectUnprepared(ctx, query, dest, params)
+	}
+	defer func() { err = nameErr(ctx, query, d.diagnoseLocks(ctx, query, err)) }()
+	ctx, done, err := d.begin(ctx, "Select", query)
 	if err != nil {
 		return err
 	}
+	defer done()
+	defer d.observe(ctx, query, params, time.Now())
+	defer d.queryStats.measure(query, time.Now(), &err)
 
+	if err := d.checkQuery(ctx, query); err != nil {
+		return err
+	}
+	if err := d.checkPlan(ctx, query, params); err != nil {
+		return err
+	}
+	s, release, err := d.acquire(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := d.checkParams(s, params); err != nil {
+		return err
+	}
 	if params == nil {
 		params = struct{}{}
 	}
+	params = d.arrayParams(params)
+	if d.rowLimit != nil && !internal(ctx) {
+		queryx := s.QueryxContext
+		if d.tx != nil {
+			queryx = d.txStmt(s).QueryxContext
+		}
+		rows, err := queryx(ctx, params)
+		if err != nil {
+			return err
+		}
+		return d.limitedScan(ctx, query, rows, dest)
+	}
+	if d.strict.AllFields || isMapDest(dest) {
+		return d.strictQuery(ctx, s, dest, params, false)
+	}
 
 	sel := s.SelectContext
 	if d.tx != nil {
-		sel = d.tx.NamedStmt(s).SelectContext
+		sel = d.txStmt(s).SelectContext
 	}
 	if err := sel(ctx, dest, params); err != nil {
 		return err
@@ -111,67 +258,180 @@ func (d *Database) Select(ctx context.Context, query string, dest, params interf
 	return nil
 }
 
+// ExecReturning executes a statement and scans its RETURNING columns into
+// dest.
+func (d *Database) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	if isSlicePtr(dest) {
+		return d.Select(ctx, query, dest, params)
+	}
+	return d.Get(ctx, query, dest, params)
+}
+
 // Transact will run the function that is passed in, rolling back all SQL
 // statements if an error is returned.
 // NOTE: A non-nil TxOptions struct is accepted to encourage thoughtful
 // selection of transaction isolation levels.
 // NOTE: Nested transactions are not currently supported and will return an error.
+// NOTE: Settings from WithTxSettings are applied when the transaction begins.
+// NOTE: A commit error matching ErrCommitAmbiguous means the transaction may
+// have committed.
+// NOTE: Once ctx is done the transaction is rolled back, without waiting for
+// f to return, and its statements fail. It is never committed: Transact
+// returns an error matching ctx.Err() (ie. context.Canceled), and any error
+// returned by f, wrapped with the transaction level.
 func (d *Database) Transact(ctx context.Context, opts sql.TxOptions, f func(DB) error) error {
+	return d.transact(ctx, opts, f, nil)
+}
+
+// transact runs f in a transaction. If set, beforeCommit is run after f
+// succeeds.
+func (d *Database) transact(ctx context.Context, opts sql.TxOptions, f func(DB) error, beforeCommit func(*sqlx.Tx) error) (err error) {
 	if d.tx != nil {
 		// TODO: Support nested tx.
 		return errors.New("nested tx not currently supported")
 	}
+	if d.strict.Deadline {
+		if _, ok := ctx.Deadline(); !ok {
+			return ErrNoDeadline
+		}
+	}
+	if d.stats.isClosed() {
+		return ErrClosed
+	}
+	if d.conn == nil {
+		release, err := d.acquireClass(ctx)
+		if err != nil {
+			return err
+		}
+		if release != nil {
+			defer release()
+		}
+	}
 
-	tx, err := d.X.BeginTxx(ctx, &opts)
+	begin := d.drv.BeginTxx
+	if d.conn != nil {
+		begin = d.conn.BeginTxx
+	}
+	txLvl := d.txLevel + 1
+	tx, err := begin(ctx, &opts)
 	if err != nil {
+		if ctx.Err() != nil {
+			return errors.Wrapf(err, "tx level %v: begin", txLvl)
+		}
 		return err
 	}
+	atomic.AddInt64(&d.stats.activeTx, 1)
+	defer atomic.AddInt64(&d.stats.activeTx, -1)
 
-	txLvl := d.txLevel + 1
-	if err := f(&Database{
-		X:        d.X,
-		tx:       tx,
-		txLevel:  txLvl,
-		stmtsMtx: d.stmtsMtx,
-		stmts:    d.stmts,
-	}); err != nil {
+	txd := *d
+	txd.tx = tx
+	txd.txLevel = txLvl
+	txd.txState = &txState{started: time.Now(), budget: d.txBudget}
+	if d.watchdog != nil && d.watchdog.OnLongTx != nil {
+		defer d.watchdog.watch(txLvl, txd.txState)()
+	}
+	outcome := TxRolledBack
+	if d.txSummary != nil {
+		defer func() { d.summarize(ctx, opts, txLvl, txd.txState, outcome, err) }()
+	}
+	if s, ok := d.txSettings(ctx); ok {
+		err = s.Apply(ctx, &txd)
+	}
+	if err == nil {
+		err = f(Wrap(&txd, txMiddleware(ctx)...))
+	}
+	if derr := txd.dropTempTables(ctx); err == nil {
+		err = derr
+	}
+	if cerr := ctx.Err(); cerr != nil {
+		// The transaction was rolled back when ctx was done.
+		tx.Rollback()
+		return errors.Wrapf(&canceledTxError{ctxErr: cerr, err: err}, "tx level %v", txLvl)
+	}
+	if err != nil {
 		if err := tx.Rollback(); err != nil {
 			return errors.Wrapf(err, "tx level %v: rollback", txLvl)
 		}
 		return errors.Wrapf(err, "tx level %v", txLvl)
 	}
+	if beforeCommit != nil {
+		if err := beforeCommit(tx); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "tx level %v", txLvl)
+		}
+	}
 
-	return errors.Wrapf(tx.Commit(), "tx level %v: commit", txLvl)
+	if err = CommitError(tx.Commit()); err != nil {
+		outcome = TxCommitFailed
+	} else {
+		outcome = TxCommitted
+	}
+	return errors.Wrapf(err, "tx level %v: commit", txLvl)
+}
+
+// WithTx returns a Database that runs every operation in tx, sharing d's
+// statement cache, so a transaction begun elsewhere (ie. by another library)
+// can be used through the DB interface. The caller remains responsible for
+// committing or rolling back tx; Transact on the returned Database fails as it
+// would for a nested transaction.
+func (d *Database) WithTx(tx *sqlx.Tx) *Database {
+	txd := *d
+	txd.tx = tx
+	txd.txLevel = d.txLevel + 1
+	txd.txState = &txState{started: time.Now()}
+	return &txd
 }
 
 // Stmt creates and/or retrieves a named statement.
+//
+// Deprecated: Use StmtContext, since preparing without a context blocks for
+// as long as the database does.
 func (d *Database) Stmt(query string) (*sqlx.NamedStmt, error) {
-	// Fetch an already-prepared statement.
-	d.stmtsMtx.Lock()
-	defer d.stmtsMtx.Unlock()
+	return d.StmtContext(context.Background(), query)
+}
 
-	s, ok := d.stmts[query]
-	if ok {
-		return s, nil
+// StmtContext creates and/or retrieves a named statement, preparing it with
+// ctx.
+// NOTE: When the cache is bounded the statement is closed once it is evicted.
+func (d *Database) StmtContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	return d.cache.get(ctx, query)
+}
+
+// txStmt returns s for use in the transaction. Wrappers are cached for the
+// life of the transaction since creating one allocates. A cached wrapper is not
+// reused once s is evicted, as s is then never returned by the cache again.
+func (d *Database) txStmt(s *sqlx.NamedStmt) *sqlx.NamedStmt {
+	t := d.txState
+	if t == nil {
+		return d.tx.NamedStmt(s)
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	ts, ok := t.stmts[s]
+	if !ok {
+		if t.stmts == nil {
+			t.stmts = make(map[*sqlx.NamedStmt]*sqlx.NamedStmt)
+		}
+		ts = d.tx.NamedStmt(s)
+		t.stmts[s] = ts
 	}
+	return ts
+}
 
-	// Prepare the named statement.
-	stmt, err := d.X.PrepareNamed(query)
-	if err != nil {
-		return nil, err
+// acquire retrieves a named statement that will not be closed by eviction
+// until release is called.
+func (d *Database) acquire(ctx context.Context, query string) (*sqlx.NamedStmt, func(), error) {
+	if d.scoped() {
+		s, err := d.scopedStmt(ctx, query)
+		return s, func() {}, err
 	}
-	d.stmts[query] = stmt
-	return stmt, nil
+	return d.cache.acquire(ctx, query)
 }
 
-// Close all managed named statements. Does not close underlying *sqlx.DB.
+// Close waits for in-flight operations (see WithCloseTimeout) and closes all
+// managed named statements. Does not close underlying *sqlx.DB.
 func (d *Database) Close() error {
-	d.stmtsMtx.Lock()
-	defer d.stmtsMtx.Unlock()
-	for _, stmt := range d.stmts {
-		if err := stmt.Close(); err != nil {
-			return err
-		}
-	}
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), d.closeTimeout)
+	defer cancel()
+	return d.Shutdown(ctx)
 }
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"log"
 	"testing"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -12,7 +13,7 @@ import (
 	"github.com/pkg/errors"
 )
 
-func ExampleUsage() {
+func Example_usage() {
 	type SignupUserRequest struct {
 		ID    string `db:"id"`
 		Email string `db:"email"`
@@ -129,3 +130,126 @@ func TestDB(t *testing.T) {
 		t.Fatalf("close: %v", err)
 	}
 }
+
+func TestTransactCanceled(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE events (id INTEGER);", nil); err != nil {
+		t.Fatal(err)
+	}
+	errFailed := errors.New("failed")
+
+	cases := []struct {
+		name string
+		// before cancels the context before Transact is called.
+		before bool
+		// f runs in the transaction with cancel, cancelling its context.
+		f       func(ctx context.Context, cancel func(), tx DB) error
+		ctxErr  error
+		wrapped error
+	}{
+		{
+			name:   "canceled before begin",
+			before: true,
+			f: func(ctx context.Context, cancel func(), tx DB) error {
+				t.Fatal("expected the transaction not to begin")
+				return nil
+			},
+			ctxErr: context.Canceled,
+		},
+		{
+			name: "canceled and nil returned",
+			f: func(ctx context.Context, cancel func(), tx DB) error {
+				if _, err := tx.Exec(ctx, "INSERT INTO events (id) VALUES (1);", nil); err != nil {
+					return err
+				}
+				cancel()
+				return nil
+			},
+			ctxErr: context.Canceled,
+		},
+		{
+			name: "statement after cancel",
+			f: func(ctx context.Context, cancel func(), tx DB) error {
+				if _, err := tx.Exec(ctx, "INSERT INTO events (id) VALUES (1);", nil); err != nil {
+					return err
+				}
+				cancel()
+				_, err := tx.Exec(ctx, "INSERT INTO events (id) VALUES (2);", nil)
+				return err
+			},
+			ctxErr: context.Canceled,
+		},
+		{
+			name: "canceled and other error returned",
+			f: func(ctx context.Context, cancel func(), tx DB) error {
+				if _, err := tx.Exec(ctx, "INSERT INTO events (id) VALUES (1);", nil); err != nil {
+					return err
+				}
+				cancel()
+				return errFailed
+			},
+			ctxErr:  context.Canceled,
+			wrapped: errFailed,
+		},
+		{
+			name: "deadline exceeded",
+			f: func(ctx context.Context, cancel func(), tx DB) error {
+				if _, err := tx.Exec(ctx, "INSERT INTO events (id) VALUES (1);", nil); err != nil {
+					return err
+				}
+				<-ctx.Done()
+				return nil
+			},
+			ctxErr: context.DeadlineExceeded,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var (
+				txCtx  context.Context
+				cancel func()
+			)
+			if c.ctxErr == context.DeadlineExceeded {
+				txCtx, cancel = context.WithTimeout(ctx, 50*time.Millisecond)
+			} else {
+				txCtx, cancel = context.WithCancel(ctx)
+			}
+			defer cancel()
+			if c.before {
+				cancel()
+			}
+
+			err := db.Transact(txCtx, sql.TxOptions{}, func(tx DB) error {
+				return c.f(txCtx, cancel, tx)
+			})
+			if !errors.Is(err, c.ctxErr) {
+				t.Fatalf("expected %v, got %v", c.ctxErr, err)
+			}
+			if c.wrapped != nil && !errors.Is(err, c.wrapped) {
+				t.Fatalf("expected %v to wrap %v", err, c.wrapped)
+			}
+			var n int
+			if err := db.Get(ctx, "SELECT COUNT(*) FROM events;", &n, nil); err != nil {
+				t.Fatal(err)
+			}
+			if n != 0 {
+				t.Fatalf("expected no commit, found %v rows", n)
+			}
+		})
+	}
+
+	// Cancelling after Transact returns has no effect.
+	txCtx, cancel := context.WithCancel(ctx)
+	if err := db.Transact(txCtx, sql.TxOptions{}, func(tx DB) error {
+		_, err := tx.Exec(txCtx, "INSERT INTO events (id) VALUES (1);", nil)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	var n int
+	if err := db.Get(ctx, "SELECT COUNT(*) FROM events;", &n, nil); err != nil || n != 1 {
+		t.Fatalf("expected the commit to persist, got %v rows, %v", n, err)
+	}
+}
@@ -0,0 +1,156 @@
+package sqln
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StmtInfo describes a cached statement.
+type StmtInfo struct {
+	Query string
+	// Name is the query name, if any (see QueryName).
+	Name string
+	// Hits is the number of operations served by the statement since it was
+	// prepared, excluding the first.
+	Hits        int64
+	Prepared    time.Time
+	PrepareTime time.Duration
+	LastUsed    time.Time
+	// InUse is the number of operations using the statement.
+	InUse int
+}
+
+// Statements returns the cached statements, the most hit first.
+func (d *Database) Statements() []StmtInfo {
+	c := d.cache
+	c.mtx.Lock()
+	infos := make([]StmtInfo, 0, len(c.entries))
+	for _, e := range c.entries {
+		infos = append(infos, StmtInfo{
+			Query:       e.query,
+			Hits:        e.hits,
+			Prepared:    e.prepared,
+			PrepareTime: e.prepareTime,
+			LastUsed:    e.used,
+			InUse:       e.refs,
+		})
+	}
+	c.mtx.Unlock()
+
+	for i := range infos {
+		infos[i].Name = QueryName(context.Background(), infos[i].Query)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Hits > infos[j].Hits })
+	return infos
+}
+
+// SlowQuery is an execution of a query that exceeded the WithSlowQueryLog
+// threshold.
+type SlowQuery struct {
+	Query       string
+	Name        string
+	Started     time.Time
+	Duration    time.Duration
+	Annotations map[string]string
+}
+
+// WithSlowQueryLog keeps the last size (defaults to 100) executions of queries
+// slower than threshold, returned by SlowQueries. Params are not kept.
+func WithSlowQueryLog(threshold time.Duration, size int) Option {
+	if size <= 0 {
+		size = 100
+	}
+	return func(d *Database) {
+		d.slowLog = &slowLog{threshold: threshold, queries: make([]SlowQuery, 0, size)}
+	}
+}
+
+// slowLog is a ring of slow queries, shared with transactions.
+type slowLog struct {
+	threshold time.Duration
+
+	mtx     sync.Mutex
+	queries []SlowQuery
+	next    int
+}
+
+func (l *slowLog) record(ctx context.Context, query string, start time.Time) {
+	elapsed := time.Since(start)
+	if elapsed < l.threshold {
+		return
+	}
+	q := SlowQuery{Query: query, Name: QueryName(ctx, query), Started: start, Duration: elapsed, Annotations: Annotations(ctx)}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if len(l.queries) < cap(l.queries) {
+		l.queries = append(l.queries, q)
+		return
+	}
+	l.queries[l.next] = q
+	l.next = (l.next + 1) % len(l.queries)
+}
+
+// SlowQueries returns the logged slow queries, the most recent first. It
+// returns nil unless WithSlowQueryLog is used.
+func (d *Database) SlowQueries() []SlowQuery {
+	if d.slowLog == nil {
+		return nil
+	}
+	l := d.slowLog
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	queries := make([]SlowQuery, 0, len(l.queries))
+	for i := len(l.queries) - 1; i >= 0; i-- {
+		queries = append(queries, l.queries[(l.next+i)%len(l.queries)])
+	}
+	return queries
+}
+
+// DebugInfo is the state of a Database served by DebugHandler.
+type DebugInfo struct {
+	Stats       Stats
+	Statements  []StmtInfo
+	InFlight    []Operation
+	SlowQueries []SlowQuery  `json:",omitempty"`
+	QueryStats  []QueryStats `json:",omitempty"`
+}
+
+// DebugInfo returns the current state of the Database, for inspection.
+func (d *Database) DebugInfo() DebugInfo {
+	return DebugInfo{
+		Stats:       d.Stats(),
+		Statements:  d.Statements(),
+		InFlight:    d.InFlight(),
+		SlowQueries: d.SlowQueries(),
+		QueryStats:  d.QueryStats(),
+	}
+}
+
+// DebugHandler returns a handler serving DebugInfo as JSON, ie.
+//
+//	http.Handle("/debug/sqln", db.DebugHandler())
+//
+// Queries are served in full, so it should not be exposed publicly.
+func (d *Database) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(d.DebugInfo()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// PublishDebugInfo publishes the output of DebugInfo as the expvar name. Like
+// expvar.Publish it panics if name is already in use.
+func (d *Database) PublishDebugInfo(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return d.DebugInfo()
+	}))
+}
@@ -0,0 +1,42 @@
+package sqln
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDebugHandler(t *testing.T) {
+	base := sqliteDB(t)
+	db := New(base.X, WithSlowQueryLog(0, 2))
+	defer db.Close()
+	ctx := context.Background()
+
+	NameQuery("two", "SELECT 2 AS two;")
+	var n int
+	for _, q := range []string{"SELECT 1;", "SELECT 2 AS two;", "SELECT 1;"} {
+		if err := db.Get(ctx, q, &n, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stmts := db.Statements()
+	if len(stmts) != 2 || stmts[0].Query != "SELECT 1;" || stmts[0].Hits != 1 || stmts[1].Name != "two" || stmts[1].Hits != 0 || stmts[1].Prepared.IsZero() {
+		t.Fatalf("unexpected statements: %+v", stmts)
+	}
+	slow := db.SlowQueries()
+	if len(slow) != 2 || slow[0].Query != "SELECT 1;" || slow[1].Name != "two" {
+		t.Fatalf("unexpected slow queries: %+v", slow)
+	}
+
+	rec := httptest.NewRecorder()
+	db.DebugHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/sqln", nil))
+	var info DebugInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Stats.Statements != 2 || len(info.Statements) != 2 || len(info.SlowQueries) != 2 || info.QueryStats != nil {
+		t.Fatalf("unexpected debug info: %+v", info)
+	}
+}
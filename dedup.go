@@ -0,0 +1,136 @@
+package sqln
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/gob"
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// WithDedup returns a context in which identical reads through the Dedup
+// middleware (ie. a lookup repeated by several layers handling one request)
+// run once. Typically applied once per request.
+func WithDedup(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dedupKey, &dedupScope{results: make(map[dedupResultKey]dedupResult)})
+}
+
+// Dedup returns a middleware that answers a Get or Select in a WithDedup
+// context from an earlier one with the same query, params and destination
+// type. Results are copied with encoding/gob, so only exported fields are
+// kept; a result that cannot be encoded is not reused. Any write through the
+// middleware, including in a transaction, forgets every result of the
+// context, and reads in transactions are always run.
+func Dedup() Middleware {
+	return newDedupDB
+}
+
+type dedupScope struct {
+	mtx     sync.Mutex
+	results map[dedupResultKey]dedupResult
+}
+
+type dedupResultKey struct {
+	query, params string
+	dest          reflect.Type
+}
+
+type dedupResult struct {
+	data []byte
+	// err is set for sql.ErrNoRows, which is reused like a result.
+	err error
+}
+
+func (s *dedupScope) forget() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.results = make(map[dedupResultKey]dedupResult)
+}
+
+func newDedupDB(db DB) DB {
+	return &dedupDB{BaseDB: BaseDB{DB: db, Wrap: newDedupTxDB}}
+}
+
+func newDedupTxDB(db DB) DB {
+	return &dedupDB{BaseDB: BaseDB{DB: db, Wrap: newDedupTxDB}, tx: true}
+}
+
+type dedupDB struct {
+	BaseDB
+	tx bool
+}
+
+func (d *dedupDB) read(ctx context.Context, query string, dest, params interface{}, run func() error) error {
+	s, ok := ctx.Value(dedupKey).(*dedupScope)
+	if !ok || d.tx {
+		return run()
+	}
+	mapper, _ := mapperOf(d.DB)
+	m, ok := withParams(mapper, params, nil).(map[string]interface{})
+	if !ok {
+		return run()
+	}
+	key := dedupResultKey{query: query, params: paramsHash(m), dest: reflect.TypeOf(dest)}
+
+	s.mtx.Lock()
+	r, hit := s.results[key]
+	s.mtx.Unlock()
+	if hit {
+		if r.err != nil {
+			return r.err
+		}
+		v := reflect.ValueOf(dest).Elem()
+		v.Set(reflect.Zero(v.Type()))
+		if err := gob.NewDecoder(bytes.NewReader(r.data)).Decode(dest); err == nil {
+			return nil
+		}
+	}
+
+	if err := run(); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.mtx.Lock()
+			s.results[key] = dedupResult{err: err}
+			s.mtx.Unlock()
+		}
+		return err
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(dest); err != nil {
+		return nil
+	}
+	s.mtx.Lock()
+	s.results[key] = dedupResult{data: buf.Bytes()}
+	s.mtx.Unlock()
+	return nil
+}
+
+func (d *dedupDB) wrote(ctx context.Context) {
+	if s, ok := ctx.Value(dedupKey).(*dedupScope); ok {
+		s.forget()
+	}
+}
+
+func (d *dedupDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	return d.read(ctx, query, dest, params, func() error {
+		return d.DB.Get(ctx, query, dest, params)
+	})
+}
+
+func (d *dedupDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	return d.read(ctx, query, dest, params, func() error {
+		return d.DB.Select(ctx, query, dest, params)
+	})
+}
+
+func (d *dedupDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	defer d.wrote(ctx)
+	return d.DB.Exec(ctx, query, params)
+}
+
+func (d *dedupDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	defer d.wrote(ctx)
+	return d.DB.ExecReturning(ctx, query, dest, params)
+}
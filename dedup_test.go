@@ -0,0 +1,74 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDedup(t *testing.T) {
+	base := sqliteDB(t)
+	ctx := context.Background()
+	if _, err := base.Exec(ctx, "CREATE TABLE dedup_users (id INTEGER PRIMARY KEY, name TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := base.Exec(ctx, "INSERT INTO dedup_users (id, name) VALUES (1, 'a');", nil); err != nil {
+		t.Fatal(err)
+	}
+	db := Wrap(base, Dedup())
+
+	const (
+		get  = "SELECT name FROM dedup_users WHERE id = :id;"
+		list = "SELECT name FROM dedup_users ORDER BY id;"
+	)
+	// Writes that bypass the middleware show whether a read was repeated.
+	rename := func(name string) {
+		if _, err := base.X.Exec("UPDATE dedup_users SET name = ? WHERE id = 1;", name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	name := func(ctx context.Context, id int) string {
+		var name string
+		if err := db.Get(ctx, get, &name, map[string]interface{}{"id": id}); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+
+	req := WithDedup(ctx)
+	if n := name(req, 1); n != "a" {
+		t.Fatalf("unexpected name %q", n)
+	}
+	rename("b")
+	if n := name(req, 1); n != "a" {
+		t.Fatalf("expected the first result, got %q", n)
+	}
+	if n := name(ctx, 1); n != "b" {
+		t.Fatalf("expected another context to read, got %q", n)
+	}
+	var names []string
+	if err := db.Select(req, list, &names, nil); err != nil || !reflect.DeepEqual(names, []string{"b"}) {
+		t.Fatalf("unexpected names %v %v", names, err)
+	}
+	var missing string
+	if err := db.Get(req, get, &missing, map[string]interface{}{"id": 2}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+	if err := db.Get(req, get, &missing, map[string]interface{}{"id": 2}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows to be reused, got %v", err)
+	}
+
+	// A write through the middleware, even in a transaction, forgets results.
+	err := db.Transact(req, sql.TxOptions{}, func(tx DB) error {
+		_, err := tx.Exec(req, "UPDATE dedup_users SET name = 'c' WHERE id = 1;", nil)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := name(req, 1); n != "c" {
+		t.Fatalf("expected a read after the write, got %q", n)
+	}
+}
@@ -0,0 +1,96 @@
+package sqln
+
+import (
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Dialect identifies the SQL flavor spoken by the underlying driver.
+type Dialect string
+
+// Supported dialects.
+const (
+	UnknownDialect Dialect = ""
+	Postgres       Dialect = "postgres"
+	MySQL          Dialect = "mysql"
+	SQLite         Dialect = "sqlite"
+	SQLServer      Dialect = "sqlserver"
+)
+
+// bindType returns the sqlx bindvar type used by the dialect.
+func (d Dialect) bindType() int {
+	switch d {
+	case Postgres:
+		return sqlx.DOLLAR
+	case MySQL, SQLite:
+		return sqlx.QUESTION
+	case SQLServer:
+		return sqlx.AT
+	}
+	return sqlx.UNKNOWN
+}
+
+var (
+	driversMtx sync.RWMutex
+	drivers    = map[string]Dialect{
+		"postgres":         Postgres,
+		"pgx":              Postgres,
+		"cloudsqlpostgres": Postgres,
+		"nrpostgres":       Postgres,
+		"mysql":            MySQL,
+		"nrmysql":          MySQL,
+		"sqlite3":          SQLite,
+		"sqlite":           SQLite,
+		"nrsqlite3":        SQLite,
+		"sqlserver":        SQLServer,
+		"mssql":            SQLServer,
+		"azuresql":         SQLServer,
+	}
+)
+
+func init() {
+	// sqlx does not know some of the driver names above.
+	sqlx.BindDriver("sqlite", sqlx.QUESTION)
+	sqlx.BindDriver("mssql", sqlx.AT)
+	sqlx.BindDriver("azuresql", sqlx.AT)
+}
+
+// RegisterDriver associates a driver name (for instance a wrapped or
+// instrumented driver) with a dialect. This also registers the bindvar type
+// with sqlx so named statements are compiled for the right dialect.
+func RegisterDriver(driverName string, d Dialect) {
+	driversMtx.Lock()
+	drivers[driverName] = d
+	driversMtx.Unlock()
+
+	sqlx.BindDriver(driverName, d.bindType())
+}
+
+func dialectOf(driverName string) Dialect {
+	driversMtx.RLock()
+	defer driversMtx.RUnlock()
+	return drivers[driverName]
+}
+
+// Dialect returns the dialect of the underlying driver.
+func (d *Database) Dialect() Dialect {
+	return d.dialect
+}
+
+// DialectOf returns the dialect of db, or the DB it decorates (see
+// Unwrapper), if it is known, ie. db is a *Database or a Replicated. It
+// returns UnknownDialect otherwise.
+func DialectOf(db DB) Dialect {
+	for db != nil {
+		if d, ok := db.(interface{ Dialect() Dialect }); ok {
+			return d.Dialect()
+		}
+		u, ok := db.(Unwrapper)
+		if !ok {
+			break
+		}
+		db = u.Unwrap()
+	}
+	return UnknownDialect
+}
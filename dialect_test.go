@@ -0,0 +1,131 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+// sqliteDB returns a Database backed by a temporary SQLite database file.
+func sqliteDB(t testing.TB) *Database {
+	// A file is used rather than :memory: since every connection to an
+	// in-memory database is a new database.
+	dbx, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_foreign_keys=1&_busy_timeout=5000")
+	if err != nil {
+		t.Fatal("unable to open sqlite:", err)
+	}
+
+	d := New(dbx)
+	t.Cleanup(func() {
+		d.Close()
+		dbx.Close()
+	})
+	return d
+}
+
+func TestSQLite(t *testing.T) {
+	testDialect(t, sqliteDB(t), SQLite)
+}
+
+func TestDialectOf(t *testing.T) {
+	// Open does not connect, so no database is needed.
+	dbx, err := sqlx.Open("mysql", "user@/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := New(dbx)
+	defer d.Close()
+
+	for name, db := range map[string]DB{
+		"database":   d,
+		"middleware": Wrap(d, Guard(GuardOptions{}), Timestamps(SystemClock)),
+		"replicated": &Replicated{Primary: d},
+	} {
+		if got := DialectOf(db); got != MySQL {
+			t.Errorf("%v: expected %q, got %q", name, MySQL, got)
+		}
+	}
+	if got := DialectOf(&cursorDB{}); got != UnknownDialect {
+		t.Errorf("expected an unknown dialect, got %q", got)
+	}
+}
+
+func TestMySQL(t *testing.T) {
+	dsn, ok := os.LookupEnv("TEST_MYSQL_DSN")
+	if !ok {
+		t.Skip("TEST_MYSQL_DSN not set")
+	}
+	dbx, err := sqlx.Connect("mysql", dsn)
+	if err != nil {
+		t.Fatal("unable to connect to mysql:", err)
+	}
+	defer dbx.Close()
+	d := New(dbx)
+	defer d.Close()
+
+	if _, err := d.X.Exec("DROP TABLE IF EXISTS dialect_child, dialect_abc;"); err != nil {
+		t.Fatal(err)
+	}
+	testDialect(t, d, MySQL)
+}
+
+// testDialect runs named statements and error classification against d.
+func testDialect(t *testing.T, d *Database, exp Dialect) {
+	if d.Dialect() != exp {
+		t.Fatalf("expected dialect %q, got %q", exp, d.Dialect())
+	}
+
+	ctx := context.Background()
+	for _, q := range []string{
+		"CREATE TABLE dialect_abc (id INT, x INT NOT NULL, PRIMARY KEY(id));",
+		"CREATE TABLE dialect_child (id INT, abc_id INT, PRIMARY KEY(id), FOREIGN KEY (abc_id) REFERENCES dialect_abc(id));",
+	} {
+		if _, err := d.X.Exec(q); err != nil {
+			t.Fatal("unable to create table:", err)
+		}
+	}
+
+	const insert = "INSERT INTO dialect_abc (id, x) VALUES (:id, :x);"
+	err := d.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		for i := 1; i <= 3; i++ {
+			if _, err := tx.Exec(ctx, insert, map[string]interface{}{"id": i, "x": i * 10}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var xs []int
+	if err := d.Select(ctx, "SELECT x FROM dialect_abc WHERE id >= :min ORDER BY id;", &xs, map[string]interface{}{"min": 2}); err != nil {
+		t.Fatal(err)
+	}
+	if len(xs) != 2 || xs[0] != 20 || xs[1] != 30 {
+		t.Fatalf("unexpected select result: %v", xs)
+	}
+
+	cases := []struct {
+		query  string
+		params map[string]interface{}
+		class  ErrorClass
+	}{
+		{insert, map[string]interface{}{"id": 1, "x": 1}, ClassUniqueViolation},
+		{insert, map[string]interface{}{"id": 4, "x": nil}, ClassNotNullViolation},
+		{"INSERT INTO dialect_child (id, abc_id) VALUES (:id, :abc_id);", map[string]interface{}{"id": 1, "abc_id": 99}, ClassForeignKeyViolation},
+	}
+	for _, c := range cases {
+		_, err := d.Exec(ctx, c.query, c.params)
+		if got := Classify(errors.Wrap(err, "wrapped")); got != c.class {
+			t.Errorf("expected %v for %v, got %v (%v)", c.class, c.params, got, err)
+		}
+	}
+}
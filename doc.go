@@ -0,0 +1,3 @@
+// Package sqln wraps sqlx and manages a map of named statements. It requires
+// Go 1.23, since Rows returns a range-over-func iterator.
+package sqln
@@ -0,0 +1,55 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// backend is the connection pool a Database runs on. *sqlx.DB implements it;
+// other backends (ie. a mock in tests) can be used with newBackend. It is
+// internal so that sqlx does not become part of the API.
+type backend interface {
+	sqlx.ExtContext
+
+	PrepareNamed(query string) (*sqlx.NamedStmt, error)
+	PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error)
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error)
+	Connx(ctx context.Context) (*sqlx.Conn, error)
+	PingContext(ctx context.Context) error
+	Stats() sql.DBStats
+	Close() error
+}
+
+var _ backend = (*sqlx.DB)(nil)
+
+// newBackend is like New for any backend. X is only set when drv is a
+// *sqlx.DB.
+func newBackend(drv backend, opts ...Option) *Database {
+	d := &Database{
+		drv:      drv,
+		dialect:  dialectOf(drv.DriverName()),
+		cache:    newStmtCache(0, drv.PrepareNamedContext),
+		registry: &registry{queries: make(map[string]bool)},
+		stats:    &dbStats{ops: make(map[uint64]operation)},
+
+		closeTimeout: 10 * time.Second,
+	}
+	d.X, _ = drv.(*sqlx.DB)
+	if p, ok := drv.(interface {
+		PreparexContext(context.Context, string) (*sqlx.Stmt, error)
+	}); ok {
+		d.cache.preparex = p.PreparexContext
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.cache.ttl > 0 {
+		d.cache.done = make(chan struct{})
+		go d.cache.janitor()
+	}
+	return d
+}
@@ -0,0 +1,48 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// countingDriver counts the statements prepared on the pool.
+type countingDriver struct {
+	*sqlx.DB
+	prepared int
+}
+
+func (c *countingDriver) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	c.prepared++
+	return c.DB.PrepareNamedContext(ctx, query)
+}
+
+func TestNewBackend(t *testing.T) {
+	drv := &countingDriver{DB: sqliteDB(t).X}
+	db := newBackend(drv)
+	defer db.Close()
+	ctx := context.Background()
+
+	if db.X != nil {
+		t.Fatal("expected X to be nil for a custom driver")
+	}
+	var n int
+	for i := 0; i < 2; i++ {
+		if err := db.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if drv.prepared != 1 {
+		t.Fatalf("expected 1 prepare, got %v", drv.prepared)
+	}
+	if err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		return tx.Get(ctx, "SELECT 1;", &n, nil)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Healthy(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,31 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// errDryRun rolls back the transaction of TransactDryRun.
+var errDryRun = errors.New("sqln: dry run")
+
+// TransactDryRun runs f in a transaction that is always rolled back, returning
+// what f produced, ie. to preview the effect of a change. Writes in f are
+// visible to f but never committed.
+func TransactDryRun[T any](ctx context.Context, db DB, opts sql.TxOptions, f func(DB) (T, error)) (T, error) {
+	var res T
+	err := db.Transact(ctx, opts, func(tx DB) error {
+		var err error
+		res, err = f(tx)
+		if err != nil {
+			return err
+		}
+		return errDryRun
+	})
+	if errors.Is(err, errDryRun) {
+		return res, nil
+	}
+	var zero T
+	return zero, err
+}
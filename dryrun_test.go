@@ -0,0 +1,46 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestTransactDryRun(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, "CREATE TABLE accounts (id INTEGER, balance INTEGER);", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, "INSERT INTO accounts (id, balance) VALUES (1, 100);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	balance, err := TransactDryRun(ctx, db, sql.TxOptions{}, func(tx DB) (int, error) {
+		if _, err := tx.Exec(ctx, "UPDATE accounts SET balance = balance - 30 WHERE id = 1;", nil); err != nil {
+			return 0, err
+		}
+		var b int
+		err := tx.Get(ctx, "SELECT balance FROM accounts WHERE id = 1;", &b, nil)
+		return b, err
+	})
+	if err != nil || balance != 70 {
+		t.Fatalf("expected previewed balance of 70, got %v %v", balance, err)
+	}
+
+	var b int
+	if err := db.Get(ctx, "SELECT balance FROM accounts WHERE id = 1;", &b, nil); err != nil || b != 100 {
+		t.Fatalf("expected balance to be rolled back, got %v %v", b, err)
+	}
+
+	errBoom := errors.New("boom")
+	_, err = TransactDryRun(ctx, db, sql.TxOptions{}, func(tx DB) (int, error) {
+		return 1, errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package sqln
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Codec transforms column values on their way to and from the database, ie.
+// to encrypt them (see NewAESGCM).
+type Codec interface {
+	Encode(plaintext []byte) ([]byte, error)
+	Decode(encoded []byte) ([]byte, error)
+}
+
+// ErrNoCodec is returned binding or scanning an Encrypted value before
+// SetCodec is called.
+var ErrNoCodec = errors.New("sqln: no codec set")
+
+var (
+	codecMtx sync.RWMutex
+	codec    Codec
+)
+
+// SetCodec sets the Codec of Encrypted values, usually once at startup.
+func SetCodec(c Codec) {
+	codecMtx.Lock()
+	defer codecMtx.Unlock()
+	codec = c
+}
+
+func currentCodec() (Codec, error) {
+	codecMtx.RLock()
+	defer codecMtx.RUnlock()
+	if codec == nil {
+		return nil, ErrNoCodec
+	}
+	return codec, nil
+}
+
+// Encrypted is a value stored encoded by the Codec (see SetCodec), ie. for
+// field-level encryption of a binary (bytea, BLOB) column. V is marshaled as
+// JSON before encoding. A NULL column scans to the zero value and the zero
+// value is stored encoded like any other, so use a Null[T] V to store NULLs.
+// NOTE: Encrypted columns cannot be compared in queries since the same value
+// encrypts differently each time.
+type Encrypted[T any] struct {
+	V T
+}
+
+// EncryptedOf returns an Encrypted holding v.
+func EncryptedOf[T any](v T) Encrypted[T] {
+	return Encrypted[T]{V: v}
+}
+
+// Value implements driver.Valuer.
+func (e Encrypted[T]) Value() (driver.Value, error) {
+	c, err := currentCodec()
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(e.V)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal")
+	}
+	b, err = c.Encode(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode")
+	}
+	return b, nil
+}
+
+// Scan implements sql.Scanner.
+func (e *Encrypted[T]) Scan(src interface{}) error {
+	var b []byte
+	switch src := src.(type) {
+	case nil:
+		*e = Encrypted[T]{}
+		return nil
+	case []byte:
+		b = src
+	case string:
+		b = []byte(src)
+	default:
+		return errors.Errorf("cannot scan %T into an encrypted value", src)
+	}
+	c, err := currentCodec()
+	if err != nil {
+		return err
+	}
+	b, err = c.Decode(b)
+	if err != nil {
+		return errors.Wrap(err, "decode")
+	}
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return errors.Wrap(err, "unmarshal")
+	}
+	e.V = v
+	return nil
+}
+
+// KeyProvider supplies the keys of NewAESGCM. Keys are identified so they can
+// be rotated: values are encrypted with the current key and decrypted with
+// the key they were encrypted with.
+type KeyProvider interface {
+	// CurrentKey returns the key to encrypt with.
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key with id.
+	Key(id string) ([]byte, error)
+}
+
+// StaticKeys is a KeyProvider of fixed keys, by id.
+type StaticKeys struct {
+	Current string
+	Keys    map[string][]byte
+}
+
+// CurrentKey implements KeyProvider.
+func (k StaticKeys) CurrentKey() (string, []byte, error) {
+	key, err := k.Key(k.Current)
+	return k.Current, key, err
+}
+
+// Key implements KeyProvider.
+func (k StaticKeys) Key(id string) ([]byte, error) {
+	key, ok := k.Keys[id]
+	if !ok {
+		return nil, errors.Errorf("unknown key %q", id)
+	}
+	return key, nil
+}
+
+// NewAESGCM returns a Codec encrypting with AES-GCM, using 16, 24 or 32 byte
+// keys (AES-128, AES-192 or AES-256) from keys. The id of the key is stored
+// with each value.
+func NewAESGCM(keys KeyProvider) Codec {
+	return aesGCM{keys: keys}
+}
+
+type aesGCM struct {
+	keys KeyProvider
+}
+
+func (a aesGCM) aead(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encode returns the key id length, key id, nonce and sealed plaintext. The
+// key id is authenticated as additional data.
+func (a aesGCM) Encode(plaintext []byte) ([]byte, error) {
+	id, key, err := a.keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	if len(id) > 255 {
+		return nil, errors.Errorf("key id %q is too long", id)
+	}
+	aead, err := a.aead(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 1+len(id)+aead.NonceSize(), 1+len(id)+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	out[0] = byte(len(id))
+	copy(out[1:], id)
+	nonce := out[1+len(id):]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "nonce")
+	}
+	return aead.Seal(out, nonce, plaintext, out[:1+len(id)]), nil
+}
+
+// Decode opens a value from Encode.
+func (a aesGCM) Decode(encoded []byte) ([]byte, error) {
+	if len(encoded) < 1 || len(encoded) < 1+int(encoded[0]) {
+		return nil, errors.New("invalid ciphertext")
+	}
+	header := encoded[:1+int(encoded[0])]
+	key, err := a.keys.Key(string(header[1:]))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := a.aead(key)
+	if err != nil {
+		return nil, err
+	}
+	rest := encoded[len(header):]
+	if len(rest) < aead.NonceSize() {
+		return nil, errors.New("invalid ciphertext")
+	}
+	return aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], header)
+}
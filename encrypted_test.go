@@ -0,0 +1,66 @@
+package sqln
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestEncrypted(t *testing.T) {
+	ctx := context.Background()
+	var v Encrypted[string]
+	if err := v.Scan([]byte("x")); err != ErrNoCodec {
+		t.Fatalf("expected ErrNoCodec, got %v", err)
+	}
+
+	keys := StaticKeys{Current: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}}
+	SetCodec(NewAESGCM(keys))
+	defer SetCodec(nil)
+
+	db := sqliteDB(t)
+	if _, err := db.Exec(ctx, "CREATE TABLE encrypted_users (id INTEGER PRIMARY KEY, ssn BLOB, tags BLOB);", nil); err != nil {
+		t.Fatal(err)
+	}
+	type user struct {
+		ID   int                 `db:"id"`
+		SSN  Encrypted[string]   `db:"ssn"`
+		Tags Encrypted[[]string] `db:"tags"`
+	}
+	if _, err := InsertStruct(ctx, db, "encrypted_users", user{ID: 1, SSN: EncryptedOf("123-45-6789"), Tags: EncryptedOf([]string{"a"})}); err != nil {
+		t.Fatal(err)
+	}
+
+	var raw []byte
+	if err := db.Get(ctx, "SELECT ssn FROM encrypted_users WHERE id = 1;", &raw, nil); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("123-45-6789")) {
+		t.Fatal("expected the value to be stored encrypted")
+	}
+
+	// Rotating the key keeps earlier values readable.
+	keys.Keys["k2"] = bytes.Repeat([]byte{2}, 16)
+	keys.Current = "k2"
+	SetCodec(NewAESGCM(keys))
+	if _, err := db.Exec(ctx, "INSERT INTO encrypted_users (id, ssn) VALUES (2, :ssn);", map[string]interface{}{"ssn": EncryptedOf("987-65-4321")}); err != nil {
+		t.Fatal(err)
+	}
+	var u user
+	if err := db.Get(ctx, "SELECT id, ssn, tags FROM encrypted_users WHERE id = 1;", &u, nil); err != nil {
+		t.Fatal(err)
+	}
+	if u.SSN.V != "123-45-6789" || len(u.Tags.V) != 1 {
+		t.Fatalf("unexpected user: %+v", u)
+	}
+	if err := db.Get(ctx, "SELECT id, ssn, tags FROM encrypted_users WHERE id = 2;", &u, nil); err != nil {
+		t.Fatal(err)
+	}
+	if u.SSN.V != "987-65-4321" || u.Tags.V != nil {
+		t.Fatalf("unexpected user: %+v", u)
+	}
+
+	raw[len(raw)-1] ^= 1
+	if err := v.Scan(raw); err == nil {
+		t.Fatal("expected tampered ciphertext to fail")
+	}
+}
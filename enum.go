@@ -0,0 +1,155 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidEnum matches (with errors.Is) the *EnumError returned for a value
+// that is not registered for its enum type.
+var ErrInvalidEnum = errors.New("sqln: invalid enum value")
+
+// EnumError is returned when binding or scanning an Enum holding a value
+// that is not registered.
+type EnumError struct {
+	// Type is the name of the enum type.
+	Type  string
+	Value interface{}
+}
+
+func (e *EnumError) Error() string {
+	return fmt.Sprintf("sqln: invalid %v value %v", e.Type, e.Value)
+}
+
+// Is reports whether target is ErrInvalidEnum.
+func (e *EnumError) Is(target error) bool { return target == ErrInvalidEnum }
+
+var enums = struct {
+	sync.RWMutex
+	values map[reflect.Type][]interface{}
+}{values: make(map[reflect.Type][]interface{})}
+
+// RegisterEnum registers the allowed values of the enum type T, replacing any
+// registered before. Typically called from an init function.
+func RegisterEnum[T ~string | ~int](values ...T) {
+	vs := make([]interface{}, len(values))
+	for i, v := range values {
+		vs[i] = v
+	}
+	enums.Lock()
+	defer enums.Unlock()
+	enums.values[reflect.TypeOf((*T)(nil)).Elem()] = vs
+}
+
+// EnumValues returns the registered values of T, in registration order.
+func EnumValues[T ~string | ~int]() []T {
+	enums.RLock()
+	defer enums.RUnlock()
+	vs := enums.values[reflect.TypeOf((*T)(nil)).Elem()]
+	values := make([]T, len(vs))
+	for i, v := range vs {
+		values[i] = v.(T)
+	}
+	return values
+}
+
+// Enum holds a value of the enum type T (see RegisterEnum) for a column or
+// param. Values that are not registered fail with an *EnumError when bound
+// or scanned, before reaching the database or the caller. Use Null[Enum[T]]
+// for nullable columns.
+type Enum[T ~string | ~int] struct {
+	V T
+}
+
+// NewEnum returns an Enum holding v, or an *EnumError if v is not registered.
+func NewEnum[T ~string | ~int](v T) (Enum[T], error) {
+	e := Enum[T]{V: v}
+	return e, e.validate()
+}
+
+// validate returns an *EnumError if the value is not registered.
+func (e Enum[T]) validate() error {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	enums.RLock()
+	vs, ok := enums.values[t]
+	enums.RUnlock()
+	if !ok {
+		return errors.Errorf("sqln: enum %v is not registered", t)
+	}
+	for _, v := range vs {
+		if v.(T) == e.V {
+			return nil
+		}
+	}
+	return &EnumError{Type: t.String(), Value: e.V}
+}
+
+// Scan implements sql.Scanner.
+func (e *Enum[T]) Scan(src interface{}) error {
+	var n sql.Null[T]
+	if err := n.Scan(src); err != nil {
+		return err
+	}
+	if !n.Valid {
+		return &EnumError{Type: reflect.TypeOf((*T)(nil)).Elem().String(), Value: nil}
+	}
+	v := Enum[T]{V: n.V}
+	if err := v.validate(); err != nil {
+		return err
+	}
+	*e = v
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (e Enum[T]) Value() (driver.Value, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+	return sql.Null[T]{V: e.V, Valid: true}.Value()
+}
+
+// String returns the value in the format of its Postgres label.
+func (e Enum[T]) String() string {
+	return fmt.Sprint(e.V)
+}
+
+// PostgresEnumSQL returns the statement creating typeName as a Postgres enum
+// of the registered values of T, labeled in their fmt format.
+func PostgresEnumSQL[T ~string | ~int](typeName string) string {
+	values := EnumValues[T]()
+	labels := make([]string, len(values))
+	for i, v := range values {
+		labels[i] = pq.QuoteLiteral(fmt.Sprint(v))
+	}
+	return fmt.Sprintf("CREATE TYPE %v AS ENUM (%v);", typeName, strings.Join(labels, ", "))
+}
+
+// CheckPostgresEnum returns an error unless the Postgres enum typeName has
+// exactly the registered values of T as labels, in order, ie. to catch drift
+// between the code and the schema on boot.
+func CheckPostgresEnum[T ~string | ~int](ctx context.Context, db DB, typeName string) error {
+	var labels []string
+	if err := db.Select(ctx, `SELECT e.enumlabel FROM pg_enum e
+	JOIN pg_type t ON t.oid = e.enumtypid
+	WHERE t.typname = :name ORDER BY e.enumsortorder;`, &labels, map[string]interface{}{"name": typeName}); err != nil {
+		return errors.Wrapf(err, "check enum %v", typeName)
+	}
+	values := EnumValues[T]()
+	want := make([]string, len(values))
+	for i, v := range values {
+		want[i] = fmt.Sprint(v)
+	}
+	if strings.Join(labels, "\x00") != strings.Join(want, "\x00") {
+		return errors.Errorf("check enum %v: has labels %q, expected %q", typeName, labels, want)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type testStatus string
+
+type testLevel int
+
+func init() {
+	RegisterEnum[testStatus]("active", "it's done")
+	RegisterEnum[testLevel](1, 2, 3)
+}
+
+func TestEnum(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, "CREATE TABLE tasks (id INTEGER, status TEXT, level INTEGER, parent TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+	type task struct {
+		ID     int                    `db:"id"`
+		Status Enum[testStatus]       `db:"status"`
+		Level  Enum[testLevel]        `db:"level"`
+		Parent Null[Enum[testStatus]] `db:"parent"`
+	}
+	in := task{ID: 1, Status: Enum[testStatus]{V: "active"}, Level: Enum[testLevel]{V: 2}}
+	if _, err := db.Exec(ctx, "INSERT INTO tasks (id, status, level, parent) VALUES (:id, :status, :level, :parent);", in); err != nil {
+		t.Fatal(err)
+	}
+	var out task
+	if err := db.Get(ctx, "SELECT id, status, level, parent FROM tasks;", &out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Fatalf("expected %+v, got %+v", in, out)
+	}
+
+	in = task{ID: 2, Status: Enum[testStatus]{V: "archived"}, Level: Enum[testLevel]{V: 1}}
+	_, err := db.Exec(ctx, "INSERT INTO tasks (id, status, level) VALUES (:id, :status, :level);", in)
+	var enumErr *EnumError
+	if !errors.As(err, &enumErr) || !errors.Is(err, ErrInvalidEnum) || enumErr.Value != testStatus("archived") {
+		t.Fatalf("expected invalid enum error, got %v", err)
+	}
+
+	if _, err := db.Exec(ctx, "INSERT INTO tasks (id, status, level) VALUES (3, 'active', 7);", nil); err != nil {
+		t.Fatal(err)
+	}
+	err = db.Get(ctx, "SELECT id, status, level, parent FROM tasks WHERE id = 3;", &out, nil)
+	if !errors.Is(err, ErrInvalidEnum) {
+		t.Fatalf("expected invalid enum error on scan, got %v", err)
+	}
+	var status Enum[testStatus]
+	if err := db.Get(ctx, "SELECT parent FROM tasks WHERE id = 1;", &status, nil); !errors.Is(err, ErrInvalidEnum) {
+		t.Fatalf("expected invalid enum error for NULL, got %v", err)
+	}
+
+	if _, err := NewEnum[testLevel](4); !errors.Is(err, ErrInvalidEnum) {
+		t.Fatalf("expected invalid enum error, got %v", err)
+	}
+	type unregistered string
+	if _, err := NewEnum(unregistered("x")); err == nil || errors.Is(err, ErrInvalidEnum) {
+		t.Fatalf("expected unregistered enum error, got %v", err)
+	}
+}
+
+func TestPostgresEnumSQL(t *testing.T) {
+	got := PostgresEnumSQL[testStatus]("task_status")
+	want := `CREATE TYPE task_status AS ENUM ('active', 'it''s done');`
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	if got := EnumValues[testLevel](); len(got) != 3 || got[2] != 3 {
+		t.Fatalf("unexpected values %v", got)
+	}
+}
@@ -0,0 +1,132 @@
+package sqln
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/pkg/errors"
+)
+
+// ExecManyReturning inserts rows (a slice of structs, or of pointers to
+// structs) into table with multi-row INSERT statements, like InsertStruct for
+// each row, and scans the returning columns of the inserted rows (ie.
+// generated ids and defaults) back into the elements of rows by position.
+// Rows are inserted in batches below the bind parameter limit; run it in a
+// transaction so a failed batch does not leave earlier ones inserted. It
+// relies on RETURNING rows being in VALUES order, as Postgres and SQLite
+// return them, and MySQL is not supported.
+func ExecManyReturning(ctx context.Context, db DB, table string, rows interface{}, returning ...string) error {
+	if len(returning) == 0 {
+		return errors.New("exec many returning: no returning columns")
+	}
+	if DialectOf(db) == MySQL {
+		return errors.New("exec many returning: mysql does not support RETURNING")
+	}
+	rv := reflect.ValueOf(rows)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice {
+		return errors.Errorf("exec many returning: expected a slice, got %T", rows)
+	}
+	if rv.Len() == 0 {
+		return nil
+	}
+
+	m, tag := mapperOf(db)
+	elem := reflect.New(reflectx.Deref(rv.Type().Elem())).Interface()
+	cols, err := writableColumns(m, elem)
+	if err != nil {
+		return errors.Wrap(err, "exec many returning")
+	}
+	ret, err := returningType(m, tag, reflectx.Deref(rv.Type().Elem()), returning)
+	if err != nil {
+		return errors.Wrap(err, "exec many returning")
+	}
+
+	perBatch := maxBulkParams / len(cols)
+	if perBatch == 0 {
+		perBatch = 1
+	}
+	for start := 0; start < rv.Len(); start += perBatch {
+		end := start + perBatch
+		if end > rv.Len() {
+			end = rv.Len()
+		}
+		if err := execBatchReturning(ctx, db, m, table, cols, returning, ret, rv.Slice(start, end)); err != nil {
+			return errors.Wrapf(err, "exec many returning: rows %v-%v", start, end-1)
+		}
+	}
+	return nil
+}
+
+// returningType returns a struct type with a field tagged for each returning
+// column, of the type of the field of t it maps to.
+func returningType(m *reflectx.Mapper, tag string, t reflect.Type, returning []string) (reflect.Type, error) {
+	names := m.TypeMap(t).Names
+	fields := make([]reflect.StructField, len(returning))
+	for i, c := range returning {
+		f, ok := names[c]
+		if !ok {
+			return nil, errors.Errorf("returning column %q not found in %v", c, t)
+		}
+		fields[i] = reflect.StructField{Name: fmt.Sprintf("F%v", i), Type: f.Field.Type, Tag: reflect.StructTag(fmt.Sprintf("%v:%q", tag, c))}
+	}
+	return reflect.StructOf(fields), nil
+}
+
+// execBatchReturning inserts the rows of batch and copies the returned
+// columns into them.
+func execBatchReturning(ctx context.Context, db DB, m *reflectx.Mapper, table string, cols, returning []string, ret reflect.Type, batch reflect.Value) error {
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO " + table + " (" + strings.Join(cols, ", ") + ") VALUES ")
+	params := make(map[string]interface{}, batch.Len()*len(cols))
+	clock := clockOf(db)
+	for i := 0; i < batch.Len(); i++ {
+		row := batch.Index(i)
+		if row.Kind() == reflect.Ptr {
+			if row.IsNil() {
+				return errors.Errorf("row %v is nil", i)
+			}
+		} else {
+			row = row.Addr()
+		}
+		if _, err := stampStruct(m, row.Interface(), clock, true); err != nil {
+			return err
+		}
+
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteByte('(')
+		for j, c := range cols {
+			name := fmt.Sprintf("r%v_%v", i, c)
+			if j > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(":" + name)
+			params[name] = m.FieldByName(row, c).Interface()
+		}
+		sb.WriteByte(')')
+	}
+	sb.WriteString(" RETURNING " + strings.Join(returning, ", ") + ";")
+
+	dest := reflect.New(reflect.SliceOf(ret))
+	if err := db.ExecReturning(ctx, sb.String(), dest.Interface(), params); err != nil {
+		return err
+	}
+	dest = dest.Elem()
+	if dest.Len() != batch.Len() {
+		return errors.Errorf("%v rows returned for %v inserted", dest.Len(), batch.Len())
+	}
+	for i := 0; i < batch.Len(); i++ {
+		row := reflect.Indirect(batch.Index(i))
+		for j, c := range returning {
+			m.FieldByName(row, c).Set(dest.Index(i).Field(j))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+)
+
+func TestExecManyReturning(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, "CREATE TABLE many_abc (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, status TEXT DEFAULT 'new');", nil); err != nil {
+		t.Fatal(err)
+	}
+	type row struct {
+		ID     int    `db:"id,readonly"`
+		Name   string `db:"name"`
+		Status string `db:"status,readonly"`
+	}
+
+	rows := []row{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	if err := ExecManyReturning(ctx, db, "many_abc", rows, "id", "status"); err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range rows {
+		if r.ID != i+1 || r.Status != "new" {
+			t.Fatalf("unexpected row %v: %+v", i, r)
+		}
+	}
+
+	ptrs := []*row{{Name: "d"}, {Name: "e"}}
+	if err := ExecManyReturning(ctx, db, "many_abc", ptrs, "id"); err != nil {
+		t.Fatal(err)
+	}
+	if ptrs[0].ID != 4 || ptrs[1].ID != 5 {
+		t.Fatalf("unexpected ids: %v %v", ptrs[0].ID, ptrs[1].ID)
+	}
+
+	if err := ExecManyReturning(ctx, db, "many_abc", rows, "missing"); err == nil {
+		t.Fatal("expected an unknown returning column to fail")
+	}
+}
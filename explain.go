@@ -0,0 +1,196 @@
+package sqln
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ExplainOptions configures Explain.
+type ExplainOptions struct {
+	// Analyze runs the query to report actual timings (EXPLAIN ANALYZE).
+	// NOTE: The statement is executed, including any side effects; run it
+	// in a transaction that is rolled back to discard them. Not supported
+	// by SQLite.
+	Analyze bool
+	// JSON returns the plan as JSON rather than text. Not supported by
+	// SQLite.
+	JSON bool
+}
+
+// Explain returns the plan of query with params bound. Text plans are
+// returned one line per row.
+func (d *Database) Explain(ctx context.Context, query string, params interface{}, opts ExplainOptions) (string, error) {
+	prefix, err := explainPrefix(d.dialect, opts)
+	if err != nil {
+		return "", err
+	}
+	if params == nil {
+		params = struct{}{}
+	}
+	q, args, err := d.drv.BindNamed(prefix+query, params)
+	if err != nil {
+		return "", err
+	}
+
+	rows, err := d.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return "", errors.Wrap(err, "explain")
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	// SQLite returns the plan in a detail column alongside ids, other
+	// dialects in a single column.
+	col := 0
+	for i, c := range cols {
+		if c == "detail" {
+			col = i
+		}
+	}
+
+	var plan []byte
+	for rows.Next() {
+		vs, err := rows.SliceScan()
+		if err != nil {
+			return "", err
+		}
+		if len(plan) > 0 {
+			plan = append(plan, '\n')
+		}
+		switch v := vs[col].(type) {
+		case []byte:
+			plan = append(plan, v...)
+		case string:
+			plan = append(plan, v...)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return string(plan), nil
+}
+
+func explainPrefix(dialect Dialect, opts ExplainOptions) (string, error) {
+	switch dialect {
+	case MySQL:
+		switch {
+		case opts.Analyze && opts.JSON:
+			return "", errors.New("explain: mysql does not support analyze with json")
+		case opts.Analyze:
+			return "EXPLAIN ANALYZE ", nil
+		case opts.JSON:
+			return "EXPLAIN FORMAT=JSON ", nil
+		}
+		return "EXPLAIN FORMAT=TREE ", nil
+	case SQLite:
+		if opts.Analyze || opts.JSON {
+			return "", errors.New("explain: sqlite only supports text plans")
+		}
+		return "EXPLAIN QUERY PLAN ", nil
+	}
+	switch {
+	case opts.Analyze && opts.JSON:
+		return "EXPLAIN (ANALYZE, FORMAT JSON) ", nil
+	case opts.Analyze:
+		return "EXPLAIN ANALYZE ", nil
+	case opts.JSON:
+		return "EXPLAIN (FORMAT JSON) ", nil
+	}
+	return "EXPLAIN ", nil
+}
+
+// SlowPlans configures the capture of plans for slow queries.
+type SlowPlans struct {
+	// Threshold is the latency above which a query's plan is captured.
+	Threshold time.Duration
+	// Options are passed to Explain. Analyze re-runs the query, so it
+	// should only be used when every captured query is free of side
+	// effects.
+	Options ExplainOptions
+	// Interval limits captures to once per query per interval. Defaults
+	// to one minute.
+	Interval time.Duration
+	// OnPlan receives captured plans, ie. to log them.
+	OnPlan func(SlowPlan)
+	// Redactor is applied to the params of captured plans. Defaults to
+	// DefaultRedactor.
+	Redactor Redactor
+}
+
+// SlowPlan is the plan of a query that exceeded the SlowPlans threshold.
+type SlowPlan struct {
+	Query string
+	// Name is the query name, if any (see QueryName).
+	Name string
+	// Params are redacted by SlowPlans.Redactor.
+	Params   map[string]interface{}
+	Duration time.Duration
+	Plan     string
+	// Err is set when the plan could not be captured.
+	Err error
+	// Annotations are those of the query's context (see WithAnnotation).
+	Annotations map[string]string
+}
+
+// WithSlowPlans captures the plans of queries slower than the threshold.
+// Plans are captured in the background, outside of any transaction the
+// query ran in.
+func WithSlowPlans(s SlowPlans) Option {
+	if s.Interval <= 0 {
+		s.Interval = time.Minute
+	}
+	if s.Redactor == nil {
+		s.Redactor = DefaultRedactor
+	}
+	return func(d *Database) {
+		d.slow = &slowPlans{cfg: s, last: make(map[string]time.Time)}
+	}
+}
+
+type slowPlans struct {
+	cfg SlowPlans
+
+	mtx  sync.Mutex
+	last map[string]time.Time
+}
+
+// observe logs query and captures its plan if it started long enough ago.
+func (d *Database) observe(ctx context.Context, query string, params interface{}, start time.Time) {
+	if d.slowLog != nil {
+		d.slowLog.record(ctx, query, start)
+	}
+	if d.slow == nil {
+		return
+	}
+	elapsed := time.Since(start)
+	if elapsed < d.slow.cfg.Threshold || !d.slow.due(query, start) {
+		return
+	}
+
+	pool := *d
+	pool.tx = nil
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), elapsed+time.Second)
+		defer cancel()
+		plan, err := pool.Explain(ctx, query, params, d.slow.cfg.Options)
+		if d.slow.cfg.OnPlan != nil {
+			d.slow.cfg.OnPlan(SlowPlan{Query: query, Name: QueryName(ctx, query), Params: d.slow.cfg.Redactor.Redact(params), Duration: elapsed, Plan: plan, Err: err, Annotations: Annotations(ctx)})
+		}
+	}()
+}
+
+func (s *slowPlans) due(query string, now time.Time) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if last, ok := s.last[query]; ok && now.Sub(last) < s.cfg.Interval {
+		return false
+	}
+	s.last[query] = now
+	return true
+}
@@ -0,0 +1,51 @@
+package sqln
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExplain(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE explain_abc (id INTEGER PRIMARY KEY, name TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	const q = "SELECT name FROM explain_abc WHERE id = :id;"
+	plan, err := db.Explain(ctx, q, map[string]interface{}{"id": 1}, ExplainOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(plan, "explain_abc") {
+		t.Fatalf("unexpected plan: %q", plan)
+	}
+	if _, err := db.Explain(ctx, q, map[string]interface{}{"id": 1}, ExplainOptions{JSON: true}); err == nil {
+		t.Fatal("expected sqlite json plans to be unsupported")
+	}
+
+	plans := make(chan SlowPlan, 2)
+	slow := New(db.X, WithSlowPlans(SlowPlans{OnPlan: func(p SlowPlan) { plans <- p }}))
+	defer slow.Close()
+	var names []string
+	for i := 0; i < 2; i++ {
+		if err := slow.Select(ctx, q, &names, map[string]interface{}{"id": 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case p := <-plans:
+		if p.Err != nil || p.Query != q || !strings.Contains(p.Plan, "explain_abc") {
+			t.Fatalf("unexpected slow plan: %+v", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a slow plan")
+	}
+	select {
+	case p := <-plans:
+		t.Fatalf("expected one plan per interval, got %+v", p)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,114 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ExportFormat is the encoding of rows written by Export.
+type ExportFormat int
+
+// Export formats.
+const (
+	// CSV writes a header of column names followed by a record per row.
+	// NULLs are written as empty fields.
+	CSV ExportFormat = iota
+	// NDJSON writes a JSON object per row, one per line.
+	NDJSON
+)
+
+// exportBatchSize is the number of rows fetched per cursor batch by Export.
+const exportBatchSize = 500
+
+// Export streams the rows of query to w through a cursor (see SelectCursor),
+// so results of any size are written with flat memory, and returns the number
+// of rows written. Outside of a transaction the query runs in a read only
+// one. Rows are read as maps (see SelectMaps), so CSV columns are in
+// alphabetical order; []byte values are written as text and times in RFC
+// 3339 format.
+func Export(ctx context.Context, db DB, query string, params interface{}, w io.Writer, format ExportFormat) (int64, error) {
+	if format != CSV && format != NDJSON {
+		return 0, errors.Errorf("export: unknown format %v", format)
+	}
+	if !InTx(db) {
+		var n int64
+		err := db.Transact(ctx, sql.TxOptions{ReadOnly: true}, func(tx DB) error {
+			var err error
+			n, err = Export(ctx, tx, query, params, w, format)
+			return err
+		})
+		return n, err
+	}
+
+	var (
+		n       int64
+		rows    []map[string]interface{}
+		columns []string
+		cw      = csv.NewWriter(w)
+		enc     = json.NewEncoder(w)
+	)
+	err := SelectCursor(ctx, db, query, &rows, params, exportBatchSize, func() error {
+		for _, row := range rows {
+			for k, v := range row {
+				row[k] = exportValue(v)
+			}
+			if format == NDJSON {
+				if err := enc.Encode(row); err != nil {
+					return err
+				}
+				n++
+				continue
+			}
+
+			if columns == nil {
+				for c := range row {
+					columns = append(columns, c)
+				}
+				sort.Strings(columns)
+				if err := cw.Write(columns); err != nil {
+					return err
+				}
+			}
+			record := make([]string, len(columns))
+			for i, c := range columns {
+				if v := row[c]; v != nil {
+					record[i] = fmt.Sprint(v)
+				}
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+			n++
+		}
+		cw.Flush()
+		return cw.Error()
+	})
+	if err != nil {
+		return n, errors.Wrap(err, "export")
+	}
+	return n, nil
+}
+
+// Export streams the rows of query to w, see the Export function.
+func (d *Database) Export(ctx context.Context, query string, params interface{}, w io.Writer, format ExportFormat) (int64, error) {
+	return Export(ctx, d, query, params, w, format)
+}
+
+// exportValue converts driver values that would not encode as expected.
+func exportValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return v
+}
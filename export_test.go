@@ -0,0 +1,60 @@
+package sqln
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+// exportDB serves map rows to the first FETCH statement.
+type exportDB struct {
+	DB
+	rows []map[string]interface{}
+}
+
+func (d *exportDB) TxLevel() int { return 1 }
+func (d *exportDB) InTx() bool   { return true }
+
+func (d *exportDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	return nil, nil
+}
+
+func (d *exportDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	*dest.(*[]map[string]interface{}) = d.rows
+	d.rows = nil
+	return nil
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	rows := func() []map[string]interface{} {
+		return []map[string]interface{}{
+			{"name": []byte("ann, the first"), "id": int64(1), "joined": time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+			{"name": nil, "id": int64(2), "joined": nil},
+		}
+	}
+
+	var buf bytes.Buffer
+	n, err := Export(ctx, &exportDB{rows: rows()}, "SELECT * FROM users;", nil, &buf, CSV)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 rows, got %v %v", n, err)
+	}
+	if exp := "id,joined,name\n1,2024-01-02T03:04:05Z,\"ann, the first\"\n2,,\n"; buf.String() != exp {
+		t.Fatalf("unexpected csv:\n%v", buf.String())
+	}
+
+	buf.Reset()
+	n, err = Export(ctx, &exportDB{rows: rows()}, "SELECT * FROM users;", nil, &buf, NDJSON)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 rows, got %v %v", n, err)
+	}
+	if exp := "{\"id\":1,\"joined\":\"2024-01-02T03:04:05Z\",\"name\":\"ann, the first\"}\n{\"id\":2,\"joined\":null,\"name\":null}\n"; buf.String() != exp {
+		t.Fatalf("unexpected ndjson:\n%v", buf.String())
+	}
+
+	if _, err := Export(ctx, &exportDB{}, "SELECT 1;", nil, &buf, ExportFormat(9)); err == nil {
+		t.Fatal("expected unknown format error")
+	}
+}
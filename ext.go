@@ -0,0 +1,62 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Database satisfies sqlx.ExtContext (within transactions too), so it can be
+// passed to helpers such as sqlx.GetContext or scany. These methods take
+// positional args and are not prepared or cached. Their queries are checked
+// as those of the DB methods are (see WithStrict).
+var _ sqlx.ExtContext = (*Database)(nil)
+
+// DriverName returns the name of the underlying driver.
+func (d *Database) DriverName() string {
+	return d.drv.DriverName()
+}
+
+// Rebind transforms a query from QUESTION to the driver's bindvar type.
+func (d *Database) Rebind(query string) string {
+	return d.drv.Rebind(query)
+}
+
+// BindNamed binds a query with named params into positional args.
+func (d *Database) BindNamed(query string, arg interface{}) (string, []interface{}, error) {
+	return d.drv.BindNamed(query, arg)
+}
+
+// QueryContext runs a query that returns rows.
+func (d *Database) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if err := d.checkQuery(ctx, query); err != nil {
+		return nil, err
+	}
+	return d.ext().QueryContext(ctx, query, args...)
+}
+
+// QueryxContext runs a query that returns sqlx rows.
+func (d *Database) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	if err := d.checkQuery(ctx, query); err != nil {
+		return nil, err
+	}
+	return d.ext().QueryxContext(ctx, query, args...)
+}
+
+// QueryRowxContext runs a query that returns at most one row.
+func (d *Database) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	if err := d.checkQuery(ctx, query); err != nil {
+		// As with errRow, the row reports err through a done context.
+		return d.ext().QueryRowxContext(errContext{err: err}, query)
+	}
+	return d.ext().QueryRowxContext(ctx, query, args...)
+}
+
+// ExecContext executes a statement.
+func (d *Database) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if err := d.checkQuery(ctx, query); err != nil {
+		return nil, err
+	}
+	return d.ext().ExecContext(ctx, query, args...)
+}
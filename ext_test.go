@@ -0,0 +1,44 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestExtContext(t *testing.T) {
+	db := sqliteDB(t)
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "CREATE TABLE ext_abc (id INTEGER PRIMARY KEY);"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		ext := tx.(sqlx.ExtContext)
+		if _, err := ext.ExecContext(ctx, ext.Rebind("INSERT INTO ext_abc (id) VALUES (?);"), 1); err != nil {
+			return err
+		}
+		// The insert is visible within the transaction.
+		var n int
+		if err := sqlx.GetContext(ctx, ext, &n, "SELECT COUNT(*) FROM ext_abc;"); err != nil {
+			return err
+		}
+		if n != 1 {
+			t.Errorf("expected 1 row in tx, got %v", n)
+		}
+		return sql.ErrTxDone
+	}); err == nil {
+		t.Fatal("expected rollback error")
+	}
+
+	var n int
+	if err := sqlx.GetContext(ctx, db, &n, "SELECT COUNT(*) FROM ext_abc;"); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("expected rolled back insert, got %v rows", n)
+	}
+}
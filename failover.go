@@ -0,0 +1,265 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// FailoverOptions configures NewWithFailover.
+type FailoverOptions struct {
+	// Options configure the Database of each primary.
+	Options []Option
+	// Interval between primary checks in Monitor. Defaults to 5 seconds.
+	Interval time.Duration
+	// IsPrimary reports whether a pool is connected to a writable primary.
+	// Defaults to pg_is_in_recovery() for Postgres, @@global.read_only for
+	// MySQL and a ping otherwise.
+	IsPrimary func(ctx context.Context, dbx *sqlx.DB) (bool, error)
+	// OnFailover is called after switching primaries, ie. to log it.
+	OnFailover func(FailoverEvent)
+}
+
+// FailoverEvent describes a switch of primary. DSNs are identified by their
+// index since they usually hold credentials.
+type FailoverEvent struct {
+	// From is -1 on the initial selection.
+	From, To int
+	// Err is set when the statement cache could not be rebuilt for the new
+	// primary.
+	Err error
+}
+
+// Failover is a DB that follows the writable primary across several DSNs,
+// ie. a cluster whose replicas may be promoted. Operations failing with
+// connection or read-only errors trigger a check for a new primary; Monitor
+// checks periodically.
+type Failover struct {
+	opts  FailoverOptions
+	pools []*sqlx.DB
+
+	mtx     sync.RWMutex
+	current *Database
+	index   int
+
+	checking sync.Mutex
+}
+
+var _ DB = &Failover{}
+
+// NewWithFailover opens a pool per DSN and selects the first primary.
+func NewWithFailover(ctx context.Context, driverName string, opts FailoverOptions, dsns ...string) (*Failover, error) {
+	if len(dsns) == 0 {
+		return nil, errors.New("failover: no dsns")
+	}
+	f := &Failover{opts: opts, index: -1}
+	for i, dsn := range dsns {
+		dbx, err := sqlx.Open(driverName, dsn)
+		if err != nil {
+			f.closePools()
+			return nil, errors.Wrapf(err, "failover: open dsn %v", i)
+		}
+		f.pools = append(f.pools, dbx)
+	}
+	return f, f.init(ctx)
+}
+
+func (f *Failover) init(ctx context.Context) error {
+	if f.opts.Interval <= 0 {
+		f.opts.Interval = 5 * time.Second
+	}
+	if f.opts.IsPrimary == nil {
+		f.opts.IsPrimary = isPrimary
+	}
+	if err := f.Check(ctx); err != nil {
+		f.closePools()
+		return err
+	}
+	return nil
+}
+
+// isPrimary is the default FailoverOptions.IsPrimary.
+func isPrimary(ctx context.Context, dbx *sqlx.DB) (bool, error) {
+	switch dialectOf(dbx.DriverName()) {
+	case Postgres:
+		var recovery bool
+		err := dbx.GetContext(ctx, &recovery, "SELECT pg_is_in_recovery();")
+		return err == nil && !recovery, err
+	case MySQL:
+		var readOnly bool
+		err := dbx.GetContext(ctx, &readOnly, "SELECT @@global.read_only;")
+		return err == nil && !readOnly, err
+	}
+	err := dbx.PingContext(ctx)
+	return err == nil, err
+}
+
+// Current returns the Database of the current primary.
+func (f *Failover) Current() *Database {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	return f.current
+}
+
+// Check switches to another primary if the current one is no longer
+// writable. The statement cache is rebuilt on the new primary with the
+// queries cached on the old one.
+func (f *Failover) Check(ctx context.Context) error {
+	f.checking.Lock()
+	defer f.checking.Unlock()
+
+	f.mtx.RLock()
+	prev, from := f.current, f.index
+	f.mtx.RUnlock()
+	if prev != nil {
+		if ok, _ := f.opts.IsPrimary(ctx, f.pools[from]); ok {
+			return nil
+		}
+	}
+
+	var lastErr error
+	for i, dbx := range f.pools {
+		if i == from {
+			continue
+		}
+		ok, err := f.opts.IsPrimary(ctx, dbx)
+		if err != nil {
+			lastErr = err
+		}
+		if !ok {
+			continue
+		}
+
+		next := New(dbx, f.opts.Options...)
+		var warmErr error
+		if prev != nil {
+			warmErr = next.WarmCache(ctx, prev.CachedQueries())
+		}
+		f.mtx.Lock()
+		f.current, f.index = next, i
+		f.mtx.Unlock()
+		if prev != nil {
+			// Waits for operations still running on the old primary.
+			prev.Close()
+		}
+		if f.opts.OnFailover != nil {
+			f.opts.OnFailover(FailoverEvent{From: from, To: i, Err: warmErr})
+		}
+		return nil
+	}
+	if lastErr != nil {
+		return errors.Wrap(lastErr, "failover: no primary")
+	}
+	return errors.New("failover: no primary")
+}
+
+// Monitor checks the primary every Interval until ctx is done.
+func (f *Failover) Monitor(ctx context.Context) {
+	t := time.NewTicker(f.opts.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		f.Check(ctx)
+	}
+}
+
+// failed triggers a check in the background when err suggests the primary
+// changed.
+func (f *Failover) failed(err error) {
+	switch Classify(err) {
+	case ClassReadOnly, ClassConnection:
+	default:
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), f.opts.Interval)
+		defer cancel()
+		f.Check(ctx)
+	}()
+}
+
+// do runs op on the current primary, retrying on the new one if the old was
+// closed by a switch before op began.
+func (f *Failover) do(op func(*Database) error) error {
+	d := f.Current()
+	err := op(d)
+	if errors.Is(err, ErrClosed) {
+		if next := f.Current(); next != d {
+			err = op(next)
+		}
+	}
+	f.failed(err)
+	return err
+}
+
+// Exec a SQL statement on the primary.
+func (f *Failover) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	var res sql.Result
+	err := f.do(func(d *Database) error {
+		var err error
+		res, err = d.Exec(ctx, query, params)
+		return err
+	})
+	return res, err
+}
+
+// Get a single record from the primary.
+func (f *Failover) Get(ctx context.Context, query string, dest, params interface{}) error {
+	return f.do(func(d *Database) error {
+		return d.Get(ctx, query, dest, params)
+	})
+}
+
+// Select multiple records from the primary.
+func (f *Failover) Select(ctx context.Context, query string, dest, params interface{}) error {
+	return f.do(func(d *Database) error {
+		return d.Select(ctx, query, dest, params)
+	})
+}
+
+// ExecReturning executes a statement on the primary, scanning its RETURNING
+// columns into dest.
+func (f *Failover) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	return f.do(func(d *Database) error {
+		return d.ExecReturning(ctx, query, dest, params)
+	})
+}
+
+// Stmt creates and/or retrieves a named statement on the primary.
+func (f *Failover) Stmt(query string) (*sqlx.NamedStmt, error) {
+	return f.Current().Stmt(query)
+}
+
+// Transact runs fn in a transaction on the primary.
+func (f *Failover) Transact(ctx context.Context, opts sql.TxOptions, fn func(DB) error) error {
+	return f.do(func(d *Database) error {
+		return d.Transact(ctx, opts, fn)
+	})
+}
+
+// Close closes the statements of the current primary and every pool.
+func (f *Failover) Close() error {
+	err := f.Current().Close()
+	if cerr := f.closePools(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (f *Failover) closePools() error {
+	var first error
+	for _, dbx := range f.pools {
+		if err := dbx.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
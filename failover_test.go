@@ -0,0 +1,71 @@
+package sqln
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestFailover(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dsns := []string{filepath.Join(dir, "a.db"), filepath.Join(dir, "b.db")}
+
+	var (
+		primary int32
+		pools   []*sqlx.DB
+		events  []FailoverEvent
+	)
+	f, err := NewWithFailover(ctx, "sqlite3", FailoverOptions{
+		IsPrimary: func(ctx context.Context, dbx *sqlx.DB) (bool, error) {
+			for i, p := range pools {
+				if p == dbx {
+					return i == int(atomic.LoadInt32(&primary)), nil
+				}
+			}
+			pools = append(pools, dbx)
+			return len(pools)-1 == int(atomic.LoadInt32(&primary)), nil
+		},
+		OnFailover: func(e FailoverEvent) { events = append(events, e) },
+	}, dsns...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, dbx := range f.pools {
+		if _, err := dbx.Exec("CREATE TABLE node (name TEXT);"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.pools[0].MustExec("INSERT INTO node (name) VALUES ('a');")
+	f.pools[1].MustExec("INSERT INTO node (name) VALUES ('b');")
+
+	const query = "SELECT name FROM node;"
+	var name string
+	if err := f.Get(ctx, query, &name, nil); err != nil || name != "a" {
+		t.Fatalf("expected the first primary, got %q %v", name, err)
+	}
+
+	atomic.StoreInt32(&primary, 1)
+	if err := f.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0] != (FailoverEvent{From: -1, To: 0}) || events[1] != (FailoverEvent{From: 0, To: 1}) {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	if s := f.Current().Stats(); s.Statements != 1 {
+		t.Fatalf("expected the statement cache to be rebuilt, got %v statements", s.Statements)
+	}
+	if err := f.Get(ctx, query, &name, nil); err != nil || name != "b" {
+		t.Fatalf("expected the new primary, got %q %v", name, err)
+	}
+
+	// A healthy primary is kept.
+	if err := f.Check(ctx); err != nil || len(events) != 2 {
+		t.Fatalf("unexpected check: %v %+v", err, events)
+	}
+}
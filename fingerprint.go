@@ -0,0 +1,105 @@
+package sqln
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// Normalize returns query with comments removed, whitespace collapsed and
+// string and numeric literals replaced by ?, so queries that differ only in
+// formatting or constants normalize the same, ie.
+//
+//	SELECT * FROM users WHERE id IN (1, 2, 3) -- by id
+//
+// normalizes to "SELECT * FROM users WHERE id IN (?)". Named params and
+// quoted identifiers are kept.
+func Normalize(query string) string {
+	return inListRe.ReplaceAllString(normalize(query, true), "(?)")
+}
+
+// Fingerprint returns a stable hash of the normalized query (see Normalize),
+// for use as a metrics or log key. Queries with the same fingerprint are
+// combined by WithQueryStats.
+func Fingerprint(query string) string {
+	sum := sha256.Sum256([]byte(Normalize(query)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// WithNormalizedStmtCache caches statements by their text without comments
+// or redundant whitespace, so queries that differ only in formatting share a
+// prepared statement. Literals are kept, so queries with different constants
+// are still prepared separately. The statement is prepared from the first
+// text seen.
+func WithNormalizedStmtCache() Option {
+	return func(d *Database) {
+		d.cache.normalize = true
+	}
+}
+
+var inListRe = regexp.MustCompile(`\(\?(?:\s*,\s*\?)+\)`)
+
+// normalize removes comments and collapses whitespace outside of quotes,
+// replacing literals by ? if literals is set.
+func normalize(query string, literals bool) string {
+	var b strings.Builder
+	b.Grow(len(query))
+	space := false
+	emit := func(s string) {
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteString(s)
+	}
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			if n := strings.IndexByte(query[i:], '\n'); n >= 0 {
+				i += n
+			} else {
+				i = len(query)
+			}
+			space = true
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			if n := strings.Index(query[i+2:], "*/"); n >= 0 {
+				i += n + 3
+			} else {
+				i = len(query)
+			}
+			space = true
+		case c == '\'':
+			j := skipQuoted(query, i, c)
+			if literals {
+				emit("?")
+			} else {
+				emit(query[i:min(j+1, len(query))])
+			}
+			i = j
+		case c == '"' || c == '`':
+			j := skipQuoted(query, i, c)
+			emit(query[i:min(j+1, len(query))])
+			i = j
+		case literals && c >= '0' && c <= '9' && (i == 0 || !isIdentByte(query[i-1]) && query[i-1] != '$'):
+			j := i
+			for j < len(query) && (isIdentByte(query[j]) || query[j] == '.') {
+				j++
+			}
+			emit("?")
+			i = j - 1
+		default:
+			j := i + 1
+			if isIdentByte(c) {
+				for j < len(query) && isIdentByte(query[j]) {
+					j++
+				}
+			}
+			emit(query[i:j])
+			i = j - 1
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,49 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		query, expected string
+	}{
+		{"SELECT * FROM users WHERE id IN (1, 2, 3) -- by id", "SELECT * FROM users WHERE id IN (?)"},
+		{"SELECT  name\n\tFROM users /* all */ WHERE name = 'it''s' AND t1.x > 1.5;", "SELECT name FROM users WHERE name = ? AND t1.x > ?;"},
+		{`SELECT "col 1" FROM t WHERE id = :id AND y = $1;`, `SELECT "col 1" FROM t WHERE id = :id AND y = $1;`},
+	}
+	for _, c := range cases {
+		if got := Normalize(c.query); got != c.expected {
+			t.Errorf("Normalize(%q): expected %q, got %q", c.query, c.expected, got)
+		}
+	}
+
+	if Fingerprint("SELECT 1;") != Fingerprint(" SELECT  2; ") {
+		t.Error("expected queries differing in constants to share a fingerprint")
+	}
+	if Fingerprint("SELECT a FROM t;") == Fingerprint("SELECT b FROM t;") {
+		t.Error("expected different queries to have different fingerprints")
+	}
+}
+
+func TestNormalizedStmtCache(t *testing.T) {
+	base := sqliteDB(t)
+	db := New(base.X, WithNormalizedStmtCache())
+	defer db.Close()
+
+	ctx := context.Background()
+	var n int
+	for _, q := range []string{"SELECT 1;", "SELECT  1;", "SELECT 1; -- again", "SELECT 2;"} {
+		if err := db.Get(ctx, q, &n, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if qs := db.CachedQueries(); len(qs) != 2 {
+		t.Fatalf("expected 2 cached statements, got %q", qs)
+	}
+	db.InvalidateStmt("SELECT\n1;")
+	if n := db.cache.len(); n != 1 {
+		t.Fatalf("expected invalidation by normalized text, got %v statements", n)
+	}
+}
@@ -0,0 +1,77 @@
+package sqln
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxInList bounds the ids bound in one GetMany query, below the bind
+// parameter limits of every dialect.
+const maxInList = 512
+
+// GetMany loads the rows with the given ids, returning them by the
+// keyColumn field along with the ids that were not found, ie. to batch-load
+// related records. query selects the rows with "IN (:ids)", which is
+// expanded to a param per id. The ids are padded to a power of two (by
+// repeating one) so only a few distinct queries are prepared, and more than
+// 512 ids are loaded in several queries.
+func GetMany[K comparable, T any](ctx context.Context, db DB, query, keyColumn string, ids []K) (map[K]T, []K, error) {
+	if !strings.Contains(query, ":ids") {
+		return nil, nil, errors.New("get many: query has no :ids param")
+	}
+	unique := make([]K, 0, len(ids))
+	seen := make(map[K]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	found := make(map[K]T, len(unique))
+	for start := 0; start < len(unique); start += maxInList {
+		end := start + maxInList
+		if end > len(unique) {
+			end = len(unique)
+		}
+		q, params := expandIn(query, "ids", unique[start:end])
+		m, err := SelectMap[K, T](ctx, db, q, keyColumn, params)
+		if err != nil {
+			return nil, nil, err
+		}
+		for k, v := range m {
+			found[k] = v
+		}
+	}
+
+	var missing []K
+	for _, id := range unique {
+		if _, ok := found[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	return found, missing, nil
+}
+
+// expandIn replaces the :name param of query with a param per value, padded
+// to a power of two with the last value.
+func expandIn[K any](query, name string, values []K) (string, map[string]interface{}) {
+	n := 1
+	for n < len(values) {
+		n *= 2
+	}
+	names := make([]string, n)
+	params := make(map[string]interface{}, n)
+	for i := range names {
+		names[i] = ":" + name + "_" + strconv.Itoa(i)
+		v := values[len(values)-1]
+		if i < len(values) {
+			v = values[i]
+		}
+		params[name+"_"+strconv.Itoa(i)] = v
+	}
+	return strings.ReplaceAll(query, ":"+name, strings.Join(names, ", ")), params
+}
@@ -0,0 +1,54 @@
+package sqln
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+type manyRow struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+func TestGetMany(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+	for _, q := range []string{
+		"CREATE TABLE get_many (id INTEGER PRIMARY KEY, name TEXT);",
+		"INSERT INTO get_many VALUES (1, 'a'), (2, 'b'), (3, 'c');",
+	} {
+		if _, err := db.Exec(ctx, q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const q = "SELECT id, name FROM get_many WHERE id IN (:ids);"
+	found, missing, err := GetMany[int64, manyRow](ctx, db, q, "id", []int64{3, 1, 4, 1, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[1].Name != "a" || found[3].Name != "c" {
+		t.Fatalf("unexpected rows: %v", found)
+	}
+	if !reflect.DeepEqual(missing, []int64{4, 5}) {
+		t.Fatalf("unexpected missing ids: %v", missing)
+	}
+
+	// Four unique ids pad to the same query as three.
+	before := db.Stats().Statements
+	if _, _, err := GetMany[int64, manyRow](ctx, db, q, "id", []int64{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if after := db.Stats().Statements; after != before {
+		t.Fatalf("expected the padded query to be reused, got %v statements after %v", after, before)
+	}
+
+	many := make([]int64, 1000)
+	for i := range many {
+		many[i] = int64(i)
+	}
+	if found, missing, err := GetMany[int64, manyRow](ctx, db, q, "id", many); err != nil || len(found) != 3 || len(missing) != 997 {
+		t.Fatalf("unexpected result for many ids: %v found, %v missing, %v", len(found), len(missing), err)
+	}
+}
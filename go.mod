@@ -1,10 +1,28 @@
 module github.com/nstogner/sqln
 
-go 1.12
+go 1.23.0
 
 require (
-	github.com/jmoiron/sqlx v1.2.0
-	github.com/lib/pq v1.2.0
+	github.com/georgysavva/scany/v2 v2.1.3
+	github.com/go-sql-driver/mysql v1.8.1
+	github.com/jackc/pgx/v5 v5.7.1
+	github.com/jmoiron/sqlx v1.4.0
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/nstogner/psqlxtest v0.0.0-20190905215411-b94ca08e5578
-	github.com/pkg/errors v0.8.1
+	github.com/pkg/errors v0.9.1
+	golang.org/x/sync v0.11.0
+	golang.org/x/tools v0.30.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	filippo.io/edwards25519 v1.1.0 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	golang.org/x/crypto v0.27.0 // indirect
+	golang.org/x/mod v0.23.0 // indirect
+	golang.org/x/text v0.18.0 // indirect
 )
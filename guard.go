@@ -0,0 +1,134 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrGuardedStatement is returned by the Guard middleware for a statement it
+// blocks.
+var ErrGuardedStatement = errors.New("sqln: guarded statement")
+
+// GuardOptions configures the Guard middleware. The zero value blocks every
+// guarded statement.
+type GuardOptions struct {
+	// AllowUnfiltered allows UPDATE and DELETE without a WHERE clause.
+	AllowUnfiltered bool
+	// AllowDestructive allows TRUNCATE and DROP outside of migration mode
+	// (see WithMigrationMode).
+	AllowDestructive bool
+}
+
+// EnvironmentGuards are the recommended GuardOptions of common environments,
+// see GuardFor.
+var EnvironmentGuards = map[string]GuardOptions{
+	"production":  {},
+	"staging":     {},
+	"development": {AllowDestructive: true},
+	"test":        {AllowUnfiltered: true, AllowDestructive: true},
+}
+
+// GuardFor returns the EnvironmentGuards of env, ie. from an APP_ENV variable.
+// Unknown environments are guarded like production.
+func GuardFor(env string) GuardOptions {
+	return EnvironmentGuards[strings.ToLower(env)]
+}
+
+// WithMigrationMode returns a context whose statements may TRUNCATE and DROP
+// through the Guard middleware, ie. for schema migrations.
+func WithMigrationMode(ctx context.Context) context.Context {
+	return context.WithValue(ctx, migrationKey, true)
+}
+
+// InMigrationMode reports whether ctx is in migration mode (see
+// WithMigrationMode).
+func InMigrationMode(ctx context.Context) bool {
+	v, _ := ctx.Value(migrationKey).(bool)
+	return v
+}
+
+// Guard returns a middleware that fails statements with ErrGuardedStatement,
+// before they reach the database, when they UPDATE or DELETE without a WHERE
+// clause or TRUNCATE or DROP outside of migration mode, as allowed by opts.
+// It is a safety net for operational scripts: statements are checked by
+// their leading keyword, ignoring comments and literals, rather than parsed,
+// so ie. a DELETE within a CTE is not checked.
+func Guard(opts GuardOptions) Middleware {
+	return func(db DB) DB {
+		return &guardDB{DB: db, opts: opts}
+	}
+}
+
+var (
+	unfilteredRe  = regexp.MustCompile(`(?i)^(UPDATE|DELETE)\b`)
+	whereRe       = regexp.MustCompile(`(?i)\bWHERE\b`)
+	destructiveRe = regexp.MustCompile(`(?i)^(TRUNCATE|DROP)\b`)
+)
+
+// check returns an ErrGuardedStatement for the first statement of query the
+// options do not allow.
+func (o GuardOptions) check(ctx context.Context, query string) error {
+	for _, stmt := range SplitStatements(query) {
+		stmt = normalize(stmt, true)
+		if m := unfilteredRe.FindString(stmt); m != "" && !o.AllowUnfiltered && !whereRe.MatchString(stmt) {
+			return errors.Wrapf(ErrGuardedStatement, "%v without WHERE", strings.ToUpper(m))
+		}
+		if m := destructiveRe.FindString(stmt); m != "" && !o.AllowDestructive && !InMigrationMode(ctx) {
+			return errors.Wrapf(ErrGuardedStatement, "%v outside of migration mode", strings.ToUpper(m))
+		}
+	}
+	return nil
+}
+
+type guardDB struct {
+	DB
+	opts GuardOptions
+}
+
+func (d *guardDB) Unwrap() DB { return d.DB }
+
+func (d *guardDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	if err := d.opts.check(ctx, query); err != nil {
+		return nil, err
+	}
+	return d.DB.Exec(ctx, query, params)
+}
+
+func (d *guardDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.opts.check(ctx, query); err != nil {
+		return err
+	}
+	return d.DB.ExecReturning(ctx, query, dest, params)
+}
+
+func (d *guardDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.opts.check(ctx, query); err != nil {
+		return err
+	}
+	return d.DB.Get(ctx, query, dest, params)
+}
+
+func (d *guardDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.opts.check(ctx, query); err != nil {
+		return err
+	}
+	return d.DB.Select(ctx, query, dest, params)
+}
+
+// ExecScript checks every statement of script before executing any of them.
+func (d *guardDB) ExecScript(ctx context.Context, script string) error {
+	if err := d.opts.check(ctx, script); err != nil {
+		return err
+	}
+	return ExecScript(ctx, d.DB, script)
+}
+
+func (d *guardDB) Transact(ctx context.Context, opts sql.TxOptions, f func(DB) error) error {
+	return d.DB.Transact(ctx, opts, func(tx DB) error {
+		return f(&guardDB{DB: tx, opts: d.opts})
+	})
+}
@@ -0,0 +1,70 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestGuard(t *testing.T) {
+	ctx := context.Background()
+	migrating := WithMigrationMode(ctx)
+
+	cases := []struct {
+		ctx     context.Context
+		opts    GuardOptions
+		query   string
+		blocked string
+	}{
+		{ctx, GuardOptions{}, "UPDATE users SET name = :name WHERE id = :id;", ""},
+		{ctx, GuardOptions{}, "update users set name = :name;", "UPDATE without WHERE"},
+		{ctx, GuardOptions{}, "DELETE FROM users /* WHERE id = 1 */;", "DELETE without WHERE"},
+		{ctx, GuardOptions{}, "DELETE FROM users WHERE note = 'x';", ""},
+		{ctx, GuardOptions{}, "DELETE FROM users -- WHERE id = 1\n;", "DELETE without WHERE"},
+		{ctx, GuardOptions{}, "INSERT INTO notes (body) VALUES ('DELETE FROM users');", ""},
+		{ctx, GuardOptions{AllowUnfiltered: true}, "DELETE FROM users;", ""},
+		{ctx, GuardOptions{}, "SELECT 1; TRUNCATE users;", "TRUNCATE outside of migration mode"},
+		{ctx, GuardOptions{}, "DROP TABLE users;", "DROP outside of migration mode"},
+		{migrating, GuardOptions{}, "DROP TABLE users;", ""},
+		{ctx, GuardFor("Development"), "DROP TABLE users;", ""},
+		{ctx, GuardFor("unknown"), "DROP TABLE users;", "DROP outside of migration mode"},
+	}
+	for i, c := range cases {
+		err := c.opts.check(c.ctx, c.query)
+		if c.blocked == "" && err != nil {
+			t.Errorf("case %v: expected %q to be allowed, got %v", i, c.query, err)
+		}
+		if c.blocked != "" && (!errors.Is(err, ErrGuardedStatement) || !strings.Contains(err.Error(), c.blocked)) {
+			t.Errorf("case %v: expected %q to be blocked with %q, got %v", i, c.query, c.blocked, err)
+		}
+	}
+
+	db := Wrap(sqliteDB(t), Guard(GuardOptions{}))
+	if _, err := db.Exec(migrating, "CREATE TABLE guarded (id INTEGER);", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		_, err := tx.Exec(ctx, "DELETE FROM guarded;", nil)
+		return err
+	}); !errors.Is(err, ErrGuardedStatement) {
+		t.Fatalf("expected the delete to be blocked in a transaction, got %v", err)
+	}
+
+	// Scripts are checked as a whole before any statement runs.
+	if _, err := db.Exec(ctx, "INSERT INTO guarded (id) VALUES (1);", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExecScript(ctx, db, "DELETE FROM guarded; DROP TABLE guarded;"); !errors.Is(err, ErrGuardedStatement) {
+		t.Fatalf("expected the script to be blocked, got %v", err)
+	}
+	var n int
+	if err := db.Get(ctx, "SELECT COUNT(*) FROM guarded;", &n, nil); err != nil || n != 1 {
+		t.Fatalf("expected no statement of the script to run, got %v rows, %v", n, err)
+	}
+	if err := ExecScript(migrating, db, "DELETE FROM guarded WHERE id = 1; DROP TABLE guarded;"); err != nil {
+		t.Fatal(err)
+	}
+}
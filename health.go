@@ -0,0 +1,84 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// dbStats holds counters shared by a Database and its transactions.
+type dbStats struct {
+	activeTx int64
+
+	mtx     sync.Mutex
+	nextID  uint64
+	ops     map[uint64]operation
+	waiters []chan struct{}
+	closed  bool
+}
+
+type operation struct {
+	Operation
+	cancel context.CancelFunc
+}
+
+// Stats reports pool usage together with sqln counters.
+type Stats struct {
+	sql.DBStats
+	// Statements is the number of cached statements.
+	Statements int
+	// InFlight is the number of Exec, Get and Select calls in progress.
+	InFlight int
+	// ActiveTx is the number of open transactions.
+	ActiveTx int
+}
+
+// Stats returns the current pool statistics.
+func (d *Database) Stats() Stats {
+	return Stats{
+		DBStats:    d.drv.Stats(),
+		Statements: d.cache.len(),
+		InFlight:   d.stats.len(),
+		ActiveTx:   int(atomic.LoadInt64(&d.stats.activeTx)),
+	}
+}
+
+const healthQuery = "SELECT 1;"
+
+// Healthy pings the database and runs a trivial query through the statement
+// cache, so a pool that connects but cannot prepare is reported unhealthy.
+func (d *Database) Healthy(ctx context.Context) error {
+	if err := d.drv.PingContext(ctx); err != nil {
+		return errors.Wrap(err, "ping")
+	}
+	s, release, err := d.acquire(ctx, healthQuery)
+	if err != nil {
+		return errors.Wrap(err, "prepare")
+	}
+	defer release()
+
+	var n int
+	if err := s.GetContext(ctx, &n, struct{}{}); err != nil {
+		return errors.Wrap(err, "select")
+	}
+	return nil
+}
+
+// HealthHandler returns a handler for readiness probes which responds 200
+// when Healthy succeeds within timeout and 503 otherwise.
+func (d *Database) HealthHandler(timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		if err := d.Healthy(ctx); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok\n"))
+	})
+}
@@ -0,0 +1,47 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthy(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	if err := db.Healthy(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s := db.Stats(); s.Statements != 1 || s.InFlight != 0 || s.ActiveTx != 0 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+	if err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		if s := db.Stats(); s.ActiveTx != 1 {
+			t.Errorf("expected 1 active tx, got %v", s.ActiveTx)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if s := db.Stats(); s.ActiveTx != 0 {
+		t.Fatalf("expected no active tx, got %v", s.ActiveTx)
+	}
+
+	h := db.HealthHandler(time.Second)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v: %v", rec.Code, rec.Body)
+	}
+
+	db.X.Close()
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %v", rec.Code)
+	}
+}
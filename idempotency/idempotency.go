@@ -0,0 +1,117 @@
+/*
+Package idempotency runs transactions at most once per key, ie. for webhook
+processors and payment flows that may receive the same request twice. Keys are
+recorded in a table, along with the JSON encoded result, in the same
+transaction as the work they guard:
+
+	CREATE TABLE idempotency_keys (
+		idempotency_key TEXT PRIMARY KEY,
+		result          TEXT,
+		created_at      TIMESTAMP NOT NULL
+	);
+
+Old keys can be deleted with the retention package.
+*/
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+)
+
+// DefaultTable is the table keys are recorded in unless Table is given.
+const DefaultTable = "idempotency_keys"
+
+type options struct {
+	table  string
+	txOpts sql.TxOptions
+	now    func() time.Time
+}
+
+// Option configures WithIdempotencyKey.
+type Option func(*options)
+
+// Table records keys in table instead of DefaultTable.
+func Table(table string) Option {
+	return func(o *options) {
+		o.table = table
+	}
+}
+
+// TxOptions sets the options of the transaction.
+func TxOptions(opts sql.TxOptions) Option {
+	return func(o *options) {
+		o.txOpts = opts
+	}
+}
+
+// Clock sets the time recorded with keys. Defaults to sqln.SystemClock.
+func Clock(c sqln.Clock) Option {
+	return func(o *options) {
+		o.now = c.Now
+	}
+}
+
+// WithIdempotencyKey runs f in a transaction unless key was already recorded
+// by a committed call, in which case f is skipped and the recorded result is
+// returned. Concurrent calls with the same key wait for the first to finish.
+// If f fails the transaction is rolled back and the key is not recorded, so
+// the call can be retried.
+func WithIdempotencyKey[T any](ctx context.Context, db sqln.DB, key string, f func(tx sqln.DB) (T, error), opts ...Option) (T, error) {
+	o := options{table: DefaultTable, now: sqln.SystemClock.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var result T
+	err := db.Transact(ctx, o.txOpts, func(tx sqln.DB) error {
+		res, err := tx.Exec(ctx, insertSQL(sqln.DialectOf(db), o.table), map[string]interface{}{
+			"key": key,
+			"now": o.now(),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "recording key %q", key)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			var recorded []byte
+			if err := tx.Get(ctx, fmt.Sprintf("SELECT result FROM %v WHERE idempotency_key = :key;", o.table), &recorded, map[string]interface{}{"key": key}); err != nil {
+				return errors.Wrapf(err, "reading result of key %q", key)
+			}
+			return errors.Wrapf(json.Unmarshal(recorded, &result), "decoding result of key %q", key)
+		}
+
+		if result, err = f(tx); err != nil {
+			return err
+		}
+		b, err := json.Marshal(result)
+		if err != nil {
+			return errors.Wrapf(err, "encoding result of key %q", key)
+		}
+		if _, err := tx.Exec(ctx, fmt.Sprintf("UPDATE %v SET result = :result WHERE idempotency_key = :key;", o.table), map[string]interface{}{
+			"key":    key,
+			"result": string(b),
+		}); err != nil {
+			return errors.Wrapf(err, "recording result of key %q", key)
+		}
+		return nil
+	})
+	return result, err
+}
+
+func insertSQL(dialect sqln.Dialect, table string) string {
+	if dialect == sqln.MySQL {
+		return fmt.Sprintf("INSERT IGNORE INTO %v (idempotency_key, created_at) VALUES (:key, :now);", table)
+	}
+	return fmt.Sprintf("INSERT INTO %v (idempotency_key, created_at) VALUES (:key, :now) ON CONFLICT DO NOTHING;", table)
+}
@@ -0,0 +1,93 @@
+package idempotency
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+)
+
+func TestWithIdempotencyKey(t *testing.T) {
+	dbx, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbx.Close()
+	db := sqln.New(dbx)
+	defer db.Close()
+
+	ctx := context.Background()
+	for _, q := range []string{
+		"CREATE TABLE idempotency_keys (idempotency_key TEXT PRIMARY KEY, result TEXT, created_at TIMESTAMP NOT NULL);",
+		"CREATE TABLE payments (id INTEGER PRIMARY KEY, amount INTEGER);",
+	} {
+		if _, err := db.Exec(ctx, q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type receipt struct {
+		ID     int64 `json:"id"`
+		Amount int   `json:"amount"`
+	}
+	calls := 0
+	pay := func(amount int) func(sqln.DB) (receipt, error) {
+		return func(tx sqln.DB) (receipt, error) {
+			calls++
+			res, err := tx.Exec(ctx, "INSERT INTO payments (amount) VALUES (:amount);", map[string]interface{}{"amount": amount})
+			if err != nil {
+				return receipt{}, err
+			}
+			id, err := res.LastInsertId()
+			return receipt{ID: id, Amount: amount}, err
+		}
+	}
+
+	first, err := WithIdempotencyKey(ctx, db, "pay-1", pay(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := WithIdempotencyKey(ctx, db, "pay-1", pay(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || second != first {
+		t.Fatalf("expected the recorded result %+v, got %+v after %v calls", first, second, calls)
+	}
+
+	// Failed calls do not record their key.
+	if _, err := WithIdempotencyKey(ctx, db, "pay-2", func(sqln.DB) (receipt, error) {
+		return receipt{}, errors.New("declined")
+	}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if r, err := WithIdempotencyKey(ctx, db, "pay-2", pay(30)); err != nil || r.Amount != 30 {
+		t.Fatalf("expected the retry to run, got %+v (%v)", r, err)
+	}
+
+	var n int
+	if err := db.Get(ctx, "SELECT COUNT(*) FROM payments;", &n, nil); err != nil || n != 2 {
+		t.Fatalf("expected 2 payments, got %v (%v)", n, err)
+	}
+}
+
+func TestInsertSQLWrapped(t *testing.T) {
+	// Open does not connect, so no database is needed.
+	dbx, err := sqlx.Open("mysql", "user@/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := sqln.New(dbx)
+	defer d.Close()
+
+	db := sqln.Wrap(d, sqln.Timestamps(sqln.SystemClock))
+	if q := insertSQL(sqln.DialectOf(db), DefaultTable); !strings.HasPrefix(q, "INSERT IGNORE") {
+		t.Fatalf("expected MySQL SQL for a decorated MySQL DB, got %q", q)
+	}
+}
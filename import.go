@@ -0,0 +1,338 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/pkg/errors"
+)
+
+// ImportOptions configures Import.
+type ImportOptions struct {
+	// Struct, if set, is a value of the struct type rows are validated
+	// against: headers are matched to its db tags and fields are parsed as the
+	// type of their field. Headers that match no field are an error. Without
+	// it headers are used as column names and values are passed as text.
+	Struct interface{}
+	// Comma is the field delimiter. Defaults to ','.
+	Comma rune
+	// OnRowError is called with each row that cannot be read or parsed. The
+	// row is skipped if it returns nil, otherwise the import is aborted with
+	// the error returned. Defaults to aborting on the first row error.
+	OnRowError func(RowError) error
+}
+
+// RowError is an error in a single row of an import.
+type RowError struct {
+	// Line is the line of the row in the input, starting at 1 for the
+	// header.
+	Line int
+	Err  error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("line %v: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e RowError) Unwrap() error { return e.Err }
+
+// Import streams CSV rows from r into table and returns the number of rows
+// inserted. The first record is a header naming the columns (see
+// ImportOptions.Struct). Empty fields are inserted as NULL. Rows are streamed
+// into COPY FROM as they are read where BulkInsert would use it, otherwise
+// they are inserted with Exec in batches.
+// NOTE: table and columns are not escaped for the INSERT fallback and must not
+// come from untrusted input.
+func (d *Database) Import(ctx context.Context, table string, r io.Reader, opts ImportOptions) (int64, error) {
+	if d.copies() && d.tx == nil && !d.isPgx() {
+		// lib/pq's COPY FROM runs within a transaction.
+		var n int64
+		err := d.Transact(ctx, sql.TxOptions{}, func(db DB) error {
+			txd, ok := databaseOf(db)
+			if !ok {
+				return errors.Errorf("import: %T is not a Database", db)
+			}
+			var err error
+			n, err = txd.Import(ctx, table, r, opts)
+			return err
+		})
+		return n, err
+	}
+
+	cr := csv.NewReader(r)
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return 0, errors.Wrap(err, "import: header")
+	}
+	columns, parsers, err := importColumns(d.Mapper(), header, opts.Struct)
+	if err != nil {
+		return 0, errors.Wrap(err, "import")
+	}
+	onRowError := opts.OnRowError
+	if onRowError == nil {
+		onRowError = func(e RowError) error { return e }
+	}
+
+	// next returns the next row and its line, skipping the rows onRowError
+	// allows, or io.EOF.
+	next := func() ([]interface{}, int, error) {
+		for {
+			record, err := cr.Read()
+			if err == io.EOF {
+				return nil, 0, io.EOF
+			}
+			var line int
+			row, err := func() ([]interface{}, error) {
+				if err != nil {
+					return nil, err
+				}
+				line, _ = cr.FieldPos(0)
+				row := make([]interface{}, len(record))
+				for i, s := range record {
+					if s == "" {
+						continue
+					}
+					if row[i], err = parsers[i](s); err != nil {
+						return nil, errors.Wrapf(err, "column %v", columns[i])
+					}
+				}
+				return row, nil
+			}()
+			if err != nil {
+				if perr, ok := err.(*csv.ParseError); ok {
+					line, err = perr.Line, perr.Err
+				}
+				if err := onRowError(RowError{Line: line, Err: err}); err != nil {
+					return nil, line, errors.Wrap(err, "import")
+				}
+				continue
+			}
+			return row, line, nil
+		}
+	}
+
+	if !d.copies() {
+		write, flush := d.insertStream(ctx, table, columns)
+		return importRows(next, write, flush)
+	}
+
+	query := copyInQuery(table, columns)
+	ctx, done, err := d.begin(ctx, "Import", query)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+	if err := d.checkQuery(ctx, query); err != nil {
+		return 0, err
+	}
+	if d.isPgx() {
+		src := &importSource{next: next}
+		n, err := d.copyFrom(ctx, table, columns, src)
+		if src.err != nil {
+			return 0, src.err
+		}
+		return n, errors.Wrap(err, "import")
+	}
+	write, flush, err := copyInStream(ctx, d.tx, table, columns)
+	if err != nil {
+		return 0, errors.Wrap(err, "import")
+	}
+	return importRows(next, write, flush)
+}
+
+// importRows writes every row returned by next, then flushes them.
+func importRows(next func() ([]interface{}, int, error), write func([]interface{}) error, flush func() (int64, error)) (int64, error) {
+	for {
+		row, line, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if err := write(row); err != nil {
+			return 0, errors.Wrapf(err, "import: line %v", line)
+		}
+	}
+	n, err := flush()
+	return n, errors.Wrap(err, "import")
+}
+
+// importSource feeds the rows of an import to pgx's CopyFrom as they are
+// read.
+type importSource struct {
+	next func() ([]interface{}, int, error)
+	row  []interface{}
+	err  error
+}
+
+func (s *importSource) Next() bool {
+	row, _, err := s.next()
+	if err != nil {
+		if err != io.EOF {
+			s.err = err
+		}
+		return false
+	}
+	s.row = row
+	return true
+}
+
+func (s *importSource) Values() ([]interface{}, error) { return s.row, nil }
+
+func (s *importSource) Err() error { return s.err }
+
+// importColumns returns the column of each header and a parser of its values,
+// matching headers to the fields of structType mapped by m.
+func importColumns(m *reflectx.Mapper, header []string, structType interface{}) ([]string, []func(string) (interface{}, error), error) {
+	var fields map[string]*reflectx.FieldInfo
+	if structType != nil {
+		t := reflectx.Deref(reflect.TypeOf(structType))
+		if t.Kind() != reflect.Struct {
+			return nil, nil, errors.Errorf("expected a struct, got %T", structType)
+		}
+		fields = m.TypeMap(t).Names
+	}
+
+	columns := make([]string, len(header))
+	parsers := make([]func(string) (interface{}, error), len(header))
+	for i, h := range header {
+		columns[i] = strings.TrimSpace(h)
+		parsers[i] = func(s string) (interface{}, error) { return s, nil }
+		if fields == nil {
+			continue
+		}
+		f, ok := fields[columns[i]]
+		if !ok {
+			f, ok = fields[strings.ToLower(columns[i])]
+		}
+		if !ok {
+			return nil, nil, errors.Errorf("header %q matches no field of %v", h, reflect.TypeOf(structType))
+		}
+		columns[i] = f.Name
+		parsers[i] = fieldParser(reflectx.Deref(f.Field.Type))
+	}
+	return columns, parsers, nil
+}
+
+// fieldParser returns a parser of text values into the kind of t. Other
+// types, ie. sql.Scanners, are passed as text.
+func fieldParser(t reflect.Type) func(string) (interface{}, error) {
+	switch {
+	case t == timeType:
+		return func(s string) (interface{}, error) {
+			if v, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return v, nil
+			}
+			return time.Parse("2006-01-02", s)
+		}
+	case t.Kind() == reflect.Bool:
+		return func(s string) (interface{}, error) { return strconv.ParseBool(s) }
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		return func(s string) (interface{}, error) { return strconv.ParseInt(s, 10, 64) }
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64:
+		return func(s string) (interface{}, error) { return strconv.ParseUint(s, 10, 64) }
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return func(s string) (interface{}, error) { return strconv.ParseFloat(s, 64) }
+	}
+	return func(s string) (interface{}, error) { return s, nil }
+}
+
+// copyInStream starts a COPY FROM into table, returning functions to write a
+// row and to end the copy.
+func copyInStream(ctx context.Context, tx interface {
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+}, table string, columns []string) (func([]interface{}) error, func() (int64, error), error) {
+	stmt, err := tx.PrepareContext(ctx, copyInQuery(table, columns))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "copy: prepare")
+	}
+	write := func(row []interface{}) error {
+		_, err := stmt.ExecContext(ctx, row...)
+		return err
+	}
+	flush := func() (int64, error) {
+		defer stmt.Close()
+		res, err := stmt.ExecContext(ctx)
+		if err != nil {
+			return 0, errors.Wrap(err, "copy: flush")
+		}
+		return res.RowsAffected()
+	}
+	return write, flush, nil
+}
+
+// insertStream returns functions to buffer a row and to insert the buffered
+// rows with Exec, inserting a batch whenever one is full.
+func (d *Database) insertStream(ctx context.Context, table string, columns []string) (func([]interface{}) error, func() (int64, error)) {
+	perBatch := maxBulkParams / len(columns)
+	if perBatch == 0 {
+		perBatch = 1
+	}
+
+	var (
+		rows  [][]interface{}
+		total int64
+	)
+	flush := func() (int64, error) {
+		if len(rows) == 0 {
+			return total, nil
+		}
+		q, params := namedInsert(table, columns, rows)
+		rows = rows[:0]
+		res, err := d.Exec(ctx, q, params)
+		if err != nil {
+			return total, err
+		}
+		n, err := res.RowsAffected()
+		total += n
+		return total, err
+	}
+	write := func(row []interface{}) error {
+		rows = append(rows, row)
+		if len(rows) < perBatch {
+			return nil
+		}
+		_, err := flush()
+		return err
+	}
+	return write, flush
+}
+
+// namedInsert returns a multi-row INSERT of rows into table, with a named
+// param per value.
+func namedInsert(table string, columns []string, rows [][]interface{}) (string, map[string]interface{}) {
+	params := make(map[string]interface{}, len(rows)*len(columns))
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO " + table + " (" + strings.Join(columns, ",") + ") VALUES ")
+	for i, r := range rows {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteByte('(')
+		for j, v := range r {
+			if j > 0 {
+				sb.WriteByte(',')
+			}
+			name := "r" + strconv.Itoa(i) + "_" + strconv.Itoa(j)
+			sb.WriteString(":" + name)
+			params[name] = v
+		}
+		sb.WriteByte(')')
+	}
+	sb.WriteByte(';')
+	return sb.String(), params
+}
@@ -0,0 +1,78 @@
+package sqln
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestImport(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT, age INTEGER, born TIMESTAMP);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	type person struct {
+		ID   int64     `db:"id"`
+		Name string    `db:"name"`
+		Age  *int      `db:"age"`
+		Born time.Time `db:"born"`
+	}
+	const input = `ID,Name,age,born
+1,ann,30,1990-01-02
+2,bob,old,1980-01-02
+3,"cy, jr",
+4,dee,,2000-01-02T03:04:05Z
+`
+
+	// The third line fails to parse, aborting the import.
+	_, err := db.Import(ctx, "people", strings.NewReader(input), ImportOptions{Struct: person{}})
+	var rowErr RowError
+	if !errors.As(err, &rowErr) || rowErr.Line != 3 || !strings.Contains(rowErr.Error(), "column age") {
+		t.Fatalf("expected a row error on line 3, got %v", err)
+	}
+
+	var skipped []int
+	n, err := db.Import(ctx, "people", strings.NewReader(input), ImportOptions{
+		Struct: &person{},
+		OnRowError: func(e RowError) error {
+			skipped = append(skipped, e.Line)
+			return nil
+		},
+	})
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 rows imported, got %v %v", n, err)
+	}
+	if len(skipped) != 2 || skipped[0] != 3 || skipped[1] != 4 {
+		t.Fatalf("expected lines 3 and 4 skipped, got %v", skipped)
+	}
+
+	var people []person
+	if err := db.Select(ctx, "SELECT * FROM people ORDER BY id;", &people, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(people) != 2 || people[0].Name != "ann" || *people[0].Age != 30 || people[1].Age != nil || !people[1].Born.Equal(time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Fatalf("unexpected people: %+v", people)
+	}
+
+	if _, err := db.Import(ctx, "people", strings.NewReader("id,nickname\n"), ImportOptions{Struct: person{}}); err == nil || !strings.Contains(err.Error(), "matches no field") {
+		t.Fatalf("expected unknown header error, got %v", err)
+	}
+
+	// Without a struct headers are column names.
+	n, err = db.Import(ctx, "people", strings.NewReader("id;name\n5;eve\n"), ImportOptions{Comma: ';'})
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 row imported, got %v %v", n, err)
+	}
+
+	// Rows are inserted with Exec, so they are checked like other queries.
+	strict := New(db.X, WithStrict(StrictOptions{Allowlist: true}), WithAllowlist("SELECT 1;"))
+	defer strict.Close()
+	if _, err := strict.Import(ctx, "people", strings.NewReader("id,name\n6,fay\n"), ImportOptions{}); errors.Cause(err) != ErrQueryNotAllowed {
+		t.Fatalf("expected ErrQueryNotAllowed, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package sqln
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Operation is an Exec, Get or Select call in progress.
+type Operation struct {
+	Method string
+	Query  string
+	// Name is the query name, if any (see QueryName).
+	Name    string
+	Started time.Time
+	// Annotations are those of the operation's context (see
+	// WithAnnotation).
+	Annotations map[string]string
+}
+
+// InFlight returns the operations in progress, oldest first.
+func (d *Database) InFlight() []Operation {
+	d.stats.mtx.Lock()
+	ops := make([]Operation, 0, len(d.stats.ops))
+	for _, op := range d.stats.ops {
+		ops = append(ops, op.Operation)
+	}
+	d.stats.mtx.Unlock()
+
+	sort.Slice(ops, func(i, j int) bool { return ops[i].Started.Before(ops[j].Started) })
+	return ops
+}
+
+// CancelAll cancels the context of every operation in progress, returning
+// the number cancelled. Operations started afterwards are not affected.
+func (d *Database) CancelAll() int {
+	d.stats.mtx.Lock()
+	defer d.stats.mtx.Unlock()
+	for _, op := range d.stats.ops {
+		op.cancel()
+	}
+	return len(d.stats.ops)
+}
+
+// Drain waits until no operations are in progress or ctx is done, ie. to
+// let in-flight work finish during shutdown before calling CancelAll.
+func (d *Database) Drain(ctx context.Context) error {
+	d.stats.mtx.Lock()
+	if len(d.stats.ops) == 0 {
+		d.stats.mtx.Unlock()
+		return nil
+	}
+	idle := make(chan struct{})
+	d.stats.waiters = append(d.stats.waiters, idle)
+	d.stats.mtx.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// begin tracks an operation until the returned func is called. The returned
+// context is cancelled by CancelAll and bounded by WithQueryTimeout. It
+// returns ErrClosed once the Database is closed.
+func (d *Database) begin(ctx context.Context, method, query string) (context.Context, func(), error) {
+	if d.txState != nil {
+		if err := d.txState.ran(ctx, query); err != nil {
+			return ctx, nil, err
+		}
+	}
+	if d.usage != nil && !internal(ctx) {
+		d.usage.ran(ctx, d.registry, query)
+	}
+	s := d.stats
+	s.mtx.Lock()
+	if s.closed {
+		s.mtx.Unlock()
+		return ctx, nil, ErrClosed
+	}
+	ctx, cancel := d.opContext(ctx)
+	s.nextID++
+	id := s.nextID
+	s.ops[id] = operation{
+		Operation: Operation{Method: method, Query: query, Name: QueryName(ctx, query), Started: time.Now(), Annotations: Annotations(ctx)},
+		cancel:    cancel,
+	}
+	started := s.ops[id].Started
+	s.mtx.Unlock()
+
+	end := func() {
+		if d.txState != nil {
+			d.txState.done(time.Since(started))
+		}
+		cancel()
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		delete(s.ops, id)
+		if len(s.ops) == 0 {
+			for _, w := range s.waiters {
+				close(w)
+			}
+			s.waiters = nil
+		}
+	}
+	release, err := d.acquireLimits(ctx, query)
+	if err != nil {
+		end()
+		return ctx, nil, err
+	}
+	if release == nil {
+		return ctx, end, nil
+	}
+	return ctx, func() {
+		release()
+		end()
+	}, nil
+}
+
+func (s *dbStats) len() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.ops)
+}
+
+func (s *dbStats) isClosed() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.closed
+}
@@ -0,0 +1,49 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInFlight(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	const forever = "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT COUNT(*) FROM c;"
+	errs := make(chan error)
+	go func() {
+		var n int
+		errs <- db.Get(ctx, forever, &n, nil)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(db.InFlight()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the query to be in flight")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if ops := db.InFlight(); ops[0].Method != "Get" || ops[0].Query != forever {
+		t.Fatalf("unexpected operations: %+v", ops)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := db.Drain(short); err != context.DeadlineExceeded {
+		t.Fatalf("expected drain to time out, got %v", err)
+	}
+
+	if n := db.CancelAll(); n != 1 {
+		t.Fatalf("expected 1 cancelled operation, got %v", n)
+	}
+	if err := <-errs; err == nil {
+		t.Fatal("expected the cancelled query to fail")
+	}
+	if err := db.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s := db.Stats(); s.InFlight != 0 {
+		t.Fatalf("expected nothing in flight, got %v", s.InFlight)
+	}
+}
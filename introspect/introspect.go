@@ -0,0 +1,202 @@
+/*
+Package introspect reads schema metadata (from information_schema, pg_catalog
+or SQLite pragmas) through a sqln.DB, for validation, admin tooling and code
+generation. Postgres, MySQL and SQLite are supported; DBs whose dialect is
+unknown (see sqln.DialectOf) are assumed to be Postgres.
+*/
+package introspect
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+)
+
+// Column describes a table column.
+type Column struct {
+	Name string `db:"name"`
+	// Type is the data type as reported by the database, ie. "integer" or
+	// "character varying".
+	Type       string         `db:"type"`
+	Nullable   bool           `db:"nullable"`
+	Default    sql.NullString `db:"default_value"`
+	PrimaryKey bool           `db:"primary_key"`
+	Position   int            `db:"position"`
+}
+
+func dialect(db sqln.DB) sqln.Dialect {
+	if d := sqln.DialectOf(db); d != sqln.UnknownDialect {
+		return d
+	}
+	return sqln.Postgres
+}
+
+var columnsQueries = map[sqln.Dialect]string{
+	sqln.Postgres: `SELECT c.column_name AS name, c.data_type AS type, c.is_nullable = 'YES' AS nullable,
+	c.column_default AS default_value, c.ordinal_position AS position,
+	EXISTS (
+		SELECT 1 FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage k
+			ON k.constraint_name = tc.constraint_name AND k.table_schema = tc.table_schema
+		WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = c.table_schema
+			AND tc.table_name = c.table_name AND k.column_name = c.column_name
+	) AS primary_key
+FROM information_schema.columns c
+WHERE c.table_schema = current_schema() AND c.table_name = :table
+ORDER BY c.ordinal_position;`,
+	sqln.MySQL: `SELECT column_name AS name, data_type AS type, is_nullable = 'YES' AS nullable,
+	column_default AS default_value, ordinal_position AS position, column_key = 'PRI' AS primary_key
+FROM information_schema.columns
+WHERE table_schema = DATABASE() AND table_name = :table
+ORDER BY ordinal_position;`,
+	sqln.SQLite: `SELECT name, lower(type) AS type, NOT "notnull" AND pk = 0 AS nullable,
+	dflt_value AS default_value, cid + 1 AS position, pk > 0 AS primary_key
+FROM pragma_table_info(:table)
+ORDER BY cid;`,
+}
+
+// Columns returns the columns of table in the current schema, in order.
+func Columns(ctx context.Context, db sqln.DB, table string) ([]Column, error) {
+	var cols []Column
+	if err := query(ctx, db, columnsQueries, &cols, map[string]interface{}{"table": table}); err != nil {
+		return nil, errors.Wrapf(err, "columns of %v", table)
+	}
+	return cols, nil
+}
+
+// query selects into dest with the query for the dialect of db.
+func query(ctx context.Context, db sqln.DB, queries map[sqln.Dialect]string, dest interface{}, params interface{}) error {
+	q, ok := queries[dialect(db)]
+	if !ok {
+		return errors.Errorf("introspect: dialect %q is not supported", dialect(db))
+	}
+	return db.Select(ctx, q, dest, params)
+}
+
+var tablesQueries = map[sqln.Dialect]string{
+	sqln.Postgres: `SELECT table_name AS name FROM information_schema.tables
+WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+ORDER BY table_name;`,
+	sqln.MySQL: `SELECT table_name AS name FROM information_schema.tables
+WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE'
+ORDER BY table_name;`,
+	sqln.SQLite: `SELECT name FROM sqlite_master
+WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+ORDER BY name;`,
+}
+
+// Tables returns the names of the tables in the current schema.
+func Tables(ctx context.Context, db sqln.DB) ([]string, error) {
+	var tables []string
+	if err := query(ctx, db, tablesQueries, &tables, nil); err != nil {
+		return nil, errors.Wrap(err, "tables")
+	}
+	return tables, nil
+}
+
+// Index describes a table index.
+type Index struct {
+	Name    string
+	Columns []string
+	Unique  bool
+	Primary bool
+}
+
+var indexesQueries = map[sqln.Dialect]string{
+	sqln.Postgres: `SELECT i.relname AS name, a.attname AS column_name, ix.indisunique AS is_unique, ix.indisprimary AS is_primary
+FROM pg_class t
+JOIN pg_namespace n ON n.oid = t.relnamespace
+JOIN pg_index ix ON ix.indrelid = t.oid
+JOIN pg_class i ON i.oid = ix.indexrelid
+CROSS JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord)
+JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+WHERE n.nspname = current_schema() AND t.relname = :table
+ORDER BY i.relname, k.ord;`,
+	sqln.MySQL: `SELECT index_name AS name, column_name, non_unique = 0 AS is_unique, index_name = 'PRIMARY' AS is_primary
+FROM information_schema.statistics
+WHERE table_schema = DATABASE() AND table_name = :table
+ORDER BY index_name, seq_in_index;`,
+	sqln.SQLite: `SELECT il.name AS name, ii.name AS column_name, il."unique" AS is_unique, il.origin = 'pk' AS is_primary
+FROM pragma_index_list(:table) il, pragma_index_info(il.name) ii
+ORDER BY il.name, ii.seqno;`,
+}
+
+// Indexes returns the indexes of table, sorted by name.
+// NOTE: A SQLite INTEGER PRIMARY KEY is the rowid and has no index.
+func Indexes(ctx context.Context, db sqln.DB, table string) ([]Index, error) {
+	var rows []struct {
+		Name    string `db:"name"`
+		Column  string `db:"column_name"`
+		Unique  bool   `db:"is_unique"`
+		Primary bool   `db:"is_primary"`
+	}
+	if err := query(ctx, db, indexesQueries, &rows, map[string]interface{}{"table": table}); err != nil {
+		return nil, errors.Wrapf(err, "indexes of %v", table)
+	}
+
+	var idxs []Index
+	for _, r := range rows {
+		if len(idxs) == 0 || idxs[len(idxs)-1].Name != r.Name {
+			idxs = append(idxs, Index{Name: r.Name, Unique: r.Unique, Primary: r.Primary})
+		}
+		last := &idxs[len(idxs)-1]
+		last.Columns = append(last.Columns, r.Column)
+	}
+	return idxs, nil
+}
+
+// ForeignKey describes a foreign key constraint.
+type ForeignKey struct {
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+}
+
+var foreignKeysQueries = map[sqln.Dialect]string{
+	sqln.Postgres: `SELECT c.conname AS name, a.attname AS column_name, rt.relname AS ref_table, ra.attname AS ref_column
+FROM pg_constraint c
+JOIN pg_class t ON t.oid = c.conrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+JOIN pg_class rt ON rt.oid = c.confrelid
+CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, refattnum, ord)
+JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+JOIN pg_attribute ra ON ra.attrelid = c.confrelid AND ra.attnum = k.refattnum
+WHERE c.contype = 'f' AND n.nspname = current_schema() AND t.relname = :table
+ORDER BY c.conname, k.ord;`,
+	sqln.MySQL: `SELECT constraint_name AS name, column_name, referenced_table_name AS ref_table, referenced_column_name AS ref_column
+FROM information_schema.key_column_usage
+WHERE table_schema = DATABASE() AND table_name = :table AND referenced_table_name IS NOT NULL
+ORDER BY constraint_name, ordinal_position;`,
+	// SQLite constraints are unnamed, so the name is the constraint id. The
+	// referenced column is empty when it implicitly is the primary key.
+	sqln.SQLite: `SELECT 'fk_' || id AS name, "from" AS column_name, "table" AS ref_table, COALESCE("to", '') AS ref_column
+FROM pragma_foreign_key_list(:table)
+ORDER BY id, seq;`,
+}
+
+// ForeignKeys returns the foreign keys of table, sorted by name.
+func ForeignKeys(ctx context.Context, db sqln.DB, table string) ([]ForeignKey, error) {
+	var rows []struct {
+		Name      string `db:"name"`
+		Column    string `db:"column_name"`
+		RefTable  string `db:"ref_table"`
+		RefColumn string `db:"ref_column"`
+	}
+	if err := query(ctx, db, foreignKeysQueries, &rows, map[string]interface{}{"table": table}); err != nil {
+		return nil, errors.Wrapf(err, "foreign keys of %v", table)
+	}
+
+	var fks []ForeignKey
+	for _, r := range rows {
+		if len(fks) == 0 || fks[len(fks)-1].Name != r.Name {
+			fks = append(fks, ForeignKey{Name: r.Name, RefTable: r.RefTable})
+		}
+		last := &fks[len(fks)-1]
+		last.Columns = append(last.Columns, r.Column)
+		last.RefColumns = append(last.RefColumns, r.RefColumn)
+	}
+	return fks, nil
+}
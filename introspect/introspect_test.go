@@ -0,0 +1,119 @@
+package introspect
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nstogner/sqln"
+)
+
+func sqliteDB(t *testing.T) *sqln.Database {
+	dbx, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_foreign_keys=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sqln.New(dbx)
+	t.Cleanup(func() {
+		db.Close()
+		dbx.Close()
+	})
+	return db
+}
+
+func TestCheckModel(t *testing.T) {
+	db := sqliteDB(t)
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, `CREATE TABLE users (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	age INTEGER,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	email TEXT NOT NULL
+);`, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	cols, err := Columns(ctx, db, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != 5 || !cols[0].PrimaryKey || cols[1].Nullable || !cols[2].Nullable || !cols[3].Default.Valid {
+		t.Fatalf("unexpected columns: %+v", cols)
+	}
+	// Decorated DBs are introspected with the queries of their dialect.
+	if wrapped, err := Columns(ctx, sqln.Wrap(db, sqln.Timestamps(sqln.SystemClock)), "users"); err != nil || len(wrapped) != len(cols) {
+		t.Fatalf("unexpected columns of a decorated DB: %+v (%v)", wrapped, err)
+	}
+
+	type user struct {
+		ID       int     `db:"id"`
+		Name     string  `db:"name"`
+		Age      *string `db:"age"`
+		Nickname string  `db:"nickname"`
+	}
+	problems, err := CheckModel(ctx, db, "users", &user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]ProblemKind{
+		"age":      TypeMismatch,
+		"nickname": MissingColumn,
+		"email":    MissingField,
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %v problems, got %v", len(expected), problems)
+	}
+	for _, p := range problems {
+		if expected[p.Column] != p.Kind {
+			t.Errorf("unexpected problem: %v", p)
+		}
+	}
+
+	if _, err := CheckModels(ctx, db, map[string]interface{}{"missing": user{}}); err == nil {
+		t.Fatal("expected error for missing table")
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	db := sqliteDB(t)
+
+	ctx := context.Background()
+	for _, q := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, org TEXT NOT NULL, email TEXT NOT NULL);",
+		"CREATE UNIQUE INDEX users_org_email ON users (org, email);",
+		"CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL REFERENCES users (id));",
+	} {
+		if _, err := db.Exec(ctx, q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tables, err := Tables(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 2 || tables[0] != "posts" || tables[1] != "users" {
+		t.Fatalf("unexpected tables: %v", tables)
+	}
+
+	idxs, err := Indexes(ctx, db, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idxs) != 1 || idxs[0].Name != "users_org_email" || !idxs[0].Unique || len(idxs[0].Columns) != 2 || idxs[0].Columns[1] != "email" {
+		t.Fatalf("unexpected indexes: %+v", idxs)
+	}
+
+	fks, err := ForeignKeys(ctx, db, "posts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fks) != 1 || fks[0].RefTable != "users" || fks[0].Columns[0] != "user_id" || fks[0].RefColumns[0] != "id" {
+		t.Fatalf("unexpected foreign keys: %+v", fks)
+	}
+}
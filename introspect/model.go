@@ -0,0 +1,207 @@
+package introspect
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+)
+
+// ProblemKind classifies a mismatch between a model and its table.
+type ProblemKind string
+
+// Problems reported by CheckModel.
+const (
+	// MissingColumn is a db tagged field with no matching column.
+	MissingColumn ProblemKind = "missing column"
+	// TypeMismatch is a field whose Go type cannot hold the column type.
+	TypeMismatch ProblemKind = "type mismatch"
+	// MissingField is a non-null column without a default that the model
+	// does not have, so inserting the model would fail.
+	MissingField ProblemKind = "missing field"
+)
+
+// Problem is a single mismatch between a model and its table.
+type Problem struct {
+	Table  string
+	Column string
+	Kind   ProblemKind
+	Detail string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%v.%v: %v: %v", p.Table, p.Column, p.Kind, p.Detail)
+}
+
+var mapper = reflectx.NewMapperFunc("db", sqlx.NameMapper)
+
+// CheckModel compares the db tagged fields of model (a struct or pointer to
+// one) with the columns of table, catching drift between Go models and
+// migrations. Fields are matched the same way sqln scans rows.
+func CheckModel(ctx context.Context, db sqln.DB, table string, model interface{}) ([]Problem, error) {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errors.Errorf("introspect: expected a struct model, got %T", model)
+	}
+
+	cols, err := Columns(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(cols) == 0 {
+		return nil, errors.Errorf("introspect: table %v not found", table)
+	}
+	byName := make(map[string]Column, len(cols))
+	for _, c := range cols {
+		byName[c.Name] = c
+	}
+
+	var problems []Problem
+	fields := make(map[string]bool)
+	for _, f := range mapper.TypeMap(t).Index {
+		if strings.Contains(f.Path, ".") || hasChildren(f) {
+			continue
+		}
+		fields[f.Path] = true
+
+		c, ok := byName[f.Path]
+		if !ok {
+			problems = append(problems, Problem{Table: table, Column: f.Path, Kind: MissingColumn,
+				Detail: fmt.Sprintf("field %v has no column", f.Name)})
+			continue
+		}
+		if want, got := goFamily(f.Field.Type), columnFamily(c.Type); want != "" && got != "" && want != got {
+			problems = append(problems, Problem{Table: table, Column: c.Name, Kind: TypeMismatch,
+				Detail: fmt.Sprintf("field %v is %v but column is %v", f.Name, f.Field.Type, c.Type)})
+		}
+	}
+
+	for _, c := range cols {
+		if !fields[c.Name] && !c.Nullable && !c.Default.Valid && !autoIncrement(c) {
+			problems = append(problems, Problem{Table: table, Column: c.Name, Kind: MissingField,
+				Detail: fmt.Sprintf("non-null %v column has no field", c.Type)})
+		}
+	}
+	return problems, nil
+}
+
+// CheckModels checks several models keyed by table name.
+func CheckModels(ctx context.Context, db sqln.DB, models map[string]interface{}) ([]Problem, error) {
+	tables := make([]string, 0, len(models))
+	for t := range models {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+
+	var all []Problem
+	for _, t := range tables {
+		problems, err := CheckModel(ctx, db, t, models[t])
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, problems...)
+	}
+	return all, nil
+}
+
+func hasChildren(f *reflectx.FieldInfo) bool {
+	for _, c := range f.Children {
+		if c != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// autoIncrement reports whether the database generates c's values without a
+// declared default, ie. a SQLite INTEGER PRIMARY KEY.
+func autoIncrement(c Column) bool {
+	return c.PrimaryKey && columnFamily(c.Type) == "integer" && !strings.Contains(c.Type, "char")
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	bytesType    = reflect.TypeOf([]byte(nil))
+	nullFamilies = map[reflect.Type]string{
+		reflect.TypeOf(sql.NullString{}):  "string",
+		reflect.TypeOf(sql.NullInt64{}):   "integer",
+		reflect.TypeOf(sql.NullInt32{}):   "integer",
+		reflect.TypeOf(sql.NullInt16{}):   "integer",
+		reflect.TypeOf(sql.NullByte{}):    "integer",
+		reflect.TypeOf(sql.NullFloat64{}): "float",
+		reflect.TypeOf(sql.NullBool{}):    "bool",
+		reflect.TypeOf(sql.NullTime{}):    "time",
+	}
+)
+
+// goFamily returns the type family of a field, or "" if unknown (ie. a custom
+// sql.Scanner).
+func goFamily(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if f, ok := nullFamilies[t]; ok {
+		return f
+	}
+	switch {
+	case t == timeType:
+		return "time"
+	case t == bytesType:
+		return "bytes"
+	}
+	if reflect.PtrTo(t).Implements(reflect.TypeOf((*sql.Scanner)(nil)).Elem()) {
+		return ""
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Bool:
+		return "bool"
+	case reflect.String:
+		return "string"
+	}
+	return ""
+}
+
+// columnFamily returns the type family of a column type, or "" if unknown.
+// Decimal types are unknown since they are commonly scanned into strings.
+func columnFamily(typ string) string {
+	typ = strings.ToLower(typ)
+	if i := strings.IndexByte(typ, '('); i >= 0 {
+		typ = typ[:i]
+	}
+	typ = strings.TrimSpace(typ)
+
+	switch typ {
+	case "smallint", "integer", "int", "bigint", "int2", "int4", "int8", "tinyint", "mediumint",
+		"serial", "bigserial", "smallserial":
+		return "integer"
+	case "real", "double precision", "double", "float", "float4", "float8":
+		return "float"
+	case "boolean", "bool":
+		return "bool"
+	case "text", "character varying", "varchar", "character", "char", "citext", "uuid", "enum",
+		"tinytext", "mediumtext", "longtext", "name":
+		return "string"
+	case "bytea", "blob", "binary", "varbinary", "longblob", "mediumblob", "tinyblob":
+		return "bytes"
+	case "date", "datetime", "time", "time without time zone", "time with time zone",
+		"timestamp", "timestamp without time zone", "timestamp with time zone", "timestamptz":
+		return "time"
+	}
+	return ""
+}
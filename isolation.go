@@ -0,0 +1,56 @@
+package sqln
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ReadCommitted returns the options of a read committed transaction, the
+// default of Postgres, in which each statement sees the data committed before
+// it began.
+func ReadCommitted() sql.TxOptions {
+	return sql.TxOptions{Isolation: sql.LevelReadCommitted}
+}
+
+// ReadCommittedReadOnly returns the options of a read only, read committed
+// transaction, ie. for reports that tolerate changes between statements.
+func ReadCommittedReadOnly() sql.TxOptions {
+	return sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: true}
+}
+
+// RepeatableRead returns the options of a repeatable read transaction, in
+// which every statement sees the same snapshot. Concurrent updates of the
+// same rows fail with serialization failures, which should be retried (see
+// RetryPolicy).
+func RepeatableRead() sql.TxOptions {
+	return sql.TxOptions{Isolation: sql.LevelRepeatableRead}
+}
+
+// RepeatableReadReadOnly returns the options of a read only, repeatable read
+// transaction, ie. for consistent reads across several statements.
+func RepeatableReadReadOnly() sql.TxOptions {
+	return sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+}
+
+// Serializable returns the options of a serializable transaction, which
+// behaves as if run alone. Any conflict with a concurrent transaction fails
+// with a serialization failure, so it must be retried (see RetryPolicy).
+func Serializable() sql.TxOptions {
+	return sql.TxOptions{Isolation: sql.LevelSerializable}
+}
+
+// RetryPolicy returns the recommended retries of a whole transaction run with
+// opts, ie.
+//
+//	opts := sqln.TransactionalOptions{Tx: sqln.Serializable(), Retry: sqln.RetryPolicy(sqln.Serializable())}
+//
+// Repeatable read and serializable transactions fail on conflicts under
+// normal load, so they are retried more often and sooner than the defaults of
+// RetryOptions.
+func RetryPolicy(opts sql.TxOptions) RetryOptions {
+	// Read only snapshots do not conflict, short of serializable.
+	if opts.Isolation >= sql.LevelRepeatableRead && !(opts.ReadOnly && opts.Isolation < sql.LevelSerializable) {
+		return RetryOptions{Attempts: 5, Backoff: 10 * time.Millisecond, MaxBackoff: 500 * time.Millisecond, Retryable: Transient}
+	}
+	return RetryOptions{Attempts: 3, Backoff: 50 * time.Millisecond, MaxBackoff: time.Second, Retryable: Transient}
+}
@@ -0,0 +1,36 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestIsolationPresets(t *testing.T) {
+	cases := []struct {
+		opts     sql.TxOptions
+		attempts int
+	}{
+		{ReadCommitted(), 3},
+		{ReadCommittedReadOnly(), 3},
+		{RepeatableRead(), 5},
+		{RepeatableReadReadOnly(), 3},
+		{Serializable(), 5},
+		{sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}, 5},
+		{sql.TxOptions{}, 3},
+	}
+	for i, c := range cases {
+		if r := RetryPolicy(c.opts); r.Attempts != c.attempts || r.Retryable == nil {
+			t.Errorf("case %v: expected %v attempts, got %+v", i, c.attempts, r)
+		}
+	}
+
+	db := sqliteDB(t)
+	ctx := context.Background()
+	if err := db.Transact(ctx, ReadCommittedReadOnly(), func(tx DB) error {
+		var n int
+		return tx.Get(ctx, "SELECT 1;", &n, nil)
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,188 @@
+package sqln
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/pkg/errors"
+)
+
+// SelectJoined selects the rows of a JOIN into parents of type P, grouping
+// rows with the same keyColumn into one parent. P must have a single slice
+// field of structs, whose columns are selected with its db name as a prefix:
+//
+//	type Order struct {
+//		ID    int        `db:"id"`
+//		Items []LineItem `db:"item"`
+//	}
+//
+//	SELECT o.id, i.sku AS "item.sku", i.qty AS "item.qty"
+//	FROM orders o LEFT JOIN line_items i ON i.order_id = o.id
+//	ORDER BY o.id;
+//
+// A row whose child columns are all NULL (ie. a LEFT JOIN without a match)
+// adds no child. Parents are returned in the order they are first seen.
+func SelectJoined[P any](ctx context.Context, db DB, query, keyColumn string, params interface{}) ([]P, error) {
+	pt := reflect.TypeOf((*P)(nil)).Elem()
+	m, tag := mapperOf(db)
+	j, err := joinOf(m, tag, pt, keyColumn)
+	if err != nil {
+		return nil, errors.Wrap(err, "select joined")
+	}
+
+	rows := reflect.New(reflect.SliceOf(j.row))
+	if err := db.Select(ctx, query, rows.Interface(), params); err != nil {
+		return nil, err
+	}
+	rows = rows.Elem()
+
+	var (
+		parents []P
+		seen    = make(map[interface{}]int)
+	)
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Index(i)
+		key := row.Field(j.key).Interface()
+		pi, ok := seen[key]
+		if !ok {
+			var p P
+			pv := reflect.ValueOf(&p).Elem()
+			for _, c := range j.parent {
+				reflectx.FieldByIndexes(pv, c.index).Set(row.Field(c.field))
+			}
+			pi = len(parents)
+			seen[key] = pi
+			parents = append(parents, p)
+		}
+
+		child, ok := j.childOf(row)
+		if !ok {
+			continue
+		}
+		children := reflectx.FieldByIndexes(reflect.ValueOf(&parents[pi]).Elem(), j.children)
+		children.Set(reflect.Append(children, child))
+	}
+	return parents, nil
+}
+
+// join describes the row type scanned by SelectJoined.
+type join struct {
+	row reflect.Type
+	// parent and child copy fields of row to the parent and child, in
+	// order of their paths so structs are set before their fields. Child
+	// fields of row are pointers.
+	parent, child []fieldCopy
+	// children is the index of the slice field of the parent.
+	children  []int
+	childType reflect.Type
+	key       int
+}
+
+type fieldCopy struct {
+	field int
+	index []int
+}
+
+// joinOf builds the join of parent type pt, mapping fields with m and tagging
+// the fields of the row type with tag.
+func joinOf(m *reflectx.Mapper, tag string, pt reflect.Type, keyColumn string) (*join, error) {
+	if pt.Kind() != reflect.Struct {
+		return nil, errors.Errorf("expected struct parent, got %v", pt)
+	}
+	pm := m.TypeMap(pt)
+
+	var prefix string
+	j := &join{key: -1}
+	for _, fi := range pm.Index {
+		t := fi.Field.Type
+		if fi.Embedded || t.Kind() != reflect.Slice || reflectx.Deref(t.Elem()).Kind() != reflect.Struct {
+			continue
+		}
+		if j.children != nil {
+			return nil, errors.Errorf("%v has more than one slice of structs", pt)
+		}
+		prefix, j.children, j.childType = fi.Path, fi.Index, t.Elem()
+	}
+	if j.children == nil {
+		return nil, errors.Errorf("%v has no slice of structs", pt)
+	}
+
+	var fields []reflect.StructField
+	for _, path := range sortedPaths(pm) {
+		if path == prefix || strings.HasPrefix(path, prefix+".") {
+			continue
+		}
+		fi := pm.Names[path]
+		if path == keyColumn {
+			j.key = len(fields)
+		}
+		j.parent = append(j.parent, fieldCopy{field: len(fields), index: fi.Index})
+		fields = append(fields, joinField(tag, len(fields), path, fi.Field.Type))
+	}
+	if j.key < 0 {
+		return nil, errors.Errorf("key %q not found in %v", keyColumn, pt)
+	}
+
+	cm := m.TypeMap(reflectx.Deref(j.childType))
+	for _, path := range sortedPaths(cm) {
+		fi := cm.Names[path]
+		j.child = append(j.child, fieldCopy{field: len(fields), index: fi.Index})
+		fields = append(fields, joinField(tag, len(fields), prefix+"."+path, reflect.PointerTo(fi.Field.Type)))
+	}
+
+	j.row = reflect.StructOf(fields)
+	if !j.row.Field(j.key).Type.Comparable() {
+		return nil, errors.Errorf("key %q of type %v is not comparable", keyColumn, j.row.Field(j.key).Type)
+	}
+	return j, nil
+}
+
+func joinField(tag string, i int, column string, t reflect.Type) reflect.StructField {
+	return reflect.StructField{Name: fmt.Sprintf("F%v", i), Type: t, Tag: reflect.StructTag(fmt.Sprintf("%v:%q", tag, column))}
+}
+
+// sortedPaths returns the column paths of m, parents before their fields.
+// Fields of scanners (ie. sql.NullString) are not columns.
+func sortedPaths(m *reflectx.StructMap) []string {
+	paths := make([]string, 0, len(m.Names))
+	for path, fi := range m.Names {
+		scanned := false
+		for p := fi.Parent; p != nil && p.Field.Type != nil; p = p.Parent {
+			if reflect.PointerTo(reflectx.Deref(p.Field.Type)).Implements(scannerType) {
+				scanned = true
+			}
+		}
+		if !scanned {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// childOf returns the child scanned into row, unless all its columns are
+// NULL.
+func (j *join) childOf(row reflect.Value) (reflect.Value, bool) {
+	ct := reflectx.Deref(j.childType)
+	child := reflect.New(ct).Elem()
+	found := false
+	for _, c := range j.child {
+		v := row.Field(c.field)
+		if v.IsNil() {
+			continue
+		}
+		found = true
+		reflectx.FieldByIndexes(child, c.index).Set(v.Elem())
+	}
+	if !found {
+		return reflect.Value{}, false
+	}
+	if j.childType.Kind() == reflect.Ptr {
+		return child.Addr(), true
+	}
+	return child, true
+}
@@ -0,0 +1,59 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func TestSelectJoined(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	for _, q := range []string{
+		"CREATE TABLE orders (id INTEGER, customer TEXT);",
+		"CREATE TABLE line_items (order_id INTEGER, sku TEXT, qty INTEGER, note TEXT);",
+		"INSERT INTO orders (id, customer) VALUES (1, 'a'), (2, 'b'), (3, 'c');",
+		"INSERT INTO line_items (order_id, sku, qty, note) VALUES (1, 'x', 1, NULL), (1, 'y', 2, 'gift'), (3, 'z', 3, NULL);",
+	} {
+		if _, err := db.Exec(ctx, q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type lineItem struct {
+		SKU  string         `db:"sku"`
+		Qty  int            `db:"qty"`
+		Note sql.NullString `db:"note"`
+	}
+	type order struct {
+		ID       int            `db:"id"`
+		Customer sql.NullString `db:"customer"`
+		Items    []lineItem     `db:"item"`
+	}
+
+	valid := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+	orders, err := SelectJoined[order](ctx, db, `SELECT o.id, o.customer, i.sku AS "item.sku", i.qty AS "item.qty", i.note AS "item.note"
+FROM orders o LEFT JOIN line_items i ON i.order_id = o.id
+WHERE o.id >= :min
+ORDER BY o.id, i.sku;`, "id", map[string]interface{}{"min": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []order{
+		{ID: 1, Customer: valid("a"), Items: []lineItem{{SKU: "x", Qty: 1}, {SKU: "y", Qty: 2, Note: valid("gift")}}},
+		{ID: 2, Customer: valid("b")},
+		{ID: 3, Customer: valid("c"), Items: []lineItem{{SKU: "z", Qty: 3}}},
+	}
+	if !reflect.DeepEqual(orders, expected) {
+		t.Fatalf("unexpected orders:\n%+v\nexpected:\n%+v", orders, expected)
+	}
+
+	if _, err := SelectJoined[order](ctx, db, "SELECT id FROM orders;", "missing", nil); err == nil {
+		t.Fatal("expected error for unknown key")
+	}
+	if _, err := SelectJoined[lineItem](ctx, db, "SELECT sku FROM line_items;", "sku", nil); err == nil {
+		t.Fatal("expected error for parent without children")
+	}
+}
@@ -0,0 +1,103 @@
+package sqln
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	// Cursors commonly include timestamps.
+	gob.Register(time.Time{})
+}
+
+// Cursor holds the key values of the last row of a page.
+type Cursor []interface{}
+
+// keysetWhere returns a condition selecting rows past the cursor params
+// (see keysetParams).
+func keysetWhere(keys []string, desc bool) string {
+	cmp := ">"
+	if desc {
+		cmp = "<"
+	}
+	vals := make([]string, len(keys))
+	for i := range keys {
+		vals[i] = fmt.Sprintf(":sqln_after_%v", i)
+	}
+	return fmt.Sprintf("(%v) %v (%v)", strings.Join(keys, ", "), cmp, strings.Join(vals, ", "))
+}
+
+func keysetOrder(keys []string, desc bool) string {
+	order := make([]string, len(keys))
+	for i, k := range keys {
+		order[i] = k
+		if desc {
+			order[i] += " DESC"
+		}
+	}
+	return strings.Join(order, ", ")
+}
+
+func keysetParams(after Cursor, limit int) map[string]interface{} {
+	m := map[string]interface{}{"sqln_limit": limit}
+	for i, v := range after {
+		m[fmt.Sprintf("sqln_after_%v", i)] = v
+	}
+	return m
+}
+
+// nextCursor returns the keys of the last row in rows (a slice of structs
+// mapped by m), or nil if it holds fewer than limit rows.
+func nextCursor(m *reflectx.Mapper, keys []string, rows reflect.Value, limit int) (Cursor, error) {
+	if rows.Len() < limit || rows.Len() == 0 {
+		return nil, nil
+	}
+	fields := m.FieldMap(reflect.Indirect(rows.Index(rows.Len() - 1)))
+	next := make(Cursor, len(keys))
+	for i, k := range keys {
+		f, ok := fields[k]
+		if !ok {
+			return nil, errors.Errorf("key %q not found in dest", k)
+		}
+		next[i] = f.Interface()
+	}
+	return next, nil
+}
+
+// Encode returns an opaque string form of the cursor for use in APIs. Values
+// must be types known to encoding/gob (see gob.Register).
+func (c Cursor) Encode() (string, error) {
+	if c == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode([]interface{}(c)); err != nil {
+		return "", errors.Wrap(err, "encoding cursor")
+	}
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// DecodeCursor parses a cursor returned by Encode. An empty string is a nil
+// cursor.
+func DecodeCursor(s string) (Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding cursor")
+	}
+	var vals []interface{}
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&vals); err != nil {
+		return nil, errors.Wrap(err, "decoding cursor")
+	}
+	return Cursor(vals), nil
+}
@@ -0,0 +1,105 @@
+package sqln
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/semaphore"
+)
+
+// ConcurrencyLimits bounds concurrent operations (see WithConcurrencyLimits).
+type ConcurrencyLimits struct {
+	// Max bounds the total weight of concurrent operations. Zero is
+	// unbounded.
+	Max int64
+	// Weights sets the weight of named queries (see QueryName) against
+	// Max, ie. to let an expensive report count as several operations.
+	// Others weigh 1.
+	Weights map[string]int64
+	// PerQuery bounds concurrent executions of named queries.
+	PerQuery map[string]int64
+}
+
+// WithConcurrencyLimits makes operations wait for capacity before running, so
+// a burst of one expensive query cannot take every pooled connection. Waiting
+// ends with the operation's context.
+func WithConcurrencyLimits(l ConcurrencyLimits) Option {
+	lim := &limiter{weights: l.Weights, perQuery: make(map[string]*semaphore.Weighted, len(l.PerQuery))}
+	if l.Max > 0 {
+		lim.max, lim.global = l.Max, semaphore.NewWeighted(l.Max)
+	}
+	for name, n := range l.PerQuery {
+		lim.perQuery[name] = semaphore.NewWeighted(n)
+	}
+	return func(d *Database) {
+		d.limits = lim
+	}
+}
+
+// acquireLimits waits for the concurrency limits and pool class of query,
+// returning nil when there are none.
+func (d *Database) acquireLimits(ctx context.Context, query string) (func(), error) {
+	var releaseClass func()
+	if d.tx == nil && d.conn == nil {
+		var err error
+		if releaseClass, err = d.acquireClass(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if d.limits == nil {
+		return releaseClass, nil
+	}
+	release, err := d.limits.acquire(ctx, QueryName(ctx, query))
+	if err != nil {
+		if releaseClass != nil {
+			releaseClass()
+		}
+		return nil, err
+	}
+	if releaseClass == nil {
+		return release, nil
+	}
+	return func() {
+		release()
+		releaseClass()
+	}, nil
+}
+
+type limiter struct {
+	max      int64
+	global   *semaphore.Weighted
+	weights  map[string]int64
+	perQuery map[string]*semaphore.Weighted
+}
+
+// acquire waits for capacity to run the named query.
+func (l *limiter) acquire(ctx context.Context, name string) (func(), error) {
+	var release []func()
+	releaseAll := func() {
+		for _, r := range release {
+			r()
+		}
+	}
+
+	if s, ok := l.perQuery[name]; ok {
+		if err := s.Acquire(ctx, 1); err != nil {
+			return nil, errors.Wrapf(err, "concurrency limit of %v", name)
+		}
+		release = append(release, func() { s.Release(1) })
+	}
+	if l.global != nil {
+		w, ok := l.weights[name]
+		if !ok || w <= 0 {
+			w = 1
+		}
+		if w > l.max {
+			w = l.max
+		}
+		if err := l.global.Acquire(ctx, w); err != nil {
+			releaseAll()
+			return nil, errors.Wrap(err, "concurrency limit")
+		}
+		release = append(release, func() { l.global.Release(w) })
+	}
+	return releaseAll, nil
+}
@@ -0,0 +1,56 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestConcurrencyLimits(t *testing.T) {
+	base := sqliteDB(t)
+	const report = "SELECT 2 AS limit_report;"
+	NameQuery("limit_report", report)
+	db := New(base.X, WithConcurrencyLimits(ConcurrencyLimits{
+		Max:      4,
+		Weights:  map[string]int64{"limit_report": 3},
+		PerQuery: map[string]int64{"limit_report": 1},
+	}))
+	defer db.Close()
+	ctx := context.Background()
+
+	// A running report holds 3 of 4 units, leaving one for other queries.
+	release, err := db.limits.acquire(ctx, "limit_report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+		t.Fatalf("expected capacity for a light query, got %v", err)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := db.Get(short, report, &n, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a second report to wait, got %v", err)
+	}
+	other, err := db.limits.acquire(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	short, cancel = context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := db.Get(short, "SELECT 1;", &n, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a light query to wait for capacity, got %v", err)
+	}
+
+	other()
+	release()
+	if err := db.Get(ctx, report, &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	if s := db.Stats(); s.InFlight != 0 {
+		t.Fatalf("expected no operations in flight, got %v", s.InFlight)
+	}
+}
@@ -0,0 +1,169 @@
+package sqln
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// Notification is a message received on a LISTEN channel.
+type Notification struct {
+	Channel string
+	Payload string
+	// PID of the notifying backend.
+	PID int
+}
+
+// ListenerOptions configures a Listener.
+type ListenerOptions struct {
+	// MinReconnect and MaxReconnect bound the exponential backoff used when
+	// the dedicated connection is lost. Default to 100ms and 10s.
+	MinReconnect time.Duration
+	MaxReconnect time.Duration
+
+	// Buffer is the capacity of each subscription channel. Notifications are
+	// dropped for subscribers whose buffer is full. Defaults to 64.
+	Buffer int
+
+	// OnEvent is called when the state of the dedicated connection changes.
+	// After a reconnect, notifications sent while disconnected may have been
+	// lost.
+	OnEvent func(pq.ListenerEventType, error)
+}
+
+// Listener manages a dedicated Postgres connection for LISTEN/NOTIFY and fans
+// notifications out to subscribers.
+type Listener struct {
+	l      *pq.Listener
+	buffer int
+
+	subsMtx sync.Mutex
+	subs    map[string][]chan Notification
+	closed  bool
+
+	done chan struct{}
+}
+
+// NewListener opens a dedicated connection to the database at dsn.
+func NewListener(dsn string, opts ListenerOptions) *Listener {
+	if opts.MinReconnect <= 0 {
+		opts.MinReconnect = 100 * time.Millisecond
+	}
+	if opts.MaxReconnect <= 0 {
+		opts.MaxReconnect = 10 * time.Second
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = 64
+	}
+
+	l := &Listener{
+		l:      pq.NewListener(dsn, opts.MinReconnect, opts.MaxReconnect, opts.OnEvent),
+		buffer: opts.Buffer,
+		subs:   make(map[string][]chan Notification),
+		done:   make(chan struct{}),
+	}
+	go l.dispatch()
+	return l
+}
+
+// Subscribe starts listening on channel (if not already) and returns a
+// channel of notifications. The returned channel is closed by Unsubscribe, or
+// when the Listener is closed.
+func (l *Listener) Subscribe(channel string) (<-chan Notification, error) {
+	c := make(chan Notification, l.buffer)
+
+	l.subsMtx.Lock()
+	if l.closed {
+		l.subsMtx.Unlock()
+		return nil, errors.New("listener closed")
+	}
+	first := len(l.subs[channel]) == 0
+	l.subs[channel] = append(l.subs[channel], c)
+	l.subsMtx.Unlock()
+
+	// Listen blocks until the connection is established, so it must not be
+	// called while holding subsMtx (dispatch needs it to drain notifications).
+	if first {
+		if err := l.l.Listen(channel); err != nil && err != pq.ErrChannelAlreadyOpen {
+			l.Unsubscribe(channel, c)
+			return nil, errors.Wrapf(err, "listen %q", channel)
+		}
+	}
+
+	return c, nil
+}
+
+// Unsubscribe removes a subscription returned by Subscribe and closes its
+// channel. The connection keeps listening on channel, which is cheap, so
+// that later subscriptions do not wait for a LISTEN.
+func (l *Listener) Unsubscribe(channel string, c <-chan Notification) {
+	l.subsMtx.Lock()
+	defer l.subsMtx.Unlock()
+
+	cs := l.subs[channel]
+	for i := range cs {
+		if cs[i] == c {
+			close(cs[i])
+			l.subs[channel] = append(cs[:i], cs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Close stops listening and closes all subscription channels.
+func (l *Listener) Close() error {
+	l.subsMtx.Lock()
+	if l.closed {
+		l.subsMtx.Unlock()
+		return nil
+	}
+	l.closed = true
+	l.subsMtx.Unlock()
+
+	err := l.l.Close()
+	<-l.done
+	return err
+}
+
+func (l *Listener) dispatch() {
+	defer close(l.done)
+	defer func() {
+		l.subsMtx.Lock()
+		defer l.subsMtx.Unlock()
+		for _, cs := range l.subs {
+			for _, c := range cs {
+				close(c)
+			}
+		}
+		l.subs = nil
+	}()
+
+	for n := range l.l.Notify {
+		// A nil notification is sent after a reconnect.
+		if n == nil {
+			continue
+		}
+
+		l.subsMtx.Lock()
+		for _, c := range l.subs[n.Channel] {
+			select {
+			case c <- Notification{Channel: n.Channel, Payload: n.Extra, PID: n.BePid}:
+			default:
+			}
+		}
+		l.subsMtx.Unlock()
+	}
+}
+
+// Notify sends a notification on channel. When db is transactional the
+// notification is delivered when the transaction commits.
+func Notify(ctx context.Context, db DB, channel, payload string) error {
+	_, err := db.Exec(ctx, "SELECT pg_notify(:channel, :payload);", map[string]interface{}{
+		"channel": channel,
+		"payload": payload,
+	})
+	return err
+}
@@ -0,0 +1,64 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nstogner/psqlxtest"
+)
+
+// testDSN mirrors the connection settings used by psqlxtest.
+func testDSN() string {
+	host := "localhost:5432"
+	if h, ok := os.LookupEnv("TEST_DB_HOST"); ok {
+		host = h
+	}
+	return "postgres://postgres:postgres@" + host + "/postgres?sslmode=disable"
+}
+
+func TestListener(t *testing.T) {
+	dbx, dropx := psqlxtest.TmpDB(t)
+	defer dropx()
+	d := New(dbx)
+	defer d.Close()
+
+	l := NewListener(testDSN(), ListenerOptions{})
+	defer l.Close()
+
+	c, err := l.Subscribe("sqln_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := d.Transact(ctx, sql.TxOptions{}, func(db DB) error {
+		return Notify(ctx, db, "sqln_test", "hello")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case n := <-c:
+		if n.Channel != "sqln_test" || n.Payload != "hello" {
+			t.Fatalf("unexpected notification: %+v", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for notification")
+	}
+
+	l.Unsubscribe("sqln_test", c)
+	if _, ok := <-c; ok {
+		t.Fatal("expected the channel to be closed")
+	}
+	l.subsMtx.Lock()
+	n := len(l.subs["sqln_test"])
+	l.subsMtx.Unlock()
+	if n != 0 {
+		t.Fatalf("expected no subscriptions, got %v", n)
+	}
+	// Unsubscribing twice is harmless.
+	l.Unsubscribe("sqln_test", c)
+}
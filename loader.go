@@ -0,0 +1,164 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// LoaderOptions configures a Loader.
+type LoaderOptions struct {
+	// Wait is how long the first Load of a batch waits for others to join
+	// it. Defaults to 1ms.
+	Wait time.Duration
+	// MaxBatch dispatches a batch early once it has as many ids. Defaults
+	// to 512.
+	MaxBatch int
+}
+
+// Loader coalesces concurrent loads by id into batches run with GetMany, and
+// caches the results, ie. to avoid N+1 queries in GraphQL resolvers. Since
+// results are never refreshed (other than with Clear), a Loader should be
+// created for each request.
+type Loader[K comparable, T any] struct {
+	db               DB
+	query, keyColumn string
+	opts             LoaderOptions
+
+	mtx   sync.Mutex
+	cache map[K]*loadResult[T]
+	batch *loadBatch[K, T]
+}
+
+type loadResult[T any] struct {
+	done chan struct{}
+	v    T
+	err  error
+}
+
+type loadBatch[K comparable, T any] struct {
+	ctx     context.Context
+	ids     []K
+	results []*loadResult[T]
+	timer   *time.Timer
+}
+
+// NewLoader returns a Loader that runs query ("IN (:ids)", see GetMany) on
+// db, keying rows by keyColumn.
+func NewLoader[K comparable, T any](db DB, query, keyColumn string, opts LoaderOptions) *Loader[K, T] {
+	if opts.Wait <= 0 {
+		opts.Wait = time.Millisecond
+	}
+	if opts.MaxBatch <= 0 {
+		opts.MaxBatch = maxInList
+	}
+	return &Loader[K, T]{db: db, query: query, keyColumn: keyColumn, opts: opts, cache: make(map[K]*loadResult[T])}
+}
+
+// Load returns the row with id, or sql.ErrNoRows if there is none.
+func (l *Loader[K, T]) Load(ctx context.Context, id K) (T, error) {
+	r := l.enqueue(ctx, id)
+	select {
+	case <-r.done:
+		return r.v, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// LoadMany returns the rows with ids, omitting those that do not exist.
+func (l *Loader[K, T]) LoadMany(ctx context.Context, ids []K) (map[K]T, error) {
+	results := make([]*loadResult[T], len(ids))
+	for i, id := range ids {
+		results[i] = l.enqueue(ctx, id)
+	}
+	m := make(map[K]T, len(ids))
+	for i, r := range results {
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		switch r.err {
+		case nil:
+			m[ids[i]] = r.v
+		case sql.ErrNoRows:
+		default:
+			return nil, r.err
+		}
+	}
+	return m, nil
+}
+
+// Clear removes id from the cache, ie. after updating the row.
+func (l *Loader[K, T]) Clear(id K) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if r, ok := l.cache[id]; ok {
+		select {
+		case <-r.done:
+			delete(l.cache, id)
+		default:
+			// Still loading; the result would be cached anyway.
+		}
+	}
+}
+
+// enqueue returns the cached result for id, adding id to the pending batch if
+// it was not loaded before.
+func (l *Loader[K, T]) enqueue(ctx context.Context, id K) *loadResult[T] {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if r, ok := l.cache[id]; ok {
+		return r
+	}
+	r := &loadResult[T]{done: make(chan struct{})}
+	l.cache[id] = r
+
+	b := l.batch
+	if b == nil {
+		// The batch outlives the caller that started it, since others
+		// wait on it.
+		b = &loadBatch[K, T]{ctx: context.WithoutCancel(ctx)}
+		b.timer = time.AfterFunc(l.opts.Wait, func() { l.dispatch(b) })
+		l.batch = b
+	}
+	b.ids = append(b.ids, id)
+	b.results = append(b.results, r)
+	if len(b.ids) >= l.opts.MaxBatch {
+		b.timer.Stop()
+		l.batch = nil
+		go l.run(b)
+	}
+	return r
+}
+
+func (l *Loader[K, T]) dispatch(b *loadBatch[K, T]) {
+	l.mtx.Lock()
+	if l.batch != b {
+		// Already dispatched on reaching MaxBatch.
+		l.mtx.Unlock()
+		return
+	}
+	l.batch = nil
+	l.mtx.Unlock()
+	l.run(b)
+}
+
+func (l *Loader[K, T]) run(b *loadBatch[K, T]) {
+	found, _, err := GetMany[K, T](b.ctx, l.db, l.query, l.keyColumn, b.ids)
+	for i, id := range b.ids {
+		r := b.results[i]
+		switch v, ok := found[id]; {
+		case err != nil:
+			r.err = err
+		case ok:
+			r.v = v
+		default:
+			r.err = sql.ErrNoRows
+		}
+		close(r.done)
+	}
+}
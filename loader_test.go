@@ -0,0 +1,76 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingSelectDB counts Selects.
+type countingSelectDB struct {
+	DB
+	mtx     sync.Mutex
+	selects int
+}
+
+func (d *countingSelectDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	d.mtx.Lock()
+	d.selects++
+	d.mtx.Unlock()
+	return d.DB.Select(ctx, query, dest, params)
+}
+
+func TestLoader(t *testing.T) {
+	base := sqliteDB(t)
+	ctx := context.Background()
+	for _, q := range []string{
+		"CREATE TABLE loader (id INTEGER PRIMARY KEY, name TEXT);",
+		"INSERT INTO loader VALUES (1, 'a'), (2, 'b'), (3, 'c');",
+	} {
+		if _, err := base.Exec(ctx, q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db := &countingSelectDB{DB: base}
+	l := NewLoader[int64, manyRow](db, "SELECT id, name FROM loader WHERE id IN (:ids);", "id", LoaderOptions{
+		// Only a full batch is dispatched, so the loads below are batched
+		// however the goroutines are scheduled.
+		Wait:     time.Hour,
+		MaxBatch: 4,
+	})
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i, id := range []int64{1, 2, 3, 4} {
+		wg.Add(1)
+		go func(i int, id int64) {
+			defer wg.Done()
+			_, errs[i] = l.Load(ctx, id)
+		}(i, id)
+	}
+	wg.Wait()
+	if db.selects != 1 {
+		t.Fatalf("expected loads to be batched, got %v selects", db.selects)
+	}
+	if errs[0] != nil || errs[3] != sql.ErrNoRows {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	selects := db.selects
+	m, err := l.LoadMany(ctx, []int64{1, 2, 4})
+	if err != nil || len(m) != 2 || m[2].Name != "b" {
+		t.Fatalf("unexpected rows: %v, %v", m, err)
+	}
+	if db.selects != selects {
+		t.Fatal("expected cached results to be reused")
+	}
+
+	// A single reload would otherwise wait for the batch to fill.
+	l.opts.Wait = time.Millisecond
+	l.Clear(1)
+	if v, err := l.Load(ctx, 1); err != nil || v.Name != "a" || db.selects != selects+1 {
+		t.Fatalf("expected a cleared id to be reloaded, got %v, %v", v, err)
+	}
+}
@@ -0,0 +1,116 @@
+package sqln
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LockDiagnostics configures the diagnosis of lock timeouts and deadlocks.
+type LockDiagnostics struct {
+	// Query lists the current lock waits as LockWait columns. Defaults to
+	// a query over pg_stat_activity for Postgres and sys.innodb_lock_waits
+	// for MySQL; SQLite has no equivalent.
+	Query string
+	// Timeout bounds the diagnostic query. Defaults to one second.
+	Timeout time.Duration
+	// OnLock receives every report, ie. to log it.
+	OnLock func(LockReport)
+}
+
+// LockWait is a session blocked by another.
+type LockWait struct {
+	PID           int64   `db:"pid"`
+	Query         string  `db:"query"`
+	BlockingPID   int64   `db:"blocking_pid"`
+	BlockingQuery string  `db:"blocking_query"`
+	WaitSeconds   float64 `db:"wait_seconds"`
+}
+
+// LockReport describes the lock waits at the time a query failed with a lock
+// timeout or deadlock. By the time a deadlock is reported the database has
+// already aborted one of its sessions, so Waits may not include it.
+type LockReport struct {
+	Query string
+	// Name is the query name, if any (see QueryName).
+	Name  string
+	Class ErrorClass
+	Waits []LockWait
+	// Err is set when the diagnostic query failed.
+	Err error
+}
+
+// LockError is returned in place of lock timeout and deadlock errors when
+// WithLockDiagnostics is used. Classify sees through it to the driver error.
+type LockError struct {
+	Err    error
+	Report LockReport
+}
+
+func (e *LockError) Error() string {
+	if len(e.Report.Waits) == 0 {
+		return e.Err.Error()
+	}
+	waits := make([]string, len(e.Report.Waits))
+	for i, w := range e.Report.Waits {
+		waits[i] = fmt.Sprintf("pid %v blocked by pid %v (%q) for %.1fs", w.PID, w.BlockingPID, w.BlockingQuery, w.WaitSeconds)
+	}
+	return fmt.Sprintf("%v: lock waits: %v", e.Err, strings.Join(waits, "; "))
+}
+
+func (e *LockError) Unwrap() error { return e.Err }
+
+// WithLockDiagnostics lists the sessions holding up a query that fails with
+// ClassLockTimeout or ClassDeadlock, attaching them to the returned error as
+// a *LockError and passing them to OnLock. The diagnostic query runs on
+// the pool, outside of any transaction the query ran in.
+func WithLockDiagnostics(l LockDiagnostics) Option {
+	if l.Timeout <= 0 {
+		l.Timeout = time.Second
+	}
+	return func(d *Database) {
+		if l.Query == "" {
+			l.Query = lockWaitsQuery(d.dialect)
+		}
+		d.locks = &l
+	}
+}
+
+func lockWaitsQuery(dialect Dialect) string {
+	switch dialect {
+	case Postgres:
+		return `SELECT a.pid, a.query, b.pid AS blocking_pid, b.query AS blocking_query,
+	COALESCE(EXTRACT(EPOCH FROM now() - a.query_start), 0)::float8 AS wait_seconds
+FROM pg_stat_activity a
+CROSS JOIN LATERAL unnest(pg_blocking_pids(a.pid)) AS blocking(pid)
+JOIN pg_stat_activity b ON b.pid = blocking.pid;`
+	case MySQL:
+		return `SELECT waiting_pid AS pid, COALESCE(waiting_query, '') AS query,
+	blocking_pid, COALESCE(blocking_query, '') AS blocking_query, wait_age_secs AS wait_seconds
+FROM sys.innodb_lock_waits;`
+	}
+	return ""
+}
+
+// diagnoseLocks wraps lock errors of query in a *LockError.
+func (d *Database) diagnoseLocks(ctx context.Context, query string, err error) error {
+	if d.locks == nil || err == nil {
+		return err
+	}
+	class := Classify(err)
+	if class != ClassLockTimeout && class != ClassDeadlock {
+		return err
+	}
+
+	r := LockReport{Query: query, Name: QueryName(ctx, query), Class: class}
+	if d.locks.Query != "" {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.locks.Timeout)
+		defer cancel()
+		r.Err = d.drv.SelectContext(ctx, &r.Waits, d.locks.Query)
+	}
+	if d.locks.OnLock != nil {
+		d.locks.OnLock(r)
+	}
+	return &LockError{Err: err, Report: r}
+}
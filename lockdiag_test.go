@@ -0,0 +1,63 @@
+package sqln
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+func TestLockDiagnostics(t *testing.T) {
+	dbx, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_busy_timeout=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbx.Close()
+	ctx := context.Background()
+
+	var reports []LockReport
+	db := New(dbx, WithLockDiagnostics(LockDiagnostics{
+		Query:  "SELECT 1 AS pid, 'INSERT' AS query, 2 AS blocking_pid, 'BEGIN IMMEDIATE' AS blocking_query, 0.5 AS wait_seconds;",
+		OnLock: func(r LockReport) { reports = append(reports, r) },
+	}))
+	defer db.Close()
+
+	if _, err := db.Exec(ctx, "CREATE TABLE locked (id INTEGER);", nil); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := dbx.Connx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE;"); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.ExecContext(ctx, "ROLLBACK;")
+
+	_, err = db.Exec(ctx, "INSERT INTO locked (id) VALUES (1);", nil)
+	var lerr *LockError
+	if !errors.As(err, &lerr) {
+		t.Fatalf("expected lock error, got %v", err)
+	}
+	if Classify(err) != ClassLockTimeout {
+		t.Fatalf("expected lock timeout class, got %v", Classify(err))
+	}
+	if len(lerr.Report.Waits) != 1 || lerr.Report.Waits[0].BlockingPID != 2 || lerr.Report.Err != nil {
+		t.Fatalf("unexpected report: %+v", lerr.Report)
+	}
+	if len(reports) != 1 || reports[0].Query != "INSERT INTO locked (id) VALUES (1);" {
+		t.Fatalf("unexpected reports: %+v", reports)
+	}
+
+	// Other errors are returned unchanged.
+	_, err = db.Exec(ctx, "INSERT INTO lock_missing (id) VALUES (1);", nil)
+	if err == nil || errors.As(err, new(*LockError)) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("expected no report, got %v", len(reports))
+	}
+}
@@ -0,0 +1,130 @@
+package sqln
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Manager holds named Databases (ie. "billing", "analytics") that share pool
+// configuration and a lifecycle.
+type Manager struct {
+	limits PoolLimits
+
+	mtx sync.RWMutex
+	dbs map[string]*Database
+}
+
+// NewManager returns a Manager that applies limits to every pool added to it.
+func NewManager(limits PoolLimits) *Manager {
+	return &Manager{
+		limits: limits,
+		dbs:    make(map[string]*Database),
+	}
+}
+
+// Add wraps dbx and registers it under name. The Manager takes ownership of
+// dbx and closes it in Close.
+func (m *Manager) Add(name string, dbx *sqlx.DB) (*Database, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if _, ok := m.dbs[name]; ok {
+		return nil, errors.Errorf("database %q already registered", name)
+	}
+	m.limits.apply(dbx)
+	d := New(dbx)
+	m.dbs[name] = d
+	return d, nil
+}
+
+// Open connects to a database and registers it under name.
+func (m *Manager) Open(name, driverName, dsn string) (*Database, error) {
+	dbx, err := sqlx.Open(driverName, dsn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "database %q: open", name)
+	}
+	d, err := m.Add(name, dbx)
+	if err != nil {
+		dbx.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// Get returns the Database registered under name.
+func (m *Manager) Get(name string) (*Database, bool) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	d, ok := m.dbs[name]
+	return d, ok
+}
+
+// Names returns the registered names in sorted order.
+func (m *Manager) Names() []string {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	names := make([]string, 0, len(m.dbs))
+	for name := range m.dbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Health pings every database concurrently and returns the errors keyed by
+// name. A nil map means all databases are healthy. Slow pings do not block
+// Add, Get or Close.
+func (m *Manager) Health(ctx context.Context) map[string]error {
+	m.mtx.RLock()
+	dbs := make(map[string]*Database, len(m.dbs))
+	for name, d := range m.dbs {
+		dbs[name] = d
+	}
+	m.mtx.RUnlock()
+
+	var (
+		wg      sync.WaitGroup
+		errsMtx sync.Mutex
+		errs    map[string]error
+	)
+	for name, d := range dbs {
+		wg.Add(1)
+		go func(name string, d *Database) {
+			defer wg.Done()
+			if err := d.drv.PingContext(ctx); err != nil {
+				errsMtx.Lock()
+				if errs == nil {
+					errs = make(map[string]error)
+				}
+				errs[name] = err
+				errsMtx.Unlock()
+			}
+		}(name, d)
+	}
+	wg.Wait()
+	return errs
+}
+
+// Close closes the statements and pools of all databases, returning the first
+// error.
+func (m *Manager) Close() error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	var first error
+	for name, d := range m.dbs {
+		if err := d.Close(); err != nil && first == nil {
+			first = errors.Wrapf(err, "database %q", name)
+		}
+		if err := d.drv.Close(); err != nil && first == nil {
+			first = errors.Wrapf(err, "database %q", name)
+		}
+		delete(m.dbs, name)
+	}
+	return first
+}
@@ -0,0 +1,92 @@
+package sqln
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestManager(t *testing.T) {
+	m := NewManager(PoolLimits{MaxOpenConns: 3})
+	defer m.Close()
+
+	dir := t.TempDir()
+	for _, name := range []string{"billing", "analytics"} {
+		if _, err := m.Open(name, "sqlite3", filepath.Join(dir, name+".db")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := m.Open("billing", "sqlite3", filepath.Join(dir, "other.db")); err == nil {
+		t.Fatal("expected duplicate name error")
+	}
+
+	if names := m.Names(); len(names) != 2 || names[0] != "analytics" {
+		t.Fatalf("unexpected names: %v", names)
+	}
+
+	billing, ok := m.Get("billing")
+	if !ok {
+		t.Fatal("expected billing database")
+	}
+	if n := billing.X.Stats().MaxOpenConnections; n != 3 {
+		t.Fatalf("expected shared limit of 3, got %v", n)
+	}
+
+	if errs := m.Health(context.Background()); errs != nil {
+		t.Fatalf("unexpected health errors: %v", errs)
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Get("billing"); ok {
+		t.Fatal("expected no databases after close")
+	}
+}
+
+// blockingPingDriver blocks pings until release is closed.
+type blockingPingDriver struct {
+	*sqlx.DB
+	pinged, release chan struct{}
+}
+
+func (d *blockingPingDriver) PingContext(ctx context.Context) error {
+	close(d.pinged)
+	<-d.release
+	return nil
+}
+
+func TestManagerHealthUnlocked(t *testing.T) {
+	m := NewManager(PoolLimits{})
+	defer m.Close()
+
+	drv := &blockingPingDriver{DB: sqliteDB(t).X, pinged: make(chan struct{}), release: make(chan struct{})}
+	m.dbs["slow"] = newBackend(drv)
+
+	health := make(chan map[string]error)
+	go func() { health <- m.Health(context.Background()) }()
+	<-drv.pinged
+
+	// Databases can be added while a ping is in progress.
+	added := make(chan error)
+	go func() {
+		_, err := m.Open("other", "sqlite3", filepath.Join(t.TempDir(), "other.db"))
+		added <- err
+	}()
+	select {
+	case err := <-added:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Add not to wait for the ping")
+	}
+
+	close(drv.release)
+	if errs := <-health; errs != nil {
+		t.Fatalf("unexpected health errors: %v", errs)
+	}
+}
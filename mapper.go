@@ -0,0 +1,99 @@
+package sqln
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// WithMapper maps columns and named params to struct fields by the tag
+// struct tag, and untagged fields by f applied to the field name (ie.
+// SnakeCase), instead of the db tag and lowercased names. Statements prepared
+// by the Database and the struct helpers run on it (InsertStruct, SelectMap,
+// Paginate...) use it.
+//
+// The *sqlx.DB is copied with the mapper set (see sqlx.DB.MapperFunc), so X
+// is not the *sqlx.DB passed to New. Repo and checksums always use the db
+// tag.
+func WithMapper(tag string, f func(string) string) Option {
+	return func(d *Database) {
+		d.mapper = reflectx.NewMapperFunc(tag, f)
+		d.mapperTag = tag
+		if d.X != nil {
+			x := *d.X
+			x.Mapper = d.mapper
+			d.X, d.drv = &x, &x
+			d.cache.prepare = x.PrepareNamedContext
+			d.cache.preparex = x.PreparexContext
+		}
+	}
+}
+
+// Mapper returns the mapper of columns to struct fields (see WithMapper).
+func (d *Database) Mapper() *reflectx.Mapper {
+	if d.mapper == nil {
+		return defaultMapper
+	}
+	return d.mapper
+}
+
+// mapperOf returns the mapper and struct tag of the Database underlying db,
+// or the defaults.
+func mapperOf(db DB) (*reflectx.Mapper, string) {
+	for db != nil {
+		if d, ok := db.(*Database); ok {
+			if d.mapper == nil {
+				break
+			}
+			return d.mapper, d.mapperTag
+		}
+		u, ok := db.(Unwrapper)
+		if !ok {
+			break
+		}
+		db = u.Unwrap()
+	}
+	return defaultMapper, "db"
+}
+
+// SnakeCase maps a field name to snake case, ie. "UserID" to "user_id", for
+// WithMapper.
+func SnakeCase(name string) string {
+	ws := words(name)
+	for i, w := range ws {
+		ws[i] = strings.ToLower(w)
+	}
+	return strings.Join(ws, "_")
+}
+
+// CamelCase maps a field name to camel case, ie. "UserID" to "userId", for
+// WithMapper.
+func CamelCase(name string) string {
+	ws := words(name)
+	for i, w := range ws {
+		w = strings.ToLower(w)
+		if i > 0 && w != "" {
+			r := []rune(w)
+			r[0] = unicode.ToUpper(r[0])
+			w = string(r)
+		}
+		ws[i] = w
+	}
+	return strings.Join(ws, "")
+}
+
+// words splits a field name at case changes, ie. "HTTPServer" into "HTTP" and
+// "Server".
+func words(name string) []string {
+	runes := []rune(name)
+	var ws []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		if unicode.IsUpper(runes[i]) && (!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			ws = append(ws, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(ws, string(runes[start:]))
+}
@@ -0,0 +1,68 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestWithMapper(t *testing.T) {
+	base := sqliteDB(t)
+	db := New(base.X, WithMapper("json", SnakeCase))
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, "CREATE TABLE mapped (user_id INTEGER, full_name TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+	type user struct {
+		UserID int
+		Name   string `json:"full_name"`
+	}
+	if _, err := InsertStruct(ctx, db, "mapped", user{UserID: 1, Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, "INSERT INTO mapped (user_id, full_name) VALUES (:user_id, :full_name);", user{UserID: 2, Name: "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var u user
+	if err := db.Get(ctx, "SELECT * FROM mapped WHERE user_id = :user_id;", &u, map[string]interface{}{"user_id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if u != (user{UserID: 1, Name: "a"}) {
+		t.Fatalf("unexpected user: %+v", u)
+	}
+	if err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		users, err := SelectMap[int, user](ctx, tx, "SELECT * FROM mapped;", "user_id", nil)
+		if err != nil {
+			return err
+		}
+		if len(users) != 2 || users[2].Name != "b" {
+			t.Errorf("unexpected users: %+v", users)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if base.X.Mapper == db.X.Mapper {
+		t.Fatal("expected the sqlx.DB passed to New to keep its mapper")
+	}
+}
+
+func TestNameMappers(t *testing.T) {
+	for _, c := range []struct{ in, snake, camel string }{
+		{"ID", "id", "id"},
+		{"UserID", "user_id", "userId"},
+		{"HTTPServer", "http_server", "httpServer"},
+		{"CreatedAt", "created_at", "createdAt"},
+		{"Address2", "address2", "address2"},
+	} {
+		if got := SnakeCase(c.in); got != c.snake {
+			t.Errorf("SnakeCase(%q) = %q, expected %q", c.in, got, c.snake)
+		}
+		if got := CamelCase(c.in); got != c.camel {
+			t.Errorf("CamelCase(%q) = %q, expected %q", c.in, got, c.camel)
+		}
+	}
+}
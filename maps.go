@@ -0,0 +1,73 @@
+package sqln
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var mapType = reflect.TypeOf(map[string]interface{}(nil))
+
+// SelectMaps selects rows as maps of column names to values, for ad-hoc
+// queries where defining a struct is overkill. Values are as returned by the
+// driver, ie. text may be []byte.
+// NOTE: db must support map destinations, as *Database does.
+func SelectMaps(ctx context.Context, db DB, query string, params interface{}) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	if err := db.Select(ctx, query, &rows, params); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// GetMap gets a single row as a map of column names to values, see
+// SelectMaps.
+func GetMap(ctx context.Context, db DB, query string, params interface{}) (map[string]interface{}, error) {
+	var row map[string]interface{}
+	if err := db.Get(ctx, query, &row, params); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// isMapDest reports whether dest is a *map[string]interface{} or a pointer to
+// a slice of them.
+func isMapDest(dest interface{}) bool {
+	t := reflect.TypeOf(dest)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return false
+	}
+	t = t.Elem()
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t == mapType
+}
+
+// scanMaps appends rows to the slice of maps s points to.
+func scanMaps(rows *sqlx.Rows, s reflect.Value) error {
+	slice := s.Elem()
+	for rows.Next() {
+		m := make(map[string]interface{})
+		if err := rows.MapScan(m); err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, reflect.ValueOf(m))
+	}
+	s.Elem().Set(slice)
+	return rows.Err()
+}
+
+// queryMaps runs an unprepared query into map destinations.
+func (d *Database) queryMaps(ctx context.Context, query string, args []interface{}, dest interface{}, one bool) error {
+	rows, err := d.ext().QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if one {
+		return scanOne(rows, dest, d.strict.GetOne)
+	}
+	return scanAll(rows, dest)
+}
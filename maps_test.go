@@ -0,0 +1,48 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestSelectMaps(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, "CREATE TABLE things (id INTEGER, name TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, "INSERT INTO things (id, name) VALUES (1, 'a'), (2, 'b');", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := SelectMaps(ctx, db, "SELECT id, name FROM things ORDER BY id;", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []map[string]interface{}{{"id": int64(1), "name": "a"}, {"id": int64(2), "name": "b"}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("unexpected rows: %v", rows)
+	}
+	if s := db.Stats(); s.Statements != 3 {
+		t.Fatalf("expected the query to be prepared, got %v statements", s.Statements)
+	}
+
+	row, err := GetMap(ctx, db, "SELECT name FROM things WHERE id = :id;", map[string]interface{}{"id": 2})
+	if err != nil || !reflect.DeepEqual(row, map[string]interface{}{"name": "b"}) {
+		t.Fatalf("unexpected row: %v %v", row, err)
+	}
+	if _, err := GetMap(ctx, db, "SELECT name FROM things WHERE id = :id;", map[string]interface{}{"id": 3}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected no rows, got %v", err)
+	}
+
+	// Unprepared queries support maps too.
+	rows, err = SelectMaps(ctx, New(db.X, WithUnprepared(func(string) bool { return true })), "SELECT id FROM things;", nil)
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("unexpected unprepared rows: %v %v", rows, err)
+	}
+}
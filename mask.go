@@ -0,0 +1,159 @@
+package sqln
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// WithRole returns a context carrying the caller's role, used by masking
+// policies.
+func WithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, roleKey, role)
+}
+
+// RoleFromContext returns the role set by WithRole.
+func RoleFromContext(ctx context.Context) (string, bool) {
+	r, ok := ctx.Value(roleKey).(string)
+	return r, ok
+}
+
+// Mask describes how a single column is masked.
+type Mask struct {
+	// Allow lists the roles that see unmasked values.
+	Allow []string
+	// Transform returns the masked value. When nil, or when the returned
+	// value is not assignable to the destination field, the field is set
+	// to its zero value.
+	Transform func(v interface{}) interface{}
+}
+
+func (m Mask) allowed(role string, ok bool) bool {
+	if !ok {
+		return false
+	}
+	for _, a := range m.Allow {
+		if a == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Masker holds per-query column masking policies.
+type Masker struct {
+	mapper *reflectx.Mapper
+
+	mtx      sync.RWMutex
+	policies map[string]map[string]Mask
+}
+
+// NewMasker returns an empty Masker.
+func NewMasker() *Masker {
+	return &Masker{
+		mapper:   reflectx.NewMapperFunc("db", sqlx.NameMapper),
+		policies: make(map[string]map[string]Mask),
+	}
+}
+
+// Register declares masked columns (keyed by column name) for query.
+func (m *Masker) Register(query string, columns map[string]Mask) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.policies[query] = columns
+}
+
+// Middleware returns a middleware that masks the results of Get and Select
+// after scanning. Only struct destinations (and slices of them) are masked.
+func (m *Masker) Middleware() Middleware {
+	return m.wrap
+}
+
+func (m *Masker) wrap(db DB) DB {
+	return &maskDB{BaseDB: BaseDB{DB: db, Wrap: m.wrap}, m: m}
+}
+
+func (m *Masker) apply(ctx context.Context, query string, dest interface{}) {
+	m.mtx.RLock()
+	cols, ok := m.policies[query]
+	m.mtx.RUnlock()
+	if !ok {
+		return
+	}
+
+	role, hasRole := RoleFromContext(ctx)
+	masked := make(map[string]Mask, len(cols))
+	for col, mask := range cols {
+		if !mask.allowed(role, hasRole) {
+			masked[col] = mask
+		}
+	}
+	if len(masked) == 0 {
+		return
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(dest))
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			m.maskStruct(reflect.Indirect(v.Index(i)), masked)
+		}
+		return
+	}
+	m.maskStruct(v, masked)
+}
+
+func (m *Masker) maskStruct(v reflect.Value, masked map[string]Mask) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	fields := m.mapper.FieldMap(v)
+	for col, mask := range masked {
+		f, ok := fields[col]
+		if !ok || !f.CanSet() {
+			continue
+		}
+
+		var nv reflect.Value
+		if mask.Transform != nil {
+			if r := mask.Transform(f.Interface()); r != nil {
+				nv = reflect.ValueOf(r)
+			}
+		}
+		if !nv.IsValid() || !nv.Type().AssignableTo(f.Type()) {
+			nv = reflect.Zero(f.Type())
+		}
+		f.Set(nv)
+	}
+}
+
+type maskDB struct {
+	BaseDB
+	m *Masker
+}
+
+func (d *maskDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.DB.Get(ctx, query, dest, params); err != nil {
+		return err
+	}
+	d.m.apply(ctx, query, dest)
+	return nil
+}
+
+func (d *maskDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.DB.Select(ctx, query, dest, params); err != nil {
+		return err
+	}
+	d.m.apply(ctx, query, dest)
+	return nil
+}
+
+func (d *maskDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.DB.ExecReturning(ctx, query, dest, params); err != nil {
+		return err
+	}
+	d.m.apply(ctx, query, dest)
+	return nil
+}
@@ -0,0 +1,66 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestMasker(t *testing.T) {
+	d := sqliteDB(t)
+	if _, err := d.X.Exec("CREATE TABLE mask_users (id INT PRIMARY KEY, email TEXT, ssn TEXT); INSERT INTO mask_users VALUES (1, 'a@b.c', '123-45-6789');"); err != nil {
+		t.Fatal("unable to create table:", err)
+	}
+
+	const query = "SELECT id, email, ssn FROM mask_users;"
+	m := NewMasker()
+	m.Register(query, map[string]Mask{
+		"ssn": {Allow: []string{"admin"}, Transform: func(v interface{}) interface{} {
+			s := v.(string)
+			return strings.Repeat("*", len(s)-4) + s[len(s)-4:]
+		}},
+		"email": {Allow: []string{"admin", "support"}},
+	})
+	db := Wrap(d, m.Middleware())
+
+	type user struct {
+		ID    int    `db:"id"`
+		Email string `db:"email"`
+		SSN   string `db:"ssn"`
+	}
+
+	ctx := context.Background()
+	cases := []struct {
+		ctx   context.Context
+		email string
+		ssn   string
+	}{
+		{ctx, "", "*******6789"},
+		{WithRole(ctx, "support"), "a@b.c", "*******6789"},
+		{WithRole(ctx, "admin"), "a@b.c", "123-45-6789"},
+	}
+	for _, c := range cases {
+		var users []user
+		if err := db.Select(c.ctx, query, &users, nil); err != nil {
+			t.Fatal(err)
+		}
+		if users[0].Email != c.email || users[0].SSN != c.ssn {
+			t.Errorf("expected (%q, %q), got %+v", c.email, c.ssn, users[0])
+		}
+	}
+
+	err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		var u user
+		if err := tx.Get(ctx, query, &u, nil); err != nil {
+			return err
+		}
+		if u.Email != "" {
+			t.Errorf("expected email to be masked inside transaction, got %q", u.Email)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
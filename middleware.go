@@ -0,0 +1,64 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Middleware decorates a DB. Implementations should also wrap the DB passed to
+// Transact callbacks so the decoration applies inside transactions, and
+// implement Unwrapper so InTx and TxLevel see through them. Embedding BaseDB
+// does both.
+type Middleware func(DB) DB
+
+// BaseDB forwards every DB method to DB, for decorators to embed so that
+// they only implement the methods they change. The DB of a transaction is
+// decorated with Wrap, usually the decorator's own constructor, so the
+// decoration applies inside Transact; without Wrap the callback receives the
+// undecorated DB. ie.
+//
+//	func newLogDB(db sqln.DB) sqln.DB {
+//		return &logDB{BaseDB: sqln.BaseDB{DB: db, Wrap: newLogDB}}
+//	}
+type BaseDB struct {
+	DB
+	Wrap func(DB) DB
+}
+
+// Unwrap returns the decorated DB.
+func (b BaseDB) Unwrap() DB { return b.DB }
+
+// Transact runs f in a transaction of the decorated DB, passing it the
+// transaction's DB decorated with Wrap.
+func (b BaseDB) Transact(ctx context.Context, opts sql.TxOptions, f func(DB) error) error {
+	return b.DB.Transact(ctx, opts, func(tx DB) error {
+		if b.Wrap != nil {
+			tx = b.Wrap(tx)
+		}
+		return f(tx)
+	})
+}
+
+// Wrap applies middlewares to db. The first middleware is the outermost.
+func Wrap(db DB, mws ...Middleware) DB {
+	for i := len(mws) - 1; i >= 0; i-- {
+		db = mws[i](db)
+	}
+	return db
+}
+
+// WithTxMiddleware returns a context whose transactions (see Transact) pass
+// their closure the transaction's DB wrapped with mws, within any middleware
+// from an earlier call, ie. to keep logging or tenant middleware that does not
+// re-wrap transactions active inside them.
+func WithTxMiddleware(ctx context.Context, mws ...Middleware) context.Context {
+	prev := txMiddleware(ctx)
+	all := make([]Middleware, 0, len(prev)+len(mws))
+	all = append(append(all, prev...), mws...)
+	return context.WithValue(ctx, txMiddlewareKey, all)
+}
+
+func txMiddleware(ctx context.Context) []Middleware {
+	mws, _ := ctx.Value(txMiddlewareKey).([]Middleware)
+	return mws
+}
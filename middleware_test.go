@@ -0,0 +1,99 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+type countDB struct {
+	BaseDB
+	n *int
+}
+
+func newCountDB(n *int) func(DB) DB {
+	var wrap func(DB) DB
+	wrap = func(db DB) DB {
+		return &countDB{BaseDB: BaseDB{DB: db, Wrap: wrap}, n: n}
+	}
+	return wrap
+}
+
+func (d *countDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	*d.n++
+	return d.DB.Exec(ctx, query, params)
+}
+
+func TestBaseDB(t *testing.T) {
+	d := sqliteDB(t)
+	var n int
+	db := Wrap(d, newCountDB(&n))
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE base_things (id INT PRIMARY KEY);", nil); err != nil {
+		t.Fatal(err)
+	}
+	err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		if _, ok := tx.(*countDB); !ok {
+			t.Errorf("expected transaction DB to be wrapped, got %T", tx)
+		}
+		if !InTx(tx) {
+			t.Error("expected InTx to see through the wrapper")
+		}
+		_, err := tx.Exec(ctx, "INSERT INTO base_things VALUES (1);", nil)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 counted execs, got %v", n)
+	}
+	if db.(Unwrapper).Unwrap() != d {
+		t.Error("expected Unwrap to return the wrapped DB")
+	}
+
+	// Without Wrap the transaction DB is not decorated.
+	plain := &BaseDB{DB: d}
+	err = plain.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		if _, ok := tx.(*Database); !ok {
+			t.Errorf("expected undecorated transaction DB, got %T", tx)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithTxMiddleware(t *testing.T) {
+	d := sqliteDB(t)
+	var outer, inner int
+	// No BaseDB.Wrap, so only WithTxMiddleware decorates the transaction.
+	mw := func(n *int) Middleware {
+		return func(db DB) DB { return &countDB{BaseDB: BaseDB{DB: db}, n: n} }
+	}
+
+	ctx := context.Background()
+	if _, err := d.Exec(ctx, "CREATE TABLE txmw_things (id INT PRIMARY KEY);", nil); err != nil {
+		t.Fatal(err)
+	}
+	ctx = WithTxMiddleware(WithTxMiddleware(ctx, mw(&outer)), mw(&inner))
+	err := d.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		c, ok := tx.(*countDB)
+		if !ok || c.n != &outer {
+			t.Errorf("expected outermost middleware from first call, got %T", tx)
+		}
+		if !InTx(tx) {
+			t.Error("expected InTx to see through the middleware")
+		}
+		_, err := tx.Exec(ctx, "INSERT INTO txmw_things VALUES (1);", nil)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outer != 1 || inner != 1 {
+		t.Errorf("expected each middleware to count 1 exec, got %v and %v", outer, inner)
+	}
+}
@@ -0,0 +1,320 @@
+/*
+Package migrations applies versioned .sql migrations through a sqln.DB, so
+applications do not need a separate migration tool and connection.
+
+Migration files are named <version>_<name>.up.sql and (optionally)
+<version>_<name>.down.sql, ie. 0001_create_users.up.sql. A file without the
+.up or .down suffix is an up migration. Applied versions are tracked in a
+schema_migrations table.
+*/
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+)
+
+// Migration is a single schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load reads migrations from the .sql files in fsys (use fs.Sub for a
+// subdirectory of an embed.FS), sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, file := range files {
+		base := strings.TrimSuffix(file, ".sql")
+		direction := "up"
+		switch path.Ext(base) {
+		case ".up":
+			base = strings.TrimSuffix(base, ".up")
+		case ".down":
+			base, direction = strings.TrimSuffix(base, ".down"), "down"
+		}
+
+		parts := strings.SplitN(base, "_", 2)
+		version, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, errors.Errorf("%v: file name must start with a version", file)
+		}
+		var name string
+		if len(parts) == 2 {
+			name = parts[1]
+		}
+
+		b, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read %v", file)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, errors.Errorf("%v: version %v is also named %q", file, version, m.Name)
+		}
+		if direction == "up" {
+			if m.Up != "" {
+				return nil, errors.Errorf("%v: duplicate up migration for version %v", file, version)
+			}
+			m.Up = string(b)
+		} else {
+			if m.Down != "" {
+				return nil, errors.Errorf("%v: duplicate down migration for version %v", file, version)
+			}
+			m.Down = string(b)
+		}
+	}
+
+	ms := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, errors.Errorf("version %v has no up migration", m.Version)
+		}
+		ms = append(ms, *m)
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+	return ms, nil
+}
+
+// Migrator applies migrations. Each migration runs in its own transaction
+// which, on Postgres, holds an advisory lock so concurrent migrators (ie. when
+// several replicas boot at once) apply each migration exactly once.
+type Migrator struct {
+	Migrations []Migration
+	// Table tracks applied versions. Defaults to "schema_migrations".
+	Table string
+}
+
+// New loads migrations from fsys (see Load).
+func New(fsys fs.FS) (*Migrator, error) {
+	ms, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{Migrations: ms}, nil
+}
+
+func (m *Migrator) table() string {
+	if m.Table == "" {
+		return "schema_migrations"
+	}
+	return m.Table
+}
+
+// lockID is derived from the table name so migrators for different tables do
+// not block each other.
+func (m *Migrator) lockID() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("sqln/migrations:" + m.table()))
+	return int64(h.Sum64())
+}
+
+// Up applies all pending migrations in version order. It matches the
+// signature of sqln.Startup.Migrate. Cached statements are invalidated (see
+// sqln.InvalidateAll) when any migration is applied. Migrations run in
+// migration mode (see sqln.WithMigrationMode).
+func (m *Migrator) Up(ctx context.Context, db sqln.DB) error {
+	ctx = sqln.WithMigrationMode(ctx)
+	if err := m.ensureTable(ctx, db); err != nil {
+		return err
+	}
+	var ran int
+	defer func() {
+		if ran > 0 {
+			sqln.InvalidateAll(db)
+		}
+	}()
+	for _, mig := range m.Migrations {
+		mig := mig
+		if err := m.locked(ctx, db, func(tx sqln.DB) error {
+			applied, err := m.isApplied(ctx, tx, mig.Version)
+			if err != nil || applied {
+				return err
+			}
+			if err := exec(ctx, tx, mig.Up); err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx, fmt.Sprintf("INSERT INTO %v (version, name, applied_at) VALUES (:version, :name, :applied_at);", m.table()),
+				map[string]interface{}{"version": mig.Version, "name": mig.Name, "applied_at": time.Now().UTC()})
+			if err == nil {
+				ran++
+			}
+			return err
+		}); err != nil {
+			return errors.Wrapf(err, "migration %v (%v): up", mig.Version, mig.Name)
+		}
+	}
+	return nil
+}
+
+// Down reverts the most recently applied steps migrations, invalidating
+// cached statements as Up does.
+func (m *Migrator) Down(ctx context.Context, db sqln.DB, steps int) error {
+	ctx = sqln.WithMigrationMode(ctx)
+	if err := m.ensureTable(ctx, db); err != nil {
+		return err
+	}
+	var ran int
+	defer func() {
+		if ran > 0 {
+			sqln.InvalidateAll(db)
+		}
+	}()
+	byVersion := make(map[int64]Migration, len(m.Migrations))
+	for _, mig := range m.Migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	for i := 0; i < steps; i++ {
+		var reverted bool
+		if err := m.locked(ctx, db, func(tx sqln.DB) error {
+			var versions []int64
+			if err := tx.Select(ctx, fmt.Sprintf("SELECT version FROM %v ORDER BY version DESC LIMIT 1;", m.table()), &versions, nil); err != nil {
+				return err
+			}
+			if len(versions) == 0 {
+				return nil
+			}
+			mig, ok := byVersion[versions[0]]
+			if !ok {
+				return errors.Errorf("applied version %v is unknown", versions[0])
+			}
+			if mig.Down == "" {
+				return errors.Errorf("version %v (%v) has no down migration", mig.Version, mig.Name)
+			}
+			if err := exec(ctx, tx, mig.Down); err != nil {
+				return errors.Wrapf(err, "migration %v (%v): down", mig.Version, mig.Name)
+			}
+			reverted = true
+			_, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %v WHERE version = :version;", m.table()), map[string]interface{}{"version": mig.Version})
+			return err
+		}); err != nil {
+			return err
+		}
+		if !reverted {
+			return nil
+		}
+		ran++
+	}
+	return nil
+}
+
+// Version returns the latest applied version, or zero if none are applied.
+func (m *Migrator) Version(ctx context.Context, db sqln.DB) (int64, error) {
+	if err := m.ensureTable(ctx, db); err != nil {
+		return 0, err
+	}
+	var v sql.NullInt64
+	if err := db.Get(ctx, fmt.Sprintf("SELECT MAX(version) FROM %v;", m.table()), &v, nil); err != nil {
+		return 0, errors.Wrap(err, "schema version")
+	}
+	return v.Int64, nil
+}
+
+// Pending returns the migrations that have not been applied.
+func (m *Migrator) Pending(ctx context.Context, db sqln.DB) ([]Migration, error) {
+	if err := m.ensureTable(ctx, db); err != nil {
+		return nil, err
+	}
+	var versions []int64
+	if err := db.Select(ctx, fmt.Sprintf("SELECT version FROM %v;", m.table()), &versions, nil); err != nil {
+		return nil, errors.Wrap(err, "applied versions")
+	}
+	applied := make(map[int64]bool, len(versions))
+	for _, v := range versions {
+		applied[v] = true
+	}
+
+	var pending []Migration
+	for _, mig := range m.Migrations {
+		if !applied[mig.Version] {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// CheckVersion returns an error if any migration is pending. It matches the
+// signature of sqln.Startup.CheckSchemaVersion, for services that do not
+// migrate on boot.
+func (m *Migrator) CheckVersion(ctx context.Context, db sqln.DB) error {
+	pending, err := m.Pending(ctx, db)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return errors.Errorf("%v pending migrations, starting at version %v", len(pending), pending[0].Version)
+	}
+	return nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context, db sqln.DB) error {
+	return errors.Wrap(m.locked(ctx, db, func(tx sqln.DB) error {
+		_, err := tx.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %v (
+	version BIGINT PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+);`, m.table()), nil)
+		return err
+	}), "creating migrations table")
+}
+
+func (m *Migrator) isApplied(ctx context.Context, tx sqln.DB, version int64) (bool, error) {
+	var n int
+	err := tx.Get(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %v WHERE version = :version;", m.table()), &n, map[string]interface{}{"version": version})
+	return n > 0, err
+}
+
+// locked runs f in a transaction holding the migration lock (Postgres only).
+func (m *Migrator) locked(ctx context.Context, db sqln.DB, f func(sqln.DB) error) error {
+	postgres := sqln.DialectOf(db) == sqln.Postgres
+	return db.Transact(ctx, sql.TxOptions{}, func(tx sqln.DB) error {
+		if postgres {
+			if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(:id);", map[string]interface{}{"id": m.lockID()}); err != nil {
+				return errors.Wrap(err, "acquiring migration lock")
+			}
+		}
+		return f(tx)
+	})
+}
+
+// exec runs a migration script with sqln.ExecScript, so its statements are
+// run unprepared but still checked by the Database and by middleware that
+// handles scripts (ie. sqln.Guard). DBs that do not run scripts execute it
+// with Exec.
+func exec(ctx context.Context, db sqln.DB, script string) error {
+	for d := db; d != nil; {
+		if _, ok := d.(sqln.Scripter); ok {
+			return sqln.ExecScript(ctx, db, script)
+		}
+		u, ok := d.(sqln.Unwrapper)
+		if !ok {
+			break
+		}
+		d = u.Unwrap()
+	}
+	_, err := db.Exec(ctx, script, nil)
+	return err
+}
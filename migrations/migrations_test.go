@@ -0,0 +1,104 @@
+package migrations
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+)
+
+func TestMigrator(t *testing.T) {
+	dbx, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbx.Close()
+	db := sqln.New(dbx)
+	defer db.Close()
+
+	m, err := New(fstest.MapFS{
+		"0001_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY);\nCREATE INDEX users_id ON users (id);\n")},
+		"0001_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"0002_posts.sql":      {Data: []byte("CREATE TABLE posts (id INTEGER PRIMARY KEY);")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := m.CheckVersion(ctx, db); err == nil {
+		t.Fatal("expected pending migrations")
+	}
+	for i := 0; i < 2; i++ {
+		if err := m.Up(ctx, db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v, err := m.Version(ctx, db); err != nil || v != 2 {
+		t.Fatalf("expected version 2, got %v (%v)", v, err)
+	}
+	if err := m.CheckVersion(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, "INSERT INTO users (id) VALUES (1);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 0002 has no down migration.
+	if err := m.Down(ctx, db, 1); err == nil {
+		t.Fatal("expected error reverting without a down migration")
+	}
+	m.Migrations[1].Down = "DROP TABLE posts;"
+	if err := m.Down(ctx, db, 5); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := m.Version(ctx, db); err != nil || v != 0 {
+		t.Fatalf("expected version 0, got %v (%v)", v, err)
+	}
+}
+
+func TestMigratorMiddleware(t *testing.T) {
+	dbx, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbx.Close()
+	d := sqln.New(dbx)
+	defer d.Close()
+	db := sqln.Wrap(d, sqln.Guard(sqln.GuardOptions{}))
+
+	m, err := New(fstest.MapFS{
+		"0001_users.sql": {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY);\nDROP TABLE users;")},
+		"0002_purge.sql": {Data: []byte("CREATE TABLE posts (id INTEGER PRIMARY KEY);\nDELETE FROM posts;")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Migration scripts run through the middleware: DROP is allowed in
+	// migration mode, an unfiltered DELETE is not.
+	ctx := context.Background()
+	if err := m.Up(ctx, db); !errors.Is(err, sqln.ErrGuardedStatement) {
+		t.Fatalf("expected the guard to block 0002, got %v", err)
+	}
+	if v, err := m.Version(ctx, db); err != nil || v != 1 {
+		t.Fatalf("expected version 1, got %v (%v)", v, err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	if _, err := Load(fstest.MapFS{"users.sql": {}}); err == nil {
+		t.Fatal("expected error without a version")
+	}
+	if _, err := Load(fstest.MapFS{"0001_a.down.sql": {Data: []byte("x")}}); err == nil {
+		t.Fatal("expected error without an up migration")
+	}
+	if _, err := Load(fstest.MapFS{"0001_a.sql": {Data: []byte("x")}, "0001_b.sql": {Data: []byte("y")}}); err == nil {
+		t.Fatal("expected error for conflicting names")
+	}
+}
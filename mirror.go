@@ -0,0 +1,220 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MirrorOptions configures a Mirror.
+type MirrorOptions struct {
+	// Target receives the mirrored queries, ie. a database on a new
+	// version under test.
+	Target DB
+	// Percent of queries to mirror, from 0 to 100.
+	Percent float64
+	// Writes also mirrors Exec and ExecReturning. Only enable it when the
+	// target is disposable.
+	Writes bool
+	// MaxInFlight bounds concurrent mirrored queries; queries beyond it
+	// are not mirrored. Defaults to 10.
+	MaxInFlight int
+	// Timeout bounds each mirrored query. Defaults to 10 seconds.
+	Timeout time.Duration
+	// OnResult receives the comparison of every mirrored query.
+	OnResult func(MirrorResult)
+}
+
+// MirrorResult compares a query with its mirror.
+type MirrorResult struct {
+	Method string
+	Query  string
+	// Name is the query name, if any (see QueryName).
+	Name                   string
+	Latency, MirrorLatency time.Duration
+	// Rows is the number of rows returned (or affected by Exec), -1 when
+	// the query failed.
+	Rows, MirrorRows int64
+	// Err is the error of the mirrored query.
+	Err error
+	// Diverged is set when only one of the queries failed or their row
+	// counts differ.
+	Diverged bool
+}
+
+// Mirror asynchronously replays a sample of queries against a second DB,
+// ie. to compare a new database version with the current one under real
+// load. Queries in transactions are not mirrored.
+// NOTE: Params are read by the mirrored query after the original returns and
+// must not be modified.
+type Mirror struct {
+	opts MirrorOptions
+	sem  chan struct{}
+	wg   sync.WaitGroup
+
+	mtx  sync.Mutex
+	rand *rand.Rand
+}
+
+// NewMirror returns a Mirror.
+func NewMirror(opts MirrorOptions) *Mirror {
+	if opts.MaxInFlight <= 0 {
+		opts.MaxInFlight = 10
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return &Mirror{
+		opts: opts,
+		sem:  make(chan struct{}, opts.MaxInFlight),
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Middleware returns a middleware that mirrors queries.
+func (m *Mirror) Middleware() Middleware {
+	return func(db DB) DB {
+		return &mirrorDB{DB: db, m: m}
+	}
+}
+
+// Wait blocks until in-flight mirrored queries are done, ie. before closing
+// the target.
+func (m *Mirror) Wait() {
+	m.wg.Wait()
+}
+
+func (m *Mirror) sampled() bool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.rand.Float64()*100 < m.opts.Percent
+}
+
+// mirror runs f against the target in the background if the query is
+// sampled and a slot is free.
+func (m *Mirror) mirror(ctx context.Context, method, query string, latency time.Duration, rows int64, f func(context.Context, DB) (int64, error)) {
+	if m.opts.Target == nil || !m.sampled() {
+		return
+	}
+	select {
+	case m.sem <- struct{}{}:
+	default:
+		return
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer func() { <-m.sem }()
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.opts.Timeout)
+		defer cancel()
+		start := time.Now()
+		mrows, err := f(ctx, m.opts.Target)
+		r := MirrorResult{
+			Method:        method,
+			Query:         query,
+			Name:          QueryName(ctx, query),
+			Latency:       latency,
+			MirrorLatency: time.Since(start),
+			Rows:          rows,
+			MirrorRows:    mrows,
+			Err:           err,
+		}
+		r.Diverged = r.Rows != r.MirrorRows
+		if m.opts.OnResult != nil {
+			m.opts.OnResult(r)
+		}
+	}()
+}
+
+type mirrorDB struct {
+	DB
+	m *Mirror
+}
+
+func (d *mirrorDB) Unwrap() DB { return d.DB }
+
+// newDest returns a new value of the type dest points to.
+func newDest(dest interface{}) interface{} {
+	return reflect.New(reflect.TypeOf(dest).Elem()).Interface()
+}
+
+// destRows counts the rows scanned into dest by Get or Select.
+func destRows(dest interface{}, err error) int64 {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return 0
+	case err != nil:
+		return -1
+	case isSlicePtr(dest):
+		return int64(reflect.ValueOf(dest).Elem().Len())
+	}
+	return 1
+}
+
+func resultRows(res sql.Result, err error) int64 {
+	if err != nil {
+		return -1
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func (d *mirrorDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	start := time.Now()
+	err := d.DB.Get(ctx, query, dest, params)
+	d.m.mirror(ctx, "Get", query, time.Since(start), destRows(dest, err), func(ctx context.Context, db DB) (int64, error) {
+		mdest := newDest(dest)
+		err := db.Get(ctx, query, mdest, params)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return destRows(mdest, err), err
+	})
+	return err
+}
+
+func (d *mirrorDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	start := time.Now()
+	err := d.DB.Select(ctx, query, dest, params)
+	d.m.mirror(ctx, "Select", query, time.Since(start), destRows(dest, err), func(ctx context.Context, db DB) (int64, error) {
+		mdest := newDest(dest)
+		err := db.Select(ctx, query, mdest, params)
+		return destRows(mdest, err), err
+	})
+	return err
+}
+
+func (d *mirrorDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := d.DB.Exec(ctx, query, params)
+	if d.m.opts.Writes {
+		d.m.mirror(ctx, "Exec", query, time.Since(start), resultRows(res, err), func(ctx context.Context, db DB) (int64, error) {
+			res, err := db.Exec(ctx, query, params)
+			return resultRows(res, err), err
+		})
+	}
+	return res, err
+}
+
+func (d *mirrorDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	start := time.Now()
+	err := d.DB.ExecReturning(ctx, query, dest, params)
+	if d.m.opts.Writes {
+		d.m.mirror(ctx, "ExecReturning", query, time.Since(start), destRows(dest, err), func(ctx context.Context, db DB) (int64, error) {
+			mdest := newDest(dest)
+			err := db.ExecReturning(ctx, query, mdest, params)
+			return destRows(mdest, err), err
+		})
+	}
+	return err
+}
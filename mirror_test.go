@@ -0,0 +1,77 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+)
+
+func TestMirror(t *testing.T) {
+	primary, target := sqliteDB(t), sqliteDB(t)
+	ctx := context.Background()
+
+	for i, db := range []*Database{primary, target} {
+		if _, err := db.Exec(ctx, "CREATE TABLE items (id INTEGER);", nil); err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j <= i; j++ {
+			if _, err := db.Exec(ctx, "INSERT INTO items (id) VALUES (:id);", map[string]interface{}{"id": j}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var (
+		mtx     sync.Mutex
+		results []MirrorResult
+	)
+	m := NewMirror(MirrorOptions{Target: target, Percent: 100, OnResult: func(r MirrorResult) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		results = append(results, r)
+	}})
+	db := Wrap(primary, m.Middleware())
+
+	var ids []int
+	if err := db.Select(ctx, "SELECT id FROM items;", &ids, nil); err != nil {
+		t.Fatal(err)
+	}
+	var id int
+	if err := db.Get(ctx, "SELECT id FROM items WHERE id = 0;", &id, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, "DELETE FROM items;", nil); err != nil {
+		t.Fatal(err)
+	}
+	err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		return tx.Select(ctx, "SELECT id FROM items;", &ids, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Wait()
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 mirrored reads, got %+v", results)
+	}
+	for _, r := range results {
+		switch r.Method {
+		case "Select":
+			if r.Rows != 1 || r.MirrorRows != 2 || !r.Diverged {
+				t.Errorf("unexpected select result: %+v", r)
+			}
+		case "Get":
+			if r.Rows != 1 || r.MirrorRows != 1 || r.Diverged || r.Err != nil {
+				t.Errorf("unexpected get result: %+v", r)
+			}
+		default:
+			t.Errorf("unexpected mirrored method: %+v", r)
+		}
+	}
+
+	var n int
+	if err := target.Get(ctx, "SELECT COUNT(*) FROM items;", &n, nil); err != nil || n != 2 {
+		t.Fatalf("expected writes not to be mirrored, got %v %v", n, err)
+	}
+}
@@ -0,0 +1,100 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Mutation describes a successful write.
+type Mutation struct {
+	Query string
+	// Name is the query name, when known (see MutationHook.Name and
+	// QueryName).
+	Name string
+	// Tables are the tables written, see MutatedTables.
+	Tables []string
+	// RowsAffected is -1 when unknown, ie. for ExecReturning.
+	RowsAffected int64
+}
+
+// MutationHook notifies applications of writes, ie. to invalidate external
+// caches.
+type MutationHook struct {
+	// After is called after each successful Exec or ExecReturning. Writes
+	// in a transaction are reported once it commits, and not at all if it
+	// rolls back, so caches are not invalidated before readers could see
+	// the new data.
+	After func(ctx context.Context, m Mutation)
+	// Name resolves query names, ie. (*Queries).NameOf. Optional.
+	Name func(query string) (string, bool)
+}
+
+// Middleware returns a middleware that calls the hook.
+func (h MutationHook) Middleware() Middleware {
+	return func(db DB) DB {
+		return &mutationDB{DB: db, h: h}
+	}
+}
+
+func (h MutationHook) mutation(ctx context.Context, query string, rows int64) Mutation {
+	m := Mutation{Query: query, Name: QueryName(ctx, query), Tables: MutatedTables(query), RowsAffected: rows}
+	if h.Name != nil && m.Name == "" {
+		m.Name, _ = h.Name(query)
+	}
+	return m
+}
+
+type mutationDB struct {
+	DB
+	h MutationHook
+
+	// pending is set inside transactions to the writes made so far.
+	pending *[]Mutation
+}
+
+func (d *mutationDB) Unwrap() DB { return d.DB }
+
+func (d *mutationDB) wrote(ctx context.Context, m Mutation) {
+	if d.pending != nil {
+		*d.pending = append(*d.pending, m)
+		return
+	}
+	d.h.After(ctx, m)
+}
+
+func (d *mutationDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	res, err := d.DB.Exec(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+	rows, rerr := res.RowsAffected()
+	if rerr != nil {
+		rows = -1
+	}
+	d.wrote(ctx, d.h.mutation(ctx, query, rows))
+	return res, nil
+}
+
+func (d *mutationDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.DB.ExecReturning(ctx, query, dest, params); err != nil {
+		return err
+	}
+	d.wrote(ctx, d.h.mutation(ctx, query, -1))
+	return nil
+}
+
+func (d *mutationDB) Transact(ctx context.Context, opts sql.TxOptions, f func(DB) error) error {
+	var pending []Mutation
+	if err := d.DB.Transact(ctx, opts, func(tx DB) error {
+		// Writes from a failed attempt (ie. one that is retried) are
+		// discarded.
+		pending = pending[:0]
+		return f(&mutationDB{DB: tx, h: d.h, pending: &pending})
+	}); err != nil {
+		return err
+	}
+	for _, m := range pending {
+		d.h.After(ctx, m)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestMutationHook(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE mutation_abc (id INTEGER PRIMARY KEY);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	const insert = "INSERT INTO mutation_abc (id) VALUES (:id);"
+	var got []Mutation
+	h := MutationHook{
+		After: func(ctx context.Context, m Mutation) { got = append(got, m) },
+		Name: func(query string) (string, bool) {
+			return "InsertABC", query == insert
+		},
+	}
+	mdb := Wrap(db, h.Middleware())
+
+	if _, err := mdb.Exec(ctx, insert, map[string]interface{}{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	want := Mutation{Query: insert, Name: "InsertABC", Tables: []string{"mutation_abc"}, RowsAffected: 1}
+	if len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+		t.Fatalf("unexpected mutations: %+v", got)
+	}
+
+	got = nil
+	if err := mdb.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		if _, err := tx.Exec(ctx, insert, map[string]interface{}{"id": 2}); err != nil {
+			return err
+		}
+		if len(got) != 0 {
+			t.Error("expected the hook to wait for commit")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 mutation after commit, got %+v", got)
+	}
+
+	got = nil
+	err := mdb.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		if _, err := tx.Exec(ctx, insert, map[string]interface{}{"id": 3}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	if err == nil || len(got) != 0 {
+		t.Fatalf("expected no mutations after rollback, got %+v (%v)", got, err)
+	}
+}
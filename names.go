@@ -0,0 +1,100 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	namesMtx sync.RWMutex
+	names    = make(map[string]string)
+)
+
+// NameQuery registers a stable name for query, used in place of its text by
+// hooks, records and errors (see QueryName). Names from a Queries file can be
+// registered with NameQueries.
+func NameQuery(name, query string) {
+	namesMtx.Lock()
+	defer namesMtx.Unlock()
+	names[query] = name
+}
+
+// NameQueries registers the names of every query in q.
+func NameQueries(q *Queries) {
+	for _, name := range q.Names() {
+		NameQuery(name, q.MustGet(name))
+	}
+}
+
+// WithQueryName returns a context that names the queries run with it,
+// overriding registered names.
+func WithQueryName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, queryNameKey, name)
+}
+
+// QueryName returns the name of query from ctx (see WithQueryName), the
+// registry (see NameQuery) or a leading name comment, ie.
+//
+//	/* name: get_user */ SELECT * FROM users WHERE id = :id
+//
+// or "" if it has none.
+func QueryName(ctx context.Context, query string) string {
+	if name, ok := ctx.Value(queryNameKey).(string); ok && name != "" {
+		return name
+	}
+	namesMtx.RLock()
+	name, ok := names[query]
+	namesMtx.RUnlock()
+	if ok {
+		return name
+	}
+	return commentName(query)
+}
+
+// commentName returns the name from a leading "/* name: <name> */" or
+// "-- name: <name>" comment of query.
+func commentName(query string) string {
+	q := strings.TrimLeft(query, " \t\r\n")
+	var comment string
+	switch {
+	case strings.HasPrefix(q, "/*"):
+		end := strings.Index(q, "*/")
+		if end < 0 {
+			return ""
+		}
+		comment = q[2:end]
+	case strings.HasPrefix(q, "--"):
+		end := strings.IndexByte(q, '\n')
+		if end < 0 {
+			end = len(q)
+		}
+		comment = q[2:end]
+	default:
+		return ""
+	}
+	name, ok := strings.CutPrefix(strings.TrimSpace(comment), "name:")
+	if !ok {
+		return ""
+	}
+	name = strings.TrimSpace(name)
+	if strings.ContainsAny(name, " \t\r\n") {
+		return ""
+	}
+	return name
+}
+
+// nameErr wraps the error of a named query with its name. sql.ErrNoRows is
+// returned unchanged since it is often compared directly.
+func nameErr(ctx context.Context, query string, err error) error {
+	if err == nil || errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if name := QueryName(ctx, query); name != "" {
+		return errors.Wrapf(err, "query %v", name)
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestQueryName(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE names_abc (id INTEGER PRIMARY KEY);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	const (
+		get     = "SELECT id FROM names_abc WHERE id = :id;"
+		missing = "SELECT id FROM names_missing;"
+	)
+	NameQuery("GetABC", get)
+	NameQuery("GetMissing", missing)
+
+	if name := QueryName(ctx, get); name != "GetABC" {
+		t.Fatalf("unexpected name: %q", name)
+	}
+	if name := QueryName(WithQueryName(ctx, "Override"), get); name != "Override" {
+		t.Fatalf("unexpected name: %q", name)
+	}
+
+	var id int
+	if err := db.Get(ctx, get, &id, map[string]interface{}{"id": 1}); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows unchanged, got %v", err)
+	}
+	err := db.Select(ctx, missing, &[]int{}, nil)
+	if err == nil || !strings.HasPrefix(err.Error(), "query GetMissing: ") {
+		t.Fatalf("expected the error to be named, got %v", err)
+	}
+}
+
+func TestCommentName(t *testing.T) {
+	ctx := context.Background()
+	for query, want := range map[string]string{
+		"/* name: get_user */ SELECT * FROM users WHERE id = :id;": "get_user",
+		"\n  /*name:list_users*/\nSELECT * FROM users;":            "list_users",
+		"-- name: count_users\nSELECT COUNT(*) FROM users;":        "count_users",
+		"SELECT 1; /* name: trailing */":                           "",
+		"/* not a name */ SELECT 1;":                               "",
+		"/* name: two words */ SELECT 1;":                          "",
+		"/* name: unterminated SELECT 1;":                          "",
+	} {
+		if got := QueryName(ctx, query); got != want {
+			t.Errorf("QueryName(%q) = %q, want %q", query, got, want)
+		}
+	}
+
+	const query = "/* name: from_comment */ SELECT 1;"
+	NameQuery("registered", query)
+	if got := QueryName(ctx, query); got != "registered" {
+		t.Fatalf("expected the registered name to take precedence, got %q", got)
+	}
+
+	db := sqliteDB(t)
+	err := db.Select(ctx, "/* name: select_missing */ SELECT id FROM names_comment_missing;", &[]int{}, nil)
+	if err == nil || !strings.HasPrefix(err.Error(), "query select_missing: ") {
+		t.Fatalf("expected the error to be named, got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is returned by Get (and ExecReturning into a single row) in
+// place of sql.ErrNoRows when enabled by WithNotFoundErrors or WithNotFound.
+// It matches sql.ErrNoRows with errors.Is, so only direct comparisons with
+// sql.ErrNoRows need updating.
+var ErrNotFound error = notFoundError{}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string { return "sqln: not found" }
+
+func (notFoundError) Is(target error) bool { return target == sql.ErrNoRows }
+
+// WithNotFoundErrors returns ErrNotFound rather than sql.ErrNoRows when Get
+// matches no row. Calls can override it with WithNotFound.
+func WithNotFoundErrors() Option {
+	return func(d *Database) {
+		d.notFound = true
+	}
+}
+
+// WithNotFound overrides WithNotFoundErrors for calls with ctx, ie. to keep
+// sql.ErrNoRows at call sites that compare it directly (convert false) or to
+// opt in before WithNotFoundErrors is enabled (convert true).
+func WithNotFound(ctx context.Context, convert bool) context.Context {
+	return context.WithValue(ctx, notFoundKey, convert)
+}
+
+// notFoundErr converts sql.ErrNoRows to ErrNotFound if enabled for ctx.
+func (d *Database) notFoundErr(ctx context.Context, err error) error {
+	if err == nil || err == ErrNotFound || !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	convert, ok := ctx.Value(notFoundKey).(bool)
+	if !ok {
+		convert = d.notFound
+	}
+	if convert {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestNotFound(t *testing.T) {
+	base := sqliteDB(t)
+	ctx := context.Background()
+	if _, err := base.Exec(ctx, "CREATE TABLE notfound_x (id INTEGER);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	const query = "SELECT id FROM notfound_x WHERE id = :id;"
+	params := map[string]interface{}{"id": 1}
+	var id int
+	if err := base.Get(ctx, query, &id, params); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+	if err := base.Get(WithNotFound(ctx, true), query, &id, params); err != ErrNotFound || !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	db := New(base.X, WithNotFoundErrors())
+	defer db.Close()
+	if err := db.Get(ctx, query, &id, params); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := db.Get(WithNotFound(ctx, false), query, &id, params); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+	if err := db.GetUnprepared(ctx, query, &id, params); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound unprepared, got %v", err)
+	}
+	err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		return tx.ExecReturning(ctx, query, &id, params)
+	})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound in tx, got %v", err)
+	}
+	if _, _, err := GetOptional[int](ctx, db, query, params); err != nil {
+		t.Fatal(err)
+	}
+}
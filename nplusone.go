@@ -0,0 +1,114 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"runtime/debug"
+	"sync"
+)
+
+// RepeatedQueries configures the DetectRepeatedQueries middleware.
+type RepeatedQueries struct {
+	// Threshold is the number of runs of a query within a scope that is
+	// reported. Defaults to 10.
+	Threshold int
+	// OnRepeated is called once per query per scope when the query reaches
+	// Threshold, ie. to log the report.
+	OnRepeated func(ctx context.Context, r RepeatedQuery)
+}
+
+// RepeatedQuery describes a query run Threshold times within one scope,
+// likely in a loop that should be a single query (an N+1 pattern).
+type RepeatedQuery struct {
+	Query string
+	// Name is the query name, if any (see QueryName).
+	Name  string
+	Count int
+	// Stack is the call stack of the run that reached the threshold, which
+	// leads to the loop.
+	Stack string
+}
+
+// WithQueryScope returns a context whose queries are counted together by the
+// DetectRepeatedQueries middleware. Typically applied once per request.
+func WithQueryScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queryScopeKey, &queryScope{counts: make(map[string]int)})
+}
+
+// DetectRepeatedQueries returns a middleware that reports queries run
+// Threshold times within one transaction, or one WithQueryScope context
+// outside of transactions, to catch N+1 patterns during development and in
+// tests. Queries are counted by Fingerprint, so literals may vary. Capturing
+// the call stack is expensive, so it is not meant for production.
+func DetectRepeatedQueries(r RepeatedQueries) Middleware {
+	if r.Threshold <= 0 {
+		r.Threshold = 10
+	}
+	return func(db DB) DB {
+		return newRepeatDB(db, r, nil)
+	}
+}
+
+// newRepeatDB counts the queries of a transaction in scope, starting a scope
+// for the outermost transaction.
+func newRepeatDB(db DB, cfg RepeatedQueries, scope *queryScope) DB {
+	return &repeatDB{BaseDB: BaseDB{DB: db, Wrap: func(tx DB) DB {
+		if scope == nil {
+			return newRepeatDB(tx, cfg, &queryScope{counts: make(map[string]int)})
+		}
+		return newRepeatDB(tx, cfg, scope)
+	}}, cfg: cfg, scope: scope}
+}
+
+type queryScope struct {
+	mtx    sync.Mutex
+	counts map[string]int
+}
+
+// count records a run of query, returning the number of runs so far.
+func (s *queryScope) count(query string) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	key := Fingerprint(query)
+	s.counts[key]++
+	return s.counts[key]
+}
+
+type repeatDB struct {
+	BaseDB
+	cfg RepeatedQueries
+	// scope is set in transactions.
+	scope *queryScope
+}
+
+func (d *repeatDB) track(ctx context.Context, query string) {
+	s := d.scope
+	if s == nil {
+		if s, _ = ctx.Value(queryScopeKey).(*queryScope); s == nil {
+			return
+		}
+	}
+	if n := s.count(query); n == d.cfg.Threshold && d.cfg.OnRepeated != nil {
+		d.cfg.OnRepeated(ctx, RepeatedQuery{Query: query, Name: QueryName(ctx, query), Count: n, Stack: string(debug.Stack())})
+	}
+}
+
+func (d *repeatDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	d.track(ctx, query)
+	return d.DB.Exec(ctx, query, params)
+}
+
+func (d *repeatDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	d.track(ctx, query)
+	return d.DB.ExecReturning(ctx, query, dest, params)
+}
+
+func (d *repeatDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	d.track(ctx, query)
+	return d.DB.Get(ctx, query, dest, params)
+}
+
+func (d *repeatDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	d.track(ctx, query)
+	return d.DB.Select(ctx, query, dest, params)
+}
@@ -0,0 +1,53 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestDetectRepeatedQueries(t *testing.T) {
+	var reports []RepeatedQuery
+	db := Wrap(sqliteDB(t), DetectRepeatedQueries(RepeatedQueries{
+		Threshold:  3,
+		OnRepeated: func(ctx context.Context, r RepeatedQuery) { reports = append(reports, r) },
+	}))
+
+	// Outside of a scope queries are not counted.
+	ctx := context.Background()
+	var n int
+	for i := 0; i < 5; i++ {
+		if err := db.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(reports) != 0 {
+		t.Fatalf("expected no reports, got %+v", reports)
+	}
+
+	scoped := WithQueryScope(ctx)
+	for i := 0; i < 5; i++ {
+		if err := db.Get(scoped, "SELECT :i;", &n, map[string]interface{}{"i": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(reports) != 1 || reports[0].Count != 3 || reports[0].Query != "SELECT :i;" || !strings.Contains(reports[0].Stack, "TestDetectRepeatedQueries") {
+		t.Fatalf("expected one report, got %+v", reports)
+	}
+
+	reports = nil
+	if err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		for i := 0; i < 3; i++ {
+			if err := tx.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0].Query != "SELECT 1;" {
+		t.Fatalf("expected the transaction's queries to be reported, got %+v", reports)
+	}
+}
@@ -0,0 +1,54 @@
+package sqln
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNull(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, "CREATE TABLE profiles (id INTEGER, nickname TEXT, age INTEGER, seen DATETIME);", nil); err != nil {
+		t.Fatal(err)
+	}
+	type profile struct {
+		ID       int             `db:"id"`
+		Nickname Null[string]    `db:"nickname"`
+		Age      Null[int64]     `db:"age"`
+		Seen     Null[time.Time] `db:"seen"`
+	}
+	in := profile{ID: 1, Nickname: NullOf("x"), Seen: NullOf(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))}
+	if _, err := db.Exec(ctx, "INSERT INTO profiles (id, nickname, age, seen) VALUES (:id, :nickname, :age, :seen);", in); err != nil {
+		t.Fatal(err)
+	}
+	var out profile
+	if err := db.Get(ctx, "SELECT id, nickname, age, seen FROM profiles;", &out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if out.Nickname != in.Nickname || out.Age.Valid || !out.Seen.V.Equal(in.Seen.V) {
+		t.Fatalf("expected %+v, got %+v", in, out)
+	}
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded profile
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Nickname != out.Nickname || decoded.Age.Valid {
+		t.Fatalf("unexpected json round trip %s: %+v", b, decoded)
+	}
+
+	if p := NullFrom[int](nil); p.Valid || p.Ptr() != nil {
+		t.Fatalf("expected invalid null, got %+v", p)
+	}
+	n := 3
+	if p := NullFrom(&n).Ptr(); p == nil || *p != 3 || p == &n {
+		t.Fatalf("unexpected pointer %v", p)
+	}
+}
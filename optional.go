@@ -0,0 +1,22 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// GetOptional gets a single record, reporting false rather than sql.ErrNoRows
+// when no row matches.
+func GetOptional[T any](ctx context.Context, db DB, query string, params interface{}) (T, bool, error) {
+	var v T
+	if err := db.Get(ctx, query, &v, params); err != nil {
+		var zero T
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, false, nil
+		}
+		return zero, false, err
+	}
+	return v, true, nil
+}
@@ -0,0 +1,35 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetOptional(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, "CREATE TABLE users (id INTEGER, name TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, "INSERT INTO users (id, name) VALUES (1, 'a');", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	type user struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	const query = "SELECT id, name FROM users WHERE id = :id;"
+	u, ok, err := GetOptional[user](ctx, db, query, map[string]interface{}{"id": 1})
+	if err != nil || !ok || u.Name != "a" {
+		t.Fatalf("unexpected result: %+v %v %v", u, ok, err)
+	}
+	u, ok, err = GetOptional[user](ctx, db, query, map[string]interface{}{"id": 2})
+	if err != nil || ok || u != (user{}) {
+		t.Fatalf("expected no row, got %+v %v %v", u, ok, err)
+	}
+	if _, _, err := GetOptional[user](ctx, db, "SELECT id FROM optional_missing;", nil); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,63 @@
+package sqln
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// Pagination describes keyset pagination over a query.
+type Pagination struct {
+	// Query is the base query, without ORDER BY or LIMIT. It may reference
+	// named params.
+	Query string
+	// Keys are the columns rows are ordered and paginated by. Together they
+	// must be unique, ie. (created_at, id).
+	Keys []string
+	// Desc orders rows by descending keys.
+	Desc bool
+	// Size is the maximum number of rows per page.
+	Size int
+}
+
+// SQL returns the query for a page. There are two variants (the first page
+// and those after a cursor) so each is cached as a single statement.
+func (p Pagination) SQL(after bool) string {
+	var where string
+	if after {
+		where = " WHERE " + keysetWhere(p.Keys, p.Desc)
+	}
+	return fmt.Sprintf("SELECT * FROM (%v) AS sqln_page%v ORDER BY %v LIMIT :sqln_limit;",
+		trimSemicolon(p.Query), where, keysetOrder(p.Keys, p.Desc))
+}
+
+// Paginate returns the page of rows following cursor (empty for the first
+// page) and the cursor of the next page, which is empty after the last page.
+// Cursors are opaque strings (see Cursor.Encode).
+func Paginate[T any](ctx context.Context, db DB, p Pagination, cursor string, params interface{}) ([]T, string, error) {
+	if len(p.Keys) == 0 || p.Size <= 0 {
+		return nil, "", errors.New("paginate: keys and size are required")
+	}
+	after, err := DecodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if after != nil && len(after) != len(p.Keys) {
+		return nil, "", errors.Errorf("paginate: cursor has %v values for %v keys", len(after), len(p.Keys))
+	}
+
+	var rows []T
+	m, _ := mapperOf(db)
+	if err := db.Select(ctx, p.SQL(after != nil), &rows, withParams(m, params, keysetParams(after, p.Size))); err != nil {
+		return nil, "", err
+	}
+
+	next, err := nextCursor(m, p.Keys, reflect.ValueOf(rows), p.Size)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "paginate")
+	}
+	s, err := next.Encode()
+	return rows, s, err
+}
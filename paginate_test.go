@@ -0,0 +1,73 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPaginate(t *testing.T) {
+	db := sqliteDB(t)
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE paginate_abc (id INTEGER PRIMARY KEY, created_at TIMESTAMP NOT NULL, kind TEXT NOT NULL);", nil); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 7; i++ {
+		// Timestamps collide so ordering relies on both keys.
+		if _, err := db.Exec(ctx, "INSERT INTO paginate_abc (id, created_at, kind) VALUES (:id, :created_at, :kind);", map[string]interface{}{
+			"id":         i,
+			"created_at": base.Add(time.Duration(i/2) * time.Hour),
+			"kind":       []string{"a", "b"}[i%2],
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type row struct {
+		ID        int       `db:"id"`
+		CreatedAt time.Time `db:"created_at"`
+		Kind      string    `db:"kind"`
+	}
+	p := Pagination{
+		Query: "SELECT id, created_at, kind FROM paginate_abc WHERE kind = :kind;",
+		Keys:  []string{"created_at", "id"},
+		Size:  2,
+	}
+
+	var (
+		ids    []int
+		cursor string
+	)
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("too many pages")
+		}
+		rows, next, err := Paginate[row](ctx, db, p, cursor, map[string]interface{}{"kind": "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range rows {
+			ids = append(ids, r.ID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	expected := []int{1, 3, 5, 7}
+	if len(ids) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, ids)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, ids)
+		}
+	}
+
+	if _, _, err := Paginate[row](ctx, db, p, "not a cursor", nil); err == nil {
+		t.Fatal("expected error for invalid cursor")
+	}
+}
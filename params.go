@@ -0,0 +1,81 @@
+package sqln
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+var defaultMapper = reflectx.NewMapperFunc("db", sqlx.NameMapper)
+
+// withParams returns params (nil, a map with string keys, or a struct mapped
+// by m) extended with extra named values. Existing values take precedence.
+func withParams(m *reflectx.Mapper, params interface{}, extra map[string]interface{}) interface{} {
+	p := make(map[string]interface{}, len(extra))
+	for k, v := range extra {
+		p[k] = v
+	}
+	if params == nil {
+		return p
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(params))
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return params
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			p[iter.Key().String()] = iter.Value().Interface()
+		}
+	case reflect.Struct:
+		for name, f := range m.FieldMap(v) {
+			p[name] = f.Interface()
+		}
+	default:
+		return params
+	}
+	return p
+}
+
+// isSlicePtr reports whether dest is a pointer to a slice (other than
+// []byte, which scans as a single value).
+func isSlicePtr(dest interface{}) bool {
+	t := reflect.TypeOf(dest)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Slice {
+		return false
+	}
+	return t.Elem().Elem().Kind() != reflect.Uint8
+}
+
+// columnFields returns the fields of struct type t that map to a column: those
+// without mapped sub-fields (time.Time has none) or that are scanned as a
+// single value (ie. sql.NullString), and that are not nested under a
+// non-embedded struct, as mapped by m.
+func columnFields(m *reflectx.Mapper, t reflect.Type) []*reflectx.FieldInfo {
+	var fields []*reflectx.FieldInfo
+	for _, f := range m.TypeMap(t).Index {
+		if strings.Contains(f.Path, ".") || hasChildren(f) && !reflect.PtrTo(f.Field.Type).Implements(scannerType) {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func hasChildren(f *reflectx.FieldInfo) bool {
+	for _, c := range f.Children {
+		if c != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// trimSemicolon strips a trailing semicolon so query can be nested.
+func trimSemicolon(query string) string {
+	return strings.TrimSuffix(strings.TrimSpace(query), ";")
+}
@@ -0,0 +1,279 @@
+/*
+Package partition maintains time-based (daily or monthly) range partitions of
+declared Postgres partitioned tables: creating partitions ahead of time,
+attaching existing tables and dropping partitions past their retention. A
+Runner does so periodically under an advisory lock (see sqln.TryLocked), so
+only one instance of a service maintains the partitions.
+*/
+package partition
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+)
+
+// Interval is the time range of each partition.
+type Interval int
+
+// Partition intervals.
+const (
+	Monthly Interval = iota
+	Daily
+)
+
+// Table declares a table partitioned by range on a timestamp column, ie.
+//
+//	CREATE TABLE events (..., created_at TIMESTAMPTZ NOT NULL) PARTITION BY RANGE (created_at);
+//
+// Partitions are named after the table and the start of their range, ie.
+// events_p202401 or events_p20240105, in UTC.
+type Table struct {
+	Name     string
+	Interval Interval
+	// Premake is the number of partitions after the current one that are
+	// kept created. Defaults to 3.
+	Premake int
+	// Retain is how long a partition is kept after its range ends. Zero
+	// keeps partitions forever.
+	Retain time.Duration
+}
+
+func (t Table) premake() int {
+	if t.Premake > 0 {
+		return t.Premake
+	}
+	return 3
+}
+
+// Bounds returns the range of the partition containing at.
+func (t Table) Bounds(at time.Time) (start, end time.Time) {
+	at = at.UTC()
+	if t.Interval == Daily {
+		start = time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 0, 1)
+	}
+	start = time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+func (t Table) layout() string {
+	if t.Interval == Daily {
+		return "20060102"
+	}
+	return "200601"
+}
+
+// PartitionName returns the name of the partition containing at.
+func (t Table) PartitionName(at time.Time) string {
+	start, _ := t.Bounds(at)
+	return t.Name + "_p" + start.Format(t.layout())
+}
+
+// parse returns the start of the range of partition, which may be
+// unqualified, if it is named like those of t.
+func (t Table) parse(partition string) (time.Time, bool) {
+	base := t.Name[strings.LastIndexByte(t.Name, '.')+1:]
+	partition = partition[strings.LastIndexByte(partition, '.')+1:]
+	suffix, ok := strings.CutPrefix(partition, base+"_p")
+	if !ok || len(suffix) != len(t.layout()) {
+		return time.Time{}, false
+	}
+	start, err := time.Parse(t.layout(), suffix)
+	return start, err == nil
+}
+
+// qualify adds the schema of t to partition.
+func (t Table) qualify(partition string) string {
+	if i := strings.LastIndexByte(t.Name, '.'); i >= 0 && !strings.Contains(partition, ".") {
+		return t.Name[:i+1] + partition
+	}
+	return partition
+}
+
+// forValues returns the bounds clause of a partition. DDL takes no params, so
+// the bounds are formatted into it.
+func forValues(start, end time.Time) string {
+	return fmt.Sprintf("FOR VALUES FROM ('%v') TO ('%v')", start.Format(time.RFC3339), end.Format(time.RFC3339))
+}
+
+// Create creates the partition containing at if it does not exist and
+// returns its name.
+func Create(ctx context.Context, db sqln.DB, t Table, at time.Time) (string, error) {
+	name := t.PartitionName(at)
+	start, end := t.Bounds(at)
+	q := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %v PARTITION OF %v %v;", name, t.Name, forValues(start, end))
+	if err := sqln.ExecScript(ctx, db, q); err != nil {
+		return "", errors.Wrapf(err, "partition: create %v", name)
+	}
+	return name, nil
+}
+
+// Attach attaches the existing table partition as the partition of t
+// containing at, ie. after loading it separately.
+func Attach(ctx context.Context, db sqln.DB, t Table, partition string, at time.Time) error {
+	start, end := t.Bounds(at)
+	q := fmt.Sprintf("ALTER TABLE %v ATTACH PARTITION %v %v;", t.Name, partition, forValues(start, end))
+	if err := sqln.ExecScript(ctx, db, q); err != nil {
+		return errors.Wrapf(err, "partition: attach %v", partition)
+	}
+	return nil
+}
+
+// Partitions returns the names of the partitions of t, qualified like t.
+func Partitions(ctx context.Context, db sqln.DB, t Table) ([]string, error) {
+	var names []string
+	err := db.Select(ctx, `SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+	WHERE i.inhparent = to_regclass(:parent) ORDER BY c.relname;`, &names, map[string]interface{}{"parent": t.Name})
+	if err != nil {
+		return nil, errors.Wrapf(err, "partition: list %v", t.Name)
+	}
+	for i := range names {
+		names[i] = t.qualify(names[i])
+	}
+	return names, nil
+}
+
+// Drop drops the partitions of t whose range ended before cutoff and returns
+// their names. Partitions not named like those of Create are kept.
+func Drop(ctx context.Context, db sqln.DB, t Table, cutoff time.Time) ([]string, error) {
+	names, err := Partitions(ctx, db, t)
+	if err != nil {
+		return nil, err
+	}
+	var dropped []string
+	for _, name := range names {
+		start, ok := t.parse(name)
+		if !ok {
+			continue
+		}
+		if _, end := t.Bounds(start); end.After(cutoff) {
+			continue
+		}
+		if err := sqln.ExecScript(ctx, db, fmt.Sprintf("DROP TABLE %v;", name)); err != nil {
+			return dropped, errors.Wrapf(err, "partition: drop %v", name)
+		}
+		dropped = append(dropped, name)
+	}
+	return dropped, nil
+}
+
+// Report summarizes the maintenance of a table.
+type Report struct {
+	Table   Table
+	Created []string
+	Dropped []string
+	Err     error
+}
+
+// Maintain creates the current and Premake following partitions of t and
+// drops those past Retain.
+func Maintain(ctx context.Context, db sqln.DB, t Table, now time.Time) Report {
+	r := Report{Table: t}
+	at, _ := t.Bounds(now)
+	for i := 0; i <= t.premake(); i++ {
+		name, err := Create(ctx, db, t, at)
+		if err != nil {
+			r.Err = err
+			return r
+		}
+		r.Created = append(r.Created, name)
+		_, at = t.Bounds(at)
+	}
+	if t.Retain > 0 {
+		r.Dropped, r.Err = Drop(ctx, db, t, now.Add(-t.Retain))
+	}
+	return r
+}
+
+// Options configures a Runner.
+type Options struct {
+	// Interval between runs. Defaults to 1h.
+	Interval time.Duration
+	// Report is called after each table is maintained.
+	Report func(Report)
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// Runner maintains registered tables.
+type Runner struct {
+	db   sqln.DB
+	opts Options
+
+	mtx    sync.Mutex
+	tables []Table
+}
+
+// NewRunner returns a Runner that maintains partitions through db.
+func NewRunner(db sqln.DB, opts Options) *Runner {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Hour
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Runner{db: db, opts: opts}
+}
+
+// Register adds a table.
+func (r *Runner) Register(t Table) error {
+	if t.Name == "" {
+		return errors.New("partition: table name is required")
+	}
+	if t.Interval != Monthly && t.Interval != Daily {
+		return errors.Errorf("partition: %v: unknown interval %v", t.Name, t.Interval)
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.tables = append(r.tables, t)
+	return nil
+}
+
+// Run maintains all tables every Interval until ctx is done.
+func (r *Runner) Run(ctx context.Context) error {
+	t := time.NewTicker(r.opts.Interval)
+	defer t.Stop()
+
+	for {
+		r.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// RunOnce maintains every table, unless another Runner holds the lock, and
+// returns their reports.
+func (r *Runner) RunOnce(ctx context.Context) ([]Report, error) {
+	r.mtx.Lock()
+	tables := append([]Table(nil), r.tables...)
+	r.mtx.Unlock()
+
+	var reports []Report
+	_, err := sqln.TryLocked(ctx, r.db, "sqln/partition", func(tx sqln.DB) error {
+		now := r.opts.Now()
+		for _, t := range tables {
+			// A failure aborts the transaction, rolling back the other
+			// tables.
+			rep := Maintain(ctx, tx, t, now)
+			if r.opts.Report != nil {
+				r.opts.Report(rep)
+			}
+			reports = append(reports, rep)
+			if rep.Err != nil {
+				return rep.Err
+			}
+		}
+		return nil
+	})
+	return reports, err
+}
@@ -0,0 +1,80 @@
+package partition
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nstogner/sqln/sqlntest"
+)
+
+func TestNames(t *testing.T) {
+	at := time.Date(2024, 1, 31, 23, 30, 0, 0, time.FixedZone("", -3600))
+	monthly := Table{Name: "events"}
+	daily := Table{Name: "audit.events", Interval: Daily}
+
+	if start, end := monthly.Bounds(at); !start.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected monthly bounds: %v %v", start, end)
+	}
+	if name := monthly.PartitionName(at); name != "events_p202402" {
+		t.Errorf("unexpected monthly name %q", name)
+	}
+	if name := daily.PartitionName(at); name != "audit.events_p20240201" {
+		t.Errorf("unexpected daily name %q", name)
+	}
+	if start, ok := daily.parse("events_p20240201"); !ok || !start.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected parse: %v %v", start, ok)
+	}
+	for _, name := range []string{"events_p202402", "events_default", "other_p20240201"} {
+		if _, ok := daily.parse(name); ok {
+			t.Errorf("expected %q not to parse", name)
+		}
+	}
+	if name := daily.qualify("events_p20240201"); name != "audit.events_p20240201" {
+		t.Errorf("unexpected qualified name %q", name)
+	}
+}
+
+func TestRunner(t *testing.T) {
+	db := sqlntest.NewPostgres(t, sqlntest.PostgresOptions{})
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE part_events (id INT, created_at TIMESTAMPTZ NOT NULL) PARTITION BY RANGE (created_at);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	table := Table{Name: "part_events", Premake: 1, Retain: 24 * time.Hour}
+	if _, err := Create(ctx, db, table, now.AddDate(0, -2, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, "CREATE TABLE part_loaded (LIKE part_events);", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := Attach(ctx, db, table, "part_loaded", now.AddDate(0, -1, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRunner(db, Options{Now: func() time.Time { return now }})
+	if err := r.Register(table); err != nil {
+		t.Fatal(err)
+	}
+	reports, err := r.RunOnce(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || !reflect.DeepEqual(reports[0].Created, []string{"part_events_p202403", "part_events_p202404"}) ||
+		!reflect.DeepEqual(reports[0].Dropped, []string{"part_events_p202401"}) {
+		t.Fatalf("unexpected reports: %+v", reports)
+	}
+	names, err := Partitions(ctx, db, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"part_events_p202403", "part_events_p202404", "part_loaded"}) {
+		t.Fatalf("unexpected partitions: %v", names)
+	}
+	if _, err := db.Exec(ctx, "INSERT INTO part_events VALUES (1, '2024-04-02');", nil); err != nil {
+		t.Fatal(err)
+	}
+}
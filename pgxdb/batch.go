@@ -0,0 +1,70 @@
+package pgxdb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+)
+
+// SendBatch sends all entries in a single round trip. Outside of a
+// transaction the batch runs in an implicit transaction, so an error in one
+// entry fails the entries after it.
+func (d *DB) SendBatch(ctx context.Context, b *sqln.Batch) ([]sqln.BatchResult, error) {
+	pb := &pgx.Batch{}
+	for i, e := range b.Entries {
+		q, args, err := bind(e.Query, e.Params)
+		if err != nil {
+			return nil, errors.Wrapf(err, "batch entry %v", i)
+		}
+		pb.Queue(q, args...)
+	}
+
+	var br pgx.BatchResults
+	if d.tx != nil {
+		br = d.tx.SendBatch(ctx, pb)
+	} else {
+		br = d.Pool.SendBatch(ctx, pb)
+	}
+
+	results := make([]sqln.BatchResult, len(b.Entries))
+	var first error
+	for i, e := range b.Entries {
+		var r sqln.BatchResult
+		switch e.Method {
+		case "Exec":
+			tag, err := br.Exec()
+			r.Result, r.Err = result(tag), err
+		case "Get":
+			rows, err := br.Query()
+			if err == nil {
+				err = scanAPI.ScanOne(e.Dest, rows)
+			}
+			if err != nil && pgxscan.NotFound(err) {
+				err = sql.ErrNoRows
+			}
+			r.Err = err
+		case "Select":
+			rows, err := br.Query()
+			if err == nil {
+				err = scanAPI.ScanAll(e.Dest, rows)
+			}
+			r.Err = err
+		default:
+			r.Err = errors.Errorf("unknown batch method %q", e.Method)
+		}
+		results[i] = r
+
+		if r.Err != nil && first == nil {
+			first = errors.Wrapf(r.Err, "batch entry %v", i)
+		}
+	}
+
+	if err := br.Close(); err != nil && first == nil {
+		first = err
+	}
+	return results, first
+}
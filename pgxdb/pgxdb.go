@@ -0,0 +1,224 @@
+/*
+Package pgxdb implements sqln.DB on top of a native pgx connection pool rather
+than database/sql. Statements are prepared and cached per connection by pgx
+and results use the binary protocol.
+*/
+package pgxdb
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+
+	"github.com/georgysavva/scany/v2/dbscan"
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jmoiron/sqlx"
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+)
+
+// ErrStmtNotSupported is returned by Stmt since pgx manages its own
+// per-connection statement cache.
+var ErrStmtNotSupported = errors.New("pgxdb: named statements are not supported, pgx caches statements per connection")
+
+// scanAPI maps columns the same way sqlx does (db tag, else lowercased field
+// name) so structs behave the same across backends.
+var scanAPI = func() *pgxscan.API {
+	dbscanAPI, err := pgxscan.NewDBScanAPI(dbscan.WithFieldNameMapper(strings.ToLower))
+	if err != nil {
+		panic(err)
+	}
+	api, err := pgxscan.NewAPI(dbscanAPI)
+	if err != nil {
+		panic(err)
+	}
+	return api
+}()
+
+// querier is satisfied by both *pgxpool.Pool and pgx.Tx.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+// DB implements sqln.DB using pgx.
+type DB struct {
+	Pool *pgxpool.Pool
+
+	tx      pgx.Tx
+	txLevel int
+}
+
+var _ sqln.DB = &DB{}
+
+// New wraps a pgx pool.
+func New(pool *pgxpool.Pool) *DB {
+	return &DB{Pool: pool}
+}
+
+func (d *DB) querier() querier {
+	if d.tx != nil {
+		return d.tx
+	}
+	return d.Pool
+}
+
+// bind compiles a named query into a positional one.
+func bind(query string, params interface{}) (string, []interface{}, error) {
+	if params == nil {
+		params = struct{}{}
+	}
+	return sqlx.BindNamed(sqlx.DOLLAR, query, params)
+}
+
+// Exec a SQL statement.
+func (d *DB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	q, args, err := bind(query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := d.querier().Exec(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	return result(tag), nil
+}
+
+// Get a single record. Returns sql.ErrNoRows when no record matches.
+func (d *DB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	q, args, err := bind(query, params)
+	if err != nil {
+		return err
+	}
+
+	if err := scanAPI.Get(ctx, d.querier(), dest, q, args...); err != nil {
+		if pgxscan.NotFound(err) {
+			return sql.ErrNoRows
+		}
+		return err
+	}
+	return nil
+}
+
+// ExecReturning executes a statement and scans its RETURNING columns into
+// dest.
+func (d *DB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	if t := reflect.TypeOf(dest); t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Slice && t.Elem().Elem().Kind() != reflect.Uint8 {
+		return d.Select(ctx, query, dest, params)
+	}
+	return d.Get(ctx, query, dest, params)
+}
+
+// Select multiple records.
+func (d *DB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	q, args, err := bind(query, params)
+	if err != nil {
+		return err
+	}
+
+	return scanAPI.Select(ctx, d.querier(), dest, q, args...)
+}
+
+// Stmt is not supported, see ErrStmtNotSupported.
+func (d *DB) Stmt(query string) (*sqlx.NamedStmt, error) {
+	return nil, ErrStmtNotSupported
+}
+
+// Transact will run the function that is passed in, rolling back all SQL
+// statements if an error is returned.
+// NOTE: Nested transactions are not currently supported and will return an error.
+func (d *DB) Transact(ctx context.Context, opts sql.TxOptions, f func(sqln.DB) error) error {
+	if d.tx != nil {
+		return errors.New("nested tx not currently supported")
+	}
+
+	txOpts, err := txOptions(opts)
+	if err != nil {
+		return err
+	}
+	tx, err := d.Pool.BeginTx(ctx, txOpts)
+	if err != nil {
+		return err
+	}
+
+	txLvl := d.txLevel + 1
+	txd := &DB{Pool: d.Pool, tx: tx, txLevel: txLvl}
+	err = applyTxSettings(ctx, txd)
+	if err == nil {
+		err = f(txd)
+	}
+	if err != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			return errors.Wrapf(err, "tx level %v: rollback", txLvl)
+		}
+		return errors.Wrapf(err, "tx level %v", txLvl)
+	}
+
+	return errors.Wrapf(sqln.CommitError(tx.Commit(ctx)), "tx level %v: commit", txLvl)
+}
+
+func applyTxSettings(ctx context.Context, tx sqln.DB) error {
+	s, ok := sqln.TxSettingsFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return s.Apply(ctx, tx)
+}
+
+func txOptions(opts sql.TxOptions) (pgx.TxOptions, error) {
+	var o pgx.TxOptions
+	switch opts.Isolation {
+	case sql.LevelDefault:
+	case sql.LevelReadUncommitted:
+		o.IsoLevel = pgx.ReadUncommitted
+	case sql.LevelReadCommitted:
+		o.IsoLevel = pgx.ReadCommitted
+	case sql.LevelRepeatableRead, sql.LevelSnapshot:
+		o.IsoLevel = pgx.RepeatableRead
+	case sql.LevelSerializable:
+		o.IsoLevel = pgx.Serializable
+	default:
+		return o, errors.Errorf("pgxdb: unsupported isolation level %v", opts.Isolation)
+	}
+	if opts.ReadOnly {
+		o.AccessMode = pgx.ReadOnly
+	}
+	return o, nil
+}
+
+// result adapts a command tag to sql.Result.
+type result pgconn.CommandTag
+
+func (r result) LastInsertId() (int64, error) {
+	return 0, errors.New("pgxdb: LastInsertId is not supported, use RETURNING")
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return pgconn.CommandTag(r).RowsAffected(), nil
+}
+
+// Dialect is always Postgres.
+func (d *DB) Dialect() sqln.Dialect {
+	return sqln.Postgres
+}
+
+// InTx reports whether d runs in a transaction.
+func (d *DB) InTx() bool {
+	return d.tx != nil
+}
+
+// TxLevel returns the transaction level of d.
+func (d *DB) TxLevel() int {
+	return d.txLevel
+}
+
+// UnsafeTx returns the underlying transaction, or nil outside of one. The
+// transaction must not be committed or rolled back.
+func (d *DB) UnsafeTx() pgx.Tx {
+	return d.tx
+}
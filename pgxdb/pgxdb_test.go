@@ -0,0 +1,74 @@
+package pgxdb
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+)
+
+func testPool(t *testing.T) *pgxpool.Pool {
+	host := "localhost:5432"
+	if h, ok := os.LookupEnv("TEST_DB_HOST"); ok {
+		host = h
+	}
+	pool, err := pgxpool.New(context.Background(), "postgres://postgres:postgres@"+host+"/postgres?sslmode=disable")
+	if err != nil {
+		t.Fatal("unable to create pool:", err)
+	}
+	if err := pool.Ping(context.Background()); err != nil {
+		t.Fatal("unable to connect to localhost db:", err)
+	}
+	return pool
+}
+
+func TestDB(t *testing.T) {
+	pool := testPool(t)
+	defer pool.Close()
+	d := New(pool)
+
+	ctx := context.Background()
+	if _, err := pool.Exec(ctx, "DROP TABLE IF EXISTS pgxdb_abc; CREATE TABLE pgxdb_abc (id INT, x INT, PRIMARY KEY(id));"); err != nil {
+		t.Fatal("unable to create table:", err)
+	}
+
+	const insert = "INSERT INTO pgxdb_abc (id,x) VALUES (:id,:x);"
+	err := d.Transact(ctx, sql.TxOptions{Isolation: sql.LevelSerializable}, func(tx sqln.DB) error {
+		if _, err := tx.Exec(ctx, insert, map[string]interface{}{"id": 1, "x": 1}); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, insert, map[string]interface{}{"id": 1, "x": 1})
+		return err
+	})
+	if sqln.Classify(err) != sqln.ClassUniqueViolation {
+		t.Fatalf("expected unique violation, got %v", err)
+	}
+
+	type abc struct {
+		ID int `db:"id"`
+		X  int `db:"x"`
+	}
+	for i := 1; i <= 2; i++ {
+		if _, err := d.Exec(ctx, insert, abc{ID: i, X: i * 10}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var rows []abc
+	if err := d.Select(ctx, "SELECT id, x FROM pgxdb_abc ORDER BY id;", &rows, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1].X != 20 {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+
+	var row abc
+	err = d.Get(ctx, "SELECT id, x FROM pgxdb_abc WHERE id = :id;", &row, map[string]interface{}{"id": 3})
+	if errors.Cause(err) != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+}
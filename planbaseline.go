@@ -0,0 +1,179 @@
+package sqln
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PlanBaseline configures WithPlanBaseline.
+type PlanBaseline struct {
+	// Path is the JSON file the baseline is saved to and read from.
+	Path string
+	// CostIncrease is the ratio of the estimated cost of a plan to its
+	// baseline above which it is a regression. Defaults to 2.
+	CostIncrease float64
+}
+
+// WithPlanBaseline tracks the plans of registered queries (see Register)
+// against a baseline: SavePlans stores their current plans in b.Path, and
+// CheckPlans reports plans that regressed since, ie. as a release gate run
+// against a production-like database.
+func WithPlanBaseline(b PlanBaseline) Option {
+	if b.CostIncrease <= 0 {
+		b.CostIncrease = 2
+	}
+	return func(d *Database) {
+		d.planBaseline = &b
+	}
+}
+
+// StoredPlan is the plan of a query in a plan baseline.
+type StoredPlan struct {
+	Query string `json:"query"`
+	Plan  string `json:"plan"`
+	// Cost is the estimated total cost of the plan, or zero if the dialect
+	// does not report one (SQLite).
+	Cost     float64  `json:"cost,omitempty"`
+	SeqScans []string `json:"seq_scans,omitempty"`
+}
+
+// PlanRegression is a plan that regressed from its baseline.
+type PlanRegression struct {
+	Query    string
+	Reason   string
+	Baseline StoredPlan
+	Plan     StoredPlan
+}
+
+// PlanRegressionError lists the plans that regressed, returned by
+// CheckPlans.
+type PlanRegressionError struct {
+	Regressions []PlanRegression
+}
+
+func (e *PlanRegressionError) Error() string {
+	msgs := make([]string, len(e.Regressions))
+	for i, r := range e.Regressions {
+		msgs[i] = fmt.Sprintf("%q: %v", r.Query, r.Reason)
+	}
+	return fmt.Sprintf("%v plan regressions: %v", len(msgs), strings.Join(msgs, "; "))
+}
+
+// SavePlans explains every registered query, with NULL params, and saves the
+// plans as the baseline, keyed by Fingerprint.
+func (d *Database) SavePlans(ctx context.Context) error {
+	if d.planBaseline == nil {
+		return errors.New("save plans: no plan baseline")
+	}
+	plans, err := d.currentPlans(ctx)
+	if err != nil {
+		return errors.Wrap(err, "save plans")
+	}
+	b, err := json.MarshalIndent(plans, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "save plans")
+	}
+	return errors.Wrap(os.WriteFile(d.planBaseline.Path, append(b, '\n'), 0o644), "save plans")
+}
+
+// CheckPlans explains every registered query in the baseline and returns a
+// *PlanRegressionError listing those whose plan scans a table sequentially
+// that it did not, or whose estimated cost grew by more than
+// PlanBaseline.CostIncrease. Queries missing from the baseline are not
+// checked.
+func (d *Database) CheckPlans(ctx context.Context) error {
+	if d.planBaseline == nil {
+		return errors.New("check plans: no plan baseline")
+	}
+	b, err := os.ReadFile(d.planBaseline.Path)
+	if err != nil {
+		return errors.Wrap(err, "check plans")
+	}
+	var baseline map[string]StoredPlan
+	if err := json.Unmarshal(b, &baseline); err != nil {
+		return errors.Wrap(err, "check plans")
+	}
+	plans, err := d.currentPlans(ctx)
+	if err != nil {
+		return errors.Wrap(err, "check plans")
+	}
+
+	var regressions []PlanRegression
+	for _, q := range d.registered() {
+		fp := Fingerprint(q)
+		old, ok := baseline[fp]
+		if !ok {
+			continue
+		}
+		if reason, ok := planRegressed(old, plans[fp], d.planBaseline.CostIncrease); ok {
+			regressions = append(regressions, PlanRegression{Query: q, Reason: reason, Baseline: old, Plan: plans[fp]})
+		}
+	}
+	if len(regressions) > 0 {
+		return &PlanRegressionError{Regressions: regressions}
+	}
+	return nil
+}
+
+// planRegressed returns why plan regressed from old, if it did.
+func planRegressed(old, plan StoredPlan, costIncrease float64) (string, bool) {
+	scanned := make(map[string]bool, len(old.SeqScans))
+	for _, t := range old.SeqScans {
+		scanned[t] = true
+	}
+	for _, t := range plan.SeqScans {
+		if !scanned[t] {
+			return "new sequential scan of " + t, true
+		}
+	}
+	if old.Cost > 0 && plan.Cost > old.Cost*costIncrease {
+		return fmt.Sprintf("cost increased from %v to %v", old.Cost, plan.Cost), true
+	}
+	return "", false
+}
+
+// currentPlans explains the registered queries, keyed by fingerprint.
+func (d *Database) currentPlans(ctx context.Context) (map[string]StoredPlan, error) {
+	ctx = withInternal(ctx)
+	plans := make(map[string]StoredPlan)
+	for _, q := range d.registered() {
+		if !explainableRe.MatchString(q) {
+			continue
+		}
+		s, err := d.StmtContext(ctx, q)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%q", q)
+		}
+		params := make(map[string]interface{}, len(s.Params))
+		for _, p := range s.Params {
+			params[p] = nil
+		}
+		plan, err := d.Explain(ctx, q, params, ExplainOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "%q", q)
+		}
+		plans[Fingerprint(q)] = StoredPlan{Query: q, Plan: plan, Cost: planCost(plan), SeqScans: seqScans(d.dialect, plan)}
+	}
+	return plans, nil
+}
+
+// planCostRe matches the total cost of a Postgres ("cost=0.00..35.50") or
+// MySQL ("cost=35.5") plan node.
+var planCostRe = regexp.MustCompile(`cost=(?:[\d.]+\.\.)?([\d.]+)`)
+
+// planCost returns the estimated cost of the root of plan, or zero.
+func planCost(plan string) float64 {
+	m := planCostRe.FindStringSubmatch(plan)
+	if m == nil {
+		return 0
+	}
+	cost, _ := strconv.ParseFloat(m[1], 64)
+	return cost
+}
@@ -0,0 +1,62 @@
+package sqln
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestPlanBaseline(t *testing.T) {
+	base := sqliteDB(t)
+	db := New(base.X, WithPlanBaseline(PlanBaseline{Path: filepath.Join(t.TempDir(), "plans.json")}))
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, q := range []string{
+		"CREATE TABLE baseline_abc (id INTEGER PRIMARY KEY, x INTEGER);",
+		"CREATE INDEX baseline_x ON baseline_abc (x);",
+	} {
+		if _, err := db.Exec(ctx, q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const query = "SELECT id FROM baseline_abc WHERE x = :x;"
+	db.Register(query)
+
+	if err := db.CheckPlans(ctx); err == nil {
+		t.Fatal("expected an error without a saved baseline")
+	}
+	if err := db.SavePlans(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CheckPlans(ctx); err != nil {
+		t.Fatalf("expected unchanged plans to pass, got %v", err)
+	}
+
+	if _, err := db.Exec(ctx, "DROP INDEX baseline_x;", nil); err != nil {
+		t.Fatal(err)
+	}
+	err := db.CheckPlans(ctx)
+	var perr *PlanRegressionError
+	if !errors.As(err, &perr) || len(perr.Regressions) != 1 || perr.Regressions[0].Reason != "new sequential scan of baseline_abc" {
+		t.Fatalf("expected a seq scan regression, got %v", err)
+	}
+}
+
+func TestPlanRegressed(t *testing.T) {
+	old := StoredPlan{Cost: 10}
+	if _, ok := planRegressed(old, StoredPlan{Cost: 15}, 2); ok {
+		t.Error("expected a small cost increase to pass")
+	}
+	if reason, ok := planRegressed(old, StoredPlan{Cost: 25}, 2); !ok || reason != "cost increased from 10 to 25" {
+		t.Errorf("expected a cost regression, got %q", reason)
+	}
+	if cost := planCost("Seq Scan on abc  (cost=0.00..35.50 rows=2550 width=4)"); cost != 35.5 {
+		t.Errorf("unexpected postgres cost %v", cost)
+	}
+	if cost := planCost("-> Table scan on abc  (cost=1.25 rows=10)"); cost != 1.25 {
+		t.Errorf("unexpected mysql cost %v", cost)
+	}
+}
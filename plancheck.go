@@ -0,0 +1,114 @@
+package sqln
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// PlanError is returned when a query's plan scans a table listed in
+// PlanCheck.Tables.
+type PlanError struct {
+	Query string
+	Table string
+	Plan  string
+}
+
+func (e *PlanError) Error() string {
+	return fmt.Sprintf("sequential scan of %v in plan of %q:\n%v", e.Table, e.Query, e.Plan)
+}
+
+// PlanCheck configures WithPlanCheck.
+type PlanCheck struct {
+	// Tables are the (large) tables that must not be scanned sequentially.
+	Tables []string
+}
+
+// WithPlanCheck explains every query on its first execution and fails it with
+// a *PlanError when the plan sequentially scans one of the tables, so missing
+// indexes surface in tests rather than in production. Queries are checked
+// with the params of that execution, so tables should hold enough rows for the
+// planner to prefer an index. It is intended for tests; explaining adds a
+// round trip to the first execution of each query.
+func WithPlanCheck(c PlanCheck) Option {
+	tables := make(map[string]bool, len(c.Tables))
+	for _, t := range c.Tables {
+		tables[strings.ToLower(t)] = true
+	}
+	return func(d *Database) {
+		d.plans = &planCheck{tables: tables, checked: make(map[string]bool)}
+	}
+}
+
+type planCheck struct {
+	tables map[string]bool
+
+	mtx     sync.Mutex
+	checked map[string]bool
+}
+
+var (
+	explainableRe = regexp.MustCompile(`(?i)^\s*(SELECT|INSERT|UPDATE|DELETE|WITH|REPLACE)\b`)
+	seqScanRes    = map[Dialect]*regexp.Regexp{
+		Postgres: regexp.MustCompile(`Seq Scan on (\w+)`),
+		MySQL:    regexp.MustCompile(`Table scan on (\w+)`),
+		// Lines with USING scan an index.
+		SQLite: regexp.MustCompile(`(?m)^.*\bSCAN (?:TABLE )?(\w+)(?:[^\n]*USING)?`),
+	}
+)
+
+// checkPlan explains query if it has not been checked yet.
+func (d *Database) checkPlan(ctx context.Context, query string, params interface{}) error {
+	if d.plans == nil || internal(ctx) || !explainableRe.MatchString(query) {
+		return nil
+	}
+	d.plans.mtx.Lock()
+	checked := d.plans.checked[query]
+	d.plans.mtx.Unlock()
+	if checked {
+		return nil
+	}
+
+	plan, err := d.Explain(ctx, query, params, ExplainOptions{})
+	if err != nil {
+		return errors.Wrap(err, "plan check")
+	}
+	if table, ok := d.plans.seqScan(d.dialect, plan); ok {
+		return &PlanError{Query: query, Table: table, Plan: plan}
+	}
+
+	d.plans.mtx.Lock()
+	d.plans.checked[query] = true
+	d.plans.mtx.Unlock()
+	return nil
+}
+
+// seqScan returns the first checked table scanned sequentially in plan.
+func (c *planCheck) seqScan(dialect Dialect, plan string) (string, bool) {
+	for _, t := range seqScans(dialect, plan) {
+		if c.tables[t] {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// seqScans returns the tables scanned sequentially in plan, in order.
+func seqScans(dialect Dialect, plan string) []string {
+	re, ok := seqScanRes[dialect]
+	if !ok {
+		re = seqScanRes[Postgres]
+	}
+	var tables []string
+	for _, m := range re.FindAllStringSubmatch(plan, -1) {
+		if strings.HasSuffix(m[0], "USING") {
+			continue
+		}
+		tables = append(tables, strings.ToLower(m[1]))
+	}
+	return tables
+}
@@ -0,0 +1,65 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestPlanCheck(t *testing.T) {
+	base := sqliteDB(t)
+	db := New(base.X, WithPlanCheck(PlanCheck{Tables: []string{"Orders"}}))
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, q := range []string{
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, customer INTEGER, note TEXT);",
+		"CREATE INDEX orders_customer ON orders (customer);",
+		"CREATE TABLE small (id INTEGER);",
+	} {
+		if _, err := db.Exec(ctx, q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ids []int
+	if err := db.Select(ctx, "SELECT id FROM orders WHERE customer = :customer;", &ids, map[string]interface{}{"customer": 1}); err != nil {
+		t.Fatalf("expected indexed query to pass, got %v", err)
+	}
+	if err := db.Select(ctx, "SELECT id FROM small;", &ids, nil); err != nil {
+		t.Fatalf("expected scan of unlisted table to pass, got %v", err)
+	}
+
+	err := db.Select(ctx, "SELECT id FROM orders WHERE note = :note;", &ids, map[string]interface{}{"note": "x"})
+	var perr *PlanError
+	if !errors.As(err, &perr) || perr.Table != "orders" {
+		t.Fatalf("expected plan error, got %v", err)
+	}
+	if s := db.Stats(); s.Statements != 5 {
+		t.Fatalf("expected the failing query not to be prepared, got %v statements", s.Statements)
+	}
+}
+
+func TestSeqScan(t *testing.T) {
+	c := &planCheck{tables: map[string]bool{"orders": true}}
+	for _, tc := range []struct {
+		dialect Dialect
+		plan    string
+		want    bool
+	}{
+		{Postgres, "Seq Scan on orders  (cost=0.00..35.50 rows=10 width=4)\n  Filter: (note = 'x'::text)", true},
+		{Postgres, "Parallel Seq Scan on orders o", true},
+		{Postgres, "Index Scan using orders_pkey on orders  (cost=0.15..8.17 rows=1 width=4)", false},
+		{Postgres, "Seq Scan on orders_archive", false},
+		{MySQL, "-> Filter: (orders.note = 'x')  (cost=0.35 rows=1)\n    -> Table scan on orders  (cost=0.35 rows=1)", true},
+		{SQLite, "SCAN orders", true},
+		{SQLite, "SCAN TABLE orders", true},
+		{SQLite, "SCAN orders USING COVERING INDEX orders_customer", false},
+		{SQLite, "SEARCH orders USING INDEX orders_customer (customer=?)", false},
+	} {
+		if _, got := c.seqScan(tc.dialect, tc.plan); got != tc.want {
+			t.Errorf("%v %q: expected %v, got %v", tc.dialect, tc.plan, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package sqln
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/semaphore"
+)
+
+// WithPoolClasses partitions the connection pool between classes of work, ie.
+// {"interactive": 40, "batch": 10}, each bounded to its number of
+// connections. Operations select a class with WithPoolClass; those without a
+// class (or with an unknown one) are only bounded by the pool, so the sum of
+// the classes should leave room for them within MaxOpenConns. Transactions and
+// WithConn hold a connection of their class until they end.
+func WithPoolClasses(classes map[string]int) Option {
+	sems := make(map[string]*semaphore.Weighted, len(classes))
+	for class, n := range classes {
+		sems[class] = semaphore.NewWeighted(int64(n))
+	}
+	return func(d *Database) {
+		d.classes = sems
+	}
+}
+
+// WithPoolClass runs operations with ctx in the pool class (see
+// WithPoolClasses).
+func WithPoolClass(ctx context.Context, class string) context.Context {
+	return context.WithValue(ctx, poolClassKey, class)
+}
+
+// PoolClass returns the pool class of ctx, if any.
+func PoolClass(ctx context.Context) string {
+	class, _ := ctx.Value(poolClassKey).(string)
+	return class
+}
+
+// acquireClass waits for a connection of the pool class of ctx, returning nil
+// when the class is not bounded.
+func (d *Database) acquireClass(ctx context.Context) (func(), error) {
+	s, ok := d.classes[PoolClass(ctx)]
+	if !ok {
+		return nil, nil
+	}
+	if err := s.Acquire(ctx, 1); err != nil {
+		return nil, errors.Wrapf(err, "pool class %v", PoolClass(ctx))
+	}
+	return func() { s.Release(1) }, nil
+}
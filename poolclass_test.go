@@ -0,0 +1,44 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestPoolClasses(t *testing.T) {
+	base := sqliteDB(t)
+	db := New(base.X, WithPoolClasses(map[string]int{"batch": 1}))
+	defer db.Close()
+	ctx := context.Background()
+	batch := WithPoolClass(ctx, "batch")
+
+	err := db.Transact(batch, sql.TxOptions{}, func(tx DB) error {
+		var n int
+		// Operations in the transaction use its connection.
+		if err := tx.Get(batch, "SELECT 1;", &n, nil); err != nil {
+			return err
+		}
+
+		short, cancel := context.WithTimeout(batch, 10*time.Millisecond)
+		defer cancel()
+		if err := db.Get(short, "SELECT 1;", &n, nil); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected batch query to wait for the class, got %v", err)
+		}
+		if err := db.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+			t.Errorf("expected unclassified query to run, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	if err := db.Get(batch, "SELECT 1;", &n, nil); err != nil {
+		t.Fatalf("expected the class to be released, got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Positional is implemented by DBs that can run queries with positional
+// args rather than named params, ie. SQL generated by third-party tools or
+// ported from database/sql code.
+type Positional interface {
+	ExecPositional(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	GetPositional(ctx context.Context, query string, dest interface{}, args ...interface{}) error
+	SelectPositional(ctx context.Context, query string, dest interface{}, args ...interface{}) error
+}
+
+// ExecPositional executes a statement with positional args on db, which must
+// be a Positional.
+func ExecPositional(ctx context.Context, db DB, query string, args ...interface{}) (sql.Result, error) {
+	p, ok := db.(Positional)
+	if !ok {
+		return nil, errors.Errorf("sqln: %T does not support positional args", db)
+	}
+	return p.ExecPositional(ctx, query, args...)
+}
+
+// GetPositional gets a single record with positional args from db, which must
+// be a Positional.
+func GetPositional(ctx context.Context, db DB, query string, dest interface{}, args ...interface{}) error {
+	p, ok := db.(Positional)
+	if !ok {
+		return errors.Errorf("sqln: %T does not support positional args", db)
+	}
+	return p.GetPositional(ctx, query, dest, args...)
+}
+
+// SelectPositional selects multiple records with positional args from db,
+// which must be a Positional.
+func SelectPositional(ctx context.Context, db DB, query string, dest interface{}, args ...interface{}) error {
+	p, ok := db.(Positional)
+	if !ok {
+		return errors.Errorf("sqln: %T does not support positional args", db)
+	}
+	return p.SelectPositional(ctx, query, dest, args...)
+}
+
+// ExecPositional executes a statement with positional args. Placeholders
+// are those of the driver ($1 for Postgres), or ? which is rebound to them.
+// The statement is prepared and cached like those of Exec, apart from them
+// and keyed by the rebound text, and it is tracked, timed and limited as they
+// are, except that it is not explained (see WithPlanCheck and WithSlowPlans).
+// It is prepared as is, so casts (ie. $1::int) and literals containing colons
+// are left alone.
+func (d *Database) ExecPositional(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var res sql.Result
+	err := d.positional(ctx, "Exec", query, func(ctx context.Context, q positionalQuerier, query string) error {
+		var err error
+		res, err = q.ExecContext(ctx, query, args...)
+		return err
+	})
+	return res, err
+}
+
+// GetPositional gets a single record with positional args, see
+// ExecPositional.
+func (d *Database) GetPositional(ctx context.Context, query string, dest interface{}, args ...interface{}) error {
+	err := d.positional(ctx, "Get", query, func(ctx context.Context, q positionalQuerier, query string) error {
+		if isMapDest(dest) {
+			rows, err := q.QueryxContext(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			return scanOne(rows, dest, d.strict.GetOne)
+		}
+		return sqlx.GetContext(ctx, q, dest, query, args...)
+	})
+	return d.notFoundErr(ctx, err)
+}
+
+// SelectPositional selects multiple records with positional args, see
+// ExecPositional.
+func (d *Database) SelectPositional(ctx context.Context, query string, dest interface{}, args ...interface{}) error {
+	return d.positional(ctx, "Select", query, func(ctx context.Context, q positionalQuerier, query string) error {
+		if (d.rowLimit != nil && !internal(ctx)) || isMapDest(dest) {
+			rows, err := q.QueryxContext(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+			if d.rowLimit != nil && !internal(ctx) {
+				return d.limitedScan(ctx, query, rows, dest)
+			}
+			defer rows.Close()
+			return scanAll(rows, dest)
+		}
+		return sqlx.SelectContext(ctx, q, dest, query, args...)
+	})
+}
+
+// positionalQuerier runs positional queries, on a prepared statement (see
+// stmtQuerier) or unprepared.
+type positionalQuerier interface {
+	sqlx.ExecerContext
+	sqlx.QueryerContext
+}
+
+// positional rebinds query and runs it with f, on the cached statement unless
+// d runs queries unprepared (see WithUnprepared).
+func (d *Database) positional(ctx context.Context, method, query string, f func(context.Context, positionalQuerier, string) error) error {
+	return d.runPositional(ctx, method, query, true, f)
+}
+
+// runPositional rebinds query and runs it with f, on the cached statement if
+// prepare is set and d runs queries prepared.
+func (d *Database) runPositional(ctx context.Context, method, query string, prepare bool, f func(context.Context, positionalQuerier, string) error) (err error) {
+	query = d.drv.Rebind(query)
+	defer func() { err = nameErr(ctx, query, d.diagnoseLocks(ctx, query, err)) }()
+	ctx, done, err := d.begin(ctx, method, query)
+	if err != nil {
+		return err
+	}
+	defer done()
+	if d.slowLog != nil {
+		defer d.slowLog.record(ctx, query, time.Now())
+	}
+	defer d.queryStats.measure(query, time.Now(), &err)
+
+	if err := d.checkQuery(ctx, query); err != nil {
+		return err
+	}
+	if !prepare || d.isDynamic(query) || d.scoped() {
+		return f(ctx, d.ext(), query)
+	}
+	s, release, err := d.cache.acquirePositional(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if d.tx != nil {
+		s = d.txStmt(s)
+	}
+	return f(ctx, stmtQuerier{s.Stmt}, query)
+}
+
+// stmtQuerier runs a prepared statement, ignoring the query it is passed.
+type stmtQuerier struct {
+	s *sqlx.Stmt
+}
+
+func (q stmtQuerier) ExecContext(ctx context.Context, _ string, args ...interface{}) (sql.Result, error) {
+	return q.s.ExecContext(ctx, args...)
+}
+
+func (q stmtQuerier) QueryContext(ctx context.Context, _ string, args ...interface{}) (*sql.Rows, error) {
+	return q.s.QueryContext(ctx, args...)
+}
+
+func (q stmtQuerier) QueryxContext(ctx context.Context, _ string, args ...interface{}) (*sqlx.Rows, error) {
+	return q.s.QueryxContext(ctx, args...)
+}
+
+func (q stmtQuerier) QueryRowxContext(ctx context.Context, _ string, args ...interface{}) *sqlx.Row {
+	return q.s.QueryRowxContext(ctx, args...)
+}
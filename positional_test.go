@@ -0,0 +1,104 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/nstogner/psqlxtest"
+	"github.com/pkg/errors"
+)
+
+func TestPositional(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	if _, err := db.ExecPositional(ctx, "CREATE TABLE items (id INTEGER, name TEXT);"); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"a", "b", "c"} {
+		if _, err := db.ExecPositional(ctx, "INSERT INTO items (id, name) VALUES (?, ?);", i+1, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := db.cache.len(); n != 2 {
+		t.Fatalf("expected 2 cached statements, got %v", n)
+	}
+
+	var names []string
+	if err := db.SelectPositional(ctx, "SELECT name FROM items WHERE id > ? ORDER BY id;", &names, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "b" {
+		t.Fatalf("unexpected names %v", names)
+	}
+	var m map[string]interface{}
+	if err := db.GetPositional(ctx, "SELECT id, name FROM items WHERE id = ?;", &m, 3); err != nil {
+		t.Fatal(err)
+	}
+	if m["name"] != "c" {
+		t.Fatalf("unexpected row %v", m)
+	}
+	var name string
+	if err := db.GetPositional(ctx, "SELECT name FROM items WHERE id = ?;", &name, 9); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected no rows, got %v", err)
+	}
+
+	// Colons are not taken for named params, and the statement is not shared
+	// with a named one of the same text.
+	var row struct {
+		Time string `db:"t"`
+		Cast string `db:"c"`
+	}
+	if err := db.GetPositional(ctx, "SELECT '12:30' AS t, 'a::b' AS c;", &row); err != nil {
+		t.Fatal(err)
+	}
+	if row.Time != "12:30" || row.Cast != "a::b" {
+		t.Fatalf("unexpected literals %+v", row)
+	}
+	var n int
+	if err := db.GetPositional(ctx, "SELECT 1;", &n); err != nil {
+		t.Fatal(err)
+	}
+	before := db.cache.len()
+	if err := db.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	if db.cache.len() != before+1 {
+		t.Fatal("expected the named statement to be cached apart from the positional one")
+	}
+
+	err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		if _, err := ExecPositional(ctx, tx, "DELETE FROM items WHERE id = ?;", 1); err != nil {
+			return err
+		}
+		return SelectPositional(ctx, tx, "SELECT name FROM items ORDER BY id;", &names)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "b" {
+		t.Fatalf("unexpected names %v", names)
+	}
+
+	if _, err := ExecPositional(ctx, Wrap(db, Guard(GuardOptions{})), "SELECT 1;"); err == nil {
+		t.Fatal("expected an error for a DB that is not Positional")
+	}
+}
+
+func TestPositionalPostgres(t *testing.T) {
+	dbx, dropx := psqlxtest.TmpDB(t)
+	defer dropx()
+	db := New(dbx)
+	defer db.Close()
+	ctx := context.Background()
+
+	var n int
+	if err := db.GetPositional(ctx, "SELECT $1::int + 1;", &n, "41"); err != nil || n != 42 {
+		t.Fatalf("unexpected result %v, %v", n, err)
+	}
+	var s string
+	if err := db.GetPositional(ctx, "SELECT 'a::b' || ?::text;", &s, ":c"); err != nil || s != "a::b:c" {
+		t.Fatalf("unexpected result %q, %v", s, err)
+	}
+}
@@ -0,0 +1,91 @@
+package sqln
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrPrepareFailed is matched (with errors.Is) by errors preparing a
+// statement, which unwrap to the driver's error. A statement that fails to
+// prepare, ie. because of a syntax error, fails the same way without
+// contacting the database for a backoff period afterwards (see
+// WithPrepareBackoff). Connection and context errors are not remembered.
+var ErrPrepareFailed = errors.New("sqln: prepare failed")
+
+type prepareError struct {
+	err error
+}
+
+func (e *prepareError) Error() string {
+	return ErrPrepareFailed.Error() + ": " + e.err.Error()
+}
+
+func (e *prepareError) Is(target error) bool {
+	return target == ErrPrepareFailed
+}
+
+func (e *prepareError) Unwrap() error {
+	return e.err
+}
+
+// WithPrepareBackoff sets how long a failed prepare is remembered, starting at
+// min and doubling with each consecutive failure up to max. Defaults to one
+// second and one minute. A min of zero disables remembering failures.
+// Invalidating a statement (ie. after a migration) forgets its failure.
+func WithPrepareBackoff(min, max time.Duration) Option {
+	return func(d *Database) {
+		d.cache.failMin, d.cache.failMax = min, max
+	}
+}
+
+// maxPrepareFailures bounds the number of failures remembered.
+const maxPrepareFailures = 1024
+
+// prepareFailure is a remembered prepare error.
+type prepareFailure struct {
+	err     error
+	until   time.Time
+	backoff time.Duration
+}
+
+// failedLocked returns the remembered error of key, if it has not expired.
+func (c *stmtCache) failedLocked(key string, now time.Time) error {
+	f, ok := c.failures[key]
+	if !ok || now.After(f.until) {
+		return nil
+	}
+	return f.err
+}
+
+// failLocked remembers the prepare error of key, doubling its backoff.
+func (c *stmtCache) failLocked(key string, err error, now time.Time) {
+	if c.failMin <= 0 || isContextErr(err) || Classify(err) == ClassConnection || Classify(err) == ClassTooManyConnections {
+		return
+	}
+	f, ok := c.failures[key]
+	if !ok {
+		if len(c.failures) >= maxPrepareFailures {
+			for k, f := range c.failures {
+				if now.After(f.until) {
+					delete(c.failures, k)
+				}
+			}
+			if len(c.failures) >= maxPrepareFailures {
+				return
+			}
+		}
+		f = &prepareFailure{}
+		c.failures[key] = f
+	}
+	switch {
+	case f.backoff == 0:
+		f.backoff = c.failMin
+	case f.backoff < c.failMax:
+		f.backoff *= 2
+	}
+	if f.backoff > c.failMax {
+		f.backoff = c.failMax
+	}
+	f.err, f.until = err, now.Add(f.backoff)
+}
@@ -0,0 +1,301 @@
+package sqln
+
+import (
+	"bufio"
+	"context"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Queries holds named queries loaded from .sql files. A file may define
+// several queries, each starting at a "-- name: <name>" line; a file without
+// any is a single query named after the file (without the extension). Other
+// comments are kept as part of the query.
+type Queries struct {
+	fsys     fs.FS
+	patterns []string
+
+	mtx     sync.RWMutex
+	queries map[string]string
+	// files holds the modification time of every loaded file.
+	files map[string]time.Time
+}
+
+// LoadQueries loads queries from files in fsys matching the glob patterns
+// (defaults to "*.sql").
+func LoadQueries(fsys fs.FS, patterns ...string) (*Queries, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"*.sql"}
+	}
+	q := &Queries{fsys: fsys, patterns: patterns}
+	queries, files, err := q.load()
+	if err != nil {
+		return nil, err
+	}
+	q.queries, q.files = queries, files
+	return q, nil
+}
+
+// Get returns the named query.
+func (q *Queries) Get(name string) (string, bool) {
+	q.mtx.RLock()
+	defer q.mtx.RUnlock()
+	s, ok := q.queries[name]
+	return s, ok
+}
+
+// MustGet returns the named query, panicking if it does not exist.
+func (q *Queries) MustGet(name string) string {
+	s, ok := q.Get(name)
+	if !ok {
+		panic("sqln: unknown query " + name)
+	}
+	return s
+}
+
+// All returns every query, ie. to register them with a Database for
+// ValidateAll.
+func (q *Queries) All() []string {
+	q.mtx.RLock()
+	defer q.mtx.RUnlock()
+	all := make([]string, 0, len(q.queries))
+	for _, s := range q.queries {
+		all = append(all, s)
+	}
+	sort.Strings(all)
+	return all
+}
+
+// NameOf returns the name of query, ie. to label queries in hooks and logs.
+func (q *Queries) NameOf(query string) (string, bool) {
+	q.mtx.RLock()
+	defer q.mtx.RUnlock()
+	for name, s := range q.queries {
+		if s == query {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Names returns the sorted names of all queries.
+func (q *Queries) Names() []string {
+	q.mtx.RLock()
+	defer q.mtx.RUnlock()
+	names := make([]string, 0, len(q.queries))
+	for name := range q.queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (q *Queries) load() (map[string]string, map[string]time.Time, error) {
+	queries := make(map[string]string)
+	files := make(map[string]time.Time)
+	for _, pattern := range q.patterns {
+		matches, err := fs.Glob(q.fsys, pattern)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "glob %q", pattern)
+		}
+		for _, file := range matches {
+			if _, ok := files[file]; ok {
+				continue
+			}
+			info, err := fs.Stat(q.fsys, file)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "stat %v", file)
+			}
+			b, err := fs.ReadFile(q.fsys, file)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "read %v", file)
+			}
+			if err := parseQueries(file, string(b), queries); err != nil {
+				return nil, nil, err
+			}
+			files[file] = info.ModTime()
+		}
+	}
+	return queries, files, nil
+}
+
+func parseQueries(file, src string, into map[string]string) error {
+	var (
+		name  string
+		body  strings.Builder
+		named bool
+	)
+	flush := func() error {
+		if name == "" {
+			return nil
+		}
+		if _, ok := into[name]; ok {
+			return errors.Errorf("%v: duplicate query %q", file, name)
+		}
+		into[name] = strings.TrimSpace(body.String())
+		body.Reset()
+		return nil
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(src))
+	for sc.Scan() {
+		line := sc.Text()
+		if n, ok := strings.CutPrefix(strings.TrimSpace(line), "-- name:"); ok {
+			if err := flush(); err != nil {
+				return err
+			}
+			// Annotations after the name (ie. ":one" for sqln-gen) are
+			// ignored.
+			if fields := strings.Fields(n); len(fields) > 0 {
+				name = fields[0]
+			}
+			named = true
+			continue
+		}
+		body.WriteString(line)
+		body.WriteByte('\n')
+	}
+	if err := sc.Err(); err != nil {
+		return errors.Wrapf(err, "parse %v", file)
+	}
+
+	if !named {
+		name = strings.TrimSuffix(path.Base(file), path.Ext(file))
+	}
+	return flush()
+}
+
+// QueryReload describes a reload of changed query files.
+type QueryReload struct {
+	Files []string
+	// Changed lists queries that were added, removed or modified.
+	Changed []string
+	// Invalid maps changed queries to their prepare errors.
+	Invalid map[string]error
+	// Err is set when the files could not be loaded, in which case the
+	// previous queries remain in use.
+	Err error
+}
+
+// Watch polls for changed query files until ctx is done, intended for
+// development. On change the queries are reloaded, statements cached by db
+// for the previous text are invalidated, and changed queries are re-prepared
+// to validate them. Each reload is passed to onReload (which may be nil).
+func (q *Queries) Watch(ctx context.Context, db *Database, interval time.Duration, onReload func(QueryReload)) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		if r, ok := q.reload(ctx, db); ok && onReload != nil {
+			onReload(r)
+		}
+	}
+}
+
+// reload reloads the queries if any file changed.
+func (q *Queries) reload(ctx context.Context, db *Database) (QueryReload, bool) {
+	var r QueryReload
+	changed, err := q.changedFiles()
+	if err != nil {
+		r.Err = err
+		return r, true
+	}
+	if len(changed) == 0 {
+		return r, false
+	}
+	r.Files = changed
+
+	queries, files, err := q.load()
+	if err != nil {
+		r.Err = err
+		return r, true
+	}
+
+	q.mtx.Lock()
+	old := q.queries
+	q.queries, q.files = queries, files
+	q.mtx.Unlock()
+
+	for name, s := range old {
+		if queries[name] != s {
+			r.Changed = append(r.Changed, name)
+			if db != nil {
+				db.cache.invalidate(s)
+			}
+		}
+	}
+	for name := range queries {
+		if _, ok := old[name]; !ok {
+			r.Changed = append(r.Changed, name)
+		}
+	}
+	sort.Strings(r.Changed)
+
+	if db != nil {
+		for _, name := range r.Changed {
+			s, ok := queries[name]
+			if !ok {
+				continue
+			}
+			if _, err := db.StmtContext(ctx, s); err != nil {
+				if r.Invalid == nil {
+					r.Invalid = make(map[string]error)
+				}
+				r.Invalid[name] = err
+			}
+		}
+	}
+	return r, true
+}
+
+// changedFiles returns files that were added, removed or modified since the
+// last load.
+func (q *Queries) changedFiles() ([]string, error) {
+	q.mtx.RLock()
+	prev := q.files
+	q.mtx.RUnlock()
+
+	seen := make(map[string]bool)
+	var changed []string
+	for _, pattern := range q.patterns {
+		matches, err := fs.Glob(q.fsys, pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "glob %q", pattern)
+		}
+		for _, file := range matches {
+			if seen[file] {
+				continue
+			}
+			seen[file] = true
+			info, err := fs.Stat(q.fsys, file)
+			if err != nil {
+				return nil, errors.Wrapf(err, "stat %v", file)
+			}
+			if mod, ok := prev[file]; !ok || !mod.Equal(info.ModTime()) {
+				changed = append(changed, file)
+			}
+		}
+	}
+	for file := range prev {
+		if !seen[file] {
+			changed = append(changed, file)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
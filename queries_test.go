@@ -0,0 +1,71 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestQueries(t *testing.T) {
+	now := time.Now()
+	fsys := fstest.MapFS{
+		"users.sql": &fstest.MapFile{ModTime: now, Data: []byte(`
+-- name: one
+SELECT 1;
+
+-- name: two
+SELECT 2;
+`)},
+		"three.sql": &fstest.MapFile{ModTime: now, Data: []byte("SELECT 3;\n")},
+	}
+
+	q, err := LoadQueries(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := q.Names(); len(names) != 3 || names[0] != "one" || names[1] != "three" || names[2] != "two" {
+		t.Fatalf("unexpected names: %v", names)
+	}
+	if name, ok := q.NameOf(q.MustGet("three")); !ok || name != "three" {
+		t.Fatalf("unexpected name: %q", name)
+	}
+
+	db := sqliteDB(t)
+	ctx := context.Background()
+	var n int
+	if err := db.Get(ctx, q.MustGet("two"), &n, nil); err != nil || n != 2 {
+		t.Fatalf("expected 2, got %v (%v)", n, err)
+	}
+
+	if _, ok := q.reload(context.Background(), db); ok {
+		t.Fatal("expected no reload without changes")
+	}
+
+	fsys["users.sql"] = &fstest.MapFile{ModTime: now.Add(time.Second), Data: []byte(`
+-- name: one
+SELECT 1;
+
+-- name: two
+SELECT 22;
+
+-- name: broken
+SELEC 4;
+`)}
+	r, ok := q.reload(context.Background(), db)
+	if !ok || r.Err != nil {
+		t.Fatalf("expected reload, got %+v", r)
+	}
+	if len(r.Changed) != 2 || r.Changed[0] != "broken" || r.Changed[1] != "two" {
+		t.Fatalf("unexpected changed queries: %v", r.Changed)
+	}
+	if len(r.Invalid) != 1 || r.Invalid["broken"] == nil {
+		t.Fatalf("expected broken to be invalid, got %v", r.Invalid)
+	}
+	if _, ok := db.cache.entries["SELECT 2;"]; ok {
+		t.Fatal("expected previous statement to be invalidated")
+	}
+	if err := db.Get(ctx, q.MustGet("two"), &n, nil); err != nil || n != 22 {
+		t.Fatalf("expected 22, got %v (%v)", n, err)
+	}
+}
@@ -0,0 +1,153 @@
+package sqln
+
+import (
+	"context"
+	"expvar"
+	"sort"
+	"sync"
+	"time"
+)
+
+// QueryStats summarizes the recent executions of a query.
+type QueryStats struct {
+	// Query is the first text seen with the fingerprint.
+	Query       string
+	Fingerprint string
+	// Name is the query name, if any (see NameQuery).
+	Name   string
+	Count  int64
+	Errors int64
+	// P50, P95 and P99 are latency percentiles over the most recent
+	// executions (see WithQueryStats).
+	P50          time.Duration
+	P95          time.Duration
+	P99          time.Duration
+	LastExecuted time.Time
+}
+
+// WithQueryStats keeps statistics for every query run through Exec, Get and
+// Select (see QueryStats), combining queries with the same Fingerprint, with
+// latency percentiles over the last window executions of each query
+// (defaults to 1000). Unprepared queries are not included, as their text is
+// expected to vary.
+func WithQueryStats(window int) Option {
+	if window <= 0 {
+		window = 1000
+	}
+	return func(d *Database) {
+		d.queryStats = &queryStats{window: window, queries: make(map[string]*queryStat)}
+	}
+}
+
+type queryStats struct {
+	window int
+
+	mtx     sync.Mutex
+	queries map[string]*queryStat
+}
+
+type queryStat struct {
+	query         string
+	count, errors int64
+	last          time.Time
+	// latencies is a ring of the most recent latencies.
+	latencies []time.Duration
+	next      int
+}
+
+// measure records an execution of query that started at start and failed
+// with *err.
+func (s *queryStats) measure(query string, start time.Time, err *error) {
+	if s == nil {
+		return
+	}
+	elapsed := time.Since(start)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	fp := Fingerprint(query)
+	q, ok := s.queries[fp]
+	if !ok {
+		q = &queryStat{query: query}
+		s.queries[fp] = q
+	}
+	q.count++
+	if *err != nil {
+		q.errors++
+	}
+	q.last = start
+	if len(q.latencies) < s.window {
+		q.latencies = append(q.latencies, elapsed)
+	} else {
+		q.latencies[q.next] = elapsed
+		q.next = (q.next + 1) % s.window
+	}
+}
+
+// QueryStats returns the statistics of every query, the most executed first.
+// It returns nil unless WithQueryStats is used.
+func (d *Database) QueryStats() []QueryStats {
+	s := d.queryStats
+	if s == nil {
+		return nil
+	}
+
+	s.mtx.Lock()
+	stats := make([]QueryStats, 0, len(s.queries))
+	for fp, q := range s.queries {
+		lat := append([]time.Duration(nil), q.latencies...)
+		sort.Slice(lat, func(i, j int) bool { return lat[i] < lat[j] })
+		stats = append(stats, QueryStats{
+			Query:        q.query,
+			Fingerprint:  fp,
+			Count:        q.count,
+			Errors:       q.errors,
+			P50:          percentile(lat, 50),
+			P95:          percentile(lat, 95),
+			P99:          percentile(lat, 99),
+			LastExecuted: q.last,
+		})
+	}
+	s.mtx.Unlock()
+
+	for i := range stats {
+		stats[i].Name = QueryName(context.Background(), stats[i].Query)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Query < stats[j].Query
+	})
+	return stats
+}
+
+// ResetQueryStats discards the statistics of every query.
+func (d *Database) ResetQueryStats() {
+	if s := d.queryStats; s != nil {
+		s.mtx.Lock()
+		s.queries = make(map[string]*queryStat)
+		s.mtx.Unlock()
+	}
+}
+
+// PublishQueryStats publishes the output of QueryStats as the expvar name,
+// served with the other variables at /debug/vars. Like expvar.Publish it
+// panics if name is already in use.
+func (d *Database) PublishQueryStats(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return d.QueryStats()
+	}))
+}
+
+// percentile returns the p-th percentile of sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
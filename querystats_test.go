@@ -0,0 +1,63 @@
+package sqln
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+)
+
+func TestQueryStats(t *testing.T) {
+	base := sqliteDB(t)
+	db := New(base.X, WithQueryStats(10))
+	defer db.Close()
+	ctx := context.Background()
+
+	var n int
+	for i := 0; i < 3; i++ {
+		if err := db.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Get(ctx, "SELECT * FROM stats_missing;", &n, nil); err == nil {
+		t.Fatal("expected error")
+	}
+
+	stats := db.QueryStats()
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 queries, got %v", stats)
+	}
+	if s := stats[0]; s.Query != "SELECT 1;" || s.Count != 3 || s.Errors != 0 || s.P99 < s.P50 || time.Since(s.LastExecuted) > time.Minute {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+	if s := stats[1]; s.Count != 1 || s.Errors != 1 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+
+	db.PublishQueryStats("sqln_test_query_stats")
+	var published []QueryStats
+	if err := json.Unmarshal([]byte(expvar.Get("sqln_test_query_stats").String()), &published); err != nil || len(published) != 2 {
+		t.Fatalf("unexpected published stats: %v %v", published, err)
+	}
+
+	db.ResetQueryStats()
+	if stats := db.QueryStats(); len(stats) != 0 {
+		t.Fatalf("expected no stats after reset, got %v", stats)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var lat []time.Duration
+	for i := 1; i <= 100; i++ {
+		lat = append(lat, time.Duration(i))
+	}
+	for p, want := range map[int]time.Duration{50: 50, 95: 95, 99: 99} {
+		if got := percentile(lat, p); got != want {
+			t.Errorf("p%v: expected %v, got %v", p, want, got)
+		}
+	}
+	if got := percentile(lat[:1], 99); got != 1 {
+		t.Errorf("expected 1, got %v", got)
+	}
+}
@@ -0,0 +1,113 @@
+package sqln
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrQueryNotAllowedNow is returned for a query outside the windows of its
+// QueryPolicy, or run on the primary when its policy is ReplicaOnly.
+var ErrQueryNotAllowedNow = errors.New("sqln: query not allowed now")
+
+// QueryPolicy restricts where and when a named query runs (see
+// WithQueryPolicies), ie. to keep heavy analytics off the primary of a shared
+// cluster during business hours.
+type QueryPolicy struct {
+	// Windows are the times the query may run. Empty allows any time.
+	Windows []TimeWindow
+	// ReplicaOnly only runs the query on a replica of a Replicated DB,
+	// regardless of read-your-writes or replica lag. Replicated routes by
+	// the policies of its Primary.
+	ReplicaOnly bool
+}
+
+// TimeWindow is a daily period, ie. {Start: 22 * time.Hour, End: 6 *
+// time.Hour} for 10pm to 6am.
+type TimeWindow struct {
+	// Start and End are offsets from midnight. A window that ends before it
+	// starts spans midnight.
+	Start, End time.Duration
+	// Days limits the window to days on which it starts. Empty is every day.
+	Days []time.Weekday
+	// Location defaults to UTC.
+	Location *time.Location
+}
+
+// contains reports whether t is within the window.
+func (w TimeWindow) contains(t time.Time) bool {
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	offset := t.Sub(midnight)
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End && w.on(t.Weekday())
+	}
+	// Spanning midnight: the evening of a day, or the morning after it.
+	return offset >= w.Start && w.on(t.Weekday()) ||
+		offset < w.End && w.on(midnight.AddDate(0, 0, -1).Weekday())
+}
+
+func (w TimeWindow) on(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// WithQueryPolicies restricts the queries named in policies (see QueryName).
+// Queries run outside their policy fail with ErrQueryNotAllowedNow.
+func WithQueryPolicies(policies map[string]QueryPolicy) Option {
+	return func(d *Database) {
+		d.policies = policies
+	}
+}
+
+// onReplica marks ctx as routed to a replica by Replicated.
+func onReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaKey, true)
+}
+
+// replicaOnly reports whether the policy of query restricts it to replicas.
+func (d *Database) replicaOnly(ctx context.Context, query string) bool {
+	if len(d.policies) == 0 {
+		return false
+	}
+	p, ok := d.policies[QueryName(ctx, query)]
+	return ok && p.ReplicaOnly
+}
+
+// checkPolicy rejects query if it is run outside its QueryPolicy.
+func (d *Database) checkPolicy(ctx context.Context, query string) error {
+	if len(d.policies) == 0 {
+		return nil
+	}
+	p, ok := d.policies[QueryName(ctx, query)]
+	if !ok {
+		return nil
+	}
+	if p.ReplicaOnly {
+		if v, _ := ctx.Value(replicaKey).(bool); !v {
+			return ErrQueryNotAllowedNow
+		}
+	}
+	if len(p.Windows) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for _, w := range p.Windows {
+		if w.contains(now) {
+			return nil
+		}
+	}
+	return ErrQueryNotAllowedNow
+}
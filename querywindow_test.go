@@ -0,0 +1,82 @@
+package sqln
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeWindow(t *testing.T) {
+	// 2024-01-05 is a Friday.
+	at := func(day, hour int) time.Time { return time.Date(2024, 1, day, hour, 30, 0, 0, time.UTC) }
+	business := TimeWindow{Start: 9 * time.Hour, End: 17 * time.Hour, Days: []time.Weekday{time.Monday, time.Friday}}
+	night := TimeWindow{Start: 22 * time.Hour, End: 6 * time.Hour, Days: []time.Weekday{time.Friday}}
+	for _, c := range []struct {
+		w    TimeWindow
+		t    time.Time
+		want bool
+	}{
+		{business, at(5, 10), true},
+		{business, at(5, 17), false},
+		{business, at(6, 10), false},
+		{night, at(5, 23), true},
+		{night, at(6, 2), true},
+		{night, at(5, 2), false},
+		{night, at(6, 23), false},
+		{TimeWindow{Start: 9 * time.Hour, End: 10 * time.Hour, Location: time.FixedZone("", -5*3600)}, at(5, 14), true},
+	} {
+		if got := c.w.contains(c.t); got != c.want {
+			t.Errorf("%+v contains %v = %v, want %v", c.w, c.t, got, c.want)
+		}
+	}
+}
+
+func TestQueryPolicies(t *testing.T) {
+	now := time.Now().UTC()
+	hour := time.Duration(now.Hour()) * time.Hour
+	open := TimeWindow{Start: hour, End: hour + time.Hour}
+	closed := TimeWindow{Start: hour + time.Hour, End: hour + 2*time.Hour}
+	if hour >= 22*time.Hour {
+		closed = TimeWindow{Start: hour - 2*time.Hour, End: hour}
+	}
+	policies := map[string]QueryPolicy{
+		"open":      {Windows: []TimeWindow{closed, open}},
+		"closed":    {Windows: []TimeWindow{closed}},
+		"analytics": {ReplicaOnly: true},
+	}
+	primary := New(sqliteDB(t).X, WithQueryPolicies(policies))
+	defer primary.Close()
+	replica := New(sqliteDB(t).X, WithQueryPolicies(policies))
+	defer replica.Close()
+	ctx := context.Background()
+
+	var n int
+	if err := primary.Get(WithQueryName(ctx, "open"), "SELECT 1;", &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.Get(WithQueryName(ctx, "closed"), "SELECT 1;", &n, nil); !errors.Is(err, ErrQueryNotAllowedNow) {
+		t.Fatalf("expected ErrQueryNotAllowedNow, got %v", err)
+	}
+	analytics := WithQueryName(ctx, "analytics")
+	if err := primary.Get(analytics, "SELECT 1;", &n, nil); !errors.Is(err, ErrQueryNotAllowedNow) {
+		t.Fatalf("expected ErrQueryNotAllowedNow on the primary, got %v", err)
+	}
+
+	r := &Replicated{Primary: primary, Replicas: []*Database{replica}, RetryOnPrimary: true}
+	if err := r.Get(analytics, "SELECT 1;", &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	sticky := WithReadYourWrites(analytics)
+	if _, err := r.Exec(sticky, "SELECT 1;", nil); err == nil {
+		t.Fatal("expected ErrQueryNotAllowedNow writing on the primary")
+	}
+	sticky.Value(stickyKey).(*stickyState).wrote = 1
+	if err := r.Select(sticky, "SELECT 1;", &[]int{}, nil); err != nil {
+		t.Fatalf("expected the replica despite the write, got %v", err)
+	}
+	r.Replicas = nil
+	if err := r.Get(analytics, "SELECT 1;", &n, nil); !errors.Is(err, ErrQueryNotAllowedNow) {
+		t.Fatalf("expected ErrQueryNotAllowedNow without replicas, got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrRateLimited is returned by the RateLimit middleware for operations over
+// their rate.
+var ErrRateLimited = errors.New("sqln: rate limited")
+
+// Rate is a token bucket refilled with Limit tokens per second, holding at
+// most Burst (at least 1).
+type Rate struct {
+	Limit float64
+	Burst int
+}
+
+// RateLimits configures the RateLimit middleware.
+type RateLimits struct {
+	// PerQuery limits named queries (see QueryName), each in a bucket of
+	// its own.
+	PerQuery map[string]Rate
+	// Default, if set, limits all other operations in one shared bucket.
+	Default *Rate
+	// Wait queues operations over their rate until a token is available
+	// rather than failing them, unless it would outlast the context's
+	// deadline.
+	Wait bool
+	// Clock defaults to SystemClock.
+	Clock Clock
+}
+
+// RateLimit returns a middleware that limits the rate of operations by query
+// name with token buckets, failing those over their rate with
+// ErrRateLimited (see RateLimits.Wait), ie. to protect a fragile table from
+// bursty callers sharing a Database. Limits apply within transactions too.
+func RateLimit(l RateLimits) Middleware {
+	if l.Clock == nil {
+		l.Clock = SystemClock
+	}
+	now := l.Clock.Now()
+	r := &rateLimiter{wait: l.Wait, clock: l.Clock, perQuery: make(map[string]*bucket, len(l.PerQuery))}
+	for name, rate := range l.PerQuery {
+		r.perQuery[name] = newBucket(rate, now)
+	}
+	if l.Default != nil {
+		r.fallback = newBucket(*l.Default, now)
+	}
+	return func(db DB) DB {
+		return newRateDB(db, r)
+	}
+}
+
+func newRateDB(db DB, r *rateLimiter) DB {
+	return &rateDB{BaseDB: BaseDB{DB: db, Wrap: func(tx DB) DB { return newRateDB(tx, r) }}, limiter: r}
+}
+
+type rateLimiter struct {
+	wait     bool
+	clock    Clock
+	perQuery map[string]*bucket
+	fallback *bucket
+}
+
+// take takes a token for the named query, waiting for it if configured.
+func (r *rateLimiter) take(ctx context.Context, name string) error {
+	b, ok := r.perQuery[name]
+	if !ok {
+		b = r.fallback
+	}
+	if b == nil {
+		return nil
+	}
+
+	now := r.clock.Now()
+	var maxDelay time.Duration
+	if r.wait {
+		maxDelay = math.MaxInt64
+		if deadline, ok := ctx.Deadline(); ok {
+			maxDelay = time.Until(deadline)
+		}
+	}
+	delay, ok := b.reserve(now, maxDelay)
+	if !ok {
+		return errors.Wrapf(ErrRateLimited, "%v", name)
+	}
+	if delay > 0 && !wait(r.clock, delay, ctx.Done()) {
+		b.cancel()
+		return ctx.Err()
+	}
+	return nil
+}
+
+type bucket struct {
+	limit float64
+	burst float64
+
+	mtx    sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(r Rate, now time.Time) *bucket {
+	burst := float64(r.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &bucket{limit: r.Limit, burst: burst, tokens: burst, last: now}
+}
+
+// reserve takes a token, returning how long to wait for it. A token that is
+// not available at now is only reserved if the wait is below maxDelay.
+func (b *bucket) reserve(now time.Time, maxDelay time.Duration) (time.Duration, bool) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.limit)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	if b.limit <= 0 {
+		return 0, false
+	}
+	delay := time.Duration((1 - b.tokens) / b.limit * float64(time.Second))
+	if delay >= maxDelay {
+		return 0, false
+	}
+	b.tokens--
+	return delay, true
+}
+
+// cancel returns a reserved token that was not used.
+func (b *bucket) cancel() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+type rateDB struct {
+	BaseDB
+	limiter *rateLimiter
+}
+
+func (d *rateDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	if err := d.limiter.take(ctx, QueryName(ctx, query)); err != nil {
+		return nil, err
+	}
+	return d.DB.Exec(ctx, query, params)
+}
+
+func (d *rateDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.limiter.take(ctx, QueryName(ctx, query)); err != nil {
+		return err
+	}
+	return d.DB.ExecReturning(ctx, query, dest, params)
+}
+
+func (d *rateDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.limiter.take(ctx, QueryName(ctx, query)); err != nil {
+		return err
+	}
+	return d.DB.Get(ctx, query, dest, params)
+}
+
+func (d *rateDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.limiter.take(ctx, QueryName(ctx, query)); err != nil {
+		return err
+	}
+	return d.DB.Select(ctx, query, dest, params)
+}
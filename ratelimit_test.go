@@ -0,0 +1,74 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestRateLimit(t *testing.T) {
+	clock := NewTestClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	db := Wrap(sqliteDB(t), RateLimit(RateLimits{
+		PerQuery: map[string]Rate{"fragile": {Limit: 1, Burst: 2}},
+		Clock:    clock,
+	}))
+	ctx := context.Background()
+	fragile := WithQueryName(ctx, "fragile")
+
+	var n int
+	for i := 0; i < 2; i++ {
+		if err := db.Get(fragile, "SELECT 1;", &n, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Get(fragile, "SELECT 1;", &n, nil); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected the burst to be exhausted, got %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := db.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+			t.Fatalf("expected unnamed queries not to be limited, got %v", err)
+		}
+	}
+	clock.Advance(time.Second)
+	if err := db.Get(fragile, "SELECT 1;", &n, nil); err != nil {
+		t.Fatalf("expected a token after a second, got %v", err)
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	clock := NewTestClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	db := Wrap(sqliteDB(t), RateLimit(RateLimits{
+		Default: &Rate{Limit: 1},
+		Wait:    true,
+		Clock:   clock,
+	}))
+	ctx := context.Background()
+
+	var n int
+	if err := db.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- db.Get(ctx, "SELECT 1;", &n, nil) }()
+	for clock.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("expected the operation to wait, got %v", err)
+	default:
+	}
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// Waiting past the deadline fails right away.
+	short, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	if err := db.Get(short, "SELECT 1;", &n, nil); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected a wait past the deadline to fail, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package sqln
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Record is a single captured query. Query logs are written as one JSON
+// encoded Record per line.
+type Record struct {
+	Time time.Time `json:"time"`
+	// Method is the DB method that ran the query (ie. Exec, Get or Select).
+	Method string `json:"method"`
+	// Name is the query name, when known.
+	Name   string                 `json:"name,omitempty"`
+	Query  string                 `json:"query"`
+	Params map[string]interface{} `json:"params,omitempty"`
+	// ParamsHash identifies the params even when some are redacted.
+	ParamsHash string `json:"params_hash,omitempty"`
+	// Result is the captured outcome, when recorded by a Recorder.
+	Result *RecordResult `json:"result,omitempty"`
+}
+
+// RecordResult is the outcome of a recorded query.
+type RecordResult struct {
+	// Rows is the JSON encoded dest of a Get or Select.
+	Rows         json.RawMessage `json:"rows,omitempty"`
+	RowsAffected int64           `json:"rows_affected,omitempty"`
+	LastInsertID int64           `json:"last_insert_id,omitempty"`
+	Err          string          `json:"err,omitempty"`
+}
+
+// ReadRecords decodes a query log. Numbers in params are decoded as
+// json.Number to preserve precision.
+func ReadRecords(r io.Reader) ([]Record, error) {
+	var recs []Record
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(sc.Bytes()))
+		dec.UseNumber()
+		var rec Record
+		if err := dec.Decode(&rec); err != nil {
+			return nil, errors.Wrapf(err, "line %v", line)
+		}
+		recs = append(recs, rec)
+	}
+	return recs, sc.Err()
+}
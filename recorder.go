@@ -0,0 +1,239 @@
+package sqln
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Recorder captures queries and their results (see Record) in record mode,
+// and answers queries from a capture without touching the database in replay
+// mode, for deterministic tests and offline debugging.
+type Recorder struct {
+	// Redactor replaces sensitive params that are not hashed. Defaults to
+	// DefaultRedactor.
+	Redactor Redactor
+
+	replay bool
+	redact map[string]bool
+
+	mtx sync.Mutex
+	w   io.Writer
+	// recs and used track a capture in replay mode.
+	recs []Record
+	used []bool
+}
+
+// NewRecorder returns a Recorder that writes records to w. Values of the
+// redacted params are replaced by a hash, so they can still be matched, and
+// other sensitive params are replaced by the Redactor.
+func NewRecorder(w io.Writer, redact ...string) *Recorder {
+	r := &Recorder{w: w, redact: make(map[string]bool, len(redact))}
+	for _, p := range redact {
+		r.redact[p] = true
+	}
+	return r
+}
+
+// NewReplayer returns a Recorder that answers queries from recs (see
+// ReadRecords). Each query is answered by the first unused record with the
+// same method, query and params.
+func NewReplayer(recs []Record) *Recorder {
+	return &Recorder{replay: true, recs: recs, used: make([]bool, len(recs))}
+}
+
+// Middleware returns the recording (or replaying) middleware. In replay mode
+// the wrapped DB is only used for Stmt.
+func (r *Recorder) Middleware() Middleware {
+	return func(db DB) DB {
+		return &recordDB{DB: db, r: r}
+	}
+}
+
+// Unused returns the records that were not replayed.
+func (r *Recorder) Unused() []Record {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	var recs []Record
+	for i, rec := range r.recs {
+		if !r.used[i] {
+			recs = append(recs, rec)
+		}
+	}
+	return recs
+}
+
+func paramsHash(params map[string]interface{}) string {
+	b, _ := json.Marshal(params)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+// newRecord builds a record of a call, redacting params.
+func (r *Recorder) newRecord(ctx context.Context, method, query string, params interface{}) Record {
+	rec := Record{Time: time.Now(), Method: method, Name: QueryName(ctx, query), Query: query}
+	m, ok := withParams(defaultMapper, params, nil).(map[string]interface{})
+	if !ok {
+		return rec
+	}
+	rec.ParamsHash = paramsHash(m)
+	redactor := r.Redactor
+	if redactor == nil {
+		redactor = DefaultRedactor
+	}
+	redacted := redactor.Redact(params)
+	for k, v := range m {
+		if r.redact[k] {
+			b, _ := json.Marshal(v)
+			sum := sha256.Sum256(b)
+			m[k] = "sha256:" + hex.EncodeToString(sum[:8])
+		} else if rv, ok := redacted[k]; ok {
+			m[k] = rv
+		}
+	}
+	if len(m) > 0 {
+		rec.Params = m
+	}
+	return rec
+}
+
+func (r *Recorder) write(rec Record, res sql.Result, dest interface{}, err error) {
+	rr := &RecordResult{}
+	if err != nil {
+		rr.Err = err.Error()
+	} else if res != nil {
+		rr.RowsAffected, _ = res.RowsAffected()
+		rr.LastInsertID, _ = res.LastInsertId()
+	} else if dest != nil {
+		rr.Rows, _ = json.Marshal(dest)
+	}
+	rec.Result = rr
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	// NOTE: Recording must not fail queries, so write errors are dropped.
+	json.NewEncoder(r.w).Encode(rec)
+}
+
+// find returns the result of the next matching record in replay mode.
+func (r *Recorder) find(method, query string, params interface{}) (*RecordResult, error) {
+	want := r.newRecord(context.Background(), method, query, params)
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for i, rec := range r.recs {
+		if r.used[i] || rec.Method != method || rec.Query != query || rec.ParamsHash != want.ParamsHash {
+			continue
+		}
+		r.used[i] = true
+		if rec.Result == nil {
+			return nil, errors.Errorf("replay: record for %v %q has no result", method, query)
+		}
+		return rec.Result, nil
+	}
+	return nil, errors.Errorf("replay: no record for %v %q", method, query)
+}
+
+// replayErr restores well known errors callers compare against.
+func replayErr(msg string) error {
+	switch msg {
+	case sql.ErrNoRows.Error():
+		return sql.ErrNoRows
+	case ErrNoRowsAffected.Error():
+		return ErrNoRowsAffected
+	}
+	return errors.New(msg)
+}
+
+type recordDB struct {
+	DB
+	r *Recorder
+}
+
+func (d *recordDB) Unwrap() DB { return d.DB }
+
+func (d *recordDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	if d.r.replay {
+		rr, err := d.r.find("Exec", query, params)
+		if err != nil {
+			return nil, err
+		}
+		if rr.Err != "" {
+			return nil, replayErr(rr.Err)
+		}
+		return replayResult{rr.LastInsertID, rr.RowsAffected}, nil
+	}
+
+	rec := d.r.newRecord(ctx, "Exec", query, params)
+	res, err := d.DB.Exec(ctx, query, params)
+	d.r.write(rec, res, nil, err)
+	return res, err
+}
+
+func (d *recordDB) query(ctx context.Context, method, query string, dest, params interface{}, run func() error) error {
+	if d.r.replay {
+		rr, err := d.r.find(method, query, params)
+		if err != nil {
+			return err
+		}
+		if rr.Err != "" {
+			return replayErr(rr.Err)
+		}
+		return errors.Wrapf(json.Unmarshal(rr.Rows, dest), "replay: %v %q", method, query)
+	}
+
+	rec := d.r.newRecord(ctx, method, query, params)
+	err := run()
+	d.r.write(rec, nil, dest, err)
+	return err
+}
+
+func (d *recordDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	return d.query(ctx, "Get", query, dest, params, func() error {
+		return d.DB.Get(ctx, query, dest, params)
+	})
+}
+
+func (d *recordDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	return d.query(ctx, "Select", query, dest, params, func() error {
+		return d.DB.Select(ctx, query, dest, params)
+	})
+}
+
+func (d *recordDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	return d.query(ctx, "ExecReturning", query, dest, params, func() error {
+		return d.DB.ExecReturning(ctx, query, dest, params)
+	})
+}
+
+func (d *recordDB) Stmt(query string) (*sqlx.NamedStmt, error) {
+	if d.DB == nil {
+		return nil, errors.New("replay: Stmt is not supported without a DB")
+	}
+	return d.DB.Stmt(query)
+}
+
+func (d *recordDB) Transact(ctx context.Context, opts sql.TxOptions, f func(DB) error) error {
+	if d.r.replay {
+		// There is nothing to isolate when answering from records.
+		return f(d)
+	}
+	return d.DB.Transact(ctx, opts, func(tx DB) error {
+		return f(&recordDB{DB: tx, r: d.r})
+	})
+}
+
+type replayResult struct {
+	lastInsertID, rowsAffected int64
+}
+
+func (r replayResult) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r replayResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
@@ -0,0 +1,70 @@
+package sqln
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	db := sqliteDB(t)
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE recorder_abc (id INTEGER PRIMARY KEY, email TEXT NOT NULL);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	type user struct {
+		ID    int    `db:"id"`
+		Email string `db:"email"`
+	}
+	run := func(db DB) ([]user, error) {
+		if _, err := db.Exec(ctx, "INSERT INTO recorder_abc (id, email) VALUES (:id, :email);", user{1, "a@example.com"}); err != nil {
+			return nil, err
+		}
+		var u user
+		if err := db.Get(ctx, "SELECT * FROM recorder_abc WHERE id = :id;", &u, map[string]interface{}{"id": 2}); err != sql.ErrNoRows {
+			return nil, err
+		}
+		var all []user
+		err := db.Select(ctx, "SELECT * FROM recorder_abc;", &all, nil)
+		return all, err
+	}
+
+	var buf bytes.Buffer
+	recorded, err := run(Wrap(db, NewRecorder(&buf, "email").Middleware()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recs, err := ReadRecords(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 3 {
+		t.Fatalf("expected 3 records, got %v", len(recs))
+	}
+	if email, _ := recs[0].Params["email"].(string); !strings.HasPrefix(email, "sha256:") {
+		t.Fatalf("expected email param to be redacted, got %q", email)
+	}
+
+	// Replay answers from the records alone.
+	rep := NewReplayer(recs)
+	replayed, err := run(Wrap(nil, rep.Middleware()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed) != 1 || replayed[0] != recorded[0] {
+		t.Fatalf("expected %v, got %v", recorded, replayed)
+	}
+	if n := len(rep.Unused()); n != 0 {
+		t.Fatalf("expected every record to be replayed, %v unused", n)
+	}
+
+	var u user
+	if err := Wrap(nil, rep.Middleware()).Get(ctx, "SELECT * FROM recorder_abc WHERE id = :id;", &u, map[string]interface{}{"id": 2}); err == nil {
+		t.Fatal("expected error once records are used up")
+	}
+}
@@ -0,0 +1,53 @@
+package sqln
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Redacted replaces the values of sensitive params.
+const Redacted = "[redacted]"
+
+// Redactor removes sensitive values from params before they are logged or
+// recorded by this package.
+type Redactor interface {
+	// Redact returns a copy of params (nil, a map or a struct) that is
+	// safe to log.
+	Redact(params interface{}) map[string]interface{}
+}
+
+// SensitiveRedactor redacts struct fields tagged `db:"...,sensitive"` and
+// params whose names contain any of Names (case insensitively).
+type SensitiveRedactor struct {
+	Names []string
+}
+
+// DefaultRedactor is used by the Recorder and slow plan capture unless
+// configured otherwise.
+var DefaultRedactor Redactor = SensitiveRedactor{Names: []string{"password", "token", "secret", "ssn"}}
+
+// Redact implements Redactor.
+func (r SensitiveRedactor) Redact(params interface{}) map[string]interface{} {
+	m, ok := withParams(defaultMapper, params, nil).(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if v := reflect.Indirect(reflect.ValueOf(params)); v.Kind() == reflect.Struct {
+		for name, f := range defaultMapper.TypeMap(v.Type()).Names {
+			if _, ok := f.Options["sensitive"]; ok {
+				m[name] = Redacted
+			}
+		}
+	}
+	for k := range m {
+		lk := strings.ToLower(k)
+		for _, n := range r.Names {
+			if strings.Contains(lk, n) {
+				m[k] = Redacted
+				break
+			}
+		}
+	}
+	return m
+}
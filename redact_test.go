@@ -0,0 +1,35 @@
+package sqln
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSensitiveRedactor(t *testing.T) {
+	type login struct {
+		Email string `db:"email"`
+		PIN   string `db:"pin,sensitive"`
+		Token string `db:"api_token"`
+	}
+	m := DefaultRedactor.Redact(login{Email: "a@example.com", PIN: "1234", Token: "t"})
+	if m["email"] != "a@example.com" || m["pin"] != Redacted || m["api_token"] != Redacted {
+		t.Fatalf("unexpected redaction: %v", m)
+	}
+	m = DefaultRedactor.Redact(map[string]interface{}{"id": 1, "Password": "hunter2"})
+	if m["id"] != 1 || m["Password"] != Redacted {
+		t.Fatalf("unexpected redaction: %v", m)
+	}
+
+	db := sqliteDB(t)
+	var buf bytes.Buffer
+	rdb := Wrap(db, NewRecorder(&buf).Middleware())
+	var n int
+	if err := rdb.Get(context.Background(), "SELECT :password = '';", &n, map[string]interface{}{"password": "hunter2"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Fatalf("expected the password to be redacted: %s", buf.String())
+	}
+}
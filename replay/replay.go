@@ -0,0 +1,138 @@
+/*
+Package replay re-executes a captured query log (see sqln.Record) against a
+database, for load testing and migration validation with real traffic shapes.
+*/
+package replay
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nstogner/sqln"
+)
+
+// Options controls how a log is replayed.
+type Options struct {
+	// Concurrency is the number of queries run in parallel. Defaults to 1.
+	Concurrency int
+	// Speed scales the original spacing between queries: 1 replays in real
+	// time, 2 at twice the speed. Zero replays as fast as possible.
+	Speed float64
+	// Writes enables replaying Exec and ExecReturning records, which are
+	// skipped by default.
+	Writes bool
+	// OnResult is called (concurrently) after each record is replayed.
+	OnResult func(Result)
+}
+
+// Result is the outcome of replaying a single record.
+type Result struct {
+	Record   sqln.Record
+	Duration time.Duration
+	Err      error
+}
+
+// Summary totals a replay.
+type Summary struct {
+	Executed int
+	Failed   int
+	Skipped  int
+	Elapsed  time.Duration
+}
+
+// Run replays recs against db. Reads are executed through Exec, which runs the
+// query without scanning results. Run stops early if ctx is done.
+func Run(ctx context.Context, db sqln.DB, recs []sqln.Record, opts Options) (Summary, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+
+	var (
+		sum    Summary
+		sumMtx sync.Mutex
+		wg     sync.WaitGroup
+		work   = make(chan sqln.Record)
+	)
+
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rec := range work {
+				start := time.Now()
+				_, err := db.Exec(ctx, rec.Query, params(rec))
+				res := Result{Record: rec, Duration: time.Since(start), Err: err}
+
+				sumMtx.Lock()
+				sum.Executed++
+				if err != nil {
+					sum.Failed++
+				}
+				sumMtx.Unlock()
+
+				if opts.OnResult != nil {
+					opts.OnResult(res)
+				}
+			}
+		}()
+	}
+
+	start := time.Now()
+	err := dispatch(ctx, recs, opts, work, func() {
+		sumMtx.Lock()
+		sum.Skipped++
+		sumMtx.Unlock()
+	})
+	close(work)
+	wg.Wait()
+
+	sum.Elapsed = time.Since(start)
+	return sum, err
+}
+
+func dispatch(ctx context.Context, recs []sqln.Record, opts Options, work chan<- sqln.Record, skip func()) error {
+	if len(recs) == 0 {
+		return nil
+	}
+
+	start, first := time.Now(), recs[0].Time
+	for _, rec := range recs {
+		if isWrite(rec) && !opts.Writes {
+			skip()
+			continue
+		}
+
+		if opts.Speed > 0 && !rec.Time.IsZero() {
+			at := start.Add(time.Duration(float64(rec.Time.Sub(first)) / opts.Speed))
+			if d := time.Until(at); d > 0 {
+				t := time.NewTimer(d)
+				select {
+				case <-ctx.Done():
+					t.Stop()
+					return ctx.Err()
+				case <-t.C:
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case work <- rec:
+		}
+	}
+	return nil
+}
+
+// isWrite reports whether rec was recorded by a method that modifies data.
+func isWrite(rec sqln.Record) bool {
+	return rec.Method == "Exec" || rec.Method == "ExecReturning"
+}
+
+func params(rec sqln.Record) interface{} {
+	if rec.Params == nil {
+		return nil
+	}
+	return rec.Params
+}
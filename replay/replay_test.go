@@ -0,0 +1,105 @@
+package replay
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nstogner/sqln"
+)
+
+const log = `{"time":"2019-09-01T00:00:00Z","method":"Exec","query":"INSERT INTO abc (id) VALUES (:id);","params":{"id":1}}
+{"time":"2019-09-01T00:00:00.001Z","method":"Exec","query":"INSERT INTO abc (id) VALUES (:id);","params":{"id":2}}
+
+{"time":"2019-09-01T00:00:00.002Z","method":"Select","query":"SELECT id FROM abc WHERE id > :id;","params":{"id":0}}
+`
+
+func TestRun(t *testing.T) {
+	dbx, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbx.Close()
+	db := sqln.New(dbx)
+	defer db.Close()
+
+	if _, err := dbx.Exec("CREATE TABLE abc (id INT PRIMARY KEY);"); err != nil {
+		t.Fatal(err)
+	}
+
+	recs, err := sqln.ReadRecords(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 3 {
+		t.Fatalf("expected 3 records, got %v", len(recs))
+	}
+
+	ctx := context.Background()
+
+	sum, err := Run(ctx, db, recs, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Executed != 1 || sum.Skipped != 2 || sum.Failed != 0 {
+		t.Fatalf("unexpected read-only summary: %+v", sum)
+	}
+
+	sum, err = Run(ctx, db, recs, Options{Writes: true, Concurrency: 2, Speed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Executed != 3 || sum.Failed != 0 {
+		t.Fatalf("unexpected summary: %+v", sum)
+	}
+
+	var n int
+	if err := db.Get(ctx, "SELECT COUNT(*) FROM abc;", &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 rows, got %v", n)
+	}
+}
+
+func TestRunExecReturning(t *testing.T) {
+	dbx, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbx.Close()
+	db := sqln.New(dbx)
+	defer db.Close()
+
+	if _, err := dbx.Exec("CREATE TABLE abc (id INT PRIMARY KEY);"); err != nil {
+		t.Fatal(err)
+	}
+	recs := []sqln.Record{{Method: "ExecReturning", Query: "INSERT INTO abc (id) VALUES (:id) RETURNING id;", Params: map[string]interface{}{"id": 1}}}
+
+	ctx := context.Background()
+	sum, err := Run(ctx, db, recs, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Executed != 0 || sum.Skipped != 1 {
+		t.Fatalf("expected ExecReturning to be skipped as a write, got %+v", sum)
+	}
+
+	sum, err = Run(ctx, db, recs, Options{Writes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Executed != 1 || sum.Failed != 0 {
+		t.Fatalf("unexpected summary: %+v", sum)
+	}
+	var n int
+	if err := db.Get(ctx, "SELECT COUNT(*) FROM abc;", &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 row, got %v", n)
+	}
+}
@@ -0,0 +1,203 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Balance selects how reads are spread across replicas.
+type Balance int
+
+// Replica balancing strategies.
+const (
+	RoundRobin Balance = iota
+	// LeastLoaded picks the replica with the fewest connections in use.
+	LeastLoaded
+)
+
+// Replicated routes Get and Select to replicas and everything else (Exec,
+// Stmt, and Transact, including reads inside transactions) to the primary.
+type Replicated struct {
+	Primary  *Database
+	Replicas []*Database
+
+	// Balance defaults to RoundRobin.
+	Balance Balance
+
+	// LagGuard, if set, routes reads to the primary rather than to a lagging
+	// replica.
+	LagGuard *LagGuard
+
+	// RetryOnPrimary retries reads which fail on a replica with a connection
+	// error, or because the replica is in recovery, on the primary.
+	RetryOnPrimary bool
+	// OnReplicaError is called for each read retried on the primary, ie. to
+	// count them.
+	OnReplicaError func(ReplicaError)
+
+	next uint32
+
+	lagMtx sync.Mutex
+	lags   map[*Database]replicaLag
+}
+
+var _ DB = &Replicated{}
+
+// NewWithReplicas wraps a primary and its read replicas.
+func NewWithReplicas(primary *sqlx.DB, replicas ...*sqlx.DB) *Replicated {
+	r := &Replicated{Primary: New(primary)}
+	for _, dbx := range replicas {
+		r.Replicas = append(r.Replicas, New(dbx))
+	}
+	return r
+}
+
+type stickyState struct {
+	wrote int32
+	// lsn is the primary's WAL position after the last write, if recorded
+	// (see LagGuard.WaitForWrites).
+	lsn uint64
+}
+
+// WithReadYourWrites returns a context in which, once a write has been made
+// through a Replicated DB, all subsequent reads go to the primary (or to a
+// replica which has replayed the write, see LagGuard.WaitForWrites).
+// Typically applied once per request.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, stickyKey, &stickyState{})
+}
+
+// written reports whether a write has been made in ctx, and the primary's WAL
+// position after it if recorded.
+func written(ctx context.Context) (bool, uint64) {
+	s, ok := ctx.Value(stickyKey).(*stickyState)
+	if !ok || atomic.LoadInt32(&s.wrote) == 0 {
+		return false, 0
+	}
+	return true, atomic.LoadUint64(&s.lsn)
+}
+
+// reader returns the Database to use for a read of query.
+func (r *Replicated) reader(ctx context.Context, query string) *Database {
+	if len(r.Replicas) == 0 {
+		return r.Primary
+	}
+	if r.Primary.replicaOnly(ctx, query) {
+		return r.replica()
+	}
+	wrote, lsn := written(ctx)
+	if wrote && lsn == 0 {
+		return r.Primary
+	}
+	if t := tokenLSN(ctx); t > lsn {
+		lsn = t
+	}
+	d := r.replica()
+	if (r.LagGuard != nil || lsn > 0) && !r.caughtUp(ctx, d, lsn) {
+		return r.Primary
+	}
+	return d
+}
+
+// routed marks ctx as routed to a replica if d is not the primary, which is
+// required by ReplicaOnly query policies.
+func (r *Replicated) routed(ctx context.Context, d *Database) context.Context {
+	if d == r.Primary {
+		return ctx
+	}
+	return onReplica(ctx)
+}
+
+// replica picks a replica according to the Balance strategy.
+func (r *Replicated) replica() *Database {
+	switch r.Balance {
+	case LeastLoaded:
+		best, bestInUse := r.Replicas[0], -1
+		for _, d := range r.Replicas {
+			if n := d.drv.Stats().InUse; bestInUse < 0 || n < bestInUse {
+				best, bestInUse = d, n
+			}
+		}
+		return best
+	default:
+		i := atomic.AddUint32(&r.next, 1)
+		return r.Replicas[int(i)%len(r.Replicas)]
+	}
+}
+
+// Exec a SQL statement on the primary.
+func (r *Replicated) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	res, err := r.Primary.Exec(ctx, query, params)
+	if err == nil {
+		r.recordWrite(ctx)
+	}
+	return res, err
+}
+
+// ExecReturning executes a statement on the primary, scanning its RETURNING
+// columns into dest.
+func (r *Replicated) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	err := r.Primary.ExecReturning(ctx, query, dest, params)
+	if err == nil {
+		r.recordWrite(ctx)
+	}
+	return err
+}
+
+// Get a single record from a replica.
+func (r *Replicated) Get(ctx context.Context, query string, dest, params interface{}) error {
+	d := r.reader(ctx, query)
+	err := d.Get(r.routed(ctx, d), query, dest, params)
+	if r.retryOnPrimary(ctx, d, query, err) {
+		return r.Primary.Get(ctx, query, dest, params)
+	}
+	return err
+}
+
+// Select multiple records from a replica.
+func (r *Replicated) Select(ctx context.Context, query string, dest, params interface{}) error {
+	d := r.reader(ctx, query)
+	err := d.Select(r.routed(ctx, d), query, dest, params)
+	if r.retryOnPrimary(ctx, d, query, err) {
+		truncate(dest)
+		return r.Primary.Select(ctx, query, dest, params)
+	}
+	return err
+}
+
+// Stmt creates and/or retrieves a named statement on the primary.
+func (r *Replicated) Stmt(query string) (*sqlx.NamedStmt, error) {
+	return r.Primary.Stmt(query)
+}
+
+// Transact runs f in a transaction on the primary.
+func (r *Replicated) Transact(ctx context.Context, opts sql.TxOptions, f func(DB) error) error {
+	if err := r.Primary.Transact(ctx, opts, f); err != nil {
+		return err
+	}
+	if !opts.ReadOnly {
+		r.recordWrite(ctx)
+	}
+	return nil
+}
+
+// Dialect returns the dialect of the primary.
+func (r *Replicated) Dialect() Dialect {
+	return r.Primary.Dialect()
+}
+
+// Close all managed named statements on the primary and replicas. Returns the
+// first error.
+func (r *Replicated) Close() error {
+	err := r.Primary.Close()
+	for _, d := range r.Replicas {
+		if cerr := d.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,64 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReplicated(t *testing.T) {
+	primary, replica := sqliteDB(t), sqliteDB(t)
+	for _, d := range []*Database{primary, replica} {
+		if _, err := d.X.Exec("CREATE TABLE replica_abc (id INT PRIMARY KEY);"); err != nil {
+			t.Fatal("unable to create table:", err)
+		}
+	}
+
+	r := &Replicated{Primary: primary, Replicas: []*Database{replica}}
+	ctx := WithReadYourWrites(context.Background())
+
+	const count = "SELECT COUNT(*) FROM replica_abc;"
+	var n int
+	if err := r.Get(ctx, count, &n, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Exec(ctx, "INSERT INTO replica_abc (id) VALUES (1);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The replica never receives the write (there is no replication here),
+	// so reads routed to it see no rows.
+	if err := r.Get(context.Background(), count, &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("expected read from replica, got %v rows", n)
+	}
+
+	if err := r.Get(ctx, count, &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expected read-your-writes from primary, got %v rows", n)
+	}
+
+	r.Balance = LeastLoaded
+	if err := r.Select(context.Background(), count, &[]int{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Mutations returning rows go to the primary.
+	var ids []int
+	if err := r.ExecReturning(context.Background(), "INSERT INTO replica_abc (id) VALUES (2), (3) RETURNING id;", &ids, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("expected 2 returned ids, got %v", ids)
+	}
+	if err := primary.Get(context.Background(), count, &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 rows on the primary, got %v", n)
+	}
+}
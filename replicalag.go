@@ -0,0 +1,139 @@
+package sqln
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// LagGuard keeps reads off Postgres replicas that are behind the primary (see
+// Replicated.LagGuard). Replicas are checked with pg_last_wal_replay_lsn and
+// pg_last_xact_replay_timestamp; a replica that cannot be checked is treated
+// as lagging.
+type LagGuard struct {
+	// MaxLag is the replay lag above which reads fall back to the primary.
+	// Zero disables the check.
+	MaxLag time.Duration
+	// Interval is how long a replica's measured lag is reused. Defaults to
+	// one second.
+	Interval time.Duration
+	// WaitForWrites records the primary's WAL position after each write in a
+	// WithReadYourWrites context, and routes later reads in the context to a
+	// replica that has replayed it rather than to the primary.
+	WaitForWrites bool
+}
+
+func (g *LagGuard) interval() time.Duration {
+	if g != nil && g.Interval > 0 {
+		return g.Interval
+	}
+	return time.Second
+}
+
+// replicaLag is the last measurement of a replica.
+type replicaLag struct {
+	at  time.Time
+	lsn uint64
+	lag time.Duration
+	err error
+}
+
+const (
+	replicaLagQuery = `SELECT COALESCE(CAST(pg_last_wal_replay_lsn() AS text), '') AS lsn,
+	CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0) END AS lag;`
+	primaryLSNQuery = "SELECT CAST(pg_current_wal_lsn() AS text);"
+)
+
+// caughtUp reports whether d is within the guard's MaxLag, if any, and has
+// replayed lsn, if non-zero.
+func (r *Replicated) caughtUp(ctx context.Context, d *Database, lsn uint64) bool {
+	now := time.Now()
+	r.lagMtx.Lock()
+	m, ok := r.lags[d]
+	r.lagMtx.Unlock()
+	if !ok || now.Sub(m.at) >= r.LagGuard.interval() || m.err == nil && m.lsn < lsn {
+		m = r.measure(ctx, d, now)
+	}
+	if m.err != nil {
+		return false
+	}
+	if r.LagGuard != nil && r.LagGuard.MaxLag > 0 && m.lag > r.LagGuard.MaxLag {
+		return false
+	}
+	return m.lsn >= lsn
+}
+
+// measure queries the lag of d, saving the result.
+func (r *Replicated) measure(ctx context.Context, d *Database, now time.Time) replicaLag {
+	var row struct {
+		LSN string  `db:"lsn"`
+		Lag float64 `db:"lag"`
+	}
+	m := replicaLag{at: now}
+	if m.err = d.Get(withInternal(ctx), replicaLagQuery, &row, nil); m.err == nil {
+		m.lag = time.Duration(row.Lag * float64(time.Second))
+		if row.LSN != "" {
+			m.lsn, m.err = parseLSN(row.LSN)
+		}
+	}
+	if isContextErr(m.err) {
+		// The caller gave up; this says nothing about the replica.
+		return m
+	}
+	r.lagMtx.Lock()
+	defer r.lagMtx.Unlock()
+	if r.lags == nil {
+		r.lags = make(map[*Database]replicaLag)
+	}
+	r.lags[d] = m
+	return m
+}
+
+// recordWrite saves the primary's WAL position in ctx after a write, if
+// WaitForWrites is set. On failure the context reads from the primary.
+func (r *Replicated) recordWrite(ctx context.Context) {
+	s, ok := ctx.Value(stickyKey).(*stickyState)
+	if !ok {
+		return
+	}
+	if r.LagGuard != nil && r.LagGuard.WaitForWrites {
+		var text string
+		if err := r.Primary.Get(withInternal(ctx), primaryLSNQuery, &text, nil); err == nil {
+			if lsn, err := parseLSN(text); err == nil {
+				s.wroteLSN(lsn)
+			}
+		}
+	}
+	atomic.StoreInt32(&s.wrote, 1)
+}
+
+func (s *stickyState) wroteLSN(lsn uint64) {
+	for {
+		cur := atomic.LoadUint64(&s.lsn)
+		if lsn <= cur || atomic.CompareAndSwapUint64(&s.lsn, cur, lsn) {
+			return
+		}
+	}
+}
+
+// parseLSN parses a Postgres WAL position, ie. "16/B374D848".
+func parseLSN(s string) (uint64, error) {
+	hi, lo, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, errors.Errorf("invalid lsn %q", s)
+	}
+	h, err := strconv.ParseUint(hi, 16, 32)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid lsn %q", s)
+	}
+	l, err := strconv.ParseUint(lo, 16, 32)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid lsn %q", s)
+	}
+	return h<<32 | l, nil
+}
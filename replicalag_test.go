@@ -0,0 +1,61 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLSN(t *testing.T) {
+	lsn, err := parseLSN("16/B374D848")
+	if err != nil || lsn != 0x16B374D848 {
+		t.Fatalf("unexpected lsn: %x %v", lsn, err)
+	}
+	for _, s := range []string{"", "16", "x/1", "1/100000000"} {
+		if _, err := parseLSN(s); err == nil {
+			t.Errorf("expected an error parsing %q", s)
+		}
+	}
+}
+
+func TestLagGuard(t *testing.T) {
+	primary, replica := sqliteDB(t), sqliteDB(t)
+	r := &Replicated{Primary: primary, Replicas: []*Database{replica}, LagGuard: &LagGuard{MaxLag: time.Second, Interval: time.Hour, WaitForWrites: true}}
+	ctx := context.Background()
+
+	// The lag of a SQLite "replica" cannot be measured, so reads go to the
+	// primary.
+	if d := r.reader(ctx, "SELECT 1;"); d != primary {
+		t.Fatal("expected the unmeasurable replica to be skipped")
+	}
+
+	r.lags[replica] = replicaLag{at: time.Now(), lsn: 100, lag: 10 * time.Millisecond}
+	if d := r.reader(ctx, "SELECT 1;"); d != replica {
+		t.Fatal("expected a read from the replica")
+	}
+
+	sticky := WithReadYourWrites(ctx)
+	s := sticky.Value(stickyKey).(*stickyState)
+	s.wroteLSN(50)
+	s.wrote = 1
+	if d := r.reader(sticky, "SELECT 1;"); d != replica {
+		t.Fatal("expected a read from the replica which replayed the write")
+	}
+	s.wroteLSN(200)
+	if d := r.reader(sticky, "SELECT 1;"); d != primary {
+		t.Fatal("expected a read from the primary until the write is replayed")
+	}
+
+	// Recording the write fails on SQLite, so the context sticks to the
+	// primary.
+	sticky = WithReadYourWrites(ctx)
+	r.recordWrite(sticky)
+	if d := r.reader(sticky, "SELECT 1;"); d != primary {
+		t.Fatal("expected a read from the primary")
+	}
+
+	r.lags[replica] = replicaLag{at: time.Now(), lsn: 100, lag: time.Minute}
+	if d := r.reader(ctx, "SELECT 1;"); d != primary {
+		t.Fatal("expected the lagging replica to be skipped")
+	}
+}
@@ -0,0 +1,55 @@
+package sqln
+
+import (
+	"context"
+	"reflect"
+	"strings"
+)
+
+// ReplicaError describes a read which failed on a replica and was retried on
+// the primary (see Replicated.RetryOnPrimary).
+type ReplicaError struct {
+	// Replica is the index of the replica in Replicas.
+	Replica int
+	Query   string
+	Name    string
+	Err     error
+}
+
+// retryOnPrimary reports whether a read which failed with err on d should be
+// retried on the primary, calling OnReplicaError if so.
+func (r *Replicated) retryOnPrimary(ctx context.Context, d *Database, query string, err error) bool {
+	if err == nil || !r.RetryOnPrimary || d == r.Primary || ctx.Err() != nil || !replicaFailed(err) || r.Primary.replicaOnly(ctx, query) {
+		return false
+	}
+	if r.OnReplicaError != nil {
+		i := 0
+		for i < len(r.Replicas) && r.Replicas[i] != d {
+			i++
+		}
+		r.OnReplicaError(ReplicaError{Replica: i, Query: query, Name: QueryName(ctx, query), Err: err})
+	}
+	return true
+}
+
+// replicaFailed reports whether err is a failure of the replica rather than
+// of the query: a connection error, a replica which is starting up or in
+// recovery, or a query canceled by a conflict with recovery.
+func replicaFailed(err error) bool {
+	switch Classify(err) {
+	case ClassConnection, ClassTooManyConnections:
+		return true
+	case ClassSerializationFailure:
+		return strings.Contains(err.Error(), "conflict with recovery")
+	}
+	return false
+}
+
+// truncate empties the slice dest points to, if any, dropping rows scanned
+// before a failure.
+func truncate(dest interface{}) {
+	if isSlicePtr(dest) {
+		v := reflect.ValueOf(dest).Elem()
+		v.SetLen(0)
+	}
+}
@@ -0,0 +1,77 @@
+package sqln
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// downDriver fails to prepare as if the connection were lost.
+type downDriver struct {
+	*sqlx.DB
+}
+
+func (downDriver) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	return nil, driver.ErrBadConn
+}
+
+func TestRetryOnPrimary(t *testing.T) {
+	primary := sqliteDB(t)
+	replica := newBackend(downDriver{DB: sqliteDB(t).X})
+	defer replica.Close()
+	ctx := context.Background()
+	if _, err := primary.Exec(ctx, "CREATE TABLE retry_abc (id INTEGER);", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := primary.Exec(ctx, "INSERT INTO retry_abc (id) VALUES (1), (2);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var retried []ReplicaError
+	r := &Replicated{Primary: primary, Replicas: []*Database{replica}}
+	var ids []int
+	if err := r.Select(ctx, "SELECT id FROM retry_abc;", &ids, nil); !errors.Is(err, driver.ErrBadConn) {
+		t.Fatalf("expected the replica error without RetryOnPrimary, got %v", err)
+	}
+
+	r.RetryOnPrimary = true
+	r.OnReplicaError = func(e ReplicaError) { retried = append(retried, e) }
+	if err := r.Select(ctx, "SELECT id FROM retry_abc;", &ids, nil); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := r.Get(ctx, "SELECT COUNT(*) FROM retry_abc;", &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || n != 2 {
+		t.Fatalf("unexpected results: %v %v", ids, n)
+	}
+	if len(retried) != 2 || retried[0].Replica != 0 || !errors.Is(retried[0].Err, driver.ErrBadConn) {
+		t.Fatalf("unexpected replica errors: %+v", retried)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if r.retryOnPrimary(canceled, replica, "SELECT 1;", driver.ErrBadConn) {
+		t.Fatal("expected no retry once the context is done")
+	}
+}
+
+func TestReplicaFailed(t *testing.T) {
+	for err, want := range map[error]bool{
+		driver.ErrBadConn: true,
+		&pq.Error{Code: "57P03", Message: "the database system is starting up"}:                  true,
+		&pq.Error{Code: "40001", Message: "canceling statement due to conflict with recovery"}:   true,
+		&pq.Error{Code: "40001", Message: "could not serialize access due to concurrent update"}: false,
+		&pq.Error{Code: "42P01", Message: `relation "missing" does not exist`}:                   false,
+		context.DeadlineExceeded: false,
+	} {
+		if got := replicaFailed(err); got != want {
+			t.Errorf("replicaFailed(%v) = %v, want %v", err, got, want)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package sqln
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Repo provides CRUD operations on a table of T, a struct with db tagged
+// fields (see InsertStruct for the readonly option). Queries are built once,
+// so they are prepared and cached like any other. Every method takes the DB
+// to run on, so a Repo can be used within a transaction by passing it the
+// transaction's DB.
+//
+// A nullable time field tagged with the softdelete option (ie.
+// `db:"deleted_at,softdelete"`) enables DeleteSoft, and rows where it is set
+// are excluded by Get and List unless IncludeDeleted is used.
+type Repo[T any] struct {
+	Table string
+	// Key is the primary key columns.
+	Key []string
+	// Clock provides the times set by DeleteSoft and for timestamps (see
+	// Timestamps). Defaults to the clock of the DB (see Timestamps).
+	Clock Clock
+
+	softDelete     string
+	includeDeleted bool
+
+	get, list, insert, update, delete string
+}
+
+// NewRepo returns a Repo for table keyed by the key columns (defaults to
+// "id").
+func NewRepo[T any](table string, key ...string) (*Repo[T], error) {
+	if len(key) == 0 {
+		key = []string{"id"}
+	}
+	var zero T
+	t, err := structType(zero)
+	if err != nil {
+		return nil, errors.Wrap(err, "repo")
+	}
+	var cols []string
+	var softDelete string
+	for _, f := range columnFields(defaultMapper, t) {
+		cols = append(cols, f.Path)
+		if _, ok := f.Options["softdelete"]; ok {
+			softDelete = f.Path
+		}
+	}
+	all, err := structColumns(defaultMapper, zero)
+	if err != nil {
+		return nil, errors.Wrap(err, "repo")
+	}
+	where := make([]string, len(key))
+	for i, k := range key {
+		if !all[k] {
+			return nil, errors.Errorf("repo: unknown key column %q", k)
+		}
+		where[i] = k + " = :" + k
+	}
+	writable, err := writableColumns(defaultMapper, zero)
+	if err != nil {
+		return nil, errors.Wrap(err, "repo")
+	}
+
+	r := &Repo[T]{Table: table, Key: key, softDelete: softDelete}
+	sel := fmt.Sprintf("SELECT %v FROM %v", strings.Join(cols, ", "), table)
+	r.get = fmt.Sprintf("%v WHERE %v", sel, strings.Join(where, " AND "))
+	r.list = sel
+	r.insert = insertSQL(table, writable)
+	r.delete = fmt.Sprintf("DELETE FROM %v WHERE %v;", table, strings.Join(where, " AND "))
+	// The update is only built when there is something to set, so that
+	// tables with only key columns can still be used.
+	if r.update, err = updateSQL(defaultMapper, table, zero, key, ""); err != nil {
+		r.update = ""
+	}
+	return r, nil
+}
+
+// MustRepo is like NewRepo but panics on error, ie. for repos in package
+// variables.
+func MustRepo[T any](table string, key ...string) *Repo[T] {
+	r, err := NewRepo[T](table, key...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// IncludeDeleted returns a copy of r whose Get and List include soft-deleted
+// rows.
+func (r *Repo[T]) IncludeDeleted() *Repo[T] {
+	c := *r
+	c.includeDeleted = true
+	return &c
+}
+
+// live reports whether soft-deleted rows are excluded.
+func (r *Repo[T]) live() bool {
+	return r.softDelete != "" && !r.includeDeleted
+}
+
+// Get returns the row with key, a value for a single key column or a struct
+// or map with every key column. sql.ErrNoRows is returned if there is none.
+func (r *Repo[T]) Get(ctx context.Context, db DB, key interface{}) (T, error) {
+	var v T
+	params, err := r.keyParams(key)
+	if err != nil {
+		return v, err
+	}
+	q := r.get
+	if r.live() {
+		q += " AND " + r.softDelete + " IS NULL"
+	}
+	err = db.Get(ctx, q+";", &v, params)
+	return v, err
+}
+
+// List returns the rows matching where (ie. "status = :status"), or every
+// row if where is empty, ordered by key.
+func (r *Repo[T]) List(ctx context.Context, db DB, where string, params interface{}) ([]T, error) {
+	var conds []string
+	if where != "" {
+		conds = append(conds, "("+where+")")
+	}
+	if r.live() {
+		conds = append(conds, r.softDelete+" IS NULL")
+	}
+	q := r.list
+	if len(conds) > 0 {
+		q += " WHERE " + strings.Join(conds, " AND ")
+	}
+	q += " ORDER BY " + strings.Join(r.Key, ", ") + ";"
+	var vs []T
+	if err := db.Select(ctx, q, &vs, params); err != nil {
+		return nil, err
+	}
+	return vs, nil
+}
+
+// Insert inserts v.
+func (r *Repo[T]) Insert(ctx context.Context, db DB, v *T) error {
+	if _, err := stampStruct(defaultMapper, v, r.clock(db), true); err != nil {
+		return err
+	}
+	_, err := db.Exec(ctx, r.insert, v)
+	return err
+}
+
+// Update sets every writable non-key column of the row with v's key.
+// ErrNoRowsAffected is returned if there is no such row.
+func (r *Repo[T]) Update(ctx context.Context, db DB, v *T) error {
+	if r.update == "" {
+		return errors.Errorf("repo %v: no columns to update", r.Table)
+	}
+	if _, err := stampStruct(defaultMapper, v, r.clock(db), false); err != nil {
+		return err
+	}
+	_, err := ExecOne(ctx, db, r.update, v)
+	return err
+}
+
+// Delete deletes the row with key (see Get). ErrNoRowsAffected is returned
+// if there is no such row.
+func (r *Repo[T]) Delete(ctx context.Context, db DB, key interface{}) error {
+	params, err := r.keyParams(key)
+	if err != nil {
+		return err
+	}
+	_, err = ExecOne(ctx, db, r.delete, params)
+	return err
+}
+
+// DeleteSoft sets the softdelete column of the row with key (see Get) to the
+// current time. ErrNoRowsAffected is returned if there is no such row that
+// is not already deleted.
+func (r *Repo[T]) DeleteSoft(ctx context.Context, db DB, key interface{}) error {
+	if r.softDelete == "" {
+		return errors.Errorf("repo %v: no softdelete column", r.Table)
+	}
+	params, err := r.keyParams(key)
+	if err != nil {
+		return err
+	}
+	where := make([]string, len(r.Key))
+	for i, k := range r.Key {
+		where[i] = k + " = :" + k
+	}
+	q := fmt.Sprintf("UPDATE %v SET %[2]v = :sqln_deleted_at WHERE %v AND %[2]v IS NULL;", r.Table, r.softDelete, strings.Join(where, " AND "))
+	params["sqln_deleted_at"] = r.clock(db).Now().UTC()
+	_, err = ExecOne(ctx, db, q, params)
+	return err
+}
+
+func (r *Repo[T]) clock(db DB) Clock {
+	if r.Clock != nil {
+		return r.Clock
+	}
+	return clockOf(db)
+}
+
+// keyParams returns the key columns of key, a single key value or a struct or
+// map with every key column.
+func (r *Repo[T]) keyParams(key interface{}) (map[string]interface{}, error) {
+	params := make(map[string]interface{}, len(r.Key)+1)
+	v := reflect.Indirect(reflect.ValueOf(key))
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		for _, k := range r.Key {
+			kv := v.MapIndex(reflect.ValueOf(k))
+			if !kv.IsValid() {
+				return nil, errors.Errorf("repo %v: key has no %q", r.Table, k)
+			}
+			params[k] = kv.Interface()
+		}
+	case v.Kind() == reflect.Struct && !isValuer(v):
+		names := defaultMapper.TypeMap(v.Type()).Names
+		for _, k := range r.Key {
+			f, ok := names[k]
+			if !ok {
+				return nil, errors.Errorf("repo %v: key has no %q", r.Table, k)
+			}
+			params[k] = v.FieldByIndex(f.Index).Interface()
+		}
+	case len(r.Key) > 1:
+		return nil, errors.Errorf("repo %v: key must have columns %v", r.Table, strings.Join(r.Key, ", "))
+	default:
+		params[r.Key[0]] = key
+	}
+	return params, nil
+}
+
+// isValuer reports whether v is a single value such as a time.Time or
+// Null[T] rather than a struct of key columns.
+func isValuer(v reflect.Value) bool {
+	return v.Type().Implements(valuerType) || reflect.PtrTo(v.Type()).Implements(valuerType) || v.Type() == timeType
+}
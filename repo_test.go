@@ -0,0 +1,113 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type repoUser struct {
+	ID    int64  `db:"id,readonly"`
+	Name  string `db:"name"`
+	Email string `db:"email"`
+}
+
+func TestRepo(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE repo_users (id INTEGER PRIMARY KEY, name TEXT, email TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+	users := MustRepo[repoUser]("repo_users")
+
+	if err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		for _, name := range []string{"a", "b"} {
+			if err := users.Insert(ctx, tx, &repoUser{Name: name, Email: name + "@example.com"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := users.Get(ctx, db, 1)
+	if err != nil || u.Name != "a" {
+		t.Fatalf("unexpected user: %+v, %v", u, err)
+	}
+	u.Email = "new@example.com"
+	if err := users.Update(ctx, db, &u); err != nil {
+		t.Fatal(err)
+	}
+	list, err := users.List(ctx, db, "email = :email", map[string]interface{}{"email": "new@example.com"})
+	if err != nil || len(list) != 1 || list[0].ID != 1 {
+		t.Fatalf("unexpected list: %+v, %v", list, err)
+	}
+
+	if err := users.Delete(ctx, db, repoUser{ID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Delete(ctx, db, 2); err != ErrNoRowsAffected {
+		t.Fatalf("expected ErrNoRowsAffected, got %v", err)
+	}
+	if _, err := users.Get(ctx, db, 2); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+	if list, err := users.List(ctx, db, "", nil); err != nil || len(list) != 1 {
+		t.Fatalf("unexpected list: %+v, %v", list, err)
+	}
+
+	if _, err := NewRepo[repoUser]("repo_users", "missing"); err == nil {
+		t.Fatal("expected unknown key column to be rejected")
+	}
+}
+
+type repoDoc struct {
+	ID        int64      `db:"id,readonly"`
+	Title     string     `db:"title"`
+	DeletedAt *time.Time `db:"deleted_at,softdelete"`
+}
+
+func TestRepoSoftDelete(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE repo_docs (id INTEGER PRIMARY KEY, title TEXT, deleted_at TIMESTAMP);", nil); err != nil {
+		t.Fatal(err)
+	}
+	docs := MustRepo[repoDoc]("repo_docs")
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	docs.Clock = NewTestClock(now)
+
+	for _, title := range []string{"a", "b"} {
+		if err := docs.Insert(ctx, db, &repoDoc{Title: title}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := docs.DeleteSoft(ctx, db, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.DeleteSoft(ctx, db, 1); err != ErrNoRowsAffected {
+		t.Fatalf("expected ErrNoRowsAffected, got %v", err)
+	}
+
+	if _, err := docs.Get(ctx, db, 1); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+	if list, err := docs.List(ctx, db, "title = :title OR 1=1", map[string]interface{}{"title": "a"}); err != nil || len(list) != 1 || list[0].Title != "b" {
+		t.Fatalf("unexpected list: %+v, %v", list, err)
+	}
+
+	d, err := docs.IncludeDeleted().Get(ctx, db, 1)
+	if err != nil || d.DeletedAt == nil || !d.DeletedAt.Equal(now) {
+		t.Fatalf("unexpected deleted doc: %+v, %v", d, err)
+	}
+	if list, err := docs.IncludeDeleted().List(ctx, db, "", nil); err != nil || len(list) != 2 {
+		t.Fatalf("unexpected list: %+v, %v", list, err)
+	}
+	if err := MustRepo[repoUser]("repo_users").DeleteSoft(ctx, db, 1); err == nil {
+		t.Fatal("expected repo without softdelete column to fail")
+	}
+}
@@ -0,0 +1,274 @@
+package sqln
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"database/sql"
+	"encoding/gob"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ResultCacheOptions configures a ResultCache.
+type ResultCacheOptions struct {
+	// MaxEntries bounds the number of cached results, evicting the least
+	// recently used. Zero is unbounded.
+	MaxEntries int
+	// Clock defaults to SystemClock.
+	Clock Clock
+}
+
+// ResultCache caches the results of registered Get and Select queries, keyed
+// by query and params, so hot reference data can skip the database. Results
+// are copied (with encoding/gob, so only exported fields are cached) and are
+// invalidated when they expire or when an Exec through the middleware writes
+// to one of the query's tables (see MutatedTables).
+type ResultCache struct {
+	opts ResultCacheOptions
+
+	mtx     sync.Mutex
+	queries map[string]cachedQuery
+	entries map[resultKey]*resultEntry
+	lru     *list.List
+	// gens counts invalidations per table so results read before an
+	// invalidation are not stored after it.
+	gens map[string]uint64
+}
+
+type cachedQuery struct {
+	ttl    time.Duration
+	tables []string
+}
+
+type resultKey struct {
+	query, params string
+}
+
+type resultEntry struct {
+	key     resultKey
+	elem    *list.Element
+	data    []byte
+	expires time.Time
+	tables  []string
+}
+
+// NewResultCache returns an empty ResultCache.
+func NewResultCache(opts ResultCacheOptions) *ResultCache {
+	if opts.Clock == nil {
+		opts.Clock = SystemClock
+	}
+	return &ResultCache{
+		opts:    opts,
+		queries: make(map[string]cachedQuery),
+		entries: make(map[resultKey]*resultEntry),
+		lru:     list.New(),
+		gens:    make(map[string]uint64),
+	}
+}
+
+// Register caches the results of query for ttl. Results are invalidated by
+// writes to tables, which default to the tables the query reads (see
+// ReadTables).
+func (c *ResultCache) Register(query string, ttl time.Duration, tables ...string) {
+	if len(tables) == 0 {
+		tables = ReadTables(query)
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.queries[query] = cachedQuery{ttl: ttl, tables: tables}
+}
+
+// Invalidate drops the cached results that depend on any of tables.
+func (c *ResultCache) Invalidate(tables ...string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for _, t := range tables {
+		c.gens[t]++
+	}
+	for _, e := range c.entries {
+		if overlaps(e.tables, tables) {
+			c.removeLocked(e)
+		}
+	}
+}
+
+// InvalidateQuery drops the cached results of query.
+func (c *ResultCache) InvalidateQuery(query string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for k, e := range c.entries {
+		if k.query == query {
+			c.removeLocked(e)
+		}
+	}
+}
+
+// Len returns the number of cached results.
+func (c *ResultCache) Len() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return len(c.entries)
+}
+
+// Middleware returns a middleware that answers registered queries from the
+// cache. Queries in transactions are not cached, and writes in transactions
+// invalidate when the transaction ends as well as when they are made.
+func (c *ResultCache) Middleware() Middleware {
+	return func(db DB) DB {
+		return &cacheDB{DB: db, c: c}
+	}
+}
+
+func overlaps(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *ResultCache) removeLocked(e *resultEntry) {
+	c.lru.Remove(e.elem)
+	delete(c.entries, e.key)
+}
+
+// lookup decodes a cached result into dest. Otherwise it returns the
+// registration of query (if any) and the generations of its tables for
+// store.
+func (c *ResultCache) lookup(query string, params interface{}, dest interface{}) (hit bool, key resultKey, q cachedQuery, gens []uint64, ok bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	q, ok = c.queries[query]
+	if !ok {
+		return false, key, q, nil, false
+	}
+	m, _ := withParams(defaultMapper, params, nil).(map[string]interface{})
+	key = resultKey{query: query, params: paramsHash(m)}
+
+	if e, found := c.entries[key]; found {
+		if c.opts.Clock.Now().Before(e.expires) {
+			v := reflect.ValueOf(dest).Elem()
+			v.Set(reflect.Zero(v.Type()))
+			if err := gob.NewDecoder(bytes.NewReader(e.data)).Decode(dest); err == nil {
+				c.lru.MoveToFront(e.elem)
+				return true, key, q, nil, true
+			}
+		}
+		c.removeLocked(e)
+	}
+
+	gens = make([]uint64, len(q.tables))
+	for i, t := range q.tables {
+		gens[i] = c.gens[t]
+	}
+	return false, key, q, gens, true
+}
+
+func (c *ResultCache) store(key resultKey, q cachedQuery, gens []uint64, dest interface{}) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(dest); err != nil {
+		// Results that cannot be encoded are not cached.
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for i, t := range q.tables {
+		if c.gens[t] != gens[i] {
+			return
+		}
+	}
+	if e, ok := c.entries[key]; ok {
+		c.removeLocked(e)
+	}
+	e := &resultEntry{key: key, data: buf.Bytes(), expires: c.opts.Clock.Now().Add(q.ttl), tables: q.tables}
+	e.elem = c.lru.PushFront(e)
+	c.entries[key] = e
+	for c.opts.MaxEntries > 0 && c.lru.Len() > c.opts.MaxEntries {
+		c.removeLocked(c.lru.Back().Value.(*resultEntry))
+	}
+}
+
+type cacheDB struct {
+	DB
+	c *ResultCache
+
+	// written is set inside transactions to the tables written so far.
+	written *[]string
+}
+
+func (d *cacheDB) Unwrap() DB { return d.DB }
+
+func (d *cacheDB) query(query string, dest, params interface{}, run func() error) error {
+	if d.written != nil {
+		return run()
+	}
+	hit, key, q, gens, ok := d.c.lookup(query, params, dest)
+	if hit {
+		return nil
+	}
+	if err := run(); err != nil || !ok {
+		return err
+	}
+	d.c.store(key, q, gens, dest)
+	return nil
+}
+
+func (d *cacheDB) wrote(query string) {
+	tables := MutatedTables(query)
+	if len(tables) == 0 {
+		return
+	}
+	d.c.Invalidate(tables...)
+	if d.written != nil {
+		*d.written = append(*d.written, tables...)
+	}
+}
+
+func (d *cacheDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	return d.query(query, dest, params, func() error {
+		return d.DB.Get(ctx, query, dest, params)
+	})
+}
+
+func (d *cacheDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	return d.query(query, dest, params, func() error {
+		return d.DB.Select(ctx, query, dest, params)
+	})
+}
+
+func (d *cacheDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	res, err := d.DB.Exec(ctx, query, params)
+	if err == nil {
+		d.wrote(query)
+	}
+	return res, err
+}
+
+func (d *cacheDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	err := d.DB.ExecReturning(ctx, query, dest, params)
+	if err == nil {
+		d.wrote(query)
+	}
+	return err
+}
+
+func (d *cacheDB) Transact(ctx context.Context, opts sql.TxOptions, f func(DB) error) error {
+	var written []string
+	defer func() {
+		// Results read by others while the transaction was open may
+		// predate its commit.
+		if len(written) > 0 {
+			d.c.Invalidate(written...)
+		}
+	}()
+	return d.DB.Transact(ctx, opts, func(tx DB) error {
+		return f(&cacheDB{DB: tx, c: d.c, written: &written})
+	})
+}
@@ -0,0 +1,83 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResultCache(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE cache_flags (name TEXT PRIMARY KEY, enabled BOOLEAN);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	clock := NewTestClock(time.Unix(0, 0))
+	c := NewResultCache(ResultCacheOptions{MaxEntries: 2, Clock: clock})
+	const (
+		list   = "SELECT name FROM cache_flags ORDER BY name;"
+		get    = "SELECT enabled FROM cache_flags WHERE name = :name;"
+		insert = "INSERT INTO cache_flags (name, enabled) VALUES (:name, true);"
+	)
+	c.Register(list, time.Minute)
+	c.Register(get, time.Minute)
+	cdb := Wrap(db, c.Middleware())
+
+	names := func() []string {
+		var names []string
+		if err := cdb.Select(ctx, list, &names, nil); err != nil {
+			t.Fatal(err)
+		}
+		return names
+	}
+	if n := names(); len(n) != 0 {
+		t.Fatalf("expected no flags, got %v", n)
+	}
+
+	// Writes that bypass the middleware are not seen until expiry.
+	if _, err := db.X.Exec("INSERT INTO cache_flags (name, enabled) VALUES ('a', true);"); err != nil {
+		t.Fatal(err)
+	}
+	if n := names(); len(n) != 0 {
+		t.Fatalf("expected cached result, got %v", n)
+	}
+	clock.Advance(time.Minute)
+	if n := names(); !reflect.DeepEqual(n, []string{"a"}) {
+		t.Fatalf("expected expired result to be reloaded, got %v", n)
+	}
+
+	// Writes through the middleware invalidate, including in transactions.
+	if _, err := cdb.Exec(ctx, insert, map[string]interface{}{"name": "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := names(); !reflect.DeepEqual(n, []string{"a", "b"}) {
+		t.Fatalf("expected invalidated result, got %v", n)
+	}
+	if err := cdb.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		_, err := tx.Exec(ctx, insert, map[string]interface{}{"name": "c"})
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n := names(); len(n) != 3 {
+		t.Fatalf("expected invalidated result, got %v", n)
+	}
+
+	// Results are cached per params and bounded by MaxEntries.
+	var enabled bool
+	for _, name := range []string{"a", "b", "c"} {
+		if err := cdb.Get(ctx, get, &enabled, map[string]interface{}{"name": name}); err != nil || !enabled {
+			t.Fatal(name, enabled, err)
+		}
+	}
+	if n := c.Len(); n != 2 {
+		t.Fatalf("expected 2 cached results, got %v", n)
+	}
+	c.InvalidateQuery(get)
+	if n := c.Len(); n != 0 {
+		t.Fatalf("expected no cached results, got %v", n)
+	}
+}
@@ -0,0 +1,143 @@
+package sqln
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// ResultSets iterates the result sets returned by a single statement, such as
+// a stored procedure call.
+type ResultSets struct {
+	rows *sqlx.Rows
+	// done ends the operation begun by QueryMulti or QueryResultSets.
+	done func()
+}
+
+// MultiQuerier is implemented by DBs that can return multiple result sets.
+type MultiQuerier interface {
+	QueryMulti(ctx context.Context, query string, params interface{}) (*ResultSets, error)
+}
+
+// QueryMulti runs a query with named params that returns multiple result
+// sets, using db or the DB it decorates. The caller must Close the result
+// sets. ie.
+//
+//	rs, err := sqln.QueryMulti(ctx, db, "CALL user_report(:id);", params)
+//	...
+//	defer rs.Close()
+//	rs.Scan(&users)
+//	if rs.NextResultSet() {
+//		rs.Scan(&orders)
+//	}
+func QueryMulti(ctx context.Context, db DB, query string, params interface{}) (*ResultSets, error) {
+	for d := db; d != nil; {
+		if m, ok := d.(MultiQuerier); ok {
+			return m.QueryMulti(ctx, query, params)
+		}
+		u, ok := d.(Unwrapper)
+		if !ok {
+			break
+		}
+		d = u.Unwrap()
+	}
+	return nil, errors.Errorf("multiple result sets not supported by %T", db)
+}
+
+// QueryMulti runs a query with named params that returns multiple result
+// sets, see the QueryMulti function. The statement is not cached. The
+// operation, and any limits it holds, lasts until the result sets are closed.
+func (d *Database) QueryMulti(ctx context.Context, query string, params interface{}) (*ResultSets, error) {
+	ctx, done, err := d.begin(ctx, "QueryMulti", query)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	rows, err := func() (*sqlx.Rows, error) {
+		if err := d.checkQuery(ctx, query); err != nil {
+			return nil, err
+		}
+		if params == nil {
+			params = struct{}{}
+		}
+		q, args, err := d.drv.BindNamed(query, d.arrayParams(params))
+		if err != nil {
+			return nil, err
+		}
+		rows, err := d.ext().QueryxContext(ctx, q, args...)
+		return rows, nameErr(ctx, query, err)
+	}()
+	if err != nil {
+		done()
+		return nil, err
+	}
+	return &ResultSets{rows: rows, done: func() {
+		d.observe(ctx, query, params, start)
+		done()
+	}}, nil
+}
+
+// QueryResultSets runs a query with positional arguments ("?" placeholders,
+// rebound for the driver, or the driver's own style) which may include
+// sql.Named and sql.Out values (ie. for SQL Server stored procedures), and
+// returns its result sets. The statement is not cached. As with QueryMulti,
+// the operation lasts until the result sets are closed.
+// NOTE: Drivers populate sql.Out destinations once all result sets have been
+// read and Close has been called.
+func (d *Database) QueryResultSets(ctx context.Context, query string, args ...interface{}) (*ResultSets, error) {
+	query = d.drv.Rebind(query)
+	ctx, done, err := d.begin(ctx, "QueryResultSets", query)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	rows, err := func() (*sqlx.Rows, error) {
+		if err := d.checkQuery(ctx, query); err != nil {
+			return nil, err
+		}
+		rows, err := d.ext().QueryxContext(ctx, query, args...)
+		return rows, nameErr(ctx, query, err)
+	}()
+	if err != nil {
+		d.queryStats.measure(query, start, &err)
+		done()
+		return nil, err
+	}
+	return &ResultSets{rows: rows, done: func() {
+		err := rows.Err()
+		if d.slowLog != nil {
+			d.slowLog.record(ctx, query, start)
+		}
+		d.queryStats.measure(query, start, &err)
+		done()
+	}}, nil
+}
+
+// Scan scans every row of the current result set into dest, which must be a
+// pointer to a slice.
+func (r *ResultSets) Scan(dest interface{}) error {
+	return sqlx.StructScan(r.rows, dest)
+}
+
+// NextResultSet advances to the next result set, reporting whether there is
+// one.
+func (r *ResultSets) NextResultSet() bool {
+	return r.rows.NextResultSet()
+}
+
+// Err returns the error, if any, encountered during iteration.
+func (r *ResultSets) Err() error {
+	return r.rows.Err()
+}
+
+// Close releases the underlying rows.
+func (r *ResultSets) Close() error {
+	err := r.rows.Close()
+	if r.done != nil {
+		r.done()
+		r.done = nil
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQueryResultSets(t *testing.T) {
+	d := New(sqliteDB(t).X, WithQueryStats(0))
+	defer d.Close()
+	ctx := context.Background()
+
+	if _, err := d.X.Exec("CREATE TABLE rs_abc (id INT PRIMARY KEY, x INT); INSERT INTO rs_abc VALUES (1, 10), (2, 20);"); err != nil {
+		t.Fatal("unable to create table:", err)
+	}
+
+	rs, err := d.QueryResultSets(ctx, "SELECT id, x FROM rs_abc WHERE x > ? ORDER BY id;", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(d.InFlight()); n != 1 {
+		t.Errorf("expected the operation to last until Close, got %v in flight", n)
+	}
+
+	var rows []struct {
+		ID int `db:"id"`
+		X  int `db:"x"`
+	}
+	if err := rs.Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1].X != 20 {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+	if rs.NextResultSet() {
+		t.Fatal("expected a single result set")
+	}
+	if err := rs.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(d.InFlight()); n != 0 {
+		t.Errorf("expected no operations in flight after Close, got %v", n)
+	}
+	if stats := d.QueryStats(); len(stats) != 1 || stats[0].Count != 1 {
+		t.Errorf("expected the query to be measured once, got %+v", stats)
+	}
+
+	d.Close()
+	if _, err := d.QueryResultSets(ctx, "SELECT 1;"); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestQueryMulti(t *testing.T) {
+	d := sqliteDB(t)
+	ctx := context.Background()
+
+	if _, err := d.X.Exec("CREATE TABLE rs_multi (id INT PRIMARY KEY, x INT); INSERT INTO rs_multi VALUES (1, 10), (2, 20);"); err != nil {
+		t.Fatal("unable to create table:", err)
+	}
+
+	db := Wrap(d, Timestamps(SystemClock))
+	rs, err := QueryMulti(ctx, db, "SELECT id, x FROM rs_multi WHERE x > :min ORDER BY id;", map[string]interface{}{"min": 15})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(d.InFlight()); n != 1 {
+		t.Errorf("expected the operation to last until Close, got %v in flight", n)
+	}
+
+	var rows []struct {
+		ID int `db:"id"`
+		X  int `db:"x"`
+	}
+	if err := rs.Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].X != 20 {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+	if rs.NextResultSet() {
+		t.Fatal("expected a single result set")
+	}
+	if err := rs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(d.InFlight()); n != 0 {
+		t.Errorf("expected no operations in flight after Close, got %v", n)
+	}
+
+	if _, err := QueryMulti(ctx, &cursorDB{}, "SELECT 1;", nil); err == nil {
+		t.Error("expected an error for a DB without multiple result sets")
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Package retention deletes expired rows according to registered policies. Deletes
+run in bounded batches through a sqln.DB (see sqln.ExecChunked), only within a
+configured window, and with a pause between batches to limit load.
+*/
+package retention
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+)
+
+// Policy declares how long rows in a table are kept.
+type Policy struct {
+	// Table and Column (a timestamp) identify expired rows.
+	Table  string
+	Column string
+	// MaxAge is how long rows are kept after Column.
+	MaxAge time.Duration
+	// BatchSize is the maximum number of rows deleted per statement.
+	// Defaults to 1000.
+	BatchSize int
+	// Key identifies rows within a batch. Defaults to the Postgres ctid.
+	Key string
+}
+
+// Window is a daily time range (offsets from midnight) in which deletes are
+// allowed to run. End may be before Start to span midnight. A zero Window
+// allows all times.
+type Window struct {
+	Start, End time.Duration
+	Location   *time.Location
+}
+
+// Contains reports whether t falls within the window.
+func (w Window) Contains(t time.Time) bool {
+	if w.Start == w.End {
+		return true
+	}
+	if w.Location != nil {
+		t = t.In(w.Location)
+	}
+	y, m, d := t.Date()
+	off := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+	if w.Start < w.End {
+		return off >= w.Start && off < w.End
+	}
+	return off >= w.Start || off < w.End
+}
+
+// Report summarizes a single policy run.
+type Report struct {
+	Policy   Policy
+	Deleted  int64
+	Batches  int
+	Duration time.Duration
+	// Stopped is set when the run ended early because the window closed.
+	Stopped bool
+	// DryRun is set when no rows were deleted; Deleted is the number of
+	// expired rows.
+	DryRun bool
+	Err    error
+}
+
+// Options configures a Scheduler.
+type Options struct {
+	Window Window
+	// Interval between runs of all policies. Defaults to 1h.
+	Interval time.Duration
+	// Pause between batches. Defaults to 100ms.
+	Pause time.Duration
+	// Report is called after each policy run.
+	Report func(Report)
+	// Progress is called after each batch, ie. to export metrics.
+	Progress func(Policy, sqln.ChunkProgress)
+	// DryRun counts the expired rows of each policy instead of deleting
+	// them.
+	DryRun bool
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// Scheduler runs registered policies.
+type Scheduler struct {
+	db   sqln.DB
+	opts Options
+
+	mtx      sync.Mutex
+	policies []Policy
+}
+
+// New returns a Scheduler that deletes through db.
+func New(db sqln.DB, opts Options) *Scheduler {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Hour
+	}
+	if opts.Pause <= 0 {
+		opts.Pause = 100 * time.Millisecond
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Scheduler{db: db, opts: opts}
+}
+
+// Register adds a policy.
+func (s *Scheduler) Register(p Policy) error {
+	if p.Table == "" || p.Column == "" {
+		return errors.New("retention: table and column are required")
+	}
+	if p.MaxAge <= 0 {
+		return errors.Errorf("retention: %v: max age must be positive", p.Table)
+	}
+	if p.BatchSize <= 0 {
+		p.BatchSize = 1000
+	}
+	if p.Key == "" {
+		p.Key = "ctid"
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.policies = append(s.policies, p)
+	return nil
+}
+
+// Run runs all policies every Interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) error {
+	t := time.NewTicker(s.opts.Interval)
+	defer t.Stop()
+
+	for {
+		s.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// RunOnce runs every policy if the window is open and returns their reports.
+func (s *Scheduler) RunOnce(ctx context.Context) []Report {
+	if !s.opts.Window.Contains(s.opts.Now()) {
+		return nil
+	}
+
+	s.mtx.Lock()
+	policies := append([]Policy(nil), s.policies...)
+	s.mtx.Unlock()
+
+	reports := make([]Report, 0, len(policies))
+	for _, p := range policies {
+		r := s.run(ctx, p)
+		if s.opts.Report != nil {
+			s.opts.Report(r)
+		}
+		reports = append(reports, r)
+	}
+	return reports
+}
+
+func (s *Scheduler) run(ctx context.Context, p Policy) Report {
+	start := s.opts.Now()
+	r := Report{Policy: p, DryRun: s.opts.DryRun}
+	cutoff := map[string]interface{}{"cutoff": start.Add(-p.MaxAge)}
+
+	if s.opts.DryRun {
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %[1]s WHERE %[2]s < :cutoff;", p.Table, p.Column)
+		if err := s.db.Get(ctx, query, &r.Deleted, cutoff); err != nil {
+			r.Err = errors.Wrapf(err, "retention: %v", p.Table)
+		}
+		r.Duration = s.opts.Now().Sub(start)
+		return r
+	}
+
+	// The window is checked between batches, canceling the delete when it
+	// closes.
+	chunkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	query := fmt.Sprintf("DELETE FROM %[1]s WHERE %[2]s IN (SELECT %[2]s FROM %[1]s WHERE %[3]s < :cutoff LIMIT :chunk_size);",
+		p.Table, p.Key, p.Column)
+	deleted, err := sqln.ExecChunked(chunkCtx, s.db, query, cutoff, p.BatchSize, sqln.ChunkOptions{
+		Sleep: s.opts.Pause,
+		OnChunk: func(c sqln.ChunkProgress) {
+			r.Batches = c.Chunks
+			if s.opts.Progress != nil {
+				s.opts.Progress(p, c)
+			}
+			if c.Last == int64(p.BatchSize) && !s.opts.Window.Contains(s.opts.Now()) {
+				r.Stopped = true
+				cancel()
+			}
+		},
+	})
+	r.Deleted = deleted
+	switch {
+	case r.Stopped && ctx.Err() == nil:
+	case err != nil:
+		r.Err = errors.Wrapf(err, "retention: %v", p.Table)
+	}
+
+	r.Duration = s.opts.Now().Sub(start)
+	return r
+}
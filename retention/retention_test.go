@@ -0,0 +1,80 @@
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nstogner/sqln"
+	"github.com/nstogner/sqln/sqlntest"
+)
+
+func TestWindowContains(t *testing.T) {
+	at := func(h int) time.Time {
+		return time.Date(2019, 9, 1, h, 30, 0, 0, time.UTC)
+	}
+
+	cases := []struct {
+		w   Window
+		t   time.Time
+		exp bool
+	}{
+		{Window{}, at(12), true},
+		{Window{Start: 1 * time.Hour, End: 5 * time.Hour}, at(2), true},
+		{Window{Start: 1 * time.Hour, End: 5 * time.Hour}, at(5), false},
+		{Window{Start: 22 * time.Hour, End: 4 * time.Hour}, at(23), true},
+		{Window{Start: 22 * time.Hour, End: 4 * time.Hour}, at(3), true},
+		{Window{Start: 22 * time.Hour, End: 4 * time.Hour}, at(12), false},
+	}
+	for i, c := range cases {
+		if got := c.w.Contains(c.t); got != c.exp {
+			t.Errorf("case %v: expected %v, got %v", i, c.exp, got)
+		}
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	db := sqlntest.NewPostgres(t, sqlntest.PostgresOptions{})
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE ret_events (id SERIAL PRIMARY KEY, created_at TIMESTAMPTZ NOT NULL);", nil); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 12; i++ {
+		if _, err := db.Exec(ctx, "INSERT INTO ret_events (created_at) VALUES (:at);", map[string]interface{}{"at": now.Add(-time.Duration(i) * time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	policy := Policy{Table: "ret_events", Column: "created_at", Key: "id", MaxAge: 150 * time.Minute, BatchSize: 4}
+	dry := New(db, Options{DryRun: true, Now: func() time.Time { return now }})
+	if err := dry.Register(policy); err != nil {
+		t.Fatal(err)
+	}
+	reports := dry.RunOnce(ctx)
+	if len(reports) != 1 || reports[0].Err != nil || !reports[0].DryRun || reports[0].Deleted != 9 {
+		t.Fatalf("unexpected dry run reports: %+v", reports)
+	}
+
+	var progress []sqln.ChunkProgress
+	s := New(db, Options{
+		Pause:    time.Millisecond,
+		Now:      func() time.Time { return now },
+		Progress: func(_ Policy, c sqln.ChunkProgress) { progress = append(progress, c) },
+	})
+	if err := s.Register(policy); err != nil {
+		t.Fatal(err)
+	}
+	reports = s.RunOnce(ctx)
+	if len(reports) != 1 || reports[0].Err != nil || reports[0].Deleted != 9 || reports[0].Batches != 3 {
+		t.Fatalf("unexpected reports: %+v", reports)
+	}
+	if len(progress) != 3 || progress[2].RowsAffected != 9 {
+		t.Fatalf("unexpected progress: %+v", progress)
+	}
+
+	var left int
+	if err := db.Get(ctx, "SELECT COUNT(*) FROM ret_events;", &left, nil); err != nil || left != 3 {
+		t.Fatalf("expected 3 rows left, got %v %v", left, err)
+	}
+}
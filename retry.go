@@ -0,0 +1,88 @@
+package sqln
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryOptions configures RetryGet, RetrySelect and Transactional.
+type RetryOptions struct {
+	// Attempts is the maximum number of attempts. Defaults to 3.
+	Attempts int
+	// Backoff is the delay before the first retry, doubling up to
+	// MaxBackoff, with jitter. Defaults to 50ms and 1s.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Retryable reports whether an error is worth retrying. Defaults to
+	// Transient.
+	Retryable func(error) bool
+	// Clock times the backoff (see TimerClock). Defaults to SystemClock.
+	Clock Clock
+}
+
+// Transient reports whether err is likely to go away on retry: connection
+// failures (ie. during a failover), too many connections, serialization
+// failures and deadlocks.
+func Transient(err error) bool {
+	switch Classify(err) {
+	case ClassConnection, ClassTooManyConnections, ClassSerializationFailure, ClassDeadlock:
+		return true
+	}
+	return false
+}
+
+// RetryGet gets a single record, retrying transient errors with backoff.
+// Reads in a transaction are not retried since the transaction is usually
+// aborted by the error.
+func RetryGet(ctx context.Context, db DB, query string, dest, params interface{}, opts RetryOptions) error {
+	return retry(ctx, db, opts, func() error {
+		return db.Get(ctx, query, dest, params)
+	})
+}
+
+// RetrySelect selects multiple records, retrying transient errors as
+// RetryGet does.
+func RetrySelect(ctx context.Context, db DB, query string, dest, params interface{}, opts RetryOptions) error {
+	return retry(ctx, db, opts, func() error {
+		return db.Select(ctx, query, dest, params)
+	})
+}
+
+func retry(ctx context.Context, db DB, opts RetryOptions, f func() error) error {
+	if opts.Attempts <= 0 {
+		opts.Attempts = 3
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 50 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = time.Second
+	}
+	if opts.Retryable == nil {
+		opts.Retryable = Transient
+	}
+	if opts.Clock == nil {
+		opts.Clock = SystemClock
+	}
+	if InTx(db) {
+		opts.Attempts = 1
+	}
+
+	backoff := opts.Backoff
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= opts.Attempts || !opts.Retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		// Half the backoff plus up to as much again in jitter.
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		if !wait(opts.Clock, delay, ctx.Done()) {
+			return err
+		}
+		if backoff *= 2; backoff > opts.MaxBackoff {
+			backoff = opts.MaxBackoff
+		}
+	}
+}
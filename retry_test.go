@@ -0,0 +1,99 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// flakyDB fails the first failures reads with err.
+type flakyDB struct {
+	DB
+	failures int
+	err      error
+	calls    int
+}
+
+func (d *flakyDB) Unwrap() DB { return d.DB }
+
+func (d *flakyDB) fail() error {
+	d.calls++
+	if d.calls <= d.failures {
+		return d.err
+	}
+	return nil
+}
+
+func (d *flakyDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.fail(); err != nil {
+		return err
+	}
+	return d.DB.Get(ctx, query, dest, params)
+}
+
+func (d *flakyDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.fail(); err != nil {
+		*dest.(*[]int) = append(*dest.(*[]int), -1)
+		return err
+	}
+	return d.DB.Select(ctx, query, dest, params)
+}
+
+func TestRetryRead(t *testing.T) {
+	ctx := context.Background()
+	opts := RetryOptions{Backoff: time.Millisecond}
+
+	db := &flakyDB{DB: sqliteDB(t), failures: 2, err: errors.Wrap(driver.ErrBadConn, "read")}
+	var n int
+	if err := RetryGet(ctx, db, "SELECT 1;", &n, nil, opts); err != nil || n != 1 || db.calls != 3 {
+		t.Fatalf("expected success on the third attempt, got %v %v after %v calls", n, err, db.calls)
+	}
+
+	db.calls = 0
+	var ns []int
+	if err := RetrySelect(ctx, db, "SELECT 1 UNION ALL SELECT 2;", &ns, nil, opts); err != nil || len(ns) != 2 {
+		t.Fatalf("expected rows of failed attempts to be replaced, got %v %v", ns, err)
+	}
+
+	db.calls, db.failures = 0, 5
+	if err := RetryGet(ctx, db, "SELECT 1;", &n, nil, opts); !errors.Is(err, driver.ErrBadConn) || db.calls != 3 {
+		t.Fatalf("expected failure after 3 attempts, got %v after %v calls", err, db.calls)
+	}
+
+	db.calls, db.err = 0, errors.New("syntax error")
+	if err := RetryGet(ctx, db, "SELECT 1;", &n, nil, opts); err == nil || db.calls != 1 {
+		t.Fatalf("expected no retry of permanent errors, got %v after %v calls", err, db.calls)
+	}
+
+	db.calls, db.err = 0, driver.ErrBadConn
+	err := db.DB.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		return RetryGet(ctx, &flakyDB{DB: tx, failures: 1, err: driver.ErrBadConn}, "SELECT 1;", &n, nil, opts)
+	})
+	if !errors.Is(err, driver.ErrBadConn) {
+		t.Fatalf("expected no retry in a transaction, got %v", err)
+	}
+}
+
+func TestRetryClock(t *testing.T) {
+	ctx := context.Background()
+	clock := NewTestClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	db := &flakyDB{DB: sqliteDB(t), failures: 1, err: driver.ErrBadConn}
+
+	done := make(chan error, 1)
+	go func() {
+		var n int
+		done <- RetryGet(ctx, db, "SELECT 1;", &n, nil, RetryOptions{Backoff: time.Hour, MaxBackoff: time.Hour, Clock: clock})
+	}()
+	// The backoff only elapses when the clock is advanced.
+	for clock.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(time.Hour)
+	if err := <-done; err != nil || db.calls != 2 {
+		t.Fatalf("expected success on the second attempt, got %v after %v calls", err, db.calls)
+	}
+}
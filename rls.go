@@ -0,0 +1,106 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoRowSecurity is returned by the RowSecurity middleware for operations
+// that would run without the row-level security settings.
+var ErrNoRowSecurity = errors.New("sqln: row-level security settings required")
+
+// RowSecurity sets parameters read by Postgres row-level security policies
+// (ie. current_setting('app.user_id')) at the start of every transaction,
+// with the same scope as SET LOCAL. Postgres only.
+type RowSecurity struct {
+	// Params derive the value of each parameter (ie. "app.user_id") from the
+	// context of the transaction. A parameter is not set when ok is false.
+	Params map[string]func(ctx context.Context) (value string, ok bool)
+	// Required rejects operations outside of a transaction, and
+	// transactions for which a parameter has no value, with
+	// ErrNoRowSecurity, so no query runs without the settings.
+	Required bool
+}
+
+// Middleware returns a middleware that applies the settings.
+func (r RowSecurity) Middleware() Middleware {
+	names := make([]string, 0, len(r.Params))
+	for name := range r.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return func(db DB) DB {
+		return &rlsDB{DB: db, r: r, names: names}
+	}
+}
+
+type rlsDB struct {
+	DB
+	r     RowSecurity
+	names []string
+}
+
+func (d *rlsDB) Unwrap() DB { return d.DB }
+
+// check rejects operations outside of a transaction if settings are required.
+func (d *rlsDB) check() error {
+	if d.r.Required && !InTx(d.DB) {
+		return ErrNoRowSecurity
+	}
+	return nil
+}
+
+func (d *rlsDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	if err := d.check(); err != nil {
+		return nil, err
+	}
+	return d.DB.Exec(ctx, query, params)
+}
+
+func (d *rlsDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.check(); err != nil {
+		return err
+	}
+	return d.DB.Get(ctx, query, dest, params)
+}
+
+func (d *rlsDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.check(); err != nil {
+		return err
+	}
+	return d.DB.Select(ctx, query, dest, params)
+}
+
+func (d *rlsDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.check(); err != nil {
+		return err
+	}
+	return d.DB.ExecReturning(ctx, query, dest, params)
+}
+
+func (d *rlsDB) Transact(ctx context.Context, opts sql.TxOptions, f func(DB) error) error {
+	values := make(map[string]string, len(d.names))
+	for _, name := range d.names {
+		v, ok := d.r.Params[name](ctx)
+		if !ok {
+			if d.r.Required {
+				return errors.Wrapf(ErrNoRowSecurity, "no value for %v", name)
+			}
+			continue
+		}
+		values[name] = v
+	}
+	return d.DB.Transact(ctx, opts, func(tx DB) error {
+		for _, name := range d.names {
+			if v, ok := values[name]; ok {
+				if err := setLocal(ctx, tx, name, v); err != nil {
+					return err
+				}
+			}
+		}
+		return f(&rlsDB{DB: tx, r: d.r, names: d.names})
+	})
+}
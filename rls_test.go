@@ -0,0 +1,67 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// setConfigDB records set_config calls, which SQLite does not support.
+type setConfigDB struct {
+	DB
+	set map[string]interface{}
+}
+
+func (d *setConfigDB) Unwrap() DB { return d.DB }
+
+func (d *setConfigDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	if strings.Contains(query, "set_config") {
+		p := params.(map[string]interface{})
+		d.set[p["name"].(string)] = p["value"]
+		return nil, nil
+	}
+	return d.DB.Exec(ctx, query, params)
+}
+
+func (d *setConfigDB) Transact(ctx context.Context, opts sql.TxOptions, f func(DB) error) error {
+	return d.DB.Transact(ctx, opts, func(tx DB) error {
+		return f(&setConfigDB{DB: tx, set: d.set})
+	})
+}
+
+type rlsUserKey struct{}
+
+func TestRowSecurity(t *testing.T) {
+	base := &setConfigDB{DB: sqliteDB(t), set: make(map[string]interface{})}
+	db := RowSecurity{
+		Params: map[string]func(context.Context) (string, bool){
+			"app.user_id": func(ctx context.Context) (string, bool) {
+				v, ok := ctx.Value(rlsUserKey{}).(string)
+				return v, ok
+			},
+		},
+		Required: true,
+	}.Middleware()(base)
+
+	var n int
+	if err := db.Get(context.Background(), "SELECT 1;", &n, nil); err != ErrNoRowSecurity {
+		t.Fatalf("expected ErrNoRowSecurity outside a transaction, got %v", err)
+	}
+	err := db.Transact(context.Background(), sql.TxOptions{}, func(tx DB) error { return nil })
+	if !errors.Is(err, ErrNoRowSecurity) {
+		t.Fatalf("expected ErrNoRowSecurity without a user, got %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), rlsUserKey{}, "42")
+	if err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		return tx.Get(ctx, "SELECT 1;", &n, nil)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if base.set["app.user_id"] != "42" {
+		t.Fatalf("expected app.user_id to be set, got %v", base.set)
+	}
+}
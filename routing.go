@@ -0,0 +1,100 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// ErrMisrouted is returned by VerifyRouting for a write that would run on a
+// replica or in a read only transaction.
+var ErrMisrouted = errors.New("sqln: misrouted write")
+
+// VerifyRouting returns a middleware that fails writes with ErrMisrouted,
+// before they reach the database, when they are made in a read only
+// transaction or through Get or Select outside of a transaction, which
+// Replicated routes to replicas (ie. an UPDATE ... RETURNING run with Get).
+// If replica is set db is a replica handle and every write fails. It is meant
+// for tests and staging, to catch read/write splitting bugs before they reach
+// production. Writes are detected like MutatedTables, plus DDL.
+func VerifyRouting(replica bool) Middleware {
+	return func(db DB) DB {
+		return &routingDB{DB: db, replica: replica}
+	}
+}
+
+var ddlRe = regexp.MustCompile(`(?i)^(CREATE|ALTER|DROP)\b`)
+
+// isWrite reports whether a statement of query writes, ignoring literals and
+// comments.
+func isWrite(query string) bool {
+	for _, stmt := range SplitStatements(query) {
+		stmt = normalize(stmt, true)
+		if ddlRe.MatchString(stmt) || len(MutatedTables(stmt)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type routingDB struct {
+	DB
+	replica  bool
+	tx       bool
+	readOnly bool
+}
+
+func (d *routingDB) Unwrap() DB { return d.DB }
+
+// check returns an ErrMisrouted if query writes and would run on a replica
+// (if read is set, it is run with Get or Select) or in a read only
+// transaction.
+func (d *routingDB) check(query string, read bool) error {
+	switch {
+	case !isWrite(query):
+		return nil
+	case d.replica:
+		return errors.Wrap(ErrMisrouted, "write on a replica")
+	case d.readOnly:
+		return errors.Wrap(ErrMisrouted, "write in a read only transaction")
+	case read && !d.tx:
+		return errors.Wrap(ErrMisrouted, "write routed as a read")
+	}
+	return nil
+}
+
+func (d *routingDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	if err := d.check(query, false); err != nil {
+		return nil, err
+	}
+	return d.DB.Exec(ctx, query, params)
+}
+
+func (d *routingDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.check(query, false); err != nil {
+		return err
+	}
+	return d.DB.ExecReturning(ctx, query, dest, params)
+}
+
+func (d *routingDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.check(query, true); err != nil {
+		return err
+	}
+	return d.DB.Get(ctx, query, dest, params)
+}
+
+func (d *routingDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	if err := d.check(query, true); err != nil {
+		return err
+	}
+	return d.DB.Select(ctx, query, dest, params)
+}
+
+func (d *routingDB) Transact(ctx context.Context, opts sql.TxOptions, f func(DB) error) error {
+	return d.DB.Transact(ctx, opts, func(tx DB) error {
+		return f(&routingDB{DB: tx, replica: d.replica, tx: true, readOnly: d.readOnly || opts.ReadOnly})
+	})
+}
@@ -0,0 +1,51 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestVerifyRouting(t *testing.T) {
+	base := sqliteDB(t)
+	ctx := context.Background()
+	if _, err := base.Exec(ctx, "CREATE TABLE routing_abc (id INTEGER PRIMARY KEY, x TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	db := Wrap(base, VerifyRouting(false))
+	if _, err := db.Exec(ctx, "INSERT INTO routing_abc (id, x) VALUES (1, 'update');", nil); err != nil {
+		t.Fatal(err)
+	}
+	var x string
+	if err := db.Get(ctx, "SELECT x FROM routing_abc WHERE x = 'delete from';", &x, nil); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		t.Fatal(err)
+	}
+	var ids []int
+	if err := db.Select(ctx, "UPDATE routing_abc SET x = 'y' RETURNING id;", &ids, nil); !errors.Is(err, ErrMisrouted) {
+		t.Fatalf("expected a write through Select to be misrouted, got %v", err)
+	}
+
+	if err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		return tx.Select(ctx, "UPDATE routing_abc SET x = 'y' RETURNING id;", &ids, nil)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	err := db.Transact(ctx, sql.TxOptions{ReadOnly: true}, func(tx DB) error {
+		if err := tx.Get(ctx, "SELECT x FROM routing_abc;", &x, nil); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "DELETE FROM routing_abc;", nil)
+		return err
+	})
+	if !errors.Is(err, ErrMisrouted) {
+		t.Fatalf("expected a write in a read only tx to be misrouted, got %v", err)
+	}
+
+	replica := Wrap(base, VerifyRouting(true))
+	if _, err := replica.Exec(ctx, "CREATE TABLE routing_def (id INTEGER);", nil); !errors.Is(err, ErrMisrouted) {
+		t.Fatalf("expected DDL on a replica to be misrouted, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package sqln
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/pkg/errors"
+)
+
+// ErrRowLimit matches (with errors.Is) the *RowLimitError returned for a
+// result set over the row limit.
+var ErrRowLimit = errors.New("sqln: row limit exceeded")
+
+// RowLimitError is returned by Select for a result set over the row limit.
+type RowLimitError struct {
+	Query string
+	Max   int
+	// Rows is the number of rows in the result set.
+	Rows int
+}
+
+func (e *RowLimitError) Error() string {
+	return fmt.Sprintf("sqln: %v rows exceed the limit of %v", e.Rows, e.Max)
+}
+
+// Is reports whether target is ErrRowLimit.
+func (e *RowLimitError) Is(target error) bool { return target == ErrRowLimit }
+
+// RowLimitPolicy is what Select does with a result set over the row limit.
+type RowLimitPolicy int
+
+// Row limit policies.
+const (
+	// RowLimitFail fails with a *RowLimitError.
+	RowLimitFail RowLimitPolicy = iota
+	// RowLimitTruncate returns the first Max rows, reporting the truncation
+	// to OnExceeded and WithResultInfo.
+	RowLimitTruncate
+	// RowLimitBatch switches calls whose context has WithRowBatches to
+	// streaming: dest receives the rows in batches of Max. Other calls fail
+	// as with RowLimitFail.
+	RowLimitBatch
+)
+
+// RowLimit bounds the rows of a Select (see WithRowLimit).
+type RowLimit struct {
+	Max    int
+	Policy RowLimitPolicy
+	// OnExceeded is called with the number of rows of every result set over
+	// Max, ie. to find queries missing a LIMIT.
+	OnExceeded func(ctx context.Context, query string, rows int)
+}
+
+// WithRowLimit bounds the number of rows Select scans into memory, so a query
+// missing a LIMIT cannot exhaust it. Rows past the limit are counted but not
+// scanned, so the full result set is still read from the database.
+// Internal queries (ie. of SelectCursor) are not limited.
+func WithRowLimit(l RowLimit) Option {
+	return func(d *Database) {
+		if l.Max > 0 {
+			d.rowLimit = &l
+		}
+	}
+}
+
+// ResultInfo describes the result set of a Select with a row limit.
+type ResultInfo struct {
+	// Rows is the number of rows in the result set, including any not
+	// returned.
+	Rows      int
+	Truncated bool
+}
+
+// WithResultInfo returns a context in which Select records its result set,
+// under a row limit, in the returned ResultInfo. It is not safe to use the
+// context for concurrent calls.
+func WithResultInfo(ctx context.Context) (context.Context, *ResultInfo) {
+	info := &ResultInfo{}
+	return context.WithValue(ctx, resultInfoKey, info), info
+}
+
+// WithRowBatches returns a context in which a Select over a RowLimitBatch
+// limit fills dest with successive batches of rows, calling f after each, as
+// SelectCursor does. Iteration stops at the first error from f, which is
+// returned.
+func WithRowBatches(ctx context.Context, f func() error) context.Context {
+	return context.WithValue(ctx, rowBatchesKey, f)
+}
+
+// limitedScan scans rows into dest, a pointer to a slice, up to the row
+// limit.
+func (d *Database) limitedScan(ctx context.Context, query string, rows *sqlx.Rows, dest interface{}) error {
+	defer rows.Close()
+	l := d.rowLimit
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return errors.New("sqln: dest must be a pointer to a slice")
+	}
+	if d.strict.AllFields {
+		if err := checkFields(rows, dest); err != nil {
+			return err
+		}
+	}
+	var batch func() error
+	if l.Policy == RowLimitBatch {
+		batch, _ = ctx.Value(rowBatchesKey).(func() error)
+	}
+
+	elem := v.Elem().Type().Elem()
+	base := reflectx.Deref(elem)
+	scan := func(item reflect.Value) error { return rows.Scan(item.Interface()) }
+	switch {
+	case base == mapType:
+		scan = func(item reflect.Value) error {
+			m := make(map[string]interface{})
+			item.Elem().Set(reflect.ValueOf(m))
+			return rows.MapScan(m)
+		}
+	case base.Kind() == reflect.Struct && !reflect.PtrTo(base).Implements(scannerType):
+		scan = func(item reflect.Value) error { return rows.StructScan(item.Interface()) }
+	}
+
+	slice := reflect.MakeSlice(v.Elem().Type(), 0, 0)
+	n := 0
+	for rows.Next() {
+		n++
+		if slice.Len() == l.Max {
+			if batch == nil {
+				continue
+			}
+			v.Elem().Set(slice)
+			if err := batch(); err != nil {
+				return err
+			}
+			slice = reflect.MakeSlice(v.Elem().Type(), 0, l.Max)
+		}
+		item := reflect.New(base)
+		if err := scan(item); err != nil {
+			return err
+		}
+		if elem.Kind() == reflect.Ptr {
+			slice = reflect.Append(slice, item)
+		} else {
+			slice = reflect.Append(slice, item.Elem())
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	info, _ := ctx.Value(resultInfoKey).(*ResultInfo)
+	if info != nil {
+		*info = ResultInfo{Rows: n}
+	}
+	if n > l.Max && l.OnExceeded != nil {
+		l.OnExceeded(ctx, query, n)
+	}
+	switch {
+	case n <= l.Max:
+	case batch != nil:
+		v.Elem().Set(slice)
+		return batch()
+	case l.Policy != RowLimitTruncate:
+		return &RowLimitError{Query: query, Max: l.Max, Rows: n}
+	case info != nil:
+		info.Truncated = true
+	}
+	v.Elem().Set(slice)
+	return nil
+}
@@ -0,0 +1,63 @@
+package sqln
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestRowLimit(t *testing.T) {
+	base := sqliteDB(t)
+	ctx := context.Background()
+	const q = "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 5) SELECT i FROM n;"
+
+	var exceeded []int
+	onExceeded := func(ctx context.Context, query string, rows int) { exceeded = append(exceeded, rows) }
+	db := New(base.X, WithRowLimit(RowLimit{Max: 2, OnExceeded: onExceeded}))
+	defer db.Close()
+
+	var ns []int
+	err := db.Select(ctx, q, &ns, nil)
+	var limitErr *RowLimitError
+	if !errors.As(err, &limitErr) || !errors.Is(err, ErrRowLimit) || limitErr.Rows != 5 || limitErr.Max != 2 || ns != nil {
+		t.Fatalf("expected a row limit error, got %v %v", err, ns)
+	}
+	if err := db.SelectUnprepared(ctx, q, &ns, nil); !errors.Is(err, ErrRowLimit) {
+		t.Fatalf("expected unprepared selects to be limited, got %v", err)
+	}
+	if err := db.Select(ctx, "SELECT 1 UNION ALL SELECT 2;", &ns, nil); err != nil || !reflect.DeepEqual(ns, []int{1, 2}) {
+		t.Fatalf("expected results within the limit, got %v %v", ns, err)
+	}
+
+	truncating := New(base.X, WithRowLimit(RowLimit{Max: 2, Policy: RowLimitTruncate, OnExceeded: onExceeded}))
+	defer truncating.Close()
+	infoCtx, info := WithResultInfo(ctx)
+	var rows []map[string]interface{}
+	if err := truncating.Select(infoCtx, q, &rows, nil); err != nil || len(rows) != 2 || rows[1]["i"] != int64(2) {
+		t.Fatalf("expected 2 rows, got %v %v", rows, err)
+	}
+	if *info != (ResultInfo{Rows: 5, Truncated: true}) {
+		t.Fatalf("unexpected result info: %+v", info)
+	}
+
+	batching := New(base.X, WithRowLimit(RowLimit{Max: 2, Policy: RowLimitBatch}))
+	defer batching.Close()
+	var batches [][]int
+	ns = nil
+	batchCtx := WithRowBatches(ctx, func() error {
+		batches = append(batches, ns)
+		return nil
+	})
+	if err := batching.Select(batchCtx, q, &ns, nil); err != nil || !reflect.DeepEqual(batches, [][]int{{1, 2}, {3, 4}, {5}}) {
+		t.Fatalf("unexpected batches: %v %v", batches, err)
+	}
+	if err := batching.Select(ctx, q, &ns, nil); !errors.Is(err, ErrRowLimit) {
+		t.Fatalf("expected a row limit error without batches, got %v", err)
+	}
+
+	if !reflect.DeepEqual(exceeded, []int{5, 5, 5}) {
+		t.Fatalf("unexpected exceeded calls: %v", exceeded)
+	}
+}
@@ -0,0 +1,144 @@
+package sqln
+
+import (
+	"context"
+	"iter"
+	"reflect"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Rows runs query and returns an iterator over its rows scanned into T (a
+// struct, a map[string]interface{} or a scannable value, or a pointer to
+// one), ie.
+//
+//	for u, err := range sqln.Rows[User](ctx, db, "SELECT * FROM users;", nil) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// On a *Database rows are streamed from its cached statement and closed when
+// the loop ends, including on break. Other DBs, such as middleware, are read
+// in full with Select before iterating. An error is yielded at most once,
+// ending the iteration.
+func Rows[T any](ctx context.Context, db DB, query string, params interface{}) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		d, ok := db.(*Database)
+		if !ok || d.isDynamic(query) {
+			var all []T
+			if err := db.Select(ctx, query, &all, params); err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, v := range all {
+				if !yield(v, nil) {
+					return
+				}
+			}
+			return
+		}
+
+		rows, done, err := d.queryRows(ctx, query, params)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer func() { done(err) }()
+		for rows.Next() {
+			var v T
+			if err = scanRow(rows, &v); err != nil {
+				yield(zero, nameErr(ctx, query, err))
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield(zero, nameErr(ctx, query, err))
+		}
+	}
+}
+
+// queryRows runs query on a cached statement, returning its rows and a func
+// to call with the scan error, if any, once done with them.
+func (d *Database) queryRows(ctx context.Context, query string, params interface{}) (rows *sqlx.Rows, done func(error), err error) {
+	defer func() { err = nameErr(ctx, query, d.diagnoseLocks(ctx, query, err)) }()
+	ctx, end, err := d.begin(ctx, "Select", query)
+	if err != nil {
+		return nil, nil, err
+	}
+	start := time.Now()
+	fail := func(err error) (*sqlx.Rows, func(error), error) {
+		d.queryStats.measure(query, start, &err)
+		end()
+		return nil, nil, err
+	}
+
+	if err := d.checkQuery(ctx, query); err != nil {
+		return fail(err)
+	}
+	if err := d.checkPlan(ctx, query, params); err != nil {
+		return fail(err)
+	}
+	s, release, err := d.acquire(ctx, query)
+	if err != nil {
+		return fail(err)
+	}
+	if err := d.checkParams(s, params); err != nil {
+		release()
+		return fail(err)
+	}
+	if params == nil {
+		params = struct{}{}
+	}
+	params = d.arrayParams(params)
+
+	queryx := s.QueryxContext
+	if d.tx != nil {
+		queryx = d.txStmt(s).QueryxContext
+	}
+	if rows, err = queryx(ctx, params); err != nil {
+		release()
+		return fail(err)
+	}
+	return rows, func(err error) {
+		rows.Close()
+		release()
+		d.observe(ctx, query, params, start)
+		d.queryStats.measure(query, start, &err)
+		end()
+	}, nil
+}
+
+// scanRow scans the current row into dest, a pointer to a struct, a map (see
+// SelectMaps) or a scannable value, or to a pointer to one, which is
+// allocated.
+func scanRow(rows *sqlx.Rows, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("sqln: dest must be a non-nil pointer")
+	}
+	if v.Elem().Kind() == reflect.Ptr {
+		v.Elem().Set(reflect.New(v.Elem().Type().Elem()))
+		v = v.Elem()
+	}
+	base := v.Elem().Type()
+	switch {
+	case base.Kind() == reflect.Struct && !v.Type().Implements(scannerType):
+		return rows.StructScan(v.Interface())
+	case base == mapType:
+		m := make(map[string]interface{})
+		if err := rows.MapScan(m); err != nil {
+			return err
+		}
+		v.Elem().Set(reflect.ValueOf(m))
+		return nil
+	}
+	return rows.Scan(v.Interface())
+}
@@ -0,0 +1,71 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRows(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, "CREATE TABLE rows_abc (id INTEGER, name TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, "INSERT INTO rows_abc (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c');", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	type row struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	const query = "SELECT id, name FROM rows_abc WHERE id >= :min ORDER BY id;"
+	var names string
+	for r, err := range Rows[*row](ctx, db, query, map[string]interface{}{"min": 2}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names += r.Name
+	}
+	if names != "bc" {
+		t.Fatalf("unexpected rows: %q", names)
+	}
+
+	for id, err := range Rows[int](ctx, db, "SELECT id FROM rows_abc ORDER BY id;", nil) {
+		if err != nil || id != 1 {
+			t.Fatalf("unexpected row: %v %v", id, err)
+		}
+		break
+	}
+	for _, s := range db.Statements() {
+		if s.InUse != 0 {
+			t.Fatalf("expected statements to be released, got %+v", s)
+		}
+	}
+
+	var n int
+	for r, err := range Rows[row](ctx, Wrap(db, DetectRepeatedQueries(RepeatedQueries{})), query, map[string]interface{}{"min": 1}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+		if r.ID != n {
+			t.Fatalf("unexpected row: %+v", r)
+		}
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 rows through middleware, got %v", n)
+	}
+
+	var errs int
+	for _, err := range Rows[row](ctx, db, "SELECT id FROM rows_missing;", nil) {
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Fatalf("expected a single error, got %v", errs)
+	}
+}
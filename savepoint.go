@@ -0,0 +1,88 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+var savepointName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Savepointer is implemented by DBs with native savepoint support.
+type Savepointer interface {
+	Savepoint(ctx context.Context, name string, f func(DB) error) error
+}
+
+// Savepoint runs f within a savepoint of the transaction tx. If f returns an
+// error the transaction is rolled back to the savepoint (and the error is
+// returned) but remains usable, ie. to tolerate an expected unique violation
+// mid-transaction.
+func Savepoint(ctx context.Context, tx DB, name string, f func(DB) error) error {
+	if sp, ok := tx.(Savepointer); ok {
+		return sp.Savepoint(ctx, name, f)
+	}
+	return savepoint(ctx, tx, name, f)
+}
+
+// Savepoint runs f within a savepoint, see the Savepoint function.
+func (d *Database) Savepoint(ctx context.Context, name string, f func(DB) error) error {
+	if d.tx == nil {
+		return errors.New("savepoint outside of a transaction")
+	}
+	return savepoint(ctx, d, name, f)
+}
+
+func savepoint(ctx context.Context, tx DB, name string, f func(DB) error) error {
+	if !savepointName.MatchString(name) {
+		return errors.Errorf("invalid savepoint name %q", name)
+	}
+
+	ctx = withInternal(ctx)
+	if _, err := tx.Exec(ctx, "SAVEPOINT "+name+";", nil); err != nil {
+		return errors.Wrapf(err, "savepoint %v", name)
+	}
+	if err := f(tx); err != nil {
+		if _, rbErr := tx.Exec(ctx, "ROLLBACK TO SAVEPOINT "+name+";", nil); rbErr != nil {
+			return errors.Wrapf(rbErr, "savepoint %v: rollback", name)
+		}
+		return errors.Wrapf(err, "savepoint %v", name)
+	}
+	if _, err := tx.Exec(ctx, "RELEASE SAVEPOINT "+name+";", nil); err != nil {
+		return errors.Wrapf(err, "savepoint %v: release", name)
+	}
+	return nil
+}
+
+// TryExec executes a statement, returning the class of the error instead of
+// the error when it is one of the expected classes (defaults to
+// ClassUniqueViolation), ie. for "insert if not exists". In a transaction
+// the statement runs in a savepoint, so an expected error does not abort the
+// transaction. ClassUnknown is returned when the statement succeeds.
+func TryExec(ctx context.Context, db DB, query string, params interface{}, expected ...ErrorClass) (sql.Result, ErrorClass, error) {
+	if len(expected) == 0 {
+		expected = []ErrorClass{ClassUniqueViolation}
+	}
+	var res sql.Result
+	exec := func(db DB) (err error) {
+		res, err = db.Exec(ctx, query, params)
+		return err
+	}
+	var err error
+	if InTx(db) {
+		err = Savepoint(ctx, db, "sqln_try", exec)
+	} else {
+		err = exec(db)
+	}
+	if err == nil {
+		return res, ClassUnknown, nil
+	}
+	class := Classify(err)
+	for _, c := range expected {
+		if class == c {
+			return nil, class, nil
+		}
+	}
+	return nil, ClassUnknown, err
+}
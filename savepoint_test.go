@@ -0,0 +1,81 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestSavepoint(t *testing.T) {
+	db := sqliteDB(t)
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE savepoint_abc (id INTEGER PRIMARY KEY);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Savepoint(ctx, "sp", func(DB) error { return nil }); err == nil {
+		t.Fatal("expected error outside of a transaction")
+	}
+
+	const insert = "INSERT INTO savepoint_abc (id) VALUES (:id);"
+	if err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		if _, err := tx.Exec(ctx, insert, map[string]interface{}{"id": 1}); err != nil {
+			return err
+		}
+		err := Savepoint(ctx, tx, "dup", func(tx DB) error {
+			if _, err := tx.Exec(ctx, insert, map[string]interface{}{"id": 2}); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, insert, map[string]interface{}{"id": 1})
+			return err
+		})
+		if Classify(err) != ClassUniqueViolation {
+			t.Errorf("expected unique violation, got %v", err)
+		}
+		_, err = tx.Exec(ctx, insert, map[string]interface{}{"id": 3})
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	if err := db.Select(ctx, "SELECT id FROM savepoint_abc ORDER BY id;", &ids, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Fatalf("expected [1 3], got %v", ids)
+	}
+}
+
+func TestTryExec(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE try_exec (id INTEGER PRIMARY KEY);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	const insert = "INSERT INTO try_exec VALUES (:id);"
+	if err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		for _, id := range []int{1, 1, 2} {
+			if _, _, err := TryExec(ctx, tx, insert, map[string]interface{}{"id": id}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.Get(ctx, "SELECT count(*) FROM try_exec;", &n, nil); err != nil || n != 2 {
+		t.Fatalf("expected 2 rows, got %v, %v", n, err)
+	}
+
+	_, class, err := TryExec(ctx, db, insert, map[string]interface{}{"id": 1})
+	if err != nil || class != ClassUniqueViolation {
+		t.Fatalf("expected an absorbed unique violation, got %v, %v", class, err)
+	}
+	if _, _, err := TryExec(ctx, db, insert, map[string]interface{}{"id": 1}, ClassCheckViolation); err == nil {
+		t.Fatal("expected an unexpected class to be returned as an error")
+	}
+}
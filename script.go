@@ -0,0 +1,145 @@
+package sqln
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Scripter is implemented by DBs that can execute scripts of several
+// statements.
+type Scripter interface {
+	ExecScript(ctx context.Context, script string) error
+}
+
+// ExecScript executes each statement of script in turn on db, or the DB it
+// decorates, ie. for schema setup in tests and operational scripts. See
+// SplitStatements for how the script is split. Statements are executed as is:
+// they are not prepared and take no params, so casts such as ::int and
+// PL/pgSQL assignments are left alone. A script is not run in a transaction
+// unless db is one.
+func ExecScript(ctx context.Context, db DB, script string) error {
+	for d := db; d != nil; {
+		if s, ok := d.(Scripter); ok {
+			return s.ExecScript(ctx, script)
+		}
+		u, ok := d.(Unwrapper)
+		if !ok {
+			break
+		}
+		d = u.Unwrap()
+	}
+	return errors.Errorf("scripts not supported by %T", db)
+}
+
+// ExecScript executes each statement of script in turn, see the ExecScript
+// function.
+func (d *Database) ExecScript(ctx context.Context, script string) error {
+	for i, stmt := range SplitStatements(script) {
+		if err := d.execScript(ctx, stmt); err != nil {
+			return errors.Wrapf(err, "statement %v", i+1)
+		}
+	}
+	return nil
+}
+
+func (d *Database) execScript(ctx context.Context, stmt string) error {
+	ctx, done, err := d.begin(ctx, "ExecScript", stmt)
+	if err != nil {
+		return err
+	}
+	defer done()
+	if err := d.checkQuery(ctx, stmt); err != nil {
+		return err
+	}
+	_, err = d.ext().ExecContext(ctx, stmt)
+	return nameErr(ctx, stmt, err)
+}
+
+// SplitStatements splits a script into statements at semicolons, ignoring
+// those in string literals, quoted identifiers, comments and dollar-quoted
+// strings (ie. the bodies of Postgres functions). Statements keep their
+// semicolon; empty statements are dropped.
+// NOTE: MySQL DELIMITER commands are not supported.
+func SplitStatements(script string) []string {
+	var (
+		stmts []string
+		start int
+	)
+	add := func(end int) {
+		if s := strings.TrimSpace(script[start:end]); s != "" && s != ";" {
+			stmts = append(stmts, s)
+		}
+		start = end
+	}
+	for i := 0; i < len(script); i++ {
+		switch c := script[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(script, i, c)
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			if n := strings.IndexByte(script[i:], '\n'); n >= 0 {
+				i += n
+			} else {
+				i = len(script)
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			if n := strings.Index(script[i+2:], "*/"); n >= 0 {
+				i += n + 3
+			} else {
+				i = len(script)
+			}
+		case c == '$':
+			if tag, ok := dollarTag(script, i); ok {
+				if n := strings.Index(script[i+len(tag):], tag); n >= 0 {
+					i += len(tag) + n + len(tag) - 1
+				} else {
+					i = len(script)
+				}
+			}
+		case c == ';':
+			add(i + 1)
+		}
+	}
+	add(len(script))
+	return stmts
+}
+
+// skipQuoted returns the index of the quote closing the one at i. Doubled
+// quotes are escapes.
+func skipQuoted(s string, i int, q byte) int {
+	for j := i + 1; j < len(s); j++ {
+		if s[j] != q {
+			continue
+		}
+		if j+1 < len(s) && s[j+1] == q {
+			j++
+			continue
+		}
+		return j
+	}
+	return len(s)
+}
+
+// dollarTag returns the dollar quote ($$ or $tag$) starting at i, if any.
+// Positional params such as $1 and identifiers containing $ are not quotes.
+func dollarTag(s string, i int) (string, bool) {
+	if i > 0 && isIdentByte(s[i-1]) {
+		return "", false
+	}
+	j := i + 1
+	for j < len(s) && isIdentByte(s[j]) {
+		j++
+	}
+	if j >= len(s) || s[j] != '$' {
+		return "", false
+	}
+	if j > i+1 && s[i+1] >= '0' && s[i+1] <= '9' {
+		return "", false
+	}
+	return s[i : j+1], true
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
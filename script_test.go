@@ -0,0 +1,57 @@
+package sqln
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- setup; with a comment
+CREATE TABLE a (id INT, s TEXT DEFAULT 'a;b', "odd;name" INT);
+/* block; comment */
+INSERT INTO a (id) VALUES (1);;
+CREATE FUNCTION f() RETURNS trigger AS $body$
+BEGIN
+	NEW.id := NEW.id + 1;
+	RETURN NEW;
+END;
+$body$ LANGUAGE plpgsql;
+SELECT $$x;y$$, 'it''s;' , $1
+`
+	expected := []string{
+		"-- setup; with a comment\nCREATE TABLE a (id INT, s TEXT DEFAULT 'a;b', \"odd;name\" INT);",
+		"/* block; comment */\nINSERT INTO a (id) VALUES (1);",
+		"CREATE FUNCTION f() RETURNS trigger AS $body$\nBEGIN\n\tNEW.id := NEW.id + 1;\n\tRETURN NEW;\nEND;\n$body$ LANGUAGE plpgsql;",
+		"SELECT $$x;y$$, 'it''s;' , $1",
+	}
+	if got := SplitStatements(script); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected statements:\n%q", got)
+	}
+}
+
+func TestExecScript(t *testing.T) {
+	d := sqliteDB(t)
+	ctx := context.Background()
+	db := Wrap(d, Timestamps(SystemClock))
+
+	err := ExecScript(ctx, db, `
+CREATE TABLE script_things (id INT PRIMARY KEY, name TEXT);
+INSERT INTO script_things VALUES (1, 'a;b');
+INSERT INTO script_things VALUES (2, CAST('2' AS TEXT) || ':x');
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	if err := d.Select(ctx, "SELECT name FROM script_things ORDER BY id;", &names, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"a;b", "2:x"}) {
+		t.Fatalf("unexpected names: %q", names)
+	}
+
+	if err := ExecScript(ctx, db, "INSERT INTO script_things VALUES (3, 'c'); INSERT INTO script_things VALUES (1, 'd');"); err == nil {
+		t.Fatal("expected an error for the duplicate key")
+	}
+}
@@ -0,0 +1,43 @@
+package sqln
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// SelectMap selects rows into a map keyed by the keyColumn field of each row,
+// ie. to batch-load related records by ID. Rows with duplicate keys are an
+// error.
+func SelectMap[K comparable, T any](ctx context.Context, db DB, query, keyColumn string, params interface{}) (map[K]T, error) {
+	var rows []T
+	if err := db.Select(ctx, query, &rows, params); err != nil {
+		return nil, err
+	}
+
+	kt := reflect.TypeOf((*K)(nil)).Elem()
+	mapper, _ := mapperOf(db)
+	m := make(map[K]T, len(rows))
+	for i := range rows {
+		v := reflect.Indirect(reflect.ValueOf(&rows[i]).Elem())
+		if v.Kind() != reflect.Struct {
+			return nil, errors.Errorf("select map: expected struct rows, got %T", rows[i])
+		}
+		f := mapper.FieldByName(v, keyColumn)
+		if !f.IsValid() {
+			return nil, errors.Errorf("select map: key %q not found in %T", keyColumn, rows[i])
+		}
+		// NOTE: Integers convert to strings as runes, which is never wanted.
+		if !f.Type().ConvertibleTo(kt) || (kt.Kind() == reflect.String) != (f.Kind() == reflect.String) {
+			return nil, errors.Errorf("select map: key %q of type %v is not convertible to %v", keyColumn, f.Type(), kt)
+		}
+
+		k := f.Convert(kt).Interface().(K)
+		if _, ok := m[k]; ok {
+			return nil, errors.Errorf("select map: duplicate key %v", k)
+		}
+		m[k] = rows[i]
+	}
+	return m, nil
+}
@@ -0,0 +1,36 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSelectMap(t *testing.T) {
+	db := sqliteDB(t)
+
+	ctx := context.Background()
+	for _, q := range []string{
+		"CREATE TABLE selectmap_abc (id INTEGER PRIMARY KEY, name TEXT NOT NULL);",
+		"INSERT INTO selectmap_abc VALUES (1, 'a'), (2, 'b'), (3, 'c');",
+	} {
+		if _, err := db.Exec(ctx, q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type user struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+	users, err := SelectMap[int, *user](ctx, db, "SELECT * FROM selectmap_abc WHERE id IN (1, 3);", "id", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[1].Name != "a" || users[3].Name != "c" {
+		t.Fatalf("unexpected map: %v", users)
+	}
+
+	if _, err := SelectMap[string, user](ctx, db, "SELECT 'x' AS name, 1 AS id UNION ALL SELECT 'x', 2;", "name", nil); err == nil {
+		t.Fatal("expected error for duplicate keys")
+	}
+}
@@ -0,0 +1,83 @@
+package sqln
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/pkg/errors"
+)
+
+// SessionConn is a driver connection passed to the connection hooks of a
+// wrapped driver (see DriverHooks.OnConnect).
+type SessionConn struct {
+	// Raw is the connection of the parent driver, ie. to register types
+	// with a native driver.
+	Raw driver.Conn
+}
+
+// Exec executes a statement on the connection with positional args.
+func (c SessionConn) Exec(ctx context.Context, query string, args ...interface{}) error {
+	nvs := make([]driver.NamedValue, len(args))
+	for i, a := range args {
+		v, err := driver.DefaultParameterConverter.ConvertValue(a)
+		if err != nil {
+			return errors.Wrapf(err, "arg %v", i)
+		}
+		nvs[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+
+	if e, ok := c.Raw.(driver.ExecerContext); ok {
+		_, err := e.ExecContext(ctx, query, nvs)
+		if err != driver.ErrSkip {
+			return err
+		}
+	}
+	var (
+		s   driver.Stmt
+		err error
+	)
+	if p, ok := c.Raw.(driver.ConnPrepareContext); ok {
+		s, err = p.PrepareContext(ctx, query)
+	} else {
+		s, err = c.Raw.Prepare(query)
+	}
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	if e, ok := s.(driver.StmtExecContext); ok {
+		_, err = e.ExecContext(ctx, nvs)
+		return err
+	}
+	vs, err := values(nvs)
+	if err != nil {
+		return err
+	}
+	_, err = s.Exec(vs)
+	return err
+}
+
+// ExecOnConnect returns a DriverHooks.OnConnect hook executing queries on
+// each new connection, ie. "SET application_name = 'billing';".
+func ExecOnConnect(queries ...string) func(context.Context, SessionConn) error {
+	return func(ctx context.Context, c SessionConn) error {
+		for _, q := range queries {
+			if err := c.Exec(ctx, q); err != nil {
+				return errors.Wrapf(err, "on connect: %q", q)
+			}
+		}
+		return nil
+	}
+}
+
+// connect runs the OnConnect hook on a new connection of the parent driver
+// and wraps it.
+func (h DriverHooks) connect(ctx context.Context, c driver.Conn) (driver.Conn, error) {
+	if h.OnConnect != nil {
+		if err := h.OnConnect(ctx, SessionConn{Raw: c}); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return &hookConn{parent: c, h: h}, nil
+}
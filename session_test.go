@@ -0,0 +1,55 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestConnectionHooks(t *testing.T) {
+	var closed, refuse int32
+	fail := errors.New("refused")
+	hooks := DriverHooks{
+		OnConnect: func(ctx context.Context, c SessionConn) error {
+			if atomic.LoadInt32(&refuse) == 1 {
+				return fail
+			}
+			return ExecOnConnect("PRAGMA foreign_keys = ON;")(ctx, c)
+		},
+		OnClose: func(SessionConn) { atomic.AddInt32(&closed, 1) },
+	}
+	if err := RegisterWrapped("sqln-test-session", "sqlite3", hooks); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := sql.Open("sqln-test-session", filepath.Join(t.TempDir(), "session.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var on int
+	if err := raw.QueryRow("PRAGMA foreign_keys;").Scan(&on); err != nil || on != 1 {
+		t.Fatalf("expected the setting to be applied on connect, got %v, %v", on, err)
+	}
+
+	conn, err := raw.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	atomic.StoreInt32(&refuse, 1)
+	if _, err := raw.Conn(context.Background()); !errors.Is(err, fail) {
+		t.Fatalf("expected the hook error, got %v", err)
+	}
+
+	conn.Close()
+	if err := raw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&closed) == 0 {
+		t.Fatal("expected OnClose to be called")
+	}
+}
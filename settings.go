@@ -0,0 +1,75 @@
+package sqln
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TxSettings are session parameters applied for the duration of a
+// transaction, as with SET LOCAL. Postgres only.
+type TxSettings struct {
+	StatementTimeout time.Duration
+	LockTimeout      time.Duration
+	// Role to assume, ie. for row-level security policies.
+	Role string
+	// Params are any other parameters, ie. {"app.user_id": "42"}.
+	Params map[string]string
+}
+
+// WithTxSettings returns a context whose transactions (see Transact) apply the
+// given settings when they begin.
+func WithTxSettings(ctx context.Context, s TxSettings) context.Context {
+	return context.WithValue(ctx, txSettingsKey, s)
+}
+
+// TxSettingsFromContext returns the settings set by WithTxSettings.
+func TxSettingsFromContext(ctx context.Context) (TxSettings, bool) {
+	s, ok := ctx.Value(txSettingsKey).(TxSettings)
+	return s, ok
+}
+
+// Apply sets the parameters within the transaction tx. It is called by
+// Transact and only needs to be called directly by other DB implementations.
+func (s TxSettings) Apply(ctx context.Context, tx DB) error {
+	if s.StatementTimeout > 0 {
+		if err := setLocal(ctx, tx, "statement_timeout", strconv.FormatInt(s.StatementTimeout.Milliseconds(), 10)); err != nil {
+			return err
+		}
+	}
+	if s.LockTimeout > 0 {
+		if err := setLocal(ctx, tx, "lock_timeout", strconv.FormatInt(s.LockTimeout.Milliseconds(), 10)); err != nil {
+			return err
+		}
+	}
+	if s.Role != "" {
+		if err := setLocal(ctx, tx, "role", s.Role); err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(s.Params))
+	for name := range s.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := setLocal(ctx, tx, name, s.Params[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setLocal sets a parameter until the end of the current transaction. Unlike
+// SET LOCAL, set_config accepts bound values.
+func setLocal(ctx context.Context, tx DB, name, value string) error {
+	const q = "SELECT set_config(:name, :value, true);"
+	if _, err := tx.Exec(withInternal(ctx), q, map[string]interface{}{"name": name, "value": value}); err != nil {
+		return errors.Wrapf(err, "setting %v", name)
+	}
+	return nil
+}
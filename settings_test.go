@@ -0,0 +1,46 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/nstogner/psqlxtest"
+)
+
+func TestTxSettings(t *testing.T) {
+	dbx, dropx := psqlxtest.TmpDB(t)
+	defer dropx()
+	db := New(dbx)
+	defer db.Close()
+
+	ctx := WithTxSettings(context.Background(), TxSettings{
+		StatementTimeout: 5 * time.Second,
+		LockTimeout:      250 * time.Millisecond,
+		Params:           map[string]string{"app.user_id": "42"},
+	})
+
+	const q = "SELECT current_setting('statement_timeout') || ' ' || current_setting('lock_timeout') || ' ' || current_setting('app.user_id', true);"
+	if err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		var got string
+		if err := tx.Get(ctx, q, &got, nil); err != nil {
+			return err
+		}
+		if exp := "5s 250ms 42"; got != exp {
+			t.Errorf("expected %q, got %q", exp, got)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Settings do not outlive the transaction.
+	var timeout string
+	if err := db.Get(context.Background(), "SELECT current_setting('statement_timeout');", &timeout, nil); err != nil {
+		t.Fatal(err)
+	}
+	if timeout != "0" {
+		t.Fatalf("expected statement_timeout to be reset, got %q", timeout)
+	}
+}
@@ -0,0 +1,140 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// ErrNoShardKey is returned by Sharded operations when the context carries no
+// shard key.
+var ErrNoShardKey = errors.New("no shard key in context")
+
+// WithShardKey returns a context routed to the shard owning key (ie. a tenant
+// or entity ID).
+func WithShardKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, shardKey, key)
+}
+
+// ShardKeyFromContext returns the key set by WithShardKey.
+func ShardKeyFromContext(ctx context.Context) (string, bool) {
+	k, ok := ctx.Value(shardKey).(string)
+	return k, ok
+}
+
+// Sharded routes each operation to one of several DBs by the shard key in the
+// context.
+type Sharded struct {
+	Shards []DB
+
+	// Hash maps a key to a shard index in [0, n). Defaults to FNV-1a.
+	Hash func(key string, n int) int
+}
+
+var _ DB = &Sharded{}
+
+// NewSharded routes across shards. The order of shards must be stable since
+// keys are mapped by index.
+func NewSharded(shards ...DB) *Sharded {
+	return &Sharded{Shards: shards}
+}
+
+func fnvHash(key string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+// ForShard returns the DB that owns key.
+func (s *Sharded) ForShard(key string) DB {
+	hash := s.Hash
+	if hash == nil {
+		hash = fnvHash
+	}
+	return s.Shards[hash(key, len(s.Shards))]
+}
+
+func (s *Sharded) fromContext(ctx context.Context) (DB, error) {
+	key, ok := ShardKeyFromContext(ctx)
+	if !ok {
+		return nil, ErrNoShardKey
+	}
+	return s.ForShard(key), nil
+}
+
+// EachShard calls f for every shard concurrently (ie. for fan-out queries) and
+// returns the first error.
+func (s *Sharded) EachShard(ctx context.Context, f func(ctx context.Context, shard int, db DB) error) error {
+	var (
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		first   error
+	)
+	for i, db := range s.Shards {
+		wg.Add(1)
+		go func(i int, db DB) {
+			defer wg.Done()
+			if err := f(ctx, i, db); err != nil {
+				errOnce.Do(func() { first = errors.Wrapf(err, "shard %v", i) })
+			}
+		}(i, db)
+	}
+	wg.Wait()
+	return first
+}
+
+// Exec a SQL statement on the shard in ctx.
+func (s *Sharded) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	db, err := s.fromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.Exec(ctx, query, params)
+}
+
+// ExecReturning executes a statement on the shard in ctx, scanning its
+// RETURNING columns into dest.
+func (s *Sharded) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	db, err := s.fromContext(ctx)
+	if err != nil {
+		return err
+	}
+	return db.ExecReturning(ctx, query, dest, params)
+}
+
+// Get a single record from the shard in ctx.
+func (s *Sharded) Get(ctx context.Context, query string, dest, params interface{}) error {
+	db, err := s.fromContext(ctx)
+	if err != nil {
+		return err
+	}
+	return db.Get(ctx, query, dest, params)
+}
+
+// Select multiple records from the shard in ctx.
+func (s *Sharded) Select(ctx context.Context, query string, dest, params interface{}) error {
+	db, err := s.fromContext(ctx)
+	if err != nil {
+		return err
+	}
+	return db.Select(ctx, query, dest, params)
+}
+
+// Stmt is not supported since there is no context to route by; use
+// ForShard(key).Stmt instead.
+func (s *Sharded) Stmt(query string) (*sqlx.NamedStmt, error) {
+	return nil, errors.New("sharded: Stmt requires a shard, use ForShard")
+}
+
+// Transact runs f in a transaction on the shard in ctx.
+func (s *Sharded) Transact(ctx context.Context, opts sql.TxOptions, f func(DB) error) error {
+	db, err := s.fromContext(ctx)
+	if err != nil {
+		return err
+	}
+	return db.Transact(ctx, opts, f)
+}
@@ -0,0 +1,59 @@
+package sqln
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSharded(t *testing.T) {
+	s := NewSharded(sqliteDB(t), sqliteDB(t), sqliteDB(t))
+
+	ctx := context.Background()
+	if err := s.EachShard(ctx, func(ctx context.Context, _ int, db DB) error {
+		_, err := db.Exec(ctx, "CREATE TABLE shard_abc (id TEXT PRIMARY KEY);", nil)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Exec(ctx, "INSERT INTO shard_abc (id) VALUES ('x');", nil); err != ErrNoShardKey {
+		t.Fatalf("expected ErrNoShardKey, got %v", err)
+	}
+
+	const keys = 30
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("tenant-%v", i)
+		if _, err := s.Exec(WithShardKey(ctx, key), "INSERT INTO shard_abc (id) VALUES (:id);", map[string]interface{}{"id": key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Every key is found on its own shard.
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("tenant-%v", i)
+		var n int
+		if err := s.ForShard(key).Get(ctx, "SELECT COUNT(*) FROM shard_abc WHERE id = :id;", &n, map[string]interface{}{"id": key}); err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 {
+			t.Fatalf("expected %v on its shard", key)
+		}
+	}
+
+	var total int64
+	if err := s.EachShard(ctx, func(ctx context.Context, _ int, db DB) error {
+		var n int64
+		if err := db.Get(ctx, "SELECT COUNT(*) FROM shard_abc;", &n, nil); err != nil {
+			return err
+		}
+		atomic.AddInt64(&total, n)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if total != keys {
+		t.Fatalf("expected %v rows across shards, got %v", keys, total)
+	}
+}
@@ -0,0 +1,185 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SlowTxSampling configures the SampleSlowTx middleware.
+type SlowTxSampling struct {
+	// Threshold is the duration of a transaction that is reported.
+	Threshold time.Duration
+	// OnSlowTx is called with the timeline of every transaction that took
+	// Threshold or longer, once it ends.
+	OnSlowTx func(ctx context.Context, t TxTimeline)
+	// MaxStatements bounds the statements recorded per transaction, later
+	// ones are only counted. Defaults to 1000.
+	MaxStatements int
+	// Clock times transactions and statements. Defaults to SystemClock.
+	Clock Clock
+}
+
+// TxTimeline is the ordered list of statements run in a slow transaction.
+type TxTimeline struct {
+	Started    time.Time
+	Duration   time.Duration
+	Statements []TimedStatement
+	// Dropped is the number of statements past MaxStatements.
+	Dropped int
+	// Err is the error returned by the transaction, if any.
+	Err error
+}
+
+// TimedStatement is a statement of a TxTimeline.
+type TimedStatement struct {
+	Method string
+	Query  string
+	// Name is the query name, if any (see QueryName).
+	Name string
+	// Offset is the time from the start of the transaction to the start of
+	// the statement, so gaps between statements (time spent in application
+	// code) can be told apart from time spent in the database.
+	Offset   time.Duration
+	Duration time.Duration
+	// Rows is the number of rows affected by an Exec or scanned into dest,
+	// or -1 if unknown.
+	Rows int64
+	Err  error
+}
+
+// SampleSlowTx returns a middleware that records the statements of every
+// transaction and reports the timeline of those that took the threshold or
+// longer, to show why a transaction was slow rather than only that it was.
+// Nested transactions are recorded in the timeline of the outermost one.
+func SampleSlowTx(s SlowTxSampling) Middleware {
+	if s.MaxStatements <= 0 {
+		s.MaxStatements = 1000
+	}
+	if s.Clock == nil {
+		s.Clock = SystemClock
+	}
+	return func(db DB) DB {
+		return newSlowTxDB(db, s, nil)
+	}
+}
+
+func newSlowTxDB(db DB, cfg SlowTxSampling, tl *timeline) DB {
+	return &slowTxDB{BaseDB: BaseDB{DB: db, Wrap: func(tx DB) DB {
+		return newSlowTxDB(tx, cfg, tl)
+	}}, cfg: cfg, tl: tl}
+}
+
+type timeline struct {
+	started time.Time
+
+	mtx        sync.Mutex
+	statements []TimedStatement
+	dropped    int
+}
+
+type slowTxDB struct {
+	BaseDB
+	cfg SlowTxSampling
+	// tl is set in transactions.
+	tl *timeline
+}
+
+// record appends a statement started at start to the timeline.
+func (d *slowTxDB) record(ctx context.Context, method, query string, start time.Time, rows int64, err error) {
+	if d.tl == nil {
+		return
+	}
+	s := TimedStatement{
+		Method:   method,
+		Query:    query,
+		Name:     QueryName(ctx, query),
+		Offset:   start.Sub(d.tl.started),
+		Duration: d.cfg.Clock.Now().Sub(start),
+		Rows:     rows,
+		Err:      err,
+	}
+	d.tl.mtx.Lock()
+	defer d.tl.mtx.Unlock()
+	if len(d.tl.statements) >= d.cfg.MaxStatements {
+		d.tl.dropped++
+		return
+	}
+	d.tl.statements = append(d.tl.statements, s)
+}
+
+func (d *slowTxDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	start := d.cfg.Clock.Now()
+	res, err := d.DB.Exec(ctx, query, params)
+	rows := int64(-1)
+	if err == nil {
+		if n, err := res.RowsAffected(); err == nil {
+			rows = n
+		}
+	}
+	d.record(ctx, "Exec", query, start, rows, err)
+	return res, err
+}
+
+func (d *slowTxDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	start := d.cfg.Clock.Now()
+	err := d.DB.ExecReturning(ctx, query, dest, params)
+	d.record(ctx, "ExecReturning", query, start, scannedRows(dest, err), err)
+	return err
+}
+
+func (d *slowTxDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	start := d.cfg.Clock.Now()
+	err := d.DB.Get(ctx, query, dest, params)
+	rows := int64(1)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		rows = 0
+	case err != nil:
+		rows = -1
+	}
+	d.record(ctx, "Get", query, start, rows, err)
+	return err
+}
+
+func (d *slowTxDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	start := d.cfg.Clock.Now()
+	err := d.DB.Select(ctx, query, dest, params)
+	d.record(ctx, "Select", query, start, scannedRows(dest, err), err)
+	return err
+}
+
+func (d *slowTxDB) Transact(ctx context.Context, opts sql.TxOptions, f func(DB) error) error {
+	if d.tl != nil {
+		return d.BaseDB.Transact(ctx, opts, f)
+	}
+	tl := &timeline{started: d.cfg.Clock.Now()}
+	err := d.DB.Transact(ctx, opts, func(tx DB) error {
+		return f(newSlowTxDB(tx, d.cfg, tl))
+	})
+	elapsed := d.cfg.Clock.Now().Sub(tl.started)
+	if elapsed >= d.cfg.Threshold && d.cfg.OnSlowTx != nil {
+		tl.mtx.Lock()
+		statements, dropped := tl.statements, tl.dropped
+		tl.mtx.Unlock()
+		d.cfg.OnSlowTx(ctx, TxTimeline{Started: tl.started, Duration: elapsed, Statements: statements, Dropped: dropped, Err: err})
+	}
+	return err
+}
+
+// scannedRows returns the number of rows scanned into dest, a slice of rows
+// or a single one, or -1 if unknown.
+func scannedRows(dest interface{}, err error) int64 {
+	if err != nil {
+		return -1
+	}
+	v := reflect.ValueOf(dest)
+	if v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Slice && v.Elem().Type().Elem().Kind() != reflect.Uint8 {
+		return int64(v.Elem().Len())
+	}
+	return 1
+}
@@ -0,0 +1,73 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestSampleSlowTx(t *testing.T) {
+	clock := NewTestClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	var timelines []TxTimeline
+	db := Wrap(sqliteDB(t), SampleSlowTx(SlowTxSampling{
+		Threshold:     time.Second,
+		OnSlowTx:      func(ctx context.Context, tl TxTimeline) { timelines = append(timelines, tl) },
+		MaxStatements: 3,
+		Clock:         clock,
+	}))
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE accounts (id INTEGER, balance INTEGER);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fast transactions are not reported.
+	if err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		_, err := tx.Exec(ctx, "INSERT INTO accounts (id, balance) VALUES (1, 10), (2, 20);", nil)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(timelines) != 0 {
+		t.Fatalf("expected no report, got %+v", timelines)
+	}
+
+	errBoom := errors.New("boom")
+	err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		var ids []int
+		if err := tx.Select(WithQueryName(ctx, "ids"), "SELECT id FROM accounts;", &ids, nil); err != nil {
+			return err
+		}
+		clock.Advance(2 * time.Second)
+		if _, err := tx.Exec(ctx, "UPDATE accounts SET balance = balance + 1;", nil); err != nil {
+			return err
+		}
+		var balance int
+		if err := tx.Get(ctx, "SELECT balance FROM accounts WHERE id = 3;", &balance, nil); !errors.Is(err, sql.ErrNoRows) {
+			return errors.Errorf("expected no rows, got %v", err)
+		}
+		tx.Get(ctx, "SELECT 1;", &balance, nil)
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	if len(timelines) != 1 {
+		t.Fatalf("expected one report, got %+v", timelines)
+	}
+	tl := timelines[0]
+	if tl.Duration != 2*time.Second || !errors.Is(tl.Err, errBoom) || tl.Dropped != 1 || len(tl.Statements) != 3 {
+		t.Fatalf("unexpected timeline %+v", tl)
+	}
+	if s := tl.Statements[0]; s.Method != "Select" || s.Name != "ids" || s.Rows != 2 || s.Offset != 0 {
+		t.Fatalf("unexpected statement %+v", s)
+	}
+	if s := tl.Statements[1]; s.Method != "Exec" || s.Rows != 2 || s.Offset != 2*time.Second {
+		t.Fatalf("unexpected statement %+v", s)
+	}
+	if s := tl.Statements[2]; s.Method != "Get" || s.Rows != 0 || s.Err == nil {
+		t.Fatalf("unexpected statement %+v", s)
+	}
+}
@@ -0,0 +1,316 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DriverEvent is an operation run through a wrapped driver (see WrapDriver).
+type DriverEvent struct {
+	// Method is one of Exec, Query, Prepare, Begin, Commit or Rollback.
+	Method string
+	Query  string
+	// Name is the query name, if any (see QueryName).
+	Name string
+	// Args are not redacted.
+	Args []driver.NamedValue
+	// Duration excludes reading the rows of a Query.
+	Duration time.Duration
+	Err      error
+}
+
+// DriverHooks configures a wrapped driver.
+type DriverHooks struct {
+	// OnEvent is called after each operation, ie. to log it.
+	OnEvent func(ctx context.Context, e DriverEvent)
+	// Stats records Exec and Query in the QueryStats of a Database (see
+	// WithQueryStats), so they are reported with its own operations.
+	Stats *Database
+	// OnConnect is called with each new connection before it is used, ie.
+	// to run SET statements (see ExecOnConnect), so session settings apply
+	// to every connection of the pool, including those opened after churn
+	// or a failover. ctx is that of the operation the connection is opened
+	// for if the parent driver implements driver.DriverContext. The
+	// connection is closed and the error returned if it fails.
+	OnConnect func(ctx context.Context, c SessionConn) error
+	// OnClose is called before a connection is closed by the pool.
+	OnClose func(c SessionConn)
+}
+
+// WrapDriver returns a database/sql driver that reports operations run
+// through parent, so code using a raw *sql.DB is observed like sqln
+// operations. Use it with sql.Register, or RegisterWrapped.
+func WrapDriver(parent driver.Driver, h DriverHooks) driver.Driver {
+	if dc, ok := parent.(driver.DriverContext); ok {
+		return &hookDriverContext{hookDriver{parent: parent, h: h}, dc}
+	}
+	return &hookDriver{parent: parent, h: h}
+}
+
+// WrapConnector is like WrapDriver for a connector, ie. for sql.OpenDB.
+func WrapConnector(c driver.Connector, h DriverHooks) driver.Connector {
+	return &hookConnector{parent: c, h: h}
+}
+
+// RegisterWrapped registers a wrapped (see WrapDriver) copy of the parent
+// driver under name, with the dialect of the parent (see RegisterDriver).
+func RegisterWrapped(name, parent string, h DriverHooks) error {
+	for _, n := range sql.Drivers() {
+		if n == name {
+			return errors.Errorf("sqln: driver %q already registered", name)
+		}
+	}
+	db, err := sql.Open(parent, "")
+	if err != nil {
+		return errors.Wrapf(err, "driver %v", parent)
+	}
+	drv := db.Driver()
+	if err := db.Close(); err != nil {
+		return err
+	}
+	sql.Register(name, WrapDriver(drv, h))
+	RegisterDriver(name, dialectOf(parent))
+	return nil
+}
+
+func (h DriverHooks) report(ctx context.Context, method, query string, args []driver.NamedValue, start time.Time, err error) {
+	if err == driver.ErrSkip {
+		return
+	}
+	if h.Stats != nil && (method == "Exec" || method == "Query") {
+		h.Stats.queryStats.measure(query, start, &err)
+	}
+	if h.OnEvent != nil {
+		h.OnEvent(ctx, DriverEvent{Method: method, Query: query, Name: QueryName(ctx, query), Args: args, Duration: time.Since(start), Err: err})
+	}
+}
+
+type hookDriver struct {
+	parent driver.Driver
+	h      DriverHooks
+}
+
+func (d *hookDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.parent.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return d.h.connect(context.Background(), c)
+}
+
+type hookDriverContext struct {
+	hookDriver
+	dc driver.DriverContext
+}
+
+func (d *hookDriverContext) OpenConnector(name string) (driver.Connector, error) {
+	c, err := d.dc.OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return &hookConnector{parent: c, h: d.h, drv: d}, nil
+}
+
+type hookConnector struct {
+	parent driver.Connector
+	h      DriverHooks
+	drv    driver.Driver
+}
+
+func (c *hookConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.parent.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.h.connect(ctx, conn)
+}
+
+func (c *hookConnector) Driver() driver.Driver {
+	if c.drv != nil {
+		return c.drv
+	}
+	return &hookDriver{parent: c.parent.Driver(), h: c.h}
+}
+
+// hookConn implements the optional interfaces of driver.Conn, falling back
+// to the behavior database/sql uses when the parent does not.
+type hookConn struct {
+	parent driver.Conn
+	h      DriverHooks
+}
+
+func (c *hookConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *hookConn) PrepareContext(ctx context.Context, query string) (s driver.Stmt, err error) {
+	defer func(start time.Time) { c.h.report(ctx, "Prepare", query, nil, start, err) }(time.Now())
+	if p, ok := c.parent.(driver.ConnPrepareContext); ok {
+		s, err = p.PrepareContext(ctx, query)
+	} else {
+		s, err = c.parent.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &hookStmt{parent: s, query: query, h: c.h}, nil
+}
+
+func (c *hookConn) Close() error {
+	if c.h.OnClose != nil {
+		c.h.OnClose(SessionConn{Raw: c.parent})
+	}
+	return c.parent.Close()
+}
+
+func (c *hookConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *hookConn) BeginTx(ctx context.Context, opts driver.TxOptions) (tx driver.Tx, err error) {
+	defer func(start time.Time) { c.h.report(ctx, "Begin", "", nil, start, err) }(time.Now())
+	if b, ok := c.parent.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else if opts != (driver.TxOptions{}) {
+		return nil, errors.New("sqln: driver does not support transaction options")
+	} else {
+		tx, err = c.parent.Begin()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &hookTx{parent: tx, ctx: ctx, h: c.h}, nil
+}
+
+func (c *hookConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (res driver.Result, err error) {
+	e, ok := c.parent.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	defer func(start time.Time) { c.h.report(ctx, "Exec", query, args, start, err) }(time.Now())
+	return e.ExecContext(ctx, query, args)
+}
+
+func (c *hookConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
+	q, ok := c.parent.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	defer func(start time.Time) { c.h.report(ctx, "Query", query, args, start, err) }(time.Now())
+	return q.QueryContext(ctx, query, args)
+}
+
+func (c *hookConn) Ping(ctx context.Context) error {
+	if p, ok := c.parent.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *hookConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.parent.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *hookConn) IsValid() bool {
+	if v, ok := c.parent.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *hookConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if ch, ok := c.parent.(driver.NamedValueChecker); ok {
+		return ch.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type hookStmt struct {
+	parent driver.Stmt
+	query  string
+	h      DriverHooks
+}
+
+func (s *hookStmt) Close() error  { return s.parent.Close() }
+func (s *hookStmt) NumInput() int { return s.parent.NumInput() }
+
+func (s *hookStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *hookStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *hookStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (res driver.Result, err error) {
+	defer func(start time.Time) { s.h.report(ctx, "Exec", s.query, args, start, err) }(time.Now())
+	if e, ok := s.parent.(driver.StmtExecContext); ok {
+		return e.ExecContext(ctx, args)
+	}
+	vs, err := values(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.parent.Exec(vs)
+}
+
+func (s *hookStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (rows driver.Rows, err error) {
+	defer func(start time.Time) { s.h.report(ctx, "Query", s.query, args, start, err) }(time.Now())
+	if q, ok := s.parent.(driver.StmtQueryContext); ok {
+		return q.QueryContext(ctx, args)
+	}
+	vs, err := values(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.parent.Query(vs)
+}
+
+func (s *hookStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if ch, ok := s.parent.(driver.NamedValueChecker); ok {
+		return ch.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type hookTx struct {
+	parent driver.Tx
+	ctx    context.Context
+	h      DriverHooks
+}
+
+func (t *hookTx) Commit() (err error) {
+	defer func(start time.Time) { t.h.report(t.ctx, "Commit", "", nil, start, err) }(time.Now())
+	return t.parent.Commit()
+}
+
+func (t *hookTx) Rollback() (err error) {
+	defer func(start time.Time) { t.h.report(t.ctx, "Rollback", "", nil, start, err) }(time.Now())
+	return t.parent.Rollback()
+}
+
+func namedValues(vs []driver.Value) []driver.NamedValue {
+	nvs := make([]driver.NamedValue, len(vs))
+	for i, v := range vs {
+		nvs[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return nvs
+}
+
+func values(nvs []driver.NamedValue) ([]driver.Value, error) {
+	vs := make([]driver.Value, len(nvs))
+	for i, nv := range nvs {
+		if nv.Name != "" {
+			return nil, errors.New("sqln: driver does not support named args")
+		}
+		vs[i] = nv.Value
+	}
+	return vs, nil
+}
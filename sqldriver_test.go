@@ -0,0 +1,74 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestRegisterWrapped(t *testing.T) {
+	stats := New(sqliteDB(t).X, WithQueryStats(10))
+	defer stats.Close()
+
+	var (
+		mtx    sync.Mutex
+		events []DriverEvent
+	)
+	hooks := DriverHooks{
+		OnEvent: func(ctx context.Context, e DriverEvent) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			events = append(events, e)
+		},
+		Stats: stats,
+	}
+	if err := RegisterWrapped("sqln-test-sqlite3", "sqlite3", hooks); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterWrapped("sqln-test-sqlite3", "sqlite3", hooks); err == nil {
+		t.Fatal("expected duplicate registration to fail")
+	}
+
+	raw, err := sql.Open("sqln-test-sqlite3", filepath.Join(t.TempDir(), "raw.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+
+	ctx := WithQueryName(context.Background(), "create_raw")
+	if _, err := raw.ExecContext(ctx, "CREATE TABLE raw (id INTEGER);"); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := raw.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("INSERT INTO raw VALUES (?);", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := raw.QueryRow("SELECT count(*) FROM raw;").Scan(&n); err != nil || n != 1 {
+		t.Fatalf("expected 1 row, got %v, %v", n, err)
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	methods := make(map[string]int)
+	for _, e := range events {
+		methods[e.Method]++
+		if e.Query == "CREATE TABLE raw (id INTEGER);" && e.Name != "create_raw" {
+			t.Errorf("expected query name, got %q", e.Name)
+		}
+	}
+	if methods["Exec"] != 2 || methods["Query"] != 1 || methods["Begin"] != 1 || methods["Commit"] != 1 {
+		t.Fatalf("unexpected events: %v", methods)
+	}
+	if qs := stats.QueryStats(); len(qs) != 3 {
+		t.Fatalf("expected 3 queries in stats, got %v", qs)
+	}
+}
@@ -0,0 +1,153 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// SQLDB exposes a Database with the query methods of *sql.DB, for libraries
+// (ie. migration tools or generated query code) that accept those rather
+// than a DB. Queries take positional args and run as with ExecPositional,
+// through the statement cache and instrumentation.
+type SQLDB struct {
+	sqlFacade
+}
+
+// SQLTx is a transaction begun by SQLDB.BeginTx, with the query methods of
+// *sql.Tx.
+type SQLTx struct {
+	sqlFacade
+	tx *sqlx.Tx
+}
+
+// SQL returns d as an SQLDB.
+func (d *Database) SQL() *SQLDB {
+	return &SQLDB{sqlFacade{d: d}}
+}
+
+// BeginTx begins a transaction, which must be ended with Commit or Rollback.
+// Prefer Transact where the caller is not bound to this interface.
+func (s *SQLDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*SQLTx, error) {
+	if s.d.tx != nil {
+		return nil, errors.New("nested tx not currently supported")
+	}
+	begin := s.d.drv.BeginTxx
+	if s.d.conn != nil {
+		begin = s.d.conn.BeginTxx
+	}
+	tx, err := begin(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLTx{sqlFacade: sqlFacade{d: s.d.WithTx(tx)}, tx: tx}, nil
+}
+
+// PingContext verifies the connection to the database.
+func (s *SQLDB) PingContext(ctx context.Context) error {
+	return s.d.drv.PingContext(ctx)
+}
+
+// Commit commits the transaction. As with Transact, an error matching
+// ErrCommitAmbiguous means it may have committed.
+func (t *SQLTx) Commit() error {
+	return CommitError(t.tx.Commit())
+}
+
+// Rollback aborts the transaction.
+func (t *SQLTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+type sqlFacade struct {
+	d *Database
+}
+
+// ExecContext executes a statement, see Database.ExecPositional.
+func (s sqlFacade) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.d.ExecPositional(ctx, query, args...)
+}
+
+// QueryContext runs a query, see Database.ExecPositional. The returned rows
+// are bound to ctx rather than to the query timeout (see WithQueryTimeout),
+// and the query is timed until the first row is ready rather than until the
+// rows are closed.
+func (s sqlFacade) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := s.d.positional(ctx, "Query", query, func(_ context.Context, q positionalQuerier, query string) error {
+		var err error
+		rows, err = q.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRowContext runs a query expected to return at most one row, see
+// QueryContext. Errors are deferred until the row is scanned.
+func (s sqlFacade) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	var row *sql.Row
+	err := s.d.positional(ctx, "Query", query, func(_ context.Context, q positionalQuerier, query string) error {
+		rq, ok := q.(rowQuerier)
+		if !ok {
+			return errors.Errorf("sqln: %T does not support QueryRowContext", q)
+		}
+		row = rq.QueryRowContext(ctx, query, args...)
+		return row.Err()
+	})
+	if row == nil {
+		return errRow(err)
+	}
+	return row
+}
+
+// PrepareContext prepares a statement on the pool, or the transaction. The
+// statement bypasses the statement cache and instrumentation.
+func (s sqlFacade) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	if s.d.tx != nil {
+		return s.d.tx.PrepareContext(ctx, query)
+	}
+	p, ok := s.d.ext().(interface {
+		PrepareContext(context.Context, string) (*sql.Stmt, error)
+	})
+	if !ok {
+		return nil, errors.Errorf("sqln: %T does not support prepare", s.d.ext())
+	}
+	return p.PrepareContext(ctx, query)
+}
+
+// rowQuerier is implemented by the positionalQueriers.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (q stmtQuerier) QueryRowContext(ctx context.Context, _ string, args ...interface{}) *sql.Row {
+	return q.s.Stmt.QueryRowContext(ctx, args...)
+}
+
+// errRow returns a row whose Scan returns err. A *sql.Row cannot be created
+// outside of database/sql, so it is queried with a context reporting err
+// from a DB that never opens a connection.
+func errRow(err error) *sql.Row {
+	return noConnDB.QueryRowContext(errContext{err: err}, "")
+}
+
+var noConnDB = &sql.DB{}
+
+// errContext is a done context whose Err is err.
+type errContext struct {
+	err error
+}
+
+var closedDone = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+func (errContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (errContext) Done() <-chan struct{}             { return closedDone }
+func (c errContext) Err() error                      { return c.err }
+func (errContext) Value(key interface{}) interface{} { return nil }
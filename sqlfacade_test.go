@@ -0,0 +1,99 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// sqlQuerier is the interface libraries commonly require of a *sql.DB.
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+var (
+	_ sqlQuerier = (*SQLDB)(nil)
+	_ sqlQuerier = (*SQLTx)(nil)
+	_ sqlQuerier = (*sql.DB)(nil)
+)
+
+func TestSQLDB(t *testing.T) {
+	d := sqliteDB(t)
+	db := d.SQL()
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, "CREATE TABLE notes (id INTEGER, body TEXT);"); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, body := range []string{"a", "b"} {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO notes (id, body) VALUES (?, ?);", i+1, body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT body FROM notes ORDER BY id;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bodies []string
+	for rows.Next() {
+		var b string
+		if err := rows.Scan(&b); err != nil {
+			t.Fatal(err)
+		}
+		bodies = append(bodies, b)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 2 || bodies[1] != "b" {
+		t.Fatalf("unexpected bodies %v", bodies)
+	}
+
+	var body string
+	if err := db.QueryRowContext(ctx, "SELECT body FROM notes WHERE id = ?;", 1).Scan(&body); err != nil || body != "a" {
+		t.Fatalf("unexpected row %q, %v", body, err)
+	}
+	if err := db.QueryRowContext(ctx, "SELECT body FROM notes WHERE id = ?;", 9).Scan(&body); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected no rows, got %v", err)
+	}
+	if err := db.QueryRowContext(ctx, "SELECT '12:30';").Scan(&body); err != nil || body != "12:30" {
+		t.Fatalf("unexpected row %q, %v", body, err)
+	}
+
+	tx, err = db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM notes;"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM notes;").Scan(&n); err != nil || n != 2 {
+		t.Fatalf("expected the delete to be rolled back, got %v rows, %v", n, err)
+	}
+	if stats := d.Statements(); len(stats) == 0 {
+		t.Fatal("expected queries to go through the statement cache")
+	}
+
+	// Errors before the query runs are returned by Scan.
+	d.Close()
+	if err := db.QueryRowContext(ctx, "SELECT 1;").Scan(&n); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package sqlntest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// FixtureOptions configures LoadFixtures.
+type FixtureOptions struct {
+	// Truncate deletes all rows from fixture tables before inserting.
+	Truncate bool
+	// Dependencies maps tables to the tables they reference by foreign key,
+	// which are loaded first (and truncated last). See PostgresDependencies.
+	Dependencies map[string][]string
+}
+
+// LoadFixtures loads fixture files in fsys matching patterns (defaults to
+// every .yml, .yaml, .json and .sql file) in a single transaction.
+//
+// A YAML or JSON file contains either a list of rows for the table named
+// after the file (ie. users.yml), or a map of table names to rows. Rows are
+// maps of column names to values. Rows are inserted in dependency order and
+// then .sql files are executed in name order, split into statements by
+// sqln.SplitStatements.
+func LoadFixtures(ctx context.Context, db sqln.DB, fsys fs.FS, opts FixtureOptions, patterns ...string) error {
+	if len(patterns) == 0 {
+		patterns = []string{"*.yml", "*.yaml", "*.json", "*.sql"}
+	}
+	var files []string
+	for _, p := range patterns {
+		matches, err := fs.Glob(fsys, p)
+		if err != nil {
+			return errors.Wrapf(err, "glob %q", p)
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	rows := make(map[string][]map[string]interface{})
+	var scripts []string
+	for _, file := range files {
+		b, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return errors.Wrapf(err, "read %v", file)
+		}
+		if path.Ext(file) == ".sql" {
+			scripts = append(scripts, sqln.SplitStatements(string(b))...)
+			continue
+		}
+		if err := parseFixture(file, b, rows); err != nil {
+			return err
+		}
+	}
+
+	tables := make([]string, 0, len(rows))
+	for t := range rows {
+		tables = append(tables, t)
+	}
+	order, err := dependencyOrder(tables, opts.Dependencies)
+	if err != nil {
+		return err
+	}
+
+	return db.Transact(ctx, sql.TxOptions{}, func(tx sqln.DB) error {
+		if opts.Truncate {
+			for i := len(order) - 1; i >= 0; i-- {
+				if _, err := tx.Exec(ctx, "DELETE FROM "+order[i]+";", nil); err != nil {
+					return errors.Wrapf(err, "truncate %v", order[i])
+				}
+			}
+		}
+		for _, t := range order {
+			for i, r := range rows[t] {
+				if _, err := tx.Exec(ctx, insertQuery(t, r), r); err != nil {
+					return errors.Wrapf(err, "insert %v row %v", t, i)
+				}
+			}
+		}
+		for _, s := range scripts {
+			if _, err := tx.Exec(ctx, s, nil); err != nil {
+				return errors.Wrapf(err, "exec %q", s)
+			}
+		}
+		return nil
+	})
+}
+
+func parseFixture(file string, b []byte, into map[string][]map[string]interface{}) error {
+	var v interface{}
+	if path.Ext(file) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return errors.Wrapf(err, "parse %v", file)
+		}
+	} else if err := yaml.Unmarshal(b, &v); err != nil {
+		return errors.Wrapf(err, "parse %v", file)
+	}
+
+	add := func(table string, list interface{}) error {
+		l, ok := list.([]interface{})
+		if !ok {
+			return errors.Errorf("%v: expected a list of rows for %v", file, table)
+		}
+		for _, r := range l {
+			m, ok := r.(map[string]interface{})
+			if !ok {
+				return errors.Errorf("%v: expected rows of %v to be maps", file, table)
+			}
+			into[table] = append(into[table], m)
+		}
+		return nil
+	}
+
+	switch v := v.(type) {
+	case []interface{}:
+		return add(strings.TrimSuffix(path.Base(file), path.Ext(file)), v)
+	case map[string]interface{}:
+		tables := make([]string, 0, len(v))
+		for t := range v {
+			tables = append(tables, t)
+		}
+		sort.Strings(tables)
+		for _, t := range tables {
+			if err := add(t, v[t]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case nil:
+		return nil
+	}
+	return errors.Errorf("%v: expected a list of rows or a map of tables", file)
+}
+
+// insertQuery builds an insert with sorted columns so rows with the same
+// columns share a statement.
+func insertQuery(table string, row map[string]interface{}) string {
+	cols := make([]string, 0, len(row))
+	for c := range row {
+		cols = append(cols, c)
+	}
+	sort.Strings(cols)
+	return fmt.Sprintf("INSERT INTO %v (%v) VALUES (:%v);", table, strings.Join(cols, ", "), strings.Join(cols, ", :"))
+}
+
+// dependencyOrder sorts tables so referenced tables come first. Ties are
+// broken by name.
+func dependencyOrder(tables []string, deps map[string][]string) ([]string, error) {
+	sort.Strings(tables)
+	include := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		include[t] = true
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var (
+		order []string
+		visit func(t string) error
+	)
+	visit = func(t string) error {
+		switch state[t] {
+		case visiting:
+			return errors.Errorf("fixtures: dependency cycle at %v", t)
+		case done:
+			return nil
+		}
+		state[t] = visiting
+		parents := append([]string(nil), deps[t]...)
+		sort.Strings(parents)
+		for _, p := range parents {
+			if p == t || !include[p] {
+				continue
+			}
+			if err := visit(p); err != nil {
+				return err
+			}
+		}
+		state[t] = done
+		order = append(order, t)
+		return nil
+	}
+	for _, t := range tables {
+		if err := visit(t); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// PostgresDependencies returns the foreign key dependencies between tables in
+// the current schema, for FixtureOptions.
+func PostgresDependencies(ctx context.Context, db sqln.DB) (map[string][]string, error) {
+	var edges []struct {
+		Child  string `db:"child"`
+		Parent string `db:"parent"`
+	}
+	if err := db.Select(ctx, `SELECT tc.table_name AS child, ccu.table_name AS parent
+FROM information_schema.table_constraints tc
+JOIN information_schema.constraint_column_usage ccu
+	ON tc.constraint_name = ccu.constraint_name AND tc.table_schema = ccu.table_schema
+WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = current_schema();`, &edges, nil); err != nil {
+		return nil, errors.Wrap(err, "listing foreign keys")
+	}
+
+	deps := make(map[string][]string)
+	for _, e := range edges {
+		deps[e.Child] = append(deps[e.Child], e.Parent)
+	}
+	return deps, nil
+}
@@ -0,0 +1,75 @@
+package sqlntest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nstogner/sqln"
+)
+
+func TestLoadFixtures(t *testing.T) {
+	dbx, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_foreign_keys=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbx.Close()
+	db := sqln.New(dbx)
+	defer db.Close()
+
+	ctx := context.Background()
+	for _, q := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);",
+		"CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL REFERENCES users (id), title TEXT NOT NULL);",
+		"INSERT INTO users (id, name) VALUES (99, 'stale');",
+	} {
+		if _, err := db.Exec(ctx, q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fsys := fstest.MapFS{
+		// Loaded before users.yml by name, but must be inserted after.
+		"a_posts.json": {Data: []byte(`{"posts": [{"id": 1, "user_id": 1, "title": "hello"}]}`)},
+		"users.yml": {Data: []byte(`
+- id: 1
+  name: a
+- id: 2
+  name: b
+`)},
+		"z_fixup.sql": {Data: []byte("UPDATE users SET name = 'c'\nWHERE id = 2;\n\nUPDATE posts SET title = 'hi';\n")},
+	}
+	if err := LoadFixtures(ctx, db, fsys, FixtureOptions{
+		Truncate:     true,
+		Dependencies: map[string][]string{"posts": {"users"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	if err := db.Select(ctx, "SELECT name FROM users ORDER BY id;", &names, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "a" || names[1] != "c" {
+		t.Fatalf("unexpected users: %v", names)
+	}
+	var title string
+	if err := db.Get(ctx, "SELECT title FROM posts;", &title, nil); err != nil || title != "hi" {
+		t.Fatalf("expected hi, got %q (%v)", title, err)
+	}
+}
+
+func TestDependencyOrder(t *testing.T) {
+	order, err := dependencyOrder([]string{"c", "b", "a"}, map[string][]string{"a": {"b"}, "b": {"c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 3 || order[0] != "c" || order[1] != "b" || order[2] != "a" {
+		t.Fatalf("unexpected order: %v", order)
+	}
+	if _, err := dependencyOrder([]string{"a", "b"}, map[string][]string{"a": {"b"}, "b": {"a"}}); err == nil {
+		t.Fatal("expected cycle error")
+	}
+}
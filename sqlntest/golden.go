@@ -0,0 +1,168 @@
+package sqlntest
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nstogner/sqln"
+)
+
+// GoldenEnv names the environment variable that, when true, makes Golden
+// rewrite golden files instead of comparing against them.
+const GoldenEnv = "SQLN_UPDATE_GOLDEN"
+
+// Golden returns db recording the SQL of every statement run through it,
+// keyed by the function that ran it, ie. the statements generated by the
+// struct helpers, Repo or a Template. When the test completes the statements
+// are compared with testdata/<test name>.golden.sql, failing the test on any
+// difference, so changes to generated queries are reviewed. Run the tests
+// with SQLN_UPDATE_GOLDEN=true to write the file.
+func Golden(t testing.TB, db sqln.DB) sqln.DB {
+	t.Helper()
+	return golden(t, db, filepath.Join("testdata", goldenName.ReplaceAllString(t.Name(), "_")+".golden.sql"))
+}
+
+var goldenName = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+func golden(t testing.TB, db sqln.DB, path string) sqln.DB {
+	g := &goldenRecorder{stmts: make(map[string][]string), seen: make(map[string]bool)}
+	t.Cleanup(func() {
+		got := g.String()
+		if update, _ := strconv.ParseBool(os.Getenv(GoldenEnv)); update {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatalf("golden: %v", err)
+			}
+			if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+				t.Fatalf("golden: %v", err)
+			}
+			return
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("golden: %v (run with %v=true to create it)", err, GoldenEnv)
+			return
+		}
+		if got != string(want) {
+			t.Errorf("golden: generated SQL differs from %v (run with %v=true to update it):\n%v", path, GoldenEnv, lineDiff(string(want), got))
+		}
+	})
+	return g.wrap(db)
+}
+
+// goldenRecorder collects statements in the order they are first run.
+type goldenRecorder struct {
+	mtx   sync.Mutex
+	sites []string
+	stmts map[string][]string
+	seen  map[string]bool
+}
+
+func (g *goldenRecorder) wrap(db sqln.DB) sqln.DB {
+	return &goldenDB{BaseDB: sqln.BaseDB{DB: db, Wrap: g.wrap}, g: g}
+}
+
+func (g *goldenRecorder) record(query string) {
+	site := callSite()
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	key := site + "\x00" + query
+	if g.seen[key] {
+		return
+	}
+	g.seen[key] = true
+	if _, ok := g.stmts[site]; !ok {
+		g.sites = append(g.sites, site)
+	}
+	g.stmts[site] = append(g.stmts[site], query)
+}
+
+func (g *goldenRecorder) String() string {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	var b strings.Builder
+	for _, site := range g.sites {
+		b.WriteString("-- " + site + "\n")
+		for _, q := range g.stmts[site] {
+			b.WriteString(strings.TrimSpace(q) + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// callSite returns the first function on the stack outside of sqln and this
+// file, which is stable across unrelated edits unlike a line number.
+func callSite() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "github.com/nstogner/sqln.") && filepath.Base(f.File) != "golden.go" {
+			return f.Function
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// lineDiff lists the lines removed from want and added in got.
+func lineDiff(want, got string) string {
+	count := func(s string) map[string]int {
+		m := make(map[string]int)
+		for _, l := range strings.Split(s, "\n") {
+			m[l]++
+		}
+		return m
+	}
+	w, g := count(want), count(got)
+	var b strings.Builder
+	for _, l := range strings.Split(want, "\n") {
+		if g[l] > 0 {
+			g[l]--
+		} else if l != "" {
+			b.WriteString("- " + l + "\n")
+		}
+	}
+	for _, l := range strings.Split(got, "\n") {
+		if w[l] > 0 {
+			w[l]--
+		} else if l != "" {
+			b.WriteString("+ " + l + "\n")
+		}
+	}
+	return b.String()
+}
+
+type goldenDB struct {
+	sqln.BaseDB
+	g *goldenRecorder
+}
+
+func (d *goldenDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	d.g.record(query)
+	return d.DB.Exec(ctx, query, params)
+}
+
+func (d *goldenDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	d.g.record(query)
+	return d.DB.Get(ctx, query, dest, params)
+}
+
+func (d *goldenDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	d.g.record(query)
+	return d.DB.Select(ctx, query, dest, params)
+}
+
+func (d *goldenDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	d.g.record(query)
+	return d.DB.ExecReturning(ctx, query, dest, params)
+}
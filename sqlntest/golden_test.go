@@ -0,0 +1,104 @@
+package sqlntest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nstogner/sqln"
+)
+
+// goldenT captures the cleanups and failures of a test.
+type goldenT struct {
+	testing.TB
+	cleanups []func()
+	errs     []string
+}
+
+func (t *goldenT) Cleanup(f func())                          { t.cleanups = append(t.cleanups, f) }
+func (t *goldenT) Errorf(format string, args ...interface{}) { t.errs = append(t.errs, format) }
+func (t *goldenT) finish() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+type goldenUser struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+func createGoldenUser(ctx context.Context, db sqln.DB, u goldenUser) error {
+	_, err := sqln.InsertStruct(ctx, db, "golden_users", u)
+	return err
+}
+
+func TestGolden(t *testing.T) {
+	dbx, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbx.Close()
+	db := sqln.New(dbx)
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE golden_users (id INTEGER PRIMARY KEY, name TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "testdata", "users.golden.sql")
+	run := func(update bool) *goldenT {
+		gt := &goldenT{TB: t}
+		if update {
+			t.Setenv(GoldenEnv, "true")
+		} else {
+			t.Setenv(GoldenEnv, "")
+		}
+		gdb := golden(gt, db, path)
+		if _, err := gdb.Exec(ctx, "DELETE FROM golden_users;", nil); err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i <= 2; i++ {
+			if err := createGoldenUser(ctx, gdb, goldenUser{ID: i, Name: "a"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		gt.finish()
+		return gt
+	}
+
+	if gt := run(false); len(gt.errs) != 1 {
+		t.Fatalf("expected a missing golden file to fail, got %v", gt.errs)
+	}
+	if gt := run(true); len(gt.errs) != 0 {
+		t.Fatalf("unexpected errors: %v", gt.errs)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `-- github.com/nstogner/sqln/sqlntest.TestGolden.func1
+DELETE FROM golden_users;
+
+-- github.com/nstogner/sqln/sqlntest.createGoldenUser
+INSERT INTO golden_users (id, name) VALUES (:id, :name);
+
+`
+	if string(b) != expected {
+		t.Fatalf("unexpected golden file:\n%v", string(b))
+	}
+	if gt := run(false); len(gt.errs) != 0 {
+		t.Fatalf("unexpected errors: %v", gt.errs)
+	}
+
+	if err := os.WriteFile(path, []byte(strings.Replace(expected, "(id, name)", "(name, id)", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if gt := run(false); len(gt.errs) != 1 {
+		t.Fatalf("expected a changed query to fail, got %v", gt.errs)
+	}
+}
@@ -0,0 +1,326 @@
+/*
+Package sqlntest provides helpers for testing code that depends on sqln.DB.
+*/
+package sqlntest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+)
+
+// Mock is a sqln.DB that answers queries from programmed expectations
+// instead of a database. By default expectations must be met in order.
+type Mock struct {
+	// Unordered allows expectations to be met in any order.
+	Unordered bool
+
+	mtx      sync.Mutex
+	expected []*Expectation
+	calls    []Call
+}
+
+var _ sqln.DB = (*Mock)(nil)
+
+// Call is a recorded call to the Mock.
+type Call struct {
+	Method string
+	Query  string
+	Params interface{}
+	Err    error
+}
+
+// Expectation is a programmed response to a query.
+type Expectation struct {
+	method string
+	query  string
+	params func(interface{}) bool
+
+	result sql.Result
+	rows   []interface{}
+	err    error
+
+	met bool
+}
+
+// ExpectExec expects an Exec of query.
+func (m *Mock) ExpectExec(query string) *Expectation {
+	return m.expect("Exec", query)
+}
+
+// ExpectGet expects a Get of query.
+func (m *Mock) ExpectGet(query string) *Expectation {
+	return m.expect("Get", query)
+}
+
+// ExpectSelect expects a Select of query.
+func (m *Mock) ExpectSelect(query string) *Expectation {
+	return m.expect("Select", query)
+}
+
+// ExpectExecReturning expects an ExecReturning of query.
+func (m *Mock) ExpectExecReturning(query string) *Expectation {
+	return m.expect("ExecReturning", query)
+}
+
+func (m *Mock) expect(method, query string) *Expectation {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	e := &Expectation{method: method, query: query}
+	m.expected = append(m.expected, e)
+	return e
+}
+
+// WithParams requires params equal to p. Maps and structs are compared by
+// their named values, so a struct can match an equivalent map.
+func (e *Expectation) WithParams(p interface{}) *Expectation {
+	want := namedValues(p)
+	return e.WithParamsFunc(func(got interface{}) bool {
+		return reflect.DeepEqual(want, namedValues(got))
+	})
+}
+
+// WithParamsFunc requires params for which match returns true.
+func (e *Expectation) WithParamsFunc(match func(params interface{}) bool) *Expectation {
+	e.params = match
+	return e
+}
+
+// WillReturnResult sets the result of an Exec.
+func (e *Expectation) WillReturnResult(r sql.Result) *Expectation {
+	e.result = r
+	return e
+}
+
+// WillReturnRows sets the rows scanned into dest. Each row must be assignable
+// to dest (Get) or its element type (Select). A Get without rows returns
+// sql.ErrNoRows.
+func (e *Expectation) WillReturnRows(rows ...interface{}) *Expectation {
+	e.rows = rows
+	return e
+}
+
+// WillReturnError makes the call fail with err.
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+func (e *Expectation) String() string {
+	return fmt.Sprintf("%v %q", e.method, e.query)
+}
+
+// Result returns a sql.Result for WillReturnResult.
+func Result(lastInsertID, rowsAffected int64) sql.Result {
+	return result{lastInsertID, rowsAffected}
+}
+
+type result struct {
+	lastInsertID, rowsAffected int64
+}
+
+func (r result) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r result) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+// Calls returns every call made so far, in order.
+func (m *Mock) Calls() []Call {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// ExpectationsWereMet returns an error listing expectations that were not
+// met.
+func (m *Mock) ExpectationsWereMet() error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	var unmet []string
+	for _, e := range m.expected {
+		if !e.met {
+			unmet = append(unmet, e.String())
+		}
+	}
+	if len(unmet) > 0 {
+		return errors.Errorf("sqlntest: unmet expectations: %v", strings.Join(unmet, ", "))
+	}
+	return nil
+}
+
+// match finds and marks the expectation for a call, recording the call.
+func (m *Mock) match(method, query string, params interface{}) (*Expectation, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	var (
+		found *Expectation
+		err   error
+	)
+	for _, e := range m.expected {
+		if e.met {
+			continue
+		}
+		if e.method == method && e.query == query && (e.params == nil || e.params(params)) {
+			found = e
+			break
+		}
+		if !m.Unordered {
+			err = errors.Errorf("sqlntest: %v %q, expected %v", method, query, e)
+			break
+		}
+	}
+	if found == nil && err == nil {
+		err = errors.Errorf("sqlntest: unexpected %v %q", method, query)
+	}
+	if found != nil {
+		found.met = true
+		err = found.err
+	}
+
+	m.calls = append(m.calls, Call{Method: method, Query: query, Params: params, Err: err})
+	return found, err
+}
+
+// Exec answers with the expectation's result.
+func (m *Mock) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	e, err := m.match("Exec", query, params)
+	if err != nil {
+		return nil, err
+	}
+	if e.result == nil {
+		return Result(0, 0), nil
+	}
+	return e.result, nil
+}
+
+// Get assigns the expectation's first row to dest.
+func (m *Mock) Get(ctx context.Context, query string, dest, params interface{}) error {
+	e, err := m.match("Get", query, params)
+	if err != nil {
+		return err
+	}
+	return e.scanOne(dest)
+}
+
+// Select assigns the expectation's rows to dest.
+func (m *Mock) Select(ctx context.Context, query string, dest, params interface{}) error {
+	e, err := m.match("Select", query, params)
+	if err != nil {
+		return err
+	}
+	return e.scanAll(dest)
+}
+
+// ExecReturning assigns the expectation's rows to dest, a single row unless
+// dest is a pointer to a slice.
+func (m *Mock) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	e, err := m.match("ExecReturning", query, params)
+	if err != nil {
+		return err
+	}
+	if t := reflect.TypeOf(dest); t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Slice {
+		return e.scanAll(dest)
+	}
+	return e.scanOne(dest)
+}
+
+// Stmt is not supported since there is no database to prepare on.
+func (m *Mock) Stmt(query string) (*sqlx.NamedStmt, error) {
+	return nil, errors.New("sqlntest: Stmt is not supported by Mock")
+}
+
+// Transact runs f with the Mock, recording a Transact call with the error
+// returned by f.
+func (m *Mock) Transact(ctx context.Context, opts sql.TxOptions, f func(sqln.DB) error) error {
+	err := f(m)
+	m.mtx.Lock()
+	m.calls = append(m.calls, Call{Method: "Transact", Err: err})
+	m.mtx.Unlock()
+	return err
+}
+
+func (e *Expectation) scanOne(dest interface{}) error {
+	if len(e.rows) == 0 {
+		return sql.ErrNoRows
+	}
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("sqlntest: dest must be a non-nil pointer")
+	}
+	return assign(v.Elem(), e.rows[0])
+}
+
+func (e *Expectation) scanAll(dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return errors.New("sqlntest: dest must be a pointer to a slice")
+	}
+	s := reflect.MakeSlice(v.Elem().Type(), len(e.rows), len(e.rows))
+	for i, r := range e.rows {
+		if err := assign(s.Index(i), r); err != nil {
+			return err
+		}
+	}
+	v.Elem().Set(s)
+	return nil
+}
+
+// assign sets dst to row, dereferencing or taking the address of row as
+// needed.
+func assign(dst reflect.Value, row interface{}) error {
+	rv := reflect.ValueOf(row)
+	switch {
+	case rv.Type().AssignableTo(dst.Type()):
+		dst.Set(rv)
+	case rv.Kind() == reflect.Ptr && rv.Elem().Type().AssignableTo(dst.Type()):
+		dst.Set(rv.Elem())
+	case dst.Kind() == reflect.Ptr && rv.Type().AssignableTo(dst.Type().Elem()):
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		dst.Set(p)
+	case rv.Type().ConvertibleTo(dst.Type()) && rv.Kind() != reflect.Struct:
+		dst.Set(rv.Convert(dst.Type()))
+	default:
+		return errors.Errorf("sqlntest: cannot assign %T to %v", row, dst.Type())
+	}
+	return nil
+}
+
+var mapper = reflectx.NewMapperFunc("db", sqlx.NameMapper)
+
+// namedValues returns params as a map of named values for comparison.
+func namedValues(params interface{}) interface{} {
+	if params == nil {
+		return map[string]interface{}{}
+	}
+	v := reflect.Indirect(reflect.ValueOf(params))
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return params
+		}
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = iter.Value().Interface()
+		}
+		return m
+	case reflect.Struct:
+		m := make(map[string]interface{})
+		for name, f := range mapper.FieldMap(v) {
+			if f.Kind() != reflect.Struct || f.Type().String() == "time.Time" {
+				m[name] = f.Interface()
+			}
+		}
+		return m
+	}
+	return params
+}
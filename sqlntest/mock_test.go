@@ -0,0 +1,81 @@
+package sqlntest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/nstogner/sqln"
+)
+
+type user struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+// rename is a typical function under test.
+func rename(ctx context.Context, db sqln.DB, id int, name string) ([]user, error) {
+	var all []user
+	err := db.Transact(ctx, sql.TxOptions{}, func(tx sqln.DB) error {
+		if _, err := sqln.ExecOne(ctx, tx, "UPDATE users SET name = :name WHERE id = :id;", user{ID: id, Name: name}); err != nil {
+			return err
+		}
+		return tx.Select(ctx, "SELECT * FROM users;", &all, nil)
+	})
+	return all, err
+}
+
+func TestMock(t *testing.T) {
+	m := &Mock{}
+	m.ExpectExec("UPDATE users SET name = :name WHERE id = :id;").
+		WithParams(map[string]interface{}{"id": 1, "name": "b"}).
+		WillReturnResult(Result(0, 1))
+	m.ExpectSelect("SELECT * FROM users;").
+		WillReturnRows(user{1, "b"}, &user{2, "c"})
+
+	all, err := rename(context.Background(), m, 1, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[1].Name != "c" {
+		t.Fatalf("unexpected rows: %v", all)
+	}
+	if err := m.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := m.Calls()
+	if len(calls) != 3 || calls[0].Method != "Exec" || calls[1].Method != "Select" || calls[2].Method != "Transact" {
+		t.Fatalf("unexpected calls: %+v", calls)
+	}
+}
+
+func TestMockErrors(t *testing.T) {
+	m := &Mock{}
+	m.ExpectExec("UPDATE users SET name = :name WHERE id = :id;").WillReturnResult(Result(0, 0))
+	if _, err := rename(context.Background(), m, 1, "b"); !errors.Is(err, sqln.ErrNoRowsAffected) {
+		t.Fatalf("expected ErrNoRowsAffected, got %v", err)
+	}
+
+	m = &Mock{}
+	m.ExpectGet("SELECT name FROM users WHERE id = :id;").WithParams(map[string]interface{}{"id": 2})
+	var name string
+	if err := m.Get(context.Background(), "SELECT name FROM users WHERE id = :id;", &name, map[string]interface{}{"id": 1}); err == nil {
+		t.Fatal("expected error for mismatched params")
+	}
+	if err := m.ExpectationsWereMet(); err == nil {
+		t.Fatal("expected unmet expectation")
+	}
+
+	m = &Mock{Unordered: true}
+	m.ExpectGet("SELECT 1;").WillReturnRows(1)
+	m.ExpectGet("SELECT 2;").WillReturnError(sql.ErrConnDone)
+	if err := m.Get(context.Background(), "SELECT 2;", &name, nil); err != sql.ErrConnDone {
+		t.Fatalf("expected ErrConnDone, got %v", err)
+	}
+	var n int64
+	if err := m.Get(context.Background(), "SELECT 1;", &n, nil); err != nil || n != 1 {
+		t.Fatalf("expected 1, got %v (%v)", n, err)
+	}
+}
@@ -0,0 +1,33 @@
+package sqlntest
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nstogner/sqln"
+)
+
+func TestNewPostgres(t *testing.T) {
+	db := NewPostgres(t, PostgresOptions{
+		Migrations: fstest.MapFS{
+			"1_users.up.sql":   {Data: []byte("CREATE TABLE users (id INT PRIMARY KEY);")},
+			"1_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		},
+	})
+	if db.Dialect() != sqln.Postgres {
+		t.Fatalf("unexpected dialect %q", db.Dialect())
+	}
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "INSERT INTO users (id) VALUES (1);", nil); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.Get(ctx, "SELECT COUNT(*) FROM users;", &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 user, got %v", n)
+	}
+}
@@ -0,0 +1,33 @@
+package sqlntest
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+)
+
+var errRollback = errors.New("sqlntest: rollback")
+
+// RollbackTx runs f in a transaction that is always rolled back, isolating the
+// test's writes so tests can share a schema and run in parallel.
+// NOTE: Code under test that calls Transact on the tx will fail since nested
+// transactions are not supported.
+func RollbackTx(t testing.TB, db sqln.DB, f func(tx sqln.DB)) {
+	t.Helper()
+
+	// Canceling the context rolls the transaction back even if f exits via
+	// t.Fatal, which skips the rollback in Transact.
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	err := db.Transact(ctx, sql.TxOptions{}, func(tx sqln.DB) error {
+		f(tx)
+		return errRollback
+	})
+	if err != nil && !errors.Is(err, errRollback) {
+		t.Fatalf("rollback tx: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package sqlntest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nstogner/sqln"
+)
+
+func TestRollbackTx(t *testing.T) {
+	dbx, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbx.Close()
+	db := sqln.New(dbx)
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE rollback_abc (id INTEGER PRIMARY KEY);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	const count = "SELECT COUNT(*) FROM rollback_abc;"
+	RollbackTx(t, db, func(tx sqln.DB) {
+		if _, err := tx.Exec(ctx, "INSERT INTO rollback_abc (id) VALUES (1);", nil); err != nil {
+			t.Fatal(err)
+		}
+		var n int
+		if err := tx.Get(ctx, count, &n, nil); err != nil || n != 1 {
+			t.Fatalf("expected 1 row in tx, got %v (%v)", n, err)
+		}
+	})
+
+	var n int
+	if err := db.Get(ctx, count, &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("expected insert to be rolled back, got %v rows", n)
+	}
+}
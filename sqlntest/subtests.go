@@ -0,0 +1,46 @@
+package sqlntest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nstogner/sqln"
+	"github.com/pkg/errors"
+)
+
+// SubtestTx is a transaction shared by subtests, see RunInTx.
+type SubtestTx struct {
+	// DB is the shared transaction, ie. to insert data seen by every
+	// subtest.
+	DB sqln.DB
+
+	t *testing.T
+}
+
+// RunInTx runs f with a transaction of db that is rolled back once f returns
+// (see RollbackTx), for subtests run with SubtestTx.Run. Each subtest is
+// isolated by a savepoint instead of a transaction or a schema of its own,
+// which makes suites of many small tests much faster.
+func RunInTx(t *testing.T, db sqln.DB, f func(s *SubtestTx)) {
+	t.Helper()
+	RollbackTx(t, db, func(tx sqln.DB) {
+		f(&SubtestTx{DB: tx, t: t})
+	})
+}
+
+// Run runs f as a subtest in a savepoint of the shared transaction, which is
+// rolled back once f returns, so later subtests do not see its writes. Like
+// t.Run it reports whether f succeeded. Subtests share the transaction, so
+// they must not call t.Parallel.
+func (s *SubtestTx) Run(name string, f func(t *testing.T, tx sqln.DB)) bool {
+	s.t.Helper()
+	var ok bool
+	err := sqln.Savepoint(context.Background(), s.DB, "sqlntest_subtest", func(tx sqln.DB) error {
+		ok = s.t.Run(name, func(t *testing.T) { f(t, tx) })
+		return errRollback
+	})
+	if err != nil && !errors.Is(err, errRollback) {
+		s.t.Fatalf("subtest %v: %v", name, err)
+	}
+	return ok
+}
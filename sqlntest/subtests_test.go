@@ -0,0 +1,51 @@
+package sqlntest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nstogner/sqln"
+)
+
+func TestRunInTx(t *testing.T) {
+	dbx, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbx.Close()
+	db := sqln.New(dbx)
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE subtests_abc (id INTEGER PRIMARY KEY);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	const count = "SELECT COUNT(*) FROM subtests_abc;"
+	RunInTx(t, db, func(s *SubtestTx) {
+		if _, err := s.DB.Exec(ctx, "INSERT INTO subtests_abc (id) VALUES (1);", nil); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"first", "second"} {
+			s.Run(name, func(t *testing.T, tx sqln.DB) {
+				var n int
+				if err := tx.Get(ctx, count, &n, nil); err != nil || n != 1 {
+					t.Fatalf("expected only the shared row, got %v (%v)", n, err)
+				}
+				if _, err := tx.Exec(ctx, "INSERT INTO subtests_abc (id) VALUES (2);", nil); err != nil {
+					t.Fatal(err)
+				}
+			})
+		}
+	})
+
+	var n int
+	if err := db.Get(ctx, count, &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("expected the transaction to be rolled back, got %v rows", n)
+	}
+}
@@ -0,0 +1,193 @@
+/*
+Package sqlnvet provides an analyzer that checks calls to sqln.DB methods
+(Exec, Get, Select and ExecReturning):
+
+  - the query is a constant, so it can be prepared once, registered and
+    reviewed, rather than built at runtime;
+  - each named parameter in the query has a matching field (by db tag, or
+    lower cased name) when params is a struct, and params is not nil when the
+    query has parameters.
+
+Run it with cmd/sqln-vet or as part of a multichecker.
+*/
+package sqlnvet
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// Analyzer checks sqln query literals and their params.
+var Analyzer = &analysis.Analyzer{
+	Name:     "sqlnvet",
+	Doc:      "check that sqln queries are constant and their named params are bound",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+const sqlnPath = "github.com/nstogner/sqln"
+
+// paramsArg is the index of the params argument of each checked method.
+var paramsArg = map[string]int{
+	"Exec":          2,
+	"Get":           3,
+	"Select":        3,
+	"ExecReturning": 3,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	db := lookupDB(pass.Pkg)
+	if db == nil {
+		return nil, nil
+	}
+
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	ins.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return
+		}
+		pi, ok := paramsArg[sel.Sel.Name]
+		if !ok || len(call.Args) != pi+1 {
+			return
+		}
+		s, ok := pass.TypesInfo.Selections[sel]
+		if !ok || s.Kind() != types.MethodVal || !types.Implements(s.Recv(), db) && !types.Implements(types.NewPointer(s.Recv()), db) {
+			return
+		}
+
+		query := call.Args[1]
+		tv := pass.TypesInfo.Types[query]
+		if tv.Value == nil || tv.Value.Kind() != constant.String {
+			pass.Reportf(query.Pos(), "sqln: non-constant query passed to %v", sel.Sel.Name)
+			return
+		}
+		checkParams(pass, constant.StringVal(tv.Value), call.Args[pi])
+	})
+	return nil, nil
+}
+
+// lookupDB finds the sqln.DB interface among the imports of pkg.
+func lookupDB(pkg *types.Package) *types.Interface {
+	for _, imp := range append([]*types.Package{pkg}, pkg.Imports()...) {
+		if imp.Path() != sqlnPath {
+			continue
+		}
+		if obj := imp.Scope().Lookup("DB"); obj != nil {
+			if iface, ok := obj.Type().Underlying().(*types.Interface); ok {
+				return iface
+			}
+		}
+	}
+	return nil
+}
+
+func checkParams(pass *analysis.Pass, query string, arg ast.Expr) {
+	params := namedParams(query)
+	if len(params) == 0 {
+		return
+	}
+
+	t := pass.TypesInfo.TypeOf(arg)
+	if t == nil {
+		return
+	}
+	if types.Identical(t, types.Typ[types.UntypedNil]) {
+		pass.Reportf(arg.Pos(), "sqln: query has params %v but params is nil", strings.Join(params, ", "))
+		return
+	}
+	if p, ok := t.Underlying().(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		// Maps (and interfaces) are only known at runtime.
+		return
+	}
+
+	fields := make(map[string]bool)
+	structColumns(st, "", fields)
+	for _, p := range params {
+		if !fields[p] {
+			pass.Reportf(arg.Pos(), "sqln: param %q has no matching field in %v", p, types.TypeString(pass.TypesInfo.TypeOf(arg), types.RelativeTo(pass.Pkg)))
+		}
+	}
+}
+
+// structColumns collects the names sqlx maps the fields of st to.
+func structColumns(st *types.Struct, prefix string, into map[string]bool) {
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		tag, _ := reflect.StructTag(st.Tag(i)).Lookup("db")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" || !f.Exported() && !f.Embedded() {
+			continue
+		}
+
+		ft := f.Type()
+		if p, ok := ft.Underlying().(*types.Pointer); ok {
+			ft = p.Elem()
+		}
+		if f.Embedded() && name == "" {
+			if est, ok := ft.Underlying().(*types.Struct); ok {
+				structColumns(est, prefix, into)
+				continue
+			}
+		}
+
+		if name == "" {
+			name = strings.ToLower(f.Name())
+		}
+		into[prefix+name] = true
+		if nst, ok := ft.Underlying().(*types.Struct); ok {
+			structColumns(nst, prefix+name+".", into)
+		}
+	}
+}
+
+// namedParams returns the distinct :name params of query, skipping quoted
+// strings and :: casts.
+func namedParams(query string) []string {
+	var (
+		params []string
+		seen   = make(map[string]bool)
+		quote  byte
+	)
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			i++
+		case c == ':' && i+1 < len(query) && isIdent(query[i+1]):
+			j := i + 1
+			for j < len(query) && (isIdent(query[j]) || query[j] == '.') {
+				j++
+			}
+			name := query[i+1 : j]
+			if !seen[name] {
+				seen[name] = true
+				params = append(params, name)
+			}
+			i = j - 1
+		}
+	}
+	return params
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
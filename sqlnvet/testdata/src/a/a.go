@@ -0,0 +1,31 @@
+package a
+
+import (
+	"context"
+
+	"github.com/nstogner/sqln"
+)
+
+type Base struct {
+	OrgID int `db:"org_id"`
+}
+
+type User struct {
+	Base
+	ID    int    `db:"id"`
+	Email string `db:"email"`
+	Name  string
+}
+
+const getUser = "SELECT * FROM users WHERE id = :id AND org_id = :org_id AND created_at::date > '2020-01-01 00:00';"
+
+func f(ctx context.Context, db sqln.DB, table string, u User) {
+	var dest User
+	db.Get(ctx, getUser, &dest, u)
+	db.Get(ctx, getUser, &dest, &u)
+	db.Get(ctx, "SELECT * FROM "+table+";", &dest, nil)                             // want `non-constant query passed to Get`
+	db.Exec(ctx, "UPDATE users SET name = :name, email = :mail WHERE id = :id;", u) // want `param "mail" has no matching field in User`
+	db.Exec(ctx, "DELETE FROM users WHERE id = :id;", nil)                          // want `query has params id but params is nil`
+	db.Exec(ctx, "DELETE FROM users WHERE id = :id;", map[string]interface{}{"id": 1})
+	db.Select(ctx, "SELECT * FROM users;", &[]User{}, nil)
+}
@@ -0,0 +1,13 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+)
+
+type DB interface {
+	Exec(ctx context.Context, query string, params interface{}) (sql.Result, error)
+	Get(ctx context.Context, query string, dest, params interface{}) error
+	Select(ctx context.Context, query string, dest, params interface{}) error
+	ExecReturning(ctx context.Context, query string, dest, params interface{}) error
+}
@@ -0,0 +1,207 @@
+package sqln
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// StartStep identifies a phase of the startup sequence run by Start.
+type StartStep string
+
+// Startup steps, in the order they are run.
+const (
+	StepWaitReady     StartStep = "wait-ready"
+	StepMigrate       StartStep = "migrate"
+	StepSchemaVersion StartStep = "schema-version"
+	StepValidate      StartStep = "validate"
+	StepWarmUp        StartStep = "warm-up"
+	StepHealth        StartStep = "health"
+)
+
+var startSteps = []StartStep{
+	StepWaitReady,
+	StepMigrate,
+	StepSchemaVersion,
+	StepValidate,
+	StepWarmUp,
+	StepHealth,
+}
+
+// StartState describes the progress of a single step.
+type StartState string
+
+// Step states reported through Startup.OnEvent.
+const (
+	StartBegin   StartState = "begin"
+	StartRetry   StartState = "retry"
+	StartDone    StartState = "done"
+	StartFailed  StartState = "failed"
+	StartSkipped StartState = "skipped"
+)
+
+// StartEvent is emitted as the startup sequence progresses.
+type StartEvent struct {
+	Step  StartStep
+	State StartState
+	// Attempt is the number of the ready check (wait-ready only).
+	Attempt int
+	// Elapsed is the time spent in the step so far.
+	Elapsed time.Duration
+	Err     error
+}
+
+// Startup configures the sequence run by Start. Any step that is left nil is
+// skipped (and reported as such).
+type Startup struct {
+	// ReadyBackoff is the initial delay between ready checks, doubling up to
+	// ReadyMaxBackoff. Defaults to 100ms and 5s.
+	ReadyBackoff    time.Duration
+	ReadyMaxBackoff time.Duration
+
+	Migrate            func(ctx context.Context, db DB) error
+	CheckSchemaVersion func(ctx context.Context, db DB) error
+	Validate           func(ctx context.Context, db DB) error
+
+	// WarmQueries are prepared concurrently during the warm-up step.
+	WarmQueries []string
+	// WarmUp functions (cache loaders etc.) are run concurrently with each
+	// other and with WarmQueries.
+	WarmUp []func(ctx context.Context, db DB) error
+
+	RegisterHealth func(ctx context.Context, db *Database) error
+
+	// OnEvent is called synchronously for every progress event.
+	OnEvent func(StartEvent)
+}
+
+// Start runs a supervised startup sequence: wait for the database to accept
+// connections, run migrations, check the schema version, validate queries,
+// warm up, and register health checks. Each step only runs once the previous
+// one succeeds. The first failure stops the sequence and is returned.
+func (d *Database) Start(ctx context.Context, s Startup) error {
+	emit := func(e StartEvent) {
+		if s.OnEvent != nil {
+			s.OnEvent(e)
+		}
+	}
+
+	for _, step := range startSteps {
+		run := d.startStep(step, s, emit)
+		if run == nil {
+			emit(StartEvent{Step: step, State: StartSkipped})
+			continue
+		}
+
+		start := time.Now()
+		emit(StartEvent{Step: step, State: StartBegin})
+		if err := run(ctx); err != nil {
+			emit(StartEvent{Step: step, State: StartFailed, Elapsed: time.Since(start), Err: err})
+			return errors.Wrapf(err, "start: %v", step)
+		}
+		emit(StartEvent{Step: step, State: StartDone, Elapsed: time.Since(start)})
+	}
+
+	return nil
+}
+
+func (d *Database) startStep(step StartStep, s Startup, emit func(StartEvent)) func(context.Context) error {
+	bind := func(f func(context.Context, DB) error) func(context.Context) error {
+		if f == nil {
+			return nil
+		}
+		return func(ctx context.Context) error { return f(ctx, d) }
+	}
+
+	switch step {
+	case StepWaitReady:
+		return func(ctx context.Context) error { return d.waitReady(ctx, s, emit) }
+	case StepMigrate:
+		return bind(s.Migrate)
+	case StepSchemaVersion:
+		return bind(s.CheckSchemaVersion)
+	case StepValidate:
+		return bind(s.Validate)
+	case StepWarmUp:
+		if len(s.WarmQueries) == 0 && len(s.WarmUp) == 0 {
+			return nil
+		}
+		return func(ctx context.Context) error { return d.warmUp(ctx, s) }
+	case StepHealth:
+		if s.RegisterHealth == nil {
+			return nil
+		}
+		return func(ctx context.Context) error { return s.RegisterHealth(ctx, d) }
+	}
+	return nil
+}
+
+// waitReady pings the database with exponential backoff until it responds or
+// ctx is done.
+func (d *Database) waitReady(ctx context.Context, s Startup, emit func(StartEvent)) error {
+	backoff, max := s.ReadyBackoff, s.ReadyMaxBackoff
+	if backoff <= 0 {
+		backoff = 100 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 5 * time.Second
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := d.drv.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		emit(StartEvent{Step: StepWaitReady, State: StartRetry, Attempt: attempt, Elapsed: time.Since(start), Err: err})
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return errors.Wrapf(err, "gave up after %v attempts", attempt)
+		case <-t.C:
+		}
+
+		if backoff *= 2; backoff > max {
+			backoff = max
+		}
+	}
+}
+
+// warmUp prepares WarmQueries and runs WarmUp functions concurrently,
+// returning the first error encountered.
+func (d *Database) warmUp(ctx context.Context, s Startup) error {
+	var (
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		first   error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() { first = err })
+	}
+
+	for _, q := range s.WarmQueries {
+		wg.Add(1)
+		go func(q string) {
+			defer wg.Done()
+			if _, err := d.StmtContext(ctx, q); err != nil {
+				fail(errors.Wrapf(err, "preparing %q", q))
+			}
+		}(q)
+	}
+	for _, f := range s.WarmUp {
+		wg.Add(1)
+		go func(f func(context.Context, DB) error) {
+			defer wg.Done()
+			if err := f(ctx, d); err != nil {
+				fail(err)
+			}
+		}(f)
+	}
+
+	wg.Wait()
+	return first
+}
@@ -0,0 +1,61 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nstogner/psqlxtest"
+	"github.com/pkg/errors"
+)
+
+func TestStart(t *testing.T) {
+	dbx, dropx := psqlxtest.TmpDB(t)
+	defer dropx()
+	d := New(dbx)
+	defer d.Close()
+
+	ctx := context.Background()
+
+	var steps []StartStep
+	err := d.Start(ctx, Startup{
+		Migrate: func(ctx context.Context, db DB) error {
+			_, err := db.Exec(ctx, "CREATE TABLE IF NOT EXISTS start_abc (id INT PRIMARY KEY);", nil)
+			return err
+		},
+		WarmQueries: []string{"SELECT id FROM start_abc WHERE id = :id;"},
+		OnEvent: func(e StartEvent) {
+			if e.State == StartDone {
+				steps = append(steps, e.Step)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []StartStep{StepWaitReady, StepMigrate, StepWarmUp}
+	if len(steps) != len(exp) {
+		t.Fatalf("expected completed steps %v, got %v", exp, steps)
+	}
+	for i := range exp {
+		if steps[i] != exp[i] {
+			t.Fatalf("expected completed steps %v, got %v", exp, steps)
+		}
+	}
+
+	boom := errors.New("boom")
+	var validated bool
+	err = d.Start(ctx, Startup{
+		CheckSchemaVersion: func(context.Context, DB) error { return boom },
+		Validate: func(context.Context, DB) error {
+			validated = true
+			return nil
+		},
+	})
+	if errors.Cause(err) != boom {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	if validated {
+		t.Fatal("validate should not run after a failed step")
+	}
+}
@@ -0,0 +1,421 @@
+package sqln
+
+import (
+	"container/list"
+	"context"
+	stderrors "errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+)
+
+// cachedStmt is an entry in the statement cache. Statements are reference
+// counted so that an evicted statement is only closed once no operation is
+// using it.
+type cachedStmt struct {
+	query string
+	key   string
+	stmt  *sqlx.NamedStmt
+	elem  *list.Element
+	used  time.Time
+
+	// prepared and prepareTime record the prepare, hits the lookups served
+	// since (see Statements).
+	prepared    time.Time
+	prepareTime time.Duration
+	hits        int64
+
+	refs    int
+	evicted bool
+	// release drops a reference taken by acquire. It is created once per
+	// statement rather than per acquire to save an allocation.
+	release func()
+}
+
+// stmtCache is an LRU cache of named statements shared by a Database and its
+// transactions. A capacity of zero means unbounded.
+type stmtCache struct {
+	mtx      sync.Mutex
+	capacity int
+	entries  map[string]*cachedStmt
+	lru      *list.List
+	closed   bool
+	// prepare prepares statements, ie. backend.PrepareNamedContext. flights
+	// deduplicates concurrent prepares of a query.
+	prepare prepareFunc
+	flights singleflight.Group
+	// preparex prepares positional statements (see acquirePositional), ie.
+	// sqlx.DB.PreparexContext.
+	preparex func(ctx context.Context, query string) (*sqlx.Stmt, error)
+
+	// failures remembers failed prepares (see WithPrepareBackoff).
+	failures         map[string]*prepareFailure
+	failMin, failMax time.Duration
+
+	adaptive *adaptiveCache
+
+	// normalize, if set, keys statements by their text without comments or
+	// redundant whitespace (see WithNormalizedStmtCache).
+	normalize bool
+	// partition, if set, keys statements by the partition of their context
+	// as well (see WithTenantStmtCache).
+	partition func(context.Context) string
+
+	// ttl, if set, expires statements unused for that long (see WithStmtTTL).
+	// done stops the janitor.
+	ttl  time.Duration
+	done chan struct{}
+	// clock times statement use and prepare failures (see WithClock).
+	clock Clock
+
+	// hook receives events, which are queued while the mutex is held and
+	// delivered by flush.
+	hook   func(StmtEvent)
+	events []StmtEvent
+}
+
+// prepareFunc prepares a named statement, ie. backend.PrepareNamedContext.
+type prepareFunc func(ctx context.Context, query string) (*sqlx.NamedStmt, error)
+
+func newStmtCache(capacity int, prepare prepareFunc) *stmtCache {
+	return &stmtCache{
+		capacity: capacity,
+		prepare:  prepare,
+		entries:  make(map[string]*cachedStmt),
+		failures: make(map[string]*prepareFailure),
+		failMin:  time.Second,
+		failMax:  time.Minute,
+		lru:      list.New(),
+		clock:    SystemClock,
+	}
+}
+
+// acquire returns the statement for query, preparing it if needed, and holds a
+// reference on it until the returned release func is called.
+func (c *stmtCache) acquire(ctx context.Context, query string) (*sqlx.NamedStmt, func(), error) {
+	e, err := c.lookup(ctx, query, false, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return e.stmt, e.release, nil
+}
+
+// positionalKey prefixes the keys of positional statements, which are
+// prepared as is rather than compiled for named params, so they never share
+// an entry with a named statement of the same text.
+const positionalKey = "\x01"
+
+// acquirePositional is like acquire for a query with positional args. The
+// returned statement has no params.
+func (c *stmtCache) acquirePositional(ctx context.Context, query string) (*sqlx.NamedStmt, func(), error) {
+	e, err := c.lookup(ctx, query, true, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return e.stmt, e.release, nil
+}
+
+func (c *stmtCache) release(e *cachedStmt) {
+	defer c.flush()
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	e.refs--
+	if e.evicted && e.refs == 0 {
+		c.closeLocked(e)
+	}
+}
+
+// get returns the statement for query without holding a reference.
+func (c *stmtCache) get(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	e, err := c.lookup(ctx, query, false, false)
+	if err != nil {
+		return nil, err
+	}
+	return e.stmt, nil
+}
+
+// lookup returns the entry for query, positional or named, taking a
+// reference if hold is set. A
+// missing statement is prepared without holding the mutex, and only once for
+// concurrent lookups of the same query, which wait for it. Failed prepares
+// are not cached, but may be remembered (see WithPrepareBackoff).
+func (c *stmtCache) lookup(ctx context.Context, query string, positional, hold bool) (*cachedStmt, error) {
+	defer c.report()
+	defer c.flush()
+	key := c.key(query)
+	if positional {
+		key = positionalKey + key
+	}
+	key = c.partitionKey(ctx, key)
+	for {
+		c.mtx.Lock()
+		if c.closed {
+			c.mtx.Unlock()
+			return nil, ErrClosed
+		}
+		if e, ok := c.entries[key]; ok {
+			c.lru.MoveToFront(e.elem)
+			e.used = c.clock.Now()
+			e.hits++
+			if c.adaptive != nil {
+				c.adaptive.hit(c)
+			}
+			c.emitLocked(StmtEvent{Type: StmtHit, Query: query})
+			if hold {
+				e.refs++
+			}
+			c.mtx.Unlock()
+			return e, nil
+		}
+		if err := c.failedLocked(key, c.clock.Now()); err != nil {
+			c.mtx.Unlock()
+			return nil, err
+		}
+		c.mtx.Unlock()
+
+		var r singleflight.Result
+		select {
+		case r = <-c.flights.DoChan(key, func() (interface{}, error) {
+			return c.prepareEntry(ctx, key, query, positional)
+		}):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if r.Err != nil {
+			if isContextErr(r.Err) && ctx.Err() == nil {
+				// The prepare was abandoned by the context of another
+				// lookup, so this one tries again.
+				continue
+			}
+			return nil, r.Err
+		}
+
+		e := r.Val.(*cachedStmt)
+		c.mtx.Lock()
+		// The statement may have been evicted, and closed, before a
+		// reference was taken, in which case it is prepared again.
+		if !e.evicted {
+			if hold {
+				e.refs++
+			}
+			c.mtx.Unlock()
+			return e, nil
+		}
+		c.mtx.Unlock()
+	}
+}
+
+// prepareEntry prepares and caches the statement for query.
+func (c *stmtCache) prepareEntry(ctx context.Context, key, query string, positional bool) (*cachedStmt, error) {
+	start := time.Now()
+	prepare := c.prepare
+	if positional {
+		prepare = c.preparePositional
+	}
+	stmt, err := prepare(ctx, query)
+	elapsed := time.Since(start)
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.emitLocked(StmtEvent{Type: StmtPrepare, Query: query, Duration: elapsed, Err: err})
+	if err != nil {
+		if !isContextErr(err) {
+			err = &prepareError{err: err}
+		}
+		c.failLocked(key, err, c.clock.Now())
+		return nil, err
+	}
+	delete(c.failures, key)
+	if c.closed {
+		c.closeLocked(&cachedStmt{query: query, stmt: stmt})
+		return nil, ErrClosed
+	}
+
+	now := c.clock.Now()
+	e := &cachedStmt{query: query, key: key, stmt: stmt, used: now, prepared: now, prepareTime: elapsed}
+	e.release = func() { c.release(e) }
+	e.elem = c.lru.PushFront(e)
+	c.entries[key] = e
+	evicted := c.trimLocked()
+
+	if c.adaptive != nil {
+		c.adaptive.miss(c, elapsed, evicted)
+	}
+	return e, nil
+}
+
+// preparePositional prepares query as is, as a named statement without
+// params.
+func (c *stmtCache) preparePositional(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	if c.preparex == nil {
+		return nil, errors.New("sqln: driver cannot prepare positional statements")
+	}
+	s, err := c.preparex(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlx.NamedStmt{QueryString: query, Stmt: s}, nil
+}
+
+func isContextErr(err error) bool {
+	return stderrors.Is(err, context.Canceled) || stderrors.Is(err, context.DeadlineExceeded)
+}
+
+func (c *stmtCache) key(query string) string {
+	if c.normalize {
+		return normalize(query, false)
+	}
+	return query
+}
+
+// partitionKey prefixes key with the partition of ctx, if any.
+func (c *stmtCache) partitionKey(ctx context.Context, key string) string {
+	if c.partition == nil {
+		return key
+	}
+	if p := c.partition(ctx); p != "" {
+		return p + "\x00" + key
+	}
+	return key
+}
+
+// trimLocked evicts least recently used entries beyond capacity, returning the
+// number evicted.
+func (c *stmtCache) trimLocked() int {
+	var n int
+	for c.capacity > 0 && c.lru.Len() > c.capacity {
+		c.evictLocked(c.lru.Back().Value.(*cachedStmt))
+		n++
+	}
+	return n
+}
+
+func (c *stmtCache) evictLocked(e *cachedStmt) {
+	c.lru.Remove(e.elem)
+	delete(c.entries, e.key)
+	e.evicted = true
+	c.emitLocked(StmtEvent{Type: StmtEvict, Query: e.query})
+	if e.refs == 0 {
+		c.closeLocked(e)
+	}
+}
+
+func (c *stmtCache) closeLocked(e *cachedStmt) error {
+	err := e.stmt.Close()
+	c.emitLocked(StmtEvent{Type: StmtClose, Query: e.query, Err: err})
+	return err
+}
+
+func (c *stmtCache) emitLocked(e StmtEvent) {
+	if c.hook != nil {
+		c.events = append(c.events, e)
+	}
+}
+
+// flush delivers queued events. It must be called without the mutex held.
+func (c *stmtCache) flush() {
+	if c.hook == nil {
+		return
+	}
+	c.mtx.Lock()
+	events := c.events
+	c.events = nil
+	c.mtx.Unlock()
+	for _, e := range events {
+		c.hook(e)
+	}
+}
+
+// invalidate evicts the statements for query, in every partition, if they
+// are cached.
+func (c *stmtCache) invalidate(query string) {
+	defer c.flush()
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for _, key := range []string{c.key(query), positionalKey + c.key(query)} {
+		delete(c.failures, key)
+		if e, ok := c.entries[key]; ok {
+			c.evictLocked(e)
+		}
+		if c.partition == nil {
+			continue
+		}
+		suffix := "\x00" + key
+		for k := range c.failures {
+			if strings.HasSuffix(k, suffix) {
+				delete(c.failures, k)
+			}
+		}
+		for k, e := range c.entries {
+			if strings.HasSuffix(k, suffix) {
+				c.evictLocked(e)
+			}
+		}
+	}
+}
+
+// invalidateAll evicts every statement, returning how many were cached.
+func (c *stmtCache) invalidateAll() int {
+	defer c.flush()
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	n := len(c.entries)
+	c.failures = make(map[string]*prepareFailure)
+	for _, e := range c.entries {
+		c.evictLocked(e)
+	}
+	return n
+}
+
+// resizeLocked sets a new capacity, evicting entries if needed.
+func (c *stmtCache) resizeLocked(capacity int) {
+	c.capacity = capacity
+	c.trimLocked()
+}
+
+// queries returns the cached queries, most recently used first.
+func (c *stmtCache) queries() []string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	qs := make([]string, 0, c.lru.Len())
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		qs = append(qs, e.Value.(*cachedStmt).query)
+	}
+	return qs
+}
+
+func (c *stmtCache) len() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return len(c.entries)
+}
+
+// close closes all statements, returning every error. Statements still in use
+// are closed once released. Later lookups return ErrClosed.
+func (c *stmtCache) close() error {
+	defer c.flush()
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.done != nil && !c.closed {
+		close(c.done)
+	}
+	c.closed = true
+	var errs []error
+	for _, e := range c.entries {
+		c.lru.Remove(e.elem)
+		delete(c.entries, e.key)
+		e.evicted = true
+		if e.refs > 0 {
+			continue
+		}
+		if err := c.closeLocked(e); err != nil {
+			errs = append(errs, errors.Wrapf(err, "closing %q", e.query))
+		}
+	}
+	return stderrors.Join(errs...)
+}
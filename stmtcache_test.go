@@ -0,0 +1,211 @@
+package sqln
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestCacheSize(t *testing.T) {
+	d := sqliteDB(t)
+	d.cache.capacity = 2
+
+	ctx := context.Background()
+	query := func(i int) string { return fmt.Sprintf("SELECT %v;", i) }
+
+	// Hold a reference to the oldest statement while it is evicted.
+	s, release, err := d.acquire(ctx, query(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 2; i++ {
+		var n int
+		if err := d.Get(ctx, query(i), &n, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := d.cache.len(); n != 2 {
+		t.Fatalf("expected 2 cached statements, got %v", n)
+	}
+
+	var n int
+	if err := s.GetContext(ctx, &n, struct{}{}); err != nil {
+		t.Fatalf("evicted statement in use should not be closed: %v", err)
+	}
+	release()
+	if err := s.GetContext(ctx, &n, struct{}{}); err == nil {
+		t.Fatal("expected evicted statement to be closed after release")
+	}
+}
+
+func TestAdaptiveCache(t *testing.T) {
+	var resizes []CacheResize
+	pressure := false
+	d := New(sqliteDB(t).X, WithAdaptiveCache(AdaptiveCache{
+		Min:            2,
+		Max:            8,
+		Window:         time.Nanosecond,
+		MemoryPressure: func() bool { return pressure },
+		OnResize:       func(r CacheResize) { resizes = append(resizes, r) },
+	}))
+	defer d.Close()
+
+	for i := 0; i < 20; i++ {
+		if _, err := d.Stmt(fmt.Sprintf("SELECT %v;", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c := d.cache.capacity; c != 8 {
+		t.Fatalf("expected capacity to grow to 8, got %v", c)
+	}
+
+	pressure = true
+	if _, err := d.Stmt("SELECT 1;"); err != nil {
+		t.Fatal(err)
+	}
+	if c := d.cache.capacity; c != 4 {
+		t.Fatalf("expected capacity to shrink to 4, got %v", c)
+	}
+	if d.cache.len() > 4 {
+		t.Fatalf("expected at most 4 statements after shrinking, got %v", d.cache.len())
+	}
+
+	last := resizes[len(resizes)-1]
+	if last.Reason != "memory pressure" || last.From != 8 || last.To != 4 {
+		t.Fatalf("unexpected last resize: %+v", last)
+	}
+}
+
+func TestStmtContext(t *testing.T) {
+	d := sqliteDB(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := d.StmtContext(ctx, "SELECT 1;"); err == nil {
+		t.Fatal("expected preparing with a cancelled context to fail")
+	}
+	if n := d.cache.len(); n != 0 {
+		t.Fatalf("expected the failed prepare not to be cached, got %v statements", n)
+	}
+
+	s, err := d.StmtContext(context.Background(), "SELECT 1;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := s.Get(&n, struct{}{}); err != nil || n != 1 {
+		t.Fatalf("expected 1, got %v (%v)", n, err)
+	}
+}
+
+func TestCacheSingleflight(t *testing.T) {
+	d := sqliteDB(t)
+	d.cache.failMin = 0
+	ctx := context.Background()
+
+	var (
+		mtx      sync.Mutex
+		prepares int
+		fail     = true
+	)
+	unblock := make(chan struct{})
+	d.cache.prepare = func(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+		mtx.Lock()
+		prepares++
+		failing := fail
+		mtx.Unlock()
+		<-unblock
+		if failing {
+			return nil, errors.New("prepare failed")
+		}
+		return d.drv.PrepareNamedContext(ctx, query)
+	}
+
+	lookup := func(n int) []error {
+		errs := make([]error, n)
+		var wg sync.WaitGroup
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = d.cache.get(ctx, "SELECT 1;")
+			}(i)
+		}
+		// Give the lookups time to join the flight before it completes.
+		time.Sleep(50 * time.Millisecond)
+		unblock <- struct{}{}
+		wg.Wait()
+		return errs
+	}
+
+	for _, err := range lookup(8) {
+		if err == nil {
+			t.Fatal("expected the failed prepare to be shared")
+		}
+	}
+	if prepares != 1 {
+		t.Fatalf("expected 1 prepare, got %v", prepares)
+	}
+
+	// Failures are not cached.
+	fail = false
+	for _, err := range lookup(8) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if prepares != 2 || d.cache.len() != 1 {
+		t.Fatalf("expected 2 prepares and 1 statement, got %v and %v", prepares, d.cache.len())
+	}
+}
+
+func TestPrepareBackoff(t *testing.T) {
+	base := sqliteDB(t)
+	db := New(base.X, WithPrepareBackoff(time.Hour, 2*time.Hour))
+	defer db.Close()
+
+	var n int
+	prepares := 0
+	db.cache.prepare = func(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+		prepares++
+		return db.drv.PrepareNamedContext(ctx, query)
+	}
+
+	ctx := context.Background()
+	const query = "SELECT COUNT(*) FROM backoff_things;"
+	for i := 0; i < 3; i++ {
+		err := db.Get(ctx, query, &n, nil)
+		if !errors.Is(err, ErrPrepareFailed) {
+			t.Fatalf("expected ErrPrepareFailed, got %v", err)
+		}
+		if errors.Unwrap(err) == nil || !strings.Contains(err.Error(), "no such table") {
+			t.Fatalf("expected the driver error to be wrapped, got %v", err)
+		}
+	}
+	if prepares != 1 {
+		t.Fatalf("expected the failure to be remembered, got %v prepares", prepares)
+	}
+
+	if _, err := db.Exec(ctx, "CREATE TABLE backoff_things (id INT);", nil); err != nil {
+		t.Fatal(err)
+	}
+	db.InvalidateStmt(query)
+	if err := db.Get(ctx, query, &n, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The backoff doubles up to the maximum.
+	now := time.Now()
+	db.cache.failLocked("q", ErrPrepareFailed, now)
+	db.cache.failLocked("q", ErrPrepareFailed, now)
+	db.cache.failLocked("q", ErrPrepareFailed, now)
+	if f := db.cache.failures["q"]; f.backoff != 2*time.Hour {
+		t.Fatalf("expected a backoff of 2h, got %v", f.backoff)
+	}
+}
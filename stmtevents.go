@@ -0,0 +1,54 @@
+package sqln
+
+import "time"
+
+// StmtEventType is the kind of a StmtEvent.
+type StmtEventType int
+
+// Statement cache events.
+const (
+	// StmtPrepare is a cache miss, which prepared the statement.
+	StmtPrepare StmtEventType = iota
+	// StmtHit is a cache hit.
+	StmtHit
+	// StmtEvict is the removal of a statement from the cache, ie. when the
+	// cache is full or the statement is invalidated.
+	StmtEvict
+	// StmtClose is the closing of a statement, once it is evicted and no
+	// longer in use.
+	StmtClose
+)
+
+func (t StmtEventType) String() string {
+	switch t {
+	case StmtPrepare:
+		return "prepare"
+	case StmtHit:
+		return "hit"
+	case StmtEvict:
+		return "evict"
+	case StmtClose:
+		return "close"
+	}
+	return "unknown"
+}
+
+// StmtEvent describes a change to the statement cache.
+type StmtEvent struct {
+	Type  StmtEventType
+	Query string
+	// Duration is the prepare latency of StmtPrepare events.
+	Duration time.Duration
+	// Err is set when preparing or closing failed.
+	Err error
+}
+
+// WithStmtHook calls f for every statement cache event, ie. to alert on
+// prepare storms or measure cache churn. f is called synchronously after the
+// operation that caused the event, once for every cache hit too, so it
+// should be cheap.
+func WithStmtHook(f func(StmtEvent)) Option {
+	return func(d *Database) {
+		d.cache.hook = f
+	}
+}
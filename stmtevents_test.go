@@ -0,0 +1,33 @@
+package sqln
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestStmtHook(t *testing.T) {
+	base := sqliteDB(t)
+	var events []StmtEventType
+	db := New(base.X, WithCacheSize(1), WithStmtHook(func(e StmtEvent) {
+		events = append(events, e.Type)
+		if e.Type == StmtPrepare && e.Duration <= 0 {
+			t.Errorf("expected a prepare duration, got %v", e.Duration)
+		}
+	}))
+	ctx := context.Background()
+
+	var n int
+	for _, q := range []string{"SELECT 1;", "SELECT 1;", "SELECT 2;"} {
+		if err := db.Get(ctx, q, &n, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := []StmtEventType{StmtPrepare, StmtHit, StmtPrepare, StmtEvict, StmtClose, StmtClose}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("expected events %v, got %v", want, events)
+	}
+}
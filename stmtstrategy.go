@@ -0,0 +1,26 @@
+package sqln
+
+// StmtStrategy selects how a Database prepares statements.
+type StmtStrategy int
+
+const (
+	// PoolStmts prepares each query once, as a NamedStmt over the pool, and
+	// caches it. database/sql prepares the statement again on each
+	// connection it runs on, and again whenever a connection is replaced.
+	// This is the default.
+	PoolStmts StmtStrategy = iota
+	// ConnStmts leaves preparation to the driver. Named params are bound by
+	// sqln and queries are run with positional args, as by the Unprepared
+	// methods, so a driver that caches statements per connection (ie. pgx's
+	// stdlib in its default QueryExecModeCacheStatement mode) prepares each
+	// query lazily on the connection it runs on. With other drivers queries
+	// may be prepared on every execution, see BenchmarkStmtStrategy.
+	ConnStmts
+)
+
+// WithStmtStrategy sets how statements are prepared. Defaults to PoolStmts.
+func WithStmtStrategy(s StmtStrategy) Option {
+	return func(d *Database) {
+		d.connStmts = s == ConnStmts
+	}
+}
@@ -0,0 +1,69 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+)
+
+func TestConnStmts(t *testing.T) {
+	base := sqliteDB(t)
+	db := New(base.X, WithStmtStrategy(ConnStmts), WithQueryStats(10))
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE conn_stmts (id INT PRIMARY KEY, x INT);", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, "INSERT INTO conn_stmts VALUES (:id, :x);", map[string]interface{}{"id": 1, "x": 10}); err != nil {
+		t.Fatal(err)
+	}
+	var x int
+	if err := db.Get(ctx, "SELECT x FROM conn_stmts WHERE id = :id;", &x, map[string]interface{}{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if x != 10 {
+		t.Fatalf("expected 10, got %v", x)
+	}
+	if qs := db.CachedQueries(); len(qs) != 0 {
+		t.Fatalf("expected no cached statements, got %q", qs)
+	}
+	if stats := db.QueryStats(); len(stats) != 3 {
+		t.Fatalf("expected stats for 3 queries, got %+v", stats)
+	}
+}
+
+func BenchmarkStmtStrategy(b *testing.B) {
+	base := sqliteDB(b)
+	ctx := context.Background()
+	if _, err := base.Exec(ctx, "CREATE TABLE bench_strategy (id INT PRIMARY KEY, x INT);", nil); err != nil {
+		b.Fatal(err)
+	}
+	if _, err := base.Exec(ctx, "INSERT INTO bench_strategy VALUES (1, 10);", nil); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, s := range []struct {
+		name     string
+		strategy StmtStrategy
+	}{
+		{"pool", PoolStmts},
+		{"conn", ConnStmts},
+	} {
+		b.Run(s.name, func(b *testing.B) {
+			db := New(base.X, WithStmtStrategy(s.strategy))
+			defer db.Close()
+			params := map[string]interface{}{"id": 1}
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				var x int
+				for pb.Next() {
+					if err := db.Get(ctx, "SELECT x FROM bench_strategy WHERE id = :id;", &x, params); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
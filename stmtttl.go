@@ -0,0 +1,41 @@
+package sqln
+
+import "time"
+
+// WithStmtTTL closes cached statements that have not been used for ttl, so a
+// long-running process with a shifting set of queries does not hold every
+// statement it ever prepared open on the server. Expired statements are
+// prepared again on next use. They are checked for in the background, every
+// half ttl, until the Database is closed.
+func WithStmtTTL(ttl time.Duration) Option {
+	return func(d *Database) {
+		d.cache.ttl = ttl
+	}
+}
+
+// janitor expires idle statements until the cache is closed.
+func (c *stmtCache) janitor() {
+	for wait(c.clock, max(c.ttl/2, time.Millisecond), c.done) {
+		c.expire(c.clock.Now())
+	}
+}
+
+// expire evicts statements last used more than ttl before now, returning the
+// number evicted.
+func (c *stmtCache) expire(now time.Time) int {
+	defer c.flush()
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	var n int
+	// The list is ordered by last use, so expired statements are at the back.
+	for e := c.lru.Back(); e != nil; {
+		s := e.Value.(*cachedStmt)
+		if now.Sub(s.used) < c.ttl {
+			break
+		}
+		e = e.Prev()
+		c.evictLocked(s)
+		n++
+	}
+	return n
+}
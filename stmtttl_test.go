@@ -0,0 +1,84 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStmtTTL(t *testing.T) {
+	base := sqliteDB(t)
+	db := New(base.X, WithStmtTTL(time.Hour))
+
+	ctx := context.Background()
+	var n int
+	for _, q := range []string{"SELECT 1;", "SELECT 2;"} {
+		if err := db.Get(ctx, q, &n, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := db.cache.expire(time.Now()); got != 0 {
+		t.Fatalf("expected no statements to expire, got %v", got)
+	}
+	if err := db.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	// SELECT 2 was last used before SELECT 1.
+	if got := db.cache.expire(db.cache.entries["SELECT 2;"].used.Add(time.Hour)); got != 1 {
+		t.Fatalf("expected 1 statement to expire, got %v", got)
+	}
+	if qs := db.CachedQueries(); len(qs) != 1 || qs[0] != "SELECT 1;" {
+		t.Fatalf("unexpected cached queries: %q", qs)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The janitor expires statements in the background.
+	db = New(base.X, WithStmtTTL(10*time.Millisecond))
+	defer db.Close()
+	if err := db.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for db.cache.len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the statement to expire")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := db.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStmtTTLClock(t *testing.T) {
+	base := sqliteDB(t)
+	clock := NewTestClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	db := New(base.X, WithStmtTTL(time.Hour), WithClock(clock))
+	defer db.Close()
+
+	var n int
+	if err := db.Get(context.Background(), "SELECT 1;", &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	for clock.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// The first check, after half the ttl, keeps the statement.
+	clock.Advance(30 * time.Minute)
+	for clock.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if got := db.cache.len(); got != 1 {
+		t.Fatalf("expected the statement to be kept, got %v", got)
+	}
+	clock.Advance(30 * time.Minute)
+	deadline := time.Now().Add(5 * time.Second)
+	for db.cache.len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the statement to expire")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,243 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Errors returned by strict checks.
+var (
+	ErrTooManyRows     = errors.New("sqln: more than one row in result set")
+	ErrNoDeadline      = errors.New("sqln: context has no deadline")
+	ErrQueryNotAllowed = errors.New("sqln: query not in allowlist")
+)
+
+// StrictOptions selects safety checks (see Strict).
+type StrictOptions struct {
+	// Params rejects map params with keys the query does not use, which
+	// usually indicates a typo. Missing params are always rejected.
+	Params bool
+	// GetOne makes Get fail with ErrTooManyRows when more than one row
+	// matches instead of returning the first.
+	GetOne bool
+	// Deadline rejects operations whose context has no deadline.
+	Deadline bool
+	// Allowlist rejects queries missing from the allowlist with
+	// ErrQueryNotAllowed, when one is set with WithAllowlist.
+	Allowlist bool
+	// Registered rejects queries that were not registered (see Register)
+	// or allowlisted with ErrQueryNotAllowed, once any query is registered,
+	// so SQL built at runtime cannot reach the database. Queries should be
+	// registered at startup, ie. from constants or (*Queries).All.
+	Registered bool
+	// AllFields requires every field of a struct destination to be selected
+	// so that an unselected field is not silently left zero. (NULLs scanned
+	// into non-nullable fields are always rejected by database/sql.)
+	AllFields bool
+}
+
+// Strict enables every strict check. Existing users that do not opt in keep
+// the default behavior.
+func Strict() Option {
+	return WithStrict(StrictOptions{
+		Params:     true,
+		GetOne:     true,
+		Deadline:   true,
+		Allowlist:  true,
+		Registered: true,
+		AllFields:  true,
+	})
+}
+
+// WithStrict enables a selection of strict checks.
+func WithStrict(s StrictOptions) Option {
+	return func(d *Database) {
+		d.strict = s
+	}
+}
+
+// WithAllowlist lists the only queries that may be run when the Allowlist
+// strict check is enabled. It may be given more than once.
+func WithAllowlist(queries ...string) Option {
+	return func(d *Database) {
+		if d.allowlist == nil {
+			d.allowlist = make(map[string]bool, len(queries))
+		}
+		for _, q := range queries {
+			d.allowlist[q] = true
+		}
+	}
+}
+
+// checkQuery runs the checks that do not need a statement.
+func (d *Database) checkQuery(ctx context.Context, query string) error {
+	if d.strict.Deadline {
+		if _, ok := ctx.Deadline(); !ok {
+			return ErrNoDeadline
+		}
+	}
+	if internal(ctx) {
+		return nil
+	}
+	if d.strict.Allowlist && d.allowlist != nil && !d.allowlist[query] {
+		return ErrQueryNotAllowed
+	}
+	if d.strict.Registered && !d.allowlist[query] && !d.registry.allows(query) {
+		return ErrQueryNotAllowed
+	}
+	return d.checkPolicy(ctx, query)
+}
+
+// withInternal marks ctx as running queries issued by this package (ie. to
+// set transaction settings), which are exempt from the allowlist.
+func withInternal(ctx context.Context) context.Context {
+	return context.WithValue(ctx, internalKey, true)
+}
+
+func internal(ctx context.Context) bool {
+	v, _ := ctx.Value(internalKey).(bool)
+	return v
+}
+
+// checkParams rejects map keys that s does not use.
+func (d *Database) checkParams(s *sqlx.NamedStmt, params interface{}) error {
+	if !d.strict.Params || params == nil {
+		return nil
+	}
+	v := reflect.Indirect(reflect.ValueOf(params))
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil
+	}
+
+	used := make(map[string]bool, len(s.Params))
+	for _, p := range s.Params {
+		used[p] = true
+	}
+	for _, k := range v.MapKeys() {
+		if !used[k.String()] {
+			return errors.Errorf("sqln: unused param %q", k.String())
+		}
+	}
+	return nil
+}
+
+// strictQuery is used by Get (one) and Select when GetOne or AllFields is
+// enabled, or dest holds maps.
+func (d *Database) strictQuery(ctx context.Context, s *sqlx.NamedStmt, dest, params interface{}, one bool) error {
+	query := s.QueryxContext
+	if d.tx != nil {
+		query = d.txStmt(s).QueryxContext
+	}
+	rows, err := query(ctx, params)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if d.strict.AllFields {
+		if err := checkFields(rows, dest); err != nil {
+			return err
+		}
+	}
+
+	if !one {
+		return scanAll(rows, dest)
+	}
+	return scanOne(rows, dest, d.strict.GetOne)
+}
+
+// scanOne scans the first row into dest. ErrTooManyRows is returned for more
+// than one row if getOne is set.
+func scanOne(rows *sqlx.Rows, dest interface{}, getOne bool) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("sqln: dest must be a non-nil pointer")
+	}
+	all := reflect.New(reflect.SliceOf(v.Type().Elem()))
+	if err := scanAll(rows, all.Interface()); err != nil {
+		return err
+	}
+	switch n := all.Elem().Len(); {
+	case n == 0:
+		return sql.ErrNoRows
+	case n > 1 && getOne:
+		return ErrTooManyRows
+	}
+	v.Elem().Set(all.Elem().Index(0))
+	return nil
+}
+
+// scanAll scans rows into dest, a pointer to a slice of structs, maps (see
+// SelectMaps) or scannable values. Unlike sqlx.StructScan it accepts
+// non-struct elements.
+func scanAll(rows *sqlx.Rows, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return errors.New("sqln: dest must be a pointer to a slice")
+	}
+	elem := v.Elem().Type().Elem()
+	base := elem
+	if base.Kind() == reflect.Ptr {
+		base = base.Elem()
+	}
+	if base.Kind() == reflect.Struct && !reflect.PtrTo(base).Implements(scannerType) {
+		return sqlx.StructScan(rows, dest)
+	}
+	if base == mapType {
+		return scanMaps(rows, v)
+	}
+
+	slice := v.Elem()
+	for rows.Next() {
+		item := reflect.New(base)
+		if err := rows.Scan(item.Interface()); err != nil {
+			return err
+		}
+		if elem.Kind() == reflect.Ptr {
+			slice = reflect.Append(slice, item)
+		} else {
+			slice = reflect.Append(slice, item.Elem())
+		}
+	}
+	v.Elem().Set(slice)
+	return rows.Err()
+}
+
+// checkFields returns an error if a field of a struct destination (or slice
+// element) has no matching column.
+func checkFields(rows *sqlx.Rows, dest interface{}) error {
+	t := reflect.TypeOf(dest)
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || reflect.PtrTo(t).Implements(scannerType) {
+		return nil
+	}
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	selected := make(map[string]bool, len(cols))
+	for _, c := range cols {
+		selected[c] = true
+	}
+
+	var missing []string
+	for _, f := range rows.Mapper.TypeMap(t).Index {
+		if !hasChildren(f) && !selected[f.Path] {
+			missing = append(missing, f.Path)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("sqln: fields not selected: %v", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
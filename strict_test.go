@@ -0,0 +1,124 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestStrict(t *testing.T) {
+	plain := sqliteDB(t)
+	db := New(plain.X, Strict(), WithAllowlist(
+		"CREATE TABLE strict_abc (id INTEGER PRIMARY KEY, name TEXT);",
+		"INSERT INTO strict_abc (id, name) VALUES (:id, :name);",
+		"SELECT id, name FROM strict_abc;",
+		"SELECT id FROM strict_abc;",
+		"SELECT id, name FROM strict_abc WHERE id = :id;",
+	))
+	defer db.Close()
+
+	if _, err := db.Exec(context.Background(), "CREATE TABLE strict_abc (id INTEGER PRIMARY KEY, name TEXT);", nil); err != ErrNoDeadline {
+		t.Fatalf("expected ErrNoDeadline, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if _, err := db.Exec(ctx, "CREATE TABLE strict_abc (id INTEGER PRIMARY KEY, name TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, "DROP TABLE strict_abc;", nil); err != ErrQueryNotAllowed {
+		t.Fatalf("expected ErrQueryNotAllowed, got %v", err)
+	}
+
+	const insert = "INSERT INTO strict_abc (id, name) VALUES (:id, :name);"
+	if _, err := db.Exec(ctx, insert, map[string]interface{}{"id": 1, "name": "a", "nme": "typo"}); err == nil {
+		t.Fatal("expected unused param error")
+	}
+	for i, name := range []string{"a", "b"} {
+		if _, err := db.Exec(ctx, insert, map[string]interface{}{"id": i + 1, "name": name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type row struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	var r row
+	if err := db.Get(ctx, "SELECT id, name FROM strict_abc;", &r, nil); !errors.Is(err, ErrTooManyRows) {
+		t.Fatalf("expected ErrTooManyRows, got %v", err)
+	}
+	if err := db.Get(ctx, "SELECT id, name FROM strict_abc WHERE id = :id;", &r, map[string]interface{}{"id": 2}); err != nil || r.Name != "b" {
+		t.Fatalf("expected b, got %+v (%v)", r, err)
+	}
+	var rows []row
+	if err := db.Select(ctx, "SELECT id FROM strict_abc;", &rows, nil); err == nil {
+		t.Fatal("expected error for unselected field")
+	}
+	var ids []int
+	if err := db.Select(ctx, "SELECT id FROM strict_abc;", &ids, nil); err != nil || len(ids) != 2 {
+		t.Fatalf("expected 2 ids, got %v (%v)", ids, err)
+	}
+
+	// The default remains lenient.
+	if err := plain.Get(context.Background(), "SELECT id, name FROM strict_abc;", &r, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStrictRegistered(t *testing.T) {
+	db := sqliteDB(t)
+	d := New(db.X, WithStrict(StrictOptions{Registered: true}))
+	defer d.Close()
+
+	ctx := context.Background()
+	const one = "SELECT 1;"
+	var n int
+	// Nothing is rejected until queries are registered.
+	if err := d.Get(ctx, "SELECT 2;", &n, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	d.Register(one)
+	if err := d.Get(ctx, one, &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Get(ctx, "SELECT 2;", &n, nil); err != ErrQueryNotAllowed {
+		t.Fatalf("expected ErrQueryNotAllowed, got %v", err)
+	}
+	if err := d.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		return Savepoint(ctx, tx, "sp", func(tx DB) error {
+			return tx.Get(ctx, one, &n, nil)
+		})
+	}); err != nil {
+		t.Fatalf("expected internal queries to be allowed, got %v", err)
+	}
+
+	// Every entry point running SQL text is checked.
+	const two = "SELECT 2;"
+	if err := ExecScript(ctx, d, one+two); errors.Cause(err) != ErrQueryNotAllowed {
+		t.Fatalf("expected ExecScript to be rejected, got %v", err)
+	}
+	if _, err := d.ExecContext(ctx, two); err != ErrQueryNotAllowed {
+		t.Fatalf("expected ExecContext to be rejected, got %v", err)
+	}
+	if _, err := d.QueryxContext(ctx, two); err != ErrQueryNotAllowed {
+		t.Fatalf("expected QueryxContext to be rejected, got %v", err)
+	}
+	if err := d.QueryRowxContext(ctx, two).Scan(&n); err != ErrQueryNotAllowed {
+		t.Fatalf("expected QueryRowxContext to be rejected, got %v", err)
+	}
+	if err := d.QueryRowxContext(ctx, one).Scan(&n); err != nil || n != 1 {
+		t.Fatalf("expected 1, got %v (%v)", n, err)
+	}
+	if _, err := d.QueryResultSets(ctx, two); err != ErrQueryNotAllowed {
+		t.Fatalf("expected QueryResultSets to be rejected, got %v", err)
+	}
+	if err := GetBuilder(ctx, d, &n, SqlizerFunc(func() (string, []interface{}, error) { return two, nil, nil })); err != ErrQueryNotAllowed {
+		t.Fatalf("expected GetBuilder to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,241 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/pkg/errors"
+)
+
+// NOTE: Columns tagged with the readonly option (ie. `db:"id,readonly"`) are
+// never written by the struct helpers, for instance serial ids and columns
+// with defaults. They may still be used as where columns. See Timestamps for
+// the created and updated options.
+
+// InsertStruct inserts v (a struct or pointer to one) into table, with a
+// column for each db tagged field.
+func InsertStruct(ctx context.Context, db DB, table string, v interface{}) (sql.Result, error) {
+	m, _ := mapperOf(db)
+	cols, err := writableColumns(m, v)
+	if err != nil {
+		return nil, err
+	}
+	params, err := stampStruct(m, v, clockOf(db), true)
+	if err != nil {
+		return nil, err
+	}
+	return db.Exec(ctx, insertSQL(table, cols), params)
+}
+
+// UpdateStruct updates the rows of table matching v's whereCols, setting
+// every other db tagged field.
+func UpdateStruct(ctx context.Context, db DB, table string, v interface{}, whereCols ...string) (sql.Result, error) {
+	if len(whereCols) == 0 {
+		return nil, errors.New("update struct: no where columns")
+	}
+	m, _ := mapperOf(db)
+	q, err := updateSQL(m, table, v, whereCols, "")
+	if err != nil {
+		return nil, err
+	}
+	params, err := stampStruct(m, v, clockOf(db), false)
+	if err != nil {
+		return nil, err
+	}
+	return db.Exec(ctx, q, params)
+}
+
+// ErrStaleVersion is returned by UpdateVersioned when the row was changed (or
+// deleted) since v was read.
+var ErrStaleVersion = errors.New("sqln: stale version")
+
+// UpdateVersioned updates the row of table matching v's whereCols with
+// optimistic locking: the update only applies if versionCol still matches v,
+// and increments it. ErrStaleVersion is returned if no row matched. On success
+// the version field of v (which must be a pointer) is incremented.
+func UpdateVersioned(ctx context.Context, db DB, table string, v interface{}, versionCol string, whereCols ...string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("update versioned: expected a pointer to a struct")
+	}
+	if len(whereCols) == 0 {
+		return errors.New("update versioned: no where columns")
+	}
+	m, _ := mapperOf(db)
+	version := m.FieldByName(rv, versionCol)
+	if !version.CanInt() {
+		return errors.Errorf("update versioned: version column %q must be an integer field", versionCol)
+	}
+
+	q, err := updateSQL(m, table, v, whereCols, versionCol)
+	if err != nil {
+		return err
+	}
+	if _, err := stampStruct(m, v, clockOf(db), false); err != nil {
+		return err
+	}
+	if _, err := ExecOne(ctx, db, q, v); err != nil {
+		if err == ErrNoRowsAffected {
+			return ErrStaleVersion
+		}
+		return err
+	}
+	version.SetInt(version.Int() + 1)
+	return nil
+}
+
+func insertSQL(table string, cols []string) string {
+	return fmt.Sprintf("INSERT INTO %v (%v) VALUES (:%v);", table, strings.Join(cols, ", "), strings.Join(cols, ", :"))
+}
+
+// updateSQL builds an update for UpdateStruct. If versionCol is set it is
+// checked and incremented as by UpdateVersioned.
+func updateSQL(m *reflectx.Mapper, table string, v interface{}, whereCols []string, versionCol string) (string, error) {
+	cols, err := writableColumns(m, v)
+	if err != nil {
+		return "", err
+	}
+	all, err := structColumns(m, v)
+	if err != nil {
+		return "", err
+	}
+
+	isWhere := make(map[string]bool, len(whereCols))
+	where := make([]string, len(whereCols))
+	for i, c := range whereCols {
+		if !all[c] {
+			return "", errors.Errorf("update struct: unknown where column %q", c)
+		}
+		isWhere[c] = true
+		where[i] = c + " = :" + c
+	}
+	if versionCol != "" {
+		if !all[versionCol] {
+			return "", errors.Errorf("update struct: unknown version column %q", versionCol)
+		}
+		isWhere[versionCol] = true
+		where = append(where, versionCol+" = :"+versionCol)
+	}
+	created := createdColumns(m, v)
+	var set []string
+	for _, c := range cols {
+		if !isWhere[c] && !created[c] {
+			set = append(set, c+" = :"+c)
+		}
+	}
+	if versionCol != "" {
+		set = append(set, versionCol+" = "+versionCol+" + 1")
+	}
+	if len(set) == 0 {
+		return "", errors.New("update struct: no columns to set")
+	}
+
+	return fmt.Sprintf("UPDATE %v SET %v WHERE %v;", table, strings.Join(set, ", "), strings.Join(where, " AND ")), nil
+}
+
+func structType(v interface{}) (reflect.Type, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errors.Errorf("expected a struct, got %T", v)
+	}
+	return t, nil
+}
+
+// writableColumns returns the columns of v that are not readonly.
+func writableColumns(m *reflectx.Mapper, v interface{}) ([]string, error) {
+	t, err := structType(v)
+	if err != nil {
+		return nil, err
+	}
+	var cols []string
+	for _, f := range columnFields(m, t) {
+		if _, ok := f.Options["readonly"]; !ok {
+			cols = append(cols, f.Path)
+		}
+	}
+	if len(cols) == 0 {
+		return nil, errors.Errorf("%v has no writable columns", t)
+	}
+	return cols, nil
+}
+
+// structColumns returns all columns of v.
+func structColumns(m *reflectx.Mapper, v interface{}) (map[string]bool, error) {
+	t, err := structType(v)
+	if err != nil {
+		return nil, err
+	}
+	cols := make(map[string]bool)
+	for _, f := range columnFields(m, t) {
+		cols[f.Path] = true
+	}
+	return cols, nil
+}
+
+// Upsert inserts v into table, updating updateCols of the existing row when
+// it conflicts on conflictCols. All writable columns other than conflictCols
+// (and created columns) are updated if updateCols is empty. MySQL ignores conflictCols and uses ON
+// DUPLICATE KEY UPDATE; other dialects use ON CONFLICT.
+func Upsert(ctx context.Context, db DB, table string, v interface{}, conflictCols, updateCols []string) (sql.Result, error) {
+	m, _ := mapperOf(db)
+	q, err := upsertSQL(m, DialectOf(db), table, v, conflictCols, updateCols)
+	if err != nil {
+		return nil, err
+	}
+	params, err := stampStruct(m, v, clockOf(db), true)
+	if err != nil {
+		return nil, err
+	}
+	return db.Exec(ctx, q, params)
+}
+
+func upsertSQL(m *reflectx.Mapper, dialect Dialect, table string, v interface{}, conflictCols, updateCols []string) (string, error) {
+	cols, err := writableColumns(m, v)
+	if err != nil {
+		return "", err
+	}
+	if dialect != MySQL && len(conflictCols) == 0 {
+		return "", errors.New("upsert: no conflict columns")
+	}
+
+	if len(updateCols) == 0 {
+		isConflict := createdColumns(m, v)
+		for _, c := range conflictCols {
+			isConflict[c] = true
+		}
+		for _, c := range cols {
+			if !isConflict[c] {
+				updateCols = append(updateCols, c)
+			}
+		}
+	}
+
+	insert := strings.TrimSuffix(insertSQL(table, cols), ";")
+	set := make([]string, len(updateCols))
+	if dialect == MySQL {
+		if len(updateCols) == 0 {
+			// A no-op update, as with DO NOTHING.
+			return fmt.Sprintf("%v ON DUPLICATE KEY UPDATE %[2]v = %[2]v;", insert, cols[0]), nil
+		}
+		for i, c := range updateCols {
+			set[i] = fmt.Sprintf("%[1]v = VALUES(%[1]v)", c)
+		}
+		return fmt.Sprintf("%v ON DUPLICATE KEY UPDATE %v;", insert, strings.Join(set, ", ")), nil
+	}
+
+	conflict := strings.Join(conflictCols, ", ")
+	if len(updateCols) == 0 {
+		return fmt.Sprintf("%v ON CONFLICT (%v) DO NOTHING;", insert, conflict), nil
+	}
+	for i, c := range updateCols {
+		set[i] = fmt.Sprintf("%[1]v = EXCLUDED.%[1]v", c)
+	}
+	return fmt.Sprintf("%v ON CONFLICT (%v) DO UPDATE SET %v;", insert, conflict, strings.Join(set, ", ")), nil
+}
@@ -0,0 +1,144 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStructHelpers(t *testing.T) {
+	db := sqliteDB(t)
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, `CREATE TABLE structs_abc (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	email TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);`, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	type timestamps struct {
+		CreatedAt time.Time `db:"created_at"`
+	}
+	type user struct {
+		ID    int    `db:"id,readonly"`
+		Name  string `db:"name"`
+		Email string `db:"email"`
+		timestamps
+		Ignored string `db:"-"`
+	}
+
+	u := user{Name: "a", Email: "a@example.com", timestamps: timestamps{CreatedAt: time.Now().UTC()}}
+	res, err := InsertStruct(ctx, db, "structs_abc", &u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u.ID, u.Email = int(id), "b@example.com"
+	if _, err := UpdateStruct(ctx, db, "structs_abc", u, "id"); err != nil {
+		t.Fatal(err)
+	}
+
+	var got user
+	if err := db.Get(ctx, "SELECT * FROM structs_abc;", &got, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != u.ID || got.Name != "a" || got.Email != "b@example.com" || !got.CreatedAt.Equal(u.CreatedAt) {
+		t.Fatalf("unexpected row: %+v", got)
+	}
+
+	if _, err := UpdateStruct(ctx, db, "structs_abc", u); err == nil {
+		t.Fatal("expected error without where columns")
+	}
+	if _, err := UpdateStruct(ctx, db, "structs_abc", u, "nope"); err == nil {
+		t.Fatal("expected error for unknown where column")
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	db := sqliteDB(t)
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE upsert_abc (key TEXT PRIMARY KEY, value TEXT NOT NULL, hits INTEGER NOT NULL);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	type setting struct {
+		Key   string `db:"key"`
+		Value string `db:"value"`
+		Hits  int    `db:"hits"`
+	}
+	for _, s := range []setting{{"a", "1", 1}, {"b", "2", 1}, {"a", "3", 2}} {
+		if _, err := Upsert(ctx, db, "upsert_abc", s, []string{"key"}, []string{"value"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []setting
+	if err := db.Select(ctx, "SELECT * FROM upsert_abc ORDER BY key;", &got, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != (setting{"a", "3", 1}) || got[1] != (setting{"b", "2", 1}) {
+		t.Fatalf("unexpected rows: %+v", got)
+	}
+
+	q, err := upsertSQL(defaultMapper, MySQL, "upsert_abc", setting{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "INSERT INTO upsert_abc (key, value, hits) VALUES (:key, :value, :hits) ON DUPLICATE KEY UPDATE key = VALUES(key), value = VALUES(value), hits = VALUES(hits);"; q != exp {
+		t.Fatalf("expected %q, got %q", exp, q)
+	}
+}
+
+func TestUpdateVersioned(t *testing.T) {
+	db := sqliteDB(t)
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE versioned_abc (id INTEGER PRIMARY KEY, body TEXT NOT NULL, version INTEGER NOT NULL);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	type doc struct {
+		ID      int    `db:"id"`
+		Body    string `db:"body"`
+		Version int    `db:"version"`
+	}
+	if _, err := InsertStruct(ctx, db, "versioned_abc", doc{ID: 1, Body: "a", Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	var first, second doc
+	for _, d := range []*doc{&first, &second} {
+		if err := db.Get(ctx, "SELECT * FROM versioned_abc WHERE id = 1;", d, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first.Body = "b"
+	if err := UpdateVersioned(ctx, db, "versioned_abc", &first, "version", "id"); err != nil {
+		t.Fatal(err)
+	}
+	if first.Version != 2 {
+		t.Fatalf("expected version 2, got %v", first.Version)
+	}
+
+	second.Body = "c"
+	if err := UpdateVersioned(ctx, db, "versioned_abc", &second, "version", "id"); err != ErrStaleVersion {
+		t.Fatalf("expected ErrStaleVersion, got %v", err)
+	}
+
+	var got doc
+	if err := db.Get(ctx, "SELECT * FROM versioned_abc WHERE id = 1;", &got, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got != first {
+		t.Fatalf("expected %+v, got %+v", first, got)
+	}
+}
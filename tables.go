@@ -0,0 +1,48 @@
+package sqln
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	writeTablesRe = regexp.MustCompile("(?i)\\b(?:INSERT\\s+(?:IGNORE\\s+)?INTO|REPLACE\\s+INTO|MERGE\\s+INTO|UPDATE|DELETE\\s+FROM|TRUNCATE(?:\\s+TABLE)?)\\s+(?:ONLY\\s+)?([\\w.\"`]+)")
+	readTablesRe  = regexp.MustCompile("(?i)\\b(?:FROM|JOIN)\\s+(?:ONLY\\s+)?([\\w.\"`]+)")
+)
+
+// notTables are keywords matched in place of a table name, ie. "DO UPDATE
+// SET" or "FOR UPDATE OF".
+var notTables = map[string]bool{"set": true, "of": true, "nowait": true, "skip": true, "select": true, "lateral": true}
+
+// MutatedTables returns the tables written by a statement. It is a heuristic
+// over INSERT, UPDATE, DELETE, MERGE and TRUNCATE statements (including those
+// in CTEs) and does not parse SQL. Names are unquoted, lower cased and stripped
+// of their schema.
+func MutatedTables(query string) []string {
+	return matchTables(writeTablesRe, query)
+}
+
+// ReadTables returns the tables named in FROM and JOIN clauses of a query,
+// with the same caveats as MutatedTables.
+func ReadTables(query string) []string {
+	return matchTables(readTablesRe, query)
+}
+
+func matchTables(re *regexp.Regexp, query string) []string {
+	var (
+		tables []string
+		seen   = make(map[string]bool)
+	)
+	for _, m := range re.FindAllStringSubmatch(query, -1) {
+		t := strings.ToLower(strings.Trim(m[1], "\"`"))
+		if i := strings.LastIndexByte(t, '.'); i >= 0 {
+			t = strings.Trim(t[i+1:], "\"`")
+		}
+		if t == "" || notTables[t] || seen[t] {
+			continue
+		}
+		seen[t] = true
+		tables = append(tables, t)
+	}
+	return tables
+}
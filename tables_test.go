@@ -0,0 +1,23 @@
+package sqln
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTables(t *testing.T) {
+	for q, want := range map[string][]string{
+		"INSERT INTO users (id) VALUES (1) ON CONFLICT (id) DO UPDATE SET id = 1;": {"users"},
+		`UPDATE "public"."Users" SET name = 'x';`:                                  {"users"},
+		"WITH d AS (DELETE FROM a RETURNING *) INSERT INTO b SELECT * FROM d;":     {"a", "b"},
+		"TRUNCATE TABLE audits;":                                                   {"audits"},
+		"SELECT * FROM users u JOIN orgs o ON o.id = u.org_id FOR UPDATE;":         nil,
+	} {
+		if got := MutatedTables(q); !reflect.DeepEqual(got, want) {
+			t.Errorf("MutatedTables(%q) = %v, want %v", q, got, want)
+		}
+	}
+	if got := ReadTables("SELECT * FROM users u JOIN orgs o ON o.id = u.org_id;"); !reflect.DeepEqual(got, []string{"users", "orgs"}) {
+		t.Errorf("unexpected read tables: %v", got)
+	}
+}
@@ -0,0 +1,135 @@
+package sqln
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Fragment lists the SQL a template slot may be filled with, by choice name
+// (ie. {"name": "name ASC", "newest": "created_at DESC"} for an ORDER BY).
+// The empty choice is used when none is given; a fragment without one must
+// always be chosen.
+type Fragment map[string]string
+
+// On is the choice that includes an Optional fragment.
+const On = "on"
+
+// Optional returns a fragment that is omitted unless On is chosen, ie. an
+// optional "AND email = :email" filter.
+func Optional(sql string) Fragment {
+	return Fragment{"": "", On: sql}
+}
+
+// maxTemplateQueries bounds the queries a template may render to, so the
+// statement cache cannot be filled by combinations of fragments.
+const maxTemplateQueries = 1024
+
+var slotRe = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// Template is a query with "{{name}}" slots filled from fixed fragments.
+// User input only selects among the fragments, so it never reaches the SQL,
+// and the template renders to a bounded set of queries that are each
+// prepared and cached like any other. Values are still passed as named
+// params.
+type Template struct {
+	text      string
+	fragments map[string]Fragment
+	slots     []string
+}
+
+// NewTemplate parses text, checking that every slot has a fragment and every
+// fragment a slot. It fails if the template renders to more than 1024
+// distinct queries.
+func NewTemplate(text string, fragments map[string]Fragment) (*Template, error) {
+	t := &Template{text: text, fragments: fragments}
+	used := make(map[string]bool)
+	for _, m := range slotRe.FindAllStringSubmatch(text, -1) {
+		name := m[1]
+		if _, ok := fragments[name]; !ok {
+			return nil, errors.Errorf("sqln: template: no fragment for slot %q", name)
+		}
+		if !used[name] {
+			used[name] = true
+			t.slots = append(t.slots, name)
+		}
+	}
+	n := 1
+	for name, f := range fragments {
+		if !used[name] {
+			return nil, errors.Errorf("sqln: template: fragment %q has no slot", name)
+		}
+		if len(f) == 0 {
+			return nil, errors.Errorf("sqln: template: fragment %q has no choices", name)
+		}
+		if n *= len(f); n > maxTemplateQueries {
+			return nil, errors.Errorf("sqln: template: more than %v queries", maxTemplateQueries)
+		}
+	}
+	sort.Strings(t.slots)
+	return t, nil
+}
+
+// MustTemplate is like NewTemplate but panics on error, ie. for templates in
+// package variables.
+func MustTemplate(text string, fragments map[string]Fragment) *Template {
+	t, err := NewTemplate(text, fragments)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Render returns the query for the given choice of each slot. Slots without
+// a choice use the empty one. Unknown slots or choices are rejected, so
+// choices may come straight from requests (ie. a sort parameter).
+func (t *Template) Render(choices map[string]string) (string, error) {
+	for name := range choices {
+		if _, ok := t.fragments[name]; !ok {
+			return "", errors.Errorf("sqln: template: unknown slot %q", name)
+		}
+	}
+	sqls := make(map[string]string, len(t.slots))
+	for _, name := range t.slots {
+		sql, ok := t.fragments[name][choices[name]]
+		if !ok {
+			return "", errors.Errorf("sqln: template: invalid choice %q for slot %q", choices[name], name)
+		}
+		sqls[name] = sql
+	}
+	return t.fill(sqls), nil
+}
+
+// All returns every query the template renders to, ie. to Register or
+// WarmCache them at startup.
+func (t *Template) All() []string {
+	var queries []string
+	sqls := make(map[string]string, len(t.slots))
+	var walk func(i int)
+	walk = func(i int) {
+		if i == len(t.slots) {
+			queries = append(queries, t.fill(sqls))
+			return
+		}
+		f := t.fragments[t.slots[i]]
+		choices := make([]string, 0, len(f))
+		for c := range f {
+			choices = append(choices, c)
+		}
+		sort.Strings(choices)
+		for _, c := range choices {
+			sqls[t.slots[i]] = f[c]
+			walk(i + 1)
+		}
+	}
+	walk(0)
+	return queries
+}
+
+func (t *Template) fill(sqls map[string]string) string {
+	return slotRe.ReplaceAllStringFunc(t.text, func(m string) string {
+		return sqls[strings.TrimSpace(m[2:len(m)-2])]
+	})
+}
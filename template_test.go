@@ -0,0 +1,57 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+	for _, q := range []string{
+		"CREATE TABLE tmpl_users (name TEXT, age INTEGER);",
+		"INSERT INTO tmpl_users VALUES ('a', 30), ('b', 20), ('c', 40);",
+	} {
+		if _, err := db.Exec(ctx, q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tmpl := MustTemplate("SELECT name FROM tmpl_users WHERE 1=1 {{older}} ORDER BY {{order}};", map[string]Fragment{
+		"older": Optional("AND age > :age"),
+		"order": {"": "name", "age": "age DESC"},
+	})
+	if all := tmpl.All(); len(all) != 4 {
+		t.Fatalf("expected 4 queries, got %v", all)
+	}
+
+	q, err := tmpl.Render(map[string]string{"older": On, "order": "age"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	if err := db.Select(ctx, q, &names, map[string]interface{}{"age": 25}); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "c" || names[1] != "a" {
+		t.Fatalf("unexpected names: %v", names)
+	}
+
+	q, err = tmpl.Render(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q != "SELECT name FROM tmpl_users WHERE 1=1  ORDER BY name;" {
+		t.Fatalf("unexpected query: %q", q)
+	}
+
+	if _, err := tmpl.Render(map[string]string{"order": "age; DROP TABLE tmpl_users"}); err == nil {
+		t.Fatal("expected invalid choice to be rejected")
+	}
+	if _, err := tmpl.Render(map[string]string{"limit": "10"}); err == nil {
+		t.Fatal("expected unknown slot to be rejected")
+	}
+	if _, err := NewTemplate("SELECT {{cols}};", nil); err == nil {
+		t.Fatal("expected missing fragment to be rejected")
+	}
+}
@@ -0,0 +1,121 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotInTx is returned by operations that must run in a transaction.
+var ErrNotInTx = errors.New("sqln: not in a transaction")
+
+var tempTableRe = regexp.MustCompile(`^\s*(\w+)\s*\(`)
+
+// CreateTempTable creates a temporary table from ddl, the table name followed
+// by its column definitions, ie. "ids (id BIGINT PRIMARY KEY)". The table is
+// only visible to the current transaction, which it is dropped with (on
+// commit for Postgres, otherwise when the function passed to Transact
+// returns), so it must run in one. Statements referencing the table should
+// run unprepared (see SelectUnprepared), since pool statements are prepared
+// outside of the transaction.
+// NOTE: ddl is not escaped and must not come from untrusted input.
+func (d *Database) CreateTempTable(ctx context.Context, ddl string) error {
+	if d.tx == nil {
+		return errors.Wrap(ErrNotInTx, "create temp table")
+	}
+	m := tempTableRe.FindStringSubmatch(ddl)
+	if m == nil {
+		return errors.Errorf("create temp table: expected a name and columns, got %q", ddl)
+	}
+	query := "CREATE TEMPORARY TABLE " + ddl
+	if d.dialect == Postgres {
+		query += " ON COMMIT DROP"
+	}
+	if _, err := d.ExecUnprepared(withInternal(ctx), query, nil); err != nil {
+		return errors.Wrapf(err, "create temp table %v", m[1])
+	}
+	if d.dialect != Postgres && d.txState != nil {
+		d.txState.mtx.Lock()
+		d.txState.temps = append(d.txState.temps, m[1])
+		d.txState.mtx.Unlock()
+	}
+	return nil
+}
+
+// dropTempTables drops the temp tables created in the transaction, for
+// databases that cannot drop them on commit.
+func (d *Database) dropTempTables(ctx context.Context) error {
+	d.txState.mtx.Lock()
+	temps := d.txState.temps
+	d.txState.temps = nil
+	d.txState.mtx.Unlock()
+	for _, t := range temps {
+		query := "DROP TABLE IF EXISTS temp." + t
+		if d.dialect == MySQL {
+			query = "DROP TEMPORARY TABLE IF EXISTS " + t
+		}
+		if _, err := d.ExecUnprepared(withInternal(ctx), query, nil); err != nil {
+			return errors.Wrapf(err, "drop temp table %v", t)
+		}
+	}
+	return nil
+}
+
+// BulkJoin is a list of values joined by BulkJoinSelect.
+type BulkJoin struct {
+	// Table is the name of the temp table the query joins. Defaults to
+	// "sqln_bulk_join".
+	Table string
+	// Column is the name of its single column. Defaults to "v".
+	Column string
+	// Type is the SQL type of the column, ie. "BIGINT".
+	Type   string
+	Values []interface{}
+}
+
+// BulkJoinSelect selects multiple records with a query that joins j, ie. in
+// place of an IN list too long to bind as params. The values are loaded into
+// a temp table (see CreateTempTable), with COPY FROM for Postgres, before the
+// query runs unprepared in the same transaction (the current one, or a new
+// one).
+func (d *Database) BulkJoinSelect(ctx context.Context, j BulkJoin, query string, dest, params interface{}) error {
+	if d.tx == nil {
+		return d.Transact(ctx, sql.TxOptions{}, func(db DB) error {
+			txd, ok := databaseOf(db)
+			if !ok {
+				return errors.Errorf("bulk join: %T is not a Database", db)
+			}
+			return txd.BulkJoinSelect(ctx, j, query, dest, params)
+		})
+	}
+	if j.Table == "" {
+		j.Table = "sqln_bulk_join"
+	}
+	if j.Column == "" {
+		j.Column = "v"
+	}
+	if j.Type == "" {
+		return errors.New("bulk join: no column type")
+	}
+	if err := d.CreateTempTable(ctx, fmt.Sprintf("%v (%v %v)", j.Table, j.Column, j.Type)); err != nil {
+		return errors.Wrap(err, "bulk join")
+	}
+	rows := make([][]interface{}, len(j.Values))
+	for i, v := range j.Values {
+		rows[i] = []interface{}{v}
+	}
+	if _, err := d.BulkInsert(ctx, j.Table, []string{j.Column}, rows); err != nil {
+		return errors.Wrap(err, "bulk join")
+	}
+	if d.dialect == Postgres {
+		// Temp tables are not analyzed automatically, leaving the planner
+		// without an estimate of their rows.
+		if _, err := d.ExecUnprepared(withInternal(ctx), "ANALYZE "+j.Table, nil); err != nil {
+			return errors.Wrap(err, "bulk join")
+		}
+	}
+	return d.SelectUnprepared(ctx, query, dest, params)
+}
@@ -0,0 +1,78 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestCreateTempTable(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	if err := db.CreateTempTable(ctx, "ids (id INTEGER)"); !errors.Is(err, ErrNotInTx) {
+		t.Fatalf("expected ErrNotInTx, got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+			d := tx.(*Database)
+			if err := d.CreateTempTable(ctx, "ids (id INTEGER)"); err != nil {
+				return err
+			}
+			_, err := d.ExecUnprepared(ctx, "INSERT INTO ids (id) VALUES (1);", nil)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("transaction %v: %v", i, err)
+		}
+	}
+	if err := db.CreateTempTable(context.Background(), "ids"); err == nil {
+		t.Fatal("expected error for ddl without columns")
+	}
+}
+
+func TestBulkJoinSelect(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, "CREATE TABLE bulk_users (id INTEGER PRIMARY KEY, name TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+	var rows [][]interface{}
+	for i := 0; i < 100; i++ {
+		rows = append(rows, []interface{}{i, "user"})
+	}
+	if _, err := db.BulkInsert(ctx, "bulk_users", []string{"id", "name"}, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []interface{}
+	for i := 0; i < 2000; i += 10 {
+		ids = append(ids, i)
+	}
+	var names []int
+	err := db.BulkJoinSelect(ctx, BulkJoin{Type: "INTEGER", Values: ids},
+		"SELECT u.id FROM bulk_users u JOIN sqln_bulk_join j ON j.v = u.id WHERE u.name = :name ORDER BY u.id;",
+		&names, map[string]interface{}{"name": "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 10 || names[9] != 90 {
+		t.Fatalf("unexpected ids %v", names)
+	}
+
+	if err := db.BulkJoinSelect(ctx, BulkJoin{Values: ids}, "SELECT 1;", &names, nil); err == nil {
+		t.Fatal("expected error without a column type")
+	}
+
+	// The transaction's DB is decorated by tx middleware.
+	mw := WithTxMiddleware(ctx, Guard(GuardOptions{}))
+	if err := db.BulkJoinSelect(mw, BulkJoin{Type: "INTEGER", Values: ids[:2]}, "SELECT u.id FROM bulk_users u JOIN sqln_bulk_join j ON j.v = u.id ORDER BY u.id;", &names, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[1] != 10 {
+		t.Fatalf("unexpected ids %v", names)
+	}
+}
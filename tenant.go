@@ -0,0 +1,336 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// WithTenant returns a context scoped to the given tenant.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenantID)
+}
+
+// TenantFromContext returns the tenant set by WithTenant.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey).(string)
+	return id, ok && id != ""
+}
+
+// WithTenantStmtCache partitions the statement cache by the key partition
+// returns for the context of each operation, so every tenant prepares its own
+// statements and a statement prepared for one tenant never runs for another.
+// Partition defaults to the tenant set by WithTenant, which Tenant and
+// TenantMiddleware pass to every operation of their transactions. Operations
+// with an empty key share the unpartitioned statements. The statements of
+// every partition count against WithCacheSize. Transactions whose TenantScope
+// sets a search_path prepare statements on their own connection instead (see
+// Tenant), since cached statements are first prepared on the pool.
+func WithTenantStmtCache(partition func(ctx context.Context) string) Option {
+	if partition == nil {
+		partition = func(ctx context.Context) string {
+			id, _ := TenantFromContext(ctx)
+			return id
+		}
+	}
+	return func(d *Database) {
+		d.cache.partition = partition
+	}
+}
+
+// PoolLimits bounds a single tenant's connection pool. Zero values leave the
+// database/sql defaults in place.
+type PoolLimits struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func (l PoolLimits) apply(dbx *sqlx.DB) {
+	if l.MaxOpenConns > 0 {
+		dbx.SetMaxOpenConns(l.MaxOpenConns)
+	}
+	if l.MaxIdleConns > 0 {
+		dbx.SetMaxIdleConns(l.MaxIdleConns)
+	}
+	if l.ConnMaxLifetime > 0 {
+		dbx.SetConnMaxLifetime(l.ConnMaxLifetime)
+	}
+	if l.ConnMaxIdleTime > 0 {
+		dbx.SetConnMaxIdleTime(l.ConnMaxIdleTime)
+	}
+}
+
+// TenantPools maintains a separate connection pool (and statement cache) per
+// tenant so one tenant cannot exhaust connections used by the others.
+type TenantPools struct {
+	open     func(tenantID string) (*sqlx.DB, error)
+	defaults PoolLimits
+	limits   map[string]PoolLimits
+
+	mtx    sync.Mutex
+	pools  map[string]*Database
+	closed bool
+}
+
+// NewTenantPools lazily opens pools using open. Tenants listed in limits use
+// those limits instead of defaults.
+func NewTenantPools(open func(tenantID string) (*sqlx.DB, error), defaults PoolLimits, limits map[string]PoolLimits) *TenantPools {
+	return &TenantPools{
+		open:     open,
+		defaults: defaults,
+		limits:   limits,
+		pools:    make(map[string]*Database),
+	}
+}
+
+// For returns the Database for tenantID, opening its pool on first use.
+func (p *TenantPools) For(tenantID string) (*Database, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.closed {
+		return nil, errors.New("tenant pools closed")
+	}
+	if d, ok := p.pools[tenantID]; ok {
+		return d, nil
+	}
+
+	dbx, err := p.open(tenantID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "tenant %q: open", tenantID)
+	}
+	limits, ok := p.limits[tenantID]
+	if !ok {
+		limits = p.defaults
+	}
+	limits.apply(dbx)
+
+	d := New(dbx)
+	p.pools[tenantID] = d
+	return d, nil
+}
+
+// FromContext returns the Database for the tenant set by WithTenant.
+func (p *TenantPools) FromContext(ctx context.Context) (*Database, error) {
+	id, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil, errors.New("no tenant in context")
+	}
+	return p.For(id)
+}
+
+// TenantStats reports pool usage for a single tenant.
+type TenantStats struct {
+	sql.DBStats
+	Limits     PoolLimits
+	Statements int
+}
+
+// Stats returns pool statistics keyed by tenant.
+func (p *TenantPools) Stats() map[string]TenantStats {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	stats := make(map[string]TenantStats, len(p.pools))
+	for id, d := range p.pools {
+		limits, ok := p.limits[id]
+		if !ok {
+			limits = p.defaults
+		}
+
+		stats[id] = TenantStats{
+			DBStats:    d.drv.Stats(),
+			Limits:     limits,
+			Statements: d.cache.len(),
+		}
+	}
+	return stats
+}
+
+// Close closes every tenant's statements and pool, returning the first error.
+func (p *TenantPools) Close() error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.closed = true
+	var first error
+	for id, d := range p.pools {
+		if err := d.Close(); err != nil && first == nil {
+			first = errors.Wrapf(err, "tenant %q", id)
+		}
+		if err := d.drv.Close(); err != nil && first == nil {
+			first = errors.Wrapf(err, "tenant %q", id)
+		}
+		delete(p.pools, id)
+	}
+	return first
+}
+
+// TenantScope describes how a tenant's operations are isolated within a
+// Postgres transaction.
+type TenantScope struct {
+	// SearchPath returns the search_path for a tenant, ie. its schema
+	// (quoted with pq.QuoteIdentifier). Optional.
+	SearchPath func(tenantID string) string
+	// Setting is a custom parameter (ie. "app.tenant_id") set to the
+	// tenant ID for row-level security policies. Optional.
+	Setting string
+}
+
+// apply sets the scope's parameters for the current transaction.
+func (s TenantScope) apply(ctx context.Context, tx DB, tenantID string) error {
+	if s.SearchPath != nil {
+		if err := setLocal(ctx, tx, "search_path", s.SearchPath(tenantID)); err != nil {
+			return err
+		}
+		if d, ok := databaseOf(tx); ok && d.txState != nil {
+			d.txState.mtx.Lock()
+			d.txState.scoped = true
+			d.txState.mtx.Unlock()
+		}
+	}
+	if s.Setting != "" {
+		if err := setLocal(ctx, tx, s.Setting, tenantID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tenant runs f in a transaction scoped to tenantID: the scope's settings are
+// applied with SET LOCAL semantics so they end with the transaction. The DB
+// passed to f runs every operation with tenantID in its context (see
+// WithTenant). Once the scope sets a search_path, statements are prepared on
+// the transaction's connection, for the transaction, rather than taken from
+// the statement cache, since those resolve names against the search_path of
+// the connection they were first prepared on.
+func Tenant(ctx context.Context, db DB, tenantID string, scope TenantScope, f func(DB) error) error {
+	ctx = WithTenant(ctx, tenantID)
+	return db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		if err := scope.apply(ctx, tx, tenantID); err != nil {
+			return err
+		}
+		return f(&tenantDB{DB: tx, scope: scope, inTx: true, tenantID: tenantID})
+	})
+}
+
+// scoped reports whether d runs in a transaction that changed its
+// search_path (see TenantScope).
+func (d *Database) scoped() bool {
+	t := d.txState
+	if t == nil {
+		return false
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.scoped
+}
+
+// scopedStmt returns the statement for query prepared on the connection of
+// d's scoped transaction, preparing it on first use. The statement is closed
+// with the transaction.
+func (d *Database) scopedStmt(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	t := d.txState
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if s, ok := t.scopedStmts[query]; ok {
+		return s, nil
+	}
+	s, err := d.tx.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if t.scopedStmts == nil {
+		t.scopedStmts = make(map[string]*sqlx.NamedStmt)
+	}
+	t.scopedStmts[query] = s
+	// The statement already belongs to the transaction (see txStmt).
+	if t.stmts == nil {
+		t.stmts = make(map[*sqlx.NamedStmt]*sqlx.NamedStmt)
+	}
+	t.stmts[s] = s
+	return s, nil
+}
+
+// TenantMiddleware scopes every operation to the tenant in its context (see
+// WithTenant). Operations outside a transaction are run in their own
+// transaction; Transact applies the scope when the transaction begins.
+// Operations without a tenant in their context are passed through.
+func TenantMiddleware(scope TenantScope) Middleware {
+	return func(db DB) DB {
+		return &tenantDB{DB: db, scope: scope}
+	}
+}
+
+type tenantDB struct {
+	DB
+	scope TenantScope
+	inTx  bool
+	// tenantID, if set, is added to the context of every operation.
+	tenantID string
+}
+
+func (d *tenantDB) Unwrap() DB { return d.DB }
+
+func (d *tenantDB) scoped(ctx context.Context, f func(context.Context, DB) error) error {
+	if d.tenantID != "" {
+		ctx = WithTenant(ctx, d.tenantID)
+	}
+	id, ok := TenantFromContext(ctx)
+	if !ok || d.inTx {
+		return f(ctx, d.DB)
+	}
+	return Tenant(ctx, d.DB, id, d.scope, func(tx DB) error {
+		return f(ctx, tx)
+	})
+}
+
+func (d *tenantDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	var res sql.Result
+	err := d.scoped(ctx, func(ctx context.Context, db DB) error {
+		var err error
+		res, err = db.Exec(ctx, query, params)
+		return err
+	})
+	return res, err
+}
+
+func (d *tenantDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	return d.scoped(ctx, func(ctx context.Context, db DB) error {
+		return db.ExecReturning(ctx, query, dest, params)
+	})
+}
+
+func (d *tenantDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	return d.scoped(ctx, func(ctx context.Context, db DB) error {
+		return db.Get(ctx, query, dest, params)
+	})
+}
+
+func (d *tenantDB) Select(ctx context.Context, query string, dest, params interface{}) error {
+	return d.scoped(ctx, func(ctx context.Context, db DB) error {
+		return db.Select(ctx, query, dest, params)
+	})
+}
+
+func (d *tenantDB) Transact(ctx context.Context, opts sql.TxOptions, f func(DB) error) error {
+	if d.tenantID != "" {
+		ctx = WithTenant(ctx, d.tenantID)
+	}
+	return d.DB.Transact(ctx, opts, func(tx DB) error {
+		id, ok := TenantFromContext(ctx)
+		if !ok {
+			return f(&tenantDB{DB: tx, scope: d.scope, inTx: true})
+		}
+		if err := d.scope.apply(ctx, tx, id); err != nil {
+			return err
+		}
+		return f(&tenantDB{DB: tx, scope: d.scope, inTx: true, tenantID: id})
+	})
+}
@@ -0,0 +1,233 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nstogner/psqlxtest"
+)
+
+func TestTenantPools(t *testing.T) {
+	p := NewTenantPools(func(string) (*sqlx.DB, error) {
+		// Open does not connect, so no database is needed.
+		return sqlx.Open("postgres", testDSN())
+	}, PoolLimits{MaxOpenConns: 2}, map[string]PoolLimits{
+		"noisy": {MaxOpenConns: 1},
+	})
+	defer p.Close()
+
+	ctx := context.Background()
+	if _, err := p.FromContext(ctx); err == nil {
+		t.Fatal("expected error without tenant in context")
+	}
+
+	a, err := p.FromContext(WithTenant(ctx, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, err := p.For("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != a2 {
+		t.Fatal("expected the same pool for the same tenant")
+	}
+	if _, err := p.For("noisy"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := p.Stats()
+	if n := stats["a"].MaxOpenConnections; n != 2 {
+		t.Fatalf("expected default limit of 2, got %v", n)
+	}
+	if n := stats["noisy"].MaxOpenConnections; n != 1 {
+		t.Fatalf("expected override limit of 1, got %v", n)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.For("a"); err == nil {
+		t.Fatal("expected error after close")
+	}
+}
+
+func TestTenantScope(t *testing.T) {
+	dbx, dropx := psqlxtest.TmpDB(t)
+	defer dropx()
+	d := New(dbx)
+	defer d.Close()
+
+	for _, q := range []string{
+		"DROP SCHEMA IF EXISTS tenant_a CASCADE; DROP SCHEMA IF EXISTS tenant_b CASCADE;",
+		"CREATE SCHEMA tenant_a; CREATE TABLE tenant_a.notes (body TEXT);",
+		"CREATE SCHEMA tenant_b; CREATE TABLE tenant_b.notes (body TEXT);",
+	} {
+		if _, err := d.X.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scope := TenantScope{
+		SearchPath: func(id string) string { return pq.QuoteIdentifier("tenant_" + id) },
+		Setting:    "app.tenant_id",
+	}
+	db := Wrap(d, TenantMiddleware(scope))
+
+	ctx := context.Background()
+	if _, err := db.Exec(WithTenant(ctx, "a"), "INSERT INTO notes (body) VALUES ('hello');", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	count := func(id string) int {
+		var n int
+		if err := Tenant(ctx, d, id, scope, func(tx DB) error {
+			var setting string
+			if err := tx.Get(ctx, "SELECT current_setting('app.tenant_id');", &setting, nil); err != nil {
+				return err
+			}
+			if setting != id {
+				t.Errorf("expected app.tenant_id %q, got %q", id, setting)
+			}
+			return tx.Get(ctx, "SELECT COUNT(*) FROM notes;", &n, nil)
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := count("a"); n != 1 {
+		t.Fatalf("expected 1 note for tenant a, got %v", n)
+	}
+	if n := count("b"); n != 0 {
+		t.Fatalf("expected no notes for tenant b, got %v", n)
+	}
+}
+
+type tenantSpyDB struct {
+	BaseDB
+	seen *[]string
+}
+
+func (d *tenantSpyDB) Get(ctx context.Context, query string, dest, params interface{}) error {
+	id, _ := TenantFromContext(ctx)
+	*d.seen = append(*d.seen, id)
+	return d.DB.Get(ctx, query, dest, params)
+}
+
+func TestTenantContext(t *testing.T) {
+	var seen []string
+	var spy func(DB) DB
+	spy = func(db DB) DB { return &tenantSpyDB{BaseDB: BaseDB{DB: db, Wrap: spy}, seen: &seen} }
+	db := spy(sqliteDB(t))
+	ctx := context.Background()
+
+	// The callback's own context has no tenant.
+	var n int
+	if err := Tenant(ctx, db, "a", TenantScope{}, func(tx DB) error {
+		return tx.Get(ctx, "SELECT 1;", &n, nil)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 1 || seen[0] != "a" {
+		t.Fatalf("expected the operation to see tenant a, got %q", seen)
+	}
+}
+
+func TestScopedStmts(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	if err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		d, _ := databaseOf(tx)
+		d.txState.scoped = true
+		var n int
+		for i := 0; i < 2; i++ {
+			if err := tx.Get(ctx, "SELECT :n;", &n, map[string]interface{}{"n": i}); err != nil {
+				return err
+			}
+			if n != i {
+				t.Errorf("expected %v, got %v", i, n)
+			}
+		}
+		if len(d.txState.scopedStmts) != 1 {
+			t.Errorf("expected the statement to be prepared once, got %v", d.txState.scopedStmts)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n := db.cache.len(); n != 0 {
+		t.Fatalf("expected scoped statements not to be cached, got %v", n)
+	}
+}
+
+func TestTenantStmtCache(t *testing.T) {
+	base := sqliteDB(t)
+	db := New(base.X, WithTenantStmtCache(nil))
+	defer db.Close()
+	ctx := context.Background()
+
+	var n int
+	for _, ctx := range []context.Context{WithTenant(ctx, "a"), WithTenant(ctx, "b"), WithTenant(ctx, "a"), ctx} {
+		if err := db.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := db.cache.len(); got != 3 {
+		t.Fatalf("expected a statement per tenant and one unpartitioned, got %v", got)
+	}
+	db.InvalidateStmt("SELECT 1;")
+	if got := db.cache.len(); got != 0 {
+		t.Fatalf("expected every partition to be invalidated, got %v", got)
+	}
+
+	// Operations in Tenant use the tenant's partition, whatever the context
+	// of the callback.
+	for _, id := range []string{"a", "b"} {
+		if err := Tenant(ctx, db, id, TenantScope{}, func(tx DB) error {
+			return tx.Get(ctx, "SELECT 1;", &n, nil)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := db.cache.len(); got != 2 {
+		t.Fatalf("expected a statement per tenant, got %v", got)
+	}
+}
+
+func TestTenantStmtCachePostgres(t *testing.T) {
+	dbx, dropx := psqlxtest.TmpDB(t)
+	defer dropx()
+	d := New(dbx, WithTenantStmtCache(nil))
+	defer d.Close()
+
+	for _, q := range []string{
+		"DROP SCHEMA IF EXISTS tenant_a CASCADE; DROP SCHEMA IF EXISTS tenant_b CASCADE;",
+		"CREATE SCHEMA tenant_a; CREATE TABLE tenant_a.notes (body TEXT); INSERT INTO tenant_a.notes VALUES ('a');",
+		"CREATE SCHEMA tenant_b; CREATE TABLE tenant_b.notes (body TEXT); INSERT INTO tenant_b.notes VALUES ('b'), ('b');",
+	} {
+		if _, err := d.X.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scope := TenantScope{SearchPath: func(id string) string { return pq.QuoteIdentifier("tenant_" + id) }}
+	ctx := context.Background()
+
+	// The same query resolves against each tenant's schema, in turn and
+	// again.
+	for _, id := range []string{"a", "b", "a", "b"} {
+		var bodies []string
+		if err := Tenant(ctx, d, id, scope, func(tx DB) error {
+			return tx.Select(ctx, "SELECT body FROM notes;", &bodies, nil)
+		}); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]int{"a": 1, "b": 2}[id]
+		if len(bodies) != want || bodies[0] != id {
+			t.Fatalf("tenant %v: expected %v of its own notes, got %q", id, want, bodies)
+		}
+	}
+}
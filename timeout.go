@@ -0,0 +1,66 @@
+package sqln
+
+import (
+	"context"
+	"time"
+)
+
+// WithQueryTimeout bounds every Exec, Get and Select by t, so a call site
+// that passes a context without a deadline cannot hang forever. Earlier
+// deadlines in the context still apply.
+func WithQueryTimeout(t time.Duration) Option {
+	return func(d *Database) {
+		d.queryTimeout = t
+	}
+}
+
+// WithStatementTimeout sets statement_timeout for every transaction that
+// does not set one with WithTxSettings, so the server also stops queries
+// whose client went away. Postgres only; ignored for other dialects.
+func WithStatementTimeout(t time.Duration) Option {
+	return func(d *Database) {
+		d.statementTimeout = t
+	}
+}
+
+// WithDeadlineTimeouts sets statement_timeout and lock_timeout for every
+// transaction to the time left until its context's deadline, unless
+// WithTxSettings sets a shorter one, so the server stops work the client has
+// given up on. Postgres only; ignored for other dialects.
+func WithDeadlineTimeouts() Option {
+	return func(d *Database) {
+		d.deadlineTimeouts = true
+	}
+}
+
+// opContext derives the context of an operation.
+func (d *Database) opContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.queryTimeout > 0 {
+		return context.WithTimeout(ctx, d.queryTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// txSettings returns the settings to apply when a transaction begins.
+func (d *Database) txSettings(ctx context.Context) (TxSettings, bool) {
+	s, ok := TxSettingsFromContext(ctx)
+	if d.statementTimeout > 0 && d.dialect == Postgres && s.StatementTimeout == 0 {
+		s.StatementTimeout = d.statementTimeout
+		ok = true
+	}
+	if deadline, has := ctx.Deadline(); has && d.deadlineTimeouts && d.dialect == Postgres {
+		// Timeouts are set in milliseconds, where zero disables them.
+		left := time.Until(deadline)
+		if left < time.Millisecond {
+			left = time.Millisecond
+		}
+		if s.StatementTimeout == 0 || left < s.StatementTimeout {
+			s.StatementTimeout = left
+		}
+		if s.LockTimeout == 0 || left < s.LockTimeout {
+			s.LockTimeout = left
+		}
+		ok = true
+	}
+	return s, ok
+}
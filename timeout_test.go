@@ -0,0 +1,59 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	db := sqliteDB(t)
+	d := New(db.X, WithQueryTimeout(20*time.Millisecond))
+	defer d.Close()
+
+	const forever = "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT COUNT(*) FROM c;"
+	start := time.Now()
+	var n int
+	if err := d.Get(context.Background(), forever, &n, nil); err == nil {
+		t.Fatal("expected the query to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected the query to be cancelled promptly, took %v", elapsed)
+	}
+}
+
+func TestStatementTimeout(t *testing.T) {
+	d := &Database{dialect: Postgres, statementTimeout: time.Second}
+	ctx := context.Background()
+	if s, ok := d.txSettings(ctx); !ok || s.StatementTimeout != time.Second {
+		t.Fatalf("expected the default statement timeout, got %+v", s)
+	}
+	ctx = WithTxSettings(ctx, TxSettings{StatementTimeout: time.Minute, Role: "reader"})
+	if s, _ := d.txSettings(ctx); s.StatementTimeout != time.Minute || s.Role != "reader" {
+		t.Fatalf("expected the context statement timeout, got %+v", s)
+	}
+
+	d.dialect = SQLite
+	if _, ok := d.txSettings(context.Background()); ok {
+		t.Fatal("expected no settings for sqlite")
+	}
+}
+
+func TestDeadlineTimeouts(t *testing.T) {
+	d := &Database{dialect: Postgres, deadlineTimeouts: true}
+	if _, ok := d.txSettings(context.Background()); ok {
+		t.Fatal("expected no settings without a deadline")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	s, ok := d.txSettings(ctx)
+	if !ok || s.StatementTimeout <= 50*time.Second || s.StatementTimeout > time.Minute || s.LockTimeout != s.StatementTimeout {
+		t.Fatalf("expected timeouts from the deadline, got %+v", s)
+	}
+
+	ctx = WithTxSettings(ctx, TxSettings{LockTimeout: time.Second})
+	if s, _ := d.txSettings(ctx); s.LockTimeout != time.Second || s.StatementTimeout <= time.Second {
+		t.Fatalf("expected the shorter lock timeout to be kept, got %+v", s)
+	}
+}
@@ -0,0 +1,168 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"regexp"
+	"time"
+
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/pkg/errors"
+)
+
+// NOTE: Fields tagged with the created option (ie. `db:"created_at,created"`)
+// are set to the current time by the struct helpers when inserting a struct
+// in which they are zero, and never updated. Fields tagged with the updated
+// option (ie. `db:"updated_at,updated"`) are set whenever a struct is
+// inserted or updated. The fields may be a time.Time, *time.Time or a
+// sql.Scanner such as sql.NullTime or Null[time.Time]. Times come from the
+// clock of the Timestamps middleware, if any, or SystemClock, in UTC.
+
+// Timestamps returns a middleware that sets the created and updated fields
+// of struct params to clock's time for any INSERT or UPDATE run with Exec or
+// ExecReturning, not only those of the struct helpers. The struct helpers
+// use clock when run on a DB wrapped by it.
+func Timestamps(clock Clock) Middleware {
+	var wrap func(DB) DB
+	wrap = func(db DB) DB {
+		return &timestampDB{BaseDB: BaseDB{DB: db, Wrap: wrap}, clock: clock}
+	}
+	return wrap
+}
+
+type timestampDB struct {
+	BaseDB
+	clock Clock
+}
+
+var insertRe = regexp.MustCompile(`(?i)^\s*(?:WITH\b.*?\)\s*)?INSERT\b`)
+
+func (d *timestampDB) stamp(query string, params interface{}) (interface{}, error) {
+	switch {
+	case insertRe.MatchString(query):
+		m, _ := mapperOf(d.DB)
+		return stampStruct(m, params, d.clock, true)
+	case len(MutatedTables(query)) > 0:
+		m, _ := mapperOf(d.DB)
+		return stampStruct(m, params, d.clock, false)
+	}
+	return params, nil
+}
+
+func (d *timestampDB) Exec(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	params, err := d.stamp(query, params)
+	if err != nil {
+		return nil, err
+	}
+	return d.DB.Exec(ctx, query, params)
+}
+
+func (d *timestampDB) ExecReturning(ctx context.Context, query string, dest, params interface{}) error {
+	params, err := d.stamp(query, params)
+	if err != nil {
+		return err
+	}
+	return d.DB.ExecReturning(ctx, query, dest, params)
+}
+
+// clockOf returns the clock of the Timestamps middleware wrapping db, or
+// SystemClock.
+func clockOf(db DB) Clock {
+	for db != nil {
+		if t, ok := db.(*timestampDB); ok {
+			return t.clock
+		}
+		u, ok := db.(Unwrapper)
+		if !ok {
+			break
+		}
+		db = u.Unwrap()
+	}
+	return SystemClock
+}
+
+// stampStruct sets the updated fields of v, and its zero created fields if
+// insert is set. A struct that is not a pointer is copied, so the returned
+// params should be used in its place. Other params are returned as is.
+func stampStruct(m *reflectx.Mapper, v interface{}, clock Clock, insert bool) (interface{}, error) {
+	if v == nil {
+		return v, nil
+	}
+	rv := reflect.ValueOf(v)
+	t := rv.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || reflect.PtrTo(t).Implements(scannerType) {
+		return v, nil
+	}
+	var stamped []reflectField
+	for _, f := range columnFields(m, t) {
+		_, created := f.Options["created"]
+		_, updated := f.Options["updated"]
+		if updated || created && insert {
+			stamped = append(stamped, reflectField{index: f.Index, path: f.Path, always: updated})
+		}
+	}
+	if len(stamped) == 0 {
+		return v, nil
+	}
+
+	if rv.Kind() != reflect.Ptr {
+		cp := reflect.New(t)
+		cp.Elem().Set(rv)
+		rv, v = cp, cp.Interface()
+	} else if rv.IsNil() {
+		return v, nil
+	}
+	now := clock.Now().UTC()
+	sv := reflect.Indirect(rv)
+	for _, f := range stamped {
+		if err := setTime(sv.FieldByIndex(f.index), now, f.always); err != nil {
+			return nil, errors.Wrapf(err, "timestamp %v", f.path)
+		}
+	}
+	return v, nil
+}
+
+type reflectField struct {
+	index  []int
+	path   string
+	always bool
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// setTime sets f to now, unless it is already set and always is not.
+func setTime(f reflect.Value, now time.Time, always bool) error {
+	if !always && !f.IsZero() {
+		return nil
+	}
+	switch {
+	case f.Type() == timeType:
+		f.Set(reflect.ValueOf(now))
+	case f.Type() == reflect.PtrTo(timeType):
+		f.Set(reflect.ValueOf(&now))
+	case f.Addr().Type().Implements(scannerType):
+		return f.Addr().Interface().(sql.Scanner).Scan(now)
+	default:
+		return errors.Errorf("unsupported type %v", f.Type())
+	}
+	return nil
+}
+
+// createdColumns returns the created columns of v, which are not updated.
+func createdColumns(m *reflectx.Mapper, v interface{}) map[string]bool {
+	cols := make(map[string]bool)
+	t, err := structType(v)
+	if err != nil {
+		return cols
+	}
+	for _, f := range columnFields(m, t) {
+		if _, ok := f.Options["created"]; ok {
+			cols[f.Path] = true
+		}
+	}
+	return cols
+}
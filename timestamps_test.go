@@ -0,0 +1,58 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type stampedRow struct {
+	ID        int64           `db:"id"`
+	Name      string          `db:"name"`
+	CreatedAt time.Time       `db:"created_at,created"`
+	UpdatedAt Null[time.Time] `db:"updated_at,updated"`
+}
+
+func TestTimestamps(t *testing.T) {
+	base := sqliteDB(t)
+	ctx := context.Background()
+	if _, err := base.Exec(ctx, "CREATE TABLE stamped (id INTEGER PRIMARY KEY, name TEXT, created_at TIMESTAMP, updated_at TIMESTAMP);", nil); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewTestClock(start)
+	db := Timestamps(clock)(base)
+
+	row := &stampedRow{ID: 1, Name: "a"}
+	if _, err := InsertStruct(ctx, db, "stamped", row); err != nil {
+		t.Fatal(err)
+	}
+	if !row.CreatedAt.Equal(start) || !row.UpdatedAt.V.Equal(start) {
+		t.Fatalf("unexpected timestamps after insert: %+v", row)
+	}
+
+	clock.Advance(time.Hour)
+	row.Name = "b"
+	if _, err := UpdateStruct(ctx, db, "stamped", row, "id"); err != nil {
+		t.Fatal(err)
+	}
+	var got stampedRow
+	if err := base.Get(ctx, "SELECT * FROM stamped WHERE id = 1;", &got, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(start) || !got.UpdatedAt.V.Equal(start.Add(time.Hour)) {
+		t.Fatalf("unexpected timestamps after update: %+v", got)
+	}
+
+	// A struct passed by value is stamped by the middleware on a copy.
+	clock.Advance(time.Hour)
+	if _, err := db.Exec(ctx, "INSERT INTO stamped (id, name, created_at, updated_at) VALUES (:id, :name, :created_at, :updated_at);", stampedRow{ID: 2, Name: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := base.Get(ctx, "SELECT * FROM stamped WHERE id = 2;", &got, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(start.Add(2 * time.Hour)) {
+		t.Fatalf("unexpected created_at: %v", got.CreatedAt)
+	}
+}
@@ -0,0 +1,62 @@
+package sqln
+
+import (
+	"context"
+	"math"
+)
+
+// ConsistencyToken identifies a point in the primary's history, the WAL
+// position of a Postgres primary (ie. "16/B374D848"). It is a string so it can
+// be passed between services, ie. in a cookie or header, to read a write made
+// elsewhere.
+type ConsistencyToken string
+
+// Token returns the consistency token of the primary's current position,
+// which covers every write committed so far.
+func (r *Replicated) Token(ctx context.Context) (ConsistencyToken, error) {
+	var lsn string
+	if err := r.Primary.Get(withInternal(ctx), primaryLSNQuery, &lsn, nil); err != nil {
+		return "", err
+	}
+	return ConsistencyToken(lsn), nil
+}
+
+// WaitForToken returns a context in which reads through a Replicated DB go to
+// a replica that has replayed token, or to the primary if the replica picked
+// has not, so a write (see ExecX) can be read without pinning every later
+// read to the primary as WithReadYourWrites does. An empty token has no
+// effect, and an invalid one routes reads to the primary.
+func WaitForToken(ctx context.Context, token ConsistencyToken) context.Context {
+	if token == "" {
+		return ctx
+	}
+	lsn, err := parseLSN(string(token))
+	if err != nil {
+		lsn = math.MaxUint64
+	}
+	if cur := tokenLSN(ctx); cur > lsn {
+		lsn = cur
+	}
+	return context.WithValue(ctx, tokenKey, lsn)
+}
+
+// tokenLSN returns the WAL position to wait for in ctx, if any.
+func tokenLSN(ctx context.Context) uint64 {
+	lsn, _ := ctx.Value(tokenKey).(uint64)
+	return lsn
+}
+
+// replicatedOf returns the Replicated DB underlying db, if any.
+func replicatedOf(db DB) *Replicated {
+	for db != nil {
+		if r, ok := db.(*Replicated); ok {
+			return r
+		}
+		u, ok := db.(Unwrapper)
+		if !ok {
+			break
+		}
+		db = u.Unwrap()
+	}
+	return nil
+}
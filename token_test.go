@@ -0,0 +1,39 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitForToken(t *testing.T) {
+	primary, replica := sqliteDB(t), sqliteDB(t)
+	r := &Replicated{Primary: primary, Replicas: []*Database{replica}}
+	r.lags = map[*Database]replicaLag{replica: {at: time.Now(), lsn: 100}}
+	ctx := context.Background()
+
+	if d := r.reader(ctx, "SELECT 1;"); d != replica {
+		t.Fatal("expected a read from the replica without a token")
+	}
+	if d := r.reader(WaitForToken(ctx, "0/32"), "SELECT 1;"); d != replica {
+		t.Fatal("expected a read from the replica which replayed the token")
+	}
+	if d := r.reader(WaitForToken(ctx, "0/C8"), "SELECT 1;"); d != primary {
+		t.Fatal("expected a read from the primary until the token is replayed")
+	}
+	if d := r.reader(WaitForToken(WaitForToken(ctx, "0/C8"), "0/32"), "SELECT 1;"); d != primary {
+		t.Fatal("expected the latest token to be waited for")
+	}
+	if d := r.reader(WaitForToken(ctx, "invalid"), "SELECT 1;"); d != primary {
+		t.Fatal("expected an invalid token to read from the primary")
+	}
+
+	// The WAL position of SQLite cannot be read, so no token is returned.
+	if _, err := primary.Exec(ctx, "CREATE TABLE token_abc (id INTEGER);", nil); err != nil {
+		t.Fatal(err)
+	}
+	res, err := ExecX(ctx, r, "INSERT INTO token_abc (id) VALUES (1);", nil)
+	if err != nil || res.RowsAffected != 1 || res.Token != "" {
+		t.Fatalf("unexpected result: %+v %v", res, err)
+	}
+}
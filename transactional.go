@@ -0,0 +1,55 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// TransactionalOptions configures Transactional.
+type TransactionalOptions struct {
+	Tx sql.TxOptions
+	// Retry configures retries of the whole transaction. Defaults to three
+	// attempts on Transient errors; set Attempts to 1 to disable.
+	Retry RetryOptions
+	// OnDone is called after each call of a wrapped function, ie. to record
+	// metrics.
+	OnDone func(ctx context.Context, r TransactionalResult)
+}
+
+// TransactionalResult describes a call of a function wrapped by
+// Transactional.
+type TransactionalResult struct {
+	Attempts int
+	Duration time.Duration
+	Err      error
+}
+
+// Transactional returns a decorator that runs service functions in a
+// transaction on db, retried as a whole on transient errors, ie.
+//
+//	tx := sqln.Transactional(db, sqln.TransactionalOptions{})
+//	createOrder := tx(func(ctx context.Context, db sqln.DB) error { ... })
+//
+// A wrapped function called within a transaction joins it (see Transact)
+// and is not retried. Since it may run more than once, f should not have
+// side effects outside of the database.
+func Transactional(db DB, opts TransactionalOptions) func(f func(ctx context.Context, tx DB) error) func(ctx context.Context) error {
+	return func(f func(ctx context.Context, tx DB) error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			start := time.Now()
+			var attempts int
+			err := retry(ctx, db, opts.Retry, func() error {
+				attempts++
+				ctx := context.WithValue(ctx, attemptKey, attempts)
+				return db.Transact(ctx, opts.Tx, func(tx DB) error {
+					return f(ctx, tx)
+				})
+			})
+			if opts.OnDone != nil {
+				opts.OnDone(ctx, TransactionalResult{Attempts: attempts, Duration: time.Since(start), Err: err})
+			}
+			return err
+		}
+	}
+}
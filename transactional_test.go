@@ -0,0 +1,49 @@
+package sqln
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestTransactional(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE transactional (id INTEGER);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var results []TransactionalResult
+	tx := Transactional(db, TransactionalOptions{
+		Retry:  RetryOptions{Backoff: time.Millisecond},
+		OnDone: func(ctx context.Context, r TransactionalResult) { results = append(results, r) },
+	})
+	var calls int
+	insert := tx(func(ctx context.Context, tx DB) error {
+		calls++
+		if !InTx(tx) {
+			t.Error("expected a transaction")
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO transactional VALUES (1);", nil); err != nil {
+			return err
+		}
+		if calls == 1 {
+			return errors.Wrap(driver.ErrBadConn, "insert")
+		}
+		return nil
+	})
+	if err := insert(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	if err := db.Get(ctx, "SELECT count(*) FROM transactional;", &n, nil); err != nil || n != 1 {
+		t.Fatalf("expected the failed attempt to be rolled back, got %v rows, %v", n, err)
+	}
+	if len(results) != 1 || results[0].Attempts != 2 || results[0].Err != nil {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
@@ -0,0 +1,90 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// TransactPrepared runs f in a transaction that ends with PREPARE TRANSACTION
+// instead of a commit, the first phase of a two-phase commit. The transaction
+// survives disconnects and restarts until it is finished with CommitPrepared
+// or RollbackPrepared using the same gid. Postgres only, and requires
+// max_prepared_transactions to be set.
+func (d *Database) TransactPrepared(ctx context.Context, gid string, opts sql.TxOptions, f func(DB) error) error {
+	return d.transact(ctx, opts, f, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, "PREPARE TRANSACTION "+pq.QuoteLiteral(gid))
+		return errors.Wrapf(err, "prepare transaction %q", gid)
+	})
+}
+
+// CommitPrepared commits a transaction prepared by TransactPrepared.
+func (d *Database) CommitPrepared(ctx context.Context, gid string) error {
+	// NOTE: Not run as a named statement since the gid cannot be a bound
+	// param and each one would otherwise be cached.
+	_, err := d.drv.ExecContext(ctx, "COMMIT PREPARED "+pq.QuoteLiteral(gid))
+	return errors.Wrapf(err, "commit prepared %q", gid)
+}
+
+// RollbackPrepared rolls back a transaction prepared by TransactPrepared.
+func (d *Database) RollbackPrepared(ctx context.Context, gid string) error {
+	_, err := d.drv.ExecContext(ctx, "ROLLBACK PREPARED "+pq.QuoteLiteral(gid))
+	return errors.Wrapf(err, "rollback prepared %q", gid)
+}
+
+// PreparedTx is a transaction awaiting its second phase.
+type PreparedTx struct {
+	GID      string    `db:"gid"`
+	Prepared time.Time `db:"prepared"`
+	Owner    string    `db:"owner"`
+}
+
+// PreparedTxs lists transactions in the current database that were prepared
+// more than olderThan ago. These are typically orphaned by a coordinator that
+// failed between the two phases.
+func (d *Database) PreparedTxs(ctx context.Context, olderThan time.Duration) ([]PreparedTx, error) {
+	var txs []PreparedTx
+	err := d.Select(ctx, `SELECT gid, prepared, owner FROM pg_prepared_xacts
+WHERE database = current_database() AND prepared < now() - :older * interval '1 millisecond'
+ORDER BY prepared;`, &txs, map[string]interface{}{"older": olderThan.Milliseconds()})
+	return txs, errors.Wrap(err, "listing prepared transactions")
+}
+
+// RecoverPrepared finishes prepared transactions older than olderThan. The
+// commit func decides the outcome of each one, ie. by checking whether the
+// other participants committed; a transaction is left alone if it returns an
+// error. The number of finished transactions is returned along with the first
+// error.
+func (d *Database) RecoverPrepared(ctx context.Context, olderThan time.Duration, commit func(PreparedTx) (bool, error)) (int, error) {
+	txs, err := d.PreparedTxs(ctx, olderThan)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		n     int
+		first error
+	)
+	for _, tx := range txs {
+		ok, err := commit(tx)
+		if err == nil {
+			if ok {
+				err = d.CommitPrepared(ctx, tx.GID)
+			} else {
+				err = d.RollbackPrepared(ctx, tx.GID)
+			}
+		}
+		if err != nil {
+			if first == nil {
+				first = errors.Wrapf(err, "recovering %q", tx.GID)
+			}
+			continue
+		}
+		n++
+	}
+	return n, first
+}
@@ -0,0 +1,62 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/nstogner/psqlxtest"
+)
+
+func TestTransactPrepared(t *testing.T) {
+	dbx, dropx := psqlxtest.TmpDB(t)
+	defer dropx()
+	db := New(dbx)
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE twophase_abc (id INTEGER PRIMARY KEY);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	insert := func(id int) func(DB) error {
+		return func(tx DB) error {
+			_, err := tx.Exec(ctx, "INSERT INTO twophase_abc (id) VALUES (:id);", map[string]interface{}{"id": id})
+			return err
+		}
+	}
+	if err := db.TransactPrepared(ctx, "test-1", sql.TxOptions{}, insert(1)); err != nil {
+		if strings.Contains(err.Error(), "max_prepared_transactions") {
+			t.Skip("prepared transactions are disabled")
+		}
+		t.Fatal(err)
+	}
+	if err := db.TransactPrepared(ctx, "test-2", sql.TxOptions{}, insert(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	count := func() int {
+		var n int
+		if err := db.Get(ctx, "SELECT COUNT(*) FROM twophase_abc;", &n, nil); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := count(); n != 0 {
+		t.Fatalf("expected prepared rows to be invisible, got %v", n)
+	}
+
+	if err := db.CommitPrepared(ctx, "test-1"); err != nil {
+		t.Fatal(err)
+	}
+	n, err := db.RecoverPrepared(ctx, 0, func(tx PreparedTx) (bool, error) {
+		return false, nil
+	})
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 recovered, got %v (%v)", n, err)
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("expected 1 committed row, got %v", n)
+	}
+}
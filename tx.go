@@ -0,0 +1,71 @@
+package sqln
+
+import (
+	"github.com/jmoiron/sqlx"
+)
+
+// TxInfo is implemented by DBs that can report whether they run in a
+// transaction.
+type TxInfo interface {
+	InTx() bool
+	// TxLevel is the transaction nesting level, zero outside of one.
+	TxLevel() int
+}
+
+// Unwrapper is implemented by middleware (see Middleware) to expose the DB
+// they decorate.
+type Unwrapper interface {
+	Unwrap() DB
+}
+
+// InTx reports whether db, or the DB it decorates, runs in a transaction.
+func InTx(db DB) bool {
+	return TxLevel(db) > 0
+}
+
+// TxLevel returns the transaction level of db, or the DB it decorates. DBs
+// that implement neither TxInfo nor Unwrapper report zero.
+func TxLevel(db DB) int {
+	for db != nil {
+		if ti, ok := db.(TxInfo); ok {
+			return ti.TxLevel()
+		}
+		u, ok := db.(Unwrapper)
+		if !ok {
+			break
+		}
+		db = u.Unwrap()
+	}
+	return 0
+}
+
+// databaseOf returns db, or the DB it decorates, as a *Database.
+func databaseOf(db DB) (*Database, bool) {
+	for db != nil {
+		if d, ok := db.(*Database); ok {
+			return d, true
+		}
+		u, ok := db.(Unwrapper)
+		if !ok {
+			break
+		}
+		db = u.Unwrap()
+	}
+	return nil, false
+}
+
+// InTx reports whether d runs in a transaction.
+func (d *Database) InTx() bool {
+	return d.tx != nil
+}
+
+// TxLevel returns the transaction level of d.
+func (d *Database) TxLevel() int {
+	return d.txLevel
+}
+
+// UnsafeTx returns the underlying transaction, or nil outside of one. The
+// transaction must not be committed or rolled back.
+func (d *Database) UnsafeTx() *sqlx.Tx {
+	return d.tx
+}
@@ -0,0 +1,60 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestWithTx(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE withtx_abc (id INTEGER PRIMARY KEY);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	const insert = "INSERT INTO withtx_abc (id) VALUES (:id);"
+	tx, err := db.X.Beginx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	txd := db.WithTx(tx)
+	if _, err := txd.Exec(ctx, insert, map[string]interface{}{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := txd.Transact(ctx, sql.TxOptions{}, func(DB) error { return nil }); err == nil {
+		t.Fatal("expected nested transactions to fail")
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	if err := db.Get(ctx, "SELECT COUNT(*) FROM withtx_abc;", &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("expected the insert to be rolled back, got %v rows", n)
+	}
+	if s := db.Stats(); s.Statements != 3 {
+		t.Fatalf("expected the statement cache to be shared, got %v statements", s.Statements)
+	}
+}
+
+func TestTxLevel(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+	wrapped := Wrap(db, BindNow(SystemClock), NewMasker().Middleware())
+
+	if InTx(wrapped) || TxLevel(wrapped) != 0 || db.UnsafeTx() != nil {
+		t.Fatal("expected no transaction")
+	}
+	if err := wrapped.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		if !InTx(tx) || TxLevel(tx) != 1 {
+			t.Errorf("expected transaction level 1, got %v", TxLevel(tx))
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,59 @@
+package sqln
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrTxBudgetExceeded is returned for statements run in a transaction that
+// has used up its budget (see WithTxBudget).
+var ErrTxBudgetExceeded = errors.New("sqln: transaction budget exceeded")
+
+// TxBudget caps the work done in a single transaction, ie. to catch N+1
+// loops in serializable transactions before they hold locks for long.
+type TxBudget struct {
+	// Statements is the maximum number of statements. Zero is unlimited.
+	Statements int
+	// Time is the maximum total time spent running statements, excluding
+	// time spent in the transaction between them. Once it is exceeded the
+	// next statement fails. Zero is unlimited.
+	Time time.Duration
+}
+
+// WithTxBudget limits every transaction to the budget. Statements issued by
+// this package (ie. for WithTxSettings) are not counted.
+func WithTxBudget(b TxBudget) Option {
+	return func(d *Database) {
+		d.txBudget = &b
+	}
+}
+
+// ran records query as the last run in the transaction, returning
+// ErrTxBudgetExceeded if it is over budget.
+func (s *txState) ran(ctx context.Context, query string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.lastQuery = query
+	if internal(ctx) {
+		return nil
+	}
+	if b := s.budget; b != nil {
+		if b.Statements > 0 && s.statements >= b.Statements {
+			return errors.Wrapf(ErrTxBudgetExceeded, "%v statements", s.statements)
+		}
+		if b.Time > 0 && s.elapsed >= b.Time {
+			return errors.Wrapf(ErrTxBudgetExceeded, "%v spent", s.elapsed)
+		}
+	}
+	s.statements++
+	return nil
+}
+
+// done records the time spent running a statement.
+func (s *txState) done(elapsed time.Duration) {
+	s.mtx.Lock()
+	s.elapsed += elapsed
+	s.mtx.Unlock()
+}
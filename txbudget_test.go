@@ -0,0 +1,39 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestTxBudget(t *testing.T) {
+	base := sqliteDB(t)
+	db := New(base.X, WithTxBudget(TxBudget{Statements: 3}))
+	defer db.Close()
+	ctx := context.Background()
+
+	var ran int
+	err := db.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		for i := 0; i < 10; i++ {
+			var n int
+			if err := tx.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+				return err
+			}
+			ran++
+		}
+		return nil
+	})
+	if !errors.Is(err, ErrTxBudgetExceeded) || ran != 3 {
+		t.Fatalf("expected the fourth statement to exceed the budget, got %v after %v", err, ran)
+	}
+
+	// Statements outside of a transaction are not limited.
+	for i := 0; i < 5; i++ {
+		var n int
+		if err := db.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// TxOutcome is how a transaction ended.
+type TxOutcome string
+
+// Transaction outcomes.
+const (
+	TxCommitted    TxOutcome = "committed"
+	TxRolledBack   TxOutcome = "rolled back"
+	TxCommitFailed TxOutcome = "commit failed"
+)
+
+// TxSummary describes a finished transaction (see WithTxSummary).
+type TxSummary struct {
+	Level     int
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	Started   time.Time
+	Duration  time.Duration
+	// Statements excludes those issued by this package (ie. for
+	// WithTxSettings).
+	Statements int
+	// DBTime is the total time spent running statements.
+	DBTime time.Duration
+	// Retries is the number of earlier attempts, when the transaction is
+	// retried by Transactional.
+	Retries int
+	Outcome TxOutcome
+	Err     error
+}
+
+// WithTxSummary calls f with a summary of every transaction once it ends, so
+// slow business transactions can be diagnosed as a whole rather than per
+// query.
+func WithTxSummary(f func(ctx context.Context, s TxSummary)) Option {
+	return func(d *Database) {
+		d.txSummary = f
+	}
+}
+
+func (d *Database) summarize(ctx context.Context, opts sql.TxOptions, level int, tx *txState, outcome TxOutcome, err error) {
+	tx.mtx.Lock()
+	statements, elapsed := tx.statements, tx.elapsed
+	tx.mtx.Unlock()
+	attempt, _ := ctx.Value(attemptKey).(int)
+	if attempt > 0 {
+		attempt--
+	}
+	d.txSummary(ctx, TxSummary{
+		Level:      level,
+		Isolation:  opts.Isolation,
+		ReadOnly:   opts.ReadOnly,
+		Started:    tx.started,
+		Duration:   time.Since(tx.started),
+		Statements: statements,
+		DBTime:     elapsed,
+		Retries:    attempt,
+		Outcome:    outcome,
+		Err:        err,
+	})
+}
@@ -0,0 +1,56 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestTxSummary(t *testing.T) {
+	base := sqliteDB(t)
+	var summaries []TxSummary
+	db := New(base.X, WithTxSummary(func(ctx context.Context, s TxSummary) {
+		summaries = append(summaries, s)
+	}))
+	defer db.Close()
+	ctx := context.Background()
+
+	if err := db.Transact(ctx, sql.TxOptions{Isolation: sql.LevelSerializable}, func(tx DB) error {
+		var n int
+		if err := tx.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+			return err
+		}
+		return tx.Get(ctx, "SELECT 2;", &n, nil)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+	err := Transactional(db, TransactionalOptions{Retry: RetryOptions{Backoff: time.Millisecond}})(func(ctx context.Context, tx DB) error {
+		calls++
+		if calls == 1 {
+			return errors.Wrap(driver.ErrBadConn, "first")
+		}
+		return nil
+	})(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(summaries) != 3 {
+		t.Fatalf("expected 3 summaries, got %+v", summaries)
+	}
+	if s := summaries[0]; s.Outcome != TxCommitted || s.Statements != 2 || s.Isolation != sql.LevelSerializable || s.DBTime <= 0 {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+	if s := summaries[1]; s.Outcome != TxRolledBack || s.Err == nil || s.Retries != 0 {
+		t.Fatalf("unexpected summary of the failed attempt: %+v", s)
+	}
+	if s := summaries[2]; s.Outcome != TxCommitted || s.Retries != 1 {
+		t.Fatalf("unexpected summary of the retry: %+v", s)
+	}
+}
@@ -0,0 +1,72 @@
+package sqln
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Union queries a live table together with its archive tables (which share
+// its columns) as one ordered result, paginated by keyset.
+type Union struct {
+	// Tables to combine, ie. the live table followed by its archives.
+	Tables []string
+	// Columns selected from every table.
+	Columns []string
+	// Where is an optional filter applied to every table. It may reference
+	// named params.
+	Where string
+	// Keys are the columns rows are ordered and paginated by. Together they
+	// must be unique, ie. (created_at, id).
+	Keys []string
+	// Desc orders rows by descending keys.
+	Desc bool
+}
+
+// SQL returns the query for a page. If after is true, the query only returns
+// rows past the cursor.
+func (u Union) SQL(after bool) string {
+	var conds []string
+	if u.Where != "" {
+		conds = append(conds, "("+u.Where+")")
+	}
+	if after {
+		conds = append(conds, keysetWhere(u.Keys, u.Desc))
+	}
+	var where string
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	cols := strings.Join(u.Columns, ", ")
+	selects := make([]string, len(u.Tables))
+	for i, t := range u.Tables {
+		selects[i] = fmt.Sprintf("SELECT %v FROM %v%v", cols, t, where)
+	}
+
+	return fmt.Sprintf("SELECT %v FROM (%v) AS sqln_union ORDER BY %v LIMIT :sqln_limit;",
+		cols, strings.Join(selects, " UNION ALL "), keysetOrder(u.Keys, u.Desc))
+}
+
+// Page selects up to limit rows after the cursor (nil for the first page)
+// into dest, a pointer to a slice of structs. The returned cursor is nil once
+// there are no more rows.
+func (u Union) Page(ctx context.Context, db DB, dest interface{}, params interface{}, after Cursor, limit int) (Cursor, error) {
+	if len(u.Tables) == 0 || len(u.Columns) == 0 || len(u.Keys) == 0 {
+		return nil, errors.New("union: tables, columns and keys are required")
+	}
+	if after != nil && len(after) != len(u.Keys) {
+		return nil, errors.Errorf("union: cursor has %v values for %v keys", len(after), len(u.Keys))
+	}
+
+	m, _ := mapperOf(db)
+	if err := db.Select(ctx, u.SQL(after != nil), dest, withParams(m, params, keysetParams(after, limit))); err != nil {
+		return nil, err
+	}
+
+	next, err := nextCursor(m, u.Keys, reflect.Indirect(reflect.ValueOf(dest)), limit)
+	return next, errors.Wrap(err, "union")
+}
@@ -0,0 +1,68 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUnionPage(t *testing.T) {
+	db := sqliteDB(t)
+
+	ctx := context.Background()
+	for _, q := range []string{
+		"CREATE TABLE events (id INTEGER PRIMARY KEY, kind TEXT);",
+		"CREATE TABLE events_2023 (id INTEGER PRIMARY KEY, kind TEXT);",
+		"INSERT INTO events VALUES (5, 'a'), (7, 'b'), (8, 'a');",
+		"INSERT INTO events_2023 VALUES (1, 'a'), (2, 'a'), (3, 'b'), (4, 'a');",
+	} {
+		if _, err := db.Exec(ctx, q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	u := Union{
+		Tables:  []string{"events", "events_2023"},
+		Columns: []string{"id", "kind"},
+		Where:   "kind = :kind",
+		Keys:    []string{"id"},
+		Desc:    true,
+	}
+
+	type event struct {
+		ID   int    `db:"id"`
+		Kind string `db:"kind"`
+	}
+	var (
+		ids    []int
+		cursor Cursor
+		pages  int
+	)
+	for {
+		var page []event
+		var err error
+		cursor, err = u.Page(ctx, db, &page, map[string]interface{}{"kind": "a"}, cursor, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		for _, e := range page {
+			ids = append(ids, e.ID)
+		}
+		if cursor == nil {
+			break
+		}
+	}
+
+	expected := []int{8, 5, 4, 2, 1}
+	if len(ids) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, ids)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, ids)
+		}
+	}
+	if pages != 3 {
+		t.Fatalf("expected 3 pages, got %v", pages)
+	}
+}
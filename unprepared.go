@@ -0,0 +1,133 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Unpreparer is implemented by DBs that can run queries without preparing
+// and caching a statement, ie. for ad-hoc report queries that would
+// otherwise fill the statement cache.
+type Unpreparer interface {
+	ExecUnprepared(ctx context.Context, query string, params interface{}) (sql.Result, error)
+	GetUnprepared(ctx context.Context, query string, dest, params interface{}) error
+	SelectUnprepared(ctx context.Context, query string, dest, params interface{}) error
+}
+
+// ExecUnprepared executes a statement on db without preparing it, falling
+// back to Exec when db is not an Unpreparer.
+func ExecUnprepared(ctx context.Context, db DB, query string, params interface{}) (sql.Result, error) {
+	if u, ok := db.(Unpreparer); ok {
+		return u.ExecUnprepared(ctx, query, params)
+	}
+	return db.Exec(ctx, query, params)
+}
+
+// GetUnprepared gets a single record from db without preparing a statement,
+// falling back to Get when db is not an Unpreparer.
+func GetUnprepared(ctx context.Context, db DB, query string, dest, params interface{}) error {
+	if u, ok := db.(Unpreparer); ok {
+		return u.GetUnprepared(ctx, query, dest, params)
+	}
+	return db.Get(ctx, query, dest, params)
+}
+
+// SelectUnprepared selects multiple records from db without preparing a
+// statement, falling back to Select when db is not an Unpreparer.
+func SelectUnprepared(ctx context.Context, db DB, query string, dest, params interface{}) error {
+	if u, ok := db.(Unpreparer); ok {
+		return u.SelectUnprepared(ctx, query, dest, params)
+	}
+	return db.Select(ctx, query, dest, params)
+}
+
+// WithUnprepared runs queries for which dynamic returns true without
+// preparing them, ie. queries over a length or from a reporting package, as
+// if called through the Unprepared methods.
+func WithUnprepared(dynamic func(query string) bool) Option {
+	return func(d *Database) {
+		d.dynamic = dynamic
+	}
+}
+
+// isDynamic reports whether query runs unprepared, either by WithUnprepared,
+// because d is pinned to a connection (see WithConn) or because statements
+// are left to the driver (see ConnStmts).
+func (d *Database) isDynamic(query string) bool {
+	if d.conn != nil || d.connStmts {
+		return true
+	}
+	return d.dynamic != nil && d.dynamic(query)
+}
+
+// ExecUnprepared executes a statement without preparing it.
+func (d *Database) ExecUnprepared(ctx context.Context, query string, params interface{}) (sql.Result, error) {
+	var res sql.Result
+	err := d.unprepared(ctx, "Exec", query, params, func(ctx context.Context, q string, args []interface{}) error {
+		var err error
+		res, err = d.ext().ExecContext(ctx, q, args...)
+		return err
+	})
+	return res, err
+}
+
+// GetUnprepared gets a single record without preparing a statement.
+func (d *Database) GetUnprepared(ctx context.Context, query string, dest, params interface{}) error {
+	err := d.unprepared(ctx, "Get", query, params, func(ctx context.Context, q string, args []interface{}) error {
+		if isMapDest(dest) {
+			return d.queryMaps(ctx, q, args, dest, true)
+		}
+		return sqlx.GetContext(ctx, d.ext(), dest, q, args...)
+	})
+	return d.notFoundErr(ctx, err)
+}
+
+// SelectUnprepared selects multiple records without preparing a statement.
+func (d *Database) SelectUnprepared(ctx context.Context, query string, dest, params interface{}) error {
+	return d.unprepared(ctx, "Select", query, params, func(ctx context.Context, q string, args []interface{}) error {
+		if d.rowLimit != nil && !internal(ctx) {
+			rows, err := d.ext().QueryxContext(ctx, q, args...)
+			if err != nil {
+				return err
+			}
+			return d.limitedScan(ctx, query, rows, dest)
+		}
+		if isMapDest(dest) {
+			return d.queryMaps(ctx, q, args, dest, false)
+		}
+		return sqlx.SelectContext(ctx, d.ext(), dest, q, args...)
+	})
+}
+
+// unprepared binds params into query and runs it with f.
+func (d *Database) unprepared(ctx context.Context, method, query string, params interface{}, f func(context.Context, string, []interface{}) error) (err error) {
+	ctx, done, err := d.begin(ctx, method, query)
+	if err != nil {
+		return err
+	}
+	defer done()
+	defer d.observe(ctx, query, params, time.Now())
+	if d.connStmts {
+		// Query texts are not expected to vary, as with prepared statements.
+		defer d.queryStats.measure(query, time.Now(), &err)
+	}
+
+	if err := d.checkQuery(ctx, query); err != nil {
+		return err
+	}
+	if err := d.checkPlan(ctx, query, params); err != nil {
+		return err
+	}
+	if params == nil {
+		params = struct{}{}
+	}
+	params = d.arrayParams(params)
+	q, args, err := d.drv.BindNamed(query, params)
+	if err != nil {
+		return err
+	}
+	return nameErr(ctx, query, d.diagnoseLocks(ctx, query, f(ctx, q, args)))
+}
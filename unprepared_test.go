@@ -0,0 +1,53 @@
+package sqln
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestUnprepared(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	if _, err := ExecUnprepared(ctx, db, "CREATE TABLE reports (id INTEGER, name TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExecUnprepared(ctx, db, "INSERT INTO reports (id, name) VALUES (:id, :name);", map[string]interface{}{"id": 1, "name": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := GetUnprepared(ctx, db, "SELECT name FROM reports WHERE id = :id;", &name, map[string]interface{}{"id": 1}); err != nil || name != "a" {
+		t.Fatalf("unexpected get: %q %v", name, err)
+	}
+	var ids []int
+	if err := SelectUnprepared(ctx, db, "SELECT id FROM reports;", &ids, nil); err != nil || len(ids) != 1 {
+		t.Fatalf("unexpected select: %v %v", ids, err)
+	}
+	if s := db.Stats(); s.Statements != 0 {
+		t.Fatalf("expected no cached statements, got %v", s.Statements)
+	}
+}
+
+func TestWithUnprepared(t *testing.T) {
+	base := sqliteDB(t)
+	db := New(base.X, WithUnprepared(func(query string) bool {
+		return strings.Contains(query, "/* report */")
+	}))
+	defer db.Close()
+	ctx := context.Background()
+
+	var n int
+	if err := db.Get(ctx, "/* report */ SELECT 1;", &n, nil); err != nil || n != 1 {
+		t.Fatalf("unexpected get: %v %v", n, err)
+	}
+	if s := db.Stats(); s.Statements != 0 {
+		t.Fatalf("expected dynamic query not to be cached, got %v", s.Statements)
+	}
+	if err := db.Get(ctx, "SELECT 1;", &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	if s := db.Stats(); s.Statements != 1 {
+		t.Fatalf("expected 1 cached statement, got %v", s.Statements)
+	}
+}
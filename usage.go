@@ -0,0 +1,177 @@
+package sqln
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UsageTracking configures WithUsageTracking.
+type UsageTracking struct {
+	// OnDeprecated is called for every execution of a deprecated query (see
+	// Deprecate), ie. to log a warning.
+	OnDeprecated func(ctx context.Context, q DeprecatedQuery)
+	// Clock times executions. Defaults to SystemClock.
+	Clock Clock
+}
+
+// DeprecatedQuery describes an execution of a deprecated query.
+type DeprecatedQuery struct {
+	Query string
+	// Name is the query name, if any (see QueryName).
+	Name   string
+	Reason string
+	// Caller is the file and line of the call into this package that ran
+	// the query.
+	Caller string
+}
+
+// QueryUsage describes the executions of a query since usage tracking
+// started (see WithUsageTracking).
+type QueryUsage struct {
+	Query        string
+	Count        int64
+	LastExecuted time.Time
+	// Deprecated is the reason the query is deprecated, if it is.
+	Deprecated string
+}
+
+// WithUsageTracking counts the executions of every query, reporting those of
+// deprecated queries to OnDeprecated, so dead and deprecated SQL can be
+// found (see QueryUsage and UnusedQueries) and retired safely. Queries are
+// counted by their exact text, as they are registered. Internal queries are
+// not counted.
+func WithUsageTracking(u UsageTracking) Option {
+	if u.Clock == nil {
+		u.Clock = SystemClock
+	}
+	return func(d *Database) {
+		d.usage = &usageTracker{cfg: u, started: u.Clock.Now(), queries: make(map[string]*queryUsage)}
+	}
+}
+
+// Deprecate registers queries (see Register) as deprecated for reason, ie.
+// the query replacing them. Executions are reported by WithUsageTracking.
+func (d *Database) Deprecate(reason string, queries ...string) {
+	d.Register(queries...)
+	d.registry.mtx.Lock()
+	defer d.registry.mtx.Unlock()
+	if d.registry.deprecated == nil {
+		d.registry.deprecated = make(map[string]string)
+	}
+	for _, q := range queries {
+		d.registry.deprecated[q] = reason
+	}
+}
+
+type usageTracker struct {
+	cfg     UsageTracking
+	started time.Time
+
+	mtx     sync.Mutex
+	queries map[string]*queryUsage
+}
+
+type queryUsage struct {
+	count int64
+	last  time.Time
+}
+
+// ran records an execution of query, reporting it if it is deprecated.
+func (u *usageTracker) ran(ctx context.Context, r *registry, query string) {
+	now := u.cfg.Clock.Now()
+	u.mtx.Lock()
+	q, ok := u.queries[query]
+	if !ok {
+		q = &queryUsage{}
+		u.queries[query] = q
+	}
+	q.count++
+	q.last = now
+	u.mtx.Unlock()
+
+	r.mtx.Lock()
+	reason, deprecated := r.deprecated[query]
+	r.mtx.Unlock()
+	if deprecated && u.cfg.OnDeprecated != nil {
+		u.cfg.OnDeprecated(ctx, DeprecatedQuery{Query: query, Name: QueryName(ctx, query), Reason: reason, Caller: caller()})
+	}
+}
+
+// QueryUsage returns the usage of every registered query and every query
+// executed since usage tracking started, sorted by query. It returns nil
+// without WithUsageTracking.
+func (d *Database) QueryUsage() []QueryUsage {
+	u := d.usage
+	if u == nil {
+		return nil
+	}
+	d.registry.mtx.Lock()
+	deprecated := make(map[string]string, len(d.registry.deprecated))
+	for q, reason := range d.registry.deprecated {
+		deprecated[q] = reason
+	}
+	d.registry.mtx.Unlock()
+
+	usage := make(map[string]QueryUsage)
+	for _, q := range d.registered() {
+		usage[q] = QueryUsage{Query: q, Deprecated: deprecated[q]}
+	}
+	u.mtx.Lock()
+	for q, qu := range u.queries {
+		usage[q] = QueryUsage{Query: q, Count: qu.count, LastExecuted: qu.last, Deprecated: deprecated[q]}
+	}
+	u.mtx.Unlock()
+
+	all := make([]QueryUsage, 0, len(usage))
+	for _, qu := range usage {
+		all = append(all, qu)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Query < all[j].Query })
+	return all
+}
+
+// UnusedQueries returns the registered queries not executed within window,
+// sorted, ie. to find dead SQL. Queries are only known to be unused over
+// the time usage has been tracked, so window should not exceed it. It
+// returns nil without WithUsageTracking.
+func (d *Database) UnusedQueries(window time.Duration) []string {
+	if d.usage == nil {
+		return nil
+	}
+	since := d.usage.cfg.Clock.Now().Add(-window)
+	registered := make(map[string]bool)
+	for _, q := range d.registered() {
+		registered[q] = true
+	}
+	var unused []string
+	for _, qu := range d.QueryUsage() {
+		if registered[qu.Query] && qu.LastExecuted.Before(since) {
+			unused = append(unused, qu.Query)
+		}
+	}
+	return unused
+}
+
+var pkgPath = reflect.TypeOf(Database{}).PkgPath()
+
+// caller returns the file and line of the first caller outside of this
+// package.
+func caller() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, pkgPath+".") || strings.HasSuffix(f.File, "_test.go") {
+			return fmt.Sprintf("%v:%v", f.File, f.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package sqln
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryUsage(t *testing.T) {
+	clock := NewTestClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	var reports []DeprecatedQuery
+	base := sqliteDB(t)
+	db := New(base.X, WithUsageTracking(UsageTracking{
+		OnDeprecated: func(ctx context.Context, q DeprecatedQuery) { reports = append(reports, q) },
+		Clock:        clock,
+	}))
+	defer db.Close()
+	ctx := context.Background()
+
+	const (
+		current = "SELECT 1;"
+		old     = "SELECT 2;"
+		dead    = "SELECT 3;"
+	)
+	db.Register(current, dead)
+	db.Deprecate("use SELECT 1", old)
+
+	var n int
+	if err := db.Get(ctx, current, &n, nil); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Hour)
+	for i := 0; i < 2; i++ {
+		if err := db.Get(WithQueryName(ctx, "old"), old, &n, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 reports, got %+v", reports)
+	}
+	if r := reports[0]; r.Query != old || r.Name != "old" || r.Reason != "use SELECT 1" || !strings.Contains(r.Caller, "usage_test.go") {
+		t.Fatalf("unexpected report %+v", r)
+	}
+
+	usage := db.QueryUsage()
+	if len(usage) != 3 {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+	if u := usage[1]; u.Query != old || u.Count != 2 || u.Deprecated == "" || !u.LastExecuted.Equal(clock.Now()) {
+		t.Fatalf("unexpected usage %+v", u)
+	}
+
+	if got := db.UnusedQueries(2 * time.Hour); len(got) != 1 || got[0] != dead {
+		t.Fatalf("expected only %q to be unused, got %v", dead, got)
+	}
+	if got := db.UnusedQueries(time.Minute); len(got) != 2 || got[0] != current {
+		t.Fatalf("expected %q and %q to be unused, got %v", current, dead, got)
+	}
+}
@@ -0,0 +1,123 @@
+package sqln
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type registry struct {
+	mtx     sync.Mutex
+	queries map[string]bool
+	// deprecated holds the reason of every deprecated query (see
+	// Deprecate).
+	deprecated map[string]string
+}
+
+// Register records queries to be checked by ValidateAll, and the only queries
+// allowed to run when the Registered strict check is enabled. Queries in the
+// allowlist (see WithAllowlist) are always checked.
+func (d *Database) Register(queries ...string) {
+	d.registry.mtx.Lock()
+	defer d.registry.mtx.Unlock()
+	for _, q := range queries {
+		d.registry.queries[q] = true
+	}
+}
+
+// allows reports whether query is registered, or no queries are.
+func (r *registry) allows(query string) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return len(r.queries) == 0 || r.queries[query]
+}
+
+// ValidateOptions configures ValidateAll.
+type ValidateOptions struct {
+	// Explain also runs EXPLAIN for each query, with NULL params, which
+	// catches some errors that preparing does not.
+	Explain bool
+}
+
+// ValidationError lists the queries that failed validation.
+type ValidationError struct {
+	Failures map[string]error
+}
+
+func (e *ValidationError) Error() string {
+	queries := make([]string, 0, len(e.Failures))
+	for q := range e.Failures {
+		queries = append(queries, q)
+	}
+	sort.Strings(queries)
+
+	msgs := make([]string, len(queries))
+	for i, q := range queries {
+		msgs[i] = fmt.Sprintf("%q: %v", q, e.Failures[q])
+	}
+	return fmt.Sprintf("%v invalid queries: %v", len(queries), strings.Join(msgs, "; "))
+}
+
+// ValidateAll prepares every registered query against the database so that
+// typos and schema drift surface on boot rather than on first use. All
+// failures are returned at once as a *ValidationError. It is intended for
+// Startup.Validate.
+func (d *Database) ValidateAll(ctx context.Context, opts ValidateOptions) error {
+	failures := make(map[string]error)
+	for _, q := range d.registered() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s, err := d.StmtContext(ctx, q)
+		if err != nil {
+			failures[q] = err
+			continue
+		}
+		if opts.Explain {
+			if err := d.explain(ctx, q, s); err != nil {
+				failures[q] = err
+			}
+		}
+	}
+	if len(failures) > 0 {
+		return &ValidationError{Failures: failures}
+	}
+	return nil
+}
+
+// registered returns the registered and allowlisted queries, sorted.
+func (d *Database) registered() []string {
+	d.registry.mtx.Lock()
+	defer d.registry.mtx.Unlock()
+	queries := make([]string, 0, len(d.registry.queries)+len(d.allowlist))
+	for q := range d.registry.queries {
+		queries = append(queries, q)
+	}
+	for q := range d.allowlist {
+		if !d.registry.queries[q] {
+			queries = append(queries, q)
+		}
+	}
+	sort.Strings(queries)
+	return queries
+}
+
+func (d *Database) explain(ctx context.Context, query string, s *sqlx.NamedStmt) error {
+	params := make(map[string]interface{}, len(s.Params))
+	for _, p := range s.Params {
+		params[p] = nil
+	}
+	q, args, err := d.drv.BindNamed("EXPLAIN "+query, params)
+	if err != nil {
+		return err
+	}
+	rows, err := d.drv.QueryxContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
@@ -0,0 +1,37 @@
+package sqln
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestValidateAll(t *testing.T) {
+	db := sqliteDB(t)
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE validate_abc (id INTEGER PRIMARY KEY, name TEXT);", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	db.Register(
+		"SELECT id, name FROM validate_abc WHERE id = :id;",
+		"SELECT nme FROM validate_abc;",
+		"SELECT * FROM validate_missing;",
+	)
+	err := db.ValidateAll(ctx, ValidateOptions{Explain: true})
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	if len(verr.Failures) != 2 || verr.Failures["SELECT nme FROM validate_abc;"] == nil || verr.Failures["SELECT * FROM validate_missing;"] == nil {
+		t.Fatalf("unexpected failures: %v", verr)
+	}
+
+	valid := New(db.X, WithAllowlist("SELECT id FROM validate_abc;"))
+	defer valid.Close()
+	if err := valid.ValidateAll(ctx, ValidateOptions{Explain: true}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,65 @@
+package sqln
+
+import "context"
+
+// WarmCache prepares queries ahead of use, ie. at boot from a list saved with
+// CachedQueries, so the first requests after a deploy do not pay for
+// preparing. Queries that fail to prepare are returned as a
+// *ValidationError; the others are still cached.
+func (d *Database) WarmCache(ctx context.Context, queries []string) error {
+	failures := make(map[string]error)
+	for _, q := range queries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := d.cache.get(ctx, q); err != nil {
+			failures[q] = err
+		}
+	}
+	if len(failures) > 0 {
+		return &ValidationError{Failures: failures}
+	}
+	return nil
+}
+
+// CachedQueries returns the queries in the statement cache, most recently
+// used first.
+func (d *Database) CachedQueries() []string {
+	return d.cache.queries()
+}
+
+// Invalidator is implemented by DBs with a statement cache that can be
+// flushed, ie. after DDL changes the tables behind cached statements.
+type Invalidator interface {
+	InvalidateStmt(query string)
+	InvalidateAll() int
+}
+
+// InvalidateAll flushes the statement cache of db, or the DB it decorates,
+// returning the number of statements evicted. DBs without a cache report
+// zero.
+func InvalidateAll(db DB) int {
+	for db != nil {
+		if i, ok := db.(Invalidator); ok {
+			return i.InvalidateAll()
+		}
+		u, ok := db.(Unwrapper)
+		if !ok {
+			break
+		}
+		db = u.Unwrap()
+	}
+	return 0
+}
+
+// InvalidateStmt evicts the cached statements for query, if any (in every
+// partition, see WithTenantStmtCache), so it is prepared again on next use.
+// Statements in use are closed once released.
+func (d *Database) InvalidateStmt(query string) {
+	d.cache.invalidate(query)
+}
+
+// InvalidateAll evicts every cached statement, returning how many there were.
+func (d *Database) InvalidateAll() int {
+	return d.cache.invalidateAll()
+}
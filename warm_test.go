@@ -0,0 +1,61 @@
+package sqln
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestWarmCache(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	var n int
+	for _, q := range []string{"SELECT 1;", "SELECT 2;"} {
+		if err := db.Get(ctx, q, &n, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hot := db.CachedQueries()
+	if !reflect.DeepEqual(hot, []string{"SELECT 2;", "SELECT 1;"}) {
+		t.Fatalf("unexpected cached queries: %v", hot)
+	}
+
+	fresh := New(db.X)
+	defer fresh.Close()
+	err := fresh.WarmCache(ctx, append(hot, "SELECT * FROM warm_missing;"))
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Failures) != 1 {
+		t.Fatalf("expected 1 failure, got %v", err)
+	}
+	if s := fresh.Stats(); s.Statements != 2 {
+		t.Fatalf("expected 2 warmed statements, got %v", s.Statements)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	db := sqliteDB(t)
+	ctx := context.Background()
+
+	var n int
+	for _, q := range []string{"SELECT 1;", "SELECT 2;"} {
+		if err := db.Get(ctx, q, &n, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.InvalidateStmt("SELECT 1;")
+	if hot := db.CachedQueries(); !reflect.DeepEqual(hot, []string{"SELECT 2;"}) {
+		t.Fatalf("unexpected cached queries: %v", hot)
+	}
+	if n := InvalidateAll(MutationHook{}.Middleware()(db)); n != 1 {
+		t.Fatalf("expected 1 invalidated, got %v", n)
+	}
+	if s := db.Stats(); s.Statements != 0 {
+		t.Fatalf("expected no statements, got %v", s.Statements)
+	}
+	if err := db.Get(ctx, "SELECT 2;", &n, nil); err != nil || n != 2 {
+		t.Fatalf("expected re-prepared query to run, got %v, %v", n, err)
+	}
+}
@@ -0,0 +1,72 @@
+package sqln
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TxWatchdog reports transactions that stay open too long, ie. ones leaked
+// idle in transaction.
+type TxWatchdog struct {
+	// Threshold is the age at which a transaction is reported.
+	Threshold time.Duration
+	// OnLongTx is called, from its own goroutine, once for each open
+	// transaction older than Threshold.
+	OnLongTx func(LongTx)
+	// Clock times transactions (see TimerClock). Defaults to SystemClock.
+	Clock Clock
+}
+
+// LongTx describes a transaction that exceeded the TxWatchdog threshold.
+type LongTx struct {
+	Level   int
+	Started time.Time
+	// LastQuery is the query most recently run in the transaction, if any.
+	LastQuery string
+}
+
+// WithTxWatchdog reports transactions open for longer than the watchdog's
+// threshold.
+func WithTxWatchdog(w TxWatchdog) Option {
+	if w.Clock == nil {
+		w.Clock = SystemClock
+	}
+	return func(d *Database) {
+		d.watchdog = &w
+	}
+}
+
+// txState is shared by a transaction's Database.
+type txState struct {
+	started time.Time
+	budget  *TxBudget
+
+	mtx        sync.Mutex
+	lastQuery  string
+	statements int
+	elapsed    time.Duration
+	// stmts caches the transaction's wrappers of pool statements.
+	stmts map[*sqlx.NamedStmt]*sqlx.NamedStmt
+	// temps are the temp tables to drop when the transaction ends (see
+	// CreateTempTable).
+	temps []string
+	// scoped is set once the transaction changes its search_path (see
+	// TenantScope), after which its statements are prepared on its own
+	// connection and kept in scopedStmts.
+	scoped      bool
+	scopedStmts map[string]*sqlx.NamedStmt
+}
+
+// watch reports tx if it is still open after the threshold. The returned
+// func stops watching.
+func (w *TxWatchdog) watch(level int, tx *txState) func() {
+	t := afterFunc(w.Clock, w.Threshold, func() {
+		tx.mtx.Lock()
+		last := tx.lastQuery
+		tx.mtx.Unlock()
+		w.OnLongTx(LongTx{Level: level, Started: tx.started, LastQuery: last})
+	})
+	return func() { t.Stop() }
+}
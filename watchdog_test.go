@@ -0,0 +1,74 @@
+package sqln
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestTxWatchdog(t *testing.T) {
+	db := sqliteDB(t)
+	long := make(chan LongTx, 1)
+	d := New(db.X, WithTxWatchdog(TxWatchdog{
+		Threshold: 10 * time.Millisecond,
+		OnLongTx:  func(tx LongTx) { long <- tx },
+	}))
+	defer d.Close()
+
+	ctx := context.Background()
+	const q = "SELECT 1;"
+	if err := d.Transact(ctx, sql.TxOptions{}, func(tx DB) error {
+		var n int
+		if err := tx.Get(ctx, q, &n, nil); err != nil {
+			return err
+		}
+		select {
+		case tx := <-long:
+			if tx.Level != 1 || tx.LastQuery != q {
+				t.Errorf("unexpected long tx: %+v", tx)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("expected the tx to be reported")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Transact(ctx, sql.TxOptions{}, func(tx DB) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case tx := <-long:
+		t.Fatalf("expected short tx not to be reported, got %+v", tx)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestTxWatchdogClock(t *testing.T) {
+	db := sqliteDB(t)
+	clock := NewTestClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	long := make(chan LongTx, 1)
+	d := New(db.X, WithTxWatchdog(TxWatchdog{
+		Threshold: time.Hour,
+		OnLongTx:  func(tx LongTx) { long <- tx },
+		Clock:     clock,
+	}))
+	defer d.Close()
+
+	if err := d.Transact(context.Background(), sql.TxOptions{}, func(tx DB) error {
+		clock.Advance(time.Hour)
+		select {
+		case <-long:
+		case <-time.After(5 * time.Second):
+			t.Error("expected the tx to be reported")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n := clock.Timers(); n != 0 {
+		t.Fatalf("expected no timers waiting, got %v", n)
+	}
+}